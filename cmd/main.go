@@ -5,26 +5,62 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"runtime"
+	"runtime/debug"
 	"strings"
 	"syscall"
+	"text/tabwriter"
+	"time"
 
 	"github.com/ochronus/goputioarr/internal/app"
+	"github.com/ochronus/goputioarr/internal/benchmark"
 	"github.com/ochronus/goputioarr/internal/config"
 	"github.com/ochronus/goputioarr/internal/download"
-	httpserver "github.com/ochronus/goputioarr/internal/http"
+	"github.com/ochronus/goputioarr/internal/hint"
+	"github.com/ochronus/goputioarr/internal/history"
+	"github.com/ochronus/goputioarr/internal/proxy"
+	"github.com/ochronus/goputioarr/internal/prune"
+	"github.com/ochronus/goputioarr/internal/putiomock"
+	"github.com/ochronus/goputioarr/internal/services/putio"
+	"github.com/ochronus/goputioarr/internal/services/release"
+	"github.com/ochronus/goputioarr/internal/state"
 	"github.com/ochronus/goputioarr/internal/utils"
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
-var version = "dev"
+// Build metadata, injected with -ldflags "-X main.version=... -X main.commit=...
+// -X main.buildDate=...". commit and buildDate fall back to the VCS details Go
+// records when building from a checkout.
+var (
+	version   = "dev"
+	commit    = ""
+	buildDate = ""
+)
 
 var (
-	configPath string
+	configPath     string
+	historyLimit   int
+	historyName    string
+	historyArr     string
+	historySince   time.Duration
+	demoMode       bool
+	staticTemplate bool
+	migrateFrom    string
+	checkUpdate    bool
+	pruneDays      int
+	pruneApply     bool
+	benchFileID    int64
+	benchDuration  time.Duration
+	benchWorkers   []int
+	stateOutput    string
+	stateForce     bool
+	putioAPIURL    string
 )
 
 func main() {
@@ -48,6 +84,7 @@ func main() {
 		RunE:  runProxy,
 	}
 	runCmd.Flags().StringVarP(&configPath, "config", "c", defaultConfigPath, "Path to config file")
+	runCmd.Flags().BoolVar(&demoMode, "demo", false, "Use a simulated put.io account instead of the real API")
 
 	selfUpdateCmd := &cobra.Command{
 		Use:   "self-update",
@@ -62,52 +99,230 @@ func main() {
 		Use:   "get-token",
 		Short: "Generate a put.io API token",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			_, err := utils.GetToken()
+			_, err := utils.GetToken(putioAPIURL)
 			return err
 		},
 	}
+	getTokenCmd.Flags().StringVar(&putioAPIURL, "api-url", "", "put.io API base URL, e.g. of a proxy (default put.io's)")
 
 	// Generate-config command
 	generateConfigCmd := &cobra.Command{
 		Use:   "generate-config",
 		Short: "Generate config",
+		Long:  "Generate a config file. On a terminal, a wizard asks for the download directory, credentials and arr connections and checks them; otherwise, or with --template, a template with placeholders is written.",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return utils.GenerateConfig(configPath)
+			if staticTemplate || !isTerminal(os.Stdin) {
+				return utils.GenerateConfig(configPath, putioAPIURL)
+			}
+			return utils.GenerateConfigInteractive(configPath, putioAPIURL, os.Stdin, os.Stdout)
 		},
 	}
 	generateConfigCmd.Flags().StringVarP(&configPath, "config", "c", defaultConfigPath, "Path to config file")
+	generateConfigCmd.Flags().BoolVar(&staticTemplate, "template", false, "Write the template with placeholders instead of running the wizard")
+	generateConfigCmd.Flags().StringVar(&putioAPIURL, "api-url", "", "put.io API base URL, e.g. of a proxy, written to the config as putio.api_url (default put.io's)")
+
+	// Migrate command
+	migrateCmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Convert a config of the Rust putioarr",
+		Long:  "Convert a config of the original Rust putioarr into a goputioarr config. An existing config at the target path is backed up first, so both paths may be the same.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return utils.Migrate(migrateFrom, configPath)
+		},
+	}
+	migrateCmd.Flags().StringVar(&migrateFrom, "from", "", "Path to the putioarr config")
+	migrateCmd.Flags().StringVarP(&configPath, "config", "c", defaultConfigPath, "Path to write the goputioarr config to")
+	migrateCmd.MarkFlagRequired("from")
 
 	// Version command
 	versionCmd := &cobra.Command{
 		Use:   "version",
-		Short: "Print the version number",
-		Run: func(cmd *cobra.Command, args []string) {
-			fmt.Printf("goputioarr version %s\n", version)
+		Short: "Print the version and build details",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			printVersion()
+			if checkUpdate {
+				return printUpdateCheck()
+			}
+			return nil
 		},
 	}
+	versionCmd.Flags().BoolVar(&checkUpdate, "check-update", false, "Check GitHub for a newer release")
+
+	// History command
+	historyCmd := &cobra.Command{
+		Use:   "history",
+		Short: "Show completed downloads",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return showHistory(history.Query{
+				Name:       historyName,
+				ImportedBy: historyArr,
+				Limit:      historyLimit,
+			}, historySince)
+		},
+	}
+	historyCmd.Flags().StringVarP(&configPath, "config", "c", defaultConfigPath, "Path to config file")
+	historyCmd.Flags().IntVarP(&historyLimit, "limit", "n", 20, "Number of entries to show (0 for all)")
+	historyCmd.Flags().StringVar(&historyName, "name", "", "Only show entries whose name contains this")
+	historyCmd.Flags().StringVar(&historyArr, "arr", "", "Only show entries imported by this arr")
+	historyCmd.Flags().DurationVar(&historySince, "since", 0, "Only show entries completed within this duration, e.g. 168h")
+
+	// Prune command
+	pruneCmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove old finished transfers and orphaned files from put.io",
+		Long:  "List finished put.io transfers and files in the managed folder (putio.parent_folder_id) that no transfer refers to, older than the given number of days. Nothing is removed without --apply. Files are only considered when parent_folder_id is set.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPrune(pruneDays, pruneApply)
+		},
+	}
+	pruneCmd.Flags().StringVarP(&configPath, "config", "c", defaultConfigPath, "Path to config file")
+	pruneCmd.Flags().IntVar(&pruneDays, "older-than", 30, "Minimum age in days")
+	pruneCmd.Flags().BoolVar(&pruneApply, "apply", false, "Remove what's listed instead of only listing it")
+
+	// Benchmark command
+	benchmarkCmd := &cobra.Command{
+		Use:   "benchmark",
+		Short: "Measure put.io download speed to pick download_workers",
+		Long:  "Download a file from put.io with different numbers of parallel downloads and recommend a download_workers setting for this connection. Without --file-id the largest file in putio.parent_folder_id (or the account root) and its subfolders is used. Nothing is written to disk.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runBenchmark(benchFileID, benchDuration, benchWorkers)
+		},
+	}
+	benchmarkCmd.Flags().StringVarP(&configPath, "config", "c", defaultConfigPath, "Path to config file")
+	benchmarkCmd.Flags().Int64Var(&benchFileID, "file-id", 0, "put.io file to download (default: the largest file found)")
+	benchmarkCmd.Flags().DurationVar(&benchDuration, "duration", benchmark.DefaultDuration, "How long to measure each worker count")
+	benchmarkCmd.Flags().IntSliceVar(&benchWorkers, "workers", benchmark.DefaultWorkers, "Worker counts to try")
+
+	// State command
+	stateCmd := &cobra.Command{
+		Use:   "state",
+		Short: "Back up or move the proxy's persisted state",
+	}
+	stateExportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Write transfer state, history and seen feed items as a JSON snapshot",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return exportState(stateOutput)
+		},
+	}
+	stateExportCmd.Flags().StringVarP(&configPath, "config", "c", defaultConfigPath, "Path to config file")
+	stateExportCmd.Flags().StringVarP(&stateOutput, "output", "o", "", "File to write the snapshot to (default: stdout)")
+	stateImportCmd := &cobra.Command{
+		Use:   "import <snapshot.json>",
+		Short: "Replace the persisted state with a snapshot written by state export",
+		Long:  "Replace the persisted state with a snapshot written by state export. Stop the proxy first, or it will overwrite the imported state with its own. Existing state is only replaced with --force.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return importState(args[0], stateForce)
+		},
+	}
+	stateImportCmd.Flags().StringVarP(&configPath, "config", "c", defaultConfigPath, "Path to config file")
+	stateImportCmd.Flags().BoolVar(&stateForce, "force", false, "Replace existing state")
+	stateCmd.AddCommand(stateExportCmd, stateImportCmd)
+
+	// Service command
+	serviceCmd := &cobra.Command{
+		Use:   "service",
+		Short: "Manage goputioarr as a system service (Windows service, macOS launch agent, systemd unit)",
+	}
+	serviceInstallCmd := &cobra.Command{
+		Use:   "install",
+		Short: "Install the service, starting the proxy at boot (or login on macOS)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			absConfigPath, err := filepath.Abs(configPath)
+			if err != nil {
+				return err
+			}
+			if _, err := os.Stat(absConfigPath); err != nil {
+				return fmt.Errorf("config file not found, run generate-config first: %w", err)
+			}
+			return controlService("install", absConfigPath)
+		},
+	}
+	serviceInstallCmd.Flags().StringVarP(&configPath, "config", "c", defaultConfigPath, "Path to config file")
+	serviceCmd.AddCommand(
+		serviceInstallCmd,
+		&cobra.Command{
+			Use:   "uninstall",
+			Short: "Stop and remove the service",
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return controlService("uninstall", "")
+			},
+		},
+		&cobra.Command{
+			Use:   "start",
+			Short: "Start the service",
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return controlService("start", "")
+			},
+		},
+		&cobra.Command{
+			Use:   "stop",
+			Short: "Stop the service",
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return controlService("stop", "")
+			},
+		},
+	)
 
 	rootCmd.AddCommand(runCmd)
+	rootCmd.AddCommand(historyCmd)
 	rootCmd.AddCommand(getTokenCmd)
 	rootCmd.AddCommand(generateConfigCmd)
+	rootCmd.AddCommand(migrateCmd)
+	rootCmd.AddCommand(pruneCmd)
+	rootCmd.AddCommand(benchmarkCmd)
 	rootCmd.AddCommand(selfUpdateCmd)
+	rootCmd.AddCommand(serviceCmd)
+	rootCmd.AddCommand(stateCmd)
 	rootCmd.AddCommand(versionCmd)
 
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, hint.Format(err))
 		os.Exit(1)
 	}
 }
 
 func runProxy(cmd *cobra.Command, args []string) error {
+	if handled, err := runAsService(serveProxy); handled {
+		return err
+	}
+
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
+	return serveProxy(ctx)
+}
+
+// serveProxy runs the proxy until ctx is cancelled
+func serveProxy(ctx context.Context) error {
 	// Load configuration
 	cfg, err := config.Load(configPath)
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
+	if demoMode {
+		if cfg.Putio.APIKey == "" {
+			cfg.Putio.APIKey = "demo"
+		}
+		demoURL, err := startDemoPutio(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to start demo put.io server: %w", err)
+		}
+		cfg.Putio.APIURL = demoURL
+		cfg.Putio.UploadURL = demoURL
+	}
+
+	// Apply the umask before anything is created; an invalid one is reported
+	// by Validate.
+	if mask, err := config.ParseUmask(cfg.Umask); cfg.Umask != "" && err == nil {
+		setUmask(mask)
+	}
+	if err := download.PrepareDirectory(cfg.DownloadDirectory, cfg.CreateDownloadDir, cfg.UID, cfg.GID); err != nil {
+		return err
+	}
+
 	// Validate configuration
 	if err := cfg.Validate(); err != nil {
 		return fmt.Errorf("invalid configuration: %w", err)
@@ -120,17 +335,281 @@ func runProxy(cmd *cobra.Command, args []string) error {
 	}
 
 	container.Logger.Infof("Starting goputioarr, version %s", version)
+	if demoMode {
+		container.Logger.Warn("Demo mode: using a simulated put.io account, nothing is downloaded from put.io")
+	}
+	if cfg.CheckForUpdates {
+		go logUpdateCheck(ctx, container.Logger)
+	}
+
+	return proxy.New(container).Run(ctx)
+}
+
+// startDemoPutio serves a simulated put.io account on a local port until ctx
+// is cancelled and returns its base URL.
+func startDemoPutio(ctx context.Context) (string, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	srv := &http.Server{
+		Handler:           putiomock.New().Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		_ = srv.Serve(listener)
+	}()
+	go func() {
+		<-ctx.Done()
+		_ = srv.Close()
+	}()
+	return "http://" + listener.Addr().String(), nil
+}
+
+func showHistory(query history.Query, since time.Duration) error {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if !cfg.History.Enabled {
+		return fmt.Errorf("history is disabled, set enabled = true in the [history] section")
+	}
+
+	store := history.NewFileStore(cfg.History.Path, cfg.History.Retention.Duration(), cfg.History.MaxEntries)
+	if since > 0 {
+		query.Since = time.Now().Add(-since)
+	}
+	entries, err := store.Query(query)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "COMPLETED\tNAME\tSIZE\tIMPORTED BY\tDOWNLOAD\tIMPORT\tHASH")
+	for _, e := range entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			e.CompletedAt.Local().Format("2006-01-02 15:04"),
+			e.Name,
+			formatBytes(e.Size),
+			e.ImportedBy,
+			e.DownloadDuration().Round(time.Second),
+			e.ImportDuration().Round(time.Second),
+			e.Hash,
+		)
+	}
+	return w.Flush()
+}
+
+func runPrune(days int, apply bool) error {
+	if days < 0 {
+		return fmt.Errorf("--older-than must not be negative")
+	}
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	client := putio.NewClient(cfg.Putio.APIKey, putio.WithBaseURLs(cfg.Putio.APIURL, cfg.Putio.UploadURL))
+	plan, err := prune.NewPlan(client, prune.Options{
+		OlderThan: time.Duration(days) * 24 * time.Hour,
+		FolderID:  cfg.Putio.ParentFolderID,
+	})
+	if err != nil {
+		return err
+	}
+	if cfg.Putio.ParentFolderID == 0 {
+		fmt.Println("Skipping orphaned files: set putio.parent_folder_id to prune the managed folder.")
+	}
+	if plan.Empty() {
+		fmt.Printf("Nothing older than %d days to prune.\n", days)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tNAME\tSTATUS\tSIZE")
+	for _, t := range plan.Transfers {
+		var name string
+		var size int64
+		if t.Name != nil {
+			name = *t.Name
+		}
+		if t.Size != nil {
+			size = *t.Size
+		}
+		fmt.Fprintf(w, "transfer\t%s\t%s\t%s\n", name, t.Status, formatBytes(size))
+	}
+	for _, f := range plan.Files {
+		fmt.Fprintf(w, "file\t%s\t%s\t%s\n", f.Name, "orphaned", formatBytes(f.Size))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if !apply {
+		fmt.Println("\nDry run: rerun with --apply to remove these.")
+		return nil
+	}
+	if err := plan.Apply(client); err != nil {
+		return err
+	}
+	fmt.Printf("\nRemoved %d transfers and %d files.\n", len(plan.Transfers), len(plan.Files))
+	return nil
+}
+
+func runBenchmark(fileID int64, duration time.Duration, workers []int) error {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	// Start download manager
-	downloadManager := download.NewManager(container)
-	if err := downloadManager.StartWithContext(ctx); err != nil {
-		return fmt.Errorf("failed to start download manager: %w", err)
+	client := putio.NewClient(cfg.Putio.APIKey, putio.WithBaseURLs(cfg.Putio.APIURL, cfg.Putio.UploadURL))
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "WORKERS\tTHROUGHPUT")
+	report, err := benchmark.Run(ctx, client, benchmark.Options{
+		FileID:   fileID,
+		FolderID: cfg.Putio.ParentFolderID,
+		Workers:  workers,
+		Duration: duration,
+	}, func(r benchmark.Result) {
+		fmt.Fprintf(w, "%d\t%s/s\n", r.Workers, formatBytes(int64(r.Throughput())))
+		_ = w.Flush()
+	})
+	if err != nil {
+		return err
 	}
-	defer downloadManager.Stop()
 
-	// Start HTTP server
-	server := httpserver.NewServer(container)
-	return server.StartWithContext(ctx)
+	recommended := report.Recommend()
+	if recommended == 0 {
+		return fmt.Errorf("nothing was downloaded from %s", report.File.Name)
+	}
+	fmt.Printf("\nMeasured with %s (%s), currently download_workers = %d.\n", report.File.Name, formatBytes(report.File.Size), cfg.DownloadWorkers)
+	fmt.Printf("Recommended: download_workers = %d\n", recommended)
+	return nil
+}
+
+func exportState(output string) error {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	snapshot, err := state.Export(cfg, time.Now())
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if output == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(output, data, 0600); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d state files and %d history entries to %s\n", len(snapshot.Files), len(snapshot.History), output)
+	return nil
+}
+
+func importState(path string, force bool) error {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	var snapshot state.Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return fmt.Errorf("failed to parse snapshot %s: %w", path, err)
+	}
+
+	if existing := state.Existing(cfg); len(existing) > 0 && !force {
+		return hint.Errorf("stop the proxy and rerun with --force to replace it", "state already exists in %s: %s", cfg.StateDirectory, strings.Join(existing, ", "))
+	}
+	if err := state.Import(cfg, &snapshot); err != nil {
+		return err
+	}
+	fmt.Printf("Imported %d state files and %d history entries exported at %s\n", len(snapshot.Files), len(snapshot.History), snapshot.ExportedAt.Local().Format(time.RFC3339))
+	return nil
+}
+
+// formatBytes renders n bytes using binary units
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// buildInfo returns the commit and build date, from ldflags or else from the
+// VCS details in the binary.
+func buildInfo() (string, string) {
+	rev, date := commit, buildDate
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && rev == "":
+				rev = setting.Value
+			case setting.Key == "vcs.time" && date == "":
+				date = setting.Value
+			}
+		}
+	}
+	if rev == "" {
+		rev = "unknown"
+	}
+	if date == "" {
+		date = "unknown"
+	}
+	return rev, date
+}
+
+func printVersion() {
+	rev, date := buildInfo()
+	fmt.Printf("goputioarr version %s\n", version)
+	fmt.Printf("  commit:     %s\n", rev)
+	fmt.Printf("  built:      %s\n", date)
+	fmt.Printf("  go version: %s\n", runtime.Version())
+	fmt.Printf("  platform:   %s/%s\n", runtime.GOOS, runtime.GOARCH)
+}
+
+func printUpdateCheck() error {
+	latest, err := release.NewClient().Latest(context.Background())
+	if err != nil {
+		return err
+	}
+	if release.IsNewer(latest.Version, version) {
+		fmt.Printf("A newer version is available: %s (%s). Run self-update to install it.\n", latest.Version, latest.URL)
+	} else {
+		fmt.Printf("Up to date (latest release: %s)\n", latest.Version)
+	}
+	return nil
+}
+
+// logUpdateCheck logs a notice if a newer release than the running one is
+// available. Failures are only logged at debug level; the check is a courtesy.
+func logUpdateCheck(ctx context.Context, logger *logrus.Logger) {
+	latest, err := release.NewClient().Latest(ctx)
+	if err != nil {
+		logger.Debugf("Update check failed: %v", err)
+		return
+	}
+	if release.IsNewer(latest.Version, version) {
+		logger.Infof("goputioarr %s is available (running %s): %s", latest.Version, version, latest.URL)
+	}
 }
 
 func performSelfUpdate() error {
@@ -190,39 +669,26 @@ func performSelfUpdate() error {
 }
 
 func fetchLatestReleaseAssetURL() (string, string, error) {
-	const apiURL = "https://api.github.com/repos/ochronus/goputioarr/releases/latest"
-
-	resp, err := http.Get(apiURL)
+	latest, err := release.NewClient().Latest(context.Background())
 	if err != nil {
-		return "", "", fmt.Errorf("failed to query latest release: %w", err)
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return "", "", fmt.Errorf("unexpected status from GitHub: %s", resp.Status)
-	}
-
-	var data struct {
-		TagName string `json:"tag_name"`
-		Assets  []struct {
-			Name               string `json:"name"`
-			BrowserDownloadURL string `json:"browser_download_url"`
-		} `json:"assets"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return "", "", fmt.Errorf("failed to decode GitHub response: %w", err)
+		return "", "", err
 	}
 
-	latest := strings.TrimPrefix(data.TagName, "v")
 	targetName := fmt.Sprintf("goputioarr-%s-%s", runtime.GOOS, runtime.GOARCH)
 	if runtime.GOOS == "windows" {
 		targetName += ".exe"
 	}
 
-	for _, asset := range data.Assets {
-		if asset.Name == targetName {
-			return latest, asset.BrowserDownloadURL, nil
-		}
+	if url, ok := latest.Assets[targetName]; ok {
+		return latest.Version, url, nil
 	}
 
 	return "", "", fmt.Errorf("no matching asset for %s/%s", runtime.GOOS, runtime.GOARCH)
 }
+
+// isTerminal reports whether f is an interactive terminal rather than a pipe
+// or a file.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
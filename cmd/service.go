@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/kardianos/service"
+)
+
+// serviceName is the name goputioarr is registered under with the system's
+// service manager
+const serviceName = "goputioarr"
+
+// proxyProgram adapts the proxy to the service manager's start and stop calls
+type proxyProgram struct {
+	run    func(context.Context) error
+	cancel context.CancelFunc
+	done   chan error
+	logger service.Logger
+}
+
+// Start runs the proxy in the background, as the service manager expects
+// Start to return right away. If the proxy stops on its own, the process
+// exits so the service manager sees the service stopped.
+func (p *proxyProgram) Start(s service.Service) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	p.done = make(chan error, 1)
+	go func() {
+		err := p.run(ctx)
+		if ctx.Err() != nil {
+			p.done <- err
+			return
+		}
+		if err != nil {
+			if p.logger != nil {
+				_ = p.logger.Error(err)
+			}
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}()
+	return nil
+}
+
+// Stop stops the proxy and waits for it to shut down
+func (p *proxyProgram) Stop(s service.Service) error {
+	p.cancel()
+	return <-p.done
+}
+
+// newService describes the proxy to the service manager, running it with
+// configPath. configPath is only needed to install the service.
+func newService(program *proxyProgram, configPath string) (service.Service, error) {
+	cfg := &service.Config{
+		Name:        serviceName,
+		DisplayName: serviceName,
+		Description: "put.io to sonarr/radarr/whisparr proxy",
+		Option:      service.KeyValue{"RunAtLoad": true},
+	}
+	if configPath != "" {
+		cfg.Arguments = []string{"run", "-c", configPath}
+	}
+	if runtime.GOOS == "darwin" {
+		// A per-user launch agent, logging to ~/Library/Logs.
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		cfg.Option["UserService"] = true
+		cfg.Option["LogOutput"] = true
+		cfg.Option["LogDirectory"] = filepath.Join(home, "Library", "Logs")
+	}
+	return service.New(program, cfg)
+}
+
+// runAsService runs the proxy under the service manager if it started the
+// process, reporting handled as false otherwise
+func runAsService(run func(context.Context) error) (handled bool, err error) {
+	if service.Interactive() {
+		return false, nil
+	}
+	program := &proxyProgram{run: run}
+	s, err := newService(program, "")
+	if err != nil {
+		return false, nil
+	}
+	program.logger, _ = s.Logger(nil)
+	return true, s.Run()
+}
+
+// controlService installs, uninstalls, starts or stops the service
+func controlService(action, configPath string) error {
+	s, err := newService(&proxyProgram{}, configPath)
+	if err != nil {
+		return fmt.Errorf("service management isn't supported on %s: %w", runtime.GOOS, err)
+	}
+	if err := service.Control(s, action); err != nil {
+		return fmt.Errorf("failed to %s service %s: %w", action, serviceName, err)
+	}
+	return nil
+}
@@ -0,0 +1,6 @@
+//go:build !unix
+
+package main
+
+// setUmask does nothing on platforms without a umask
+func setUmask(mask int) {}
@@ -0,0 +1,11 @@
+//go:build unix
+
+package main
+
+import "syscall"
+
+// setUmask sets the mask applied to the permissions of files and directories
+// the proxy creates
+func setUmask(mask int) {
+	syscall.Umask(mask)
+}
@@ -2,10 +2,20 @@ package app
 
 import (
 	"fmt"
+	"text/template"
 
+	"github.com/ochronus/goputioarr/internal/autofetch"
 	"github.com/ochronus/goputioarr/internal/config"
+	"github.com/ochronus/goputioarr/internal/destination"
+	"github.com/ochronus/goputioarr/internal/history"
 	"github.com/ochronus/goputioarr/internal/services/arr"
+	"github.com/ochronus/goputioarr/internal/services/heartbeat"
+	"github.com/ochronus/goputioarr/internal/services/httpdump"
+	"github.com/ochronus/goputioarr/internal/services/mqtt"
+	"github.com/ochronus/goputioarr/internal/services/notify"
 	"github.com/ochronus/goputioarr/internal/services/putio"
+	"github.com/ochronus/goputioarr/internal/services/rclone"
+	"github.com/ochronus/goputioarr/internal/services/unpack"
 	"github.com/sirupsen/logrus"
 )
 
@@ -16,14 +26,35 @@ type Container struct {
 	Config        *config.Config
 	Logger        *logrus.Logger
 	PutioClient   putio.ClientAPI
+	PutioCalls    *putio.CallCounter
 	ArrClients    []ArrServiceClient
+	Imports       *ImportTracker
+	Transfers     *TransferStore
+	Downloads     *DownloadCounters
+	Pipeline      *PipelineStats
+	Holds         *HashSet
+	Pause         *PauseSwitch
+	Stalls        *StallTracker
+	Locations     *LocationRegistry
+	Ownership     *HashSet
+	Labels        *LabelRegistry
+	Stages        *StageJournal
+	Notifier      notify.Notifier
+	Heartbeat     *heartbeat.Pinger
+	MQTT          *MQTTPublisher
+	RSS           *autofetch.Fetcher
+	Unpacker      *unpack.Unpacker
+	Destination   destination.Backend
+	History       history.Store
 	ValidatePutio bool
 }
 
 // ArrServiceClient couples a service name with its Arr client interface.
+// PathMappings translates local download paths to the service's view of them.
 type ArrServiceClient struct {
-	Name   string
-	Client arr.ClientAPI
+	Name         string
+	Client       arr.ClientAPI
+	PathMappings map[string]string
 }
 
 // Option allows customizing the container during construction.
@@ -59,6 +90,39 @@ func WithPutioValidation(validate bool) Option {
 	}
 }
 
+// WithNotifier overrides the default notifier.
+func WithNotifier(notifier notify.Notifier) Option {
+	return func(c *Container) error {
+		if notifier == nil {
+			return fmt.Errorf("notifier cannot be nil")
+		}
+		c.Notifier = notifier
+		return nil
+	}
+}
+
+// WithHistory overrides the default history store.
+func WithHistory(store history.Store) Option {
+	return func(c *Container) error {
+		if store == nil {
+			return fmt.Errorf("history store cannot be nil")
+		}
+		c.History = store
+		return nil
+	}
+}
+
+// WithDestination overrides where completed downloads are stored.
+func WithDestination(backend destination.Backend) Option {
+	return func(c *Container) error {
+		if backend == nil {
+			return fmt.Errorf("destination cannot be nil")
+		}
+		c.Destination = backend
+		return nil
+	}
+}
+
 // WithArrClients overrides the default Arr clients.
 func WithArrClients(clients []ArrServiceClient) Option {
 	return func(c *Container) error {
@@ -77,6 +141,13 @@ func NewContainer(cfg *config.Config, opts ...Option) (*Container, error) {
 	container := &Container{
 		Config:        cfg,
 		Logger:        buildDefaultLogger(cfg.Loglevel),
+		Imports:       NewImportTracker(),
+		Transfers:     NewTransferStore(),
+		Downloads:     NewDownloadCounters(),
+		Pipeline:      NewPipelineStats(),
+		Pause:         NewPauseSwitch(),
+		Stalls:        NewStallTracker(),
+		PutioCalls:    putio.NewCallCounter(),
 		ValidatePutio: true,
 	}
 
@@ -88,11 +159,104 @@ func NewContainer(cfg *config.Config, opts ...Option) (*Container, error) {
 	}
 
 	if container.PutioClient == nil {
-		container.PutioClient = putio.NewClient(cfg.Putio.APIKey)
+		container.PutioClient = putio.NewClient(cfg.Putio.APIKey,
+			putio.WithBaseURLs(cfg.Putio.APIURL, cfg.Putio.UploadURL),
+			putio.WithFilesPerPage(cfg.Putio.FilesPerPage),
+			putio.WithTransfersPerPage(cfg.Putio.TransfersPerPage),
+			putio.WithSaveParentID(cfg.Putio.ParentFolderID),
+			putio.WithCallCounter(container.PutioCalls),
+			putio.WithTimeouts(cfg.Putio.Timeout.Duration(), cfg.Putio.UploadTimeout.Duration()),
+			putio.WithTransport(httpdump.NewTransport(nil, container.Logger, "put.io")),
+		)
 	}
 
 	if container.ArrClients == nil {
-		container.ArrClients = buildArrClients(cfg)
+		container.ArrClients = buildArrClients(cfg, container.Logger)
+	}
+
+	if container.MQTT == nil && cfg.MQTT != nil {
+		publisher, err := buildMQTTPublisher(cfg.MQTT, container.Logger)
+		if err != nil {
+			return nil, err
+		}
+		container.MQTT = publisher
+	}
+	container.MQTT.Watch(container.Transfers)
+
+	if container.Notifier == nil {
+		notifier, err := buildNotifier(cfg)
+		if err != nil {
+			return nil, err
+		}
+		if container.MQTT != nil {
+			notifier = append(notifier, container.MQTT)
+		}
+		container.Notifier = notifier
+	}
+
+	if container.Heartbeat == nil && cfg.HeartbeatURL != "" {
+		container.Heartbeat = heartbeat.New(cfg.HeartbeatURL)
+	}
+
+	if container.Ownership == nil {
+		ownership, err := NewOwnershipRegistry(cfg.OwnedTransfersPath())
+		if err != nil {
+			return nil, err
+		}
+		container.Ownership = ownership
+	}
+
+	if container.Holds == nil {
+		holds, err := NewHoldRegistry(cfg.HeldTransfersPath())
+		if err != nil {
+			return nil, err
+		}
+		container.Holds = holds
+	}
+
+	if container.Labels == nil {
+		labels, err := NewLabelRegistry(cfg.TransferLabelsPath())
+		if err != nil {
+			return nil, err
+		}
+		container.Labels = labels
+	}
+
+	if container.Locations == nil {
+		locations, err := NewLocationRegistry(cfg.TransferLocationsPath())
+		if err != nil {
+			return nil, err
+		}
+		container.Locations = locations
+	}
+
+	if container.Stages == nil {
+		stages, err := NewStageJournal(cfg.TransferStagesPath())
+		if err != nil {
+			return nil, err
+		}
+		container.Stages = stages
+	}
+	container.Stages.Watch(container.Transfers, container.Logger)
+
+	if container.RSS == nil && cfg.RSS != nil {
+		fetcher, err := autofetch.NewFetcher(cfg.RSS, container.PutioClient, cfg.RSSSeenPath(), container.Logger)
+		if err != nil {
+			return nil, err
+		}
+		container.RSS = fetcher
+	}
+
+	if container.Unpacker == nil && cfg.Unpack.Enabled {
+		container.Unpacker = unpack.New()
+	}
+
+	if container.Destination == nil {
+		container.Destination = buildDestination(cfg)
+	}
+
+	if container.History == nil && cfg.History.Enabled {
+		container.History = history.NewFileStore(cfg.History.Path, cfg.History.Retention.Duration(), cfg.History.MaxEntries)
 	}
 
 	if container.ValidatePutio {
@@ -120,14 +284,142 @@ func buildDefaultLogger(levelStr string) *logrus.Logger {
 	return logger
 }
 
-func buildArrClients(cfg *config.Config) []ArrServiceClient {
+func buildDestination(cfg *config.Config) destination.Backend {
+	if cfg.Rclone == nil {
+		return destination.Local{}
+	}
+	client := rclone.NewClient(cfg.Rclone.URL, rclone.WithCredentials(cfg.Rclone.Username, cfg.Rclone.Password))
+	return destination.NewRclone(client, cfg.DownloadDirectory, cfg.Rclone.Remote)
+}
+
+func buildArrClients(cfg *config.Config, logger *logrus.Logger) []ArrServiceClient {
 	arrConfigs := cfg.GetArrConfigs()
 	arrClients := make([]ArrServiceClient, 0, len(arrConfigs))
 	for _, svc := range arrConfigs {
+		opts := []arr.ClientOption{arr.WithTransport(httpdump.NewTransport(nil, logger, svc.Name))}
+		if arrCfg := cfg.ArrConfigByName(svc.Name); arrCfg != nil {
+			opts = append(opts,
+				arr.WithImportedPathMatching(arrCfg.MatchImportedPath),
+				arr.WithCaseInsensitivePaths(arrCfg.CaseInsensitivePaths),
+				arr.WithAPIVersion(arrCfg.APIVersion),
+				arr.WithHistoryPath(arrCfg.HistoryPath),
+				arr.WithTimeout(arrCfg.Timeout.Duration()),
+				arr.WithMaxRetries(arrCfg.MaxRetries),
+				arr.WithBackoff(arrCfg.Backoff.Duration()),
+			)
+		}
 		arrClients = append(arrClients, ArrServiceClient{
-			Name:   svc.Name,
-			Client: arr.NewClient(svc.URL, svc.APIKey),
+			Name:         svc.Name,
+			Client:       arr.NewBreaker(arr.NewClient(svc.URL, svc.APIKey, opts...), arr.WithStateChange(breakerLogger(svc.Name, logger))),
+			PathMappings: cfg.ArrPathMappings(svc.Name),
 		})
 	}
 	return arrClients
 }
+
+// breakerLogger logs when requests to the named arr service are paused
+// because it keeps failing, and when it recovers.
+func breakerLogger(name string, logger *logrus.Logger) func(open bool, err error) {
+	return func(open bool, err error) {
+		if open {
+			logger.Warnf("%s keeps failing, pausing requests to it for %s: %v", name, arr.DefaultCooldown, err)
+			return
+		}
+		logger.Infof("%s is responding again, resuming requests", name)
+	}
+}
+
+func buildMQTTPublisher(cfg *config.MQTTConfig, logger *logrus.Logger) (*MQTTPublisher, error) {
+	prefix := cfg.TopicPrefix
+	if prefix == "" {
+		prefix = "goputioarr"
+	}
+	clientID := cfg.ClientID
+	if clientID == "" {
+		clientID = "goputioarr"
+	}
+	client, err := mqtt.NewClient(cfg.Broker,
+		mqtt.WithClientID(clientID),
+		mqtt.WithCredentials(cfg.Username, cfg.Password),
+		mqtt.WithBirth(mqtt.Message{Topic: prefix + "/status", Payload: []byte("online"), Retain: true}),
+		mqtt.WithWill(mqtt.Message{Topic: prefix + "/status", Payload: []byte("offline"), Retain: true}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("mqtt.broker: %w", err)
+	}
+	return NewMQTTPublisher(client, prefix, logger), nil
+}
+
+func buildNotifier(cfg *config.Config) (notify.Multi, error) {
+	var notifiers notify.Multi
+	if cfg.Notifications.WebhookURL != "" {
+		notifiers = append(notifiers, notify.NewWebhook(cfg.Notifications.WebhookURL))
+	}
+
+	add := func(name string, events []string, text, fallback string, build func(*template.Template) notify.Notifier) error {
+		tmpl, err := notify.ParseTemplate(text, fallback)
+		if err != nil {
+			return fmt.Errorf("notifications.%s.template is invalid: %w", name, err)
+		}
+		notifier, err := notify.Filter(build(tmpl), events)
+		if err != nil {
+			return fmt.Errorf("notifications.%s.events is invalid: %w", name, err)
+		}
+		notifiers = append(notifiers, notifier)
+		return nil
+	}
+	if c := cfg.Notifications.Discord; c != nil {
+		err := add("discord", c.Events, c.Template, notify.DiscordTemplate, func(tmpl *template.Template) notify.Notifier {
+			return notify.NewDiscord(c.WebhookURL, tmpl)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if c := cfg.Notifications.Telegram; c != nil {
+		err := add("telegram", c.Events, c.Template, notify.TelegramTemplate, func(tmpl *template.Template) notify.Notifier {
+			return notify.NewTelegram(c.BotToken, c.ChatID, tmpl)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if c := cfg.Notifications.Pushover; c != nil {
+		err := add("pushover", c.Events, c.Template, notify.PushoverTemplate, func(tmpl *template.Template) notify.Notifier {
+			return notify.NewPushover(c.Token, c.UserKey, tmpl)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if c := cfg.Notifications.Apprise; c != nil {
+		err := add("apprise", c.Events, c.Template, notify.AppriseTemplate, func(tmpl *template.Template) notify.Notifier {
+			if c.APIURL != "" {
+				return notify.NewAppriseAPI(c.APIURL, c.Key, c.URLs, tmpl)
+			}
+			return notify.NewAppriseCLI(c.Binary, c.URLs, tmpl)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if c := cfg.Notifications.Email; c != nil {
+		events := c.Events
+		if len(events) == 0 {
+			for _, t := range notify.FailureEvents {
+				events = append(events, string(t))
+			}
+		}
+		port := c.Port
+		if port == 0 {
+			port = 587
+		}
+		err := add("email", events, c.Template, notify.EmailTemplate, func(tmpl *template.Template) notify.Notifier {
+			return notify.NewEmail(c.Host, port, c.Username, c.Password, c.From, c.To, tmpl)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return notifiers, nil
+}
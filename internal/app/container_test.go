@@ -1,9 +1,15 @@
 package app
 
 import (
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/ochronus/goputioarr/internal/config"
+	"github.com/ochronus/goputioarr/internal/destination"
+	"github.com/ochronus/goputioarr/internal/putiomock"
+	"github.com/ochronus/goputioarr/internal/services/arr"
+	"github.com/ochronus/goputioarr/internal/services/notify"
 	"github.com/ochronus/goputioarr/internal/services/putio"
 )
 
@@ -18,13 +24,19 @@ func (m *mockPutioClient) GetAccountInfo() (*putio.AccountInfoResponse, error) {
 func (m *mockPutioClient) ListTransfers() (*putio.ListTransferResponse, error) {
 	return &putio.ListTransferResponse{Transfers: []putio.Transfer{}}, nil
 }
+func (m *mockPutioClient) ListTransfersSince(uint64) (*putio.ListTransferResponse, error) {
+	return m.ListTransfers()
+}
 func (m *mockPutioClient) GetTransfer(id uint64) (*putio.GetTransferResponse, error) {
 	return &putio.GetTransferResponse{Transfer: putio.Transfer{ID: id, Status: "SEEDING"}}, nil
 }
-func (m *mockPutioClient) RemoveTransfer(uint64) error { return nil }
-func (m *mockPutioClient) DeleteFile(int64) error      { return nil }
-func (m *mockPutioClient) AddTransfer(string) error    { return nil }
-func (m *mockPutioClient) UploadFile([]byte) error     { return nil }
+func (m *mockPutioClient) RemoveTransfer(uint64) error                 { return nil }
+func (m *mockPutioClient) DeleteFile(int64) error                      { return nil }
+func (m *mockPutioClient) RemoveTransfers([]uint64) error              { return nil }
+func (m *mockPutioClient) RetryTransfer(uint64) error                  { return nil }
+func (m *mockPutioClient) DeleteFiles([]int64) error                   { return nil }
+func (m *mockPutioClient) AddTransfer(string) (*putio.Transfer, error) { return nil, nil }
+func (m *mockPutioClient) UploadFile([]byte) (*putio.Transfer, error)  { return nil, nil }
 func (m *mockPutioClient) ListFiles(fileID int64) (*putio.ListFileResponse, error) {
 	return &putio.ListFileResponse{
 		Parent: putio.FileResponse{ID: fileID, Name: "parent", FileType: "FOLDER"},
@@ -34,7 +46,9 @@ func (m *mockPutioClient) ListFiles(fileID int64) (*putio.ListFileResponse, erro
 func (m *mockPutioClient) GetFileURL(int64) (string, error) { return "http://example.com", nil }
 
 type mockArrClient struct {
-	calls int
+	calls     int
+	status    *arr.SystemStatus
+	statusErr error
 }
 
 func (m *mockArrClient) CheckImported(string) (bool, error) {
@@ -42,6 +56,12 @@ func (m *mockArrClient) CheckImported(string) (bool, error) {
 	return false, nil
 }
 
+func (m *mockArrClient) FindImports([]string) (map[string]arr.Import, error) { return nil, nil }
+
+func (m *mockArrClient) Blocklist(string) (bool, error) { return false, nil }
+
+func (m *mockArrClient) SystemStatus() (*arr.SystemStatus, error) { return m.status, m.statusErr }
+
 func baseConfig() *config.Config {
 	return &config.Config{
 		DownloadDirectory: "/downloads",
@@ -76,6 +96,110 @@ func TestNewContainerDefaults(t *testing.T) {
 	if container.ArrClients[0].Name != "Sonarr" {
 		t.Errorf("expected Arr client name 'Sonarr', got %q", container.ArrClients[0].Name)
 	}
+	if _, ok := container.ArrClients[0].Client.(*arr.Breaker); !ok {
+		t.Errorf("expected Arr client to be wrapped in a circuit breaker, got %T", container.ArrClients[0].Client)
+	}
+}
+
+func TestNewContainerUnpacker(t *testing.T) {
+	cfg := baseConfig()
+	container, err := NewContainer(cfg, WithPutioClient(&mockPutioClient{}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if container.Unpacker != nil {
+		t.Error("expected no unpacker when unpacking is disabled")
+	}
+
+	cfg.Unpack.Enabled = true
+	container, err = NewContainer(cfg, WithPutioClient(&mockPutioClient{}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if container.Unpacker == nil {
+		t.Error("expected unpacker when unpacking is enabled")
+	}
+}
+
+func TestNewContainerDestination(t *testing.T) {
+	cfg := baseConfig()
+	container, err := NewContainer(cfg, WithPutioClient(&mockPutioClient{}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := container.Destination.(destination.Local); !ok {
+		t.Errorf("expected the local destination by default, got %T", container.Destination)
+	}
+
+	cfg.Rclone = &config.RcloneConfig{URL: "http://localhost:5572", Remote: "gdrive:downloads"}
+	container, err = NewContainer(cfg, WithPutioClient(&mockPutioClient{}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if container.Destination.Name() != "gdrive:downloads" {
+		t.Errorf("expected the rclone destination, got %T", container.Destination)
+	}
+
+	if _, err := NewContainer(cfg, WithDestination(nil)); err == nil {
+		t.Error("expected an error for a nil destination")
+	}
+}
+
+func TestNewContainerNotifiers(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(*config.NotificationsConfig)
+		expected  int
+		errMsg    string
+	}{
+		{name: "none", configure: func(*config.NotificationsConfig) {}, expected: 0},
+		{
+			name: "all backends",
+			configure: func(n *config.NotificationsConfig) {
+				n.WebhookURL = "http://hook"
+				n.Discord = &config.DiscordConfig{WebhookURL: "http://discord"}
+				n.Telegram = &config.TelegramConfig{BotToken: "token", ChatID: "1", Events: []string{"import_timeout"}}
+				n.Pushover = &config.PushoverConfig{Token: "app", UserKey: "user", Template: "{{.Transfer}} {{.Message}}"}
+				n.Apprise = &config.AppriseConfig{URLs: []string{"json://localhost"}}
+				n.Email = &config.EmailConfig{Host: "smtp", From: "a@example.com", To: []string{"b@example.com"}}
+			},
+			expected: 6,
+		},
+		{
+			name: "unknown event",
+			configure: func(n *config.NotificationsConfig) {
+				n.Discord = &config.DiscordConfig{WebhookURL: "http://discord", Events: []string{"imported"}}
+			},
+			errMsg: "notifications.discord.events is invalid",
+		},
+		{
+			name: "bad template",
+			configure: func(n *config.NotificationsConfig) {
+				n.Telegram = &config.TelegramConfig{BotToken: "token", ChatID: "1", Template: "{{.Nope}}"}
+			},
+			errMsg: "notifications.telegram.template is invalid",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := baseConfig()
+			tt.configure(&cfg.Notifications)
+			container, err := NewContainer(cfg, WithPutioClient(&mockPutioClient{}))
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Fatalf("expected error containing %q, got %v", tt.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := len(container.Notifier.(notify.Multi)); got != tt.expected {
+				t.Errorf("expected %d notifiers, got %d", tt.expected, got)
+			}
+		})
+	}
 }
 
 func TestContainerOverrides(t *testing.T) {
@@ -147,3 +271,53 @@ func TestPutioValidationCallsAccountInfo(t *testing.T) {
 		t.Error("expected mock put.io client to be retained")
 	}
 }
+
+func TestNewContainerUsesConfiguredPutioURL(t *testing.T) {
+	server := httptest.NewServer(putiomock.New().Handler())
+	defer server.Close()
+	cfg := baseConfig()
+	cfg.Putio.APIURL = server.URL + "/"
+
+	// Validating the key only succeeds if the mock is asked.
+	if _, err := NewContainer(cfg); err != nil {
+		t.Fatalf("expected put.io requests to go to api_url, got %v", err)
+	}
+}
+
+func TestNewContainerMQTT(t *testing.T) {
+	cfg := baseConfig()
+	cfg.MQTT = &config.MQTTConfig{Broker: "tcp://broker:1883"}
+	container, err := NewContainer(cfg, WithPutioClient(&mockPutioClient{}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if container.MQTT == nil || container.MQTT.prefix != "goputioarr" {
+		t.Fatalf("expected MQTT publisher with default prefix, got %+v", container.MQTT)
+	}
+	notifiers := container.Notifier.(notify.Multi)
+	if len(notifiers) != 1 || notifiers[0] != notify.Notifier(container.MQTT) {
+		t.Errorf("expected the MQTT publisher to receive notifications, got %v", notifiers)
+	}
+
+	cfg.MQTT.Broker = "http://broker"
+	if _, err := NewContainer(cfg, WithPutioClient(&mockPutioClient{})); err == nil || !strings.Contains(err.Error(), "mqtt.broker") {
+		t.Errorf("expected broker error, got %v", err)
+	}
+}
+
+func TestNewContainerRSS(t *testing.T) {
+	cfg := baseConfig()
+	cfg.RSS = &config.RSSConfig{Feeds: []config.RSSFeedConfig{{URL: "https://indexer/rss"}}}
+	container, err := NewContainer(cfg, WithPutioClient(&mockPutioClient{}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if container.RSS == nil {
+		t.Fatal("expected an RSS fetcher")
+	}
+
+	cfg.RSS.Feeds[0].Include = []string{"("}
+	if _, err := NewContainer(cfg, WithPutioClient(&mockPutioClient{})); err == nil || !strings.Contains(err.Error(), "invalid pattern") {
+		t.Errorf("expected pattern error, got %v", err)
+	}
+}
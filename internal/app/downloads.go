@@ -0,0 +1,69 @@
+package app
+
+import "sync/atomic"
+
+// DownloadCounters counts the downloads the download manager had to abort and
+// retry, and its workers that crashed, so they can be reported with the other
+// stats. All methods are safe to call on nil counters.
+type DownloadCounters struct {
+	stalled       atomic.Int64
+	timedOut      atomic.Int64
+	retried       atomic.Int64
+	workerCrashes atomic.Int64
+}
+
+// DownloadCountersSnapshot is a point-in-time copy of DownloadCounters.
+type DownloadCountersSnapshot struct {
+	Stalled  int64 `json:"stalled"`
+	TimedOut int64 `json:"timed_out"`
+	Retried  int64 `json:"retried"`
+	// WorkerCrashes counts download manager workers that panicked and were
+	// restarted.
+	WorkerCrashes int64 `json:"worker_crashes"`
+}
+
+// NewDownloadCounters creates zeroed DownloadCounters.
+func NewDownloadCounters() *DownloadCounters {
+	return &DownloadCounters{}
+}
+
+// Stalled counts a download aborted because no data arrived for too long.
+func (c *DownloadCounters) Stalled() {
+	if c != nil {
+		c.stalled.Add(1)
+	}
+}
+
+// TimedOut counts a download aborted because it took too long overall.
+func (c *DownloadCounters) TimedOut() {
+	if c != nil {
+		c.timedOut.Add(1)
+	}
+}
+
+// Retried counts a download started again after being aborted.
+func (c *DownloadCounters) Retried() {
+	if c != nil {
+		c.retried.Add(1)
+	}
+}
+
+// WorkerCrashed counts a worker that panicked.
+func (c *DownloadCounters) WorkerCrashed() {
+	if c != nil {
+		c.workerCrashes.Add(1)
+	}
+}
+
+// Snapshot returns the current counts.
+func (c *DownloadCounters) Snapshot() DownloadCountersSnapshot {
+	if c == nil {
+		return DownloadCountersSnapshot{}
+	}
+	return DownloadCountersSnapshot{
+		Stalled:       c.stalled.Load(),
+		TimedOut:      c.timedOut.Load(),
+		Retried:       c.retried.Load(),
+		WorkerCrashes: c.workerCrashes.Load(),
+	}
+}
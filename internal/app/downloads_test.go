@@ -0,0 +1,28 @@
+package app
+
+import "testing"
+
+func TestDownloadCounters(t *testing.T) {
+	counters := NewDownloadCounters()
+	counters.Stalled()
+	counters.Stalled()
+	counters.TimedOut()
+	counters.Retried()
+	counters.WorkerCrashed()
+
+	expected := DownloadCountersSnapshot{Stalled: 2, TimedOut: 1, Retried: 1, WorkerCrashes: 1}
+	if got := counters.Snapshot(); got != expected {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+}
+
+func TestDownloadCountersNilSafe(t *testing.T) {
+	var counters *DownloadCounters
+	counters.Stalled()
+	counters.TimedOut()
+	counters.Retried()
+	counters.WorkerCrashed()
+	if got := counters.Snapshot(); got != (DownloadCountersSnapshot{}) {
+		t.Errorf("expected zero counts, got %+v", got)
+	}
+}
@@ -0,0 +1,170 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/ochronus/goputioarr/internal/fileutil"
+)
+
+// HashSet is a set of info hashes, kept in a JSON file when it has a path so
+// it survives restarts. It records the transfers the proxy added to put.io
+// (Container.Ownership), so transfers added by other means can be left alone,
+// and the transfers added paused (Container.Holds), which the download manager
+// leaves alone until they are released. All methods are safe to call on a nil
+// set, which is empty.
+type HashSet struct {
+	path string
+	// what names the set's contents in errors.
+	what   string
+	mu     sync.Mutex
+	hashes map[string]bool
+	// fresh is set until a set whose file didn't exist is seeded.
+	fresh bool
+}
+
+// NewOwnershipRegistry loads the hashes of the transfers the proxy owns from
+// path, like NewHashSet.
+func NewOwnershipRegistry(path string) (*HashSet, error) {
+	return NewHashSet(path, "owned transfers")
+}
+
+// NewHoldRegistry loads the hashes of the held transfers from path, like
+// NewHashSet.
+func NewHoldRegistry(path string) (*HashSet, error) {
+	return NewHashSet(path, "held transfers")
+}
+
+// NewHashSet loads a set from path; what names its contents in errors. An
+// empty path keeps the set in memory only; a missing file starts an empty,
+// fresh set.
+func NewHashSet(path, what string) (*HashSet, error) {
+	s := &HashSet{path: path, what: what, hashes: make(map[string]bool)}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		s.fresh = true
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", what, err)
+	}
+
+	var hashes []string
+	if err := json.Unmarshal(data, &hashes); err != nil {
+		return nil, fmt.Errorf("failed to parse %s %s: %w", what, path, err)
+	}
+	for _, hash := range hashes {
+		s.hashes[normalizeHash(hash)] = true
+	}
+	return s, nil
+}
+
+// Add adds hash to the set.
+func (s *HashSet) Add(hash string) error {
+	hash = normalizeHash(hash)
+	if s == nil || hash == "" {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.hashes[hash] {
+		return nil
+	}
+	s.hashes[hash] = true
+	return s.saveLocked()
+}
+
+// Has reports whether hash is in the set.
+func (s *HashSet) Has(hash string) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hashes[normalizeHash(hash)]
+}
+
+// Remove drops hash from the set, e.g. once its transfer is removed or
+// released. It reports whether hash was in the set.
+func (s *HashSet) Remove(hash string) (bool, error) {
+	hash = normalizeHash(hash)
+	if s == nil {
+		return false, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.hashes[hash] {
+		return false, nil
+	}
+	delete(s.hashes, hash)
+	return true, s.saveLocked()
+}
+
+// List returns the hashes in the set, sorted.
+func (s *HashSet) List() []string {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sortedLocked()
+}
+
+// Fresh reports whether the set's file didn't exist yet and the set wasn't
+// seeded since. For the ownership registry, that means this is the first run
+// tracking ownership.
+func (s *HashSet) Fresh() bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fresh
+}
+
+// Seed adds hashes and writes the set, even if hashes is empty, so it's no
+// longer fresh. It returns the number of hashes added.
+func (s *HashSet) Seed(hashes []string) (int, error) {
+	if s == nil {
+		return 0, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	added := 0
+	for _, hash := range hashes {
+		hash = normalizeHash(hash)
+		if hash != "" && !s.hashes[hash] {
+			s.hashes[hash] = true
+			added++
+		}
+	}
+	s.fresh = false
+	return added, s.saveLocked()
+}
+
+func (s *HashSet) sortedLocked() []string {
+	hashes := make([]string, 0, len(s.hashes))
+	for hash := range s.hashes {
+		hashes = append(hashes, hash)
+	}
+	sort.Strings(hashes)
+	return hashes
+}
+
+// saveLocked atomically writes the set to disk. The caller must hold s.mu.
+func (s *HashSet) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+	if err := fileutil.WriteJSONAtomic(s.path, s.sortedLocked()); err != nil {
+		return fmt.Errorf("failed to write %s: %w", s.what, err)
+	}
+	return nil
+}
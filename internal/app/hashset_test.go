@@ -0,0 +1,169 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestHashSetAddAndRemove(t *testing.T) {
+	holds, err := NewHoldRegistry("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	holds.Add("ABCD")
+	holds.Add("1234")
+	holds.Add("")
+	if !holds.Has("abcd") {
+		t.Error("expected hash to be held regardless of case")
+	}
+	if got := holds.List(); !reflect.DeepEqual(got, []string{"1234", "abcd"}) {
+		t.Errorf("unexpected holds %v", got)
+	}
+
+	if released, _ := holds.Remove(" abcd "); !released {
+		t.Error("expected Release to report the hold")
+	}
+	if released, _ := holds.Remove("abcd"); released {
+		t.Error("expected a second Release to report no hold")
+	}
+	if holds.Has("abcd") {
+		t.Error("expected hash to be released")
+	}
+}
+
+func TestHashSetPersistsSorted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "held_transfers.json")
+
+	holds, err := NewHoldRegistry(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := holds.Add("ABCD"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := holds.Add("1234"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := holds.Remove("1234"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	reloaded, err := NewHoldRegistry(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := reloaded.List(); !reflect.DeepEqual(got, []string{"abcd"}) {
+		t.Errorf("expected holds to survive a reload, got %v", got)
+	}
+}
+
+func TestHashSetNilSafe(t *testing.T) {
+	var set *HashSet
+	if err := set.Add("hash"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if removed, err := set.Remove("hash"); removed || err != nil {
+		t.Errorf("expected nothing to remove, got %v, %v", removed, err)
+	}
+	if added, err := set.Seed([]string{"hash"}); added != 0 || err != nil {
+		t.Errorf("expected nothing to be seeded, got %d, %v", added, err)
+	}
+	if set.Has("hash") || set.List() != nil || set.Fresh() {
+		t.Error("expected a nil set to be empty")
+	}
+}
+
+func TestHashSetPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "owned_transfers.json")
+
+	r, err := NewOwnershipRegistry(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := r.Add("ABCDEF"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := r.Add("123456"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := r.Remove("123456"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	reloaded, err := NewOwnershipRegistry(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reloaded.Has("abcdef") {
+		t.Error("expected hash to be owned after reload")
+	}
+	if reloaded.Has("123456") {
+		t.Error("expected forgotten hash not to be owned")
+	}
+}
+
+func TestHashSetSeed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "owned_transfers.json")
+
+	r, err := NewOwnershipRegistry(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !r.Fresh() {
+		t.Fatal("expected a registry without a file to be fresh")
+	}
+	added, err := r.Seed([]string{"ABC", "abc", ""})
+	if err != nil || added != 1 {
+		t.Fatalf("expected 1 hash to be added, got %d (err %v)", added, err)
+	}
+	if r.Fresh() || !r.Has("abc") {
+		t.Error("expected the seeded registry to own the hash and no longer be fresh")
+	}
+
+	reloaded, err := NewOwnershipRegistry(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if reloaded.Fresh() || !reloaded.Has("abc") {
+		t.Error("expected the seeded registry to be saved")
+	}
+
+	empty, _ := NewOwnershipRegistry(filepath.Join(t.TempDir(), "owned_transfers.json"))
+	if _, err := empty.Seed(nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(empty.path); err != nil {
+		t.Errorf("expected an empty seed to write the file: %v", err)
+	}
+}
+
+func TestHashSetInMemory(t *testing.T) {
+	r, err := NewOwnershipRegistry("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r.Add("abc")
+	r.Add("")
+	if !r.Has("ABC") {
+		t.Error("expected hash to be owned")
+	}
+	if r.Has("") {
+		t.Error("expected empty hash not to be owned")
+	}
+	if r.Fresh() {
+		t.Error("expected an in-memory registry not to be fresh")
+	}
+}
+
+func TestHashSetInvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "owned_transfers.json")
+	if err := os.WriteFile(path, []byte("not json"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if _, err := NewOwnershipRegistry(path); err == nil {
+		t.Fatal("expected error for invalid file")
+	}
+}
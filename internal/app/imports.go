@@ -0,0 +1,78 @@
+package app
+
+import (
+	"strings"
+	"sync"
+)
+
+// ImportTracker relays import notifications from the arr services (e.g. via
+// webhook) to the transfers waiting for their import, so the download manager
+// can check the imports without waiting for the next history poll. Only
+// transfers being waited for are tracked. All methods are safe to call on a
+// nil tracker.
+type ImportTracker struct {
+	mu      sync.Mutex
+	signals map[string]chan struct{}
+}
+
+// NewImportTracker creates an empty ImportTracker.
+func NewImportTracker() *ImportTracker {
+	return &ImportTracker{signals: make(map[string]chan struct{})}
+}
+
+// Notify wakes whoever waits for the transfer with the given hash, and
+// reports whether anyone does. Notifications for other hashes are dropped.
+func (t *ImportTracker) Notify(hash string) bool {
+	if t == nil {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	ch, ok := t.signals[normalizeHash(hash)]
+	if !ok {
+		return false
+	}
+	select {
+	case ch <- struct{}{}:
+	default:
+		// a notification is already pending
+	}
+	return true
+}
+
+// Wait starts tracking the transfer with the given hash and returns a channel
+// receiving a value each time an import of it is reported. Call Forget once
+// done waiting. A nil tracker returns a nil channel, which blocks forever in
+// a select.
+func (t *ImportTracker) Wait(hash string) <-chan struct{} {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	hash = normalizeHash(hash)
+	ch, ok := t.signals[hash]
+	if !ok {
+		ch = make(chan struct{}, 1)
+		t.signals[hash] = ch
+	}
+	return ch
+}
+
+// Forget stops tracking the given hash.
+func (t *ImportTracker) Forget(hash string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.signals, normalizeHash(hash))
+}
+
+// normalizeHash lowercases info hashes; arrs report them uppercase while
+// put.io uses lowercase.
+func normalizeHash(hash string) string {
+	return strings.ToLower(strings.TrimSpace(hash))
+}
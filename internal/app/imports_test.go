@@ -0,0 +1,72 @@
+package app
+
+import (
+	"testing"
+)
+
+func TestImportTrackerNotifyAndWait(t *testing.T) {
+	tracker := NewImportTracker()
+
+	signal := tracker.Wait("abcd1234")
+	select {
+	case <-signal:
+		t.Fatal("did not expect signal before Notify")
+	default:
+	}
+
+	// Repeated notifications coalesce into one pending signal.
+	if !tracker.Notify("ABCD1234") || !tracker.Notify("abcd1234") {
+		t.Fatal("expected a waited-for hash to be notified")
+	}
+	select {
+	case <-signal:
+	default:
+		t.Fatal("expected signal after Notify")
+	}
+	select {
+	case <-signal:
+		t.Fatal("expected a single pending signal")
+	default:
+	}
+
+	// Later imports (e.g. the next episode of a pack) signal again.
+	tracker.Notify("abcd1234")
+	select {
+	case <-signal:
+	default:
+		t.Fatal("expected signal after another Notify")
+	}
+}
+
+func TestImportTrackerIgnoresUnknownHashes(t *testing.T) {
+	tracker := NewImportTracker()
+	if tracker.Notify("hash") {
+		t.Error("expected a hash nobody waits for to be ignored")
+	}
+	if len(tracker.signals) != 0 {
+		t.Errorf("expected nothing to be kept for unknown hashes, got %v", tracker.signals)
+	}
+
+	// A later grab of the same hash isn't treated as imported.
+	select {
+	case <-tracker.Wait("hash"):
+		t.Fatal("expected no signal for an earlier notification")
+	default:
+	}
+
+	tracker.Forget("hash")
+	if tracker.Notify("hash") {
+		t.Error("expected a forgotten hash to be ignored")
+	}
+}
+
+func TestImportTrackerNil(t *testing.T) {
+	var tracker *ImportTracker
+	if tracker.Notify("hash") {
+		t.Error("expected nil tracker to ignore notifications")
+	}
+	tracker.Forget("hash")
+	if tracker.Wait("hash") != nil {
+		t.Error("expected nil channel from nil tracker")
+	}
+}
@@ -0,0 +1,115 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/ochronus/goputioarr/internal/config"
+	"github.com/ochronus/goputioarr/internal/fileutil"
+)
+
+// LabelRegistry remembers the Transmission labels transfers were added with,
+// which select their [labels] settings. When backed by a file, the registry
+// survives restarts. All methods are safe to call on a nil registry, which
+// records nothing.
+type LabelRegistry struct {
+	path   string
+	mu     sync.Mutex
+	labels map[string][]string
+}
+
+// NewLabelRegistry loads the registry from path. An empty path keeps the
+// registry in memory only; a missing file starts an empty registry.
+func NewLabelRegistry(path string) (*LabelRegistry, error) {
+	r := &LabelRegistry{path: path, labels: make(map[string][]string)}
+	if path == "" {
+		return r, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transfer labels: %w", err)
+	}
+
+	var labels map[string][]string
+	if err := json.Unmarshal(data, &labels); err != nil {
+		return nil, fmt.Errorf("failed to parse transfer labels %s: %w", path, err)
+	}
+	for hash, l := range labels {
+		r.labels[normalizeHash(hash)] = l
+	}
+	return r, nil
+}
+
+// Set records the labels of the transfer with the given hash. Empty labels
+// forget the transfer's.
+func (r *LabelRegistry) Set(hash string, labels []string) error {
+	hash = normalizeHash(hash)
+	if r == nil || hash == "" {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(labels) == 0 {
+		if _, ok := r.labels[hash]; !ok {
+			return nil
+		}
+		delete(r.labels, hash)
+	} else {
+		r.labels[hash] = append([]string(nil), labels...)
+	}
+	return r.saveLocked()
+}
+
+// Get returns the labels of the transfer with the given hash.
+func (r *LabelRegistry) Get(hash string) []string {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.labels[normalizeHash(hash)]...)
+}
+
+// Forget drops the labels of the transfer with the given hash, e.g. once it's
+// removed.
+func (r *LabelRegistry) Forget(hash string) error {
+	return r.Set(hash, nil)
+}
+
+// saveLocked atomically writes the registry to disk. The caller must hold r.mu.
+func (r *LabelRegistry) saveLocked() error {
+	if r.path == "" {
+		return nil
+	}
+
+	if err := fileutil.WriteJSONAtomic(r.path, r.labels); err != nil {
+		return fmt.Errorf("failed to write transfer labels: %w", err)
+	}
+	return nil
+}
+
+// TransferLabel returns the [labels] settings selected by the labels the
+// transfer with the given hash was added with, and the label's name.
+func (c *Container) TransferLabel(hash string) (string, config.LabelConfig, bool) {
+	return c.Config.LabelFor(c.Labels.Get(hash))
+}
+
+// TransferDirectory returns the local directory the transfer with the given
+// hash is downloaded to, if it isn't download_directory: the one set with
+// torrent-set-location, or else its label's subdirectory.
+func (c *Container) TransferDirectory(hash string) (string, bool) {
+	if dir, ok := c.Locations.Get(hash); ok {
+		return dir, true
+	}
+	if _, label, ok := c.TransferLabel(hash); ok && label.Subdirectory != "" {
+		return filepath.Join(c.Config.DownloadDirectory, label.Subdirectory), true
+	}
+	return "", false
+}
@@ -0,0 +1,60 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLabelRegistryPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "transfer_labels.json")
+
+	r, err := NewLabelRegistry(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := r.Set("ABCDEF", []string{"tv-sonarr"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := r.Set("123456", []string{"radarr"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := r.Forget("123456"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	reloaded, err := NewLabelRegistry(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if labels := reloaded.Get("abcdef"); !reflect.DeepEqual(labels, []string{"tv-sonarr"}) {
+		t.Errorf("expected labels to survive a reload, got %v", labels)
+	}
+	if labels := reloaded.Get("123456"); len(labels) != 0 {
+		t.Errorf("expected forgotten labels to be gone, got %v", labels)
+	}
+}
+
+func TestLabelRegistryInvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transfer_labels.json")
+	if err := os.WriteFile(path, []byte("["), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewLabelRegistry(path); err == nil {
+		t.Error("expected an error for an invalid file")
+	}
+}
+
+func TestLabelRegistryNilSafe(t *testing.T) {
+	var r *LabelRegistry
+	if err := r.Set("abc", []string{"tv"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if labels := r.Get("abc"); labels != nil {
+		t.Errorf("expected nil registry to have no labels, got %v", labels)
+	}
+	if err := r.Forget("abc"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/ochronus/goputioarr/internal/fileutil"
+)
+
+// LocationRegistry records the download directory set for individual
+// transfers with torrent-set-location, e.g. when a download is moved to
+// another category in an arr. The download manager puts the transfer's files
+// there instead of download_directory, and torrent-get reports it. When backed
+// by a file, the registry survives restarts. All methods are safe to call on a
+// nil registry, which records nothing.
+type LocationRegistry struct {
+	path string
+	mu   sync.Mutex
+	dirs map[string]string
+}
+
+// NewLocationRegistry loads the registry from path. An empty path keeps the
+// registry in memory only; a missing file starts an empty registry.
+func NewLocationRegistry(path string) (*LocationRegistry, error) {
+	r := &LocationRegistry{path: path, dirs: make(map[string]string)}
+	if path == "" {
+		return r, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transfer locations: %w", err)
+	}
+
+	var dirs map[string]string
+	if err := json.Unmarshal(data, &dirs); err != nil {
+		return nil, fmt.Errorf("failed to parse transfer locations %s: %w", path, err)
+	}
+	for hash, dir := range dirs {
+		r.dirs[normalizeHash(hash)] = dir
+	}
+	return r, nil
+}
+
+// Set records dir, a local path, as the download directory of the transfer
+// with the given hash.
+func (r *LocationRegistry) Set(hash, dir string) error {
+	hash = normalizeHash(hash)
+	if r == nil || hash == "" {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if current, ok := r.dirs[hash]; ok && current == dir {
+		return nil
+	}
+	r.dirs[hash] = dir
+	return r.saveLocked()
+}
+
+// Get returns the download directory recorded for the transfer with the given
+// hash.
+func (r *LocationRegistry) Get(hash string) (string, bool) {
+	if r == nil {
+		return "", false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	dir, ok := r.dirs[normalizeHash(hash)]
+	return dir, ok
+}
+
+// Forget drops the download directory recorded for the transfer with the
+// given hash.
+func (r *LocationRegistry) Forget(hash string) error {
+	hash = normalizeHash(hash)
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.dirs[hash]; !ok {
+		return nil
+	}
+	delete(r.dirs, hash)
+	return r.saveLocked()
+}
+
+// saveLocked atomically writes the registry to disk. The caller must hold r.mu.
+func (r *LocationRegistry) saveLocked() error {
+	if r.path == "" {
+		return nil
+	}
+
+	if err := fileutil.WriteJSONAtomic(r.path, r.dirs); err != nil {
+		return fmt.Errorf("failed to write transfer locations: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package app
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestLocationRegistry(t *testing.T) {
+	locations, err := NewLocationRegistry("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	locations.Set("ABCD", "/downloads/tv")
+	locations.Set("", "/downloads/movies")
+	if dir, ok := locations.Get("abcd"); !ok || dir != "/downloads/tv" {
+		t.Errorf("expected the location regardless of case, got %q %v", dir, ok)
+	}
+	if _, ok := locations.Get(""); ok {
+		t.Error("expected no location for an empty hash")
+	}
+
+	locations.Set("abcd", "/downloads/anime")
+	if dir, _ := locations.Get("abcd"); dir != "/downloads/anime" {
+		t.Errorf("expected the location to be replaced, got %q", dir)
+	}
+
+	locations.Forget(" ABCD ")
+	if _, ok := locations.Get("abcd"); ok {
+		t.Error("expected the location to be forgotten")
+	}
+}
+
+func TestLocationRegistryPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "transfer_locations.json")
+
+	locations, err := NewLocationRegistry(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := locations.Set("ABCD", "/downloads/tv"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := locations.Set("1234", "/downloads/movies"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := locations.Forget("1234"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	reloaded, err := NewLocationRegistry(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dir, ok := reloaded.Get("abcd"); !ok || dir != "/downloads/tv" {
+		t.Errorf("expected the location to survive a reload, got %q %v", dir, ok)
+	}
+	if _, ok := reloaded.Get("1234"); ok {
+		t.Error("expected the forgotten location to be gone")
+	}
+}
+
+func TestLocationRegistryNil(t *testing.T) {
+	var locations *LocationRegistry
+	locations.Set("hash", "/downloads")
+	locations.Forget("hash")
+	if _, ok := locations.Get("hash"); ok {
+		t.Error("expected a nil registry to record nothing")
+	}
+}
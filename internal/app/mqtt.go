@@ -0,0 +1,173 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ochronus/goputioarr/internal/services/mqtt"
+	"github.com/ochronus/goputioarr/internal/services/notify"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// mqttQueueSize bounds the messages waiting for the broker; more are
+	// dropped rather than holding up the download manager.
+	mqttQueueSize   = 256
+	mqttSendTimeout = 10 * time.Second
+)
+
+// MQTTClient is the subset of the MQTT client used by MQTTPublisher.
+type MQTTClient interface {
+	Publish(ctx context.Context, msg mqtt.Message) error
+	Close() error
+}
+
+var _ MQTTClient = (*mqtt.Client)(nil)
+
+// MQTTPublisher mirrors transfer state to an MQTT broker for home automation:
+//
+//	{prefix}/status             "online" or "offline" (retained, set by the client)
+//	{prefix}/transfers/{hash}   TransferState as JSON (retained, cleared when forgotten)
+//	{prefix}/active             number of transfers downloading or unpacking (retained)
+//	{prefix}/events             notification events as JSON
+//
+// Messages are queued and sent in the background. All methods are safe to call
+// on a nil publisher.
+type MQTTPublisher struct {
+	client MQTTClient
+	prefix string
+	logger *logrus.Logger
+	queue  chan mqtt.Message
+	stop   chan struct{}
+	done   chan struct{}
+
+	mu         sync.Mutex
+	active     map[string]bool
+	lastActive int
+}
+
+var _ notify.Notifier = (*MQTTPublisher)(nil)
+
+// NewMQTTPublisher creates a publisher sending to client under prefix.
+func NewMQTTPublisher(client MQTTClient, prefix string, logger *logrus.Logger) *MQTTPublisher {
+	return &MQTTPublisher{
+		client:     client,
+		prefix:     prefix,
+		logger:     logger,
+		queue:      make(chan mqtt.Message, mqttQueueSize),
+		active:     make(map[string]bool),
+		lastActive: -1,
+	}
+}
+
+// Watch publishes the changes of store.
+func (p *MQTTPublisher) Watch(store *TransferStore) {
+	if p == nil {
+		return
+	}
+	store.OnChange(p.transferChanged)
+}
+
+// Notify publishes event to the events topic.
+func (p *MQTTPublisher) Notify(_ context.Context, event notify.Event) error {
+	if p == nil {
+		return nil
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	p.enqueue(mqtt.Message{Topic: p.prefix + "/events", Payload: payload})
+	return nil
+}
+
+// Start sends queued messages until Stop is called.
+func (p *MQTTPublisher) Start() {
+	if p == nil || p.stop != nil {
+		return
+	}
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+	go p.run()
+}
+
+// Stop sends the messages still queued and disconnects from the broker.
+func (p *MQTTPublisher) Stop() {
+	if p == nil || p.stop == nil {
+		return
+	}
+	close(p.stop)
+	<-p.done
+	if err := p.client.Close(); err != nil {
+		p.logger.Debugf("mqtt: disconnecting: %v", err)
+	}
+}
+
+func (p *MQTTPublisher) run() {
+	defer close(p.done)
+	failing := false
+	send := func(msg mqtt.Message) {
+		ctx, cancel := context.WithTimeout(context.Background(), mqttSendTimeout)
+		defer cancel()
+		err := p.client.Publish(ctx, msg)
+		switch {
+		case err != nil && !failing:
+			// Log once per outage rather than for every message.
+			p.logger.Warnf("mqtt: %v", err)
+			failing = true
+		case err == nil && failing:
+			p.logger.Info("mqtt: publishing again")
+			failing = false
+		}
+	}
+	for {
+		select {
+		case msg := <-p.queue:
+			send(msg)
+		case <-p.stop:
+			for {
+				select {
+				case msg := <-p.queue:
+					send(msg)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (p *MQTTPublisher) transferChanged(state TransferState, forgotten bool) {
+	// Holding the lock keeps the order of messages for a transfer.
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	topic := p.prefix + "/transfers/" + state.Hash
+	if forgotten {
+		// An empty retained message removes the retained state.
+		p.enqueue(mqtt.Message{Topic: topic, Retain: true})
+	} else if payload, err := json.Marshal(state); err == nil {
+		p.enqueue(mqtt.Message{Topic: topic, Payload: payload, Retain: true})
+	}
+
+	if !forgotten && (state.Stage == StageDownloading || state.Stage == StageUnpacking) {
+		p.active[state.Hash] = true
+	} else {
+		delete(p.active, state.Hash)
+	}
+	if len(p.active) != p.lastActive {
+		p.lastActive = len(p.active)
+		p.enqueue(mqtt.Message{Topic: p.prefix + "/active", Payload: []byte(strconv.Itoa(p.lastActive)), Retain: true})
+	}
+}
+
+func (p *MQTTPublisher) enqueue(msg mqtt.Message) {
+	select {
+	case p.queue <- msg:
+	default:
+		p.logger.Debugf("mqtt: queue full, dropping message for %s", msg.Topic)
+	}
+}
@@ -0,0 +1,98 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/ochronus/goputioarr/internal/services/mqtt"
+	"github.com/ochronus/goputioarr/internal/services/notify"
+	"github.com/sirupsen/logrus"
+)
+
+type mockMQTTClient struct {
+	mu       sync.Mutex
+	messages []mqtt.Message
+	closed   bool
+}
+
+func (m *mockMQTTClient) Publish(_ context.Context, msg mqtt.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+func (m *mockMQTTClient) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closed = true
+	return nil
+}
+
+func TestMQTTPublisher(t *testing.T) {
+	client := &mockMQTTClient{}
+	publisher := NewMQTTPublisher(client, "home/putio", logrus.New())
+	store := NewTransferStore()
+	publisher.Watch(store)
+	publisher.Start()
+
+	store.Track("AA", "Show", 10)
+	store.AddProgress("aa", 5)
+	store.SetStage("aa", StageWaitingForImport)
+	store.Forget("aa")
+	publisher.Notify(context.Background(), notify.Event{Type: notify.EventLowDiskSpace, Title: "Low disk space"})
+	publisher.Stop()
+
+	if !client.closed {
+		t.Error("expected Stop to close the client")
+	}
+	want := []struct {
+		topic   string
+		stage   TransferStage
+		payload string
+		retain  bool
+	}{
+		{topic: "home/putio/transfers/aa", stage: StageDownloading, retain: true},
+		{topic: "home/putio/active", payload: "1", retain: true},
+		{topic: "home/putio/transfers/aa", stage: StageWaitingForImport, retain: true},
+		{topic: "home/putio/active", payload: "0", retain: true},
+		{topic: "home/putio/transfers/aa", payload: "", retain: true},
+		{topic: "home/putio/events"},
+	}
+	if len(client.messages) != len(want) {
+		t.Fatalf("expected %d messages, got %+v", len(want), client.messages)
+	}
+	for i, w := range want {
+		msg := client.messages[i]
+		if msg.Topic != w.topic || msg.Retain != w.retain {
+			t.Errorf("message %d: expected %s (retain %v), got %s (retain %v)", i, w.topic, w.retain, msg.Topic, msg.Retain)
+			continue
+		}
+		switch {
+		case w.stage != "":
+			var state TransferState
+			if err := json.Unmarshal(msg.Payload, &state); err != nil || state.Stage != w.stage || state.Hash != "aa" {
+				t.Errorf("message %d: expected %s state, got %s", i, w.stage, msg.Payload)
+			}
+		case w.topic == "home/putio/events":
+			var event notify.Event
+			if err := json.Unmarshal(msg.Payload, &event); err != nil || event.Type != notify.EventLowDiskSpace {
+				t.Errorf("message %d: expected event, got %s", i, msg.Payload)
+			}
+		case string(msg.Payload) != w.payload:
+			t.Errorf("message %d: expected %q, got %q", i, w.payload, msg.Payload)
+		}
+	}
+}
+
+func TestMQTTPublisherNil(t *testing.T) {
+	var publisher *MQTTPublisher
+	publisher.Watch(NewTransferStore())
+	publisher.Start()
+	publisher.Stop()
+	if err := publisher.Notify(context.Background(), notify.Event{}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
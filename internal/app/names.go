@@ -0,0 +1,131 @@
+package app
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// maxNameBytes is the longest file name most filesystems accept.
+	maxNameBytes = 255
+	// maxPathBytes is the longest path Linux accepts.
+	maxPathBytes = 4096
+	// shortNameBytes is what names are shortened to when the whole path
+	// would be too long.
+	shortNameBytes = 64
+	// nameHashLen is the number of hex digits of the name's hash appended
+	// to shortened names, so names sharing a long prefix stay distinct.
+	nameHashLen = 8
+)
+
+// unsafeNameChars are path separators and characters Windows and SMB shares
+// don't allow in file names.
+const unsafeNameChars = `/\<>:"|?*`
+
+// windowsReservedNames are device names Windows refuses as file names, with
+// or without an extension.
+var windowsReservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// SanitizeName makes a put.io file name safe to use as a single path element
+// on local and SMB filesystems. Path separators, control characters, invalid
+// UTF-8 and characters Windows doesn't allow become underscores, surrounding
+// spaces and trailing dots are removed, reserved device names get an
+// underscore prefix and overlong names are shortened, keeping the extension.
+// Names that would refer to the current or parent directory become "_".
+func SanitizeName(name string) string {
+	return truncateName(cleanName(name), maxNameBytes)
+}
+
+// cleanName does everything SanitizeName does except shortening.
+func cleanName(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		switch {
+		case r < 0x20 || r == 0x7f:
+			b.WriteByte('_')
+		case r == utf8.RuneError && !strings.HasPrefix(name[i:], string(utf8.RuneError)):
+			b.WriteByte('_')
+		case strings.ContainsRune(unsafeNameChars, r):
+			b.WriteByte('_')
+		default:
+			b.WriteRune(r)
+		}
+	}
+
+	sanitized := strings.TrimRight(strings.TrimSpace(b.String()), ". ")
+	if sanitized == "" {
+		return "_"
+	}
+
+	base, _, _ := strings.Cut(sanitized, ".")
+	if windowsReservedNames[strings.ToUpper(strings.TrimSpace(base))] {
+		sanitized = "_" + sanitized
+	}
+	return sanitized
+}
+
+// truncateName shortens name to at most limit bytes without splitting a UTF-8
+// sequence, keeping a short extension. Shortened names end in "~" and a hash
+// of the full name, so the result is the same on every run and names that
+// only differ past the cut don't collide.
+func truncateName(name string, limit int) string {
+	if len(name) <= limit {
+		return name
+	}
+	sum := sha1.Sum([]byte(name))
+	suffix := "~" + hex.EncodeToString(sum[:])[:nameHashLen]
+	ext := filepath.Ext(name)
+	if len(ext) > limit/4 {
+		ext = ""
+	}
+	base := strings.TrimSuffix(name, ext)
+	for base != "" && len(base)+len(suffix)+len(ext) > limit {
+		_, size := utf8.DecodeLastRuneInString(base)
+		base = base[:len(base)-size]
+	}
+	return base + suffix + ext
+}
+
+// TargetPath joins the sanitized name to basePath. If the name is too long for
+// the filesystem, or the path would be, the name is shortened and the path it
+// would have had is returned as original; otherwise original is empty.
+func TargetPath(basePath, name string) (to, original string, err error) {
+	clean := cleanName(name)
+	full := filepath.Join(basePath, clean)
+	to = filepath.Join(basePath, truncateName(clean, maxNameBytes))
+	if len(to) > maxPathBytes {
+		to = filepath.Join(basePath, truncateName(clean, shortNameBytes))
+		if len(to) > maxPathBytes {
+			return "", "", fmt.Errorf("%s is too deep for the filesystem", full)
+		}
+	}
+	if to != full {
+		original = full
+	}
+	return to, original, nil
+}
+
+// WithinDirectory reports whether path, once cleaned, is dir itself or lies
+// inside it.
+func WithinDirectory(dir, path string) bool {
+	dir = filepath.Clean(dir)
+	path = filepath.Clean(path)
+	if path == dir {
+		return true
+	}
+	prefix := dir
+	if !strings.HasSuffix(prefix, string(filepath.Separator)) {
+		prefix += string(filepath.Separator)
+	}
+	return strings.HasPrefix(path, prefix)
+}
@@ -0,0 +1,147 @@
+package app
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeName(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "plain", input: "Show.S01E01.1080p.mkv", expected: "Show.S01E01.1080p.mkv"},
+		{name: "unicode", input: "Amélie (2001) 日本語", expected: "Amélie (2001) 日本語"},
+		{name: "parent directory", input: "..", expected: "_"},
+		{name: "current directory", input: ".", expected: "_"},
+		{name: "empty", input: "", expected: "_"},
+		{name: "traversal", input: "../../etc/passwd", expected: ".._.._etc_passwd"},
+		{name: "absolute", input: "/etc/cron.d", expected: "_etc_cron.d"},
+		{name: "windows traversal", input: `..\..\Windows`, expected: ".._.._Windows"},
+		{name: "control characters", input: "bad\x00name\nhere\x7f", expected: "bad_name_here_"},
+		{name: "invalid utf-8", input: "bad\xffname", expected: "bad_name"},
+		{name: "replacement character kept", input: "a�b", expected: "a�b"},
+		{name: "windows characters", input: `Who: What? <1|2> "quoted" *`, expected: "Who_ What_ _1_2_ _quoted_ _"},
+		{name: "trailing dots and spaces", input: " Show... ", expected: "Show"},
+		{name: "reserved device", input: "CON", expected: "_CON"},
+		{name: "reserved device with extension", input: "nul.txt", expected: "_nul.txt"},
+		{name: "reserved prefix is fine", input: "CONSOLE.txt", expected: "CONSOLE.txt"},
+		{name: "com port", input: "com1.mkv", expected: "_com1.mkv"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizeName(tt.input); got != tt.expected {
+				t.Errorf("SanitizeName(%q) = %q, expected %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSanitizeNameStaysInDirectory(t *testing.T) {
+	base := "/downloads"
+	for _, input := range []string{"..", "../..", "../x", "./..", "a/../../b", `..\x`, "\x00/.."} {
+		to := filepath.Join(base, SanitizeName(input))
+		if filepath.Dir(to) != base {
+			t.Errorf("%q escaped the download directory: %s", input, to)
+		}
+	}
+}
+
+func TestSanitizeNameTruncates(t *testing.T) {
+	long := strings.Repeat("é", 200) + ".mkv"
+	got := SanitizeName(long)
+	if len(got) > maxNameBytes {
+		t.Errorf("expected at most %d bytes, got %d", maxNameBytes, len(got))
+	}
+	if !strings.HasSuffix(got, ".mkv") {
+		t.Errorf("expected the extension to be kept, got %q", got)
+	}
+	if !strings.HasPrefix(got, "éé") || strings.ContainsRune(got, '�') {
+		t.Errorf("expected the name to be cut on a rune boundary, got %q", got)
+	}
+}
+
+func TestWithinDirectory(t *testing.T) {
+	tests := []struct {
+		name     string
+		dir      string
+		path     string
+		expected bool
+	}{
+		{name: "directory itself", dir: "/downloads", path: "/downloads", expected: true},
+		{name: "child", dir: "/downloads", path: "/downloads/show/file.mkv", expected: true},
+		{name: "trailing slash on directory", dir: "/downloads/", path: "/downloads/show", expected: true},
+		{name: "root directory", dir: "/", path: "/downloads", expected: true},
+		{name: "parent", dir: "/downloads", path: "/", expected: false},
+		{name: "traversal", dir: "/downloads", path: "/downloads/../etc/passwd", expected: false},
+		{name: "sibling sharing a prefix", dir: "/downloads", path: "/downloads-other/file", expected: false},
+		{name: "traversal back inside", dir: "/downloads", path: "/downloads/a/../b", expected: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WithinDirectory(tt.dir, tt.path); got != tt.expected {
+				t.Errorf("WithinDirectory(%q, %q) = %v, expected %v", tt.dir, tt.path, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSanitizeNameTruncatesDeterministically(t *testing.T) {
+	a := strings.Repeat("x", 300) + "a.mkv"
+	b := strings.Repeat("x", 300) + "b.mkv"
+	if SanitizeName(a) != SanitizeName(a) {
+		t.Error("expected the same name on every call")
+	}
+	if SanitizeName(a) == SanitizeName(b) {
+		t.Errorf("expected names differing past the cut to stay distinct, both got %q", SanitizeName(a))
+	}
+}
+
+func TestTargetPath(t *testing.T) {
+	long := strings.Repeat("n", 300)
+	deep := "/downloads/" + strings.Repeat("d", 4000)
+	tooDeep := "/downloads/" + strings.Repeat("d", 4090)
+
+	tests := []struct {
+		name         string
+		base         string
+		input        string
+		maxLen       int
+		wantOriginal bool
+		wantErr      bool
+	}{
+		{name: "short name", base: "/downloads", input: "movie.mkv", maxLen: maxPathBytes},
+		{name: "sanitized name", base: "/downloads", input: "a/b", maxLen: maxPathBytes},
+		{name: "long name", base: "/downloads", input: long, maxLen: len("/downloads/") + maxNameBytes, wantOriginal: true},
+		{name: "deep path", base: deep, input: long, maxLen: maxPathBytes, wantOriginal: true},
+		{name: "too deep", base: tooDeep, input: long, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			to, original, err := TargetPath(tt.base, tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %s", to)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(to) > tt.maxLen {
+				t.Errorf("expected at most %d bytes, got %d", tt.maxLen, len(to))
+			}
+			if filepath.Dir(to) != tt.base {
+				t.Errorf("expected %s to be in %s", to, tt.base)
+			}
+			if (original != "") != tt.wantOriginal {
+				t.Errorf("unexpected original %q", original)
+			}
+			if original != "" && original != filepath.Join(tt.base, tt.input) {
+				t.Errorf("expected original %s, got %s", filepath.Join(tt.base, tt.input), original)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package app
+
+import (
+	"context"
+	"sync"
+)
+
+// PauseSwitch pauses the download queue as a whole, e.g. before host
+// maintenance. While paused, the download manager starts no new downloads;
+// the ones in progress finish. The switch is kept in memory only. All methods
+// are safe to call on a nil switch, which never pauses.
+type PauseSwitch struct {
+	mu sync.Mutex
+	// resumed is closed when the switch isn't paused and replaced when it's
+	// paused again.
+	resumed chan struct{}
+	paused  bool
+}
+
+// NewPauseSwitch creates a PauseSwitch that isn't paused.
+func NewPauseSwitch() *PauseSwitch {
+	resumed := make(chan struct{})
+	close(resumed)
+	return &PauseSwitch{resumed: resumed}
+}
+
+// Pause stops new downloads from starting. It reports whether the switch
+// wasn't paused already.
+func (s *PauseSwitch) Pause() bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.paused {
+		return false
+	}
+	s.paused = true
+	s.resumed = make(chan struct{})
+	return true
+}
+
+// Resume lets new downloads start again. It reports whether the switch was
+// paused.
+func (s *PauseSwitch) Resume() bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.paused {
+		return false
+	}
+	s.paused = false
+	close(s.resumed)
+	return true
+}
+
+// Paused reports whether new downloads are paused.
+func (s *PauseSwitch) Paused() bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.paused
+}
+
+// Wait blocks while the switch is paused. It returns false if ctx is
+// cancelled first.
+func (s *PauseSwitch) Wait(ctx context.Context) bool {
+	if s == nil {
+		return ctx.Err() == nil
+	}
+	s.mu.Lock()
+	resumed := s.resumed
+	s.mu.Unlock()
+	select {
+	case <-resumed:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
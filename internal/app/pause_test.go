@@ -0,0 +1,57 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPauseSwitchWaitsUntilResumed(t *testing.T) {
+	s := NewPauseSwitch()
+	if s.Paused() || !s.Wait(context.Background()) {
+		t.Fatal("expected a new switch not to be paused")
+	}
+
+	if !s.Pause() || s.Pause() {
+		t.Error("expected only the first Pause to report a change")
+	}
+	done := make(chan bool)
+	go func() { done <- s.Wait(context.Background()) }()
+	select {
+	case <-done:
+		t.Fatal("expected Wait to block while paused")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	if !s.Resume() || s.Resume() {
+		t.Error("expected only the first Resume to report a change")
+	}
+	select {
+	case ok := <-done:
+		if !ok {
+			t.Error("expected Wait to succeed once resumed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected Wait to return once resumed")
+	}
+}
+
+func TestPauseSwitchWaitCancelled(t *testing.T) {
+	s := NewPauseSwitch()
+	s.Pause()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if s.Wait(ctx) {
+		t.Error("expected Wait to fail once cancelled")
+	}
+}
+
+func TestPauseSwitchNilSafe(t *testing.T) {
+	var s *PauseSwitch
+	if s.Pause() || s.Paused() || s.Resume() {
+		t.Error("expected a nil switch never to pause")
+	}
+	if !s.Wait(context.Background()) {
+		t.Error("expected a nil switch not to block")
+	}
+}
@@ -0,0 +1,124 @@
+package app
+
+import (
+	"sync"
+	"time"
+)
+
+// pipelineBuckets is the number of one-minute buckets PipelineStats keeps,
+// enough for the last day.
+const pipelineBuckets = 24 * 60
+
+// PipelineStats aggregates downloaded bytes, completed and failed transfers
+// and the time transfers took from grab to import over the last hour and day,
+// so users can tell how healthy the pipeline has been. All methods are safe to
+// call on nil stats.
+type PipelineStats struct {
+	now func() time.Time
+
+	mu      sync.Mutex
+	buckets [pipelineBuckets]pipelineBucket
+}
+
+// pipelineBucket holds the events of one minute.
+type pipelineBucket struct {
+	minute      time.Time
+	bytes       int64
+	completed   int64
+	failed      int64
+	importTotal time.Duration
+}
+
+// PipelineWindow summarizes the pipeline over a period.
+type PipelineWindow struct {
+	BytesDownloaded int64   `json:"bytes_downloaded"`
+	BytesPerSecond  float64 `json:"bytes_per_second"`
+	Completed       int64   `json:"completed"`
+	Failed          int64   `json:"failed"`
+	// SuccessRate is the share of finished transfers that were imported,
+	// zero if none finished.
+	SuccessRate float64 `json:"success_rate"`
+	// AverageImportTime is the mean time from grab to import, in seconds.
+	AverageImportTime float64 `json:"average_import_seconds"`
+}
+
+// PipelineSnapshot is a point-in-time copy of PipelineStats.
+type PipelineSnapshot struct {
+	LastHour PipelineWindow `json:"last_hour"`
+	LastDay  PipelineWindow `json:"last_day"`
+}
+
+// NewPipelineStats creates empty PipelineStats.
+func NewPipelineStats() *PipelineStats {
+	return &PipelineStats{now: time.Now}
+}
+
+// Downloaded adds n bytes written to disk.
+func (s *PipelineStats) Downloaded(n int64) {
+	s.record(func(b *pipelineBucket) { b.bytes += n })
+}
+
+// Completed records a transfer imported d after it was grabbed.
+func (s *PipelineStats) Completed(d time.Duration) {
+	s.record(func(b *pipelineBucket) {
+		b.completed++
+		b.importTotal += d
+	})
+}
+
+// Failed records a transfer that failed to download or wasn't imported in
+// time.
+func (s *PipelineStats) Failed() {
+	s.record(func(b *pipelineBucket) { b.failed++ })
+}
+
+func (s *PipelineStats) record(update func(*pipelineBucket)) {
+	if s == nil {
+		return
+	}
+	minute := s.now().Truncate(time.Minute)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b := &s.buckets[minute.Unix()/60%pipelineBuckets]
+	if !b.minute.Equal(minute) {
+		*b = pipelineBucket{minute: minute}
+	}
+	update(b)
+}
+
+// Snapshot summarizes the last hour and the last day.
+func (s *PipelineStats) Snapshot() PipelineSnapshot {
+	if s == nil {
+		return PipelineSnapshot{}
+	}
+	now := s.now().Truncate(time.Minute)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return PipelineSnapshot{
+		LastHour: s.windowLocked(now, time.Hour),
+		LastDay:  s.windowLocked(now, 24*time.Hour),
+	}
+}
+
+// windowLocked sums the buckets of the period ending with the current minute.
+func (s *PipelineStats) windowLocked(now time.Time, period time.Duration) PipelineWindow {
+	var w PipelineWindow
+	var importTotal time.Duration
+	for _, b := range s.buckets {
+		if b.minute.IsZero() || now.Sub(b.minute) >= period || b.minute.After(now) {
+			continue
+		}
+		w.BytesDownloaded += b.bytes
+		w.Completed += b.completed
+		w.Failed += b.failed
+		importTotal += b.importTotal
+	}
+	w.BytesPerSecond = float64(w.BytesDownloaded) / period.Seconds()
+	if finished := w.Completed + w.Failed; finished > 0 {
+		w.SuccessRate = float64(w.Completed) / float64(finished)
+	}
+	if w.Completed > 0 {
+		w.AverageImportTime = (importTotal / time.Duration(w.Completed)).Seconds()
+	}
+	return w
+}
@@ -0,0 +1,59 @@
+package app
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPipelineStatsWindows(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 30, 0, time.UTC)
+	stats := NewPipelineStats()
+	stats.now = func() time.Time { return now }
+
+	at := func(ago time.Duration, record func()) {
+		now = now.Add(-ago)
+		record()
+		now = now.Add(ago)
+	}
+	at(3*time.Hour, func() { stats.Downloaded(7200) })
+	at(3*time.Hour, func() { stats.Completed(4 * time.Minute) })
+	at(3*time.Hour, func() { stats.Failed() })
+	at(10*time.Minute, func() { stats.Downloaded(3600) })
+	at(10*time.Minute, func() { stats.Completed(2 * time.Minute) })
+	at(25*time.Hour, func() { stats.Downloaded(1_000_000) })
+
+	snapshot := stats.Snapshot()
+
+	hour := PipelineWindow{BytesDownloaded: 3600, BytesPerSecond: 1, Completed: 1, SuccessRate: 1, AverageImportTime: 120}
+	if snapshot.LastHour != hour {
+		t.Errorf("expected last hour %+v, got %+v", hour, snapshot.LastHour)
+	}
+	day := PipelineWindow{BytesDownloaded: 10800, BytesPerSecond: 10800.0 / 86400, Completed: 2, Failed: 1, SuccessRate: 2.0 / 3, AverageImportTime: 180}
+	if snapshot.LastDay != day {
+		t.Errorf("expected last day %+v, got %+v", day, snapshot.LastDay)
+	}
+}
+
+func TestPipelineStatsReusesExpiredBuckets(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	stats := NewPipelineStats()
+	stats.now = func() time.Time { return now }
+
+	stats.Downloaded(100)
+	now = now.Add(24 * time.Hour)
+	stats.Downloaded(5)
+
+	if got := stats.Snapshot().LastDay.BytesDownloaded; got != 5 {
+		t.Errorf("expected the day-old bucket to be replaced, got %d bytes", got)
+	}
+}
+
+func TestPipelineStatsNilSafe(t *testing.T) {
+	var stats *PipelineStats
+	stats.Downloaded(1)
+	stats.Completed(time.Second)
+	stats.Failed()
+	if got := stats.Snapshot(); got != (PipelineSnapshot{}) {
+		t.Errorf("expected zero stats, got %+v", got)
+	}
+}
@@ -0,0 +1,51 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ochronus/goputioarr/internal/services/arr"
+	"github.com/sirupsen/logrus"
+)
+
+// ProbeArrClients calls each arr service's system status endpoint to verify
+// its API key and that the configured URL points at the expected kind of
+// service. Problems are logged rather than fatal, since an arr may still be
+// starting up; they are also returned so callers can report them.
+func ProbeArrClients(clients []ArrServiceClient, logger *logrus.Logger) []error {
+	var problems []error
+	for _, svc := range clients {
+		status, err := probeArrClient(svc)
+		if err != nil {
+			logger.Error(err)
+			problems = append(problems, err)
+			continue
+		}
+		logger.Infof("%s: connected to %s %s", svc.Name, status.AppName, status.Version)
+	}
+	return problems
+}
+
+func probeArrClient(svc ArrServiceClient) (*arr.SystemStatus, error) {
+	status, err := svc.Client.SystemStatus()
+	if err != nil {
+		var httpErr *arr.HTTPError
+		if errors.As(err, &httpErr) {
+			switch httpErr.StatusCode {
+			case http.StatusUnauthorized, http.StatusForbidden:
+				return nil, fmt.Errorf("%s returned %d — check api_key", svc.Name, httpErr.StatusCode)
+			case http.StatusNotFound:
+				return nil, fmt.Errorf("%s returned 404 — check url and api_version", svc.Name)
+			}
+			return nil, fmt.Errorf("%s returned %d — imports can't be detected until it recovers", svc.Name, httpErr.StatusCode)
+		}
+		return nil, fmt.Errorf("%s is unreachable — check url: %w", svc.Name, err)
+	}
+
+	if status.AppName != "" && !strings.EqualFold(status.AppName, svc.Name) {
+		return nil, fmt.Errorf("%s is configured, but its url points at %s %s — check url", svc.Name, status.AppName, status.Version)
+	}
+	return status, nil
+}
@@ -0,0 +1,65 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/ochronus/goputioarr/internal/services/arr"
+	"github.com/sirupsen/logrus"
+)
+
+func TestProbeArrClients(t *testing.T) {
+	tests := []struct {
+		name    string
+		client  *mockArrClient
+		wantErr string
+	}{
+		{
+			name:   "healthy",
+			client: &mockArrClient{status: &arr.SystemStatus{AppName: "Radarr", Version: "5.2.6"}},
+		},
+		{
+			name:    "bad api key",
+			client:  &mockArrClient{statusErr: &arr.HTTPError{StatusCode: http.StatusUnauthorized, Status: "401 Unauthorized"}},
+			wantErr: "Radarr returned 401 — check api_key",
+		},
+		{
+			name:    "wrong path",
+			client:  &mockArrClient{statusErr: &arr.HTTPError{StatusCode: http.StatusNotFound, Status: "404 Not Found"}},
+			wantErr: "Radarr returned 404 — check url and api_version",
+		},
+		{
+			name:    "server error",
+			client:  &mockArrClient{statusErr: &arr.HTTPError{StatusCode: http.StatusBadGateway, Status: "502 Bad Gateway"}},
+			wantErr: "Radarr returned 502 — imports can't be detected until it recovers",
+		},
+		{
+			name:    "unreachable",
+			client:  &mockArrClient{statusErr: errors.New("connection refused")},
+			wantErr: "Radarr is unreachable — check url: connection refused",
+		},
+		{
+			name:    "wrong service",
+			client:  &mockArrClient{status: &arr.SystemStatus{AppName: "Sonarr", Version: "4.0.1"}},
+			wantErr: "Radarr is configured, but its url points at Sonarr 4.0.1 — check url",
+		},
+	}
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems := ProbeArrClients([]ArrServiceClient{{Name: "Radarr", Client: tt.client}}, logger)
+			if tt.wantErr == "" {
+				if len(problems) != 0 {
+					t.Errorf("expected no problems, got %v", problems)
+				}
+				return
+			}
+			if len(problems) != 1 || problems[0].Error() != tt.wantErr {
+				t.Errorf("expected %q, got %v", tt.wantErr, problems)
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/ochronus/goputioarr/internal/fileutil"
+	"github.com/sirupsen/logrus"
+)
+
+// StageJournal remembers the pipeline stage each transfer last reached, and
+// the targets of the ones whose files changed after the download (e.g. by
+// unpacking), so the download manager can resume transfers from there after a
+// restart. When backed by a file, the journal survives restarts. All methods
+// are safe to call on a nil journal, which records nothing.
+type StageJournal struct {
+	path    string
+	mu      sync.Mutex
+	entries map[string]stageEntry
+}
+
+// stageEntry is what the journal records for a transfer. Without targets it is
+// stored as just the stage.
+type stageEntry struct {
+	Stage   TransferStage   `json:"stage"`
+	Targets json.RawMessage `json:"targets,omitempty"`
+}
+
+func (e stageEntry) MarshalJSON() ([]byte, error) {
+	if e.Targets == nil {
+		return json.Marshal(e.Stage)
+	}
+	type entry stageEntry
+	return json.Marshal(entry(e))
+}
+
+func (e *stageEntry) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		return json.Unmarshal(data, &e.Stage)
+	}
+	type entry stageEntry
+	return json.Unmarshal(data, (*entry)(e))
+}
+
+// NewStageJournal loads the journal from path. An empty path keeps the
+// journal in memory only; a missing file starts an empty journal.
+func NewStageJournal(path string) (*StageJournal, error) {
+	j := &StageJournal{path: path, entries: make(map[string]stageEntry)}
+	if path == "" {
+		return j, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return j, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transfer stages: %w", err)
+	}
+
+	var entries map[string]stageEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse transfer stages %s: %w", path, err)
+	}
+	for hash, entry := range entries {
+		j.entries[normalizeHash(hash)] = entry
+	}
+	return j, nil
+}
+
+// Watch records the stage changes of the transfers in store, logging the
+// ones that can't be saved.
+func (j *StageJournal) Watch(store *TransferStore, logger *logrus.Logger) {
+	if j == nil {
+		return
+	}
+	store.OnChange(func(state TransferState, forgotten bool) {
+		var err error
+		if forgotten {
+			err = j.Forget(state.Hash)
+		} else {
+			err = j.Set(state.Hash, state.Stage)
+		}
+		if err != nil {
+			logger.Warnf("Failed to save the stage of %s: %v", state.Name, err)
+		}
+	})
+}
+
+// Set records that the transfer with the given hash reached stage.
+func (j *StageJournal) Set(hash string, stage TransferStage) error {
+	hash = normalizeHash(hash)
+	if j == nil || hash == "" {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	entry := j.entries[hash]
+	if entry.Stage == stage {
+		return nil
+	}
+	entry.Stage = stage
+	j.entries[hash] = entry
+	return j.saveLocked()
+}
+
+// SetTargets records the targets of the transfer with the given hash, encoded
+// by the download manager, for when they can no longer be worked out from
+// put.io. They are kept until the transfer is forgotten.
+func (j *StageJournal) SetTargets(hash string, targets json.RawMessage) error {
+	hash = normalizeHash(hash)
+	if j == nil || hash == "" {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	entry := j.entries[hash]
+	entry.Targets = targets
+	j.entries[hash] = entry
+	return j.saveLocked()
+}
+
+// Targets returns the targets recorded for the transfer with the given hash.
+func (j *StageJournal) Targets(hash string) (json.RawMessage, bool) {
+	if j == nil {
+		return nil, false
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	targets := j.entries[normalizeHash(hash)].Targets
+	return targets, targets != nil
+}
+
+// Get returns the stage the transfer with the given hash last reached.
+func (j *StageJournal) Get(hash string) (TransferStage, bool) {
+	if j == nil {
+		return "", false
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	entry, ok := j.entries[normalizeHash(hash)]
+	return entry.Stage, ok
+}
+
+// Forget drops the transfer with the given hash, e.g. once it's done.
+func (j *StageJournal) Forget(hash string) error {
+	hash = normalizeHash(hash)
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, ok := j.entries[hash]; !ok {
+		return nil
+	}
+	delete(j.entries, hash)
+	return j.saveLocked()
+}
+
+// saveLocked atomically writes the journal to disk. The caller must hold j.mu.
+func (j *StageJournal) saveLocked() error {
+	if j.path == "" {
+		return nil
+	}
+
+	if err := fileutil.WriteJSONAtomic(j.path, j.entries); err != nil {
+		return fmt.Errorf("failed to write transfer stages: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package app
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStageJournalRecordsStoreChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "transfer_stages.json")
+	journal, err := NewStageJournal(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	store := NewTransferStore()
+	journal.Watch(store, nil)
+
+	store.Track("ABCDEF", "Show", 100)
+	store.SetStage("ABCDEF", StageWaitingForImport)
+	store.Track("123456", "Movie", 100)
+	store.Forget("123456")
+
+	reloaded, err := NewStageJournal(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stage, ok := reloaded.Get("abcdef"); !ok || stage != StageWaitingForImport {
+		t.Errorf("expected the stage to survive a reload, got %q", stage)
+	}
+	if _, ok := reloaded.Get("123456"); ok {
+		t.Error("expected a forgotten transfer to be gone")
+	}
+}
+
+func TestStageJournalNilSafe(t *testing.T) {
+	var journal *StageJournal
+	journal.Watch(NewTransferStore(), nil)
+	if err := journal.Set("abc", StageImported); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, ok := journal.Get("abc"); ok {
+		t.Error("expected a nil journal to record nothing")
+	}
+	if err := journal.Forget("abc"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestStageJournalTargets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transfer_stages.json")
+	if err := os.WriteFile(path, []byte(`{"abcdef": "waiting_for_import"}`), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	journal, err := NewStageJournal(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := journal.Targets("abcdef"); ok {
+		t.Error("expected no targets before they are recorded")
+	}
+
+	if err := journal.SetTargets("ABCDEF", json.RawMessage(`[{"to":"/downloads/Show/e01.mkv"}]`)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := journal.Set("abcdef", StageImported); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	reloaded, err := NewStageJournal(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stage, _ := reloaded.Get("abcdef"); stage != StageImported {
+		t.Errorf("expected the stage to survive a reload, got %q", stage)
+	}
+	targets, _ := reloaded.Targets("abcdef")
+	var decoded []struct{ To string }
+	if err := json.Unmarshal(targets, &decoded); err != nil || len(decoded) != 1 || decoded[0].To != "/downloads/Show/e01.mkv" {
+		t.Errorf("expected the targets to survive a reload, got %s", targets)
+	}
+
+	reloaded.Forget("abcdef")
+	if _, ok := reloaded.Targets("abcdef"); ok {
+		t.Error("expected the targets to be forgotten with the transfer")
+	}
+}
@@ -0,0 +1,105 @@
+package app
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ochronus/goputioarr/internal/services/putio"
+)
+
+// StallTracker watches the progress of the transfers put.io is downloading
+// and marks the ones that made none for too long as stalled, so they can be
+// reported to the arrs, retried or removed. All methods are safe to call on a
+// nil tracker, which marks nothing.
+type StallTracker struct {
+	mu        sync.Mutex
+	transfers map[uint64]*stallState
+}
+
+// stallState is the last progress seen for a transfer.
+type stallState struct {
+	status     string
+	downloaded int64
+	since      time.Time
+	stalled    bool
+}
+
+// stallStatuses are the put.io statuses of transfers that should be making
+// progress. Transfers stuck preparing or queued stall like downloading ones.
+var stallStatuses = map[string]bool{
+	"IN_QUEUE":           true,
+	"PREPARING_DOWNLOAD": true,
+	"DOWNLOADING":        true,
+}
+
+// NewStallTracker creates an empty StallTracker.
+func NewStallTracker() *StallTracker {
+	return &StallTracker{transfers: make(map[uint64]*stallState)}
+}
+
+// Observe records the progress of transfers, as listed by put.io at now, and
+// returns the ones that just stalled: put.io has been queueing, preparing or
+// downloading them without progress, or a change of status, for at least
+// timeout. Transfers past downloading, or gone, are forgotten.
+func (t *StallTracker) Observe(transfers []putio.Transfer, now time.Time, timeout time.Duration) []putio.Transfer {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var stalled []putio.Transfer
+	downloading := make(map[uint64]bool, len(transfers))
+	for _, pt := range transfers {
+		if !stallStatuses[pt.Status] {
+			continue
+		}
+		downloading[pt.ID] = true
+		var downloaded int64
+		if pt.Downloaded != nil {
+			downloaded = *pt.Downloaded
+		}
+
+		state, ok := t.transfers[pt.ID]
+		if !ok || pt.Status != state.status || downloaded != state.downloaded {
+			t.transfers[pt.ID] = &stallState{status: pt.Status, downloaded: downloaded, since: now}
+			continue
+		}
+		if !state.stalled && now.Sub(state.since) >= timeout {
+			state.stalled = true
+			stalled = append(stalled, pt)
+		}
+	}
+	for id := range t.transfers {
+		if !downloading[id] {
+			delete(t.transfers, id)
+		}
+	}
+	return stalled
+}
+
+// Stalled reports whether the transfer with the given put.io ID is stalled,
+// and since when it made no progress.
+func (t *StallTracker) Stalled(id uint64) (time.Time, bool) {
+	if t == nil {
+		return time.Time{}, false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	state, ok := t.transfers[id]
+	if !ok || !state.stalled {
+		return time.Time{}, false
+	}
+	return state.since, true
+}
+
+// Forget drops what's known about the transfer with the given put.io ID, e.g.
+// once it's retried, so it gets the full timeout again.
+func (t *StallTracker) Forget(id uint64) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.transfers, id)
+}
@@ -0,0 +1,77 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ochronus/goputioarr/internal/services/putio"
+)
+
+func TestStallTrackerMarksTransfersWithoutProgress(t *testing.T) {
+	tracker := NewStallTracker()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	downloading := func(id uint64, downloaded int64) putio.Transfer {
+		return putio.Transfer{ID: id, Status: "DOWNLOADING", Downloaded: &downloaded}
+	}
+
+	if stalled := tracker.Observe([]putio.Transfer{downloading(1, 0), downloading(2, 0)}, start, time.Hour); len(stalled) != 0 {
+		t.Fatalf("expected nothing stalled yet, got %v", stalled)
+	}
+
+	// Transfer 2 makes progress, transfer 1 doesn't.
+	stalled := tracker.Observe([]putio.Transfer{downloading(1, 0), downloading(2, 100)}, start.Add(time.Hour), time.Hour)
+	if len(stalled) != 1 || stalled[0].ID != 1 {
+		t.Fatalf("expected transfer 1 to stall, got %v", stalled)
+	}
+	if since, ok := tracker.Stalled(1); !ok || !since.Equal(start) {
+		t.Errorf("expected transfer 1 stalled since the start, got %v %v", since, ok)
+	}
+	if _, ok := tracker.Stalled(2); ok {
+		t.Error("expected transfer 2 not to be stalled")
+	}
+
+	// A stalled transfer is only reported once.
+	if stalled := tracker.Observe([]putio.Transfer{downloading(1, 0)}, start.Add(2*time.Hour), time.Hour); len(stalled) != 0 {
+		t.Errorf("expected no newly stalled transfers, got %v", stalled)
+	}
+
+	// Progress clears the stall.
+	tracker.Observe([]putio.Transfer{downloading(1, 50)}, start.Add(3*time.Hour), time.Hour)
+	if _, ok := tracker.Stalled(1); ok {
+		t.Error("expected progress to clear the stall")
+	}
+
+	// Transfers that finished downloading are forgotten.
+	tracker.Observe([]putio.Transfer{{ID: 1, Status: "SEEDING"}}, start.Add(5*time.Hour), time.Hour)
+	if _, ok := tracker.Stalled(1); ok {
+		t.Error("expected a seeding transfer not to be stalled")
+	}
+}
+
+func TestStallTrackerMarksTransfersStuckBeforeDownloading(t *testing.T) {
+	tracker := NewStallTracker()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tracker.Observe([]putio.Transfer{{ID: 1, Status: "IN_QUEUE"}, {ID: 2, Status: "IN_QUEUE"}}, start, time.Hour)
+	// Transfer 2 moves on to preparing, which counts as progress.
+	stalled := tracker.Observe([]putio.Transfer{{ID: 1, Status: "IN_QUEUE"}, {ID: 2, Status: "PREPARING_DOWNLOAD"}}, start.Add(time.Hour), time.Hour)
+	if len(stalled) != 1 || stalled[0].ID != 1 {
+		t.Fatalf("expected the queued transfer to stall, got %v", stalled)
+	}
+
+	stalled = tracker.Observe([]putio.Transfer{{ID: 2, Status: "PREPARING_DOWNLOAD"}}, start.Add(2*time.Hour), time.Hour)
+	if len(stalled) != 1 || stalled[0].ID != 2 {
+		t.Errorf("expected the transfer stuck preparing to stall, got %v", stalled)
+	}
+}
+
+func TestStallTrackerNilSafe(t *testing.T) {
+	var tracker *StallTracker
+	if stalled := tracker.Observe([]putio.Transfer{{ID: 1, Status: "DOWNLOADING"}}, time.Now(), 0); stalled != nil {
+		t.Errorf("expected a nil tracker to mark nothing, got %v", stalled)
+	}
+	if _, ok := tracker.Stalled(1); ok {
+		t.Error("expected a nil tracker to mark nothing")
+	}
+	tracker.Forget(1)
+}
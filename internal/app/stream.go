@@ -0,0 +1,35 @@
+package app
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// StreamPrefix is the path the proxy serves put.io files at for STRM files.
+const StreamPrefix = "/stream"
+
+// StreamURL returns the URL a STRM file points at for the put.io file with
+// the given ID: the proxy's stream endpoint under stream_url, signed so only
+// the proxy's own links are served. The put.io API key stays off the disk.
+func (c *Container) StreamURL(fileID int64) string {
+	base := strings.TrimSuffix(c.Config.StreamURL, "/")
+	return fmt.Sprintf("%s%s/%d?sig=%s", base, StreamPrefix, fileID, c.streamSignature(fileID))
+}
+
+// ValidStreamSignature reports whether sig is the signature of the stream URL
+// for the put.io file with the given ID.
+func (c *Container) ValidStreamSignature(fileID int64, sig string) bool {
+	return hmac.Equal([]byte(sig), []byte(c.streamSignature(fileID)))
+}
+
+// streamSignature signs fileID with the put.io API key, so replacing the key
+// invalidates existing STRM files.
+func (c *Container) streamSignature(fileID int64) string {
+	mac := hmac.New(sha256.New, []byte("goputioarr stream\x00"+c.Config.Putio.APIKey))
+	mac.Write([]byte(strconv.FormatInt(fileID, 10)))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
@@ -0,0 +1,339 @@
+package app
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// TransferStage is where a transfer is in the local download pipeline.
+type TransferStage string
+
+const (
+	StageDownloading      TransferStage = "downloading"
+	StageQueued           TransferStage = "queued"
+	StageUnpacking        TransferStage = "unpacking"
+	StageWaitingForImport TransferStage = "waiting_for_import"
+	StageImported         TransferStage = "imported"
+	StageFailed           TransferStage = "failed"
+)
+
+// TransferState is the local pipeline state of a transfer.
+type TransferState struct {
+	Hash       string        `json:"hash"`
+	Name       string        `json:"name"`
+	Stage      TransferStage `json:"stage"`
+	Size       int64         `json:"size"`
+	Downloaded int64         `json:"downloaded"`
+	Error      string        `json:"error,omitempty"`
+	UpdatedAt  time.Time     `json:"updated_at"`
+	// Rate is the local download speed in bytes per second over the last
+	// rateWindow, 0 when nothing was downloaded in that time.
+	Rate int64 `json:"rate,omitempty"`
+	// FolderImport is set for items of putio.import_folder_id, which have no
+	// put.io transfer.
+	FolderImport bool `json:"folder_import,omitempty"`
+}
+
+// TransferStore shares the download manager's view of each transfer with the
+// HTTP layer, so torrent-get can report local progress and errors instead of
+// put.io's status alone. All methods are safe to call on a nil store.
+type TransferStore struct {
+	mu     sync.RWMutex
+	now    func() time.Time
+	states map[string]*TransferState
+	meters map[string]*rateMeter
+	// lastRate is the last non-zero combined rate, kept for RecentRate.
+	lastRate  int64
+	observers []func(state TransferState, forgotten bool)
+}
+
+// rateWindow is how far back a transfer's download rate is measured; a
+// transfer that downloaded nothing for that long has a rate of 0.
+const rateWindow = 10 * time.Second
+
+// rateBuckets is the number of one-second buckets rateWindow is split into.
+const rateBuckets = int64(rateWindow / time.Second)
+
+// rateMeter counts the bytes downloaded in each second of a sliding window.
+type rateMeter struct {
+	start   time.Time
+	buckets [rateBuckets]int64
+	// last is the second, since the Unix epoch, of the newest bucket.
+	last int64
+}
+
+func newRateMeter(now time.Time) *rateMeter {
+	return &rateMeter{start: now, last: now.Unix()}
+}
+
+// add counts n bytes downloaded at now.
+func (m *rateMeter) add(now time.Time, n int64) {
+	second := now.Unix()
+	if second > m.last {
+		for i := max(m.last+1, second-rateBuckets+1); i <= second; i++ {
+			m.buckets[bucket(i)] = 0
+		}
+		m.last = second
+	}
+	if second > m.last-rateBuckets {
+		m.buckets[bucket(second)] += n
+	}
+}
+
+// bucket returns the index of the bucket counting the given second.
+func bucket(second int64) int64 {
+	return (second%rateBuckets + rateBuckets) % rateBuckets
+}
+
+// rate returns the bytes per second downloaded within rateWindow of now. Until
+// the meter is that old, the rate is averaged over its age.
+func (m *rateMeter) rate(now time.Time) int64 {
+	second := now.Unix()
+	var total int64
+	for i := max(second-rateBuckets+1, m.last-rateBuckets+1); i <= min(second, m.last); i++ {
+		total += m.buckets[bucket(i)]
+	}
+	// The current second has only partly passed.
+	span := rateWindow - time.Second + now.Sub(now.Truncate(time.Second))
+	elapsed := max(min(now.Sub(m.start), span), time.Second)
+	return int64(float64(total) / elapsed.Seconds())
+}
+
+// NewTransferStore creates an empty TransferStore.
+func NewTransferStore() *TransferStore {
+	return &TransferStore{
+		now:    time.Now,
+		states: make(map[string]*TransferState),
+		meters: make(map[string]*rateMeter),
+	}
+}
+
+// OnChange registers fn to be called after a transfer is tracked, changes
+// stage or error, or is forgotten, with forgotten set. Progress updates are
+// too frequent to report. fn runs on the caller's goroutine and must not block.
+func (s *TransferStore) OnChange(fn func(state TransferState, forgotten bool)) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.observers = append(s.observers, fn)
+}
+
+// Track starts tracking a transfer, resetting any previous state for it.
+func (s *TransferStore) Track(hash, name string, size int64) {
+	s.track(hash, name, size, false)
+}
+
+// TrackFolderImport starts tracking an item of the import folder like Track.
+func (s *TransferStore) TrackFolderImport(hash, name string, size int64) {
+	s.track(hash, name, size, true)
+}
+
+func (s *TransferStore) track(hash, name string, size int64, folderImport bool) {
+	if s == nil || hash == "" {
+		return
+	}
+	s.mu.Lock()
+	state := &TransferState{
+		Hash:         normalizeHash(hash),
+		Name:         name,
+		Stage:        StageDownloading,
+		Size:         size,
+		UpdatedAt:    s.now(),
+		FolderImport: folderImport,
+	}
+	s.states[state.Hash] = state
+	delete(s.meters, state.Hash)
+	observers, snapshot := s.observers, *state
+	s.mu.Unlock()
+	notifyObservers(observers, snapshot, false)
+}
+
+// SetStage moves a tracked transfer to stage and clears its error.
+func (s *TransferStore) SetStage(hash string, stage TransferStage) {
+	s.update(hash, true, func(state *TransferState) {
+		state.Stage = stage
+		state.Error = ""
+	})
+}
+
+// Fail marks a tracked transfer as failed with the given message.
+func (s *TransferStore) Fail(hash, message string) {
+	s.update(hash, true, func(state *TransferState) {
+		state.Stage = StageFailed
+		state.Error = message
+	})
+}
+
+// SetError records an error message without changing the stage.
+func (s *TransferStore) SetError(hash, message string) {
+	s.update(hash, true, func(state *TransferState) {
+		state.Error = message
+	})
+}
+
+// AddProgress adds n downloaded bytes to a tracked transfer.
+func (s *TransferStore) AddProgress(hash string, n int64) {
+	s.update(hash, false, func(state *TransferState) {
+		state.Downloaded += n
+		now := s.now()
+		meter, ok := s.meters[state.Hash]
+		if !ok {
+			meter = newRateMeter(now)
+			s.meters[state.Hash] = meter
+		}
+		meter.add(now, n)
+		if rate := s.rateLocked(now); rate > 0 {
+			s.lastRate = rate
+		}
+	})
+}
+
+// CurrentRate returns the combined download rate of all transfers, in bytes
+// per second, over the last rateWindow, as an estimate of how fast the next
+// download will go. It drops to 0 once nothing is downloading.
+func (s *TransferStore) CurrentRate() int64 {
+	if s == nil {
+		return 0
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.rateLocked(s.now())
+}
+
+// RecentRate is CurrentRate, but while nothing is downloading it returns the
+// last non-zero rate measured instead of 0, so the next download can still be
+// estimated. It is 0 until anything has been downloaded.
+func (s *TransferStore) RecentRate() int64 {
+	if s == nil {
+		return 0
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if rate := s.rateLocked(s.now()); rate > 0 {
+		return rate
+	}
+	return s.lastRate
+}
+
+// rateLocked returns the combined rate of all transfers at now. The caller
+// must hold s.mu.
+func (s *TransferStore) rateLocked(now time.Time) int64 {
+	var rate int64
+	for _, meter := range s.meters {
+		rate += meter.rate(now)
+	}
+	return rate
+}
+
+// snapshotLocked returns a copy of state with its current rate. The caller
+// must hold s.mu.
+func (s *TransferStore) snapshotLocked(state *TransferState) TransferState {
+	snapshot := *state
+	if meter, ok := s.meters[state.Hash]; ok {
+		snapshot.Rate = meter.rate(s.now())
+	}
+	return snapshot
+}
+
+// Get returns a copy of the state of the transfer with the given hash.
+func (s *TransferStore) Get(hash string) (TransferState, bool) {
+	if s == nil {
+		return TransferState{}, false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	state, ok := s.states[normalizeHash(hash)]
+	if !ok {
+		return TransferState{}, false
+	}
+	return s.snapshotLocked(state), true
+}
+
+// List returns copies of all tracked states, ordered by hash.
+func (s *TransferStore) List() []TransferState {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	states := make([]TransferState, 0, len(s.states))
+	for _, state := range s.states {
+		states = append(states, s.snapshotLocked(state))
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Hash < states[j].Hash })
+	return states
+}
+
+// Forget stops tracking the transfer with the given hash.
+func (s *TransferStore) Forget(hash string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	state, ok := s.states[normalizeHash(hash)]
+	delete(s.states, normalizeHash(hash))
+	delete(s.meters, normalizeHash(hash))
+	observers := s.observers
+	s.mu.Unlock()
+	if ok {
+		notifyObservers(observers, *state, true)
+	}
+}
+
+// ForgetFailed stops tracking the transfer with the given hash if it failed,
+// e.g. once the arr removed it after seeing the error.
+func (s *TransferStore) ForgetFailed(hash string) {
+	if state, ok := s.Get(hash); ok && state.Stage == StageFailed {
+		s.Forget(hash)
+	}
+}
+
+// ExpireFailed stops tracking the transfers that failed more than maxAge ago,
+// so failures nothing acts on don't accumulate.
+func (s *TransferStore) ExpireFailed(maxAge time.Duration) {
+	if s == nil {
+		return
+	}
+	cutoff := s.now().Add(-maxAge)
+	var expired []string
+	s.mu.RLock()
+	for hash, state := range s.states {
+		if state.Stage == StageFailed && state.UpdatedAt.Before(cutoff) {
+			expired = append(expired, hash)
+		}
+	}
+	s.mu.RUnlock()
+	for _, hash := range expired {
+		s.ForgetFailed(hash)
+	}
+}
+
+// update applies fn to a tracked transfer and, if notify is set, reports the
+// result to the observers.
+func (s *TransferStore) update(hash string, notify bool, fn func(*TransferState)) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	state, ok := s.states[normalizeHash(hash)]
+	if !ok {
+		s.mu.Unlock()
+		return
+	}
+	fn(state)
+	state.UpdatedAt = s.now()
+	observers, snapshot := s.observers, *state
+	s.mu.Unlock()
+	if notify {
+		notifyObservers(observers, snapshot, false)
+	}
+}
+
+func notifyObservers(observers []func(TransferState, bool), state TransferState, forgotten bool) {
+	for _, fn := range observers {
+		fn(state, forgotten)
+	}
+}
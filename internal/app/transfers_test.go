@@ -0,0 +1,215 @@
+package app
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTransferStoreLifecycle(t *testing.T) {
+	store := NewTransferStore()
+	store.Track("ABCD", "Show", 100)
+
+	store.AddProgress("abcd", 40)
+	store.AddProgress("ABCD", 10)
+	state, ok := store.Get("abcd")
+	if !ok {
+		t.Fatal("expected tracked state")
+	}
+	if state.Stage != StageDownloading || state.Downloaded != 50 || state.Size != 100 || state.Name != "Show" {
+		t.Errorf("unexpected state %+v", state)
+	}
+
+	store.Fail("abcd", "disk full")
+	state, _ = store.Get("abcd")
+	if state.Stage != StageFailed || state.Error != "disk full" {
+		t.Errorf("expected failed state, got %+v", state)
+	}
+
+	store.SetStage("abcd", StageWaitingForImport)
+	state, _ = store.Get("abcd")
+	if state.Stage != StageWaitingForImport || state.Error != "" {
+		t.Errorf("expected stage change to clear the error, got %+v", state)
+	}
+
+	store.SetError("abcd", "import timed out")
+	state, _ = store.Get("abcd")
+	if state.Stage != StageWaitingForImport || state.Error != "import timed out" {
+		t.Errorf("expected error without stage change, got %+v", state)
+	}
+
+	store.Forget("ABCD")
+	if _, ok := store.Get("abcd"); ok {
+		t.Error("expected state to be forgotten")
+	}
+}
+
+func TestTransferStoreIgnoresUntracked(t *testing.T) {
+	store := NewTransferStore()
+	store.AddProgress("missing", 10)
+	store.SetStage("missing", StageImported)
+	store.Track("", "no hash", 1)
+
+	if states := store.List(); len(states) != 0 {
+		t.Errorf("expected no states, got %+v", states)
+	}
+}
+
+func TestTransferStoreList(t *testing.T) {
+	store := NewTransferStore()
+	store.Track("bb", "B", 1)
+	store.Track("aa", "A", 1)
+
+	states := store.List()
+	if len(states) != 2 || states[0].Hash != "aa" || states[1].Hash != "bb" {
+		t.Errorf("unexpected states %+v", states)
+	}
+}
+
+func TestTransferStoreFolderImport(t *testing.T) {
+	store := NewTransferStore()
+	store.TrackFolderImport("AA", "Folder", 1)
+	store.SetStage("aa", StageImported)
+
+	state, ok := store.Get("aa")
+	if !ok || !state.FolderImport || state.Stage != StageImported {
+		t.Errorf("expected a folder import to stay flagged, got %+v", state)
+	}
+	store.Track("aa", "Transfer", 1)
+	if state, _ := store.Get("aa"); state.FolderImport {
+		t.Error("expected Track to reset the folder import flag")
+	}
+}
+
+func TestTransferStoreNilSafe(t *testing.T) {
+	var store *TransferStore
+	store.Track("hash", "name", 1)
+	store.TrackFolderImport("hash", "name", 1)
+	store.SetStage("hash", StageImported)
+	store.Fail("hash", "err")
+	store.SetError("hash", "err")
+	store.AddProgress("hash", 1)
+	store.Forget("hash")
+	store.ForgetFailed("hash")
+	store.ExpireFailed(time.Hour)
+	if _, ok := store.Get("hash"); ok {
+		t.Error("expected nil store to report nothing")
+	}
+	if store.List() != nil {
+		t.Error("expected nil list from nil store")
+	}
+	if store.CurrentRate() != 0 {
+		t.Error("expected no rate from nil store")
+	}
+}
+
+func TestTransferStoreExpireFailed(t *testing.T) {
+	now := time.Unix(0, 0)
+	store := NewTransferStore()
+	store.now = func() time.Time { return now }
+
+	store.Track("old", "Old", 1)
+	store.Fail("old", "boom")
+	store.Track("active", "Active", 1)
+	now = now.Add(2 * time.Hour)
+	store.Track("recent", "Recent", 1)
+	store.Fail("recent", "boom")
+
+	store.ExpireFailed(time.Hour)
+	if _, ok := store.Get("old"); ok {
+		t.Error("expected the old failure to be dropped")
+	}
+	if _, ok := store.Get("recent"); !ok {
+		t.Error("expected the recent failure to be kept")
+	}
+	if _, ok := store.Get("active"); !ok {
+		t.Error("expected a transfer that didn't fail to be kept")
+	}
+
+	store.ForgetFailed("active")
+	store.ForgetFailed("recent")
+	if _, ok := store.Get("active"); !ok {
+		t.Error("expected ForgetFailed to keep a transfer that didn't fail")
+	}
+	if _, ok := store.Get("recent"); ok {
+		t.Error("expected ForgetFailed to drop a failed transfer")
+	}
+}
+
+func TestTransferStoreOnChange(t *testing.T) {
+	store := NewTransferStore()
+	type change struct {
+		stage     TransferStage
+		forgotten bool
+	}
+	var changes []change
+	store.OnChange(func(state TransferState, forgotten bool) {
+		changes = append(changes, change{state.Stage, forgotten})
+	})
+
+	store.Track("aa", "A", 10)
+	store.AddProgress("aa", 5)
+	store.SetStage("aa", StageWaitingForImport)
+	store.SetStage("missing", StageImported)
+	store.Forget("aa")
+	store.Forget("aa")
+
+	want := []change{{StageDownloading, false}, {StageWaitingForImport, false}, {StageWaitingForImport, true}}
+	if len(changes) != len(want) {
+		t.Fatalf("expected %v, got %v", want, changes)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("change %d: expected %v, got %v", i, want[i], changes[i])
+		}
+	}
+}
+
+func TestTransferStoreRate(t *testing.T) {
+	now := time.Unix(0, 0)
+	store := NewTransferStore()
+	store.now = func() time.Time { return now }
+	store.Track("abcd", "Show", 100000)
+	store.Track("ef01", "Movie", 100000)
+
+	// A new download is measured over the time it has run.
+	store.AddProgress("abcd", 2000)
+	now = now.Add(2 * time.Second)
+	if state, _ := store.Get("abcd"); state.Rate != 1000 {
+		t.Errorf("expected 1000 B/s, got %d", state.Rate)
+	}
+
+	// Only the last rateWindow counts.
+	for i := 0; i < 20; i++ {
+		store.AddProgress("abcd", 3000)
+		store.AddProgress("ef01", 1000)
+		now = now.Add(time.Second)
+	}
+	if state, _ := store.Get("abcd"); state.Rate != 3000 {
+		t.Errorf("expected 3000 B/s over the window, got %d", state.Rate)
+	}
+	if states := store.List(); states[1].Rate != 1000 {
+		t.Errorf("expected each transfer to have its own rate, got %+v", states)
+	}
+	if rate := store.CurrentRate(); rate != 4000 {
+		t.Errorf("expected the combined rate of 4000 B/s, got %d", rate)
+	}
+
+	// The rate decays as the download stalls, down to 0.
+	now = now.Add(6 * time.Second)
+	if state, _ := store.Get("abcd"); state.Rate != 1000 {
+		t.Errorf("expected the rate to decay to 1000 B/s, got %d", state.Rate)
+	}
+	now = now.Add(rateWindow)
+	if state, _ := store.Get("abcd"); state.Rate != 0 || store.CurrentRate() != 0 {
+		t.Errorf("expected no rate once idle, got %d (current %d)", state.Rate, store.CurrentRate())
+	}
+	if rate := store.RecentRate(); rate < 4000 {
+		t.Errorf("expected the last measured rate of about 4000 B/s once idle, got %d", rate)
+	}
+
+	store.Forget("abcd")
+	store.AddProgress("ef01", 9000)
+	if rate := store.CurrentRate(); rate != 1000 {
+		t.Errorf("expected only the remaining transfer to count, got %d", rate)
+	}
+}
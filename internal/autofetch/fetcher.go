@@ -0,0 +1,192 @@
+// Package autofetch adds the items of RSS feeds that match configured
+// patterns to put.io on a schedule, for content outside the arrs.
+package autofetch
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/ochronus/goputioarr/internal/config"
+	"github.com/ochronus/goputioarr/internal/services/putio"
+	"github.com/ochronus/goputioarr/internal/services/rss"
+	"github.com/sirupsen/logrus"
+)
+
+const defaultInterval = 15 * time.Minute
+
+// FeedSource fetches the items of a feed.
+type FeedSource interface {
+	Fetch(ctx context.Context, url string) ([]rss.Item, error)
+}
+
+var _ FeedSource = (*rss.Client)(nil)
+
+type feed struct {
+	name    string
+	url     string
+	include []*regexp.Regexp
+	exclude []*regexp.Regexp
+}
+
+// matches reports whether title passes the feed's filters.
+func (f *feed) matches(title string) bool {
+	for _, re := range f.exclude {
+		if re.MatchString(title) {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, re := range f.include {
+		if re.MatchString(title) {
+			return true
+		}
+	}
+	return false
+}
+
+// Fetcher polls feeds and adds matching items to put.io. Items are added like
+// any transfer not added through the proxy: the download manager only handles
+// them with manage_foreign_transfers or a putio.parent_folder_id. All methods
+// are safe to call on a nil fetcher.
+type Fetcher struct {
+	feeds    []feed
+	interval time.Duration
+	source   FeedSource
+	client   putio.ClientAPI
+	seen     *seenItems
+	logger   *logrus.Logger
+	now      func() time.Time
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// Option configures the Fetcher.
+type Option func(*Fetcher)
+
+// WithFeedSource sets where feeds are fetched from.
+func WithFeedSource(source FeedSource) Option {
+	return func(f *Fetcher) {
+		f.source = source
+	}
+}
+
+// NewFetcher creates a fetcher for cfg, adding items with client. Handled
+// items are remembered in the file at seenPath, or in memory if it's empty.
+func NewFetcher(cfg *config.RSSConfig, client putio.ClientAPI, seenPath string, logger *logrus.Logger, opts ...Option) (*Fetcher, error) {
+	seen, err := loadSeenItems(seenPath)
+	if err != nil {
+		return nil, err
+	}
+	f := &Fetcher{
+		interval: cfg.Interval.Duration(),
+		source:   rss.NewClient(),
+		client:   client,
+		seen:     seen,
+		logger:   logger,
+		now:      time.Now,
+	}
+	if f.interval == 0 {
+		f.interval = defaultInterval
+	}
+	for i, fc := range cfg.Feeds {
+		fd := feed{name: fc.Name, url: fc.URL}
+		if fd.name == "" {
+			fd.name = fmt.Sprintf("feed %d", i+1)
+		}
+		for _, pattern := range fc.Include {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("rss feed %s: invalid pattern %q: %w", fd.name, pattern, err)
+			}
+			fd.include = append(fd.include, re)
+		}
+		for _, pattern := range fc.Exclude {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("rss feed %s: invalid pattern %q: %w", fd.name, pattern, err)
+			}
+			fd.exclude = append(fd.exclude, re)
+		}
+		f.feeds = append(f.feeds, fd)
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f, nil
+}
+
+// Start fetches the feeds every interval in the background until Stop is
+// called.
+func (f *Fetcher) Start() {
+	if f == nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	f.cancel = cancel
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		ticker := time.NewTicker(f.interval)
+		defer ticker.Stop()
+		for {
+			f.Fetch(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop cancels a fetch in progress and stops fetching.
+func (f *Fetcher) Stop() {
+	if f == nil || f.cancel == nil {
+		return
+	}
+	f.cancel()
+	f.wg.Wait()
+}
+
+// Fetch checks every feed once and adds the new matching items.
+func (f *Fetcher) Fetch(ctx context.Context) {
+	if f == nil {
+		return
+	}
+	for i := range f.feeds {
+		f.fetchFeed(ctx, &f.feeds[i])
+	}
+	if err := f.seen.Save(f.now()); err != nil {
+		f.logger.Warnf("Failed to save seen feed items: %v", err)
+	}
+}
+
+func (f *Fetcher) fetchFeed(ctx context.Context, fd *feed) {
+	log := f.logger.WithField("feed", fd.name)
+	items, err := f.source.Fetch(ctx, fd.url)
+	if err != nil {
+		log.Warnf("Failed to fetch %s: %v", fd.name, err)
+		return
+	}
+	for _, item := range items {
+		// Items that don't match aren't remembered, so changed patterns
+		// apply to what the feed still lists.
+		now := f.now()
+		if !fd.matches(item.Title) || f.seen.Seen(fd.url, item.GUID, now) {
+			continue
+		}
+		if _, err := f.client.AddTransfer(item.Link); err != nil {
+			// Not remembered, so the next fetch retries it.
+			log.Warnf("Failed to add %s to put.io: %v", item.Title, err)
+			continue
+		}
+		log.Infof("Added %s to put.io", item.Title)
+		f.seen.Add(fd.url, item.GUID, now)
+	}
+}
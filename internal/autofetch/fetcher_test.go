@@ -0,0 +1,199 @@
+package autofetch
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ochronus/goputioarr/internal/config"
+	"github.com/ochronus/goputioarr/internal/services/putio"
+	"github.com/ochronus/goputioarr/internal/services/rss"
+	"github.com/sirupsen/logrus"
+)
+
+type mockPutioClient struct {
+	mu     sync.Mutex
+	added  []string
+	addErr error
+}
+
+func (m *mockPutioClient) GetAccountInfo() (*putio.AccountInfoResponse, error) {
+	return &putio.AccountInfoResponse{}, nil
+}
+func (m *mockPutioClient) ListTransfers() (*putio.ListTransferResponse, error) {
+	return &putio.ListTransferResponse{}, nil
+}
+func (m *mockPutioClient) ListTransfersSince(uint64) (*putio.ListTransferResponse, error) {
+	return m.ListTransfers()
+}
+func (m *mockPutioClient) GetTransfer(id uint64) (*putio.GetTransferResponse, error) {
+	return &putio.GetTransferResponse{Transfer: putio.Transfer{ID: id}}, nil
+}
+func (m *mockPutioClient) RemoveTransfer(uint64) error    { return nil }
+func (m *mockPutioClient) DeleteFile(int64) error         { return nil }
+func (m *mockPutioClient) RemoveTransfers([]uint64) error { return nil }
+func (m *mockPutioClient) RetryTransfer(uint64) error     { return nil }
+func (m *mockPutioClient) DeleteFiles([]int64) error      { return nil }
+func (m *mockPutioClient) AddTransfer(url string) (*putio.Transfer, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.addErr != nil {
+		return nil, m.addErr
+	}
+	m.added = append(m.added, url)
+	return &putio.Transfer{ID: 1}, nil
+}
+func (m *mockPutioClient) UploadFile([]byte) (*putio.Transfer, error) { return nil, nil }
+func (m *mockPutioClient) ListFiles(int64) (*putio.ListFileResponse, error) {
+	return &putio.ListFileResponse{}, nil
+}
+func (m *mockPutioClient) GetFileURL(int64) (string, error) { return "", nil }
+
+func (m *mockPutioClient) addedLinks() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.added...)
+}
+
+type mockFeedSource struct {
+	items map[string][]rss.Item
+	err   error
+}
+
+func (m *mockFeedSource) Fetch(ctx context.Context, url string) ([]rss.Item, error) {
+	return m.items[url], m.err
+}
+
+func testLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	return logger
+}
+
+func newTestFetcher(t *testing.T, cfg *config.RSSConfig, client putio.ClientAPI, source FeedSource, seenPath string) *Fetcher {
+	t.Helper()
+	f, err := NewFetcher(cfg, client, seenPath, testLogger(), WithFeedSource(source))
+	if err != nil {
+		t.Fatalf("NewFetcher: %v", err)
+	}
+	return f
+}
+
+var showFeed = &config.RSSConfig{Feeds: []config.RSSFeedConfig{{
+	URL:     "https://indexer/rss",
+	Include: []string{"(?i)^some show"},
+	Exclude: []string{"(?i)\\bcam\\b"},
+}}}
+
+var showItems = map[string][]rss.Item{
+	"https://indexer/rss": {
+		{GUID: "1", Title: "Some Show S01E01 1080p", Link: "magnet:?xt=urn:btih:1"},
+		{GUID: "2", Title: "Some Show S01E02 CAM", Link: "magnet:?xt=urn:btih:2"},
+		{GUID: "3", Title: "Other Show S01E01", Link: "magnet:?xt=urn:btih:3"},
+		{GUID: "4", Title: "some show S01E03", Link: "https://indexer/4.torrent"},
+	},
+}
+
+func TestFetchAddsMatchingItemsOnce(t *testing.T) {
+	client := &mockPutioClient{}
+	seenPath := filepath.Join(t.TempDir(), "state", "rss_seen.json")
+	f := newTestFetcher(t, showFeed, client, &mockFeedSource{items: showItems}, seenPath)
+
+	f.Fetch(context.Background())
+	f.Fetch(context.Background())
+
+	added := client.addedLinks()
+	if len(added) != 2 || added[0] != "magnet:?xt=urn:btih:1" || added[1] != "https://indexer/4.torrent" {
+		t.Errorf("expected the 2 matching items to be added once, got %v", added)
+	}
+
+	// The seen items survive a restart.
+	restarted := newTestFetcher(t, showFeed, client, &mockFeedSource{items: showItems}, seenPath)
+	restarted.Fetch(context.Background())
+	if len(client.addedLinks()) != 2 {
+		t.Errorf("expected nothing to be added after a restart, got %v", client.addedLinks())
+	}
+}
+
+func TestFetchRetriesFailedItems(t *testing.T) {
+	client := &mockPutioClient{addErr: errors.New("put.io is down")}
+	f := newTestFetcher(t, showFeed, client, &mockFeedSource{items: showItems}, "")
+
+	f.Fetch(context.Background())
+	client.addErr = nil
+	f.Fetch(context.Background())
+
+	if added := client.addedLinks(); len(added) != 2 {
+		t.Errorf("expected the failed items to be added on the next fetch, got %v", added)
+	}
+}
+
+func TestFetchFeedError(t *testing.T) {
+	client := &mockPutioClient{}
+	f := newTestFetcher(t, showFeed, client, &mockFeedSource{err: errors.New("timeout")}, "")
+
+	f.Fetch(context.Background())
+
+	if added := client.addedLinks(); len(added) != 0 {
+		t.Errorf("expected nothing to be added, got %v", added)
+	}
+}
+
+func TestFeedMatches(t *testing.T) {
+	all := newTestFetcher(t, &config.RSSConfig{Feeds: []config.RSSFeedConfig{{URL: "https://indexer/rss"}}}, &mockPutioClient{}, &mockFeedSource{}, "")
+	if !all.feeds[0].matches("Anything") {
+		t.Error("expected a feed without patterns to match everything")
+	}
+	if all.feeds[0].name != "feed 1" {
+		t.Errorf("expected a default name, got %q", all.feeds[0].name)
+	}
+}
+
+func TestNewFetcherInvalidPattern(t *testing.T) {
+	cfg := &config.RSSConfig{Feeds: []config.RSSFeedConfig{{Name: "broken", URL: "https://indexer/rss", Include: []string{"("}}}}
+	if _, err := NewFetcher(cfg, &mockPutioClient{}, "", testLogger()); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+}
+
+func TestSeenItemsExpire(t *testing.T) {
+	seen, err := loadSeenItems("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	seen.Add("feed", "old", start)
+	seen.Add("feed", "listed", start)
+	seen.Seen("feed", "listed", start.Add(20*24*time.Hour))
+
+	if err := seen.Save(start.Add(40 * 24 * time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if seen.Seen("feed", "old", start) {
+		t.Error("expected an item no longer listed to be forgotten")
+	}
+	if !seen.Seen("feed", "listed", start) {
+		t.Error("expected a recently listed item to be kept")
+	}
+}
+
+func TestStartStop(t *testing.T) {
+	client := &mockPutioClient{}
+	f := newTestFetcher(t, showFeed, client, &mockFeedSource{items: showItems}, "")
+
+	f.Start()
+	f.Stop()
+
+	// The first fetch runs right away.
+	if added := client.addedLinks(); len(added) != 2 {
+		t.Errorf("expected the feed to be fetched on start, got %v", added)
+	}
+
+	var nilFetcher *Fetcher
+	nilFetcher.Start()
+	nilFetcher.Fetch(context.Background())
+	nilFetcher.Stop()
+}
@@ -0,0 +1,86 @@
+package autofetch
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ochronus/goputioarr/internal/fileutil"
+)
+
+// seenRetention is how long an item is remembered after it was last listed
+// by its feed.
+const seenRetention = 30 * 24 * time.Hour
+
+// seenItems remembers the feed items already handled, by feed URL and GUID,
+// so they're only added once. When backed by a file it survives restarts.
+type seenItems struct {
+	path  string
+	mu    sync.Mutex
+	items map[string]time.Time
+}
+
+// loadSeenItems loads the items from path. An empty path keeps them in memory
+// only; a missing file starts empty.
+func loadSeenItems(path string) (*seenItems, error) {
+	s := &seenItems{path: path, items: make(map[string]time.Time)}
+	if path == "" {
+		return s, nil
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read seen feed items: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.items); err != nil {
+		return nil, fmt.Errorf("failed to parse seen feed items %s: %w", path, err)
+	}
+	return s, nil
+}
+
+func seenKey(feedURL, guid string) string {
+	return feedURL + "\n" + guid
+}
+
+// Seen reports whether the item was handled and, if so, refreshes it.
+func (s *seenItems) Seen(feedURL, guid string, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := seenKey(feedURL, guid)
+	if _, ok := s.items[key]; !ok {
+		return false
+	}
+	s.items[key] = now
+	return true
+}
+
+// Add records an item as handled.
+func (s *seenItems) Add(feedURL, guid string, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items[seenKey(feedURL, guid)] = now
+}
+
+// Save drops the items feeds haven't listed for seenRetention and writes the
+// rest to disk.
+func (s *seenItems) Save(now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, last := range s.items {
+		if now.Sub(last) > seenRetention {
+			delete(s.items, key)
+		}
+	}
+	if s.path == "" {
+		return nil
+	}
+
+	if err := fileutil.WriteJSONAtomic(s.path, s.items); err != nil {
+		return fmt.Errorf("failed to write seen feed items: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+// Package benchmark measures how fast a file downloads from put.io with
+// different numbers of parallel downloads, to suggest a download_workers
+// setting for the connection.
+package benchmark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ochronus/goputioarr/internal/services/putio"
+)
+
+const (
+	// DefaultDuration is how long each worker count is measured.
+	DefaultDuration = 15 * time.Second
+	// maxListings bounds the folders searched for a test file.
+	maxListings = 20
+	// goodEnough is the share of the best throughput a worker count must
+	// reach to be recommended; more workers than needed only add load.
+	goodEnough = 0.9
+)
+
+// DefaultWorkers are the worker counts tried by default.
+var DefaultWorkers = []int{1, 2, 4, 8}
+
+// Options configure a benchmark.
+type Options struct {
+	// FileID is the file to download. 0 picks the largest file in FolderID
+	// or its subfolders.
+	FileID   int64
+	FolderID int64
+	// Workers are the numbers of parallel downloads to try, DefaultWorkers
+	// if empty.
+	Workers []int
+	// Duration is how long each worker count is measured, DefaultDuration
+	// if 0.
+	Duration time.Duration
+	// HTTPClient downloads the file, http.DefaultClient if nil.
+	HTTPClient *http.Client
+}
+
+// Result is the throughput measured with a number of parallel downloads.
+type Result struct {
+	Workers int
+	Bytes   int64
+	Elapsed time.Duration
+}
+
+// Throughput returns the bytes downloaded per second.
+func (r Result) Throughput() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Bytes) / r.Elapsed.Seconds()
+}
+
+// Report is the outcome of a benchmark.
+type Report struct {
+	File    putio.FileResponse
+	Results []Result
+}
+
+// Recommend returns the fewest workers that reached nearly the best
+// throughput, or 0 if nothing was downloaded.
+func (r *Report) Recommend() int {
+	var best float64
+	for _, result := range r.Results {
+		best = max(best, result.Throughput())
+	}
+	if best == 0 {
+		return 0
+	}
+	recommended := 0
+	for _, result := range r.Results {
+		if result.Throughput() >= best*goodEnough && (recommended == 0 || result.Workers < recommended) {
+			recommended = result.Workers
+		}
+	}
+	return recommended
+}
+
+// Run downloads the file selected by opts with each worker count in turn and
+// reports the throughput of each. progress, if set, is called after each
+// worker count is measured.
+func Run(ctx context.Context, client putio.ClientAPI, opts Options, progress func(Result)) (*Report, error) {
+	if len(opts.Workers) == 0 {
+		opts.Workers = DefaultWorkers
+	}
+	if opts.Duration <= 0 {
+		opts.Duration = DefaultDuration
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = http.DefaultClient
+	}
+
+	file, err := pickFile(client, opts)
+	if err != nil {
+		return nil, err
+	}
+	url, err := client.GetFileURL(file.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the download URL of %s: %w", file.Name, err)
+	}
+
+	report := &Report{File: file}
+	for _, workers := range opts.Workers {
+		if workers <= 0 {
+			continue
+		}
+		result, err := measure(ctx, opts.HTTPClient, url, workers, opts.Duration)
+		if err != nil {
+			return nil, err
+		}
+		report.Results = append(report.Results, result)
+		if progress != nil {
+			progress(result)
+		}
+	}
+	return report, nil
+}
+
+// pickFile returns the file selected by opts: FileID, or else the largest
+// file found in FolderID and its subfolders.
+func pickFile(client putio.ClientAPI, opts Options) (putio.FileResponse, error) {
+	if opts.FileID != 0 {
+		resp, err := client.ListFiles(opts.FileID)
+		if err != nil {
+			return putio.FileResponse{}, fmt.Errorf("failed to look up file %d: %w", opts.FileID, err)
+		}
+		if resp.Parent.FileType == "FOLDER" {
+			return putio.FileResponse{}, fmt.Errorf("file %d is a folder", opts.FileID)
+		}
+		return resp.Parent, nil
+	}
+
+	var largest putio.FileResponse
+	folders := []int64{opts.FolderID}
+	for listings := 0; len(folders) > 0 && listings < maxListings; listings++ {
+		resp, err := client.ListFiles(folders[0])
+		if err != nil {
+			return putio.FileResponse{}, fmt.Errorf("failed to list files: %w", err)
+		}
+		folders = folders[1:]
+		for _, f := range resp.Files {
+			switch {
+			case f.FileType == "FOLDER":
+				folders = append(folders, f.ID)
+			case f.Size > largest.Size:
+				largest = f
+			}
+		}
+	}
+	if largest.ID == 0 {
+		return putio.FileResponse{}, errors.New("no file found to download, pick one with --file-id")
+	}
+	return largest, nil
+}
+
+// measure downloads url with the given number of parallel downloads for
+// duration, starting each download again when it completes, and counts the
+// bytes received.
+func measure(ctx context.Context, client *http.Client, url string, workers int, duration time.Duration) (Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+
+	var bytes atomic.Int64
+	var wg sync.WaitGroup
+	errs := make([]error, workers)
+	start := time.Now()
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				if err := download(ctx, client, url, &bytes); err != nil && ctx.Err() == nil {
+					errs[i] = err
+					cancel()
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return Result{}, err
+	}
+	return Result{Workers: workers, Bytes: bytes.Load(), Elapsed: time.Since(start)}, nil
+}
+
+// download fetches url once, adding the bytes received to n.
+func download(ctx context.Context, client *http.Client, url string, n *atomic.Int64) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("download failed: %s", resp.Status)
+	}
+	_, err = io.Copy(counter{n}, resp.Body)
+	return err
+}
+
+// counter is an io.Writer discarding what's written and counting it.
+type counter struct {
+	n *atomic.Int64
+}
+
+func (c counter) Write(p []byte) (int, error) {
+	c.n.Add(int64(len(p)))
+	return len(p), nil
+}
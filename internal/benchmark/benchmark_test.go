@@ -0,0 +1,90 @@
+package benchmark
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ochronus/goputioarr/internal/putiomock"
+	"github.com/ochronus/goputioarr/internal/services/putio"
+)
+
+func TestRun(t *testing.T) {
+	mock := putiomock.New(putiomock.WithDownloadTime(0), putiomock.WithFileSize(64*1024))
+	server := httptest.NewServer(mock.Handler())
+	defer server.Close()
+	mock.Add("Show.S01E01", "")
+	client := putio.NewClient("token", putio.WithBaseURLs(server.URL, server.URL))
+
+	var measured []int
+	report, err := Run(context.Background(), client, Options{
+		Workers:  []int{1, 2},
+		Duration: 50 * time.Millisecond,
+	}, func(r Result) { measured = append(measured, r.Workers) })
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	if report.File.Name != "Show.S01E01.mkv" {
+		t.Errorf("expected the video in the transfer folder to be picked, got %q", report.File.Name)
+	}
+	if len(measured) != 2 || measured[0] != 1 || measured[1] != 2 {
+		t.Errorf("expected progress for 1 and 2 workers, got %v", measured)
+	}
+	for _, r := range report.Results {
+		if r.Bytes == 0 || r.Throughput() <= 0 {
+			t.Errorf("expected bytes downloaded with %d workers, got %+v", r.Workers, r)
+		}
+	}
+	if report.Recommend() == 0 {
+		t.Error("expected a recommendation")
+	}
+}
+
+func TestRunNoFiles(t *testing.T) {
+	server := httptest.NewServer(putiomock.New().Handler())
+	defer server.Close()
+	client := putio.NewClient("token", putio.WithBaseURLs(server.URL, server.URL))
+
+	if _, err := Run(context.Background(), client, Options{Duration: time.Millisecond}, nil); err == nil {
+		t.Fatal("expected an error without any file to download")
+	}
+}
+
+func TestRecommend(t *testing.T) {
+	tests := []struct {
+		name    string
+		results []Result
+		want    int
+	}{
+		{"empty", nil, 0},
+		{"nothing downloaded", []Result{{Workers: 1, Elapsed: time.Second}}, 0},
+		{
+			"fewest workers near the best",
+			[]Result{
+				{Workers: 1, Bytes: 40, Elapsed: time.Second},
+				{Workers: 2, Bytes: 95, Elapsed: time.Second},
+				{Workers: 4, Bytes: 100, Elapsed: time.Second},
+				{Workers: 8, Bytes: 98, Elapsed: time.Second},
+			},
+			2,
+		},
+		{
+			"best only",
+			[]Result{
+				{Workers: 1, Bytes: 10, Elapsed: time.Second},
+				{Workers: 4, Bytes: 100, Elapsed: time.Second},
+			},
+			4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := &Report{Results: tt.results}
+			if got := report.Recommend(); got != tt.want {
+				t.Errorf("Recommend() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
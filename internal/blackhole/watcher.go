@@ -0,0 +1,221 @@
+// Package blackhole picks up .torrent and .magnet files dropped into a watch
+// directory and adds them to put.io, for tools that only support a blackhole
+// download client.
+package blackhole
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ochronus/goputioarr/internal/app"
+	"github.com/ochronus/goputioarr/internal/services/putio"
+	"github.com/ochronus/goputioarr/internal/services/torrent"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultInterval = 10 * time.Second
+	// settleTime is how long a file must go unmodified before it's picked up,
+	// so files still being written are left alone.
+	settleTime = 2 * time.Second
+	// failedSuffix is appended to files put.io rejected, so they're not
+	// retried on every scan.
+	failedSuffix = ".failed"
+)
+
+// Watcher polls a directory for .torrent and .magnet files, uploads them to
+// put.io and claims the transfers like torrent-add does, so the download
+// manager handles them. Files are removed once added. All methods are safe to
+// call on a nil watcher.
+type Watcher struct {
+	dir       string
+	interval  time.Duration
+	client    putio.ClientAPI
+	ownership *app.HashSet
+	logger    *logrus.Logger
+	now       func() time.Time
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewWatcher creates a watcher for the blackhole configured in container, or
+// returns nil if none is.
+func NewWatcher(container *app.Container) *Watcher {
+	cfg := container.Config.Blackhole
+	if cfg == nil {
+		return nil
+	}
+	interval := cfg.Interval.Duration()
+	if interval == 0 {
+		interval = defaultInterval
+	}
+	return &Watcher{
+		dir:       cfg.Directory,
+		interval:  interval,
+		client:    container.PutioClient,
+		ownership: container.Ownership,
+		logger:    container.Logger,
+		now:       time.Now,
+	}
+}
+
+// Start scans the directory every interval in the background until Stop is
+// called.
+func (w *Watcher) Start() {
+	if w == nil {
+		return
+	}
+	w.stop = make(chan struct{})
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		w.logger.Infof("Watching %s for .torrent and .magnet files", w.dir)
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		for {
+			w.Scan()
+			select {
+			case <-w.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops scanning and waits for a scan in progress to finish.
+func (w *Watcher) Stop() {
+	if w == nil || w.stop == nil {
+		return
+	}
+	close(w.stop)
+	w.wg.Wait()
+}
+
+// Scan adds the files currently in the directory to put.io.
+func (w *Watcher) Scan() {
+	if w == nil {
+		return
+	}
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		w.logger.Warnf("Failed to read blackhole directory %s: %v", w.dir, err)
+		return
+	}
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || (ext != ".torrent" && ext != ".magnet") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || w.now().Sub(info.ModTime()) < settleTime {
+			continue
+		}
+		w.add(filepath.Join(w.dir, entry.Name()), ext)
+	}
+}
+
+// add uploads the file at path and removes it. A file put.io rejects is
+// renamed with failedSuffix; on other errors it's left for the next scan.
+func (w *Watcher) add(path, ext string) {
+	log := w.logger.WithField("file", filepath.Base(path))
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Warnf("Failed to read %s: %v", path, err)
+		return
+	}
+
+	var hash string
+	if ext == ".magnet" {
+		hash, err = w.addMagnet(strings.TrimSpace(string(data)))
+	} else {
+		hash, err = w.addTorrent(data)
+	}
+	if err != nil {
+		if !rejected(err) {
+			log.Warnf("Failed to add %s to put.io, retrying: %v", path, err)
+			return
+		}
+		log.Errorf("put.io rejected %s: %v", path, err)
+		if err := os.Rename(path, path+failedSuffix); err != nil {
+			log.Warnf("Failed to rename %s: %v", path, err)
+		}
+		return
+	}
+
+	if hash != "" {
+		if err := w.ownership.Add(hash); err != nil {
+			log.Warnf("Failed to record transfer ownership: %v", err)
+		}
+	} else {
+		log.Warnf("Unknown info hash for %s, the transfer won't be managed unless manage_foreign_transfers is enabled", path)
+	}
+	log.Infof("Added %s to put.io", path)
+	if err := os.Remove(path); err != nil {
+		log.Warnf("Failed to remove %s: %v", path, err)
+	}
+}
+
+// addTorrent uploads a torrent file and returns its info hash, if known.
+func (w *Watcher) addTorrent(data []byte) (string, error) {
+	transfer, err := w.client.UploadFile(data)
+	if err != nil {
+		return "", err
+	}
+	if meta, err := torrent.ParseMetainfo(data); err == nil {
+		return meta.InfoHash, nil
+	}
+	if transfer != nil && transfer.Hash != nil {
+		return *transfer.Hash, nil
+	}
+	return "", nil
+}
+
+// addMagnet adds a magnet link and returns its info hash, if known. A link
+// already on put.io is claimed without adding it again.
+func (w *Watcher) addMagnet(link string) (string, error) {
+	var hash string
+	if magnet, err := torrent.ParseMagnet(link); err == nil {
+		hash = magnet.InfoHash
+	}
+	if hash != "" {
+		transfers, err := w.client.ListTransfers()
+		if err != nil {
+			return "", err
+		}
+		for _, t := range transfers.Transfers {
+			if t.Hash != nil && strings.EqualFold(*t.Hash, hash) {
+				return hash, nil
+			}
+		}
+	}
+
+	transfer, err := w.client.AddTransfer(link)
+	if err != nil {
+		return "", err
+	}
+	if hash == "" && transfer != nil && transfer.Hash != nil {
+		hash = *transfer.Hash
+	}
+	return hash, nil
+}
+
+// rejected reports whether put.io refused a file for good, as opposed to
+// failing in a way worth retrying.
+func rejected(err error) bool {
+	var httpErr *putio.HTTPError
+	if !errors.As(err, &httpErr) {
+		return false
+	}
+	switch httpErr.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusTooManyRequests:
+		return false
+	}
+	return httpErr.StatusCode >= 400 && httpErr.StatusCode < 500
+}
@@ -0,0 +1,209 @@
+package blackhole
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ochronus/goputioarr/internal/app"
+	"github.com/ochronus/goputioarr/internal/config"
+	"github.com/ochronus/goputioarr/internal/services/putio"
+	"github.com/sirupsen/logrus"
+)
+
+type mockPutioClient struct {
+	transfers []putio.Transfer
+	uploaded  [][]byte
+	added     []string
+	addErr    error
+}
+
+func (m *mockPutioClient) GetAccountInfo() (*putio.AccountInfoResponse, error) {
+	return &putio.AccountInfoResponse{}, nil
+}
+func (m *mockPutioClient) ListTransfers() (*putio.ListTransferResponse, error) {
+	return &putio.ListTransferResponse{Transfers: m.transfers}, nil
+}
+func (m *mockPutioClient) ListTransfersSince(uint64) (*putio.ListTransferResponse, error) {
+	return m.ListTransfers()
+}
+func (m *mockPutioClient) GetTransfer(id uint64) (*putio.GetTransferResponse, error) {
+	return &putio.GetTransferResponse{Transfer: putio.Transfer{ID: id}}, nil
+}
+func (m *mockPutioClient) RemoveTransfer(uint64) error    { return nil }
+func (m *mockPutioClient) DeleteFile(int64) error         { return nil }
+func (m *mockPutioClient) RemoveTransfers([]uint64) error { return nil }
+func (m *mockPutioClient) RetryTransfer(uint64) error     { return nil }
+func (m *mockPutioClient) DeleteFiles([]int64) error      { return nil }
+func (m *mockPutioClient) AddTransfer(url string) (*putio.Transfer, error) {
+	if m.addErr != nil {
+		return nil, m.addErr
+	}
+	m.added = append(m.added, url)
+	return &putio.Transfer{ID: 1}, nil
+}
+func (m *mockPutioClient) UploadFile(data []byte) (*putio.Transfer, error) {
+	if m.addErr != nil {
+		return nil, m.addErr
+	}
+	m.uploaded = append(m.uploaded, data)
+	return &putio.Transfer{ID: 2}, nil
+}
+func (m *mockPutioClient) ListFiles(int64) (*putio.ListFileResponse, error) {
+	return &putio.ListFileResponse{}, nil
+}
+func (m *mockPutioClient) GetFileURL(int64) (string, error) { return "", nil }
+
+const magnetHash = "c12fe1c06bba254a9dc9f519b335aa7c1367a88a"
+
+func setupWatcher(t *testing.T, client *mockPutioClient) (*Watcher, string, *app.HashSet) {
+	t.Helper()
+	dir := t.TempDir()
+	ownership, err := app.NewOwnershipRegistry("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	w := NewWatcher(&app.Container{
+		Config:      &config.Config{Blackhole: &config.BlackholeConfig{Directory: dir}},
+		PutioClient: client,
+		Ownership:   ownership,
+		Logger:      logger,
+	})
+	// Files written by the test are old enough to be picked up.
+	w.now = func() time.Time { return time.Now().Add(time.Minute) }
+	return w, dir, ownership
+}
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestNewWatcherDisabled(t *testing.T) {
+	if w := NewWatcher(&app.Container{Config: &config.Config{}}); w != nil {
+		t.Errorf("expected no watcher without a blackhole, got %+v", w)
+	}
+	var w *Watcher
+	w.Start()
+	w.Scan()
+	w.Stop()
+}
+
+func TestScanAddsFiles(t *testing.T) {
+	info := "d6:lengthi1024e4:name8:file.mkv12:piece lengthi16384e6:pieces0:e"
+	sum := sha1.Sum([]byte(info))
+	torrentHash := hex.EncodeToString(sum[:])
+
+	client := &mockPutioClient{}
+	w, dir, ownership := setupWatcher(t, client)
+	magnet := "magnet:?xt=urn:btih:" + magnetHash + "&dn=Show"
+	magnetPath := writeFile(t, dir, "Show.magnet", magnet+"\n")
+	torrentPath := writeFile(t, dir, "Movie.TORRENT", "d4:info"+info+"e")
+	other := writeFile(t, dir, "notes.txt", "keep me")
+
+	w.Scan()
+
+	if len(client.added) != 1 || client.added[0] != magnet {
+		t.Errorf("expected the magnet link to be added, got %v", client.added)
+	}
+	if len(client.uploaded) != 1 {
+		t.Errorf("expected the torrent to be uploaded, got %d uploads", len(client.uploaded))
+	}
+	if !ownership.Has(magnetHash) || !ownership.Has(torrentHash) {
+		t.Error("expected both transfers to be claimed")
+	}
+	for _, path := range []string{magnetPath, torrentPath} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be removed, got %v", path, err)
+		}
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Errorf("expected other files to be left alone: %v", err)
+	}
+}
+
+func TestScanSkipsFilesBeingWritten(t *testing.T) {
+	client := &mockPutioClient{}
+	w, dir, _ := setupWatcher(t, client)
+	w.now = time.Now
+	path := writeFile(t, dir, "Show.magnet", "magnet:?xt=urn:btih:"+magnetHash)
+
+	w.Scan()
+
+	if len(client.added) != 0 {
+		t.Errorf("expected a fresh file to wait, got %v", client.added)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("expected the file to stay: %v", err)
+	}
+}
+
+func TestScanClaimsMagnetAlreadyOnPutio(t *testing.T) {
+	hash := magnetHash
+	client := &mockPutioClient{transfers: []putio.Transfer{{ID: 5, Hash: &hash}}}
+	w, dir, ownership := setupWatcher(t, client)
+	writeFile(t, dir, "Show.magnet", "magnet:?xt=urn:btih:"+magnetHash)
+
+	w.Scan()
+
+	if len(client.added) != 0 {
+		t.Errorf("expected the duplicate not to be added again, got %v", client.added)
+	}
+	if !ownership.Has(magnetHash) {
+		t.Error("expected the existing transfer to be claimed")
+	}
+}
+
+func TestScanErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantFailed bool
+	}{
+		{name: "rejected", err: &putio.HTTPError{StatusCode: http.StatusBadRequest}, wantFailed: true},
+		{name: "rate limited", err: &putio.HTTPError{StatusCode: http.StatusTooManyRequests}},
+		{name: "unreachable", err: errors.New("connection refused")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, dir, _ := setupWatcher(t, &mockPutioClient{addErr: tt.err})
+			path := writeFile(t, dir, "Show.torrent", "not a torrent")
+
+			w.Scan()
+
+			_, failedErr := os.Stat(path + failedSuffix)
+			_, keptErr := os.Stat(path)
+			if tt.wantFailed && (failedErr != nil || keptErr == nil) {
+				t.Errorf("expected the file to be renamed to %s", failedSuffix)
+			}
+			if !tt.wantFailed && keptErr != nil {
+				t.Errorf("expected the file to be kept for a retry: %v", keptErr)
+			}
+		})
+	}
+}
+
+func TestStartStop(t *testing.T) {
+	client := &mockPutioClient{}
+	w, dir, _ := setupWatcher(t, client)
+	writeFile(t, dir, "Show.magnet", "magnet:?xt=urn:btih:"+magnetHash)
+
+	w.Start()
+	w.Stop()
+
+	// The first scan runs right away.
+	if len(client.added) != 1 {
+		t.Errorf("expected the file to be added on start, got %v", client.added)
+	}
+}
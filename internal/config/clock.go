@@ -0,0 +1,16 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// ParseClock parses a local time of day written as "HH:MM" (24-hour clock)
+// and returns it as the time since midnight.
+func ParseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, expected HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
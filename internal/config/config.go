@@ -2,14 +2,54 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"net/mail"
 	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/BurntSushi/toml"
+	"github.com/ochronus/goputioarr/internal/hint"
 	"github.com/sirupsen/logrus"
 )
 
+// Actions applied when a transfer is not imported within import_timeout
+const (
+	ImportTimeoutKeep        = "keep"
+	ImportTimeoutDeleteLocal = "delete_local"
+	ImportTimeoutBlocklist   = "blocklist"
+)
+
+// How client certificates are used when tls.client_ca_file is set
+const (
+	ClientAuthRequired    = "required"
+	ClientAuthAlternative = "alternative"
+)
+
+// How the files of finished transfers reach the download directory
+const (
+	DownloadModeDownload = "download"
+	DownloadModeStrm     = "strm"
+)
+
+// Order in which the files of a transfer are queued for download
+const (
+	DownloadOrderDefault       = "default"
+	DownloadOrderSmallestFirst = "smallest_first"
+	DownloadOrderLargestFirst  = "largest_first"
+)
+
+// What happens to put.io transfers that stall, see putio.stall_timeout
+const (
+	StallActionReport = "report"
+	StallActionRetry  = "retry"
+	StallActionRemove = "remove"
+)
+
 const (
 	MinPollingInterval      = 1
 	MaxPollingInterval      = 3600
@@ -17,49 +57,420 @@ const (
 	MaxDownloadWorkers      = 100
 	MinOrchestrationWorkers = 1
 	MaxOrchestrationWorkers = 100
+	MaxArrRetries           = 10
+	MaxDownloadRetries      = 10
 )
 
+// apiVersionPattern matches arr API versions such as v1 or v3
+var apiVersionPattern = regexp.MustCompile(`^v[0-9]+$`)
+
 // Config represents the main application configuration
 type Config struct {
-	BindAddress          string      `toml:"bind_address"`
-	DownloadDirectory    string      `toml:"download_directory"`
-	DownloadWorkers      int         `toml:"download_workers"`
-	Loglevel             string      `toml:"loglevel"`
-	OrchestrationWorkers int         `toml:"orchestration_workers"`
-	Password             string      `toml:"password"`
-	PollingInterval      int         `toml:"polling_interval"`
-	Port                 int         `toml:"port"`
-	SkipDirectories      []string    `toml:"skip_directories"`
-	UID                  int         `toml:"uid"`
-	Username             string      `toml:"username"`
-	Putio                PutioConfig `toml:"putio"`
-	Sonarr               *ArrConfig  `toml:"sonarr"`
-	Radarr               *ArrConfig  `toml:"radarr"`
-	Whisparr             *ArrConfig  `toml:"whisparr"`
+	AllowedNetworks         []string               `toml:"allowed_networks"`
+	BindAddress             string                 `toml:"bind_address"`
+	CheckForUpdates         bool                   `toml:"check_for_updates"`
+	CreateDownloadDir       bool                   `toml:"create_download_directory"`
+	DeleteLocalAfterImport  *bool                  `toml:"delete_local_after_import"`
+	DeleteRemoteFiles       *bool                  `toml:"delete_remote_files"`
+	DownloadDirectory       string                 `toml:"download_directory"`
+	DownloadMode            string                 `toml:"download_mode"`
+	DownloadOrder           string                 `toml:"download_order"`
+	DownloadConnectTimeout  Duration               `toml:"download_connect_timeout"`
+	DownloadResponseTimeout Duration               `toml:"download_response_timeout"`
+	DownloadRetries         int                    `toml:"download_retries"`
+	DownloadStallTimeout    Duration               `toml:"download_stall_timeout"`
+	DownloadTimeout         Duration               `toml:"download_timeout"`
+	DownloadWorkers         int                    `toml:"download_workers"`
+	DrainTimeout            Duration               `toml:"drain_timeout"`
+	GID                     int                    `toml:"gid"`
+	HeartbeatURL            string                 `toml:"heartbeat_url"`
+	ImportTimeout           Duration               `toml:"import_timeout"`
+	ImportTimeoutAction     string                 `toml:"import_timeout_action"`
+	Loglevel                string                 `toml:"loglevel"`
+	ManageForeignTransfers  bool                   `toml:"manage_foreign_transfers"`
+	MaxActiveTransfers      int                    `toml:"max_active_transfers"`
+	MinFreeSpace            Size                   `toml:"min_free_space"`
+	OnlyNewTransfers        bool                   `toml:"only_new_transfers"`
+	OrchestrationWorkers    int                    `toml:"orchestration_workers"`
+	Password                string                 `toml:"password"`
+	PathMappings            map[string]string      `toml:"path_mappings"`
+	PollingInterval         Duration               `toml:"polling_interval"`
+	Port                    int                    `toml:"port"`
+	SkipDirectories         []string               `toml:"skip_directories"`
+	StateDirectory          string                 `toml:"state_directory"`
+	StreamURL               string                 `toml:"stream_url"`
+	TrustedProxies          []string               `toml:"trusted_proxies"`
+	UID                     int                    `toml:"uid"`
+	Umask                   string                 `toml:"umask"`
+	Username                string                 `toml:"username"`
+	Users                   []UserConfig           `toml:"users"`
+	WebhookDownloadClient   string                 `toml:"webhook_download_client"`
+	Auth                    AuthConfig             `toml:"auth"`
+	Autoscale               AutoscaleConfig        `toml:"autoscale"`
+	Blackhole               *BlackholeConfig       `toml:"blackhole"`
+	ForwardAuth             *ForwardAuthConfig     `toml:"forward_auth"`
+	History                 HistoryConfig          `toml:"history"`
+	HTTP                    HTTPConfig             `toml:"http"`
+	Maintenance             *MaintenanceConfig     `toml:"maintenance"`
+	MQTT                    *MQTTConfig            `toml:"mqtt"`
+	Notifications           NotificationsConfig    `toml:"notifications"`
+	Unpack                  UnpackConfig           `toml:"unpack"`
+	WebDAV                  WebDAVConfig           `toml:"webdav"`
+	Rclone                  *RcloneConfig          `toml:"rclone"`
+	RSS                     *RSSConfig             `toml:"rss"`
+	TLS                     TLSConfig              `toml:"tls"`
+	Putio                   PutioConfig            `toml:"putio"`
+	Labels                  map[string]LabelConfig `toml:"labels"`
+	Sonarr                  *ArrConfig             `toml:"sonarr"`
+	Radarr                  *ArrConfig             `toml:"radarr"`
+	Whisparr                *ArrConfig             `toml:"whisparr"`
+}
+
+// UserConfig holds credentials for an additional RPC user
+type UserConfig struct {
+	Username string `toml:"username"`
+	Password string `toml:"password"`
+	Token    string `toml:"token"`
+	Disabled bool   `toml:"disabled"`
+}
+
+// AuthConfig controls brute-force protection for the RPC endpoint
+type AuthConfig struct {
+	// MaxFailures is the number of failed logins from one IP within Window
+	// before it is locked out. Zero disables rate limiting.
+	MaxFailures int      `toml:"max_failures"`
+	Window      Duration `toml:"window"`
+	Lockout     Duration `toml:"lockout"`
+}
+
+// BlackholeConfig holds the watch directory .torrent and .magnet files are
+// picked up from, for tools that only support a blackhole download client.
+// Interval defaults to 10 seconds.
+type BlackholeConfig struct {
+	Directory string   `toml:"directory"`
+	Interval  Duration `toml:"interval"`
+}
+
+// MaintenanceConfig holds a daily maintenance window, e.g. while the download
+// disk is backed up. During the window no new downloads start and put.io is
+// polled every PollingInterval (default 5 minutes) instead of polling_interval.
+// Start and End are local times of day ("HH:MM"); a window ending before it
+// starts spans midnight.
+type MaintenanceConfig struct {
+	Start           string   `toml:"start"`
+	End             string   `toml:"end"`
+	PollingInterval Duration `toml:"polling_interval"`
+}
+
+// ForwardAuthConfig trusts the user name an authenticating reverse proxy
+// (Authelia, Authentik, Traefik forward-auth) sets in Header on requests it
+// let through. The header is only honored on requests coming directly from
+// trusted_proxies, and only on the endpoint groups listed in Endpoints; the
+// others still need credentials. Header defaults to Remote-User.
+type ForwardAuthConfig struct {
+	Header    string   `toml:"header"`
+	Endpoints []string `toml:"endpoints"`
+}
+
+// Endpoint groups forward_auth can be enabled for.
+const (
+	EndpointRPC      = "rpc"
+	EndpointWebhooks = "webhooks"
+	EndpointAPI      = "api"
+	EndpointWebDAV   = "webdav"
+)
+
+// DefaultForwardAuthHeader is the header the proxy's user name is read from
+// when forward_auth.header is unset.
+const DefaultForwardAuthHeader = "Remote-User"
+
+// HeaderName returns the header the proxy sets the user name in.
+func (f *ForwardAuthConfig) HeaderName() string {
+	if f.Header == "" {
+		return DefaultForwardAuthHeader
+	}
+	return f.Header
+}
+
+// Allows reports whether the endpoint group accepts the proxy's header.
+func (f *ForwardAuthConfig) Allows(endpoint string) bool {
+	if f == nil {
+		return false
+	}
+	for _, e := range f.Endpoints {
+		if e == endpoint {
+			return true
+		}
+	}
+	return false
+}
+
+// RcloneConfig moves completed downloads from download_directory to an rclone
+// remote through the remote control API of an rclone instance (rclone rcd),
+// which has to see download_directory at the same path.
+type RcloneConfig struct {
+	URL      string `toml:"url"`
+	Username string `toml:"username"`
+	Password string `toml:"password"`
+	// Remote is where downloads go, e.g. "gdrive:downloads".
+	Remote string `toml:"remote"`
+}
+
+// HTTPConfig holds limits for the RPC HTTP server. Zero values disable the
+// corresponding limit. LegacySessionID hands out a fixed
+// X-Transmission-Session-Id and accepts RPC requests without it, for clients
+// that don't implement the session handshake.
+type HTTPConfig struct {
+	ReadHeaderTimeout Duration `toml:"read_header_timeout"`
+	ReadTimeout       Duration `toml:"read_timeout"`
+	WriteTimeout      Duration `toml:"write_timeout"`
+	IdleTimeout       Duration `toml:"idle_timeout"`
+	MaxBodySize       int64    `toml:"max_body_size"`
+	LegacySessionID   bool     `toml:"legacy_session_id"`
+}
+
+// TLSConfig serves the HTTP endpoints over HTTPS when CertFile and KeyFile are
+// set. With ClientCAFile, clients must present a certificate signed by that
+// CA: in addition to their credentials with ClientAuth "required" (the
+// default), or instead of them with "alternative", where requests without a
+// certificate still log in with credentials.
+type TLSConfig struct {
+	CertFile     string `toml:"cert_file"`
+	KeyFile      string `toml:"key_file"`
+	ClientCAFile string `toml:"client_ca_file"`
+	ClientAuth   string `toml:"client_auth"`
+}
+
+// Enabled reports whether the HTTP endpoints are served over HTTPS.
+func (t TLSConfig) Enabled() bool {
+	return t.CertFile != ""
+}
+
+// AutoscaleConfig controls adaptive scaling of download workers. When enabled,
+// download_workers is the initial worker count and the manager adjusts it
+// between MinWorkers and MaxWorkers based on throughput and error rate.
+type AutoscaleConfig struct {
+	Enabled    bool     `toml:"enabled"`
+	MinWorkers int      `toml:"min_workers"`
+	MaxWorkers int      `toml:"max_workers"`
+	Interval   Duration `toml:"interval"`
+}
+
+// HistoryConfig holds download history configuration. Path defaults to
+// history.jsonl in the state directory.
+type HistoryConfig struct {
+	Enabled    bool     `toml:"enabled"`
+	Path       string   `toml:"path"`
+	Retention  Duration `toml:"retention"`
+	MaxEntries int      `toml:"max_entries"`
+}
+
+// NotificationsConfig holds notification backend configuration. DailySummary
+// logs pipeline statistics once a day and sends them to the notifiers.
+type NotificationsConfig struct {
+	WebhookURL   string          `toml:"webhook_url"`
+	DailySummary bool            `toml:"daily_summary"`
+	Discord      *DiscordConfig  `toml:"discord"`
+	Telegram     *TelegramConfig `toml:"telegram"`
+	Pushover     *PushoverConfig `toml:"pushover"`
+	Apprise      *AppriseConfig  `toml:"apprise"`
+	Email        *EmailConfig    `toml:"email"`
+}
+
+// DiscordConfig holds Discord webhook notification configuration. Events
+// selects the event types to send (all if empty) and Template overrides the
+// message template.
+type DiscordConfig struct {
+	WebhookURL string   `toml:"webhook_url"`
+	Events     []string `toml:"events"`
+	Template   string   `toml:"template"`
+}
+
+// TelegramConfig holds Telegram bot notification configuration. Events and
+// Template work as in DiscordConfig.
+type TelegramConfig struct {
+	BotToken string   `toml:"bot_token"`
+	ChatID   string   `toml:"chat_id"`
+	Events   []string `toml:"events"`
+	Template string   `toml:"template"`
+}
+
+// AppriseConfig holds Apprise notification configuration. Without APIURL the
+// apprise command (Binary, default "apprise") sends to URLs; with it, an
+// Apprise API server does, either to URLs or to the configuration stored under
+// Key. Events and Template work as in DiscordConfig.
+type AppriseConfig struct {
+	URLs     []string `toml:"urls"`
+	APIURL   string   `toml:"api_url"`
+	Key      string   `toml:"key"`
+	Binary   string   `toml:"binary"`
+	Events   []string `toml:"events"`
+	Template string   `toml:"template"`
+}
+
+// EmailConfig holds SMTP notification configuration. Port defaults to 587
+// (STARTTLS); 465 uses TLS from the start. Events defaults to the failure
+// events rather than all of them; Template works as in DiscordConfig and
+// renders the body.
+type EmailConfig struct {
+	Host     string   `toml:"host"`
+	Port     int      `toml:"port"`
+	Username string   `toml:"username"`
+	Password string   `toml:"password"`
+	From     string   `toml:"from"`
+	To       []string `toml:"to"`
+	Events   []string `toml:"events"`
+	Template string   `toml:"template"`
+}
+
+// PushoverConfig holds Pushover notification configuration. Events and
+// Template work as in DiscordConfig.
+type PushoverConfig struct {
+	Token    string   `toml:"token"`
+	UserKey  string   `toml:"user_key"`
+	Events   []string `toml:"events"`
+	Template string   `toml:"template"`
+}
+
+// MQTTConfig holds MQTT broker configuration for publishing transfer state.
+// Broker is a URL such as tcp://host:1883 or, with TLS, ssl://host:8883.
+// TopicPrefix and ClientID default to "goputioarr".
+type MQTTConfig struct {
+	Broker      string `toml:"broker"`
+	Username    string `toml:"username"`
+	Password    string `toml:"password"`
+	ClientID    string `toml:"client_id"`
+	TopicPrefix string `toml:"topic_prefix"`
+}
+
+// RSSConfig holds the feeds whose matching items are added to put.io on a
+// schedule. Interval defaults to 15 minutes.
+type RSSConfig struct {
+	Interval Duration        `toml:"interval"`
+	Feeds    []RSSFeedConfig `toml:"feeds"`
+}
+
+// RSSFeedConfig is an RSS or Atom feed. An item matches if its title matches
+// any of Include, or Include is empty, and none of Exclude.
+type RSSFeedConfig struct {
+	Name    string   `toml:"name"`
+	URL     string   `toml:"url"`
+	Include []string `toml:"include"`
+	Exclude []string `toml:"exclude"`
+}
+
+// MinRSSInterval is the shortest interval feeds may be fetched at.
+const MinRSSInterval = time.Minute
+
+// UnpackConfig holds archive extraction configuration
+type UnpackConfig struct {
+	Enabled bool `toml:"enabled"`
+}
+
+// WebDAVConfig enables a read-only WebDAV view of the download directory at
+// /webdav, using the RPC credentials.
+type WebDAVConfig struct {
+	Enabled bool `toml:"enabled"`
 }
 
 // PutioConfig holds put.io API configuration
 type PutioConfig struct {
-	APIKey string `toml:"api_key"`
+	APIKey           string `toml:"api_key"`
+	FilesPerPage     int    `toml:"files_per_page"`
+	TransfersPerPage int    `toml:"transfers_per_page"`
+	ParentFolderID   int64  `toml:"parent_folder_id"`
+	// Timeout limits each put.io API request, UploadTimeout each torrent file
+	// upload. 0 means no limit.
+	Timeout       Duration `toml:"timeout"`
+	UploadTimeout Duration `toml:"upload_timeout"`
+	// StrictStatuses sets the error of transfers whose status the proxy
+	// doesn't know, so they stand out in the arrs' queues.
+	StrictStatuses bool `toml:"strict_statuses"`
+	// ImportFolderID is a put.io folder whose items are downloaded and handed
+	// to the arrs as if they were transfers. 0 disables folder imports.
+	ImportFolderID int64 `toml:"import_folder_id"`
+	// StallTimeout marks a transfer put.io has been downloading without
+	// progress for that long as stalled; StallAction decides what happens to
+	// it. 0 disables stall detection.
+	StallTimeout Duration `toml:"stall_timeout"`
+	StallAction  string   `toml:"stall_action"`
+	// APIURL and UploadURL replace the base URLs of put.io's API and of
+	// torrent uploads, e.g. to go through a proxy or use an API mock. Empty
+	// means put.io's own.
+	APIURL    string `toml:"api_url"`
+	UploadURL string `toml:"upload_url"`
+}
+
+// LabelConfig holds the settings of transfers added with a Transmission label,
+// configured as [labels.<label>]
+type LabelConfig struct {
+	// Subdirectory of download_directory the transfer is downloaded to.
+	Subdirectory string `toml:"subdirectory"`
+	// SeedTime stops seeding this long after import. 0 seeds for as long as
+	// put.io does.
+	SeedTime Duration `toml:"seed_time"`
+	// SkipDirectories replaces skip_directories for the transfer, if set.
+	SkipDirectories []string `toml:"skip_directories"`
 }
 
 // ArrConfig holds sonarr/radarr/whisparr configuration
 type ArrConfig struct {
-	URL    string `toml:"url"`
-	APIKey string `toml:"api_key"`
+	URL                  string            `toml:"url"`
+	APIKey               string            `toml:"api_key"`
+	PathMappings         map[string]string `toml:"path_mappings"`
+	MatchImportedPath    bool              `toml:"match_imported_path"`
+	CaseInsensitivePaths bool              `toml:"case_insensitive_paths"`
+	DeleteRemoteFiles    *bool             `toml:"delete_remote_files"`
+	APIVersion           string            `toml:"api_version"`
+	HistoryPath          string            `toml:"history_path"`
+	Timeout              Duration          `toml:"timeout"`
+	MaxRetries           int               `toml:"max_retries"`
+	Backoff              Duration          `toml:"backoff"`
 }
 
 // DefaultConfig returns a Config with default values
 func DefaultConfig() *Config {
 	return &Config{
-		BindAddress:          "0.0.0.0",
-		DownloadWorkers:      4,
-		OrchestrationWorkers: 10,
-		Loglevel:             "info",
-		PollingInterval:      10,
-		Port:                 9091,
-		UID:                  1000,
-		SkipDirectories:      []string{"sample", "extras"},
+		BindAddress:             "0.0.0.0",
+		DownloadMode:            DownloadModeDownload,
+		DownloadWorkers:         4,
+		DownloadRetries:         2,
+		DownloadStallTimeout:    Seconds(60),
+		DownloadConnectTimeout:  Seconds(30),
+		DownloadResponseTimeout: Seconds(60),
+		OrchestrationWorkers:    10,
+		Loglevel:                "info",
+		ImportTimeoutAction:     ImportTimeoutKeep,
+		PollingInterval:         Seconds(10),
+		Port:                    9091,
+		UID:                     1000,
+		GID:                     -1,
+		SkipDirectories:         []string{"sample", "extras"},
+		Auth: AuthConfig{
+			MaxFailures: 5,
+			Window:      Seconds(60),
+			Lockout:     Seconds(300),
+		},
+		Autoscale: AutoscaleConfig{
+			MinWorkers: 1,
+			MaxWorkers: 16,
+			Interval:   Seconds(30),
+		},
+		History: HistoryConfig{
+			Retention:  Duration(90 * 24 * time.Hour),
+			MaxEntries: 10000,
+		},
+		Putio: PutioConfig{
+			Timeout:       Seconds(10),
+			UploadTimeout: Seconds(120),
+		},
+		HTTP: HTTPConfig{
+			ReadHeaderTimeout: Seconds(10),
+			ReadTimeout:       Seconds(30),
+			WriteTimeout:      Seconds(30),
+			IdleTimeout:       Seconds(120),
+			MaxBodySize:       10 << 20,
+		},
 	}
 }
 
@@ -82,24 +493,89 @@ func Load(configPath string) (*Config, error) {
 
 	data, err := os.ReadFile(configPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+		return nil, &LoadError{Path: configPath, Err: fmt.Errorf("failed to read config file: %w", err)}
 	}
 
 	if _, err := toml.Decode(string(data), cfg); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+		return nil, &LoadError{Path: configPath, Err: fmt.Errorf("failed to parse config file: %w", err)}
+	}
+
+	if err := cfg.applyEnvironment(os.LookupEnv); err != nil {
+		return nil, &ValidationError{Err: err}
+	}
+
+	if cfg.StateDirectory == "" {
+		cfg.StateDirectory = filepath.Dir(configPath)
+	}
+	if cfg.History.Path == "" {
+		cfg.History.Path = filepath.Join(cfg.StateDirectory, "history.jsonl")
 	}
 
 	return cfg, nil
 }
 
-// Validate checks if the configuration is valid
+// applyEnvironment overrides uid, gid and umask with the PUID, PGID and UMASK
+// environment variables, following the convention of linuxserver.io
+// containers.
+func (c *Config) applyEnvironment(lookup func(string) (string, bool)) error {
+	if v, ok := lookup("PUID"); ok && v != "" {
+		uid, err := strconv.Atoi(v)
+		if err != nil || uid < 0 {
+			return fmt.Errorf("invalid PUID %q: must be a non-negative number", v)
+		}
+		c.UID = uid
+	}
+	if v, ok := lookup("PGID"); ok && v != "" {
+		gid, err := strconv.Atoi(v)
+		if err != nil || gid < 0 {
+			return fmt.Errorf("invalid PGID %q: must be a non-negative number", v)
+		}
+		c.GID = gid
+	}
+	if v, ok := lookup("UMASK"); ok && v != "" {
+		c.Umask = v
+	}
+	return nil
+}
+
+// ParseUmask parses an octal umask such as "022" or "0002"
+func ParseUmask(s string) (int, error) {
+	mask, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || mask > 0o777 {
+		return 0, fmt.Errorf("%q is not an octal umask between 000 and 777", s)
+	}
+	return int(mask), nil
+}
+
+// Validate checks if the configuration is valid. The error it returns is a
+// *ValidationError.
 func (c *Config) Validate() error {
-	if c.Username == "" {
+	if err := c.validate(); err != nil {
+		return &ValidationError{Err: err}
+	}
+	return nil
+}
+
+func (c *Config) validate() error {
+	if c.Username == "" && len(c.Users) == 0 {
 		return fmt.Errorf("username is required")
 	}
-	if c.Password == "" {
+	if c.Username != "" && c.Password == "" {
 		return fmt.Errorf("password is required")
 	}
+	seenUsers := map[string]bool{c.Username: c.Username != ""}
+	for i, user := range c.Users {
+		if user.Username == "" {
+			return fmt.Errorf("users[%d].username is required", i)
+		}
+		if user.Password == "" && user.Token == "" {
+			return fmt.Errorf("users[%d] (%s) requires a password or token", i, user.Username)
+		}
+		if seenUsers[user.Username] {
+			return fmt.Errorf("duplicate username: %s", user.Username)
+		}
+		seenUsers[user.Username] = true
+	}
 	if c.DownloadDirectory == "" {
 		return fmt.Errorf("download_directory is required")
 	}
@@ -107,7 +583,8 @@ func (c *Config) Validate() error {
 	info, err := os.Stat(c.DownloadDirectory)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return fmt.Errorf("download_directory does not exist: %s", c.DownloadDirectory)
+			return hint.Errorf("create the directory, or when running in Docker check the volume mapping",
+				"download_directory does not exist: %s", c.DownloadDirectory)
 		}
 		return fmt.Errorf("unable to stat download_directory: %w", err)
 	}
@@ -116,11 +593,120 @@ func (c *Config) Validate() error {
 	}
 	tmpFile, err := os.CreateTemp(c.DownloadDirectory, ".goputioarr-perm-*")
 	if err != nil {
-		return fmt.Errorf("download_directory is not writable: %w", err)
+		return hint.Errorf("check the directory's permissions and the user the proxy runs as",
+			"download_directory is not writable: %w", err)
 	}
 	tmpFile.Close()
 	os.Remove(tmpFile.Name())
 
+	if c.UID < 0 {
+		return fmt.Errorf("uid cannot be negative")
+	}
+	if c.MinFreeSpace < 0 {
+		return fmt.Errorf("min_free_space cannot be negative")
+	}
+	if c.DownloadStallTimeout < 0 || c.DownloadTimeout < 0 {
+		return fmt.Errorf("download_stall_timeout and download_timeout cannot be negative")
+	}
+	if c.DownloadConnectTimeout < 0 || c.DownloadResponseTimeout < 0 {
+		return fmt.Errorf("download_connect_timeout and download_response_timeout cannot be negative")
+	}
+	if c.DownloadRetries < 0 || c.DownloadRetries > MaxDownloadRetries {
+		return fmt.Errorf("download_retries must be between 0 and %d", MaxDownloadRetries)
+	}
+	if c.GID < -1 {
+		return fmt.Errorf("gid must be -1 (unchanged) or a group ID")
+	}
+	if c.Umask != "" {
+		if _, err := ParseUmask(c.Umask); err != nil {
+			return fmt.Errorf("umask is invalid: %w", err)
+		}
+	}
+
+	if c.Auth.MaxFailures < 0 {
+		return fmt.Errorf("auth.max_failures cannot be negative")
+	}
+	if c.Auth.MaxFailures > 0 && (c.Auth.Window <= 0 || c.Auth.Lockout <= 0) {
+		return fmt.Errorf("auth.window and auth.lockout must be positive when auth.max_failures is set")
+	}
+
+	if _, err := ParseNamePatterns(c.SkipDirectories); err != nil {
+		return fmt.Errorf("skip_directories is invalid: %w", err)
+	}
+	for name, label := range c.Labels {
+		if label.Subdirectory != "" && !filepath.IsLocal(label.Subdirectory) {
+			return fmt.Errorf("labels.%s.subdirectory must be a relative path within download_directory", name)
+		}
+		if label.SeedTime < 0 {
+			return fmt.Errorf("labels.%s.seed_time cannot be negative", name)
+		}
+		if _, err := ParseNamePatterns(label.SkipDirectories); err != nil {
+			return fmt.Errorf("labels.%s.skip_directories is invalid: %w", name, err)
+		}
+	}
+
+	if _, err := ParseNetworks(c.AllowedNetworks); err != nil {
+		return fmt.Errorf("allowed_networks is invalid: %w", err)
+	}
+	if _, err := ParseNetworks(c.TrustedProxies); err != nil {
+		return fmt.Errorf("trusted_proxies is invalid: %w", err)
+	}
+
+	if c.HTTP.ReadHeaderTimeout < 0 || c.HTTP.ReadTimeout < 0 || c.HTTP.WriteTimeout < 0 || c.HTTP.IdleTimeout < 0 {
+		return fmt.Errorf("http timeouts cannot be negative")
+	}
+	if c.HTTP.MaxBodySize < 0 {
+		return fmt.Errorf("http.max_body_size cannot be negative")
+	}
+
+	if err := c.TLS.validate(); err != nil {
+		return err
+	}
+
+	if c.Putio.ParentFolderID < 0 {
+		return fmt.Errorf("putio.parent_folder_id cannot be negative")
+	}
+	if c.Putio.ImportFolderID < 0 {
+		return fmt.Errorf("putio.import_folder_id cannot be negative")
+	}
+	if c.Putio.ImportFolderID != 0 && c.Putio.ImportFolderID == c.Putio.ParentFolderID {
+		return fmt.Errorf("putio.import_folder_id must differ from putio.parent_folder_id")
+	}
+	if c.Putio.FilesPerPage < 0 || c.Putio.TransfersPerPage < 0 {
+		return fmt.Errorf("putio.files_per_page and putio.transfers_per_page cannot be negative")
+	}
+	if c.Putio.Timeout < 0 || c.Putio.UploadTimeout < 0 {
+		return fmt.Errorf("putio.timeout and putio.upload_timeout cannot be negative")
+	}
+	if c.Putio.StallTimeout < 0 {
+		return fmt.Errorf("putio.stall_timeout cannot be negative")
+	}
+	switch c.Putio.StallAction {
+	case "", StallActionReport, StallActionRetry, StallActionRemove:
+	default:
+		return fmt.Errorf("putio.stall_action must be one of: %s, %s, %s",
+			StallActionReport, StallActionRetry, StallActionRemove)
+	}
+	for _, option := range []struct{ name, value string }{
+		{"api_url", c.Putio.APIURL},
+		{"upload_url", c.Putio.UploadURL},
+	} {
+		if option.value == "" {
+			continue
+		}
+		u, err := url.Parse(option.value)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("putio.%s must be an http or https URL", option.name)
+		}
+	}
+
+	if c.History.Enabled && c.History.Path == "" {
+		return fmt.Errorf("history.path is required when history is enabled")
+	}
+	if c.History.Retention < 0 || c.History.MaxEntries < 0 {
+		return fmt.Errorf("history.retention and history.max_entries cannot be negative")
+	}
+
 	if c.Port < 1 || c.Port > 65535 {
 		return fmt.Errorf("port must be between 1 and 65535")
 	}
@@ -128,8 +714,201 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("loglevel must be one of: panic, fatal, error, warn, info, debug, trace")
 	}
 
+	validateMappings := func(name string, mappings map[string]string) error {
+		for local, remote := range mappings {
+			if local == "" || remote == "" {
+				return fmt.Errorf("%s entries must map a non-empty local path to a non-empty remote path", name)
+			}
+		}
+		return nil
+	}
+	if err := validateMappings("path_mappings", c.PathMappings); err != nil {
+		return err
+	}
+
+	if c.ImportTimeout < 0 {
+		return fmt.Errorf("import_timeout cannot be negative")
+	}
+	if c.DrainTimeout < 0 {
+		return fmt.Errorf("drain_timeout cannot be negative")
+	}
+	switch c.ImportTimeoutAction {
+	case "", ImportTimeoutKeep, ImportTimeoutDeleteLocal, ImportTimeoutBlocklist:
+	default:
+		return fmt.Errorf("import_timeout_action must be one of: %s, %s, %s",
+			ImportTimeoutKeep, ImportTimeoutDeleteLocal, ImportTimeoutBlocklist)
+	}
+	switch c.DownloadMode {
+	case "", DownloadModeDownload, DownloadModeStrm:
+	default:
+		return fmt.Errorf("download_mode must be one of: %s, %s", DownloadModeDownload, DownloadModeStrm)
+	}
+	if c.Streaming() {
+		u, err := url.Parse(c.StreamURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("stream_url must be the http or https URL media servers reach the proxy at when download_mode is %q", DownloadModeStrm)
+		}
+	}
+	switch c.DownloadOrder {
+	case "", DownloadOrderDefault, DownloadOrderSmallestFirst, DownloadOrderLargestFirst:
+	default:
+		return fmt.Errorf("download_order must be one of: %s, %s, %s",
+			DownloadOrderDefault, DownloadOrderSmallestFirst, DownloadOrderLargestFirst)
+	}
+	if c.HeartbeatURL != "" {
+		if _, err := url.ParseRequestURI(c.HeartbeatURL); err != nil {
+			return fmt.Errorf("heartbeat_url is invalid: %v", err)
+		}
+	}
+
+	if c.Notifications.WebhookURL != "" {
+		if _, err := url.ParseRequestURI(c.Notifications.WebhookURL); err != nil {
+			return fmt.Errorf("notifications.webhook_url is invalid: %v", err)
+		}
+	}
+
+	if discord := c.Notifications.Discord; discord != nil {
+		if discord.WebhookURL == "" {
+			return fmt.Errorf("notifications.discord.webhook_url is required")
+		}
+		if _, err := url.ParseRequestURI(discord.WebhookURL); err != nil {
+			return fmt.Errorf("notifications.discord.webhook_url is invalid: %v", err)
+		}
+	}
+
+	if telegram := c.Notifications.Telegram; telegram != nil {
+		if telegram.BotToken == "" || telegram.ChatID == "" {
+			return fmt.Errorf("notifications.telegram requires bot_token and chat_id")
+		}
+	}
+
+	if pushover := c.Notifications.Pushover; pushover != nil {
+		if pushover.Token == "" || pushover.UserKey == "" {
+			return fmt.Errorf("notifications.pushover requires token and user_key")
+		}
+	}
+
+	if apprise := c.Notifications.Apprise; apprise != nil {
+		switch {
+		case apprise.Key != "" && apprise.APIURL == "":
+			return fmt.Errorf("notifications.apprise.key requires api_url")
+		case apprise.Key != "" && len(apprise.URLs) > 0:
+			return fmt.Errorf("notifications.apprise needs either urls or key, not both")
+		case apprise.Key == "" && len(apprise.URLs) == 0:
+			return fmt.Errorf("notifications.apprise requires urls (or api_url and key)")
+		}
+		if apprise.APIURL != "" {
+			if _, err := url.ParseRequestURI(apprise.APIURL); err != nil {
+				return fmt.Errorf("notifications.apprise.api_url is invalid: %v", err)
+			}
+		}
+	}
+
+	if email := c.Notifications.Email; email != nil {
+		if email.Host == "" || email.From == "" || len(email.To) == 0 {
+			return fmt.Errorf("notifications.email requires host, from and to")
+		}
+		if email.Port < 0 || email.Port > 65535 {
+			return fmt.Errorf("notifications.email.port must be between 1 and 65535")
+		}
+		for _, addr := range append([]string{email.From}, email.To...) {
+			if _, err := mail.ParseAddress(addr); err != nil {
+				return fmt.Errorf("notifications.email address %q is invalid: %v", addr, err)
+			}
+		}
+	}
+
+	if blackhole := c.Blackhole; blackhole != nil {
+		if blackhole.Directory == "" {
+			return fmt.Errorf("blackhole.directory is required")
+		}
+		info, err := os.Stat(blackhole.Directory)
+		if err != nil {
+			return fmt.Errorf("blackhole.directory is not accessible: %w", err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("blackhole.directory is not a directory: %s", blackhole.Directory)
+		}
+		if blackhole.Interval < 0 {
+			return fmt.Errorf("blackhole.interval cannot be negative")
+		}
+	}
+
+	if maintenance := c.Maintenance; maintenance != nil {
+		if err := maintenance.validate(); err != nil {
+			return err
+		}
+	}
+
+	if fa := c.ForwardAuth; fa != nil {
+		if len(c.TrustedProxies) == 0 {
+			return fmt.Errorf("forward_auth requires trusted_proxies so the header can't be spoofed")
+		}
+		if len(fa.Endpoints) == 0 {
+			return fmt.Errorf("forward_auth.endpoints requires at least one endpoint group")
+		}
+		for _, endpoint := range fa.Endpoints {
+			switch endpoint {
+			case EndpointRPC, EndpointWebhooks, EndpointAPI, EndpointWebDAV:
+			default:
+				return fmt.Errorf("forward_auth.endpoints must be among: rpc, webhooks, api, webdav, got %q", endpoint)
+			}
+		}
+	}
+
+	if rclone := c.Rclone; rclone != nil {
+		u, err := url.Parse(rclone.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("rclone.url must be an http or https URL")
+		}
+		if !strings.Contains(rclone.Remote, ":") {
+			return fmt.Errorf("rclone.remote must be an rclone remote such as \"gdrive:downloads\", got %q", rclone.Remote)
+		}
+	}
+
+	if rss := c.RSS; rss != nil {
+		if len(rss.Feeds) == 0 {
+			return fmt.Errorf("rss.feeds requires at least one feed")
+		}
+		if rss.Interval != 0 && rss.Interval.Duration() < MinRSSInterval {
+			return fmt.Errorf("rss.interval must be at least %s", MinRSSInterval)
+		}
+		for i, feed := range rss.Feeds {
+			u, err := url.Parse(feed.URL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("rss.feeds[%d].url must be an http or https URL", i)
+			}
+			for _, pattern := range append(append([]string{}, feed.Include...), feed.Exclude...) {
+				if _, err := regexp.Compile(pattern); err != nil {
+					return fmt.Errorf("rss.feeds[%d] has an invalid pattern %q: %v", i, pattern, err)
+				}
+			}
+		}
+	}
+
+	if mqtt := c.MQTT; mqtt != nil {
+		if mqtt.Broker == "" {
+			return fmt.Errorf("mqtt.broker is required")
+		}
+		u, err := url.Parse(mqtt.Broker)
+		if err != nil {
+			return fmt.Errorf("mqtt.broker is invalid: %v", err)
+		}
+		switch u.Scheme {
+		case "tcp", "mqtt", "ssl", "tls", "mqtts":
+		default:
+			return fmt.Errorf("mqtt.broker must use the tcp, mqtt, ssl, tls or mqtts scheme")
+		}
+		if u.Hostname() == "" {
+			return fmt.Errorf("mqtt.broker is missing a host")
+		}
+		if strings.ContainsAny(mqtt.TopicPrefix, "+#") {
+			return fmt.Errorf("mqtt.topic_prefix cannot contain wildcards")
+		}
+	}
+
 	if c.Putio.APIKey == "" {
-		return fmt.Errorf("putio.api_key is required")
+		return hint.Errorf("generate one with `goputioarr get-token`", "putio.api_key is required")
 	}
 	if c.Sonarr == nil && c.Radarr == nil && c.Whisparr == nil {
 		return fmt.Errorf("at least one of sonarr, radarr, or whisparr must be configured")
@@ -140,12 +919,28 @@ func (c *Config) Validate() error {
 			return fmt.Errorf("%s.url is required", name)
 		}
 		if _, err := url.ParseRequestURI(cfg.URL); err != nil {
-			return fmt.Errorf("%s.url is invalid: %v", name, err)
+			return hint.Errorf("use the full address including the scheme, e.g. http://localhost:8989",
+				"%s.url is invalid: %v", name, err)
 		}
 		if cfg.APIKey == "" {
-			return fmt.Errorf("%s.api_key is required", name)
+			return hint.Errorf("find it under Settings > General in the service's web UI", "%s.api_key is required", name)
 		}
-		return nil
+		if cfg.APIVersion != "" && !apiVersionPattern.MatchString(cfg.APIVersion) {
+			return fmt.Errorf("%s.api_version must look like v3, got %q", name, cfg.APIVersion)
+		}
+		if cfg.HistoryPath != "" && (!strings.HasPrefix(cfg.HistoryPath, "/") || strings.Contains(cfg.HistoryPath, "?")) {
+			return fmt.Errorf("%s.history_path must be an absolute path without a query, got %q", name, cfg.HistoryPath)
+		}
+		if cfg.Timeout < 0 {
+			return fmt.Errorf("%s.timeout cannot be negative", name)
+		}
+		if cfg.MaxRetries < 0 || cfg.MaxRetries > MaxArrRetries {
+			return fmt.Errorf("%s.max_retries must be between 0 and %d", name, MaxArrRetries)
+		}
+		if cfg.Backoff < 0 {
+			return fmt.Errorf("%s.backoff cannot be negative", name)
+		}
+		return validateMappings(name+".path_mappings", cfg.PathMappings)
 	}
 
 	if c.Sonarr != nil {
@@ -164,7 +959,7 @@ func (c *Config) Validate() error {
 		}
 	}
 
-	if c.PollingInterval < MinPollingInterval || c.PollingInterval > MaxPollingInterval {
+	if c.PollingInterval < Seconds(MinPollingInterval) || c.PollingInterval > Seconds(MaxPollingInterval) {
 		return fmt.Errorf("polling_interval must be between %d and %d seconds", MinPollingInterval, MaxPollingInterval)
 	}
 	if c.DownloadWorkers < MinDownloadWorkers || c.DownloadWorkers > MaxDownloadWorkers {
@@ -173,10 +968,263 @@ func (c *Config) Validate() error {
 	if c.OrchestrationWorkers < MinOrchestrationWorkers || c.OrchestrationWorkers > MaxOrchestrationWorkers {
 		return fmt.Errorf("orchestration_workers must be between %d and %d", MinOrchestrationWorkers, MaxOrchestrationWorkers)
 	}
+	if c.MaxActiveTransfers < 0 {
+		return fmt.Errorf("max_active_transfers cannot be negative")
+	}
+	if c.Autoscale.Enabled {
+		if err := c.Autoscale.validate(c.DownloadWorkers); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
 
+// Remaining returns how much of the maintenance window is left at now, or 0
+// outside the window. It's safe to call on a nil config, which has no window.
+func (m *MaintenanceConfig) Remaining(now time.Time) time.Duration {
+	if m == nil {
+		return 0
+	}
+	// Validated with the configuration; an invalid window is never on.
+	start, err := ParseClock(m.Start)
+	if err != nil {
+		return 0
+	}
+	end, err := ParseClock(m.End)
+	if err != nil {
+		return 0
+	}
+
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	since := now.Sub(midnight)
+	switch {
+	case start < end:
+		if since >= start && since < end {
+			return end - since
+		}
+	case since >= start:
+		return 24*time.Hour - since + end
+	case since < end:
+		return end - since
+	}
+	return 0
+}
+
+func (m *MaintenanceConfig) validate() error {
+	start, err := ParseClock(m.Start)
+	if err != nil {
+		return fmt.Errorf("maintenance.start is invalid: %w", err)
+	}
+	end, err := ParseClock(m.End)
+	if err != nil {
+		return fmt.Errorf("maintenance.end is invalid: %w", err)
+	}
+	if start == end {
+		return fmt.Errorf("maintenance.start and maintenance.end must differ")
+	}
+	if m.PollingInterval != 0 && m.PollingInterval < Seconds(MinPollingInterval) {
+		return fmt.Errorf("maintenance.polling_interval must be at least %d seconds", MinPollingInterval)
+	}
+	return nil
+}
+
+func (a AutoscaleConfig) validate(downloadWorkers int) error {
+	if a.MinWorkers < MinDownloadWorkers || a.MinWorkers > MaxDownloadWorkers {
+		return fmt.Errorf("autoscale.min_workers must be between %d and %d", MinDownloadWorkers, MaxDownloadWorkers)
+	}
+	if a.MaxWorkers < a.MinWorkers || a.MaxWorkers > MaxDownloadWorkers {
+		return fmt.Errorf("autoscale.max_workers must be between min_workers and %d", MaxDownloadWorkers)
+	}
+	if downloadWorkers < a.MinWorkers || downloadWorkers > a.MaxWorkers {
+		return fmt.Errorf("download_workers must be between autoscale.min_workers and autoscale.max_workers")
+	}
+	if a.Interval < Seconds(1) {
+		return fmt.Errorf("autoscale.interval must be at least 1s")
+	}
+	return nil
+}
+
+// ParseNetworks parses a list of CIDR ranges. Bare IP addresses are treated as
+// single-host networks.
+func ParseNetworks(entries []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(entries))
+	for _, entry := range entries {
+		if ip := net.ParseIP(entry); ip != nil {
+			bits := 32
+			if ip.To4() == nil {
+				bits = 128
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network %q", entry)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+func (t TLSConfig) validate() error {
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		return fmt.Errorf("tls.cert_file and tls.key_file must be set together")
+	}
+	if t.ClientCAFile != "" && !t.Enabled() {
+		return fmt.Errorf("tls.client_ca_file requires tls.cert_file and tls.key_file")
+	}
+	files := []struct{ key, path string }{
+		{"tls.cert_file", t.CertFile},
+		{"tls.key_file", t.KeyFile},
+		{"tls.client_ca_file", t.ClientCAFile},
+	}
+	for _, file := range files {
+		if file.path == "" {
+			continue
+		}
+		if _, err := os.Stat(file.path); err != nil {
+			return fmt.Errorf("%s is not accessible: %w", file.key, err)
+		}
+	}
+	switch t.ClientAuth {
+	case "", ClientAuthRequired, ClientAuthAlternative:
+	default:
+		return fmt.Errorf("tls.client_auth must be one of: %s, %s", ClientAuthRequired, ClientAuthAlternative)
+	}
+	if t.ClientAuth != "" && t.ClientCAFile == "" {
+		return fmt.Errorf("tls.client_auth requires tls.client_ca_file")
+	}
+	return nil
+}
+
+// Credentials returns every enabled set of RPC credentials, including the
+// top-level username/password when configured.
+func (c *Config) Credentials() []UserConfig {
+	var users []UserConfig
+	if c.Username != "" {
+		users = append(users, UserConfig{Username: c.Username, Password: c.Password})
+	}
+	for _, user := range c.Users {
+		if !user.Disabled {
+			users = append(users, user)
+		}
+	}
+	return users
+}
+
+// ArrConfigByName returns the configuration of the named arr service
+// (case-insensitive), or nil if it isn't configured.
+func (c *Config) ArrConfigByName(name string) *ArrConfig {
+	switch strings.ToLower(name) {
+	case "sonarr":
+		return c.Sonarr
+	case "radarr":
+		return c.Radarr
+	case "whisparr":
+		return c.Whisparr
+	}
+	return nil
+}
+
+// ShouldDeleteLocalAfterImport reports whether local files should be removed
+// once a transfer has been imported. Defaults to true.
+func (c *Config) ShouldDeleteLocalAfterImport() bool {
+	return c.DeleteLocalAfterImport == nil || *c.DeleteLocalAfterImport
+}
+
+// Streaming reports whether video files are linked to with STRM files instead
+// of being downloaded.
+func (c *Config) Streaming() bool {
+	return c.DownloadMode == DownloadModeStrm
+}
+
+// ShouldDeleteRemoteFiles reports whether put.io files should be deleted once
+// a transfer imported by the named arr service is done seeding. The service's
+// delete_remote_files setting takes precedence over the global one; both
+// default to true. They are never deleted when streaming, as the imported STRM
+// files point at them.
+func (c *Config) ShouldDeleteRemoteFiles(arrName string) bool {
+	if c.Streaming() {
+		return false
+	}
+	if arrCfg := c.ArrConfigByName(arrName); arrCfg != nil && arrCfg.DeleteRemoteFiles != nil {
+		return *arrCfg.DeleteRemoteFiles
+	}
+	if c.DeleteRemoteFiles != nil {
+		return *c.DeleteRemoteFiles
+	}
+	return true
+}
+
+// OwnedTransfersPath returns the file used to remember the transfers the
+// proxy added, or an empty string to keep them in memory only.
+func (c *Config) OwnedTransfersPath() string {
+	if c.StateDirectory == "" {
+		return ""
+	}
+	return filepath.Join(c.StateDirectory, "owned_transfers.json")
+}
+
+// LabelFor returns the settings of the first of labels configured in [labels],
+// compared case-insensitively, and its name as configured.
+func (c *Config) LabelFor(labels []string) (string, LabelConfig, bool) {
+	for _, label := range labels {
+		for name, settings := range c.Labels {
+			if strings.EqualFold(name, label) {
+				return name, settings, true
+			}
+		}
+	}
+	return "", LabelConfig{}, false
+}
+
+// TransferLabelsPath returns the file used to remember the labels transfers
+// were added with, or an empty string to keep them in memory only.
+func (c *Config) TransferLabelsPath() string {
+	if c.StateDirectory == "" {
+		return ""
+	}
+	return filepath.Join(c.StateDirectory, "transfer_labels.json")
+}
+
+// TransferLocationsPath returns the file used to remember the download
+// directories set with torrent-set-location, or an empty string to keep them
+// in memory only.
+func (c *Config) TransferLocationsPath() string {
+	if c.StateDirectory == "" {
+		return ""
+	}
+	return filepath.Join(c.StateDirectory, "transfer_locations.json")
+}
+
+// TransferStagesPath returns the file used to remember the pipeline stage
+// each transfer reached, or an empty string to keep them in memory only.
+func (c *Config) TransferStagesPath() string {
+	if c.StateDirectory == "" {
+		return ""
+	}
+	return filepath.Join(c.StateDirectory, "transfer_stages.json")
+}
+
+// HeldTransfersPath returns the file used to remember the transfers added
+// paused or stopped, or an empty string to keep them in memory only.
+func (c *Config) HeldTransfersPath() string {
+	if c.StateDirectory == "" {
+		return ""
+	}
+	return filepath.Join(c.StateDirectory, "held_transfers.json")
+}
+
+// RSSSeenPath returns the file used to remember the feed items already added,
+// or an empty string to keep them in memory only.
+func (c *Config) RSSSeenPath() string {
+	if c.StateDirectory == "" {
+		return ""
+	}
+	return filepath.Join(c.StateDirectory, "rss_seen.json")
+}
+
 // GetArrConfigs returns a list of configured arr services
 func (c *Config) GetArrConfigs() []struct {
 	Name   string
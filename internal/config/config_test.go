@@ -1,11 +1,15 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/ochronus/goputioarr/internal/hint"
 )
 
 func TestDefaultConfig(t *testing.T) {
@@ -23,12 +27,18 @@ func TestDefaultConfig(t *testing.T) {
 	if cfg.Loglevel != "info" {
 		t.Errorf("expected Loglevel to be 'info', got '%s'", cfg.Loglevel)
 	}
-	if cfg.PollingInterval != 10 {
-		t.Errorf("expected PollingInterval to be 10, got %d", cfg.PollingInterval)
+	if cfg.PollingInterval != Seconds(10) {
+		t.Errorf("expected PollingInterval to be 10s, got %s", cfg.PollingInterval)
 	}
 	if cfg.Port != 9091 {
 		t.Errorf("expected Port to be 9091, got %d", cfg.Port)
 	}
+	if cfg.Putio.Timeout != Seconds(10) || cfg.Putio.UploadTimeout != Seconds(120) {
+		t.Errorf("unexpected put.io timeouts %s and %s", cfg.Putio.Timeout, cfg.Putio.UploadTimeout)
+	}
+	if cfg.DownloadConnectTimeout != Seconds(30) || cfg.DownloadResponseTimeout != Seconds(60) {
+		t.Errorf("unexpected download timeouts %s and %s", cfg.DownloadConnectTimeout, cfg.DownloadResponseTimeout)
+	}
 	if cfg.UID != 1000 {
 		t.Errorf("expected UID to be 1000, got %d", cfg.UID)
 	}
@@ -38,6 +48,12 @@ func TestDefaultConfig(t *testing.T) {
 	if cfg.SkipDirectories[0] != "sample" || cfg.SkipDirectories[1] != "extras" {
 		t.Errorf("unexpected SkipDirectories: %v", cfg.SkipDirectories)
 	}
+	if cfg.HTTP.MaxBodySize != 10<<20 || cfg.HTTP.ReadTimeout != Seconds(30) {
+		t.Errorf("unexpected HTTP defaults: %+v", cfg.HTTP)
+	}
+	if cfg.Auth.MaxFailures != 5 || cfg.Auth.Window != Seconds(60) || cfg.Auth.Lockout != Seconds(300) {
+		t.Errorf("unexpected Auth defaults: %+v", cfg.Auth)
+	}
 }
 
 func TestDefaultConfigPath(t *testing.T) {
@@ -114,8 +130,8 @@ api_key = "radarr-key"
 	if cfg.UID != 500 {
 		t.Errorf("expected UID 500, got %d", cfg.UID)
 	}
-	if cfg.PollingInterval != 5 {
-		t.Errorf("expected PollingInterval 5, got %d", cfg.PollingInterval)
+	if cfg.PollingInterval != Seconds(5) {
+		t.Errorf("expected PollingInterval 5s, got %s", cfg.PollingInterval)
 	}
 	if cfg.OrchestrationWorkers != 5 {
 		t.Errorf("expected OrchestrationWorkers 5, got %d", cfg.OrchestrationWorkers)
@@ -141,12 +157,18 @@ api_key = "radarr-key"
 	if cfg.Radarr.URL != "http://localhost:7878" {
 		t.Errorf("expected Radarr.URL 'http://localhost:7878', got '%s'", cfg.Radarr.URL)
 	}
+	if expected := filepath.Join(tmpDir, "history.jsonl"); cfg.History.Path != expected {
+		t.Errorf("expected History.Path '%s', got '%s'", expected, cfg.History.Path)
+	}
 }
 
 func TestLoadNonExistentFile(t *testing.T) {
 	_, err := Load("/nonexistent/path/config.toml")
 	if err == nil {
-		t.Error("expected error for non-existent file")
+		t.Fatal("expected error for non-existent file")
+	}
+	if got := hint.Of(err); !strings.Contains(got, "generate-config") {
+		t.Errorf("hint = %q, want one pointing at generate-config", got)
 	}
 }
 
@@ -165,7 +187,10 @@ password = incomplete
 
 	_, err = Load(configPath)
 	if err == nil {
-		t.Error("expected error for invalid TOML")
+		t.Fatal("expected error for invalid TOML")
+	}
+	if got := hint.Of(err); !strings.Contains(got, "TOML syntax") {
+		t.Errorf("hint = %q, want one about the TOML syntax", got)
 	}
 }
 
@@ -250,255 +275,1442 @@ func TestConfigValidate(t *testing.T) {
 			errMsg:  "password is required",
 		},
 		{
-			name: "missing download_directory",
+			name: "users list without top-level username",
 			build: func() *Config {
 				cfg := baseValid()
-				cfg.DownloadDirectory = ""
+				cfg.Username = ""
+				cfg.Password = ""
+				cfg.Users = []UserConfig{{Username: "sonarr", Password: "pass"}}
+				return cfg
+			},
+			wantErr: false,
+		},
+		{
+			name: "user missing username",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.Users = []UserConfig{{Password: "pass"}}
 				return cfg
 			},
 			wantErr: true,
-			errMsg:  "download_directory is required",
+			errMsg:  "users[0].username is required",
 		},
 		{
-			name: "missing putio api_key",
+			name: "user missing password and token",
 			build: func() *Config {
 				cfg := baseValid()
-				cfg.Putio = PutioConfig{}
+				cfg.Users = []UserConfig{{Username: "radarr"}}
 				return cfg
 			},
 			wantErr: true,
-			errMsg:  "putio.api_key is required",
+			errMsg:  "users[0] (radarr) requires a password or token",
 		},
 		{
-			name: "no arr configured",
+			name: "duplicate username",
 			build: func() *Config {
 				cfg := baseValid()
-				cfg.Sonarr = nil
+				cfg.Users = []UserConfig{{Username: "user", Token: "abc"}}
 				return cfg
 			},
 			wantErr: true,
-			errMsg:  "at least one of sonarr, radarr, or whisparr must be configured",
+			errMsg:  "duplicate username: user",
 		},
 		{
-			name: "download_directory does not exist",
+			name: "negative auth max_failures",
 			build: func() *Config {
 				cfg := baseValid()
-				cfg.DownloadDirectory = filepath.Join(validDir, "missing")
+				cfg.Auth.MaxFailures = -1
 				return cfg
 			},
 			wantErr: true,
-			errMsg:  fmt.Sprintf("download_directory does not exist: %s", filepath.Join(validDir, "missing")),
+			errMsg:  "auth.max_failures cannot be negative",
 		},
 		{
-			name: "download_directory not a directory",
+			name: "auth lockout missing",
 			build: func() *Config {
 				cfg := baseValid()
-				cfg.DownloadDirectory = fileAsDir
+				cfg.Auth.Lockout = 0
 				return cfg
 			},
 			wantErr: true,
-			errMsg:  fmt.Sprintf("download_directory is not a directory: %s", fileAsDir),
+			errMsg:  "auth.window and auth.lockout must be positive when auth.max_failures is set",
 		},
 		{
-			name: "download_directory not writable",
+			name: "valid allowed_networks",
 			build: func() *Config {
 				cfg := baseValid()
-				cfg.DownloadDirectory = nonWritableDir
+				cfg.AllowedNetworks = []string{"192.168.1.0/24", "10.0.0.1", "fd00::/8"}
 				return cfg
 			},
-			wantErr:     true,
-			errMsg:      "download_directory is not writable",
-			errContains: true,
+			wantErr: false,
 		},
 		{
-			name: "polling_interval too low",
+			name: "invalid allowed_networks",
 			build: func() *Config {
 				cfg := baseValid()
-				cfg.PollingInterval = MinPollingInterval - 1
+				cfg.AllowedNetworks = []string{"192.168.1.0/33"}
 				return cfg
 			},
 			wantErr: true,
-			errMsg:  fmt.Sprintf("polling_interval must be between %d and %d seconds", MinPollingInterval, MaxPollingInterval),
+			errMsg:  `allowed_networks is invalid: invalid network "192.168.1.0/33"`,
 		},
 		{
-			name: "polling_interval too high",
+			name: "invalid trusted_proxies",
 			build: func() *Config {
 				cfg := baseValid()
-				cfg.PollingInterval = MaxPollingInterval + 1
+				cfg.TrustedProxies = []string{"proxy"}
 				return cfg
 			},
 			wantErr: true,
-			errMsg:  fmt.Sprintf("polling_interval must be between %d and %d seconds", MinPollingInterval, MaxPollingInterval),
+			errMsg:  `trusted_proxies is invalid: invalid network "proxy"`,
 		},
 		{
-			name: "download_workers too low",
+			name: "negative http timeout",
 			build: func() *Config {
 				cfg := baseValid()
-				cfg.DownloadWorkers = MinDownloadWorkers - 1
+				cfg.HTTP.WriteTimeout = -1
 				return cfg
 			},
 			wantErr: true,
-			errMsg:  fmt.Sprintf("download_workers must be between %d and %d", MinDownloadWorkers, MaxDownloadWorkers),
+			errMsg:  "http timeouts cannot be negative",
 		},
 		{
-			name: "download_workers too high",
+			name: "negative max_body_size",
 			build: func() *Config {
 				cfg := baseValid()
-				cfg.DownloadWorkers = MaxDownloadWorkers + 1
+				cfg.HTTP.MaxBodySize = -1
 				return cfg
 			},
 			wantErr: true,
-			errMsg:  fmt.Sprintf("download_workers must be between %d and %d", MinDownloadWorkers, MaxDownloadWorkers),
+			errMsg:  "http.max_body_size cannot be negative",
 		},
 		{
-			name: "orchestration_workers too low",
+			name: "negative import_folder_id",
 			build: func() *Config {
 				cfg := baseValid()
-				cfg.OrchestrationWorkers = MinOrchestrationWorkers - 1
+				cfg.Putio.ImportFolderID = -1
 				return cfg
 			},
 			wantErr: true,
-			errMsg:  fmt.Sprintf("orchestration_workers must be between %d and %d", MinOrchestrationWorkers, MaxOrchestrationWorkers),
+			errMsg:  "putio.import_folder_id cannot be negative",
 		},
 		{
-			name: "orchestration_workers too high",
+			name: "import_folder_id is the parent folder",
 			build: func() *Config {
 				cfg := baseValid()
-				cfg.OrchestrationWorkers = MaxOrchestrationWorkers + 1
+				cfg.Putio.ParentFolderID = 42
+				cfg.Putio.ImportFolderID = 42
 				return cfg
 			},
 			wantErr: true,
-			errMsg:  fmt.Sprintf("orchestration_workers must be between %d and %d", MinOrchestrationWorkers, MaxOrchestrationWorkers),
+			errMsg:  "putio.import_folder_id must differ from putio.parent_folder_id",
 		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			cfg := tt.build()
-			err := cfg.Validate()
-			if tt.wantErr {
-				if err == nil {
-					t.Errorf("expected error containing '%s', got nil", tt.errMsg)
-				} else if tt.errContains {
-					if !strings.Contains(err.Error(), tt.errMsg) {
-						t.Errorf("expected error containing '%s', got '%s'", tt.errMsg, err.Error())
-					}
-				} else if err.Error() != tt.errMsg {
-					t.Errorf("expected error '%s', got '%s'", tt.errMsg, err.Error())
-				}
-			} else {
-				if err != nil {
-					t.Errorf("unexpected error: %v", err)
-				}
-			}
-		})
-	}
-}
-
-func TestGetArrConfigs(t *testing.T) {
-	tests := []struct {
-		name     string
-		config   *Config
-		expected int
-	}{
 		{
-			name:     "no arr configs",
-			config:   &Config{},
-			expected: 0,
+			name: "empty path mapping target",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.PathMappings = map[string]string{"/downloads": ""}
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  "path_mappings entries must map a non-empty local path to a non-empty remote path",
 		},
 		{
-			name: "only sonarr",
-			config: &Config{
-				Sonarr: &ArrConfig{URL: "http://sonarr", APIKey: "key1"},
+			name: "empty arr path mapping source",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.Sonarr.PathMappings = map[string]string{"": "/data"}
+				return cfg
 			},
-			expected: 1,
+			wantErr: true,
+			errMsg:  "sonarr.path_mappings entries must map a non-empty local path to a non-empty remote path",
 		},
 		{
-			name: "sonarr and radarr",
-			config: &Config{
-				Sonarr: &ArrConfig{URL: "http://sonarr", APIKey: "key1"},
-				Radarr: &ArrConfig{URL: "http://radarr", APIKey: "key2"},
+			name: "arr api version and history path",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.Sonarr.APIVersion = "v1"
+				cfg.Sonarr.HistoryPath = "/api/v1/history"
+				return cfg
 			},
-			expected: 2,
+			wantErr: false,
 		},
 		{
-			name: "all three",
-			config: &Config{
-				Sonarr:   &ArrConfig{URL: "http://sonarr", APIKey: "key1"},
-				Radarr:   &ArrConfig{URL: "http://radarr", APIKey: "key2"},
-				Whisparr: &ArrConfig{URL: "http://whisparr", APIKey: "key3"},
+			name: "arr timeout and retries",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.Sonarr.Timeout = Seconds(120)
+				cfg.Sonarr.MaxRetries = 5
+				cfg.Sonarr.Backoff = Duration(time.Second)
+				return cfg
 			},
-			expected: 3,
+			wantErr: false,
 		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			configs := tt.config.GetArrConfigs()
-			if len(configs) != tt.expected {
-				t.Errorf("expected %d configs, got %d", tt.expected, len(configs))
-			}
-		})
-	}
-}
-
-func TestGetArrConfigsContent(t *testing.T) {
-	cfg := &Config{
-		Sonarr:   &ArrConfig{URL: "http://sonarr:8989", APIKey: "sonarr-key"},
-		Radarr:   &ArrConfig{URL: "http://radarr:7878", APIKey: "radarr-key"},
-		Whisparr: &ArrConfig{URL: "http://whisparr:6969", APIKey: "whisparr-key"},
-	}
-
-	configs := cfg.GetArrConfigs()
-
-	// Check Sonarr
-	found := false
-	for _, c := range configs {
-		if c.Name == "Sonarr" {
-			found = true
-			if c.URL != "http://sonarr:8989" {
-				t.Errorf("expected Sonarr URL 'http://sonarr:8989', got '%s'", c.URL)
-			}
-			if c.APIKey != "sonarr-key" {
-				t.Errorf("expected Sonarr APIKey 'sonarr-key', got '%s'", c.APIKey)
-			}
-		}
-	}
-	if !found {
-		t.Error("Sonarr config not found")
-	}
-
-	// Check Radarr
-	found = false
-	for _, c := range configs {
-		if c.Name == "Radarr" {
-			found = true
-			if c.URL != "http://radarr:7878" {
-				t.Errorf("expected Radarr URL 'http://radarr:7878', got '%s'", c.URL)
-			}
-			if c.APIKey != "radarr-key" {
-				t.Errorf("expected Radarr APIKey 'radarr-key', got '%s'", c.APIKey)
-			}
-		}
-	}
-	if !found {
-		t.Error("Radarr config not found")
-	}
-
-	// Check Whisparr
-	found = false
-	for _, c := range configs {
-		if c.Name == "Whisparr" {
-			found = true
-			if c.URL != "http://whisparr:6969" {
-				t.Errorf("expected Whisparr URL 'http://whisparr:6969', got '%s'", c.URL)
-			}
-			if c.APIKey != "whisparr-key" {
-				t.Errorf("expected Whisparr APIKey 'whisparr-key', got '%s'", c.APIKey)
-			}
-		}
-	}
-	if !found {
-		t.Error("Whisparr config not found")
+		{
+			name: "negative arr timeout",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.Radarr = &ArrConfig{URL: "http://localhost", APIKey: "key", Timeout: Seconds(-1)}
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  "radarr.timeout cannot be negative",
+		},
+		{
+			name: "too many arr retries",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.Sonarr.MaxRetries = 11
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  "sonarr.max_retries must be between 0 and 10",
+		},
+		{
+			name: "negative arr backoff",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.Sonarr.Backoff = Duration(-time.Second)
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  "sonarr.backoff cannot be negative",
+		},
+		{
+			name: "invalid arr api version",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.Sonarr.APIVersion = "3"
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  `sonarr.api_version must look like v3, got "3"`,
+		},
+		{
+			name: "relative arr history path",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.Sonarr.HistoryPath = "api/v1/history?page=1"
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  `sonarr.history_path must be an absolute path without a query, got "api/v1/history?page=1"`,
+		},
+		{
+			name: "negative import_timeout",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.ImportTimeout = -1
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  "import_timeout cannot be negative",
+		},
+		{
+			name: "negative drain_timeout",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.DrainTimeout = -1
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  "drain_timeout cannot be negative",
+		},
+		{
+			name: "invalid import_timeout_action",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.ImportTimeoutAction = "explode"
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  "import_timeout_action must be one of: keep, delete_local, blocklist",
+		},
+		{
+			name: "invalid download mode",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.DownloadMode = "symlink"
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  "download_mode must be one of: download, strm",
+		},
+		{
+			name: "strm download mode",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.DownloadMode = DownloadModeStrm
+				cfg.StreamURL = "http://goputioarr:9091"
+				return cfg
+			},
+			wantErr: false,
+		},
+		{
+			name: "strm download mode without stream_url",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.DownloadMode = DownloadModeStrm
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  `stream_url must be the http or https URL media servers reach the proxy at when download_mode is "strm"`,
+		},
+		{
+			name: "invalid notifications webhook_url",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.Notifications.WebhookURL = "not a url"
+				return cfg
+			},
+			wantErr:     true,
+			errMsg:      "notifications.webhook_url is invalid",
+			errContains: true,
+		},
+		{
+			name: "discord without webhook_url",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.Notifications.Discord = &DiscordConfig{}
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  "notifications.discord.webhook_url is required",
+		},
+		{
+			name: "telegram without chat_id",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.Notifications.Telegram = &TelegramConfig{BotToken: "token"}
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  "notifications.telegram requires bot_token and chat_id",
+		},
+		{
+			name: "pushover without user_key",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.Notifications.Pushover = &PushoverConfig{Token: "app"}
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  "notifications.pushover requires token and user_key",
+		},
+		{
+			name: "apprise without urls",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.Notifications.Apprise = &AppriseConfig{}
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  "notifications.apprise requires urls (or api_url and key)",
+		},
+		{
+			name: "apprise key without api_url",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.Notifications.Apprise = &AppriseConfig{Key: "goputioarr"}
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  "notifications.apprise.key requires api_url",
+		},
+		{
+			name: "apprise api with key",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.Notifications.Apprise = &AppriseConfig{APIURL: "http://apprise:8000", Key: "goputioarr"}
+				return cfg
+			},
+			wantErr: false,
+		},
+		{
+			name: "email without recipients",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.Notifications.Email = &EmailConfig{Host: "smtp.example.com", From: "goputioarr@example.com"}
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  "notifications.email requires host, from and to",
+		},
+		{
+			name: "email with invalid address",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.Notifications.Email = &EmailConfig{Host: "smtp.example.com", From: "goputioarr@example.com", To: []string{"not an address"}}
+				return cfg
+			},
+			wantErr:     true,
+			errMsg:      `notifications.email address "not an address" is invalid`,
+			errContains: true,
+		},
+		{
+			name: "valid email",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.Notifications.Email = &EmailConfig{Host: "smtp.example.com", Port: 465, From: "goputioarr <goputioarr@example.com>", To: []string{"me@example.com"}}
+				return cfg
+			},
+			wantErr: false,
+		},
+		{
+			name: "valid blackhole",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.Blackhole = &BlackholeConfig{Directory: validDir, Interval: Seconds(5)}
+				return cfg
+			},
+			wantErr: false,
+		},
+		{
+			name: "blackhole without directory",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.Blackhole = &BlackholeConfig{}
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  "blackhole.directory is required",
+		},
+		{
+			name: "blackhole directory is a file",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.Blackhole = &BlackholeConfig{Directory: fileAsDir}
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  fmt.Sprintf("blackhole.directory is not a directory: %s", fileAsDir),
+		},
+		{
+			name: "negative blackhole interval",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.Blackhole = &BlackholeConfig{Directory: validDir, Interval: Seconds(-1)}
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  "blackhole.interval cannot be negative",
+		},
+		{
+			name: "valid tls with client certificates",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.TLS = TLSConfig{CertFile: fileAsDir, KeyFile: fileAsDir, ClientCAFile: fileAsDir, ClientAuth: ClientAuthAlternative}
+				return cfg
+			},
+			wantErr: false,
+		},
+		{
+			name: "tls certificate without key",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.TLS = TLSConfig{CertFile: fileAsDir}
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  "tls.cert_file and tls.key_file must be set together",
+		},
+		{
+			name: "tls client ca without certificate",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.TLS = TLSConfig{ClientCAFile: fileAsDir}
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  "tls.client_ca_file requires tls.cert_file and tls.key_file",
+		},
+		{
+			name: "tls missing key file",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.TLS = TLSConfig{CertFile: fileAsDir, KeyFile: filepath.Join(validDir, "missing.key")}
+				return cfg
+			},
+			wantErr:     true,
+			errMsg:      "tls.key_file is not accessible",
+			errContains: true,
+		},
+		{
+			name: "invalid tls client auth",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.TLS = TLSConfig{CertFile: fileAsDir, KeyFile: fileAsDir, ClientCAFile: fileAsDir, ClientAuth: "optional"}
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  "tls.client_auth must be one of: required, alternative",
+		},
+		{
+			name: "tls client auth without client ca",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.TLS = TLSConfig{CertFile: fileAsDir, KeyFile: fileAsDir, ClientAuth: ClientAuthRequired}
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  "tls.client_auth requires tls.client_ca_file",
+		},
+		{
+			name: "valid rclone",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.Rclone = &RcloneConfig{URL: "http://localhost:5572", Remote: "gdrive:downloads"}
+				return cfg
+			},
+			wantErr: false,
+		},
+		{
+			name: "rclone without url",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.Rclone = &RcloneConfig{Remote: "gdrive:downloads"}
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  "rclone.url must be an http or https URL",
+		},
+		{
+			name: "rclone remote is not a remote",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.Rclone = &RcloneConfig{URL: "http://localhost:5572", Remote: "downloads"}
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  `rclone.remote must be an rclone remote such as "gdrive:downloads", got "downloads"`,
+		},
+		{
+			name: "valid forward auth",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.TrustedProxies = []string{"172.17.0.1"}
+				cfg.ForwardAuth = &ForwardAuthConfig{Endpoints: []string{EndpointAPI, EndpointWebDAV}}
+				return cfg
+			},
+			wantErr: false,
+		},
+		{
+			name: "forward auth without trusted proxies",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.ForwardAuth = &ForwardAuthConfig{Endpoints: []string{EndpointRPC}}
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  "forward_auth requires trusted_proxies so the header can't be spoofed",
+		},
+		{
+			name: "forward auth without endpoints",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.TrustedProxies = []string{"172.17.0.1"}
+				cfg.ForwardAuth = &ForwardAuthConfig{}
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  "forward_auth.endpoints requires at least one endpoint group",
+		},
+		{
+			name: "forward auth unknown endpoint",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.TrustedProxies = []string{"172.17.0.1"}
+				cfg.ForwardAuth = &ForwardAuthConfig{Endpoints: []string{"stats"}}
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  `forward_auth.endpoints must be among: rpc, webhooks, api, webdav, got "stats"`,
+		},
+		{
+			name: "valid rss",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.RSS = &RSSConfig{Interval: Seconds(600), Feeds: []RSSFeedConfig{
+					{URL: "https://indexer/api?t=search", Include: []string{"(?i)some show"}, Exclude: []string{"CAM"}},
+				}}
+				return cfg
+			},
+			wantErr: false,
+		},
+		{
+			name: "rss without feeds",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.RSS = &RSSConfig{}
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  "rss.feeds requires at least one feed",
+		},
+		{
+			name: "rss interval too short",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.RSS = &RSSConfig{Interval: Seconds(10), Feeds: []RSSFeedConfig{{URL: "https://indexer/rss"}}}
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  "rss.interval must be at least 1m0s",
+		},
+		{
+			name: "rss feed without http url",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.RSS = &RSSConfig{Feeds: []RSSFeedConfig{{URL: "indexer/rss"}}}
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  "rss.feeds[0].url must be an http or https URL",
+		},
+		{
+			name: "rss invalid pattern",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.RSS = &RSSConfig{Feeds: []RSSFeedConfig{{URL: "https://indexer/rss", Exclude: []string{"("}}}}
+				return cfg
+			},
+			wantErr:     true,
+			errMsg:      "rss.feeds[0] has an invalid pattern",
+			errContains: true,
+		},
+		{
+			name: "mqtt without broker",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.MQTT = &MQTTConfig{}
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  "mqtt.broker is required",
+		},
+		{
+			name: "mqtt broker with http scheme",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.MQTT = &MQTTConfig{Broker: "http://broker:1883"}
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  "mqtt.broker must use the tcp, mqtt, ssl, tls or mqtts scheme",
+		},
+		{
+			name: "mqtt topic prefix with wildcard",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.MQTT = &MQTTConfig{Broker: "tcp://broker:1883", TopicPrefix: "home/#"}
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  "mqtt.topic_prefix cannot contain wildcards",
+		},
+		{
+			name: "valid mqtt",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.MQTT = &MQTTConfig{Broker: "ssl://broker", TopicPrefix: "home/putio"}
+				return cfg
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid heartbeat_url",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.HeartbeatURL = "not a url"
+				return cfg
+			},
+			wantErr:     true,
+			errMsg:      "heartbeat_url is invalid",
+			errContains: true,
+		},
+		{
+			name: "missing download_directory",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.DownloadDirectory = ""
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  "download_directory is required",
+		},
+		{
+			name: "missing putio api_key",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.Putio = PutioConfig{}
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  "putio.api_key is required",
+		},
+		{
+			name: "no arr configured",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.Sonarr = nil
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  "at least one of sonarr, radarr, or whisparr must be configured",
+		},
+		{
+			name: "download_directory does not exist",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.DownloadDirectory = filepath.Join(validDir, "missing")
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  fmt.Sprintf("download_directory does not exist: %s", filepath.Join(validDir, "missing")),
+		},
+		{
+			name: "download_directory not a directory",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.DownloadDirectory = fileAsDir
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  fmt.Sprintf("download_directory is not a directory: %s", fileAsDir),
+		},
+		{
+			name: "download_directory not writable",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.DownloadDirectory = nonWritableDir
+				return cfg
+			},
+			wantErr:     true,
+			errMsg:      "download_directory is not writable",
+			errContains: true,
+		},
+		{
+			name: "polling_interval too low",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.PollingInterval = Seconds(MinPollingInterval - 1)
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  fmt.Sprintf("polling_interval must be between %d and %d seconds", MinPollingInterval, MaxPollingInterval),
+		},
+		{
+			name: "polling_interval too high",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.PollingInterval = Seconds(MaxPollingInterval + 1)
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  fmt.Sprintf("polling_interval must be between %d and %d seconds", MinPollingInterval, MaxPollingInterval),
+		},
+		{
+			name: "download_workers too low",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.DownloadWorkers = MinDownloadWorkers - 1
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  fmt.Sprintf("download_workers must be between %d and %d", MinDownloadWorkers, MaxDownloadWorkers),
+		},
+		{
+			name: "download_workers too high",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.DownloadWorkers = MaxDownloadWorkers + 1
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  fmt.Sprintf("download_workers must be between %d and %d", MinDownloadWorkers, MaxDownloadWorkers),
+		},
+		{
+			name: "orchestration_workers too low",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.OrchestrationWorkers = MinOrchestrationWorkers - 1
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  fmt.Sprintf("orchestration_workers must be between %d and %d", MinOrchestrationWorkers, MaxOrchestrationWorkers),
+		},
+		{
+			name: "orchestration_workers too high",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.OrchestrationWorkers = MaxOrchestrationWorkers + 1
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  fmt.Sprintf("orchestration_workers must be between %d and %d", MinOrchestrationWorkers, MaxOrchestrationWorkers),
+		},
+		{
+			name: "autoscale valid",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.Autoscale.Enabled = true
+				return cfg
+			},
+		},
+		{
+			name: "autoscale max below min",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.Autoscale.Enabled = true
+				cfg.Autoscale.MinWorkers = 4
+				cfg.Autoscale.MaxWorkers = 2
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  fmt.Sprintf("autoscale.max_workers must be between min_workers and %d", MaxDownloadWorkers),
+		},
+		{
+			name: "autoscale download_workers outside bounds",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.Autoscale.Enabled = true
+				cfg.Autoscale.MaxWorkers = cfg.DownloadWorkers - 1
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  "download_workers must be between autoscale.min_workers and autoscale.max_workers",
+		},
+		{
+			name: "autoscale interval too short",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.Autoscale.Enabled = true
+				cfg.Autoscale.Interval = 0
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  "autoscale.interval must be at least 1s",
+		},
+		{
+			name: "negative download stall timeout",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.DownloadStallTimeout = -1
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  "download_stall_timeout and download_timeout cannot be negative",
+		},
+		{
+			name: "invalid skip directory pattern",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.SkipDirectories = []string{"regex:("}
+				return cfg
+			},
+			wantErr:     true,
+			errMsg:      "skip_directories is invalid",
+			errContains: true,
+		},
+		{
+			name: "label subdirectory outside download directory",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.Labels = map[string]LabelConfig{"tv": {Subdirectory: "../tv"}}
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  "labels.tv.subdirectory must be a relative path within download_directory",
+		},
+		{
+			name: "negative label seed time",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.Labels = map[string]LabelConfig{"tv": {SeedTime: -1}}
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  "labels.tv.seed_time cannot be negative",
+		},
+		{
+			name: "invalid label skip directory pattern",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.Labels = map[string]LabelConfig{"tv": {SkipDirectories: []string{"regex:("}}}
+				return cfg
+			},
+			wantErr:     true,
+			errMsg:      "labels.tv.skip_directories is invalid",
+			errContains: true,
+		},
+		{
+			name: "maintenance window",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.Maintenance = &MaintenanceConfig{Start: "23:30", End: "01:00"}
+				return cfg
+			},
+		},
+		{
+			name: "invalid maintenance start",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.Maintenance = &MaintenanceConfig{Start: "2am", End: "04:00"}
+				return cfg
+			},
+			wantErr:     true,
+			errMsg:      "maintenance.start is invalid",
+			errContains: true,
+		},
+		{
+			name: "empty maintenance window",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.Maintenance = &MaintenanceConfig{Start: "02:00", End: "02:00"}
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  "maintenance.start and maintenance.end must differ",
+		},
+		{
+			name: "maintenance polling interval too short",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.Maintenance = &MaintenanceConfig{Start: "02:00", End: "04:00", PollingInterval: Duration(time.Millisecond)}
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  "maintenance.polling_interval must be at least 1 seconds",
+		},
+		{
+			name: "negative putio stall timeout",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.Putio.StallTimeout = -1
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  "putio.stall_timeout cannot be negative",
+		},
+		{
+			name: "invalid putio stall action",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.Putio.StallAction = "ignore"
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  "putio.stall_action must be one of: report, retry, remove",
+		},
+		{
+			name: "invalid putio api_url",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.Putio.APIURL = "api.put.io/v2"
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  "putio.api_url must be an http or https URL",
+		},
+		{
+			name: "valid putio upload_url",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.Putio.UploadURL = "http://localhost:8080/v2"
+				return cfg
+			},
+		},
+		{
+			name: "invalid download order",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.DownloadOrder = "random"
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  "download_order must be one of: default, smallest_first, largest_first",
+		},
+		{
+			name: "largest first download order",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.DownloadOrder = DownloadOrderLargestFirst
+				return cfg
+			},
+		},
+		{
+			name: "negative max active transfers",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.MaxActiveTransfers = -1
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  "max_active_transfers cannot be negative",
+		},
+		{
+			name: "negative download connect timeout",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.DownloadConnectTimeout = -1
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  "download_connect_timeout and download_response_timeout cannot be negative",
+		},
+		{
+			name: "negative putio upload timeout",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.Putio.UploadTimeout = -1
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  "putio.timeout and putio.upload_timeout cannot be negative",
+		},
+		{
+			name: "too many download retries",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.DownloadRetries = MaxDownloadRetries + 1
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  "download_retries must be between 0 and 10",
+		},
+		{
+			name: "gid and umask",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.GID = 1000
+				cfg.Umask = "002"
+				return cfg
+			},
+			wantErr: false,
+		},
+		{
+			name: "negative uid",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.UID = -1
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  "uid cannot be negative",
+		},
+		{
+			name: "invalid gid",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.GID = -2
+				return cfg
+			},
+			wantErr: true,
+			errMsg:  "gid must be -1 (unchanged) or a group ID",
+		},
+		{
+			name: "invalid umask",
+			build: func() *Config {
+				cfg := baseValid()
+				cfg.Umask = "098"
+				return cfg
+			},
+			wantErr:     true,
+			errMsg:      "umask is invalid",
+			errContains: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.build()
+			err := cfg.Validate()
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error containing '%s', got nil", tt.errMsg)
+				} else if tt.errContains {
+					if !strings.Contains(err.Error(), tt.errMsg) {
+						t.Errorf("expected error containing '%s', got '%s'", tt.errMsg, err.Error())
+					}
+				} else if err.Error() != tt.errMsg {
+					t.Errorf("expected error '%s', got '%s'", tt.errMsg, err.Error())
+				}
+			} else {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestLabelFor(t *testing.T) {
+	cfg := &Config{Labels: map[string]LabelConfig{
+		"tv-sonarr": {Subdirectory: "tv"},
+		"radarr":    {Subdirectory: "movies"},
+	}}
+
+	name, label, ok := cfg.LabelFor([]string{"other", "TV-Sonarr", "radarr"})
+	if !ok || name != "tv-sonarr" || label.Subdirectory != "tv" {
+		t.Errorf("expected the first configured label, got %q %+v %v", name, label, ok)
+	}
+	if _, _, ok := cfg.LabelFor([]string{"other"}); ok {
+		t.Error("expected no settings for unconfigured labels")
+	}
+	if _, _, ok := cfg.LabelFor(nil); ok {
+		t.Error("expected no settings without labels")
+	}
+}
+
+func TestMaintenanceRemaining(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2024, 3, 1, hour, minute, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name     string
+		window   *MaintenanceConfig
+		now      time.Time
+		expected time.Duration
+	}{
+		{name: "no window", now: at(3, 0)},
+		{name: "inside", window: &MaintenanceConfig{Start: "02:00", End: "04:00"}, now: at(3, 0), expected: time.Hour},
+		{name: "at start", window: &MaintenanceConfig{Start: "02:00", End: "04:00"}, now: at(2, 0), expected: 2 * time.Hour},
+		{name: "at end", window: &MaintenanceConfig{Start: "02:00", End: "04:00"}, now: at(4, 0)},
+		{name: "before", window: &MaintenanceConfig{Start: "02:00", End: "04:00"}, now: at(1, 0)},
+		{name: "across midnight, evening", window: &MaintenanceConfig{Start: "23:00", End: "01:00"}, now: at(23, 30), expected: 90 * time.Minute},
+		{name: "across midnight, morning", window: &MaintenanceConfig{Start: "23:00", End: "01:00"}, now: at(0, 30), expected: 30 * time.Minute},
+		{name: "across midnight, outside", window: &MaintenanceConfig{Start: "23:00", End: "01:00"}, now: at(12, 0)},
+		{name: "invalid", window: &MaintenanceConfig{Start: "late", End: "01:00"}, now: at(0, 30)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.window.Remaining(tt.now); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestApplyEnvironment(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       map[string]string
+		wantUID   int
+		wantGID   int
+		wantUmask string
+		wantErr   bool
+	}{
+		{name: "no overrides", env: map[string]string{}, wantUID: 1000, wantGID: -1},
+		{name: "empty values ignored", env: map[string]string{"PUID": "", "PGID": "", "UMASK": ""}, wantUID: 1000, wantGID: -1},
+		{name: "overrides", env: map[string]string{"PUID": "911", "PGID": "100", "UMASK": "002"}, wantUID: 911, wantGID: 100, wantUmask: "002"},
+		{name: "invalid PUID", env: map[string]string{"PUID": "abc"}, wantErr: true},
+		{name: "negative PGID", env: map[string]string{"PGID": "-5"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			err := cfg.applyEnvironment(func(key string) (string, bool) {
+				v, ok := tt.env[key]
+				return v, ok
+			})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cfg.UID != tt.wantUID || cfg.GID != tt.wantGID || cfg.Umask != tt.wantUmask {
+				t.Errorf("got uid %d, gid %d, umask %q", cfg.UID, cfg.GID, cfg.Umask)
+			}
+		})
+	}
+}
+
+func TestLoadAppliesEnvironment(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(configPath, []byte("uid = 500\ngid = 500\n"), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	t.Setenv("PUID", "911")
+	t.Setenv("PGID", "")
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.UID != 911 || cfg.GID != 500 {
+		t.Errorf("expected PUID to override uid and an empty PGID to be ignored, got uid %d, gid %d", cfg.UID, cfg.GID)
+	}
+}
+
+func TestParseUmask(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int
+		wantErr  bool
+	}{
+		{input: "022", expected: 0o022},
+		{input: "0002", expected: 0o002},
+		{input: "777", expected: 0o777},
+		{input: "1000", wantErr: true},
+		{input: "8", wantErr: true},
+		{input: "-1", wantErr: true},
+		{input: "abc", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseUmask(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseUmask(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.expected {
+				t.Errorf("ParseUmask(%q) = %o, expected %o", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCredentials(t *testing.T) {
+	cfg := &Config{
+		Username: "admin",
+		Password: "secret",
+		Users: []UserConfig{
+			{Username: "sonarr", Password: "pass"},
+			{Username: "old", Password: "pass", Disabled: true},
+			{Username: "dashboard", Token: "token"},
+		},
+	}
+
+	creds := cfg.Credentials()
+	if len(creds) != 3 {
+		t.Fatalf("expected 3 credentials, got %d", len(creds))
+	}
+	if creds[0].Username != "admin" || creds[0].Password != "secret" {
+		t.Errorf("expected top-level credentials first, got %+v", creds[0])
+	}
+	for _, c := range creds {
+		if c.Username == "old" {
+			t.Error("expected disabled user to be excluded")
+		}
+	}
+
+	if got := (&Config{}).Credentials(); len(got) != 0 {
+		t.Errorf("expected no credentials, got %d", len(got))
+	}
+}
+
+func TestShouldDeleteRemoteFiles(t *testing.T) {
+	yes, no := true, false
+	tests := []struct {
+		name     string
+		config   *Config
+		arrName  string
+		expected bool
+	}{
+		{name: "defaults to true", config: &Config{}, arrName: "sonarr", expected: true},
+		{name: "global false", config: &Config{DeleteRemoteFiles: &no}, arrName: "sonarr", expected: false},
+		{
+			name: "per-arr overrides global",
+			config: &Config{
+				DeleteRemoteFiles: &no,
+				Sonarr:            &ArrConfig{DeleteRemoteFiles: &yes},
+			},
+			arrName:  "Sonarr",
+			expected: true,
+		},
+		{
+			name: "other arr falls back to global",
+			config: &Config{
+				DeleteRemoteFiles: &yes,
+				Sonarr:            &ArrConfig{DeleteRemoteFiles: &no},
+				Radarr:            &ArrConfig{},
+			},
+			arrName:  "Radarr",
+			expected: true,
+		},
+		{name: "unknown importer uses global", config: &Config{DeleteRemoteFiles: &no}, arrName: "", expected: false},
+		{
+			name: "never when streaming",
+			config: &Config{
+				DownloadMode: DownloadModeStrm,
+				Sonarr:       &ArrConfig{DeleteRemoteFiles: &yes},
+			},
+			arrName:  "sonarr",
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.ShouldDeleteRemoteFiles(tt.arrName); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestGetArrConfigs(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   *Config
+		expected int
+	}{
+		{
+			name:     "no arr configs",
+			config:   &Config{},
+			expected: 0,
+		},
+		{
+			name: "only sonarr",
+			config: &Config{
+				Sonarr: &ArrConfig{URL: "http://sonarr", APIKey: "key1"},
+			},
+			expected: 1,
+		},
+		{
+			name: "sonarr and radarr",
+			config: &Config{
+				Sonarr: &ArrConfig{URL: "http://sonarr", APIKey: "key1"},
+				Radarr: &ArrConfig{URL: "http://radarr", APIKey: "key2"},
+			},
+			expected: 2,
+		},
+		{
+			name: "all three",
+			config: &Config{
+				Sonarr:   &ArrConfig{URL: "http://sonarr", APIKey: "key1"},
+				Radarr:   &ArrConfig{URL: "http://radarr", APIKey: "key2"},
+				Whisparr: &ArrConfig{URL: "http://whisparr", APIKey: "key3"},
+			},
+			expected: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configs := tt.config.GetArrConfigs()
+			if len(configs) != tt.expected {
+				t.Errorf("expected %d configs, got %d", tt.expected, len(configs))
+			}
+		})
+	}
+}
+
+func TestGetArrConfigsContent(t *testing.T) {
+	cfg := &Config{
+		Sonarr:   &ArrConfig{URL: "http://sonarr:8989", APIKey: "sonarr-key"},
+		Radarr:   &ArrConfig{URL: "http://radarr:7878", APIKey: "radarr-key"},
+		Whisparr: &ArrConfig{URL: "http://whisparr:6969", APIKey: "whisparr-key"},
+	}
+
+	configs := cfg.GetArrConfigs()
+
+	// Check Sonarr
+	found := false
+	for _, c := range configs {
+		if c.Name == "Sonarr" {
+			found = true
+			if c.URL != "http://sonarr:8989" {
+				t.Errorf("expected Sonarr URL 'http://sonarr:8989', got '%s'", c.URL)
+			}
+			if c.APIKey != "sonarr-key" {
+				t.Errorf("expected Sonarr APIKey 'sonarr-key', got '%s'", c.APIKey)
+			}
+		}
+	}
+	if !found {
+		t.Error("Sonarr config not found")
+	}
+
+	// Check Radarr
+	found = false
+	for _, c := range configs {
+		if c.Name == "Radarr" {
+			found = true
+			if c.URL != "http://radarr:7878" {
+				t.Errorf("expected Radarr URL 'http://radarr:7878', got '%s'", c.URL)
+			}
+			if c.APIKey != "radarr-key" {
+				t.Errorf("expected Radarr APIKey 'radarr-key', got '%s'", c.APIKey)
+			}
+		}
+	}
+	if !found {
+		t.Error("Radarr config not found")
+	}
+
+	// Check Whisparr
+	found = false
+	for _, c := range configs {
+		if c.Name == "Whisparr" {
+			found = true
+			if c.URL != "http://whisparr:6969" {
+				t.Errorf("expected Whisparr URL 'http://whisparr:6969', got '%s'", c.URL)
+			}
+			if c.APIKey != "whisparr-key" {
+				t.Errorf("expected Whisparr APIKey 'whisparr-key', got '%s'", c.APIKey)
+			}
+		}
+	}
+	if !found {
+		t.Error("Whisparr config not found")
+	}
+}
+
+func TestForwardAuthConfig(t *testing.T) {
+	var disabled *ForwardAuthConfig
+	if disabled.Allows(EndpointRPC) {
+		t.Error("expected a nil forward_auth to allow nothing")
+	}
+
+	fa := &ForwardAuthConfig{Endpoints: []string{EndpointWebDAV}}
+	if fa.HeaderName() != DefaultForwardAuthHeader {
+		t.Errorf("expected the default header, got %q", fa.HeaderName())
+	}
+	if !fa.Allows(EndpointWebDAV) || fa.Allows(EndpointRPC) {
+		t.Error("expected only the listed endpoint groups to be allowed")
+	}
+	fa.Header = "X-Forwarded-User"
+	if fa.HeaderName() != "X-Forwarded-User" {
+		t.Errorf("expected the configured header, got %q", fa.HeaderName())
+	}
+}
+
+func TestConfigValidateHints(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Username = "user"
+	cfg.Password = "pass"
+	cfg.DownloadDirectory = t.TempDir()
+	cfg.Sonarr = &ArrConfig{URL: "http://localhost", APIKey: "key"}
+
+	err := cfg.Validate()
+	if err == nil || err.Error() != "putio.api_key is required" {
+		t.Fatalf("expected the missing api_key error, got %v", err)
+	}
+	if got := hint.Of(err); !strings.Contains(got, "get-token") {
+		t.Errorf("hint = %q, want one pointing at get-token", got)
+	}
+}
+
+func TestConfigValidateFallbackHint(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Username = "user"
+	cfg.Password = "pass"
+	cfg.DownloadDirectory = t.TempDir()
+	cfg.Port = 0
+
+	err := cfg.Validate()
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected a ValidationError, got %v", err)
+	}
+	if got := hint.Of(err); !strings.Contains(got, "README") {
+		t.Errorf("hint = %q, want one pointing at the option's description", got)
 	}
 }
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Duration is a time.Duration that can be decoded from TOML either as a Go
+// duration string ("30s", "2m") or, for backward compatibility, as a plain
+// integer number of seconds.
+type Duration time.Duration
+
+// Seconds returns a Duration of n seconds.
+func Seconds(n int) Duration {
+	return Duration(time.Duration(n) * time.Second)
+}
+
+// Duration returns the value as a time.Duration.
+func (d Duration) Duration() time.Duration {
+	return time.Duration(d)
+}
+
+// String returns the duration formatted like time.Duration.
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+// UnmarshalTOML implements toml.Unmarshaler.
+func (d *Duration) UnmarshalTOML(v interface{}) error {
+	switch value := v.(type) {
+	case int64:
+		*d = Duration(time.Duration(value) * time.Second)
+	case float64:
+		*d = Duration(value * float64(time.Second))
+	case string:
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q: %w", value, err)
+		}
+		*d = Duration(parsed)
+	default:
+		return fmt.Errorf("invalid duration type %T", v)
+	}
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler so durations round-trip as strings.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
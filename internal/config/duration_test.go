@@ -0,0 +1,76 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+func TestDurationUnmarshalTOML(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected time.Duration
+		wantErr  bool
+	}{
+		{name: "plain integer is seconds", input: `d = 30`, expected: 30 * time.Second},
+		{name: "float is seconds", input: `d = 1.5`, expected: 1500 * time.Millisecond},
+		{name: "duration string seconds", input: `d = "45s"`, expected: 45 * time.Second},
+		{name: "duration string minutes", input: `d = "2m"`, expected: 2 * time.Minute},
+		{name: "compound duration string", input: `d = "1h30m"`, expected: 90 * time.Minute},
+		{name: "invalid string", input: `d = "soon"`, wantErr: true},
+		{name: "invalid type", input: `d = true`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out struct {
+				D Duration `toml:"d"`
+			}
+			_, err := toml.Decode(tt.input, &out)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got nil (value %s)", out.D)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if out.D.Duration() != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, out.D)
+			}
+		})
+	}
+}
+
+func TestDurationString(t *testing.T) {
+	if got := Seconds(90).String(); got != "1m30s" {
+		t.Errorf("expected '1m30s', got '%s'", got)
+	}
+	text, err := Seconds(10).MarshalText()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(text) != "10s" {
+		t.Errorf("expected '10s', got '%s'", string(text))
+	}
+}
+
+func TestLoadPollingIntervalDurationString(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(configPath, []byte(`polling_interval = "1m"`), 0644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if cfg.PollingInterval.Duration() != time.Minute {
+		t.Errorf("expected PollingInterval 1m, got %s", cfg.PollingInterval)
+	}
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"errors"
+	"io/fs"
+
+	"github.com/ochronus/goputioarr/internal/hint"
+)
+
+// LoadError is a config file that couldn't be read or parsed.
+type LoadError struct {
+	Path string
+	Err  error
+}
+
+func (e *LoadError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *LoadError) Unwrap() error {
+	return e.Err
+}
+
+// Hint returns what to check to get the file loaded.
+func (e *LoadError) Hint() string {
+	switch {
+	case errors.Is(e.Err, fs.ErrNotExist):
+		return "create one with `goputioarr generate-config`, or pass its path with -c"
+	case errors.Is(e.Err, fs.ErrPermission):
+		return "check the file's permissions and the user the proxy runs as"
+	}
+	return "check the TOML syntax around the reported line; strings and durations such as \"10s\" need quotes"
+}
+
+// ValidationError is a config option that failed validation.
+type ValidationError struct {
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// Hint returns the hint of the failed check if it has one, or else where the
+// option is described.
+func (e *ValidationError) Hint() string {
+	if h := hint.Of(e.Err); h != "" {
+		return h
+	}
+	return "see the option's description in the README, or in the template written by `goputioarr generate-config`"
+}
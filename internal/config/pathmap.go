@@ -0,0 +1,105 @@
+package config
+
+import (
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// MapPath translates a local path to the path seen by another container using
+// the longest matching prefix in mappings (local prefix -> remote prefix).
+// Prefixes only match on whole path components. Paths without a matching
+// prefix are returned unchanged. Local paths may use the platform's separator.
+func MapPath(p string, mappings map[string]string) string {
+	if len(mappings) == 0 {
+		return p
+	}
+	slashed := filepath.ToSlash(p)
+
+	prefixes := make([]string, 0, len(mappings))
+	for prefix := range mappings {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Slice(prefixes, func(i, j int) bool {
+		return len(prefixes[i]) > len(prefixes[j])
+	})
+
+	for _, prefix := range prefixes {
+		local := strings.TrimSuffix(filepath.ToSlash(prefix), "/")
+		var rest string
+		switch {
+		case slashed == local:
+			rest = ""
+		case strings.HasPrefix(slashed, local+"/"):
+			rest = slashed[len(local):]
+		default:
+			continue
+		}
+		return joinRemote(mappings[prefix], rest)
+	}
+
+	return p
+}
+
+// UnmapPath is the inverse of MapPath: it translates a path reported by
+// another container back to the local path using the longest matching remote
+// prefix. Windows-style remote paths are matched with either separator, and
+// local paths are returned with the platform's separator.
+func UnmapPath(p string, mappings map[string]string) string {
+	if len(mappings) == 0 {
+		return p
+	}
+
+	locals := make([]string, 0, len(mappings))
+	for local := range mappings {
+		locals = append(locals, local)
+	}
+	sort.Slice(locals, func(i, j int) bool {
+		return len(mappings[locals[i]]) > len(mappings[locals[j]])
+	})
+
+	normalized := strings.ReplaceAll(p, `\`, "/")
+	for _, local := range locals {
+		remote := strings.TrimSuffix(strings.ReplaceAll(mappings[local], `\`, "/"), "/")
+		slashedLocal := strings.TrimSuffix(filepath.ToSlash(local), "/")
+		switch {
+		case normalized == remote:
+			return filepath.FromSlash(slashedLocal)
+		case strings.HasPrefix(normalized, remote+"/"):
+			return filepath.FromSlash(path.Join(slashedLocal, normalized[len(remote):]))
+		}
+	}
+
+	return p
+}
+
+// joinRemote appends rest to remote, using backslashes when remote is a
+// Windows-style path (e.g. an arr running on Windows).
+func joinRemote(remote, rest string) string {
+	if rest == "" {
+		return remote
+	}
+	if strings.Contains(remote, `\`) && !strings.Contains(remote, "/") {
+		return strings.TrimSuffix(remote, `\`) + strings.ReplaceAll(rest, "/", `\`)
+	}
+	return path.Join(remote, rest)
+}
+
+// ArrPathMappings returns the path mappings that apply to the named arr
+// service: the global [path_mappings] overlaid with the service's own.
+func (c *Config) ArrPathMappings(name string) map[string]string {
+	arrCfg := c.ArrConfigByName(name)
+	if arrCfg == nil || len(arrCfg.PathMappings) == 0 {
+		return c.PathMappings
+	}
+
+	merged := make(map[string]string, len(c.PathMappings)+len(arrCfg.PathMappings))
+	for local, remote := range c.PathMappings {
+		merged[local] = remote
+	}
+	for local, remote := range arrCfg.PathMappings {
+		merged[local] = remote
+	}
+	return merged
+}
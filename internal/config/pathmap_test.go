@@ -0,0 +1,106 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMapPath(t *testing.T) {
+	mappings := map[string]string{
+		"/downloads":          "/data/downloads",
+		"/downloads/tv":       "/tv-downloads",
+		"/mnt/share/":         `D:\share`,
+		"/other/downloads/xx": "/unused",
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "exact prefix", input: "/downloads", expected: "/data/downloads"},
+		{name: "nested path", input: "/downloads/movie/movie.mkv", expected: "/data/downloads/movie/movie.mkv"},
+		{name: "longest prefix wins", input: "/downloads/tv/show.mkv", expected: "/tv-downloads/show.mkv"},
+		{name: "partial component does not match", input: "/downloads2/file.mkv", expected: "/downloads2/file.mkv"},
+		{name: "unmapped path", input: "/elsewhere/file.mkv", expected: "/elsewhere/file.mkv"},
+		{name: "windows remote", input: "/mnt/share/movie/file.mkv", expected: `D:\share\movie\file.mkv`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MapPath(tt.input, mappings); got != tt.expected {
+				t.Errorf("MapPath(%q) = %q, expected %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+
+	if got := MapPath("/downloads/file", nil); got != "/downloads/file" {
+		t.Errorf("expected nil mappings to leave path unchanged, got %q", got)
+	}
+}
+
+func TestUnmapPath(t *testing.T) {
+	mappings := map[string]string{
+		"/downloads":    "/data/downloads",
+		"/downloads/tv": "/data/downloads/tv-sorted",
+		"/mnt/share/":   `D:\share`,
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "exact prefix", input: "/data/downloads", expected: "/downloads"},
+		{name: "nested path", input: "/data/downloads/movie/movie.mkv", expected: "/downloads/movie/movie.mkv"},
+		{name: "longest prefix wins", input: "/data/downloads/tv-sorted/show.mkv", expected: "/downloads/tv/show.mkv"},
+		{name: "partial component does not match", input: "/data/downloads2/file.mkv", expected: "/data/downloads2/file.mkv"},
+		{name: "windows remote", input: `D:\share\movie\file.mkv`, expected: "/mnt/share/movie/file.mkv"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := UnmapPath(tt.input, mappings); got != tt.expected {
+				t.Errorf("UnmapPath(%q) = %q, expected %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+
+	if got := UnmapPath("/data/file", nil); got != "/data/file" {
+		t.Errorf("expected nil mappings to leave path unchanged, got %q", got)
+	}
+}
+
+func TestPathMappingUsesLocalSeparator(t *testing.T) {
+	mappings := map[string]string{filepath.FromSlash("/downloads"): "/data/downloads"}
+	local := filepath.FromSlash("/downloads/movie/movie.mkv")
+
+	if got := MapPath(local, mappings); got != "/data/downloads/movie/movie.mkv" {
+		t.Errorf("MapPath(%q) = %q", local, got)
+	}
+	if got := UnmapPath("/data/downloads/movie/movie.mkv", mappings); got != local {
+		t.Errorf("UnmapPath returned %q, expected %q", got, local)
+	}
+}
+
+func TestArrPathMappings(t *testing.T) {
+	cfg := &Config{
+		PathMappings: map[string]string{"/downloads": "/data/downloads"},
+		Sonarr: &ArrConfig{
+			PathMappings: map[string]string{"/downloads": "/sonarr/downloads", "/extra": "/x"},
+		},
+		Radarr: &ArrConfig{},
+	}
+
+	expectedSonarr := map[string]string{"/downloads": "/sonarr/downloads", "/extra": "/x"}
+	if got := cfg.ArrPathMappings("Sonarr"); !reflect.DeepEqual(got, expectedSonarr) {
+		t.Errorf("unexpected sonarr mappings: %v", got)
+	}
+	if got := cfg.ArrPathMappings("Radarr"); !reflect.DeepEqual(got, cfg.PathMappings) {
+		t.Errorf("expected radarr to use global mappings, got %v", got)
+	}
+	if got := cfg.ArrPathMappings("Whisparr"); !reflect.DeepEqual(got, cfg.PathMappings) {
+		t.Errorf("expected unconfigured service to use global mappings, got %v", got)
+	}
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Prefixes of skip_directories entries that aren't exact names.
+const (
+	globPrefix  = "glob:"
+	regexPrefix = "regex:"
+)
+
+// NamePatterns matches file and directory names against skip_directories
+// entries. An entry is a glob when prefixed with "glob:" ("glob:*sample*"), a
+// regular expression when prefixed with "regex:" ("regex:^screens?$"), and an
+// exact name otherwise, so names that happen to contain * or / still match
+// only themselves. All matching is case-insensitive.
+type NamePatterns []func(name string) bool
+
+// ParseNamePatterns parses skip_directories entries.
+func ParseNamePatterns(entries []string) (NamePatterns, error) {
+	patterns := make(NamePatterns, 0, len(entries))
+	for _, entry := range entries {
+		switch {
+		case strings.HasPrefix(entry, regexPrefix):
+			re, err := regexp.Compile("(?i)" + strings.TrimPrefix(entry, regexPrefix))
+			if err != nil {
+				return nil, fmt.Errorf("invalid regular expression %q: %w", entry, err)
+			}
+			patterns = append(patterns, re.MatchString)
+		case strings.HasPrefix(entry, globPrefix):
+			glob := strings.ToLower(strings.TrimPrefix(entry, globPrefix))
+			if _, err := filepath.Match(glob, ""); err != nil {
+				return nil, fmt.Errorf("invalid glob %q: %w", entry, err)
+			}
+			patterns = append(patterns, func(name string) bool {
+				matched, _ := filepath.Match(glob, strings.ToLower(name))
+				return matched
+			})
+		default:
+			lower := strings.ToLower(entry)
+			patterns = append(patterns, func(name string) bool {
+				return strings.ToLower(name) == lower
+			})
+		}
+	}
+	return patterns, nil
+}
+
+// Match reports whether name matches any of the patterns.
+func (p NamePatterns) Match(name string) bool {
+	for _, match := range p {
+		if match(name) {
+			return true
+		}
+	}
+	return false
+}
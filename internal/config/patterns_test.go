@@ -0,0 +1,45 @@
+package config
+
+import "testing"
+
+func TestNamePatterns(t *testing.T) {
+	patterns, err := ParseNamePatterns([]string{"extras", "glob:*sample*", "regex:^screens?$", "*literal*"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests := []struct {
+		name     string
+		expected bool
+	}{
+		{"extras", true},
+		{"EXTRAS", true},
+		{"extras2", false},
+		{"sample", true},
+		{"Show.S01E01.SAMPLE.mkv", true},
+		{"screen", true},
+		{"Screens", true},
+		{"screenshots", false},
+		{"Show.S01E01.mkv", false},
+		{"*literal*", true},
+		{"a literal name", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := patterns.Match(tt.name); got != tt.expected {
+				t.Errorf("Match(%q) = %v, expected %v", tt.name, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseNamePatternsInvalid(t *testing.T) {
+	for _, entry := range []string{"regex:(unclosed", "glob:[abc"} {
+		if _, err := ParseNamePatterns([]string{entry}); err == nil {
+			t.Errorf("expected %q to be rejected", entry)
+		}
+	}
+	if patterns, err := ParseNamePatterns(nil); err != nil || patterns.Match("sample") {
+		t.Errorf("expected no patterns to match nothing, got %v", err)
+	}
+}
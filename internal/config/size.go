@@ -0,0 +1,89 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Size is a number of bytes that can be decoded from TOML either as a plain
+// integer number of bytes or as a string with a unit ("500MB", "10GiB").
+// Decimal units are powers of 1000 and binary units powers of 1024.
+type Size int64
+
+var sizeUnits = map[string]int64{
+	"":    1,
+	"B":   1,
+	"KB":  1000,
+	"MB":  1000 * 1000,
+	"GB":  1000 * 1000 * 1000,
+	"TB":  1000 * 1000 * 1000 * 1000,
+	"KIB": 1 << 10,
+	"MIB": 1 << 20,
+	"GIB": 1 << 30,
+	"TIB": 1 << 40,
+}
+
+// ParseSize parses a size such as "500MB", "1.5GB" or "10GiB".
+func ParseSize(s string) (Size, error) {
+	trimmed := strings.TrimSpace(s)
+	split := strings.IndexFunc(trimmed, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	number, unit := trimmed, ""
+	if split >= 0 {
+		number, unit = trimmed[:split], strings.TrimSpace(trimmed[split:])
+	}
+	multiplier, ok := sizeUnits[strings.ToUpper(unit)]
+	if !ok || number == "" {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q: %w", s, err)
+	}
+	return Size(value * float64(multiplier)), nil
+}
+
+// Bytes returns the size in bytes.
+func (s Size) Bytes() int64 {
+	return int64(s)
+}
+
+// String formats the size with a decimal unit, e.g. "1.5 GB".
+func (s Size) String() string {
+	n := int64(s)
+	if n < 1000 {
+		return fmt.Sprintf("%d B", n)
+	}
+	value := float64(n)
+	for _, unit := range []string{"kB", "MB", "GB", "TB"} {
+		value /= 1000
+		if value < 1000 || unit == "TB" {
+			return fmt.Sprintf("%.1f %s", value, unit)
+		}
+	}
+	return ""
+}
+
+// UnmarshalTOML implements toml.Unmarshaler.
+func (s *Size) UnmarshalTOML(v interface{}) error {
+	switch value := v.(type) {
+	case int64:
+		*s = Size(value)
+	case string:
+		parsed, err := ParseSize(value)
+		if err != nil {
+			return err
+		}
+		*s = parsed
+	default:
+		return fmt.Errorf("invalid size type %T", v)
+	}
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler so sizes round-trip as strings.
+func (s Size) MarshalText() ([]byte, error) {
+	return []byte(strconv.FormatInt(int64(s), 10)), nil
+}
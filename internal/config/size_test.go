@@ -0,0 +1,68 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/BurntSushi/toml"
+)
+
+func TestSizeUnmarshalTOML(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected int64
+		wantErr  bool
+	}{
+		{name: "plain integer is bytes", input: `s = 1024`, expected: 1024},
+		{name: "bytes", input: `s = "512B"`, expected: 512},
+		{name: "no unit", input: `s = "2048"`, expected: 2048},
+		{name: "megabytes", input: `s = "500MB"`, expected: 500_000_000},
+		{name: "fractional gigabytes", input: `s = "1.5GB"`, expected: 1_500_000_000},
+		{name: "gibibytes", input: `s = "10GiB"`, expected: 10 << 30},
+		{name: "lowercase with space", input: `s = "2 gb"`, expected: 2_000_000_000},
+		{name: "unknown unit", input: `s = "5XB"`, wantErr: true},
+		{name: "missing number", input: `s = "GB"`, wantErr: true},
+		{name: "invalid number", input: `s = "1.2.3GB"`, wantErr: true},
+		{name: "invalid type", input: `s = true`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out struct {
+				S Size `toml:"s"`
+			}
+			_, err := toml.Decode(tt.input, &out)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got nil (value %d)", out.S)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if out.S.Bytes() != tt.expected {
+				t.Errorf("expected %d, got %d", tt.expected, out.S.Bytes())
+			}
+		})
+	}
+}
+
+func TestSizeString(t *testing.T) {
+	tests := []struct {
+		size     Size
+		expected string
+	}{
+		{size: 0, expected: "0 B"},
+		{size: 999, expected: "999 B"},
+		{size: 1500, expected: "1.5 kB"},
+		{size: 10_000_000_000, expected: "10.0 GB"},
+		{size: 2_500_000_000_000_000, expected: "2500.0 TB"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.size.String(); got != tt.expected {
+			t.Errorf("Size(%d).String() = %q, expected %q", tt.size, got, tt.expected)
+		}
+	}
+}
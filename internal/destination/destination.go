@@ -0,0 +1,148 @@
+// Package destination stores completed downloads where the arrs import them
+// from: in the download directory itself, or on an rclone remote.
+package destination
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ochronus/goputioarr/internal/services/rclone"
+)
+
+// Backend receives the completed downloads of the download directory. Paths
+// are the downloads' local paths in the download directory, whether or not
+// the backend moved them away.
+type Backend interface {
+	// Name describes the backend in logs.
+	Name() string
+	// Store moves the completed download at path, a file or directory, to
+	// the backend.
+	Store(ctx context.Context, path string) error
+	// Remove deletes the download stored from path. It returns an error
+	// matching fs.ErrNotExist if there's nothing to delete.
+	Remove(ctx context.Context, path string) error
+}
+
+// Local keeps downloads in the download directory.
+type Local struct{}
+
+var _ Backend = Local{}
+
+// Name implements Backend.
+func (Local) Name() string {
+	return "download directory"
+}
+
+// Store implements Backend. Downloads are already in place.
+func (Local) Store(ctx context.Context, path string) error {
+	return nil
+}
+
+// Remove implements Backend.
+func (Local) Remove(ctx context.Context, path string) error {
+	if _, err := os.Lstat(path); err != nil {
+		return err
+	}
+	return os.RemoveAll(path)
+}
+
+// RcloneAPI is the part of the rclone client the Rclone backend uses.
+type RcloneAPI interface {
+	MoveFile(ctx context.Context, srcFs, srcRemote, dstFs, dstRemote string) error
+	MoveDir(ctx context.Context, srcFs, dstFs string) error
+	Stat(ctx context.Context, fs, remote string) (exists, isDir bool, err error)
+	DeleteFile(ctx context.Context, fs, remote string) error
+	Purge(ctx context.Context, fs, remote string) error
+}
+
+var _ RcloneAPI = (*rclone.Client)(nil)
+
+// Rclone moves downloads to an rclone remote, keeping their path relative to
+// the download directory. The arrs import them through an rclone mount of
+// the remote.
+type Rclone struct {
+	client      RcloneAPI
+	downloadDir string
+	remote      string
+}
+
+var _ Backend = (*Rclone)(nil)
+
+// NewRclone creates a backend moving downloads from downloadDir to remote,
+// e.g. "gdrive:downloads", with client.
+func NewRclone(client RcloneAPI, downloadDir, remote string) *Rclone {
+	return &Rclone{client: client, downloadDir: downloadDir, remote: remote}
+}
+
+// Name implements Backend.
+func (r *Rclone) Name() string {
+	return r.remote
+}
+
+// Store implements Backend.
+func (r *Rclone) Store(ctx context.Context, path string) error {
+	rel, err := r.relativePath(path)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return r.client.MoveFile(ctx, filepath.Dir(path), filepath.Base(path), r.remote, rel)
+	}
+	if err := r.client.MoveDir(ctx, path, joinRemote(r.remote, rel)); err != nil {
+		return err
+	}
+	// sync/move leaves the emptied top-level directory behind.
+	os.Remove(path)
+	return nil
+}
+
+// Remove implements Backend.
+func (r *Rclone) Remove(ctx context.Context, path string) error {
+	rel, err := r.relativePath(path)
+	if err != nil {
+		return err
+	}
+	exists, isDir, err := r.client.Stat(ctx, r.remote, rel)
+	switch {
+	case err != nil:
+		return err
+	case !exists:
+		return fmt.Errorf("%s: %w", joinRemote(r.remote, rel), fs.ErrNotExist)
+	case isDir:
+		err = r.client.Purge(ctx, r.remote, rel)
+	default:
+		err = r.client.DeleteFile(ctx, r.remote, rel)
+	}
+	if errors.Is(err, rclone.ErrNotFound) {
+		return fmt.Errorf("%w: %w", err, fs.ErrNotExist)
+	}
+	return err
+}
+
+// relativePath returns path relative to the download directory, in the
+// slash-separated form rclone uses.
+func (r *Rclone) relativePath(path string) (string, error) {
+	rel, err := filepath.Rel(r.downloadDir, path)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is not in the download directory", path)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// joinRemote appends rel to an rclone remote, which may be the root of the
+// remote ("gdrive:").
+func joinRemote(remote, rel string) string {
+	if strings.HasSuffix(remote, ":") || strings.HasSuffix(remote, "/") {
+		return remote + rel
+	}
+	return remote + "/" + rel
+}
@@ -0,0 +1,162 @@
+package destination
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ochronus/goputioarr/internal/services/rclone"
+)
+
+type mockRclone struct {
+	calls   []string
+	stat    map[string]bool // remote path -> is directory
+	failErr error
+}
+
+func (m *mockRclone) MoveFile(ctx context.Context, srcFs, srcRemote, dstFs, dstRemote string) error {
+	m.calls = append(m.calls, "movefile "+srcFs+" "+srcRemote+" "+dstFs+" "+dstRemote)
+	return m.failErr
+}
+
+func (m *mockRclone) MoveDir(ctx context.Context, srcFs, dstFs string) error {
+	m.calls = append(m.calls, "movedir "+srcFs+" "+dstFs)
+	if m.failErr != nil {
+		return m.failErr
+	}
+	// rclone empties the source directory.
+	entries, _ := os.ReadDir(srcFs)
+	for _, entry := range entries {
+		os.RemoveAll(filepath.Join(srcFs, entry.Name()))
+	}
+	return nil
+}
+
+func (m *mockRclone) Stat(ctx context.Context, fs, remote string) (bool, bool, error) {
+	isDir, ok := m.stat[remote]
+	return ok, isDir, nil
+}
+
+func (m *mockRclone) DeleteFile(ctx context.Context, fs, remote string) error {
+	m.calls = append(m.calls, "deletefile "+fs+" "+remote)
+	return m.failErr
+}
+
+func (m *mockRclone) Purge(ctx context.Context, fs, remote string) error {
+	m.calls = append(m.calls, "purge "+fs+" "+remote)
+	return m.failErr
+}
+
+func TestLocal(t *testing.T) {
+	dir := t.TempDir()
+	download := filepath.Join(dir, "show")
+	if err := os.MkdirAll(filepath.Join(download, "season"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var backend Local
+	if err := backend.Store(context.Background(), download); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	if _, err := os.Stat(download); err != nil {
+		t.Errorf("expected the download to stay in place: %v", err)
+	}
+	if err := backend.Remove(context.Background(), download); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if _, err := os.Stat(download); !os.IsNotExist(err) {
+		t.Errorf("expected the download to be removed, got %v", err)
+	}
+	if err := backend.Remove(context.Background(), download); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected fs.ErrNotExist for a missing download, got %v", err)
+	}
+}
+
+func TestRcloneStore(t *testing.T) {
+	dir := t.TempDir()
+	show := filepath.Join(dir, "Show S01")
+	if err := os.MkdirAll(show, 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(show, "ep1.mkv"), []byte("video"), 0644)
+	movie := filepath.Join(dir, "movie.mkv")
+	os.WriteFile(movie, []byte("video"), 0644)
+
+	client := &mockRclone{}
+	backend := NewRclone(client, dir, "gdrive:")
+
+	if err := backend.Store(context.Background(), show); err != nil {
+		t.Fatalf("Store directory: %v", err)
+	}
+	if err := backend.Store(context.Background(), movie); err != nil {
+		t.Fatalf("Store file: %v", err)
+	}
+
+	want := []string{
+		"movedir " + show + " gdrive:Show S01",
+		"movefile " + dir + " movie.mkv gdrive: movie.mkv",
+	}
+	if len(client.calls) != len(want) || client.calls[0] != want[0] || client.calls[1] != want[1] {
+		t.Errorf("expected calls %q, got %q", want, client.calls)
+	}
+	if _, err := os.Stat(show); !os.IsNotExist(err) {
+		t.Errorf("expected the emptied directory to be removed, got %v", err)
+	}
+
+	if err := backend.Store(context.Background(), filepath.Join(filepath.Dir(dir), "elsewhere")); err == nil {
+		t.Error("expected paths outside the download directory to be refused")
+	}
+}
+
+func TestRcloneStoreError(t *testing.T) {
+	dir := t.TempDir()
+	movie := filepath.Join(dir, "movie.mkv")
+	os.WriteFile(movie, []byte("video"), 0644)
+
+	backend := NewRclone(&mockRclone{failErr: errors.New("quota exceeded")}, dir, "gdrive:downloads")
+	if err := backend.Store(context.Background(), movie); err == nil {
+		t.Error("expected the move error to be returned")
+	}
+}
+
+func TestRcloneRemove(t *testing.T) {
+	client := &mockRclone{stat: map[string]bool{"Show S01": true, "movie.mkv": false}}
+	backend := NewRclone(client, "/downloads", "gdrive:downloads")
+
+	if err := backend.Remove(context.Background(), "/downloads/Show S01"); err != nil {
+		t.Fatalf("Remove directory: %v", err)
+	}
+	if err := backend.Remove(context.Background(), "/downloads/movie.mkv"); err != nil {
+		t.Fatalf("Remove file: %v", err)
+	}
+	if len(client.calls) != 2 || client.calls[0] != "purge gdrive:downloads Show S01" || client.calls[1] != "deletefile gdrive:downloads movie.mkv" {
+		t.Errorf("unexpected calls %q", client.calls)
+	}
+
+	if err := backend.Remove(context.Background(), "/downloads/gone.mkv"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected fs.ErrNotExist for a missing download, got %v", err)
+	}
+
+	client.failErr = rclone.ErrNotFound
+	if err := backend.Remove(context.Background(), "/downloads/movie.mkv"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected rclone's not found to match fs.ErrNotExist, got %v", err)
+	}
+}
+
+func TestJoinRemote(t *testing.T) {
+	tests := []struct {
+		remote, rel, want string
+	}{
+		{remote: "gdrive:", rel: "show/ep.mkv", want: "gdrive:show/ep.mkv"},
+		{remote: "gdrive:downloads", rel: "show", want: "gdrive:downloads/show"},
+		{remote: "gdrive:downloads/", rel: "show", want: "gdrive:downloads/show"},
+	}
+	for _, tt := range tests {
+		if got := joinRemote(tt.remote, tt.rel); got != tt.want {
+			t.Errorf("joinRemote(%q, %q) = %q, want %q", tt.remote, tt.rel, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package download
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// maxErrorRate is the share of failed downloads in an interval above which
+	// the autoscaler removes a worker.
+	maxErrorRate = 0.25
+	// minThroughputGain is the relative throughput improvement a scale-up has
+	// to deliver to be kept.
+	minThroughputGain = 0.05
+	// holdIntervals is how many intervals the autoscaler waits after backing
+	// off an unproductive scale-up before probing again.
+	holdIntervals = 3
+)
+
+// downloadStats counts download activity for the autoscaler.
+type downloadStats struct {
+	bytes     atomic.Int64
+	succeeded atomic.Int64
+	failed    atomic.Int64
+	active    atomic.Int64
+}
+
+// statsSnapshot is a point-in-time copy of the cumulative counters.
+type statsSnapshot struct {
+	bytes     int64
+	succeeded int64
+	failed    int64
+}
+
+func (s *downloadStats) snapshot() statsSnapshot {
+	return statsSnapshot{
+		bytes:     s.bytes.Load(),
+		succeeded: s.succeeded.Load(),
+		failed:    s.failed.Load(),
+	}
+}
+
+// intervalStats describes download activity over one autoscale interval.
+type intervalStats struct {
+	throughput float64 // bytes per second
+	succeeded  int64
+	failed     int64
+	backlog    bool // work was waiting for a free worker
+}
+
+func (s intervalStats) errorRate() float64 {
+	total := s.succeeded + s.failed
+	if total == 0 {
+		return 0
+	}
+	return float64(s.failed) / float64(total)
+}
+
+// autoscaler picks a download worker count by hill climbing: it adds workers
+// while there is queued work and each addition improves throughput, and
+// removes them when errors pile up or an addition didn't pay off.
+type autoscaler struct {
+	min, max       int
+	lastStep       int
+	lastThroughput float64
+	hold           int
+}
+
+func newAutoscaler(min, max int) *autoscaler {
+	return &autoscaler{min: min, max: max}
+}
+
+// next returns the worker count to use for the coming interval.
+func (a *autoscaler) next(current int, s intervalStats) int {
+	target := current
+	switch {
+	case s.failed > 0 && s.errorRate() > maxErrorRate:
+		target = current - 1
+	case a.hold > 0:
+		a.hold--
+	case !s.backlog:
+		// Nothing is waiting, so more workers wouldn't help.
+	case a.lastStep > 0 && s.throughput < a.lastThroughput*(1+minThroughputGain):
+		target = current - 1
+		a.hold = holdIntervals
+	default:
+		target = current + 1
+	}
+
+	if target < a.min {
+		target = a.min
+	}
+	if target > a.max {
+		target = a.max
+	}
+	a.lastStep = target - current
+	a.lastThroughput = s.throughput
+	return target
+}
+
+// runAutoscaler periodically resizes the download worker pool until the
+// manager is stopped.
+func (m *Manager) runAutoscaler() {
+	cfg := m.config.Autoscale
+	scaler := newAutoscaler(cfg.MinWorkers, cfg.MaxWorkers)
+	ticker := newJitteredTicker(cfg.Interval.Duration())
+	defer ticker.Stop()
+
+	last := m.stats.snapshot()
+	lastAt := time.Now()
+	for {
+		select {
+		case <-m.downloader.ctx.Done():
+			return
+		case now := <-ticker.C:
+			current := m.downloadWorkerCount()
+			snap := m.stats.snapshot()
+			s := intervalStats{
+				succeeded: snap.succeeded - last.succeeded,
+				failed:    snap.failed - last.failed,
+				backlog:   m.downloads.Len() > 0 || int(m.stats.active.Load()) >= current,
+			}
+			if elapsed := now.Sub(lastAt).Seconds(); elapsed > 0 {
+				s.throughput = float64(snap.bytes-last.bytes) / elapsed
+			}
+			last, lastAt = snap, now
+
+			if target := scaler.next(current, s); target != current {
+				m.logger.Infof("autoscale: %d -> %d download workers (%.1f MB/s, %.0f%% errors)",
+					current, target, s.throughput/(1<<20), s.errorRate()*100)
+				m.setDownloadWorkers(target)
+			}
+		}
+	}
+}
+
+// setDownloadWorkers starts or stops download workers until n are running.
+// Stopped workers finish their current download first.
+func (m *Manager) setDownloadWorkers(n int) {
+	m.workersMu.Lock()
+	defer m.workersMu.Unlock()
+
+	for len(m.workerCancels) < n {
+		ctx, cancel := context.WithCancel(m.downloader.ctx)
+		m.workerCancels = append(m.workerCancels, cancel)
+		m.nextWorkerID++
+		id := m.nextWorkerID
+		m.downloader.Go(func() {
+			m.supervise(ctx, fmt.Sprintf("download worker %d", id), func() { m.downloadWorker(ctx, id) })
+		})
+	}
+	for len(m.workerCancels) > n {
+		last := len(m.workerCancels) - 1
+		m.workerCancels[last]()
+		m.workerCancels = m.workerCancels[:last]
+	}
+}
+
+// downloadWorkerCount returns the number of running download workers.
+func (m *Manager) downloadWorkerCount() int {
+	m.workersMu.Lock()
+	defer m.workersMu.Unlock()
+	return len(m.workerCancels)
+}
+
+// countingWriter adds the number of bytes written to n and reports them to
+// onWrite, if set.
+type countingWriter struct {
+	w       io.Writer
+	n       *atomic.Int64
+	onWrite func(n int64)
+}
+
+func (c countingWriter) Write(p []byte) (int, error) {
+	written, err := c.w.Write(p)
+	c.n.Add(int64(written))
+	if c.onWrite != nil {
+		c.onWrite(int64(written))
+	}
+	return written, err
+}
@@ -0,0 +1,129 @@
+package download
+
+import (
+	"bytes"
+	"sync/atomic"
+	"testing"
+)
+
+func TestAutoscalerNext(t *testing.T) {
+	tests := []struct {
+		name     string
+		scaler   autoscaler
+		current  int
+		stats    intervalStats
+		expected int
+	}{
+		{
+			name:     "scales up with backlog",
+			scaler:   autoscaler{min: 1, max: 8},
+			current:  2,
+			stats:    intervalStats{throughput: 100, succeeded: 2, backlog: true},
+			expected: 3,
+		},
+		{
+			name:     "holds without backlog",
+			scaler:   autoscaler{min: 1, max: 8},
+			current:  2,
+			stats:    intervalStats{throughput: 100, succeeded: 2},
+			expected: 2,
+		},
+		{
+			name:     "keeps scaling while throughput improves",
+			scaler:   autoscaler{min: 1, max: 8, lastStep: 1, lastThroughput: 100},
+			current:  3,
+			stats:    intervalStats{throughput: 150, backlog: true},
+			expected: 4,
+		},
+		{
+			name:     "backs off unproductive scale-up",
+			scaler:   autoscaler{min: 1, max: 8, lastStep: 1, lastThroughput: 100},
+			current:  3,
+			stats:    intervalStats{throughput: 101, backlog: true},
+			expected: 2,
+		},
+		{
+			name:     "waits while holding",
+			scaler:   autoscaler{min: 1, max: 8, hold: 2},
+			current:  2,
+			stats:    intervalStats{throughput: 100, backlog: true},
+			expected: 2,
+		},
+		{
+			name:     "scales down on errors",
+			scaler:   autoscaler{min: 1, max: 8},
+			current:  4,
+			stats:    intervalStats{succeeded: 2, failed: 2, backlog: true},
+			expected: 3,
+		},
+		{
+			name:     "respects max",
+			scaler:   autoscaler{min: 1, max: 4},
+			current:  4,
+			stats:    intervalStats{throughput: 100, backlog: true},
+			expected: 4,
+		},
+		{
+			name:     "respects min",
+			scaler:   autoscaler{min: 2, max: 4},
+			current:  2,
+			stats:    intervalStats{failed: 3},
+			expected: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scaler := tt.scaler
+			if got := scaler.next(tt.current, tt.stats); got != tt.expected {
+				t.Errorf("expected %d workers, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestAutoscalerHoldsAfterBackingOff(t *testing.T) {
+	scaler := newAutoscaler(1, 8)
+	scaler.lastStep = 1
+	scaler.lastThroughput = 100
+
+	current := scaler.next(3, intervalStats{throughput: 100, backlog: true})
+	for i := 0; i < holdIntervals; i++ {
+		if got := scaler.next(current, intervalStats{throughput: 100, backlog: true}); got != current {
+			t.Fatalf("interval %d: expected to hold at %d, got %d", i, current, got)
+		}
+	}
+	if got := scaler.next(current, intervalStats{throughput: 100, backlog: true}); got != current+1 {
+		t.Errorf("expected to probe %d after holding, got %d", current+1, got)
+	}
+}
+
+func TestSetDownloadWorkers(t *testing.T) {
+	manager := setupTestManager()
+
+	manager.setDownloadWorkers(3)
+	if got := manager.downloadWorkerCount(); got != 3 {
+		t.Fatalf("expected 3 workers, got %d", got)
+	}
+	manager.setDownloadWorkers(1)
+	if got := manager.downloadWorkerCount(); got != 1 {
+		t.Fatalf("expected 1 worker, got %d", got)
+	}
+
+	manager.Stop()
+	if got := manager.downloadWorkerCount(); got != 1 {
+		t.Errorf("expected worker count to be unchanged after stop, got %d", got)
+	}
+}
+
+func TestCountingWriter(t *testing.T) {
+	var buf bytes.Buffer
+	var n atomic.Int64
+	w := countingWriter{w: &buf, n: &n}
+
+	w.Write([]byte("hello"))
+	w.Write([]byte(" world"))
+	if n.Load() != 11 {
+		t.Errorf("expected 11 bytes counted, got %d", n.Load())
+	}
+}
@@ -0,0 +1,48 @@
+package download
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// PrepareDirectory gets the download directory ready before the first
+// download: it is created if it's missing and create is set, and the
+// directories created are handed over to uid and gid when running as root. An
+// existing directory keeps its owner. Whether it ends up writable is left to
+// the config validation, which reports it with the setting's name.
+func PrepareDirectory(dir string, create bool, uid, gid int) error {
+	if dir == "" || !create {
+		return nil
+	}
+	missing := missingDirectories(dir)
+	if len(missing) == 0 {
+		return nil
+	}
+	// Permissions come from the umask, like files created by the arrs.
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return fmt.Errorf("failed to create download_directory: %w", localError(err))
+	}
+	for _, path := range missing {
+		if err := chownToUser(path, uid, gid); err != nil {
+			return fmt.Errorf("failed to change ownership of download_directory: %w", localError(err))
+		}
+	}
+	return nil
+}
+
+// missingDirectories returns dir and its parents that don't exist yet,
+// outermost first.
+func missingDirectories(dir string) []string {
+	var missing []string
+	for path := filepath.Clean(dir); ; path = filepath.Dir(path) {
+		if _, err := os.Lstat(path); err == nil {
+			break
+		}
+		missing = append([]string{path}, missing...)
+		if parent := filepath.Dir(path); parent == path {
+			break
+		}
+	}
+	return missing
+}
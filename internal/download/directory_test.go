@@ -0,0 +1,64 @@
+package download
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPrepareDirectory(t *testing.T) {
+	tests := []struct {
+		name       string
+		create     bool
+		wantExists bool
+	}{
+		{name: "created when enabled", create: true, wantExists: true},
+		{name: "left missing when disabled", create: false, wantExists: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "nested", "downloads")
+			if err := PrepareDirectory(dir, tt.create, os.Getuid(), os.Getgid()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			_, err := os.Stat(dir)
+			if exists := err == nil; exists != tt.wantExists {
+				t.Errorf("expected directory to exist: %v, got %v", tt.wantExists, exists)
+			}
+		})
+	}
+}
+
+func TestPrepareDirectoryExisting(t *testing.T) {
+	dir := t.TempDir()
+	if err := PrepareDirectory(dir, true, os.Getuid(), os.Getgid()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := PrepareDirectory("", true, 0, -1); err != nil {
+		t.Fatalf("expected an empty directory to be ignored, got %v", err)
+	}
+}
+
+func TestPrepareDirectoryCreateFails(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if err := PrepareDirectory(filepath.Join(file, "downloads"), true, 0, -1); err == nil {
+		t.Fatal("expected an error creating a directory below a file")
+	}
+}
+
+func TestMissingDirectories(t *testing.T) {
+	base := t.TempDir()
+	dir := filepath.Join(base, "a", "b")
+	want := []string{filepath.Join(base, "a"), dir}
+	if got := missingDirectories(dir); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if got := missingDirectories(base); len(got) != 0 {
+		t.Errorf("expected no missing directories, got %v", got)
+	}
+}
@@ -0,0 +1,51 @@
+package download
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// LocalError is a failure to write to the local disk, e.g. creating a
+// directory or writing a downloaded file.
+type LocalError struct {
+	Path string
+	Err  error
+}
+
+func (e *LocalError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *LocalError) Unwrap() error {
+	return e.Err
+}
+
+// Hint returns what to check for the error, or "" if there's nothing the user
+// can do about it.
+func (e *LocalError) Hint() string {
+	switch {
+	case errors.Is(e.Err, fs.ErrPermission):
+		return "check the permissions of download_directory and the user the proxy runs as"
+	case errors.Is(e.Err, syscall.ENOSPC):
+		return "the disk is full; free up space, or set min_free_space to wait for it"
+	case errors.Is(e.Err, syscall.EROFS):
+		return "download_directory is read-only; when running in Docker check the volume isn't mounted :ro"
+	}
+	return ""
+}
+
+// localError returns err as a *LocalError if it's a filesystem error, and
+// unchanged otherwise, e.g. when a download failed reading from put.io.
+func localError(err error) error {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return &LocalError{Path: pathErr.Path, Err: err}
+	}
+	var linkErr *os.LinkError
+	if errors.As(err, &linkErr) {
+		return &LocalError{Path: linkErr.New, Err: err}
+	}
+	return err
+}
@@ -0,0 +1,43 @@
+package download
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/ochronus/goputioarr/internal/hint"
+)
+
+func TestLocalError(t *testing.T) {
+	if localError(nil) != nil {
+		t.Error("expected nil to stay nil")
+	}
+	remote := errors.New("unexpected EOF")
+	if err := localError(remote); err != remote {
+		t.Errorf("expected a non-filesystem error to be unchanged, got %v", err)
+	}
+
+	full := &fs.PathError{Op: "write", Path: "/downloads/movie.mkv.downloading", Err: syscall.ENOSPC}
+	err := localError(full)
+	var localErr *LocalError
+	if !errors.As(err, &localErr) || localErr.Path != full.Path {
+		t.Fatalf("expected a LocalError for the path, got %v", err)
+	}
+	if err.Error() != full.Error() {
+		t.Errorf("expected the message to be kept, got %q", err.Error())
+	}
+	if got := hint.Of(err); !strings.Contains(got, "min_free_space") {
+		t.Errorf("hint = %q, want one about free space", got)
+	}
+
+	denied := localError(&os.LinkError{Op: "rename", Old: "a", New: "b", Err: syscall.EACCES})
+	if got := hint.Of(denied); !strings.Contains(got, "permissions") {
+		t.Errorf("hint = %q, want one about permissions", got)
+	}
+	if got := hint.Of(localError(&fs.PathError{Op: "open", Path: "a", Err: syscall.EINVAL})); got != "" {
+		t.Errorf("hint = %q, want none", got)
+	}
+}
@@ -0,0 +1,100 @@
+package download
+
+import (
+	"github.com/ochronus/goputioarr/internal/services/putio"
+)
+
+// track starts tracking transfer in the transfer store.
+func (m *Manager) track(transfer *Transfer) {
+	if transfer.FolderImport {
+		m.container.Transfers.TrackFolderImport(transfer.GetHash(), transfer.Name, transfer.Size)
+		return
+	}
+	m.container.Transfers.Track(transfer.GetHash(), transfer.Name, transfer.Size)
+}
+
+// listImportFolder lists the items of putio.import_folder_id. ok is false if
+// folder imports are disabled or the folder couldn't be listed.
+func (m *Manager) listImportFolder() (items []putio.FileResponse, ok bool) {
+	folderID := m.config.Putio.ImportFolderID
+	if folderID == 0 {
+		return nil, false
+	}
+	resp, err := m.putioClient.ListFiles(folderID)
+	if err != nil {
+		m.logger.Warnf("Failed to list the import folder %d: %v", folderID, err)
+		return nil, false
+	}
+	return resp.Files, true
+}
+
+// checkExistingFolderImports resumes the items of the import folder that
+// were in the pipeline before a restart, like checkExistingTransfers does for
+// transfers.
+func (m *Manager) checkExistingFolderImports() {
+	items, ok := m.listImportFolder()
+	if !ok {
+		return
+	}
+	for i := range items {
+		transfer := NewFolderTransfer(m.config, &items[i])
+		if m.reconcile(transfer) {
+			m.markFileSeen(items[i].ID)
+		}
+	}
+}
+
+// queueFolderImports queues the items of the import folder the manager hasn't
+// seen yet for download, as if put.io had just finished a transfer of each.
+func (m *Manager) queueFolderImports() {
+	items, ok := m.listImportFolder()
+	if !ok {
+		return
+	}
+
+	present := make(map[int64]bool, len(items))
+	for i := range items {
+		item := &items[i]
+		present[item.ID] = true
+		if m.isFileSeen(item.ID) {
+			continue
+		}
+
+		transfer := NewFolderTransfer(m.config, item)
+		m.markFileSeen(item.ID)
+		if !m.claimPipeline(transfer) {
+			continue
+		}
+		m.transferLogger(transfer).Infof("%s: ready for download from the import folder", transfer)
+		m.transfers.Push(TransferMessage{
+			Type:     MessageQueuedForDownload,
+			Transfer: transfer,
+		})
+	}
+	m.cleanupSeenFiles(present)
+}
+
+// isFileSeen checks if an import folder item has been seen
+func (m *Manager) isFileSeen(id int64) bool {
+	m.seenMu.RLock()
+	defer m.seenMu.RUnlock()
+	return m.seenFiles[id]
+}
+
+// markFileSeen marks an import folder item as seen
+func (m *Manager) markFileSeen(id int64) {
+	m.seenMu.Lock()
+	defer m.seenMu.Unlock()
+	m.seenFiles[id] = true
+}
+
+// cleanupSeenFiles forgets the import folder items that are gone
+func (m *Manager) cleanupSeenFiles(present map[int64]bool) {
+	m.seenMu.Lock()
+	defer m.seenMu.Unlock()
+	for id := range m.seenFiles {
+		if !present[id] {
+			delete(m.seenFiles, id)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package download
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ochronus/goputioarr/internal/app"
+	"github.com/ochronus/goputioarr/internal/services/putio"
+)
+
+// folderPutioClient records the calls that clean up after a transfer.
+type folderPutioClient struct {
+	mockPutioClient
+	deleted      []int64
+	getTransfers int
+}
+
+func (c *folderPutioClient) DeleteFile(fileID int64) error {
+	c.deleted = append(c.deleted, fileID)
+	return nil
+}
+
+func (c *folderPutioClient) GetTransfer(transferID uint64) (*putio.GetTransferResponse, error) {
+	c.getTransfers++
+	return &putio.GetTransferResponse{}, nil
+}
+
+func setupFolderManager(items ...putio.FileResponse) (*Manager, *folderPutioClient) {
+	manager := setupTestManager()
+	manager.config.Putio.ImportFolderID = 50
+	client := &folderPutioClient{mockPutioClient: mockPutioClient{
+		listFilesByID: map[int64]*putio.ListFileResponse{
+			50: {Files: items},
+		},
+	}}
+	manager.putioClient = client
+	manager.container.Transfers = app.NewTransferStore()
+	return manager, client
+}
+
+func TestQueueFolderImports(t *testing.T) {
+	manager, client := setupFolderManager(
+		putio.FileResponse{ID: 1, Name: "Old Show", FileType: "FOLDER", Size: 100},
+		putio.FileResponse{ID: 2, Name: "Old Movie.mkv", FileType: "VIDEO", Size: 200},
+	)
+
+	manager.queueFolderImports()
+	manager.queueFolderImports()
+
+	if depth := manager.transfers.Len(); depth != 2 {
+		t.Fatalf("expected each item to be queued once, got %d messages", depth)
+	}
+	msg, _ := popTransfer(manager, time.Second)
+	transfer := msg.Transfer
+	if msg.Type != MessageQueuedForDownload || !transfer.FolderImport || transfer.TransferID != 0 {
+		t.Errorf("expected a queued folder import, got %+v", msg)
+	}
+	if transfer.Name != "Old Show" || *transfer.FileID != 1 || transfer.Size != 100 || transfer.GetHash() != FolderImportHash(1) {
+		t.Errorf("unexpected transfer %+v", transfer)
+	}
+
+	// Items that are gone are forgotten, so they're queued again if they
+	// come back.
+	client.listFilesByID[50] = &putio.ListFileResponse{}
+	manager.queueFolderImports()
+	if manager.isFileSeen(1) || manager.isFileSeen(2) {
+		t.Error("expected items that left the folder to be forgotten")
+	}
+}
+
+func TestQueueFolderImportsDisabled(t *testing.T) {
+	manager, _ := setupFolderManager(putio.FileResponse{ID: 1, Name: "Old Show"})
+	manager.config.Putio.ImportFolderID = 0
+
+	manager.queueFolderImports()
+
+	if depth := manager.transfers.Len(); depth != 0 {
+		t.Errorf("expected nothing to be queued, got %d messages", depth)
+	}
+}
+
+func TestCheckExistingFolderImports(t *testing.T) {
+	movie := putio.FileResponse{ID: 1, Name: "Old Movie.mkv", FileType: "VIDEO", Size: 200}
+	manager, client := setupFolderManager(movie)
+	client.listFilesByID[1] = &putio.ListFileResponse{Parent: movie}
+	manager.arrClients = []app.ArrServiceClient{{Name: "Radarr", Client: &mockArrClient{imported: true}}}
+
+	manager.checkExistingFolderImports()
+
+	msg, ok := popTransfer(manager, time.Second)
+	if !ok || msg.Type != MessageImported || !msg.Transfer.FolderImport {
+		t.Fatalf("expected the imported item to be resumed, got %+v", msg)
+	}
+	state, ok := manager.container.Transfers.Get(FolderImportHash(1))
+	if !ok || !state.FolderImport || state.Stage != app.StageImported {
+		t.Errorf("expected an imported folder import to be tracked, got %+v", state)
+	}
+	if !manager.isFileSeen(1) {
+		t.Error("expected the resumed item not to be queued again")
+	}
+}
+
+func TestWatchSeedingFinishesFolderImport(t *testing.T) {
+	manager, client := setupFolderManager()
+
+	transfer := NewFolderTransfer(manager.config, &putio.FileResponse{ID: 7, Name: "Old Show"})
+	manager.track(transfer)
+
+	manager.watchSeeding(transfer)
+
+	if client.getTransfers != 0 {
+		t.Errorf("expected no put.io transfer to be polled, got %d calls", client.getTransfers)
+	}
+	if len(client.deleted) != 1 || client.deleted[0] != 7 {
+		t.Errorf("expected the item to be deleted from put.io, got %v", client.deleted)
+	}
+	if _, ok := manager.container.Transfers.Get(transfer.GetHash()); ok {
+		t.Error("expected the item to be forgotten")
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !linux && !darwin && !windows
+
+package download
+
+import "errors"
+
+// freeSpace isn't implemented on this platform, so min_free_space has no
+// effect
+func freeSpace(dir string) (uint64, error) {
+	return 0, errors.New("free space can't be checked on this platform")
+}
@@ -0,0 +1,69 @@
+package download
+
+import (
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// jitterFraction is how much polling intervals vary either way, so instances
+// and watchers polling at the same interval don't fire in bursts.
+const jitterFraction = 0.2
+
+// jitter returns d varied randomly by up to ±jitterFraction.
+func jitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return d
+	}
+	return d + time.Duration((rand.Float64()*2-1)*jitterFraction*float64(d))
+}
+
+// stagger returns a random delay in [0, d), spreading out tickers started at
+// the same time.
+func stagger(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return rand.N(d)
+}
+
+// jitteredTicker is like time.Ticker, but its first tick is staggered across
+// one interval and every later interval is jittered. Like time.Ticker, it
+// drops ticks for slow receivers.
+type jitteredTicker struct {
+	C <-chan time.Time
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func newJitteredTicker(d time.Duration) *jitteredTicker {
+	c := make(chan time.Time, 1)
+	t := &jitteredTicker{C: c, stop: make(chan struct{}), done: make(chan struct{})}
+	go func() {
+		defer close(t.done)
+		timer := time.NewTimer(stagger(d))
+		defer timer.Stop()
+		for {
+			select {
+			case <-t.stop:
+				return
+			case now := <-timer.C:
+				select {
+				case c <- now:
+				default:
+				}
+				timer.Reset(jitter(d))
+			}
+		}
+	}()
+	return t
+}
+
+// Stop turns off the ticker. No more ticks are sent after Stop returns,
+// although one may already be waiting on C.
+func (t *jitteredTicker) Stop() {
+	t.stopOnce.Do(func() { close(t.stop) })
+	<-t.done
+}
@@ -0,0 +1,54 @@
+package download
+
+import (
+	"testing"
+	"time"
+)
+
+func TestJitterBounds(t *testing.T) {
+	d := time.Second
+	low, high := d, d
+	for i := 0; i < 1000; i++ {
+		got := jitter(d)
+		if got < 800*time.Millisecond || got > 1200*time.Millisecond {
+			t.Fatalf("expected jitter within ±20%%, got %v", got)
+		}
+		low, high = min(low, got), max(high, got)
+	}
+	if high-low < 200*time.Millisecond {
+		t.Errorf("expected jittered intervals to vary, got range %v-%v", low, high)
+	}
+
+	if jitter(0) != 0 || stagger(0) != 0 {
+		t.Error("expected zero durations to stay zero")
+	}
+	for i := 0; i < 1000; i++ {
+		if got := stagger(d); got < 0 || got >= d {
+			t.Fatalf("expected stagger within [0, %v), got %v", d, got)
+		}
+	}
+}
+
+func TestJitteredTicker(t *testing.T) {
+	ticker := newJitteredTicker(10 * time.Millisecond)
+	for i := 0; i < 3; i++ {
+		select {
+		case <-ticker.C:
+		case <-time.After(time.Second):
+			t.Fatalf("expected tick %d", i+1)
+		}
+	}
+
+	ticker.Stop()
+	ticker.Stop()
+	// Drain a tick that may have been sent before Stop.
+	select {
+	case <-ticker.C:
+	default:
+	}
+	select {
+	case <-ticker.C:
+		t.Error("expected no ticks after Stop")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
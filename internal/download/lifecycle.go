@@ -0,0 +1,93 @@
+package download
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// drainPollInterval is how often Drain checks whether in-flight work is done.
+const drainPollInterval = 100 * time.Millisecond
+
+// group is a set of goroutines sharing a context, so each part of the
+// pipeline (poller, orchestrator, downloader, watchers) can be stopped on its
+// own.
+type group struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newGroup(parent context.Context) *group {
+	ctx, cancel := context.WithCancel(parent)
+	return &group{ctx: ctx, cancel: cancel}
+}
+
+// Go runs fn in a goroutine tracked by the group.
+func (g *group) Go(fn func()) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		fn()
+	}()
+}
+
+// Stop cancels the group's context and waits for its goroutines to return.
+func (g *group) Stop() {
+	g.cancel()
+	g.wg.Wait()
+}
+
+// newGroups (re)creates the manager's contexts and goroutine groups under parent.
+func (m *Manager) newGroups(parent context.Context) {
+	m.ctx, m.cancel = context.WithCancel(parent)
+	m.poller = newGroup(m.ctx)
+	m.orchestrator = newGroup(m.ctx)
+	m.downloader = newGroup(m.ctx)
+	m.watchers = newGroup(m.ctx)
+}
+
+// StopPolling stops picking up new transfers from put.io. Transfers that are
+// already being downloaded or watched are still processed.
+func (m *Manager) StopPolling() {
+	m.poller.Stop()
+}
+
+// Drain stops polling, waits for queued and in-flight downloads to finish and
+// then stops the manager. Transfers waiting for an import or for seeding to
+// finish are picked up again on the next start. If ctx is done first, the
+// manager is stopped anyway and ctx's error is returned.
+func (m *Manager) Drain(ctx context.Context) error {
+	m.StopPolling()
+
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	for !m.idle() {
+		select {
+		case <-ctx.Done():
+			m.Stop()
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+
+	m.Stop()
+	return nil
+}
+
+// idle reports whether no transfer is waiting for or being downloaded.
+func (m *Manager) idle() bool {
+	return m.transfers.Len() == 0 && m.downloads.Len() == 0 &&
+		m.inFlight.Load() == 0 && m.stats.active.Load() == 0
+}
+
+// Stop stops polling, orchestration, downloads and watchers, in that order,
+// and waits for all of them to return. The manager can be started again
+// afterwards.
+func (m *Manager) Stop() {
+	m.poller.Stop()
+	m.orchestrator.Stop()
+	m.downloader.Stop()
+	m.watchers.Stop()
+	m.cancel()
+}
@@ -0,0 +1,114 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStopPollingKeepsPipelineRunning(t *testing.T) {
+	manager := setupTestManager()
+	if err := manager.StartWithContext(context.Background()); err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+	defer manager.Stop()
+
+	manager.StopPolling()
+	if manager.poller.ctx.Err() == nil {
+		t.Error("expected poller context to be cancelled")
+	}
+
+	dir := filepath.Join(t.TempDir(), "Show")
+	done := make(chan DownloadDoneStatus, 1)
+	manager.downloads.Push(DownloadTargetMessage{
+		Target:   DownloadTarget{To: dir, TargetType: TargetTypeDirectory},
+		DoneChan: done,
+	})
+
+	select {
+	case status := <-done:
+		if status != DownloadStatusSuccess {
+			t.Errorf("expected success, got %v", status)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected download workers to keep running after StopPolling")
+	}
+}
+
+func TestDrainFinishesQueuedDownloads(t *testing.T) {
+	manager := setupTestManager()
+	if err := manager.StartWithContext(context.Background()); err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+
+	dir := filepath.Join(t.TempDir(), "Show")
+	manager.downloads.Push(DownloadTargetMessage{
+		Target:   DownloadTarget{To: dir, TargetType: TargetTypeDirectory},
+		DoneChan: make(chan DownloadDoneStatus, 1),
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := manager.Drain(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(dir); err != nil {
+		t.Errorf("expected queued download to finish before draining, stat err: %v", err)
+	}
+	if manager.watchers.ctx.Err() == nil {
+		t.Error("expected manager to be stopped after draining")
+	}
+}
+
+func TestDrainTimeout(t *testing.T) {
+	manager := setupTestManager()
+	if err := manager.StartWithContext(context.Background()); err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+	manager.inFlight.Add(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := manager.Drain(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+	if manager.downloader.ctx.Err() == nil {
+		t.Error("expected manager to be stopped after timing out")
+	}
+}
+
+func TestManagerRestart(t *testing.T) {
+	manager := setupTestManager()
+	for i := 0; i < 2; i++ {
+		if err := manager.StartWithContext(context.Background()); err != nil {
+			t.Fatalf("failed to start: %v", err)
+		}
+		if got := manager.downloadWorkerCount(); got != manager.config.DownloadWorkers {
+			t.Errorf("run %d: expected %d download workers, got %d", i, manager.config.DownloadWorkers, got)
+		}
+		manager.Stop()
+	}
+}
+
+func TestStartResetsSeen(t *testing.T) {
+	manager := setupTestManager()
+	manager.markSeen(1)
+	manager.markSeen(2)
+	manager.markFileSeen(3)
+	manager.abandoned[2] = true
+
+	if err := manager.StartWithContext(context.Background()); err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+	defer manager.Stop()
+
+	if manager.isSeen(1) || manager.isFileSeen(3) {
+		t.Error("expected seen transfers and folder items to be forgotten")
+	}
+	if !manager.isSeen(2) {
+		t.Error("expected an abandoned transfer to stay skipped")
+	}
+}
@@ -0,0 +1,78 @@
+package download
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ochronus/goputioarr/internal/services/putio"
+)
+
+const (
+	// listingCacheTTL is how long put.io folder listings are reused, long
+	// enough for transfers sharing a parent to be queued together but short
+	// enough that changes on put.io show up on the next poll.
+	listingCacheTTL = 30 * time.Second
+	// targetListingConcurrency bounds the put.io requests made at once while
+	// building the targets of one transfer.
+	targetListingConcurrency = 4
+)
+
+// listingCache keeps put.io folder listings for a short time, so transfers
+// that share a parent folder don't list it again. Safe for concurrent use.
+type listingCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu        sync.Mutex
+	entries   map[int64]cachedListing
+	lastSweep time.Time
+}
+
+type cachedListing struct {
+	response *putio.ListFileResponse
+	expires  time.Time
+}
+
+func newListingCache(ttl time.Duration) *listingCache {
+	return &listingCache{ttl: ttl, now: time.Now, entries: make(map[int64]cachedListing)}
+}
+
+// get returns the cached listing of fileID, if it hasn't expired.
+func (c *listingCache) get(fileID int64) (*putio.ListFileResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[fileID]
+	if !ok || !c.now().Before(entry.expires) {
+		return nil, false
+	}
+	return entry.response, true
+}
+
+// put caches the listing of fileID, dropping expired listings now and then.
+func (c *listingCache) put(fileID int64, response *putio.ListFileResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	if now.Sub(c.lastSweep) >= c.ttl {
+		for id, entry := range c.entries {
+			if !now.Before(entry.expires) {
+				delete(c.entries, id)
+			}
+		}
+		c.lastSweep = now
+	}
+	c.entries[fileID] = cachedListing{response: response, expires: now.Add(c.ttl)}
+}
+
+// listFiles lists fileID on put.io, reusing a recent listing if there is one.
+func (m *Manager) listFiles(fileID int64) (*putio.ListFileResponse, error) {
+	if response, ok := m.listings.get(fileID); ok {
+		return response, nil
+	}
+	response, err := m.putioClient.ListFiles(fileID)
+	if err != nil {
+		return nil, err
+	}
+	m.listings.put(fileID, response)
+	return response, nil
+}
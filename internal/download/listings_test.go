@@ -0,0 +1,144 @@
+package download
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ochronus/goputioarr/internal/services/putio"
+)
+
+func TestListingCacheExpires(t *testing.T) {
+	now := time.Unix(1000, 0)
+	cache := newListingCache(30 * time.Second)
+	cache.now = func() time.Time { return now }
+	response := &putio.ListFileResponse{Parent: putio.FileResponse{ID: 1}}
+
+	if _, ok := cache.get(1); ok {
+		t.Fatal("expected an empty cache")
+	}
+	cache.put(1, response)
+	if got, ok := cache.get(1); !ok || got != response {
+		t.Fatal("expected the cached listing")
+	}
+
+	now = now.Add(30 * time.Second)
+	if _, ok := cache.get(1); ok {
+		t.Fatal("expected the listing to expire")
+	}
+	cache.put(2, response)
+	if _, ok := cache.entries[1]; ok {
+		t.Error("expected expired listings to be swept")
+	}
+}
+
+// slowPutioClient lists a tree of folders slowly, tracking how many requests
+// run at once.
+type slowPutioClient struct {
+	mockPutioClient
+	active    atomic.Int32
+	maxActive atomic.Int32
+	mu        sync.Mutex
+	listed    map[int64]int
+}
+
+func (c *slowPutioClient) track() func() {
+	n := c.active.Add(1)
+	for {
+		highest := c.maxActive.Load()
+		if n <= highest || c.maxActive.CompareAndSwap(highest, n) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+	return func() { c.active.Add(-1) }
+}
+
+func (c *slowPutioClient) ListFiles(fileID int64) (*putio.ListFileResponse, error) {
+	defer c.track()()
+	c.mu.Lock()
+	c.listed[fileID]++
+	c.mu.Unlock()
+	return c.mockPutioClient.ListFiles(fileID)
+}
+
+func (c *slowPutioClient) GetFileURL(fileID int64) (string, error) {
+	defer c.track()()
+	return c.mockPutioClient.GetFileURL(fileID)
+}
+
+// seasonPack builds a folder 1 with folders 10..19, each holding two videos.
+func seasonPack() *slowPutioClient {
+	client := &slowPutioClient{listed: make(map[int64]int)}
+	client.listFilesByID = map[int64]*putio.ListFileResponse{
+		1: {Parent: putio.FileResponse{ID: 1, Name: "Show", FileType: "FOLDER"}},
+	}
+	client.fileURLs = map[int64]string{}
+	for i := int64(10); i < 20; i++ {
+		folder := putio.FileResponse{ID: i, Name: fmt.Sprintf("Season %d", i), FileType: "FOLDER"}
+		listing := &putio.ListFileResponse{Parent: folder}
+		for j := int64(0); j < 2; j++ {
+			id := i*100 + j
+			video := putio.FileResponse{ID: id, Name: fmt.Sprintf("episode%d.mkv", j), FileType: "VIDEO"}
+			listing.Files = append(listing.Files, video)
+			client.listFilesByID[id] = &putio.ListFileResponse{Parent: video}
+			client.fileURLs[id] = fmt.Sprintf("http://example.com/%d", id)
+		}
+		root := client.listFilesByID[1]
+		root.Files = append(root.Files, folder)
+		client.listFilesByID[i] = listing
+	}
+	return client
+}
+
+func TestRecurseDownloadTargetsInParallel(t *testing.T) {
+	manager := setupTestManager()
+	client := seasonPack()
+	manager.putioClient = client
+
+	targets, err := manager.recurseDownloadTargets(1, "hash", "", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(targets) != 1+10*3 {
+		t.Fatalf("expected 31 targets, got %d", len(targets))
+	}
+	if highest := client.maxActive.Load(); highest < 2 || highest > targetListingConcurrency {
+		t.Errorf("expected between 2 and %d concurrent requests, got %d", targetListingConcurrency, highest)
+	}
+
+	// Targets keep depth-first order: each folder before its episodes.
+	i := 1
+	for season := 10; season < 20; season++ {
+		dir := filepath.Join("/downloads", "Show", fmt.Sprintf("Season %d", season))
+		if targets[i].To != dir || targets[i].TargetType != TargetTypeDirectory {
+			t.Fatalf("expected %s at %d, got %+v", dir, i, targets[i])
+		}
+		for j := 0; j < 2; j++ {
+			want := filepath.Join(dir, fmt.Sprintf("episode%d.mkv", j))
+			if targets[i+1+j].To != want {
+				t.Fatalf("expected %s at %d, got %s", want, i+1+j, targets[i+1+j].To)
+			}
+		}
+		i += 3
+	}
+}
+
+func TestRecurseDownloadTargetsReusesListings(t *testing.T) {
+	manager := setupTestManager()
+	client := seasonPack()
+	manager.putioClient = client
+
+	for range 2 {
+		if _, err := manager.recurseDownloadTargets(10, "hash", "", true); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if client.listed[10] != 1 {
+		t.Errorf("expected the folder to be listed once, got %d", client.listed[10])
+	}
+}
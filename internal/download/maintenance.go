@@ -0,0 +1,45 @@
+package download
+
+import (
+	"context"
+	"time"
+)
+
+// defaultMaintenancePolling is how often put.io is polled during a
+// maintenance window unless maintenance.polling_interval is set.
+const defaultMaintenancePolling = 5 * time.Minute
+
+// maintenancePolling returns the put.io polling interval during a maintenance
+// window.
+func (m *Manager) maintenancePolling() time.Duration {
+	if m.config.Maintenance != nil && m.config.Maintenance.PollingInterval > 0 {
+		return m.config.Maintenance.PollingInterval.Duration()
+	}
+	return defaultMaintenancePolling
+}
+
+// waitForMaintenance holds back a download during the maintenance window and
+// lets it start once the window is over. It returns false if ctx is cancelled
+// first.
+func (m *Manager) waitForMaintenance(ctx context.Context) bool {
+	for {
+		remaining := m.config.Maintenance.Remaining(m.now())
+		if remaining <= 0 {
+			if m.inMaintenance.CompareAndSwap(true, false) {
+				m.logger.Info("Maintenance window over, resuming downloads")
+			}
+			return true
+		}
+		if m.inMaintenance.CompareAndSwap(false, true) {
+			m.logger.Infof("Maintenance window, pausing downloads for %s", remaining.Round(time.Second))
+		}
+
+		timer := time.NewTimer(remaining)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return false
+		case <-timer.C:
+		}
+	}
+}
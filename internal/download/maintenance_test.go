@@ -0,0 +1,49 @@
+package download
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ochronus/goputioarr/internal/config"
+)
+
+func TestWaitForMaintenance(t *testing.T) {
+	manager := setupTestManager()
+	manager.config.Maintenance = &config.MaintenanceConfig{Start: "02:00", End: "04:00"}
+	now := time.Date(2024, 3, 1, 1, 0, 0, 0, time.Local)
+	manager.now = func() time.Time { return now }
+
+	if !manager.waitForMaintenance(context.Background()) {
+		t.Fatal("expected downloads to start outside the maintenance window")
+	}
+
+	now = time.Date(2024, 3, 1, 3, 0, 0, 0, time.Local)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if manager.waitForMaintenance(ctx) {
+		t.Error("expected downloads to wait during the maintenance window")
+	}
+	if !manager.inMaintenance.Load() {
+		t.Error("expected the maintenance window to be recorded")
+	}
+
+	now = time.Date(2024, 3, 1, 4, 0, 0, 0, time.Local)
+	if !manager.waitForMaintenance(context.Background()) {
+		t.Fatal("expected downloads to resume after the maintenance window")
+	}
+	if manager.inMaintenance.Load() {
+		t.Error("expected the end of the maintenance window to be recorded")
+	}
+}
+
+func TestMaintenancePolling(t *testing.T) {
+	manager := setupTestManager()
+	if got := manager.maintenancePolling(); got != defaultMaintenancePolling {
+		t.Errorf("expected the default interval without a window, got %s", got)
+	}
+	manager.config.Maintenance = &config.MaintenanceConfig{Start: "02:00", End: "04:00", PollingInterval: config.Seconds(600)}
+	if got := manager.maintenancePolling(); got != 10*time.Minute {
+		t.Errorf("expected the configured interval, got %s", got)
+	}
+}
@@ -2,17 +2,30 @@ package download
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"math"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ochronus/goputioarr/internal/app"
 	"github.com/ochronus/goputioarr/internal/config"
+	"github.com/ochronus/goputioarr/internal/destination"
+	"github.com/ochronus/goputioarr/internal/hint"
+	"github.com/ochronus/goputioarr/internal/history"
+	"github.com/ochronus/goputioarr/internal/services/arr"
+	"github.com/ochronus/goputioarr/internal/services/notify"
 	"github.com/ochronus/goputioarr/internal/services/putio"
+	"github.com/ochronus/goputioarr/internal/services/unpack"
 	"github.com/sirupsen/logrus"
 )
 
@@ -21,37 +34,98 @@ type ArrServiceClient = app.ArrServiceClient
 
 // Manager handles the download orchestration
 type Manager struct {
-	container    *app.Container
-	config       *config.Config
-	putioClient  putio.ClientAPI
-	arrClients   []app.ArrServiceClient
-	transferChan chan TransferMessage
-	downloadChan chan DownloadTargetMessage
-	seen         map[uint64]bool
-	seenMu       sync.RWMutex
-	logger       *logrus.Logger
-
-	ctx    context.Context
-	cancel context.CancelFunc
-	wg     sync.WaitGroup
+	container   *app.Container
+	config      *config.Config
+	putioClient putio.ClientAPI
+	arrClients  []app.ArrServiceClient
+	destination destination.Backend
+	httpClient  *http.Client
+	transfers   *queue[TransferMessage]
+	downloads   *queue[DownloadTargetMessage]
+	seen        map[uint64]bool
+	seenFiles   map[int64]bool
+	// abandoned holds the seen transfers given up on after import_timeout,
+	// skipped until added again.
+	abandoned map[uint64]bool
+	seenMu    sync.RWMutex
+	logger    *logrus.Logger
+	startedAt time.Time
+	stats     downloadStats
+
+	workersMu     sync.Mutex
+	workerCancels []context.CancelFunc
+	nextWorkerID  int
+
+	// inFlight counts transfers whose downloads are being orchestrated.
+	inFlight atomic.Int64
+	// activeSlots limits the transfers downloaded at once to
+	// max_active_transfers; nil means no limit.
+	activeSlots chan struct{}
+
+	// freeSpace reports the space available in a directory; lowSpace is set
+	// while downloads wait for min_free_space.
+	freeSpace func(dir string) (uint64, error)
+	lowSpace  atomic.Bool
+
+	// now tells the time of day for the maintenance window; inMaintenance is
+	// set while downloads wait for the window to end.
+	now           func() time.Time
+	inMaintenance atomic.Bool
+
+	// restartDelay is how long a crashed worker waits before it's restarted.
+	restartDelay time.Duration
+
+	// pipelines makes sure each transfer is handled by one goroutine at a time.
+	pipelines *pipelineRegistry
+
+	listings *listingCache
+	fileURLs *fileURLCache
+	// skip matches the names of folders and files not to download.
+	skip config.NamePatterns
+
+	// ctx is the parent of every group's context; it outlives the groups so
+	// notifications can still be sent while they shut down.
+	ctx          context.Context
+	cancel       context.CancelFunc
+	poller       *group
+	orchestrator *group
+	downloader   *group
+	watchers     *group
 }
 
 // NewManager creates a new download manager
 func NewManager(container *app.Container) *Manager {
-	ctx, cancel := context.WithCancel(context.Background())
-
-	return &Manager{
+	m := &Manager{
 		container:    container,
 		config:       container.Config,
 		putioClient:  container.PutioClient,
 		arrClients:   container.ArrClients,
-		transferChan: make(chan TransferMessage, 100),
-		downloadChan: make(chan DownloadTargetMessage, 100),
+		destination:  container.Destination,
+		httpClient:   newHTTPClient(container.Config),
+		transfers:    newQueue[TransferMessage](),
+		downloads:    newQueue[DownloadTargetMessage](),
 		seen:         make(map[uint64]bool),
+		seenFiles:    make(map[int64]bool),
+		abandoned:    make(map[uint64]bool),
 		logger:       container.Logger,
-		ctx:          ctx,
-		cancel:       cancel,
+		startedAt:    time.Now().UTC(),
+		freeSpace:    freeSpace,
+		now:          time.Now,
+		restartDelay: workerRestartDelay,
+		pipelines:    newPipelineRegistry(),
+		listings:     newListingCache(listingCacheTTL),
+		fileURLs:     newFileURLCache(),
+	}
+	if m.destination == nil {
+		m.destination = destination.Local{}
+	}
+	// Validated with the configuration; invalid entries are ignored.
+	m.skip, _ = config.ParseNamePatterns(container.Config.SkipDirectories)
+	if n := container.Config.MaxActiveTransfers; n > 0 {
+		m.activeSlots = make(chan struct{}, n)
 	}
+	m.newGroups(context.Background())
+	return m
 }
 
 // Start begins the download manager's operations with a background context.
@@ -61,105 +135,191 @@ func (m *Manager) Start() error {
 
 // StartWithContext begins the download manager's operations using the provided parent context.
 func (m *Manager) StartWithContext(ctx context.Context) error {
-	// derive a cancellable context from the provided parent
-	m.ctx, m.cancel = context.WithCancel(ctx)
+	m.newGroups(ctx)
+	m.startedAt = time.Now().UTC()
+	// Nothing of the pipeline runs before the start, so transfers claimed
+	// or seen before a stop are picked up again from scratch.
+	m.pipelines.reset()
+	m.resetSeen()
 
 	// Start orchestration workers
 	for i := 0; i < m.config.OrchestrationWorkers; i++ {
-		m.wg.Add(1)
-		go m.orchestrationWorker(i)
+		name := fmt.Sprintf("orchestration worker %d", i)
+		m.orchestrator.Go(func() {
+			m.supervise(m.orchestrator.ctx, name, func() { m.orchestrationWorker(i) })
+		})
 	}
 
 	// Start download workers
-	for i := 0; i < m.config.DownloadWorkers; i++ {
-		m.wg.Add(1)
-		go m.downloadWorker(i)
+	m.workersMu.Lock()
+	m.workerCancels = nil
+	m.workersMu.Unlock()
+	m.setDownloadWorkers(m.config.DownloadWorkers)
+	if m.config.Autoscale.Enabled {
+		m.downloader.Go(m.runAutoscaler)
 	}
 
 	// Start the transfer producer
-	m.wg.Add(1)
-	go m.produceTransfers()
+	m.poller.Go(func() { m.supervise(m.poller.ctx, "transfer poller", m.produceTransfers) })
 
 	return nil
 }
 
-// Stop signals all workers to exit and waits for them to finish.
-func (m *Manager) Stop() {
-	m.cancel()
-	m.wg.Wait()
-}
-
 // orchestrationWorker handles transfer state transitions
 func (m *Manager) orchestrationWorker(id int) {
-	defer m.wg.Done()
-
 	for {
-		select {
-		case <-m.ctx.Done():
+		msg, ok := m.transfers.Pop(m.orchestrator.ctx)
+		if !ok {
 			return
-		case msg := <-m.transferChan:
-			switch msg.Type {
-			case MessageQueuedForDownload:
-				m.handleQueuedForDownload(msg.Transfer)
-			case MessageDownloaded:
-				m.wg.Add(1)
-				go m.watchForImport(msg.Transfer)
-			case MessageImported:
-				m.wg.Add(1)
-				go m.watchSeeding(msg.Transfer)
-			}
+		}
+		switch msg.Type {
+		case MessageQueuedForDownload:
+			m.queueForDownload(msg.Transfer)
+		case MessageDownloaded:
+			transfer := msg.Transfer
+			m.watchers.Go(func() {
+				m.recovered(fmt.Sprintf("import watcher for %s", transfer), func() { m.watchForImport(transfer) })
+			})
+		case MessageImported:
+			transfer := msg.Transfer
+			m.watchers.Go(func() {
+				m.recovered(fmt.Sprintf("seeding watcher for %s", transfer), func() { m.watchSeeding(transfer) })
+			})
 		}
 	}
 }
 
-// downloadWorker handles file downloads
-func (m *Manager) downloadWorker(id int) {
-	defer m.wg.Done()
+// queueForDownload hands a transfer that's ready for download to
+// runQueuedForDownload once it gets a slot under max_active_transfers. A
+// transfer that has to wait is reported as queued and waits on a goroutine of
+// its own, so the orchestration workers keep handling the other transfers.
+func (m *Manager) queueForDownload(transfer *Transfer) {
+	if release, ok := m.tryActiveSlot(); ok {
+		m.runQueuedForDownload(transfer, release)
+		return
+	}
 
+	m.track(transfer)
+	m.container.Transfers.SetStage(transfer.GetHash(), app.StageQueued)
+	m.transferLogger(transfer).Infof("%s: waiting, %d transfers are already downloading", transfer, cap(m.activeSlots))
+	m.inFlight.Add(1)
+	m.orchestrator.Go(func() {
+		defer m.inFlight.Add(-1)
+		if release, ok := m.acquireActiveSlot(); ok {
+			m.runQueuedForDownload(transfer, release)
+		}
+	})
+}
+
+// runQueuedForDownload handles a transfer that's ready for download, then
+// releases its active slot. If that panics, the transfer is no longer marked
+// seen, so the next poll picks it up again instead of leaving it stuck.
+func (m *Manager) runQueuedForDownload(transfer *Transfer, release func()) {
+	m.inFlight.Add(1)
+	defer m.inFlight.Add(-1)
+	defer release()
+	if m.recovered(fmt.Sprintf("download of %s", transfer), func() { m.handleQueuedForDownload(transfer) }) {
+		m.unmarkSeen(transfer.TransferID)
+	}
+}
+
+// downloadWorker handles file downloads until ctx is cancelled. While
+// downloads are paused or during the maintenance window, it holds on to the
+// target it took from the queue.
+func (m *Manager) downloadWorker(ctx context.Context, id int) {
 	for {
-		select {
-		case <-m.ctx.Done():
+		msg, ok := m.downloads.Pop(ctx)
+		if !ok {
+			return
+		}
+		if !m.waitForMaintenance(ctx) || !m.container.Pause.Wait(ctx) {
+			// Leave the target for another worker or the next start.
+			m.downloads.Push(msg)
 			return
-		case msg := <-m.downloadChan:
-			status := m.downloadTarget(&msg.Target)
-			select {
-			case <-m.ctx.Done():
-				return
-			case msg.DoneChan <- status:
-			}
 		}
+		if !m.waitForSpace(ctx, &msg.Target) {
+			// Leave the target for another worker or the next start.
+			m.downloads.Push(msg)
+			return
+		}
+		m.runTarget(msg)
 	}
 }
 
+// runTarget downloads msg's target and reports the result on its DoneChan,
+// which is buffered, so this never blocks. A download that panics is reported
+// as failed before the panic is passed on, so its transfer isn't left waiting.
+func (m *Manager) runTarget(msg DownloadTargetMessage) {
+	status := DownloadStatusFailed
+	defer func() { msg.DoneChan <- status }()
+	status = m.downloadTarget(&msg.Target)
+}
+
+// tryActiveSlot takes a slot under max_active_transfers if one is free and
+// returns the function releasing it.
+func (m *Manager) tryActiveSlot() (release func(), ok bool) {
+	if m.activeSlots == nil {
+		return func() {}, true
+	}
+	select {
+	case m.activeSlots <- struct{}{}:
+		return m.releaseActiveSlot, true
+	default:
+		return nil, false
+	}
+}
+
+// acquireActiveSlot waits for a slot under max_active_transfers and returns
+// the function releasing it. ok is false if the manager stopped first.
+func (m *Manager) acquireActiveSlot() (release func(), ok bool) {
+	if m.activeSlots == nil {
+		return func() {}, true
+	}
+	select {
+	case m.activeSlots <- struct{}{}:
+		return m.releaseActiveSlot, true
+	case <-m.orchestrator.ctx.Done():
+		return nil, false
+	}
+}
+
+func (m *Manager) releaseActiveSlot() {
+	<-m.activeSlots
+}
+
 // handleQueuedForDownload processes a transfer that's ready for download
 func (m *Manager) handleQueuedForDownload(transfer *Transfer) {
-	m.logger.Infof("%s: download started", transfer)
+	m.transferLogger(transfer).Infof("%s: download started", transfer)
+	transfer.MarkStarted()
+	m.track(transfer)
 
 	targets, err := m.getDownloadTargets(transfer)
 	if err != nil {
-		m.logger.Errorf("%s: failed to get download targets: %v", transfer, err)
+		withHint(m.transferLogger(transfer), err).Errorf("%s: failed to get download targets: %v", transfer, err)
+		m.container.Transfers.Fail(transfer.GetHash(), "failed to list files: "+hint.Format(err))
+		m.container.Pipeline.Failed()
+		m.downloadFailed(transfer, fmt.Sprintf("Listing the files of %s on put.io failed: %v", transfer.Name, err))
+		m.releasePipeline(transfer)
 		return
 	}
 
+	orderTargets(targets, m.config.DownloadOrder)
+
 	// Create channels for each target
 	doneChans := make([]chan DownloadDoneStatus, len(targets))
 	for i, target := range targets {
 		doneChans[i] = make(chan DownloadDoneStatus, 1)
-		select {
-		case <-m.ctx.Done():
-			return
-		case m.downloadChan <- DownloadTargetMessage{
+		m.downloads.Push(DownloadTargetMessage{
 			Target:   target,
 			DoneChan: doneChans[i],
-		}:
-		}
+		})
 	}
 
 	// Wait for all downloads to complete
 	allSuccess := true
 	for _, doneChan := range doneChans {
 		select {
-		case <-m.ctx.Done():
+		case <-m.orchestrator.ctx.Done():
 			return
 		case status := <-doneChan:
 			if status != DownloadStatusSuccess {
@@ -169,147 +329,449 @@ func (m *Manager) handleQueuedForDownload(transfer *Transfer) {
 	}
 
 	if allSuccess {
-		m.logger.Infof("%s: download done", transfer)
+		m.transferLogger(transfer).Infof("%s: download done", transfer)
+		transfer.MarkDownloaded()
+		if m.container.Unpacker != nil {
+			m.container.Transfers.SetStage(transfer.GetHash(), app.StageUnpacking)
+			targets = m.unpackTargets(targets)
+			m.recordTargets(transfer, targets)
+		}
 		transfer.SetTargets(targets)
-		select {
-		case <-m.ctx.Done():
+		if err := m.storeDownload(transfer); err != nil {
+			withHint(m.transferLogger(transfer), err).Errorf("%s: failed to move to %s: %v", transfer, m.destination.Name(), err)
+			m.container.Transfers.Fail(transfer.GetHash(), fmt.Sprintf("failed to move to %s: %s", m.destination.Name(), hint.Format(err)))
+			m.container.Pipeline.Failed()
+			m.downloadFailed(transfer, fmt.Sprintf("Moving %s to %s failed: %v", transfer.Name, m.destination.Name(), err))
+			m.releasePipeline(transfer)
 			return
-		case m.transferChan <- TransferMessage{
+		}
+		m.container.Transfers.SetStage(transfer.GetHash(), app.StageWaitingForImport)
+		m.transfers.Push(TransferMessage{
 			Type:     MessageDownloaded,
 			Transfer: transfer,
-		}:
-		}
+		})
 	} else {
-		m.logger.Warnf("%s: not all targets downloaded", transfer)
+		m.transferLogger(transfer).Warnf("%s: not all targets downloaded", transfer)
+		m.container.Transfers.Fail(transfer.GetHash(), "local download failed, see the logs for details")
+		m.container.Pipeline.Failed()
+		m.downloadFailed(transfer, fmt.Sprintf("Downloading %s failed after %d retries, see the logs for details.", transfer.Name, m.config.DownloadRetries))
+		m.releasePipeline(transfer)
 	}
 }
 
+// downloadFailed notifies the user that transfer couldn't be downloaded.
+func (m *Manager) downloadFailed(transfer *Transfer, message string) {
+	m.notify(notify.Event{
+		Type:       notify.EventDownloadFailed,
+		Title:      "Download failed",
+		Message:    message,
+		Transfer:   transfer.Name,
+		Hash:       transfer.GetHash(),
+		TransferID: transfer.TransferID,
+	})
+}
+
+// unpackTargets extracts the archives found in downloaded directories next to
+// them and returns the targets with the archive volumes replaced by the
+// extracted files, so imports are checked for those instead. The archives stay
+// on disk and are removed with the rest of the transfer after import. A
+// single-file archive transfer is extracted into a folder named after it,
+// which the archive is moved into and which becomes the top-level target. If
+// an archive can't be extracted, the original targets are returned.
+func (m *Manager) unpackTargets(targets []DownloadTarget) []DownloadTarget {
+	if len(targets) == 1 && targets[0].TargetType == TargetTypeFile && unpack.IsFirstVolume(targets[0].To) {
+		return m.unpackTopLevel(targets[0])
+	}
+
+	var extracted []DownloadTarget
+	unpacked := false
+	for _, target := range targets {
+		if target.TargetType != TargetTypeFile || !unpack.IsFirstVolume(target.To) {
+			continue
+		}
+		files, ok := m.extract(target, filepath.Dir(target.To))
+		if !ok {
+			return targets
+		}
+		extracted = append(extracted, files...)
+		unpacked = true
+	}
+	if !unpacked {
+		return targets
+	}
+
+	result := make([]DownloadTarget, 0, len(targets)+len(extracted))
+	for _, target := range targets {
+		if target.TargetType == TargetTypeFile && unpack.IsArchive(target.To) {
+			continue
+		}
+		result = append(result, target)
+	}
+	return append(result, extracted...)
+}
+
+// unpackTopLevel extracts a single-file archive transfer into a folder next to
+// it named after the archive.
+func (m *Manager) unpackTopLevel(target DownloadTarget) []DownloadTarget {
+	dir := strings.TrimSuffix(target.To, filepath.Ext(target.To))
+	if err := os.Mkdir(dir, 0777); err != nil {
+		m.targetLogger(&target).Warnf("%s: unpacking failed: %v", &target, err)
+		return []DownloadTarget{target}
+	}
+	files, ok := m.extract(target, dir)
+	if !ok {
+		os.RemoveAll(dir)
+		return []DownloadTarget{target}
+	}
+	archive := filepath.Join(dir, filepath.Base(target.To))
+	if err := os.Rename(target.To, archive); err != nil {
+		m.targetLogger(&target).Warnf("%s: failed to move into %s: %v", &target, dir, err)
+	}
+
+	topLevel := target
+	topLevel.To = dir
+	topLevel.TargetType = TargetTypeDirectory
+	return append([]DownloadTarget{topLevel}, files...)
+}
+
+// extract unpacks the archive at target into dest and returns the extracted
+// files as targets of the same transfer.
+func (m *Manager) extract(target DownloadTarget, dest string) ([]DownloadTarget, bool) {
+	m.targetLogger(&target).Infof("%s: unpacking", &target)
+	files, err := m.container.Unpacker.Extract(m.orchestrator.ctx, target.To, dest)
+	if err != nil {
+		m.targetLogger(&target).Warnf("%s: unpacking failed: %v", &target, err)
+		return nil, false
+	}
+	extracted := make([]DownloadTarget, 0, len(files))
+	for _, file := range files {
+		extracted = append(extracted, DownloadTarget{
+			To:           file,
+			TargetType:   TargetTypeFile,
+			TransferHash: target.TransferHash,
+			TransferID:   target.TransferID,
+		})
+	}
+	return extracted, true
+}
+
 // downloadTarget downloads a single target (file or directory)
 func (m *Manager) downloadTarget(target *DownloadTarget) DownloadDoneStatus {
 	switch target.TargetType {
 	case TargetTypeDirectory:
 		if _, err := os.Stat(target.To); os.IsNotExist(err) {
-			if err := os.MkdirAll(target.To, 0755); err != nil {
-				m.logger.Errorf("%s: failed to create directory: %v", target, err)
+			// Permissions come from the umask, like files created by the arrs.
+			if err := os.MkdirAll(target.To, 0777); err != nil {
+				withHint(m.targetLogger(target), localError(err)).Errorf("%s: failed to create directory: %v", target, err)
 				return DownloadStatusFailed
 			}
-			// Change ownership if running as root
-			if os.Getuid() == 0 {
-				if err := os.Chown(target.To, m.config.UID, -1); err != nil {
-					m.logger.Warnf("%s: failed to change ownership: %v", target, err)
-				}
+			if err := chownToUser(target.To, m.config.UID, m.config.GID); err != nil {
+				m.targetLogger(target).Warnf("%s: failed to change ownership: %v", target, err)
 			}
-			m.logger.Infof("%s: directory created", target)
+			m.targetLogger(target).Infof("%s: directory created", target)
 		}
 		return DownloadStatusSuccess
 
 	case TargetTypeFile:
-		if _, err := os.Stat(target.To); err == nil {
-			m.logger.Infof("%s: already exists", target)
+		if info, err := os.Stat(target.To); err == nil {
+			m.targetLogger(target).Infof("%s: already exists", target)
+			m.container.Transfers.AddProgress(target.TransferHash, info.Size())
 			return DownloadStatusSuccess
 		}
 
-		m.logger.Infof("%s: download started", target)
-		if err := m.fetchFile(target); err != nil {
-			m.logger.Errorf("%s: download failed: %v", target, err)
+		if target.Stream {
+			if err := m.writeStreamFile(target); err != nil {
+				withHint(m.targetLogger(target), err).Errorf("%s: failed to write stream file: %v", target, err)
+				return DownloadStatusFailed
+			}
+			m.targetLogger(target).Infof("%s: stream file written", target)
+			return DownloadStatusSuccess
+		}
+
+		m.targetLogger(target).Infof("%s: download started", target)
+		if err := m.fetchWithRetries(target); err != nil {
+			m.stats.failed.Add(1)
+			withHint(m.targetLogger(target), err).Errorf("%s: download failed: %v", target, err)
 			return DownloadStatusFailed
 		}
-		m.logger.Infof("%s: download succeeded", target)
+		m.stats.succeeded.Add(1)
+		m.targetLogger(target).Infof("%s: download succeeded", target)
 		return DownloadStatusSuccess
 	}
 
 	return DownloadStatusFailed
 }
 
-// fetchFile downloads a file from a URL
-func (m *Manager) fetchFile(target *DownloadTarget) error {
-	if target.From == "" {
-		return fmt.Errorf("no URL found for target")
+// fetchWithRetries fetches a file target, starting again up to
+// download_retries times if the download is aborted. Retries continue from
+// the data already downloaded, which is only discarded once no retry is left.
+func (m *Manager) fetchWithRetries(target *DownloadTarget) error {
+	m.stats.active.Add(1)
+	defer m.stats.active.Add(-1)
+
+	err := m.fetchFile(target, false)
+	for attempt := 1; err != nil && downloadAborted(err) && attempt <= m.config.DownloadRetries; attempt++ {
+		m.targetLogger(target).Warnf("%s: %v, retrying (%d/%d)", target, err, attempt, m.config.DownloadRetries)
+		m.container.Downloads.Retried()
+		err = m.fetchFile(target, true)
 	}
-
-	tmpPath := target.To + ".downloading"
-
-	// Create parent directory if needed
-	if err := os.MkdirAll(filepath.Dir(target.To), 0755); err != nil {
-		return err
+	if err != nil {
+		m.discardPartial(target)
 	}
+	return err
+}
 
-	tmpFile, err := os.Create(tmpPath)
+// fetchFile downloads a file target. put.io download URLs expire, so the URL
+// of a put.io file is resolved just before fetching it rather than when the
+// target was queued, and resolved again once if it has expired regardless.
+// The download is aborted if no data arrives for download_stall_timeout or it
+// takes longer than download_timeout; what was downloaded until then is kept,
+// and with resume set, the download continues from there if the server
+// supports range requests.
+func (m *Manager) fetchFile(target *DownloadTarget, resume bool) error {
+	url, err := m.targetURL(target)
 	if err != nil {
 		return err
 	}
-	defer tmpFile.Close()
 
-	ctx := m.ctx
-	if ctx == nil {
-		ctx = context.Background()
+	ctx, cancel := context.WithCancelCause(m.downloader.ctx)
+	defer cancel(nil)
+	if timeout := m.config.DownloadTimeout.Duration(); timeout > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeoutCause(ctx, timeout, errDownloadTimedOut)
+		defer cancelTimeout()
 	}
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.From, nil)
+	stallTimeout := m.config.DownloadStallTimeout.Duration()
+	var stall *time.Timer
+	if stallTimeout > 0 {
+		stall = time.AfterFunc(stallTimeout, func() { cancel(errDownloadStalled) })
+		defer stall.Stop()
+	}
+
+	tmpPath := partialPath(target)
+
+	// Create parent directory if needed
+	if err := os.MkdirAll(filepath.Dir(target.To), 0777); err != nil {
+		return localError(err)
+	}
+
+	tmpFile, offset, err := openPartial(tmpPath, resume)
 	if err != nil {
-		os.Remove(tmpPath)
-		return err
+		return localError(err)
 	}
+	defer tmpFile.Close()
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := m.get(ctx, url, offset)
+	if err == nil && target.FileID != 0 && urlExpired(resp.StatusCode) {
+		resp.Body.Close()
+		m.targetLogger(target).Infof("%s: download URL expired (%s), resolving it again", target, resp.Status)
+		m.fileURLs.forget(target.FileID)
+		if url, err = m.resolveFileURL(target.FileID); err == nil {
+			resp, err = m.get(ctx, url, offset)
+		}
+	}
 	if err != nil {
-		os.Remove(tmpPath)
-		return err
+		return m.failFetch(target, m.abortCause(ctx, err))
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		os.Remove(tmpPath)
-		return fmt.Errorf("HTTP error: %s", resp.Status)
+	switch {
+	case offset > 0 && resumesAt(resp, offset, target.Size):
+		m.targetLogger(target).Infof("%s: resuming at %d bytes", target, offset)
+	case resp.StatusCode == http.StatusOK:
+		if offset > 0 {
+			// The server sent the whole file instead of the rest of it.
+			m.targetLogger(target).Infof("%s: can't resume, downloading again", target)
+			m.container.Transfers.AddProgress(target.TransferHash, -offset)
+			if err := restartPartial(tmpFile); err != nil {
+				return m.failFetch(target, localError(err))
+			}
+		}
+	default:
+		return m.failFetch(target, fmt.Errorf("HTTP error: %s", resp.Status))
 	}
 
-	_, err = io.Copy(tmpFile, resp.Body)
+	_, err = io.Copy(countingWriter{w: tmpFile, n: &m.stats.bytes, onWrite: func(n int64) {
+		m.container.Transfers.AddProgress(target.TransferHash, n)
+		m.container.Pipeline.Downloaded(n)
+		if stall != nil && n > 0 {
+			stall.Reset(stallTimeout)
+		}
+	}}, resp.Body)
 	if err != nil {
-		os.Remove(tmpPath)
-		return err
+		// Write errors are the disk's; read errors are put.io's.
+		return m.failFetch(target, localError(m.abortCause(ctx, err)))
 	}
 
 	tmpFile.Close()
 
-	// Change ownership if running as root
-	if os.Getuid() == 0 {
-		if err := os.Chown(tmpPath, m.config.UID, -1); err != nil {
-			m.logger.Warnf("%s: failed to change ownership: %v", target, err)
-		}
+	if err := chownToUser(tmpPath, m.config.UID, m.config.GID); err != nil {
+		m.targetLogger(target).Warnf("%s: failed to change ownership: %v", target, err)
 	}
 
 	// Rename to final location
-	return os.Rename(tmpPath, target.To)
+	return localError(os.Rename(tmpPath, target.To))
+}
+
+// targetURL returns the URL to download target from: the one it was created
+// with, or a fresh one for its put.io file.
+func (m *Manager) targetURL(target *DownloadTarget) (string, error) {
+	if target.From != "" {
+		return target.From, nil
+	}
+	if target.FileID == 0 {
+		return "", fmt.Errorf("no URL found for target")
+	}
+	return m.resolveFileURL(target.FileID)
+}
+
+// resolveFileURL returns the download URL of a put.io file, reusing the one
+// from an earlier attempt while it's valid.
+func (m *Manager) resolveFileURL(fileID int64) (string, error) {
+	if url, ok := m.fileURLs.get(fileID); ok {
+		return url, nil
+	}
+	url, err := m.putioClient.GetFileURL(fileID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get download URL: %w", err)
+	}
+	if url == "" {
+		return "", fmt.Errorf("no URL found for file %d", fileID)
+	}
+	m.fileURLs.put(fileID, url)
+	return url, nil
+}
+
+// get requests url, asking for the part from offset on if it isn't 0.
+func (m *Manager) get(ctx context.Context, url string, offset int64) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	return m.httpClient.Do(req)
+}
+
+// newHTTPClient returns the client files are downloaded with. It has no
+// overall timeout, since downloads are long; download_timeout and
+// download_stall_timeout bound them instead.
+func newHTTPClient(cfg *config.Config) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	dialer := &net.Dialer{Timeout: cfg.DownloadConnectTimeout.Duration(), KeepAlive: 30 * time.Second}
+	transport.DialContext = dialer.DialContext
+	transport.TLSHandshakeTimeout = cfg.DownloadConnectTimeout.Duration()
+	transport.ResponseHeaderTimeout = cfg.DownloadResponseTimeout.Duration()
+	return &http.Client{Transport: transport}
+}
+
+var (
+	// errDownloadStalled aborts a download that received no data for
+	// download_stall_timeout.
+	errDownloadStalled = errors.New("download stalled")
+	// errDownloadTimedOut aborts a download that took longer than
+	// download_timeout.
+	errDownloadTimedOut = errors.New("download timed out")
+)
+
+// abortCause returns why ctx aborted a download, counting it, or err if the
+// download wasn't aborted by a stall or timeout.
+func (m *Manager) abortCause(ctx context.Context, err error) error {
+	switch cause := context.Cause(ctx); {
+	case errors.Is(cause, errDownloadStalled):
+		m.container.Downloads.Stalled()
+		return cause
+	case errors.Is(cause, errDownloadTimedOut):
+		m.container.Downloads.TimedOut()
+		return cause
+	}
+	return err
+}
+
+// downloadAborted reports whether a download was aborted for stalling or
+// taking too long, and is worth retrying.
+func downloadAborted(err error) bool {
+	return errors.Is(err, errDownloadStalled) || errors.Is(err, errDownloadTimedOut)
+}
+
+// urlExpired reports whether a download was refused because its put.io URL is
+// no longer valid.
+func urlExpired(status int) bool {
+	return status == http.StatusForbidden || status == http.StatusGone
 }
 
 // getDownloadTargets recursively builds the list of download targets for a transfer
 func (m *Manager) getDownloadTargets(transfer *Transfer) ([]DownloadTarget, error) {
-	m.logger.Infof("%s: generating targets", transfer)
+	m.transferLogger(transfer).Infof("%s: generating targets", transfer)
 
 	if transfer.FileID == nil {
 		return nil, fmt.Errorf("no file ID for transfer")
 	}
 
-	return m.recurseDownloadTargets(*transfer.FileID, transfer.GetHash(), "", true)
+	// Empty unless torrent-set-location or the transfer's label moved it
+	// elsewhere.
+	basePath, _ := m.container.TransferDirectory(transfer.GetHash())
+	targets, err := m.recurseDownloadTargets(*transfer.FileID, transfer.GetHash(), basePath, true)
+	for i := range targets {
+		targets[i].TransferID = transfer.TransferID
+	}
+	return targets, err
 }
 
-// recurseDownloadTargets recursively builds download targets
+// recurseDownloadTargets recursively builds download targets. Sibling folders
+// are listed in parallel, with at most targetListingConcurrency put.io
+// requests at a time; targets keep the order of a depth-first walk.
 func (m *Manager) recurseDownloadTargets(fileID int64, hash string, basePath string, topLevel bool) ([]DownloadTarget, error) {
+	return m.walkDownloadTargets(make(chan struct{}, targetListingConcurrency), m.skipPatterns(hash), fileID, hash, basePath, topLevel)
+}
+
+// skipPatterns returns the names not to download for the transfer with the
+// given hash: its label's skip_directories if set, or else the global ones.
+func (m *Manager) skipPatterns(hash string) config.NamePatterns {
+	if _, label, ok := m.container.TransferLabel(hash); ok && label.SkipDirectories != nil {
+		// Validated with the configuration; invalid entries are ignored.
+		skip, _ := config.ParseNamePatterns(label.SkipDirectories)
+		return skip
+	}
+	return m.skip
+}
+
+// walkDownloadTargets builds the targets below fileID, leaving out the ones
+// skip matches. sem is held only around put.io requests, so nested folders
+// can't deadlock waiting for it. File targets get their download URL when
+// they're fetched.
+func (m *Manager) walkDownloadTargets(sem chan struct{}, skip config.NamePatterns, fileID int64, hash string, basePath string, topLevel bool) ([]DownloadTarget, error) {
 	if basePath == "" {
 		basePath = m.config.DownloadDirectory
 	}
 
 	var targets []DownloadTarget
 
-	response, err := m.putioClient.ListFiles(fileID)
+	sem <- struct{}{}
+	response, err := m.listFiles(fileID)
+	<-sem
 	if err != nil {
 		return nil, err
 	}
 
-	to := filepath.Join(basePath, response.Parent.Name)
+	// put.io names end up on disk; keep them from escaping basePath and
+	// within the filesystem's length limits.
+	to, original, err := app.TargetPath(basePath, response.Parent.Name)
+	if err != nil {
+		return nil, err
+	}
+	if original != "" {
+		m.logger.Infof("%s: shortened to %s", original, to)
+	}
+	if !app.WithinDirectory(m.config.DownloadDirectory, to) {
+		return nil, fmt.Errorf("refusing %q: %s is outside the download directory", response.Parent.Name, to)
+	}
 
 	switch response.Parent.FileType {
 	case "FOLDER":
-		if !ShouldSkipDirectory(response.Parent.Name, m.config.SkipDirectories) {
+		// A transfer's own folder is downloaded whatever its name.
+		if topLevel || !skip.Match(response.Parent.Name) {
 			targets = append(targets, DownloadTarget{
 				From:         "",
 				To:           to,
@@ -318,76 +780,338 @@ func (m *Manager) recurseDownloadTargets(fileID int64, hash string, basePath str
 				TransferHash: hash,
 			})
 
-			for _, file := range response.Files {
-				childTargets, err := m.recurseDownloadTargets(file.ID, hash, to, false)
-				if err != nil {
-					return nil, err
+			children := make([][]DownloadTarget, len(response.Files))
+			errs := make([]error, len(response.Files))
+			var wg sync.WaitGroup
+			for i, file := range response.Files {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					defer m.recoverTo(fmt.Sprintf("listing of %s", to), &errs[i])
+					children[i], errs[i] = m.walkDownloadTargets(sem, skip, file.ID, hash, to, false)
+				}()
+			}
+			wg.Wait()
+			for i := range children {
+				if errs[i] != nil {
+					return nil, errs[i]
 				}
-				targets = append(targets, childTargets...)
+				targets = append(targets, children[i]...)
 			}
 		}
 
 	case "VIDEO":
-		url, err := m.putioClient.GetFileURL(response.Parent.ID)
-		if err != nil {
-			return nil, err
+		// A transfer that's a single file is downloaded whatever its name.
+		if !topLevel && skip.Match(response.Parent.Name) {
+			m.logger.Infof("%s: skipped", to)
+			break
 		}
-		targets = append(targets, DownloadTarget{
-			From:         url,
+		target := DownloadTarget{
+			FileID:       response.Parent.ID,
 			To:           to,
 			TargetType:   TargetTypeFile,
 			TopLevel:     topLevel,
 			TransferHash: hash,
-		})
+			Size:         response.Parent.Size,
+		}
+		if m.config.Streaming() {
+			streamTarget(&target)
+		}
+		targets = append(targets, target)
 	}
 
 	return targets, nil
 }
 
-// watchForImport watches for a transfer to be imported by arr services
+// watchForImport watches for a transfer to be imported by arr services. An
+// import reported via webhook checks the imports right away; polling the arr
+// history is the fallback. Either way every file must have been imported, so
+// the first episode of a pack doesn't get the rest cleaned up.
 func (m *Manager) watchForImport(transfer *Transfer) {
-	defer m.wg.Done()
-	m.logger.Infof("%s: watching imports", transfer)
+	m.transferLogger(transfer).Infof("%s: watching imports", transfer)
 
-	ticker := time.NewTicker(time.Duration(m.config.PollingInterval) * time.Second)
+	imports := m.container.Imports
+	importSignal := imports.Wait(transfer.GetHash())
+	defer imports.Forget(transfer.GetHash())
+
+	ticker := newJitteredTicker(m.config.PollingInterval.Duration())
 	defer ticker.Stop()
 
+	var timeout <-chan time.Time
+	if m.config.ImportTimeout > 0 {
+		timer := time.NewTimer(m.config.ImportTimeout.Duration())
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
 	for {
 		select {
-		case <-m.ctx.Done():
+		case <-m.watchers.ctx.Done():
 			return
+		case <-timeout:
+			if m.handleImportTimeout(transfer) {
+				m.abandonTransfer(transfer)
+				return
+			}
+			timeout = nil
+		case <-importSignal:
+			m.transferLogger(transfer).Debugf("%s: import reported by webhook, checking imports", transfer)
+			if m.isImported(transfer) {
+				m.handleImported(transfer)
+				return
+			}
 		case <-ticker.C:
 			if m.isImported(transfer) {
-				m.logger.Infof("%s: imported", transfer)
-
-				// Clean up downloaded files
-				topLevel := transfer.GetTopLevel()
-				if topLevel != nil {
-					info, err := os.Stat(topLevel.To)
-					if err == nil {
-						if info.IsDir() {
-							os.RemoveAll(topLevel.To)
-						} else {
-							os.Remove(topLevel.To)
-						}
-						m.logger.Infof("%s: deleted", topLevel)
-					}
-				}
-
-				select {
-				case <-m.ctx.Done():
-					return
-				case m.transferChan <- TransferMessage{
-					Type:     MessageImported,
-					Transfer: transfer,
-				}:
-				}
+				m.handleImported(transfer)
 				return
 			}
 		}
 	}
 }
 
+// handleImported records an imported transfer in the history, cleans up its
+// local files and hands it over to the seeding watcher.
+func (m *Manager) handleImported(transfer *Transfer) {
+	m.transferLogger(transfer).Infof("%s: imported", transfer)
+	transfer.MarkImported()
+	m.container.Transfers.SetStage(transfer.GetHash(), app.StageImported)
+	m.container.Pipeline.Completed(transfer.GrabToImport())
+	m.recordHistory(transfer)
+
+	switch {
+	case !m.config.ShouldDeleteLocalAfterImport():
+		m.transferLogger(transfer).Infof("%s: keeping local files", transfer)
+	case !m.importVerified(transfer):
+		m.transferLogger(transfer).Warnf("%s: could not verify the import, keeping local files", transfer)
+	default:
+		m.deleteLocalFiles(transfer)
+	}
+
+	m.transfers.Push(TransferMessage{
+		Type:     MessageImported,
+		Transfer: transfer,
+	})
+}
+
+// handleImportTimeout flags a transfer that wasn't imported within
+// import_timeout as stalled, sends a notification and applies the configured
+// import_timeout_action. It reports whether watching for the import should stop.
+func (m *Manager) handleImportTimeout(transfer *Transfer) bool {
+	transfer.SetStalled(true)
+
+	action := m.config.ImportTimeoutAction
+	if action == "" {
+		action = config.ImportTimeoutKeep
+	}
+	m.transferLogger(transfer).Warnf("%s: not imported after %s, flagged as stalled (action: %s)", transfer, m.config.ImportTimeout, action)
+	m.container.Transfers.SetError(transfer.GetHash(), fmt.Sprintf("not imported after %s", m.config.ImportTimeout))
+	m.container.Pipeline.Failed()
+
+	m.notify(notify.Event{
+		Type:       notify.EventImportTimeout,
+		Title:      "Import timed out",
+		Message:    fmt.Sprintf("%s was not imported after %s (action: %s)", transfer.Name, m.config.ImportTimeout, action),
+		Transfer:   transfer.Name,
+		Hash:       transfer.GetHash(),
+		TransferID: transfer.TransferID,
+	})
+
+	switch action {
+	case config.ImportTimeoutDeleteLocal:
+		m.deleteLocalFiles(transfer)
+		return true
+	case config.ImportTimeoutBlocklist:
+		for _, svc := range m.arrClients {
+			found, err := svc.Client.Blocklist(transfer.GetHash())
+			if err != nil {
+				m.logArrError(m.transferLogger(transfer), err, "%s: failed to blocklist in %s: %v", transfer, svc.Name, err)
+				continue
+			}
+			if found {
+				m.transferLogger(transfer).Infof("%s: blocklisted in %s", transfer, svc.Name)
+			}
+		}
+		m.deleteLocalFiles(transfer)
+		return true
+	default:
+		return false
+	}
+}
+
+// deleteLocalFiles removes the transfer's top-level download target from disk
+func (m *Manager) deleteLocalFiles(transfer *Transfer) {
+	topLevel := transfer.GetTopLevel()
+	if topLevel == nil {
+		return
+	}
+	if err := m.destination.Remove(m.watchers.ctx, topLevel.To); err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			m.targetLogger(topLevel).Warnf("%s: failed to delete from %s: %v", topLevel, m.destination.Name(), err)
+		}
+		return
+	}
+	m.targetLogger(topLevel).Infof("%s: deleted", topLevel)
+}
+
+// storeDownload hands the downloaded files of transfer over to the
+// destination backend.
+func (m *Manager) storeDownload(transfer *Transfer) error {
+	topLevel := transfer.GetTopLevel()
+	if topLevel == nil {
+		return nil
+	}
+	m.transferLogger(transfer).Debugf("%s: storing in %s", transfer, m.destination.Name())
+	return m.destination.Store(m.orchestrator.ctx, topLevel.To)
+}
+
+// importVerified reports whether the local files of an imported transfer can be
+// deleted without losing data: every file must either be gone already (moved
+// by the arr), have other hard links (hardlinked into the library) or have a
+// copy at the importedPath recorded in the arr history.
+func (m *Manager) importVerified(transfer *Transfer) bool {
+	var unlinked []DownloadTarget
+	infos := make(map[string]os.FileInfo)
+	for _, target := range transfer.GetFileTargets() {
+		info, err := os.Stat(target.To)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			m.targetLogger(&target).Warnf("%s: unable to stat: %v", &target, err)
+			return false
+		}
+		if linkCount(info) > 1 {
+			continue
+		}
+		unlinked = append(unlinked, target)
+		infos[target.To] = info
+	}
+	if len(unlinked) == 0 {
+		return true
+	}
+
+	copied := m.importedCopies(unlinked, infos)
+	for _, target := range unlinked {
+		if !copied[target.To] {
+			m.targetLogger(&target).Warnf("%s: no hard link or imported copy found", &target)
+			return false
+		}
+	}
+	return true
+}
+
+// importedCopies returns the targets with a copy at the importedPath an arr
+// recorded for them, reading each arr's history once.
+func (m *Manager) importedCopies(targets []DownloadTarget, infos map[string]os.FileInfo) map[string]bool {
+	copied := make(map[string]bool, len(targets))
+	for _, svc := range m.arrClients {
+		var paths []string
+		for _, target := range targets {
+			if !copied[target.To] {
+				paths = append(paths, config.MapPath(target.To, svc.PathMappings))
+			}
+		}
+		if len(paths) == 0 {
+			break
+		}
+		imports, err := svc.Client.FindImports(paths)
+		if err != nil {
+			m.logArrError(m.logger.WithField("arr", svc.Name), err, "Error looking up imported paths from %s: %v", svc.Name, err)
+			continue
+		}
+		for _, target := range targets {
+			found, ok := imports[config.MapPath(target.To, svc.PathMappings)]
+			if !ok || found.ImportedPath == "" || copied[target.To] {
+				continue
+			}
+			localPath := config.UnmapPath(found.ImportedPath, svc.PathMappings)
+			imported, err := os.Stat(localPath)
+			if err != nil {
+				m.targetLogger(&target).Debugf("%s: imported copy %s not accessible: %v", &target, localPath, err)
+				continue
+			}
+			copied[target.To] = isImportedCopy(infos[target.To], imported, found.Exact)
+		}
+	}
+	return copied
+}
+
+// isImportedCopy reports whether imported is target's file, or a copy of it:
+// the same size and either recorded for target's own path (arrs rename the
+// files they import) or named like it. A record for a folder holding target
+// alone doesn't say which of its files was imported.
+func isImportedCopy(target, imported os.FileInfo, exact bool) bool {
+	if os.SameFile(target, imported) {
+		return true
+	}
+	if imported.Size() != target.Size() {
+		return false
+	}
+	return exact || strings.EqualFold(imported.Name(), target.Name())
+}
+
+// logArrError logs a failed arr request. Requests skipped because the service
+// keeps failing are only logged at debug level; the breaker already warned.
+func (m *Manager) logArrError(log *logrus.Entry, err error, format string, args ...interface{}) {
+	if errors.Is(err, arr.ErrCircuitOpen) {
+		log.Debugf(format, args...)
+		return
+	}
+	withHint(log, err).Errorf(format, args...)
+}
+
+// withHint returns log with the hint of err, if it has one, as a field.
+func withHint(log logrus.FieldLogger, err error) logrus.FieldLogger {
+	if h := hint.Of(err); h != "" {
+		return log.WithField("hint", h)
+	}
+	return log
+}
+
+// transferLogger returns a logger that tags each line with the transfer's
+// correlation ID, its put.io transfer ID, which the RPC handlers log too.
+func (m *Manager) transferLogger(transfer *Transfer) *logrus.Entry {
+	return m.logger.WithField("transfer_id", transfer.TransferID)
+}
+
+// targetLogger is transferLogger for a download target.
+func (m *Manager) targetLogger(target *DownloadTarget) *logrus.Entry {
+	return m.logger.WithField("transfer_id", target.TransferID)
+}
+
+// recordHistory adds a completed transfer to the download history, if enabled
+func (m *Manager) recordHistory(transfer *Transfer) {
+	if m.container.History == nil {
+		return
+	}
+	ts := transfer.GetTimestamps()
+	entry := history.Entry{
+		Name:         transfer.Name,
+		Hash:         transfer.GetHash(),
+		Size:         transfer.Size,
+		ImportedBy:   transfer.GetImportedBy(),
+		StartedAt:    ts.Started,
+		DownloadedAt: ts.Downloaded,
+		ImportedAt:   ts.Imported,
+		CompletedAt:  time.Now(),
+	}
+	if err := m.container.History.Record(entry); err != nil {
+		m.transferLogger(transfer).Warnf("%s: failed to record history: %v", transfer, err)
+	}
+}
+
+// notify sends an event through the configured notifier, if any
+func (m *Manager) notify(event notify.Event) {
+	if m.container.Notifier == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(m.ctx, 30*time.Second)
+	defer cancel()
+	if err := m.container.Notifier.Notify(ctx, event); err != nil {
+		m.logger.Warnf("Failed to send %s notification: %v", event.Type, err)
+	}
+}
+
 // isImported checks if all file targets have been imported by arr services
 func (m *Manager) isImported(transfer *Transfer) bool {
 	fileTargets := transfer.GetFileTargets()
@@ -399,17 +1123,19 @@ func (m *Manager) isImported(transfer *Transfer) bool {
 		return false
 	}
 
+	importedBy := ""
 	for _, target := range fileTargets {
 		imported := false
 		for _, svc := range m.arrClients {
-			isImported, err := svc.Client.CheckImported(target.To)
+			isImported, err := svc.Client.CheckImported(config.MapPath(target.To, svc.PathMappings))
 			if err != nil {
-				m.logger.Errorf("Error checking import from %s: %v", svc.Name, err)
+				m.logArrError(m.targetLogger(&target), err, "Error checking import from %s: %v", svc.Name, err)
 				continue
 			}
 			if isImported {
-				m.logger.Infof("%s: found imported by %s", &target, svc.Name)
+				m.targetLogger(&target).Infof("%s: found imported by %s", &target, svc.Name)
 				imported = true
+				importedBy = svc.Name
 				break
 			}
 		}
@@ -418,130 +1144,445 @@ func (m *Manager) isImported(transfer *Transfer) bool {
 		}
 	}
 
+	transfer.SetImportedBy(importedBy)
 	return true
 }
 
 // watchSeeding watches for a transfer to stop seeding
 func (m *Manager) watchSeeding(transfer *Transfer) {
-	defer m.wg.Done()
-	m.logger.Infof("%s: watching seeding", transfer)
+	if transfer.FolderImport {
+		// Nothing seeds an import folder item, so it's done once imported.
+		m.finishTransfer(transfer)
+		return
+	}
+	m.transferLogger(transfer).Infof("%s: watching seeding", transfer)
 
-	ticker := time.NewTicker(time.Duration(m.config.PollingInterval) * time.Second)
+	ticker := newJitteredTicker(m.config.PollingInterval.Duration())
 	defer ticker.Stop()
 
+	// A label's seed_time stops seeding early.
+	var seedTimeout <-chan time.Time
+	if name, label, ok := m.container.TransferLabel(transfer.GetHash()); ok && label.SeedTime > 0 {
+		m.transferLogger(transfer).Infof("%s: seeding for up to %s (label %s)", transfer, label.SeedTime.Duration(), name)
+		timer := time.NewTimer(label.SeedTime.Duration())
+		defer timer.Stop()
+		seedTimeout = timer.C
+	}
+
 	for {
 		select {
-		case <-m.ctx.Done():
+		case <-m.watchers.ctx.Done():
+			return
+		case <-seedTimeout:
+			m.transferLogger(transfer).Infof("%s: seed time reached", transfer)
+			m.removeSeeded(transfer)
 			return
 		case <-ticker.C:
 			resp, err := m.putioClient.GetTransfer(transfer.TransferID)
 			if err != nil {
-				m.logger.Warnf("%s: failed to get transfer status: %v", transfer, err)
+				m.transferLogger(transfer).Warnf("%s: failed to get transfer status: %v", transfer, err)
 				continue
 			}
 
 			if resp.Transfer.Status != "SEEDING" {
-				m.logger.Infof("%s: stopped seeding", transfer)
+				m.transferLogger(transfer).Infof("%s: stopped seeding", transfer)
+				m.removeSeeded(transfer)
+				return
+			}
+		}
+	}
+}
 
-				// Remove transfer from put.io
-				if err := m.putioClient.RemoveTransfer(transfer.TransferID); err != nil {
-					m.logger.Warnf("%s: failed to remove transfer: %v", transfer, err)
-				} else {
-					m.logger.Infof("%s: removed from put.io", transfer)
-				}
+// removeSeeded removes a transfer that's done seeding from put.io and
+// finishes it.
+func (m *Manager) removeSeeded(transfer *Transfer) {
+	if err := m.putioClient.RemoveTransfer(transfer.TransferID); err != nil {
+		m.transferLogger(transfer).Warnf("%s: failed to remove transfer: %v", transfer, err)
+	} else {
+		m.transferLogger(transfer).Infof("%s: removed from put.io", transfer)
+	}
 
-				// Delete remote files
-				if transfer.FileID != nil {
-					if err := m.putioClient.DeleteFile(*transfer.FileID); err != nil {
-						m.logger.Warnf("%s: unable to delete remote files: %v", transfer, err)
-					} else {
-						m.logger.Infof("%s: deleted remote files", transfer)
-					}
-				}
+	m.transferLogger(transfer).Infof("%s: done seeding", transfer)
+	m.finishTransfer(transfer)
+}
 
-				m.logger.Infof("%s: done seeding", transfer)
-				return
-			}
+// finishTransfer deletes the put.io files of a transfer that's done, unless
+// they're kept, and stops tracking it.
+func (m *Manager) finishTransfer(transfer *Transfer) {
+	if !m.config.ShouldDeleteRemoteFiles(transfer.GetImportedBy()) {
+		m.transferLogger(transfer).Infof("%s: keeping remote files", transfer)
+	} else if transfer.FileID != nil {
+		if err := m.putioClient.DeleteFile(*transfer.FileID); err != nil {
+			m.transferLogger(transfer).Warnf("%s: unable to delete remote files: %v", transfer, err)
+		} else {
+			m.transferLogger(transfer).Infof("%s: deleted remote files", transfer)
 		}
 	}
+
+	m.forgetTransfer(transfer)
+}
+
+// abandonTransfer gives up on a transfer whose local files were removed after
+// import_timeout. It's forgotten like a finished transfer, and the poller
+// skips it until it's added again.
+func (m *Manager) abandonTransfer(transfer *Transfer) {
+	m.forgetTransfer(transfer)
+	if transfer.FolderImport {
+		return
+	}
+	m.seenMu.Lock()
+	defer m.seenMu.Unlock()
+	m.abandoned[transfer.TransferID] = true
+}
+
+// forgetTransfer takes a transfer out of the pipeline and drops what the
+// registries keep for it.
+func (m *Manager) forgetTransfer(transfer *Transfer) {
+	m.releasePipeline(transfer)
+	m.container.Transfers.Forget(transfer.GetHash())
+	if err := m.container.Locations.Forget(transfer.GetHash()); err != nil {
+		m.transferLogger(transfer).Warnf("%s: failed to update transfer locations: %v", transfer, err)
+	}
+	if err := m.container.Labels.Forget(transfer.GetHash()); err != nil {
+		m.transferLogger(transfer).Warnf("%s: failed to update transfer labels: %v", transfer, err)
+	}
+	if _, err := m.container.Ownership.Remove(transfer.GetHash()); err != nil {
+		m.transferLogger(transfer).Warnf("%s: failed to update transfer ownership: %v", transfer, err)
+	}
 }
 
 // produceTransfers monitors put.io for new transfers
 func (m *Manager) produceTransfers() {
-	defer m.wg.Done()
-
 	m.logger.Info("Checking unfinished transfers")
 
 	// Check existing transfers on startup
 	m.checkExistingTransfers()
+	m.checkExistingFolderImports()
 
 	m.logger.Info("Done checking for unfinished transfers. Starting to monitor transfers.")
 
-	ticker := time.NewTicker(time.Duration(m.config.PollingInterval) * time.Second)
+	ticker := newJitteredTicker(m.config.PollingInterval.Duration())
 	defer ticker.Stop()
 
 	lastLogTime := time.Now()
+	lastSummary := lastLogTime
+
+	// While put.io keeps failing, polls are skipped until retryAt.
+	var failures int
+	var failingSince, retryAt time.Time
+	// authLost is set once the user was told put.io rejects the API key.
+	var authLost bool
+	// During the maintenance window, put.io is polled less often.
+	var lastPoll time.Time
+	// Between full listings, paging stops at settledID.
+	var settledID uint64
+	var lastFullList time.Time
 
 	for {
 		select {
-		case <-m.ctx.Done():
+		case <-m.poller.ctx.Done():
 			return
-		case <-ticker.C:
-			listResp, err := m.putioClient.ListTransfers()
+		case now := <-ticker.C:
+			if now.Before(retryAt) {
+				continue
+			}
+			if m.config.Maintenance.Remaining(m.now()) > 0 && now.Sub(lastPoll) < m.maintenancePolling() {
+				continue
+			}
+			lastPoll = now
+			full := settledID == 0 || now.Sub(lastFullList) >= fullListInterval
+			listResp, err := m.listTransfers(full, settledID)
 			if err != nil {
-				m.logger.Warnf("List put.io transfers failed. Retrying..: %v", err)
+				if failures == 0 {
+					failingSince = now
+				}
+				failures++
+				delay := pollBackoff(m.config.PollingInterval.Duration(), failures)
+				retryAt = now.Add(delay)
+				withHint(m.logger, err).Warnf("List put.io transfers failed (%d in a row), retrying in %s: %v", failures, delay, err)
+				m.heartbeat(failures, err)
+				if !authLost && isAuthError(err) {
+					authLost = true
+					m.putioAuthFailed(err)
+				}
 				continue
 			}
+			authLost = false
+			if failures > 0 {
+				m.putioRecovered(failures, now.Sub(failingSince))
+				failures, retryAt = 0, time.Time{}
+			}
+			m.heartbeat(0, nil)
 
-			for _, pt := range listResp.Transfers {
-				if m.isSeen(pt.ID) || !pt.IsDownloadable() {
-					continue
-				}
+			m.checkStalls(listResp.Transfers, now)
+			m.container.Transfers.ExpireFailed(failedStateTTL)
+			m.queueReadyTransfers(listResp.Transfers)
+			m.queueFolderImports()
 
-				transfer := NewTransfer(m.config, &pt)
-				m.logger.Infof("%s: ready for download", transfer)
+			if full {
+				lastFullList = now
+				settledID = m.settledID(listResp.Transfers)
 
-				select {
-				case <-m.ctx.Done():
-					return
-				case m.transferChan <- TransferMessage{
-					Type:     MessageQueuedForDownload,
-					Transfer: transfer,
-				}:
+				// Clean up seen list
+				activeIDs := make(map[uint64]bool)
+				for _, t := range listResp.Transfers {
+					activeIDs[t.ID] = true
 				}
-
-				m.markSeen(pt.ID)
+				m.cleanupSeen(activeIDs)
 			}
 
-			// Clean up seen list
-			activeIDs := make(map[uint64]bool)
-			for _, t := range listResp.Transfers {
-				activeIDs[t.ID] = true
-			}
-			m.cleanupSeen(activeIDs)
-
 			// Log status periodically
 			if time.Since(lastLogTime) >= 60*time.Second {
 				m.logger.Infof("Active transfers: %d", len(listResp.Transfers))
 				for _, pt := range listResp.Transfers {
 					transfer := NewTransfer(m.config, &pt)
-					m.logger.Infof("  %s", transfer)
+					m.transferLogger(transfer).Infof("  %s", transfer)
 				}
+				m.logQueueDepths()
+				m.logPutioCalls()
+				m.logDownloadCounters()
 				lastLogTime = time.Now()
 			}
+			if m.config.Notifications.DailySummary && time.Since(lastSummary) >= 24*time.Hour {
+				m.dailySummary()
+				lastSummary = time.Now()
+			}
+		}
+	}
+}
+
+// failedStateTTL is how long torrent-get reports a failed local download
+// before its state is dropped.
+const failedStateTTL = 24 * time.Hour
+
+// fullListInterval is how often every page of put.io transfers is listed.
+// Polls in between stop paging at the settled transfers.
+const fullListInterval = 15 * time.Minute
+
+// listTransfers lists every put.io transfer if full is set, and otherwise
+// stops paging at the page reaching settledID.
+func (m *Manager) listTransfers(full bool, settledID uint64) (*putio.ListTransferResponse, error) {
+	if full {
+		return m.putioClient.ListTransfers()
+	}
+	return m.putioClient.ListTransfersSince(settledID)
+}
+
+// settledID returns the highest transfer ID below which every listed transfer
+// is settled, so polls can stop paging there until the next full listing.
+func (m *Manager) settledID(transfers []putio.Transfer) uint64 {
+	var highest uint64
+	lowestUnsettled := uint64(math.MaxUint64)
+	for _, pt := range transfers {
+		highest = max(highest, pt.ID)
+		if !m.isSettled(&pt) {
+			lowestUnsettled = min(lowestUnsettled, pt.ID)
+		}
+	}
+	if lowestUnsettled == math.MaxUint64 {
+		return highest
+	}
+	return lowestUnsettled - 1
+}
+
+// isSettled reports whether nothing more is expected of a put.io transfer: it
+// failed or finished on put.io and was either handled already or isn't
+// relevant. Held and abandoned transfers aren't settled.
+func (m *Manager) isSettled(pt *putio.Transfer) bool {
+	if pt.Status != "ERROR" && !pt.IsDownloadable() {
+		return false
+	}
+	if pt.Hash != nil && m.container.Holds.Has(*pt.Hash) {
+		return false
+	}
+	m.seenMu.RLock()
+	handled := m.seen[pt.ID] && !m.abandoned[pt.ID]
+	m.seenMu.RUnlock()
+	return handled || !m.isRelevant(pt)
+}
+
+// maxPollBackoff caps how long polling backs off while put.io keeps failing.
+const maxPollBackoff = 5 * time.Minute
+
+// pollBackoff returns how long to wait before polling put.io again after
+// failures consecutive failed polls: the interval, doubled for each failure
+// after the first, capped at maxPollBackoff (or the interval, if longer).
+func pollBackoff(interval time.Duration, failures int) time.Duration {
+	limit := max(interval, maxPollBackoff)
+	delay := interval
+	for i := 1; i < failures && delay < limit; i++ {
+		delay *= 2
+	}
+	return min(delay, limit)
+}
+
+// heartbeatFailAfter is how many polls in a row must fail before the
+// heartbeat reports a failure; a single failed poll is routine.
+const heartbeatFailAfter = 3
+
+// heartbeat reports the outcome of a poll to the heartbeat URL, if one is
+// configured, without holding up polling. failures is the number of polls in
+// a row that failed, the last with pollErr.
+func (m *Manager) heartbeat(failures int, pollErr error) {
+	pinger := m.container.Heartbeat
+	if pinger == nil || (failures > 0 && failures < heartbeatFailAfter) {
+		return
+	}
+	go m.recovered("heartbeat", func() {
+		ctx, cancel := context.WithTimeout(m.ctx, 30*time.Second)
+		defer cancel()
+		var err error
+		if failures == 0 {
+			err = pinger.Success(ctx)
+		} else {
+			err = pinger.Fail(ctx, fmt.Sprintf("List put.io transfers failed %d times in a row: %v", failures, pollErr))
+		}
+		if err != nil {
+			m.logger.Warnf("Failed to ping heartbeat_url: %v", err)
 		}
+	})
+}
+
+// putioRecovered reports that put.io responds again after failed polls.
+func (m *Manager) putioRecovered(failures int, downFor time.Duration) {
+	message := fmt.Sprintf("put.io is reachable again after %d failed polls over %s", failures, downFor.Round(time.Second))
+	m.logger.Info(message)
+	m.notify(notify.Event{
+		Type:    notify.EventPutioRecovered,
+		Title:   "put.io reachable again",
+		Message: message,
+	})
+}
+
+// putioAuthFailed reports that put.io rejected the API key.
+func (m *Manager) putioAuthFailed(err error) {
+	m.logger.Errorf("put.io rejected the API key, generate a new one with get-token: %v", err)
+	m.notify(notify.Event{
+		Type:    notify.EventPutioAuthFailed,
+		Title:   "put.io API key rejected",
+		Message: fmt.Sprintf("put.io rejected the API key (%v). Nothing is downloaded until putio.api_key is replaced; generate a new key with get-token.", err),
+	})
+}
+
+// isAuthError reports whether err is put.io rejecting the API key.
+func isAuthError(err error) bool {
+	var httpErr *putio.HTTPError
+	return errors.As(err, &httpErr) &&
+		(httpErr.StatusCode == http.StatusUnauthorized || httpErr.StatusCode == http.StatusForbidden)
+}
+
+// queueReadyTransfers queues the downloadable transfers the manager hasn't
+// seen yet. Transfers added paused are skipped until torrent-start releases
+// them, so the next poll picks them up.
+func (m *Manager) queueReadyTransfers(transfers []putio.Transfer) {
+	for _, pt := range transfers {
+		if (m.isSeen(pt.ID) && !m.readded(&pt)) || !pt.IsDownloadable() || !m.isRelevant(&pt) {
+			continue
+		}
+		if pt.Hash != nil && m.container.Holds.Has(*pt.Hash) {
+			continue
+		}
+
+		transfer := NewTransfer(m.config, &pt)
+		m.markSeen(pt.ID)
+		if !m.claimPipeline(transfer) {
+			continue
+		}
+		m.transferLogger(transfer).Infof("%s: ready for download", transfer)
+
+		m.transfers.Push(TransferMessage{
+			Type:     MessageQueuedForDownload,
+			Transfer: transfer,
+		})
 	}
 }
 
-// checkExistingTransfers checks for transfers that may have been imported while we were offline
+// QueueDepths returns the number of transfer messages and download targets
+// waiting for a worker.
+func (m *Manager) QueueDepths() QueueDepths {
+	return QueueDepths{
+		Transfers: m.transfers.Len(),
+		Downloads: m.downloads.Len(),
+	}
+}
+
+// logQueueDepths logs the queue depths, warning when work is piling up.
+func (m *Manager) logQueueDepths() {
+	depths := m.QueueDepths()
+	if depths.Transfers >= queueWarnDepth || depths.Downloads >= queueWarnDepth {
+		m.logger.Warnf("Work is piling up: %d transfer messages and %d download targets queued", depths.Transfers, depths.Downloads)
+		return
+	}
+	m.logger.Infof("Queued: %d transfer messages, %d download targets", depths.Transfers, depths.Downloads)
+}
+
+// logPutioCalls logs the put.io API requests made during the previous minute,
+// by endpoint.
+func (m *Manager) logPutioCalls() {
+	calls := m.container.PutioCalls.LastMinute()
+	endpoints := make([]string, 0, len(calls))
+	total := 0
+	for endpoint, n := range calls {
+		endpoints = append(endpoints, endpoint)
+		total += n
+	}
+	if total == 0 {
+		m.logger.Info("put.io API calls in the last minute: 0")
+		return
+	}
+	sort.Strings(endpoints)
+	parts := make([]string, len(endpoints))
+	for i, endpoint := range endpoints {
+		parts[i] = fmt.Sprintf("%s: %d", endpoint, calls[endpoint])
+	}
+	m.logger.Infof("put.io API calls in the last minute: %d (%s)", total, strings.Join(parts, ", "))
+}
+
+// logDownloadCounters logs how many downloads were aborted and retried since
+// startup, if any were.
+func (m *Manager) logDownloadCounters() {
+	counts := m.container.Downloads.Snapshot()
+	if counts == (app.DownloadCountersSnapshot{}) {
+		return
+	}
+	m.logger.Infof("Aborted downloads since startup: %d stalled, %d timed out, %d retried", counts.Stalled, counts.TimedOut, counts.Retried)
+}
+
+// dailySummary logs the pipeline statistics of the last day and sends them to
+// the notifiers.
+func (m *Manager) dailySummary() {
+	day := m.container.Pipeline.Snapshot().LastDay
+	message := fmt.Sprintf("Last 24h: %s downloaded (%s/s), %d imported, %d failed",
+		config.Size(day.BytesDownloaded), config.Size(int64(day.BytesPerSecond)), day.Completed, day.Failed)
+	if day.Completed+day.Failed > 0 {
+		message += fmt.Sprintf(", %.0f%% success", day.SuccessRate*100)
+	}
+	if day.Completed > 0 {
+		message += fmt.Sprintf(", %s average from grab to import", time.Duration(day.AverageImportTime*float64(time.Second)).Round(time.Second))
+	}
+	m.logger.Info(message)
+	m.notify(notify.Event{
+		Type:    notify.EventDailySummary,
+		Title:   "Daily summary",
+		Message: message,
+	})
+}
+
+// checkExistingTransfers resumes the transfers that were in the pipeline before
+// a restart, or were imported while the proxy was offline.
 func (m *Manager) checkExistingTransfers() {
 	listResp, err := m.putioClient.ListTransfers()
 	if err != nil {
-		m.logger.Errorf("Failed to list transfers: %v", err)
+		withHint(m.logger, err).Errorf("Failed to list transfers: %v", err)
 		return
 	}
+	m.adoptExistingTransfers(listResp.Transfers)
 
 	for _, pt := range listResp.Transfers {
+		if !m.isRelevant(&pt) {
+			continue
+		}
+
 		name := "??"
 		if pt.Name != nil {
 			name = *pt.Name
@@ -549,35 +1590,96 @@ func (m *Manager) checkExistingTransfers() {
 
 		transfer := NewTransfer(m.config, &pt)
 
+		if pt.Hash != nil && m.container.Holds.Has(*pt.Hash) {
+			m.transferLogger(transfer).Infof("%s is held until torrent-start", name)
+			continue
+		}
 		if pt.IsDownloadable() {
-			m.logger.Infof("Getting download target for %s", name)
-
-			targets, err := m.getDownloadTargets(transfer)
-			if err != nil {
-				m.logger.Warnf("Could not get target for %s: %v", name, err)
-				continue
-			}
-
-			transfer.SetTargets(targets)
-
-			if m.isImported(transfer) {
-				m.logger.Infof("%s: already imported", transfer)
+			m.transferLogger(transfer).Infof("Getting download target for %s", name)
+			if m.reconcile(transfer) {
 				m.markSeen(transfer.TransferID)
-				select {
-				case <-m.ctx.Done():
-					return
-				case m.transferChan <- TransferMessage{
-					Type:     MessageImported,
-					Transfer: transfer,
-				}:
-				}
-			} else {
-				m.logger.Infof("%s: not imported yet", transfer)
 			}
 		}
 	}
 }
 
+// adoptExistingTransfers records transfers on put.io as owned on the first
+// run tracking ownership, so the ones earlier versions were handling keep
+// being handled. Only transfers the other state files show the proxy handled,
+// or saved into the parent folder, are adopted; with manage_foreign_transfers
+// all of them are, so running once with it adopts every transfer.
+func (m *Manager) adoptExistingTransfers(transfers []putio.Transfer) {
+	if !m.container.Ownership.Fresh() {
+		return
+	}
+	var hashes []string
+	skipped := 0
+	for _, pt := range transfers {
+		if pt.Hash == nil {
+			continue
+		}
+		if m.config.ManageForeignTransfers || m.isOwned(&pt) || m.handledBefore(*pt.Hash) {
+			hashes = append(hashes, *pt.Hash)
+		} else {
+			skipped++
+		}
+	}
+	added, err := m.container.Ownership.Seed(hashes)
+	if err != nil {
+		m.logger.Warnf("Failed to record the existing transfers as owned: %v", err)
+		return
+	}
+	if added > 0 {
+		m.logger.Warnf("Adopted %d transfers already on put.io, as ownership wasn't tracked before. Remove them from %s to leave them alone.", added, m.config.OwnedTransfersPath())
+	}
+	if skipped > 0 {
+		m.logger.Warnf("Left %d transfers already on put.io alone, as nothing shows the proxy added them. Run once with manage_foreign_transfers to adopt them.", skipped)
+	}
+}
+
+// handledBefore reports whether the proxy's state files know the transfer with
+// the given hash, i.e. the proxy handled it before ownership was tracked.
+func (m *Manager) handledBefore(hash string) bool {
+	if len(m.container.Labels.Get(hash)) > 0 || m.container.Holds.Has(hash) {
+		return true
+	}
+	if _, ok := m.container.Locations.Get(hash); ok {
+		return true
+	}
+	_, ok := m.container.Stages.Get(hash)
+	return ok
+}
+
+// isRelevant reports whether the manager should handle a put.io transfer.
+// Transfers the proxy added are always handled; others only with
+// manage_foreign_transfers. With only_new_transfers, those other transfers are
+// ignored if they were created before the manager started; transfers without
+// a known creation time are handled.
+func (m *Manager) isRelevant(pt *putio.Transfer) bool {
+	if m.isOwned(pt) {
+		return true
+	}
+	if !m.config.ManageForeignTransfers {
+		return false
+	}
+	if !m.config.OnlyNewTransfers {
+		return true
+	}
+	created, ok := pt.Created()
+	return !ok || !created.Before(m.startedAt)
+}
+
+// isOwned reports whether a put.io transfer was added by the proxy, either
+// because its hash is in the ownership registry or because it is saved into
+// the configured parent folder.
+func (m *Manager) isOwned(pt *putio.Transfer) bool {
+	if pt.Hash != nil && m.container.Ownership.Has(*pt.Hash) {
+		return true
+	}
+	parent := m.config.Putio.ParentFolderID
+	return parent != 0 && pt.SaveParentID != nil && *pt.SaveParentID == parent
+}
+
 // isSeen checks if a transfer ID has been seen
 func (m *Manager) isSeen(id uint64) bool {
 	m.seenMu.RLock()
@@ -592,6 +1694,37 @@ func (m *Manager) markSeen(id uint64) {
 	m.seen[id] = true
 }
 
+// unmarkSeen forgets that a transfer ID has been seen, so it's handled again
+func (m *Manager) unmarkSeen(id uint64) {
+	m.seenMu.Lock()
+	defer m.seenMu.Unlock()
+	delete(m.seen, id)
+}
+
+// resetSeen forgets the seen transfers and folder items, except the abandoned
+// transfers, which stay skipped until they're added again.
+func (m *Manager) resetSeen() {
+	m.seenMu.Lock()
+	defer m.seenMu.Unlock()
+	m.seen = make(map[uint64]bool, len(m.abandoned))
+	for id := range m.abandoned {
+		m.seen[id] = true
+	}
+	m.seenFiles = make(map[int64]bool)
+}
+
+// readded reports whether an abandoned transfer was added again, which
+// records its ownership anew, and if so stops skipping it.
+func (m *Manager) readded(pt *putio.Transfer) bool {
+	m.seenMu.Lock()
+	defer m.seenMu.Unlock()
+	if !m.abandoned[pt.ID] || pt.Hash == nil || !m.container.Ownership.Has(*pt.Hash) {
+		return false
+	}
+	delete(m.abandoned, pt.ID)
+	return true
+}
+
 // cleanupSeen removes IDs from seen that are no longer in the active list
 func (m *Manager) cleanupSeen(activeIDs map[uint64]bool) {
 	m.seenMu.Lock()
@@ -599,6 +1732,7 @@ func (m *Manager) cleanupSeen(activeIDs map[uint64]bool) {
 	for id := range m.seen {
 		if !activeIDs[id] {
 			delete(m.seen, id)
+			delete(m.abandoned, id)
 		}
 	}
 }
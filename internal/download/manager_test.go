@@ -1,16 +1,29 @@
 package download
 
 import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/ochronus/goputioarr/internal/app"
 	"github.com/ochronus/goputioarr/internal/config"
+	"github.com/ochronus/goputioarr/internal/history"
+	"github.com/ochronus/goputioarr/internal/services/arr"
+	"github.com/ochronus/goputioarr/internal/services/heartbeat"
+	"github.com/ochronus/goputioarr/internal/services/notify"
 	"github.com/ochronus/goputioarr/internal/services/putio"
+	"github.com/ochronus/goputioarr/internal/services/unpack"
 	"github.com/sirupsen/logrus"
 )
 
@@ -18,6 +31,10 @@ type mockPutioClient struct {
 	listFilesResp *putio.ListFileResponse
 	listFilesByID map[int64]*putio.ListFileResponse
 	fileURLs      map[int64]string
+
+	mu      sync.Mutex
+	removed []uint64
+	retried []uint64
 }
 
 func (m *mockPutioClient) GetAccountInfo() (*putio.AccountInfoResponse, error) {
@@ -28,17 +45,37 @@ func (m *mockPutioClient) ListTransfers() (*putio.ListTransferResponse, error) {
 	return &putio.ListTransferResponse{Transfers: []putio.Transfer{}}, nil
 }
 
+func (m *mockPutioClient) ListTransfersSince(uint64) (*putio.ListTransferResponse, error) {
+	return m.ListTransfers()
+}
+
 func (m *mockPutioClient) GetTransfer(transferID uint64) (*putio.GetTransferResponse, error) {
 	return &putio.GetTransferResponse{}, nil
 }
 
-func (m *mockPutioClient) RemoveTransfer(transferID uint64) error { return nil }
+func (m *mockPutioClient) RemoveTransfer(transferID uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.removed = append(m.removed, transferID)
+	return nil
+}
 
 func (m *mockPutioClient) DeleteFile(fileID int64) error { return nil }
 
-func (m *mockPutioClient) AddTransfer(url string) error { return nil }
+func (m *mockPutioClient) RemoveTransfers(transferIDs []uint64) error { return nil }
+
+func (m *mockPutioClient) RetryTransfer(transferID uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.retried = append(m.retried, transferID)
+	return nil
+}
+
+func (m *mockPutioClient) DeleteFiles(fileIDs []int64) error { return nil }
+
+func (m *mockPutioClient) AddTransfer(url string) (*putio.Transfer, error) { return nil, nil }
 
-func (m *mockPutioClient) UploadFile(data []byte) error { return nil }
+func (m *mockPutioClient) UploadFile(data []byte) (*putio.Transfer, error) { return nil, nil }
 
 func (m *mockPutioClient) ListFiles(fileID int64) (*putio.ListFileResponse, error) {
 	if m.listFilesByID != nil {
@@ -65,14 +102,39 @@ func (m *mockPutioClient) GetFileURL(fileID int64) (string, error) {
 }
 
 type mockArrClient struct {
-	imported bool
-	err      error
+	imported     bool
+	imports      map[string]arr.Import
+	findCalls    int
+	err          error
+	checkedPaths []string
+	blocklisted  []string
 }
 
 func (m *mockArrClient) CheckImported(targetPath string) (bool, error) {
+	m.checkedPaths = append(m.checkedPaths, targetPath)
 	return m.imported, m.err
 }
 
+func (m *mockArrClient) FindImports(targetPaths []string) (map[string]arr.Import, error) {
+	m.findCalls++
+	imports := make(map[string]arr.Import)
+	for _, targetPath := range targetPaths {
+		if found, ok := m.imports[targetPath]; ok {
+			imports[targetPath] = found
+		}
+	}
+	return imports, m.err
+}
+
+func (m *mockArrClient) Blocklist(downloadID string) (bool, error) {
+	m.blocklisted = append(m.blocklisted, downloadID)
+	return true, m.err
+}
+
+func (m *mockArrClient) SystemStatus() (*arr.SystemStatus, error) {
+	return &arr.SystemStatus{AppName: "Sonarr"}, m.err
+}
+
 func TestRecurseDownloadTargetsWithMocks(t *testing.T) {
 	manager := setupTestManager()
 
@@ -108,7 +170,7 @@ func TestRecurseDownloadTargetsWithMocks(t *testing.T) {
 	if targets[0].TargetType != TargetTypeDirectory || targets[0].To != filepath.Join("/downloads", "root") {
 		t.Errorf("unexpected directory target: %+v", targets[0])
 	}
-	if targets[1].TargetType != TargetTypeFile || targets[1].From != "http://example.com/movie.mkv" {
+	if targets[1].TargetType != TargetTypeFile || targets[1].FileID != 200 || targets[1].From != "" {
 		t.Errorf("unexpected file target: %+v", targets[1])
 	}
 	if !targets[0].TopLevel || targets[1].TopLevel {
@@ -116,6 +178,143 @@ func TestRecurseDownloadTargetsWithMocks(t *testing.T) {
 	}
 }
 
+func TestRecurseDownloadTargetsSkipsPatterns(t *testing.T) {
+	manager := setupTestManager()
+	manager.skip, _ = config.ParseNamePatterns([]string{"glob:*sample*", "regex:^screens?$"})
+	manager.putioClient = &mockPutioClient{
+		listFilesByID: map[int64]*putio.ListFileResponse{
+			100: {
+				Parent: putio.FileResponse{ID: 100, Name: "root", FileType: "FOLDER"},
+				Files:  []putio.FileResponse{{ID: 200}, {ID: 300}, {ID: 400}},
+			},
+			200: {Parent: putio.FileResponse{ID: 200, Name: "movie.mkv", FileType: "VIDEO"}},
+			300: {Parent: putio.FileResponse{ID: 300, Name: "movie.Sample.mkv", FileType: "VIDEO"}},
+			400: {
+				Parent: putio.FileResponse{ID: 400, Name: "Screens", FileType: "FOLDER"},
+				Files:  []putio.FileResponse{{ID: 500}},
+			},
+			500: {Parent: putio.FileResponse{ID: 500, Name: "shot.mkv", FileType: "VIDEO"}},
+			600: {Parent: putio.FileResponse{ID: 600, Name: "sample.mkv", FileType: "VIDEO"}},
+			700: {
+				Parent: putio.FileResponse{ID: 700, Name: "Movie.Sample.Pack", FileType: "FOLDER"},
+				Files:  []putio.FileResponse{{ID: 200}},
+			},
+		},
+	}
+
+	targets, err := manager.recurseDownloadTargets(100, "hash123", "/downloads", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(targets) != 2 || targets[1].FileID != 200 {
+		t.Errorf("expected only the folder and movie.mkv, got %+v", targets)
+	}
+
+	targets, err = manager.recurseDownloadTargets(600, "hash123", "/downloads", true)
+	if err != nil || len(targets) != 1 {
+		t.Errorf("expected a single-file transfer to be downloaded whatever its name, got %+v, %v", targets, err)
+	}
+
+	targets, err = manager.recurseDownloadTargets(700, "hash123", "/downloads", true)
+	if err != nil || len(targets) != 2 {
+		t.Errorf("expected a transfer's folder to be downloaded whatever its name, got %+v, %v", targets, err)
+	}
+}
+
+func TestRecurseDownloadTargetsRefusesPathsOutsideDownloadDirectory(t *testing.T) {
+	manager := setupTestManager()
+	manager.putioClient = &mockPutioClient{
+		listFilesByID: map[int64]*putio.ListFileResponse{
+			100: {
+				Parent: putio.FileResponse{ID: 100, Name: "movie.mkv", FileType: "VIDEO"},
+			},
+		},
+		fileURLs: map[int64]string{100: "http://example.com/movie.mkv"},
+	}
+
+	if _, err := manager.recurseDownloadTargets(100, "hash123", "/elsewhere", true); err == nil {
+		t.Fatal("expected targets outside the download directory to be refused")
+	}
+}
+
+func TestRecurseDownloadTargetsKeepsMaliciousNamesInside(t *testing.T) {
+	manager := setupTestManager()
+	manager.putioClient = &mockPutioClient{
+		listFilesByID: map[int64]*putio.ListFileResponse{
+			100: {
+				Parent: putio.FileResponse{ID: 100, Name: "..", FileType: "FOLDER"},
+				Files:  []putio.FileResponse{{ID: 200}},
+			},
+			200: {
+				Parent: putio.FileResponse{ID: 200, Name: "../../etc/cron.d/evil", FileType: "VIDEO"},
+			},
+		},
+		fileURLs: map[int64]string{200: "http://example.com/evil"},
+	}
+
+	targets, err := manager.recurseDownloadTargets(100, "hash123", "", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, target := range targets {
+		if !app.WithinDirectory("/downloads", target.To) || target.To == "/downloads" {
+			t.Errorf("target escaped the download directory: %s", target.To)
+		}
+	}
+}
+
+func TestGetDownloadTargetsTagsTransferID(t *testing.T) {
+	manager := setupTestManager()
+	manager.putioClient = &mockPutioClient{
+		listFilesByID: map[int64]*putio.ListFileResponse{
+			100: {
+				Parent: putio.FileResponse{ID: 100, Name: "root", FileType: "FOLDER"},
+				Files:  []putio.FileResponse{{ID: 200}},
+			},
+			200: {
+				Parent: putio.FileResponse{ID: 200, Name: "movie.mkv", FileType: "VIDEO"},
+			},
+		},
+	}
+
+	fileID := int64(100)
+	hash := "hash123"
+	transfer := &Transfer{Name: "root", FileID: &fileID, Hash: &hash, TransferID: 77}
+	targets, err := manager.getDownloadTargets(transfer)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(targets) != 2 {
+		t.Fatalf("expected 2 targets, got %d", len(targets))
+	}
+	for _, target := range targets {
+		if target.TransferID != 77 {
+			t.Errorf("expected transfer ID 77 on %s, got %d", &target, target.TransferID)
+		}
+	}
+}
+
+func TestGetDownloadTargetsUsesTransferLocation(t *testing.T) {
+	manager := setupTestManager()
+	manager.container.Locations, _ = app.NewLocationRegistry("")
+	manager.container.Locations.Set("hash123", "/downloads/tv")
+	manager.putioClient = &mockPutioClient{
+		listFilesByID: map[int64]*putio.ListFileResponse{
+			100: {Parent: putio.FileResponse{ID: 100, Name: "show.mkv", FileType: "VIDEO"}},
+		},
+	}
+
+	fileID := int64(100)
+	hash := "hash123"
+	targets, err := manager.getDownloadTargets(&Transfer{Name: "show", FileID: &fileID, Hash: &hash})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(targets) != 1 || targets[0].To != filepath.Join("/downloads/tv", "show.mkv") {
+		t.Errorf("expected the target in the transfer's location, got %+v", targets)
+	}
+}
+
 func TestIsImportedWithMockArrClient(t *testing.T) {
 	manager := setupTestManager()
 
@@ -134,6 +333,107 @@ func TestIsImportedWithMockArrClient(t *testing.T) {
 	if !manager.isImported(transfer) {
 		t.Fatalf("expected transfer to be marked as imported")
 	}
+	if got := transfer.GetImportedBy(); got != "sonarr" {
+		t.Errorf("expected transfer to be imported by sonarr, got '%s'", got)
+	}
+}
+
+func TestIsImportedAppliesPathMappings(t *testing.T) {
+	manager := setupTestManager()
+
+	arrClient := &mockArrClient{imported: true}
+	manager.arrClients = []ArrServiceClient{
+		{Name: "sonarr", Client: arrClient, PathMappings: map[string]string{"/downloads": "/data/downloads"}},
+	}
+
+	transfer := &Transfer{Name: "Test Transfer", TransferID: 123}
+	transfer.SetTargets([]DownloadTarget{
+		{To: "/downloads/show/episode.mkv", TargetType: TargetTypeFile},
+	})
+
+	if !manager.isImported(transfer) {
+		t.Fatalf("expected transfer to be marked as imported")
+	}
+	if len(arrClient.checkedPaths) != 1 || arrClient.checkedPaths[0] != "/data/downloads/show/episode.mkv" {
+		t.Errorf("expected mapped path to be checked, got %v", arrClient.checkedPaths)
+	}
+}
+
+func TestGetDownloadTargetsUsesLabelSettings(t *testing.T) {
+	manager := setupTestManager()
+	manager.config.Labels = map[string]config.LabelConfig{
+		"tv-sonarr": {Subdirectory: "tv", SkipDirectories: []string{"glob:*.nfo"}},
+	}
+	labels, err := app.NewLabelRegistry("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	manager.container.Labels = labels
+	if err := labels.Set("hash123", []string{"tv-sonarr"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	manager.putioClient = &mockPutioClient{
+		listFilesByID: map[int64]*putio.ListFileResponse{
+			100: {
+				Parent: putio.FileResponse{ID: 100, Name: "show", FileType: "FOLDER"},
+				Files:  []putio.FileResponse{{ID: 200}, {ID: 300}, {ID: 400}},
+			},
+			200: {Parent: putio.FileResponse{ID: 200, Name: "show.mkv", FileType: "VIDEO"}},
+			300: {Parent: putio.FileResponse{ID: 300, Name: "show.nfo", FileType: "VIDEO"}},
+			400: {
+				Parent: putio.FileResponse{ID: 400, Name: "sample", FileType: "FOLDER"},
+				Files:  []putio.FileResponse{{ID: 500}},
+			},
+			500: {Parent: putio.FileResponse{ID: 500, Name: "sample.mkv", FileType: "VIDEO"}},
+		},
+	}
+
+	fileID := int64(100)
+	hash := "hash123"
+	transfer := &Transfer{Name: "show", FileID: &fileID, Hash: &hash}
+	targets, err := manager.getDownloadTargets(transfer)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got []string
+	for _, target := range targets {
+		got = append(got, target.To)
+	}
+	// The label's skip_directories replace the global ones, so the sample
+	// folder is downloaded and the .nfo isn't.
+	want := []string{"/downloads/tv/show", "/downloads/tv/show/show.mkv", "/downloads/tv/show/sample", "/downloads/tv/show/sample/sample.mkv"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected targets %v, got %v", want, got)
+	}
+}
+
+func TestDownloadWorkerWaitsWhilePaused(t *testing.T) {
+	manager := setupTestManager()
+	manager.container.Pause = app.NewPauseSwitch()
+	manager.container.Pause.Pause()
+	done := make(chan DownloadDoneStatus, 1)
+	manager.downloads.Push(DownloadTargetMessage{
+		Target:   DownloadTarget{To: "/downloads/show", TargetType: TargetTypeDirectory},
+		DoneChan: done,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		manager.downloadWorker(ctx, 0)
+		close(stopped)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("expected no download to start while paused")
+	case <-time.After(50 * time.Millisecond):
+	}
+	cancel()
+	<-stopped
+	if manager.downloads.Len() != 1 {
+		t.Errorf("expected the target to be left in the queue, got %d", manager.downloads.Len())
+	}
 }
 
 func setupTestManager() *Manager {
@@ -141,7 +441,7 @@ func setupTestManager() *Manager {
 		DownloadDirectory:    "/downloads",
 		DownloadWorkers:      2,
 		OrchestrationWorkers: 2,
-		PollingInterval:      1,
+		PollingInterval:      config.Seconds(1),
 		SkipDirectories:      []string{"sample", "extras"},
 		UID:                  1000,
 		Putio: config.PutioConfig{
@@ -161,6 +461,13 @@ func setupTestManager() *Manager {
 	return NewManager(container)
 }
 
+// popTransfer waits up to timeout for the next transfer message.
+func popTransfer(m *Manager, timeout time.Duration) (TransferMessage, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return m.transfers.Pop(ctx)
+}
+
 func TestNewManager(t *testing.T) {
 	manager := setupTestManager()
 
@@ -173,11 +480,11 @@ func TestNewManager(t *testing.T) {
 	if manager.putioClient == nil {
 		t.Error("expected non-nil putioClient")
 	}
-	if manager.transferChan == nil {
-		t.Error("expected non-nil transferChan")
+	if manager.transfers == nil {
+		t.Error("expected non-nil transfer queue")
 	}
-	if manager.downloadChan == nil {
-		t.Error("expected non-nil downloadChan")
+	if manager.downloads == nil {
+		t.Error("expected non-nil download queue")
 	}
 	if manager.seen == nil {
 		t.Error("expected non-nil seen map")
@@ -387,6 +694,139 @@ func TestDownloadTargetFileSuccess(t *testing.T) {
 	}
 }
 
+// rotatingURLClient hands out a new download URL on every GetFileURL call.
+type rotatingURLClient struct {
+	mockPutioClient
+	urls     []string
+	resolved int
+}
+
+func (c *rotatingURLClient) GetFileURL(fileID int64) (string, error) {
+	url := c.urls[min(c.resolved, len(c.urls)-1)]
+	c.resolved++
+	return url, nil
+}
+
+func TestDownloadTargetResolvesURLLazily(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/expired" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte("test file content"))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		urls         []string
+		wantStatus   DownloadDoneStatus
+		wantResolved int
+	}{
+		{name: "fresh URL", urls: []string{server.URL + "/file"}, wantStatus: DownloadStatusSuccess, wantResolved: 1},
+		{name: "expired URL is resolved again", urls: []string{server.URL + "/expired", server.URL + "/file"}, wantStatus: DownloadStatusSuccess, wantResolved: 2},
+		{name: "only resolved again once", urls: []string{server.URL + "/expired"}, wantStatus: DownloadStatusFailed, wantResolved: 2},
+		{name: "no URL", urls: []string{""}, wantStatus: DownloadStatusFailed, wantResolved: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := setupTestManager()
+			client := &rotatingURLClient{urls: tt.urls}
+			manager.putioClient = client
+			target := &DownloadTarget{FileID: 42, To: filepath.Join(t.TempDir(), "file.txt"), TargetType: TargetTypeFile}
+
+			if status := manager.downloadTarget(target); status != tt.wantStatus {
+				t.Errorf("expected status %v, got %v", tt.wantStatus, status)
+			}
+			if client.resolved != tt.wantResolved {
+				t.Errorf("expected the URL to be resolved %d times, got %d", tt.wantResolved, client.resolved)
+			}
+		})
+	}
+}
+
+// hangingServer sends part of a file and then hangs on the first `hangs`
+// requests, and sends the whole file after that. If trickle is set, it
+// instead keeps sending a byte at that interval without ever finishing.
+func hangingServer(t *testing.T, hangs int32, trickle time.Duration) *httptest.Server {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if trickle > 0 {
+			for {
+				w.Write([]byte("x"))
+				w.(http.Flusher).Flush()
+				select {
+				case <-r.Context().Done():
+					return
+				case <-time.After(trickle):
+				}
+			}
+		}
+		if requests.Add(1) <= hangs {
+			w.Write([]byte("partial"))
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+			return
+		}
+		w.Write([]byte("test file content"))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestDownloadTargetAbortsStalledAndSlowDownloads(t *testing.T) {
+	tests := []struct {
+		name       string
+		hangs      int32
+		trickle    time.Duration
+		stall      time.Duration
+		timeout    time.Duration
+		retries    int
+		wantStatus DownloadDoneStatus
+		wantCounts app.DownloadCountersSnapshot
+	}{
+		{
+			name: "stall recovered by a retry", hangs: 1, stall: 50 * time.Millisecond, retries: 2,
+			wantStatus: DownloadStatusSuccess,
+			wantCounts: app.DownloadCountersSnapshot{Stalled: 1, Retried: 1},
+		},
+		{
+			name: "stalls until out of retries", hangs: 5, stall: 50 * time.Millisecond, retries: 1,
+			wantStatus: DownloadStatusFailed,
+			wantCounts: app.DownloadCountersSnapshot{Stalled: 2, Retried: 1},
+		},
+		{
+			name: "trickling download times out", trickle: 5 * time.Millisecond, stall: time.Second, timeout: 50 * time.Millisecond,
+			wantStatus: DownloadStatusFailed,
+			wantCounts: app.DownloadCountersSnapshot{TimedOut: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := hangingServer(t, tt.hangs, tt.trickle)
+			manager := setupTestManager()
+			manager.container.Downloads = app.NewDownloadCounters()
+			manager.config.DownloadStallTimeout = config.Duration(tt.stall)
+			manager.config.DownloadTimeout = config.Duration(tt.timeout)
+			manager.config.DownloadRetries = tt.retries
+			targetPath := filepath.Join(t.TempDir(), "file.txt")
+
+			status := manager.downloadTarget(&DownloadTarget{From: server.URL, To: targetPath, TargetType: TargetTypeFile})
+			if status != tt.wantStatus {
+				t.Errorf("expected status %v, got %v", tt.wantStatus, status)
+			}
+			if got := manager.container.Downloads.Snapshot(); got != tt.wantCounts {
+				t.Errorf("expected counts %+v, got %+v", tt.wantCounts, got)
+			}
+			if _, err := os.Stat(targetPath + ".downloading"); !os.IsNotExist(err) {
+				t.Errorf("expected the partial download to be removed, got %v", err)
+			}
+		})
+	}
+}
+
 func TestDownloadTargetFileHTTPError(t *testing.T) {
 	manager := setupTestManager()
 
@@ -572,18 +1012,6 @@ func TestFetchFileLargeContent(t *testing.T) {
 	}
 }
 
-func TestManagerChannelBufferSizes(t *testing.T) {
-	manager := setupTestManager()
-
-	// Verify channel buffer sizes
-	if cap(manager.transferChan) != 100 {
-		t.Errorf("expected transferChan buffer size 100, got %d", cap(manager.transferChan))
-	}
-	if cap(manager.downloadChan) != 100 {
-		t.Errorf("expected downloadChan buffer size 100, got %d", cap(manager.downloadChan))
-	}
-}
-
 func TestManagerConfigReference(t *testing.T) {
 	cfg := &config.Config{
 		DownloadDirectory: "/original",
@@ -945,24 +1373,17 @@ func TestManagerTransferChannelSend(t *testing.T) {
 		Transfer: transfer,
 	}
 
-	select {
-	case manager.transferChan <- msg:
-		// Successfully sent
-	default:
-		t.Error("failed to send to transfer channel")
-	}
+	manager.transfers.Push(msg)
 
-	// Receive the message
-	select {
-	case received := <-manager.transferChan:
-		if received.Type != MessageQueuedForDownload {
-			t.Errorf("expected MessageQueuedForDownload, got %v", received.Type)
-		}
-		if received.Transfer.Name != "Test Transfer" {
-			t.Errorf("expected transfer name 'Test Transfer', got '%s'", received.Transfer.Name)
-		}
-	default:
-		t.Error("failed to receive from transfer channel")
+	received, ok := manager.transfers.TryPop()
+	if !ok {
+		t.Fatal("failed to receive from transfer queue")
+	}
+	if received.Type != MessageQueuedForDownload {
+		t.Errorf("expected MessageQueuedForDownload, got %v", received.Type)
+	}
+	if received.Transfer.Name != "Test Transfer" {
+		t.Errorf("expected transfer name 'Test Transfer', got '%s'", received.Transfer.Name)
 	}
 }
 
@@ -980,21 +1401,14 @@ func TestManagerDownloadChannelSend(t *testing.T) {
 		DoneChan: doneChan,
 	}
 
-	select {
-	case manager.downloadChan <- msg:
-		// Successfully sent
-	default:
-		t.Error("failed to send to download channel")
-	}
+	manager.downloads.Push(msg)
 
-	// Receive the message
-	select {
-	case received := <-manager.downloadChan:
-		if received.Target.To != "/downloads/test.mkv" {
-			t.Errorf("expected target path '/downloads/test.mkv', got '%s'", received.Target.To)
-		}
-	default:
-		t.Error("failed to receive from download channel")
+	received, ok := manager.downloads.TryPop()
+	if !ok {
+		t.Fatal("failed to receive from download queue")
+	}
+	if received.Target.To != "/downloads/test.mkv" {
+		t.Errorf("expected target path '/downloads/test.mkv', got '%s'", received.Target.To)
 	}
 }
 
@@ -1200,3 +1614,1217 @@ func TestDownloadTargetBinaryContent(t *testing.T) {
 		}
 	}
 }
+
+// pathArrClient reports the paths in imported as imported.
+type pathArrClient struct {
+	mockArrClient
+	mu       sync.Mutex
+	imported map[string]bool
+}
+
+func (p *pathArrClient) CheckImported(targetPath string) (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.imported[targetPath], nil
+}
+
+func (p *pathArrClient) setImported(path string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.imported[path] = true
+}
+
+func TestWatchForImportWebhookSignal(t *testing.T) {
+	manager := setupTestManager()
+	manager.container.Imports = app.NewImportTracker()
+	manager.config.PollingInterval = config.Seconds(60)
+
+	dir := t.TempDir()
+	topLevel := filepath.Join(dir, "Show")
+	if err := os.MkdirAll(topLevel, 0755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	first := filepath.Join(topLevel, "ep1.mkv")
+	second := filepath.Join(topLevel, "ep2.mkv")
+	arrClient := &pathArrClient{imported: map[string]bool{}}
+	manager.arrClients = []ArrServiceClient{{Name: "sonarr", Client: arrClient}}
+
+	hash := "abcdef123456"
+	transfer := &Transfer{Name: "Show", TransferID: 1, Hash: &hash}
+	transfer.SetTargets([]DownloadTarget{
+		{To: topLevel, TargetType: TargetTypeDirectory, TopLevel: true},
+		{To: first, TargetType: TargetTypeFile},
+		{To: second, TargetType: TargetTypeFile},
+	})
+
+	// Wait for the watcher to register before notifying.
+	manager.watchers.Go(func() { manager.watchForImport(transfer) })
+	notify := func() {
+		deadline := time.Now().Add(2 * time.Second)
+		// Arrs report hashes uppercase.
+		for !manager.container.Imports.Notify("ABCDEF123456") {
+			if time.Now().After(deadline) {
+				t.Fatal("expected the watcher to wait for the import")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	// The first episode of the pack is imported: the rest must stay.
+	arrClient.setImported(first)
+	notify()
+	if msg, ok := popTransfer(manager, 200*time.Millisecond); ok {
+		t.Fatalf("did not expect a partial import to complete the transfer: %+v", msg)
+	}
+	if _, err := os.Stat(topLevel); err != nil {
+		t.Fatalf("expected local files to be kept, stat err: %v", err)
+	}
+
+	arrClient.setImported(second)
+	notify()
+	msg, ok := popTransfer(manager, 2*time.Second)
+	if !ok {
+		t.Fatal("expected webhook signal to mark the transfer as imported")
+	}
+	if msg.Type != MessageImported || msg.Transfer != transfer {
+		t.Errorf("unexpected message: %+v", msg)
+	}
+	// The per-arr delete_remote_files setting applies to webhook imports too.
+	if got := transfer.GetImportedBy(); got != "sonarr" {
+		t.Errorf("expected the transfer to be imported by sonarr, got %q", got)
+	}
+
+	if _, err := os.Stat(topLevel); !os.IsNotExist(err) {
+		t.Errorf("expected local files to be deleted, stat err: %v", err)
+	}
+
+	manager.Stop()
+	if manager.container.Imports.Notify(hash) {
+		t.Error("expected the hash to be forgotten once imported")
+	}
+}
+
+func TestHandleImportedKeepsLocalFiles(t *testing.T) {
+	manager := setupTestManager()
+	keep := false
+	manager.config.DeleteLocalAfterImport = &keep
+
+	topLevel := filepath.Join(t.TempDir(), "Show")
+	if err := os.MkdirAll(topLevel, 0755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	transfer := &Transfer{Name: "Show", TransferID: 1}
+	transfer.SetTargets([]DownloadTarget{{To: topLevel, TargetType: TargetTypeDirectory, TopLevel: true}})
+
+	go manager.handleImported(transfer)
+
+	msg, ok := popTransfer(manager, 2*time.Second)
+	if !ok {
+		t.Fatal("expected imported message")
+	}
+	if msg.Type != MessageImported {
+		t.Errorf("expected imported message, got %v", msg.Type)
+	}
+
+	if _, err := os.Stat(topLevel); err != nil {
+		t.Errorf("expected local files to be kept, stat err: %v", err)
+	}
+}
+
+func TestImportVerified(t *testing.T) {
+	exact := func(file, library string) map[string]arr.Import {
+		return map[string]arr.Import{file: {ImportedPath: library, Exact: true}}
+	}
+	tests := []struct {
+		name     string
+		setup    func(t *testing.T, file, library string) map[string]arr.Import
+		expected bool
+	}{
+		{
+			name:     "moved by the arr",
+			setup:    func(t *testing.T, file, library string) map[string]arr.Import { return nil },
+			expected: true,
+		},
+		{
+			name: "hardlinked into the library",
+			setup: func(t *testing.T, file, library string) map[string]arr.Import {
+				writeTestFile(t, file, "data")
+				if err := os.Link(file, library); err != nil {
+					t.Skipf("hard links not supported: %v", err)
+				}
+				return nil
+			},
+			expected: true,
+		},
+		{
+			name: "copied to importedPath",
+			setup: func(t *testing.T, file, library string) map[string]arr.Import {
+				writeTestFile(t, file, "data")
+				writeTestFile(t, library, "data")
+				return exact(file, library)
+			},
+			expected: true,
+		},
+		{
+			name: "folder record for another file of the same size",
+			setup: func(t *testing.T, file, library string) map[string]arr.Import {
+				writeTestFile(t, file, "data")
+				writeTestFile(t, library, "data")
+				return map[string]arr.Import{file: {ImportedPath: library}}
+			},
+			expected: false,
+		},
+		{
+			name: "folder record for a copy with the same name",
+			setup: func(t *testing.T, file, library string) map[string]arr.Import {
+				writeTestFile(t, file, "data")
+				copied := filepath.Join(filepath.Dir(library), "tv", "ep.mkv")
+				if err := os.MkdirAll(filepath.Dir(copied), 0755); err != nil {
+					t.Fatal(err)
+				}
+				writeTestFile(t, copied, "data")
+				return map[string]arr.Import{file: {ImportedPath: copied}}
+			},
+			expected: true,
+		},
+		{
+			name: "importedPath missing",
+			setup: func(t *testing.T, file, library string) map[string]arr.Import {
+				writeTestFile(t, file, "data")
+				return exact(file, library)
+			},
+			expected: false,
+		},
+		{
+			name: "partial copy",
+			setup: func(t *testing.T, file, library string) map[string]arr.Import {
+				writeTestFile(t, file, "data")
+				writeTestFile(t, library, "da")
+				return exact(file, library)
+			},
+			expected: false,
+		},
+		{
+			name: "no import history",
+			setup: func(t *testing.T, file, library string) map[string]arr.Import {
+				writeTestFile(t, file, "data")
+				return nil
+			},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			file := filepath.Join(dir, "ep.mkv")
+			library := filepath.Join(dir, "library.mkv")
+
+			manager := setupTestManager()
+			manager.arrClients = []ArrServiceClient{
+				{Name: "sonarr", Client: &mockArrClient{imported: true, imports: tt.setup(t, file, library)}},
+			}
+
+			transfer := &Transfer{Name: "Show", TransferID: 1}
+			transfer.SetTargets([]DownloadTarget{{To: file, TargetType: TargetTypeFile, TopLevel: true}})
+
+			if got := manager.importVerified(transfer); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestImportVerifiedReadsHistoryOnce(t *testing.T) {
+	dir := t.TempDir()
+	imports := make(map[string]arr.Import)
+	var targets []DownloadTarget
+	for _, name := range []string{"e01.mkv", "e02.mkv", "e03.mkv"} {
+		file := filepath.Join(dir, name)
+		library := filepath.Join(dir, "library-"+name)
+		writeTestFile(t, file, "data")
+		writeTestFile(t, library, "data")
+		imports[file] = arr.Import{ImportedPath: library, Exact: true}
+		targets = append(targets, DownloadTarget{To: file, TargetType: TargetTypeFile})
+	}
+
+	manager := setupTestManager()
+	arrClient := &mockArrClient{imports: imports}
+	manager.arrClients = []ArrServiceClient{{Name: "sonarr", Client: arrClient}}
+	transfer := &Transfer{Name: "Show", TransferID: 1}
+	transfer.SetTargets(targets)
+
+	if !manager.importVerified(transfer) {
+		t.Fatal("expected the copies to be verified")
+	}
+	if arrClient.findCalls != 1 {
+		t.Errorf("expected the history to be read once, got %d", arrClient.findCalls)
+	}
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+}
+
+func TestUnpackTargets(t *testing.T) {
+	manager := setupTestManager()
+	manager.container.Unpacker = unpack.New()
+
+	dir := filepath.Join(t.TempDir(), "Release")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	archive := filepath.Join(dir, "release.zip")
+	f, err := os.Create(archive)
+	if err != nil {
+		t.Fatalf("failed to create zip: %v", err)
+	}
+	zw := zip.NewWriter(f)
+	w, _ := zw.Create("release.mkv")
+	w.Write([]byte("video"))
+	zw.Close()
+	f.Close()
+
+	targets := []DownloadTarget{
+		{To: dir, TargetType: TargetTypeDirectory, TopLevel: true, TransferHash: "abcd"},
+		{To: archive, TargetType: TargetTypeFile, TransferHash: "abcd"},
+		{To: filepath.Join(dir, "release.nfo"), TargetType: TargetTypeFile, TransferHash: "abcd"},
+	}
+
+	got := manager.unpackTargets(targets)
+	if len(got) != 3 {
+		t.Fatalf("expected 3 targets, got %+v", got)
+	}
+	for _, target := range got {
+		if target.To == archive {
+			t.Error("expected archive to be replaced by the extracted files")
+		}
+	}
+	last := got[len(got)-1]
+	if last.To != filepath.Join(dir, "release.mkv") || last.TargetType != TargetTypeFile || last.TransferHash != "abcd" {
+		t.Errorf("unexpected extracted target %+v", last)
+	}
+	if _, err := os.Stat(last.To); err != nil {
+		t.Errorf("expected extracted file on disk: %v", err)
+	}
+}
+
+func TestUnpackTargetsKeepsTargetsOnFailure(t *testing.T) {
+	manager := setupTestManager()
+	manager.container.Unpacker = unpack.New()
+
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "broken.zip")
+	writeTestFile(t, archive, "not a zip")
+
+	targets := []DownloadTarget{
+		{To: dir, TargetType: TargetTypeDirectory, TopLevel: true},
+		{To: archive, TargetType: TargetTypeFile},
+	}
+	if got := manager.unpackTargets(targets); len(got) != 2 || got[1].To != archive {
+		t.Errorf("expected targets to be unchanged, got %+v", got)
+	}
+}
+
+func TestUnpackTargetsTopLevelArchive(t *testing.T) {
+	manager := setupTestManager()
+	manager.container.Unpacker = unpack.New()
+
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "Release.zip")
+	f, err := os.Create(archive)
+	if err != nil {
+		t.Fatalf("failed to create zip: %v", err)
+	}
+	zw := zip.NewWriter(f)
+	w, _ := zw.Create("release.mkv")
+	w.Write([]byte("video"))
+	zw.Close()
+	f.Close()
+
+	targets := []DownloadTarget{{To: archive, TargetType: TargetTypeFile, TopLevel: true, TransferHash: "abcd"}}
+	got := manager.unpackTargets(targets)
+
+	folder := filepath.Join(dir, "Release")
+	if len(got) != 2 {
+		t.Fatalf("expected folder and extracted file, got %+v", got)
+	}
+	if got[0].To != folder || got[0].TargetType != TargetTypeDirectory || !got[0].TopLevel {
+		t.Errorf("expected folder to become the top-level target, got %+v", got[0])
+	}
+	if got[1].To != filepath.Join(folder, "release.mkv") || got[1].TopLevel {
+		t.Errorf("unexpected extracted target %+v", got[1])
+	}
+	if _, err := os.Stat(filepath.Join(folder, "Release.zip")); err != nil {
+		t.Errorf("expected archive to be moved into the folder: %v", err)
+	}
+}
+
+type recordingHistory struct {
+	entries []history.Entry
+}
+
+func (r *recordingHistory) Record(entry history.Entry) error {
+	r.entries = append(r.entries, entry)
+	return nil
+}
+
+func (r *recordingHistory) Query(history.Query) ([]history.Entry, error) { return r.entries, nil }
+
+func TestRecordHistory(t *testing.T) {
+	manager := setupTestManager()
+	store := &recordingHistory{}
+	manager.container.History = store
+
+	hash := "abcdef"
+	transfer := &Transfer{Name: "Show", TransferID: 1, Hash: &hash, Size: 1024}
+	transfer.MarkStarted()
+	transfer.MarkDownloaded()
+	transfer.MarkImported()
+	transfer.SetImportedBy("sonarr")
+
+	manager.recordHistory(transfer)
+
+	if len(store.entries) != 1 {
+		t.Fatalf("expected 1 history entry, got %d", len(store.entries))
+	}
+	entry := store.entries[0]
+	if entry.Name != "Show" || entry.Hash != hash || entry.Size != 1024 || entry.ImportedBy != "sonarr" {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+	if entry.StartedAt.IsZero() || entry.DownloadedAt.IsZero() || entry.ImportedAt.IsZero() || entry.CompletedAt.IsZero() {
+		t.Errorf("expected all timestamps to be set, got %+v", entry)
+	}
+
+	manager.container.History = nil
+	manager.recordHistory(transfer)
+}
+
+func TestIsRelevantOnlyNewTransfers(t *testing.T) {
+	manager := setupTestManager()
+	manager.config.ManageForeignTransfers = true
+	manager.startedAt = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	before := "2024-06-01T11:59:59"
+	after := "2024-06-01T12:00:01"
+	invalid := "yesterday"
+	tests := []struct {
+		name      string
+		createdAt *string
+		onlyNew   bool
+		expected  bool
+	}{
+		{name: "disabled", createdAt: &before, onlyNew: false, expected: true},
+		{name: "created before start", createdAt: &before, onlyNew: true, expected: false},
+		{name: "created after start", createdAt: &after, onlyNew: true, expected: true},
+		{name: "unknown creation time", createdAt: nil, onlyNew: true, expected: true},
+		{name: "unparsable creation time", createdAt: &invalid, onlyNew: true, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager.config.OnlyNewTransfers = tt.onlyNew
+			if got := manager.isRelevant(&putio.Transfer{ID: 1, CreatedAt: tt.createdAt}); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestIsRelevantOwnership(t *testing.T) {
+	manager := setupTestManager()
+	registry, _ := app.NewOwnershipRegistry("")
+	registry.Add("OWNED")
+	manager.container.Ownership = registry
+	manager.config.Putio.ParentFolderID = 77
+
+	owned, foreign := "owned", "foreign"
+	var parent, otherParent int64 = 77, 1
+	beforeStart := "2000-01-01T00:00:00"
+	tests := []struct {
+		name     string
+		transfer putio.Transfer
+		manage   bool
+		onlyNew  bool
+		expected bool
+	}{
+		{name: "owned by hash", transfer: putio.Transfer{Hash: &owned}, expected: true},
+		{name: "in parent folder", transfer: putio.Transfer{Hash: &foreign, SaveParentID: &parent}, expected: true},
+		{name: "foreign", transfer: putio.Transfer{Hash: &foreign, SaveParentID: &otherParent}, expected: false},
+		{name: "no hash", transfer: putio.Transfer{}, expected: false},
+		{name: "foreign managed", transfer: putio.Transfer{Hash: &foreign}, manage: true, expected: true},
+		{name: "owned before start", transfer: putio.Transfer{Hash: &owned, CreatedAt: &beforeStart}, onlyNew: true, expected: true},
+		{name: "foreign before start", transfer: putio.Transfer{Hash: &foreign, CreatedAt: &beforeStart}, manage: true, onlyNew: true, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager.config.ManageForeignTransfers = tt.manage
+			manager.config.OnlyNewTransfers = tt.onlyNew
+			if got := manager.isRelevant(&tt.transfer); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestAdoptExistingTransfers(t *testing.T) {
+	manager := setupTestManager()
+	path := filepath.Join(t.TempDir(), "owned_transfers.json")
+	registry, _ := app.NewOwnershipRegistry(path)
+	manager.container.Ownership = registry
+	labels, _ := app.NewLabelRegistry("")
+	labels.Set("labeled", []string{"tv"})
+	manager.container.Labels = labels
+	stages, _ := app.NewStageJournal("")
+	stages.Set("staged", app.StageDownloading)
+	manager.container.Stages = stages
+	manager.config.Putio.ParentFolderID = 77
+
+	labeled, staged, inParent, foreign := "labeled", "staged", "parent", "foreign"
+	parent := int64(77)
+	manager.adoptExistingTransfers([]putio.Transfer{
+		{ID: 1, Hash: &labeled},
+		{ID: 2, Hash: &staged},
+		{ID: 3, Hash: &inParent, SaveParentID: &parent},
+		{ID: 4, Hash: &foreign},
+		{ID: 5},
+	})
+	if !registry.Has(labeled) || !registry.Has(staged) || !registry.Has(inParent) || registry.Fresh() {
+		t.Fatal("expected the transfers the proxy handled to be adopted on the first run")
+	}
+	if registry.Has(foreign) {
+		t.Error("expected a transfer the proxy never handled not to be adopted")
+	}
+
+	later := "later"
+	labels.Set(later, []string{"tv"})
+	manager.adoptExistingTransfers([]putio.Transfer{{ID: 6, Hash: &later}})
+	if registry.Has(later) {
+		t.Error("expected transfers to be adopted only on the first run")
+	}
+}
+
+func TestAdoptExistingTransfersManageForeign(t *testing.T) {
+	manager := setupTestManager()
+	registry, _ := app.NewOwnershipRegistry(filepath.Join(t.TempDir(), "owned_transfers.json"))
+	manager.container.Ownership = registry
+	manager.config.ManageForeignTransfers = true
+
+	foreign := "foreign"
+	manager.adoptExistingTransfers([]putio.Transfer{{ID: 1, Hash: &foreign}})
+	if !registry.Has(foreign) {
+		t.Error("expected manage_foreign_transfers to adopt every transfer on the first run")
+	}
+}
+
+func TestSettledID(t *testing.T) {
+	manager := setupTestManager()
+	manager.config.ManageForeignTransfers = true
+	holds, _ := app.NewHoldRegistry("")
+	manager.container.Holds = holds
+	holds.Add("HELD")
+
+	held := "held"
+	fileID := int64(5)
+	manager.markSeen(10)
+	manager.markSeen(20)
+	manager.markSeen(30)
+
+	tests := []struct {
+		name      string
+		transfers []putio.Transfer
+		expected  uint64
+	}{
+		{name: "all handled", transfers: []putio.Transfer{{ID: 30, FileID: &fileID}, {ID: 20, FileID: &fileID}, {ID: 10, Status: "ERROR"}}, expected: 30},
+		{name: "downloading", transfers: []putio.Transfer{{ID: 30, FileID: &fileID}, {ID: 25, Status: "DOWNLOADING"}, {ID: 10, FileID: &fileID}}, expected: 24},
+		{name: "not handled yet", transfers: []putio.Transfer{{ID: 30, FileID: &fileID}, {ID: 15, FileID: &fileID}, {ID: 10, FileID: &fileID}}, expected: 14},
+		{name: "held", transfers: []putio.Transfer{{ID: 30, FileID: &fileID}, {ID: 20, Hash: &held, FileID: &fileID}}, expected: 19},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := manager.settledID(tt.transfers); got != tt.expected {
+				t.Errorf("expected %d, got %d", tt.expected, got)
+			}
+		})
+	}
+
+	manager.config.ManageForeignTransfers = false
+	if got := manager.settledID([]putio.Transfer{{ID: 40, FileID: &fileID}, {ID: 30, FileID: &fileID}}); got != 40 {
+		t.Errorf("expected transfers the proxy doesn't manage to be settled, got %d", got)
+	}
+}
+
+type recordingNotifier struct {
+	mu     sync.Mutex
+	events []notify.Event
+}
+
+func (r *recordingNotifier) Notify(ctx context.Context, event notify.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+	return nil
+}
+
+func TestHandleQueuedForDownloadNotifiesFailure(t *testing.T) {
+	manager := setupTestManager()
+	notifier := &recordingNotifier{}
+	manager.container.Notifier = notifier
+	manager.container.Transfers = app.NewTransferStore()
+
+	name, hash := "Show", "abc123"
+	transfer := NewTransfer(manager.config, &putio.Transfer{ID: 7, Name: &name, Hash: &hash})
+	manager.handleQueuedForDownload(transfer)
+
+	if state, _ := manager.container.Transfers.Get(hash); state.Stage != app.StageFailed {
+		t.Errorf("expected failed stage, got %+v", state)
+	}
+	if len(notifier.events) != 1 {
+		t.Fatalf("expected one notification, got %+v", notifier.events)
+	}
+	event := notifier.events[0]
+	if event.Type != notify.EventDownloadFailed || event.Transfer != name || event.Hash != hash || event.TransferID != 7 {
+		t.Errorf("unexpected event %+v", event)
+	}
+}
+
+func TestDailySummary(t *testing.T) {
+	manager := setupTestManager()
+	notifier := &recordingNotifier{}
+	manager.container.Notifier = notifier
+	manager.container.Pipeline = app.NewPipelineStats()
+	manager.container.Pipeline.Downloaded(5_000_000_000)
+	manager.container.Pipeline.Completed(90 * time.Minute)
+	manager.container.Pipeline.Failed()
+
+	manager.dailySummary()
+
+	if len(notifier.events) != 1 || notifier.events[0].Type != notify.EventDailySummary {
+		t.Fatalf("expected one daily summary notification, got %+v", notifier.events)
+	}
+	message := notifier.events[0].Message
+	for _, want := range []string{"5.0 GB downloaded", "1 imported", "1 failed", "50% success", "1h30m0s average"} {
+		if !strings.Contains(message, want) {
+			t.Errorf("expected %q in %q", want, message)
+		}
+	}
+}
+
+func TestHandleImportTimeoutActions(t *testing.T) {
+	tests := []struct {
+		action          string
+		expectStop      bool
+		expectDeleted   bool
+		expectBlocklist bool
+	}{
+		{action: "", expectStop: false},
+		{action: config.ImportTimeoutKeep, expectStop: false},
+		{action: config.ImportTimeoutDeleteLocal, expectStop: true, expectDeleted: true},
+		{action: config.ImportTimeoutBlocklist, expectStop: true, expectDeleted: true, expectBlocklist: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.action, func(t *testing.T) {
+			manager := setupTestManager()
+			manager.config.ImportTimeout = config.Seconds(3600)
+			manager.config.ImportTimeoutAction = tt.action
+			notifier := &recordingNotifier{}
+			manager.container.Notifier = notifier
+			arrClient := &mockArrClient{}
+			manager.arrClients = []ArrServiceClient{{Name: "sonarr", Client: arrClient}}
+
+			topLevel := filepath.Join(t.TempDir(), "Show")
+			if err := os.MkdirAll(topLevel, 0755); err != nil {
+				t.Fatalf("failed to create dir: %v", err)
+			}
+			hash := "abcdef"
+			transfer := &Transfer{Name: "Show", TransferID: 1, Hash: &hash}
+			transfer.SetTargets([]DownloadTarget{{To: topLevel, TargetType: TargetTypeDirectory, TopLevel: true}})
+
+			stop := manager.handleImportTimeout(transfer)
+			if stop != tt.expectStop {
+				t.Errorf("expected stop=%v, got %v", tt.expectStop, stop)
+			}
+			if !transfer.IsStalled() {
+				t.Error("expected transfer to be flagged as stalled")
+			}
+			if len(notifier.events) != 1 || notifier.events[0].Type != notify.EventImportTimeout || notifier.events[0].Hash != hash {
+				t.Errorf("expected one import timeout notification, got %+v", notifier.events)
+			}
+
+			_, err := os.Stat(topLevel)
+			if deleted := os.IsNotExist(err); deleted != tt.expectDeleted {
+				t.Errorf("expected deleted=%v, got %v", tt.expectDeleted, deleted)
+			}
+			if blocklisted := len(arrClient.blocklisted) > 0; blocklisted != tt.expectBlocklist {
+				t.Errorf("expected blocklist=%v, got %v", tt.expectBlocklist, arrClient.blocklisted)
+			}
+		})
+	}
+}
+
+func TestWatchForImportTimeout(t *testing.T) {
+	manager := setupTestManager()
+	manager.config.PollingInterval = config.Seconds(60)
+	manager.config.ImportTimeout = config.Duration(50 * time.Millisecond)
+	manager.config.ImportTimeoutAction = config.ImportTimeoutDeleteLocal
+
+	hash := "abcdef"
+	transfer := &Transfer{Name: "Show", TransferID: 1, Hash: &hash}
+
+	done := make(chan struct{})
+	manager.watchers.Go(func() {
+		manager.watchForImport(transfer)
+		close(done)
+	})
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected watcher to stop after import timeout")
+	}
+	if !transfer.IsStalled() {
+		t.Error("expected transfer to be flagged as stalled")
+	}
+}
+
+func TestAbandonedTransferSkippedUntilReadded(t *testing.T) {
+	manager := setupTestManager()
+	manager.config.ManageForeignTransfers = false
+	manager.config.Putio.ParentFolderID = 77
+	ownership, _ := app.NewOwnershipRegistry("")
+	labels, _ := app.NewLabelRegistry("")
+	manager.container.Ownership = ownership
+	manager.container.Labels = labels
+	manager.container.Transfers = app.NewTransferStore()
+	manager.container.Locations, _ = app.NewLocationRegistry("")
+
+	hash := "abcdef"
+	var parent, fileID int64 = 77, 5
+	pt := putio.Transfer{ID: 1, Hash: &hash, Status: "COMPLETED", SaveParentID: &parent, FileID: &fileID}
+	ownership.Add(hash)
+	labels.Set(hash, []string{"tv"})
+	manager.container.Locations.Set(hash, "/elsewhere")
+
+	manager.queueReadyTransfers([]putio.Transfer{pt})
+	msg, ok := popTransfer(manager, time.Second)
+	if !ok {
+		t.Fatal("expected the transfer to be queued")
+	}
+	manager.abandonTransfer(msg.Transfer)
+
+	if ownership.Has(hash) || len(labels.Get(hash)) != 0 {
+		t.Error("expected the registries to forget the abandoned transfer")
+	}
+	if _, ok := manager.container.Locations.Get(hash); ok {
+		t.Error("expected the location to be forgotten")
+	}
+	if manager.pipelines.len() != 0 {
+		t.Error("expected the transfer to leave the pipeline")
+	}
+
+	// Saved into the parent folder it's still relevant, but stays skipped.
+	manager.queueReadyTransfers([]putio.Transfer{pt})
+	if msg, ok := popTransfer(manager, 50*time.Millisecond); ok {
+		t.Fatalf("did not expect an abandoned transfer to be queued again: %+v", msg)
+	}
+
+	// torrent-add records the ownership again.
+	ownership.Add(hash)
+	manager.queueReadyTransfers([]putio.Transfer{pt})
+	if _, ok := popTransfer(manager, time.Second); !ok {
+		t.Fatal("expected a re-added transfer to be queued")
+	}
+
+	manager.cleanupSeen(map[uint64]bool{})
+	if len(manager.abandoned) != 0 || len(manager.seen) != 0 {
+		t.Error("expected removed transfers to be forgotten")
+	}
+}
+
+func TestDownloadTargetTracksProgress(t *testing.T) {
+	manager := setupTestManager()
+	store := app.NewTransferStore()
+	manager.container.Transfers = store
+	store.Track("abcd", "Show", 30)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("0123456789"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "existing.bin")
+	writeTestFile(t, existing, "01234")
+
+	targets := []*DownloadTarget{
+		{From: server.URL, To: filepath.Join(dir, "new.bin"), TargetType: TargetTypeFile, TransferHash: "abcd"},
+		{To: existing, TargetType: TargetTypeFile, TransferHash: "abcd"},
+	}
+	for _, target := range targets {
+		if status := manager.downloadTarget(target); status != DownloadStatusSuccess {
+			t.Fatalf("%s: expected success, got %v", target, status)
+		}
+	}
+
+	state, _ := store.Get("abcd")
+	if state.Downloaded != 15 {
+		t.Errorf("expected 15 bytes of progress, got %d", state.Downloaded)
+	}
+}
+
+func TestDownloadTargetFailureResetsProgress(t *testing.T) {
+	manager := setupTestManager()
+	store := app.NewTransferStore()
+	manager.container.Transfers = store
+	store.Track("abcd", "Show", 100)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "100")
+		w.Write([]byte("partial"))
+	}))
+	defer server.Close()
+
+	target := &DownloadTarget{From: server.URL, To: filepath.Join(t.TempDir(), "file.bin"), TargetType: TargetTypeFile, TransferHash: "abcd"}
+	if status := manager.downloadTarget(target); status != DownloadStatusFailed {
+		t.Fatalf("expected failure for truncated body, got %v", status)
+	}
+
+	state, _ := store.Get("abcd")
+	if state.Downloaded != 0 {
+		t.Errorf("expected progress of the failed download to be dropped, got %d", state.Downloaded)
+	}
+}
+
+func TestHandleImportedUpdatesTransferStore(t *testing.T) {
+	manager := setupTestManager()
+	store := app.NewTransferStore()
+	manager.container.Transfers = store
+	keep := false
+	manager.config.DeleteLocalAfterImport = &keep
+
+	hash := "abcd"
+	store.Track(hash, "Show", 1)
+	store.SetStage(hash, app.StageWaitingForImport)
+
+	manager.handleImported(&Transfer{Name: "Show", TransferID: 1, Hash: &hash})
+
+	state, _ := store.Get(hash)
+	if state.Stage != app.StageImported {
+		t.Errorf("expected imported stage, got %s", state.Stage)
+	}
+}
+
+func TestHandleImportedRecordsHistory(t *testing.T) {
+	manager := setupTestManager()
+	history := &recordingHistory{}
+	manager.container.History = history
+	keep := false
+	manager.config.DeleteLocalAfterImport = &keep
+
+	hash := "abcd"
+	manager.handleImported(&Transfer{Name: "Show", TransferID: 1, Hash: &hash})
+
+	if len(history.entries) != 1 || history.entries[0].Hash != hash {
+		t.Errorf("expected the import to be recorded right away, got %+v", history.entries)
+	}
+}
+
+func TestQueueReadyTransfersSkipsHeld(t *testing.T) {
+	manager := setupTestManager()
+	manager.config.ManageForeignTransfers = true
+	holds, _ := app.NewHoldRegistry("")
+	manager.container.Holds = holds
+	holds.Add("HELD")
+
+	held, ready := "held", "ready"
+	fileID := int64(5)
+	transfers := []putio.Transfer{
+		{ID: 1, Hash: &held, FileID: &fileID},
+		{ID: 2, Hash: &ready, FileID: &fileID},
+		{ID: 3, Hash: &ready},
+	}
+
+	manager.queueReadyTransfers(transfers)
+	msg, ok := popTransfer(manager, time.Second)
+	if !ok || msg.Transfer.TransferID != 2 {
+		t.Fatalf("expected only the ready transfer to be queued, got %+v", msg)
+	}
+	if manager.transfers.Len() != 0 {
+		t.Fatalf("expected no other transfers to be queued, got %d", manager.transfers.Len())
+	}
+
+	holds.Remove("held")
+	manager.queueReadyTransfers(transfers)
+	msg, ok = popTransfer(manager, time.Second)
+	if !ok || msg.Transfer.TransferID != 1 {
+		t.Fatalf("expected the released transfer to be queued, got %+v", msg)
+	}
+	if manager.transfers.Len() != 0 {
+		t.Errorf("expected seen transfers not to be queued again, got %d", manager.transfers.Len())
+	}
+}
+
+func TestPollBackoff(t *testing.T) {
+	tests := []struct {
+		interval time.Duration
+		failures int
+		expected time.Duration
+	}{
+		{interval: 10 * time.Second, failures: 1, expected: 10 * time.Second},
+		{interval: 10 * time.Second, failures: 2, expected: 20 * time.Second},
+		{interval: 10 * time.Second, failures: 4, expected: 80 * time.Second},
+		{interval: 10 * time.Second, failures: 6, expected: maxPollBackoff},
+		{interval: 10 * time.Second, failures: 1000, expected: maxPollBackoff},
+		{interval: time.Hour, failures: 3, expected: time.Hour},
+	}
+	for _, tt := range tests {
+		if got := pollBackoff(tt.interval, tt.failures); got != tt.expected {
+			t.Errorf("pollBackoff(%v, %d) = %v, expected %v", tt.interval, tt.failures, got, tt.expected)
+		}
+	}
+}
+
+// flakyPutioClient fails the first failures transfer listings, with err if
+// set.
+type flakyPutioClient struct {
+	mockPutioClient
+	mu       sync.Mutex
+	failures int
+	calls    int
+	err      error
+}
+
+func (f *flakyPutioClient) ListTransfers() (*putio.ListTransferResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.calls <= f.failures {
+		if f.err != nil {
+			return nil, f.err
+		}
+		return nil, errors.New("put.io is down")
+	}
+	return f.mockPutioClient.ListTransfers()
+}
+
+func (f *flakyPutioClient) ListTransfersSince(uint64) (*putio.ListTransferResponse, error) {
+	return f.ListTransfers()
+}
+
+func (f *flakyPutioClient) callCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+func TestProduceTransfersBacksOffAndReportsRecovery(t *testing.T) {
+	manager := setupTestManager()
+	manager.config.PollingInterval = config.Duration(5 * time.Millisecond)
+	client := &flakyPutioClient{failures: 4}
+	manager.putioClient = client
+	notifier := &recordingNotifier{}
+	manager.container.Notifier = notifier
+
+	manager.poller.Go(manager.produceTransfers)
+	defer manager.Stop()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		notifier.mu.Lock()
+		events := append([]notify.Event(nil), notifier.events...)
+		notifier.mu.Unlock()
+		if len(events) > 0 {
+			if len(events) != 1 || events[0].Type != notify.EventPutioRecovered {
+				t.Fatalf("expected one recovery notification, got %+v", events)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected a recovery notification")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// The startup check and three backed-off polls failed before the one that
+	// recovered; polling then continues at the normal interval.
+	calls := client.callCount()
+	time.Sleep(100 * time.Millisecond)
+	if client.callCount() <= calls {
+		t.Error("expected polling to continue after recovery")
+	}
+}
+
+func TestProduceTransfersReportsAuthFailureOnce(t *testing.T) {
+	manager := setupTestManager()
+	manager.config.PollingInterval = config.Duration(5 * time.Millisecond)
+	client := &flakyPutioClient{failures: 4, err: &putio.HTTPError{StatusCode: http.StatusUnauthorized, Status: "401 Unauthorized"}}
+	manager.putioClient = client
+	notifier := &recordingNotifier{}
+	manager.container.Notifier = notifier
+
+	manager.poller.Go(manager.produceTransfers)
+	defer manager.Stop()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		notifier.mu.Lock()
+		events := append([]notify.Event(nil), notifier.events...)
+		notifier.mu.Unlock()
+		if len(events) == 2 {
+			if events[0].Type != notify.EventPutioAuthFailed || events[1].Type != notify.EventPutioRecovered {
+				t.Fatalf("expected one auth failure, then recovery, got %+v", events)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected auth failure and recovery notifications, got %+v", events)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestIsAuthError(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected bool
+	}{
+		{err: &putio.HTTPError{StatusCode: http.StatusUnauthorized}, expected: true},
+		{err: fmt.Errorf("listing: %w", &putio.HTTPError{StatusCode: http.StatusForbidden}), expected: true},
+		{err: &putio.HTTPError{StatusCode: http.StatusBadGateway}, expected: false},
+		{err: errors.New("connection refused"), expected: false},
+	}
+	for _, tt := range tests {
+		if got := isAuthError(tt.err); got != tt.expected {
+			t.Errorf("isAuthError(%v) = %v, expected %v", tt.err, got, tt.expected)
+		}
+	}
+}
+
+func TestProduceTransfersPingsHeartbeat(t *testing.T) {
+	var mu sync.Mutex
+	var pings []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		pings = append(pings, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	manager := setupTestManager()
+	manager.config.PollingInterval = config.Duration(5 * time.Millisecond)
+	// The startup check and the first four polls fail.
+	manager.putioClient = &flakyPutioClient{failures: 5}
+	manager.container.Heartbeat = heartbeat.New(server.URL + "/ping/abc")
+
+	manager.poller.Go(manager.produceTransfers)
+	defer manager.Stop()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		got := append([]string(nil), pings...)
+		mu.Unlock()
+		if len(got) > 0 && got[len(got)-1] == "GET /ping/abc" {
+			fails := 0
+			for _, ping := range got {
+				if ping == "POST /ping/abc/fail" {
+					fails++
+				}
+			}
+			// Only the third and fourth failures in a row are reported.
+			if fails != 2 {
+				t.Errorf("expected 2 failure pings before the success ping, got %v", got)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected a success ping after the failures, got %v", got)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+type recordingDestination struct {
+	stored    []string
+	removed   []string
+	storeErr  error
+	removeErr error
+}
+
+func (r *recordingDestination) Name() string { return "remote:" }
+
+func (r *recordingDestination) Store(ctx context.Context, path string) error {
+	r.stored = append(r.stored, path)
+	return r.storeErr
+}
+
+func (r *recordingDestination) Remove(ctx context.Context, path string) error {
+	r.removed = append(r.removed, path)
+	return r.removeErr
+}
+
+func TestDestinationReceivesTopLevelTarget(t *testing.T) {
+	manager := setupTestManager()
+	backend := &recordingDestination{}
+	manager.destination = backend
+
+	transfer := &Transfer{Name: "Show"}
+	transfer.SetTargets([]DownloadTarget{
+		{To: "/downloads/Show", TargetType: TargetTypeDirectory, TopLevel: true},
+		{To: "/downloads/Show/ep1.mkv", TargetType: TargetTypeFile},
+	})
+
+	if err := manager.storeDownload(transfer); err != nil {
+		t.Fatalf("storeDownload: %v", err)
+	}
+	manager.deleteLocalFiles(transfer)
+
+	if len(backend.stored) != 1 || backend.stored[0] != "/downloads/Show" {
+		t.Errorf("expected the top-level directory to be stored, got %v", backend.stored)
+	}
+	if len(backend.removed) != 1 || backend.removed[0] != "/downloads/Show" {
+		t.Errorf("expected the top-level directory to be removed, got %v", backend.removed)
+	}
+
+	backend.storeErr = errors.New("quota exceeded")
+	if err := manager.storeDownload(transfer); err == nil {
+		t.Error("expected the store error to be returned")
+	}
+}
+
+func TestGetResponseTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	manager := setupTestManager()
+	manager.config.DownloadResponseTimeout = config.Duration(50 * time.Millisecond)
+	manager.httpClient = newHTTPClient(manager.config)
+
+	start := time.Now()
+	resp, err := manager.get(context.Background(), server.URL, 0)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatal("expected the request to time out waiting for the response")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the response timeout to apply, took %s", elapsed)
+	}
+}
+
+func TestNewHTTPClientTimeouts(t *testing.T) {
+	cfg := &config.Config{DownloadConnectTimeout: config.Seconds(5), DownloadResponseTimeout: config.Seconds(7)}
+	client := newHTTPClient(cfg)
+	transport := client.Transport.(*http.Transport)
+	if client.Timeout != 0 {
+		t.Errorf("expected no overall timeout, got %s", client.Timeout)
+	}
+	if transport.TLSHandshakeTimeout != 5*time.Second || transport.ResponseHeaderTimeout != 7*time.Second {
+		t.Errorf("unexpected transport timeouts %s and %s", transport.TLSHandshakeTimeout, transport.ResponseHeaderTimeout)
+	}
+}
+
+func TestAcquireActiveSlot(t *testing.T) {
+	manager := setupTestManager()
+	if release, ok := manager.tryActiveSlot(); !ok {
+		t.Fatal("expected no limit by default")
+	} else {
+		release()
+	}
+
+	manager.config.MaxActiveTransfers = 1
+	manager = NewManager(manager.container)
+	release, ok := manager.tryActiveSlot()
+	if !ok {
+		t.Fatal("expected a free slot")
+	}
+	if _, ok := manager.tryActiveSlot(); ok {
+		t.Fatal("expected no second slot")
+	}
+
+	acquired := make(chan bool, 1)
+	go func() {
+		release, ok := manager.acquireActiveSlot()
+		if ok {
+			defer release()
+		}
+		acquired <- ok
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("expected the second transfer to wait for the slot")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	release()
+	select {
+	case ok := <-acquired:
+		if !ok {
+			t.Error("expected the second transfer to get the slot")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the released slot to be taken")
+	}
+
+	release, _ = manager.acquireActiveSlot()
+	defer release()
+	stopped := make(chan bool, 1)
+	go func() {
+		_, ok := manager.acquireActiveSlot()
+		stopped <- ok
+	}()
+	manager.Stop()
+	if ok := <-stopped; ok {
+		t.Error("expected waiting to end when the manager stops")
+	}
+}
+
+func TestQueueForDownloadWaitsOffTheWorker(t *testing.T) {
+	manager := setupTestManager()
+	manager.config.MaxActiveTransfers = 1
+	manager = NewManager(manager.container)
+	manager.container.Transfers = app.NewTransferStore()
+	release, _ := manager.tryActiveSlot()
+
+	hash := "abcd"
+	fileID := int64(100)
+	transfer := &Transfer{Name: "Show", TransferID: 7, FileID: &fileID, Hash: &hash}
+	done := make(chan struct{})
+	go func() {
+		manager.queueForDownload(transfer)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected the orchestration worker not to wait for a slot")
+	}
+	if state, ok := manager.container.Transfers.Get(hash); !ok || state.Stage != app.StageQueued {
+		t.Fatalf("expected the waiting transfer to be tracked as queued, got %+v", state)
+	}
+	if manager.idle() {
+		t.Error("expected a transfer waiting for a slot to keep the manager busy")
+	}
+
+	release()
+	deadline := time.Now().Add(time.Second)
+	for {
+		if state, _ := manager.container.Transfers.Get(hash); state.Stage != app.StageQueued {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the transfer to be handled once the slot was released")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	manager.Stop()
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package download
+
+import "os"
+
+// linkCount returns 1 on platforms where hard links can't be detected, so
+// cleanup falls back to looking for the imported copy
+func linkCount(info os.FileInfo) uint64 {
+	return 1
+}
@@ -0,0 +1,16 @@
+//go:build unix
+
+package download
+
+import (
+	"os"
+	"syscall"
+)
+
+// linkCount returns the number of hard links to the file described by info
+func linkCount(info os.FileInfo) uint64 {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(stat.Nlink)
+	}
+	return 1
+}
@@ -0,0 +1,30 @@
+package download
+
+import (
+	"sort"
+
+	"github.com/ochronus/goputioarr/internal/config"
+)
+
+// orderTargets sorts the targets of a transfer for download_order. Directory
+// targets stay first, in walk order, so they exist before their files are
+// written; file targets are sorted by size. The default order leaves targets
+// in walk order.
+func orderTargets(targets []DownloadTarget, order string) {
+	var less func(a, b *DownloadTarget) bool
+	switch order {
+	case config.DownloadOrderSmallestFirst:
+		less = func(a, b *DownloadTarget) bool { return a.Size < b.Size }
+	case config.DownloadOrderLargestFirst:
+		less = func(a, b *DownloadTarget) bool { return a.Size > b.Size }
+	default:
+		return
+	}
+	sort.SliceStable(targets, func(i, j int) bool {
+		a, b := &targets[i], &targets[j]
+		if a.TargetType != b.TargetType {
+			return a.TargetType == TargetTypeDirectory
+		}
+		return a.TargetType == TargetTypeFile && less(a, b)
+	})
+}
@@ -0,0 +1,40 @@
+package download
+
+import (
+	"testing"
+
+	"github.com/ochronus/goputioarr/internal/config"
+)
+
+func TestOrderTargets(t *testing.T) {
+	walk := func() []DownloadTarget {
+		return []DownloadTarget{
+			{To: "/d/show", TargetType: TargetTypeDirectory},
+			{To: "/d/show/episode.mkv", TargetType: TargetTypeFile, Size: 1000},
+			{To: "/d/show/subs", TargetType: TargetTypeDirectory},
+			{To: "/d/show/subs/en.srt", TargetType: TargetTypeFile, Size: 10},
+			{To: "/d/show/sample.mkv", TargetType: TargetTypeFile, Size: 100},
+			{To: "/d/show/subs/de.srt", TargetType: TargetTypeFile, Size: 10},
+		}
+	}
+	tests := []struct {
+		order    string
+		expected []string
+	}{
+		{order: "", expected: []string{"/d/show", "/d/show/episode.mkv", "/d/show/subs", "/d/show/subs/en.srt", "/d/show/sample.mkv", "/d/show/subs/de.srt"}},
+		{order: config.DownloadOrderDefault, expected: []string{"/d/show", "/d/show/episode.mkv", "/d/show/subs", "/d/show/subs/en.srt", "/d/show/sample.mkv", "/d/show/subs/de.srt"}},
+		{order: config.DownloadOrderSmallestFirst, expected: []string{"/d/show", "/d/show/subs", "/d/show/subs/en.srt", "/d/show/subs/de.srt", "/d/show/sample.mkv", "/d/show/episode.mkv"}},
+		{order: config.DownloadOrderLargestFirst, expected: []string{"/d/show", "/d/show/subs", "/d/show/episode.mkv", "/d/show/sample.mkv", "/d/show/subs/en.srt", "/d/show/subs/de.srt"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.order, func(t *testing.T) {
+			targets := walk()
+			orderTargets(targets, tt.order)
+			for i, target := range targets {
+				if target.To != tt.expected[i] {
+					t.Fatalf("unexpected order at %d: got %s, expected %s", i, target.To, tt.expected[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package download
+
+// chownToUser does nothing on platforms without Unix file ownership
+func chownToUser(path string, uid, gid int) error {
+	return nil
+}
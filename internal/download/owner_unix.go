@@ -0,0 +1,15 @@
+//go:build unix
+
+package download
+
+import "os"
+
+// chownToUser hands path over to uid and gid when running as root, so files
+// created by a root container are owned by the configured user. A gid of -1
+// leaves the group unchanged.
+func chownToUser(path string, uid, gid int) error {
+	if os.Getuid() != 0 {
+		return nil
+	}
+	return os.Chown(path, uid, gid)
+}
@@ -0,0 +1,47 @@
+//go:build unix
+
+package download
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestPrepareDirectoryOnlyChownsCreated(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("changing ownership requires root")
+	}
+	existing := t.TempDir()
+	dir := filepath.Join(existing, "nested", "downloads")
+	if err := PrepareDirectory(dir, true, 1234, 1234); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	owner := func(path string) int {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("failed to stat %s: %v", path, err)
+		}
+		return int(info.Sys().(*syscall.Stat_t).Uid)
+	}
+	if got := owner(existing); got != 0 {
+		t.Errorf("expected the existing directory to keep its owner, got uid %d", got)
+	}
+	for _, path := range []string{filepath.Dir(dir), dir} {
+		if got := owner(path); got != 1234 {
+			t.Errorf("expected %s to be handed over, got uid %d", path, got)
+		}
+	}
+
+	if err := os.Chown(dir, 0, 0); err != nil {
+		t.Fatalf("failed to chown: %v", err)
+	}
+	if err := PrepareDirectory(dir, true, 1234, 1234); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := owner(dir); got != 0 {
+		t.Errorf("expected an existing download directory to keep its owner, got uid %d", got)
+	}
+}
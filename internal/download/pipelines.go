@@ -0,0 +1,79 @@
+package download
+
+import "sync"
+
+// pipelineRegistry records the transfers that are somewhere in the manager's
+// pipeline, from being queued for download until they're done, failed or
+// handed back to put.io, so each is downloaded and watched by one goroutine at
+// a time. Startup checks, the poller and the import folder can all come
+// across the same transfer; only the first to claim it queues it.
+type pipelineRegistry struct {
+	mu     sync.Mutex
+	active map[string]bool
+}
+
+func newPipelineRegistry() *pipelineRegistry {
+	return &pipelineRegistry{active: make(map[string]bool)}
+}
+
+// claim records hash as being in the pipeline and reports whether it wasn't
+// already. Transfers without a hash can't be told apart and are always let in.
+func (r *pipelineRegistry) claim(hash string) bool {
+	if hash == "" {
+		return true
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.active[hash] {
+		return false
+	}
+	r.active[hash] = true
+	return true
+}
+
+// release records that hash left the pipeline.
+func (r *pipelineRegistry) release(hash string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.active, hash)
+}
+
+// reset forgets every transfer. Only call it while no goroutine of the
+// pipeline is running.
+func (r *pipelineRegistry) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.active = make(map[string]bool)
+}
+
+// len returns the number of transfers in the pipeline.
+func (r *pipelineRegistry) len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.active)
+}
+
+// claimPipeline claims transfer for the pipeline, logging when it's skipped
+// because another part of the manager already handles it.
+func (m *Manager) claimPipeline(transfer *Transfer) bool {
+	if m.pipelines.claim(pipelineKey(transfer)) {
+		return true
+	}
+	m.transferLogger(transfer).Debugf("%s: already being handled, not queued again", transfer)
+	return false
+}
+
+// releasePipeline records that transfer left the pipeline, so it may be
+// queued again.
+func (m *Manager) releasePipeline(transfer *Transfer) {
+	m.pipelines.release(pipelineKey(transfer))
+}
+
+// pipelineKey returns the key transfer is registered under, its hash, or ""
+// if it has none.
+func pipelineKey(transfer *Transfer) string {
+	if transfer.Hash == nil {
+		return ""
+	}
+	return *transfer.Hash
+}
@@ -0,0 +1,58 @@
+package download
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ochronus/goputioarr/internal/services/putio"
+)
+
+func TestPipelineRegistry(t *testing.T) {
+	r := newPipelineRegistry()
+	if !r.claim("abc") || r.claim("abc") {
+		t.Fatal("expected only the first claim of a hash to succeed")
+	}
+	if !r.claim("") || !r.claim("") {
+		t.Error("expected transfers without a hash to always be let in")
+	}
+	r.release("abc")
+	if !r.claim("abc") {
+		t.Error("expected a released hash to be claimable again")
+	}
+	r.reset()
+	if r.len() != 0 {
+		t.Errorf("expected reset to forget every transfer, got %d", r.len())
+	}
+}
+
+func TestQueueReadyTransfersSkipsTransfersInThePipeline(t *testing.T) {
+	manager := setupTestManager()
+	manager.config.ManageForeignTransfers = true
+
+	hash := "abc"
+	fileID := int64(5)
+	transfers := []putio.Transfer{{ID: 1, Hash: &hash, FileID: &fileID}}
+
+	manager.queueReadyTransfers(transfers)
+	msg, ok := popTransfer(manager, time.Second)
+	if !ok {
+		t.Fatal("expected the transfer to be queued")
+	}
+
+	// The transfer briefly drops out of put.io's list while it's downloaded.
+	manager.cleanupSeen(map[uint64]bool{})
+	manager.queueReadyTransfers(transfers)
+	if manager.transfers.Len() != 0 {
+		t.Fatal("expected a transfer in the pipeline not to be queued again")
+	}
+
+	manager.finishTransfer(msg.Transfer)
+	if manager.pipelines.len() != 0 {
+		t.Errorf("expected a finished transfer to leave the pipeline, got %d", manager.pipelines.len())
+	}
+	manager.cleanupSeen(map[uint64]bool{})
+	manager.queueReadyTransfers(transfers)
+	if manager.transfers.Len() != 1 {
+		t.Errorf("expected the transfer to be queued again once finished, got %d", manager.transfers.Len())
+	}
+}
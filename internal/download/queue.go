@@ -0,0 +1,91 @@
+package download
+
+import (
+	"context"
+	"sync"
+)
+
+// queueWarnDepth is the queue depth above which the manager logs a warning.
+const queueWarnDepth = 1000
+
+// QueueDepths reports how much work is waiting in the manager's queues.
+type QueueDepths struct {
+	Transfers int `json:"transfers"`
+	Downloads int `json:"downloads"`
+}
+
+// queue is an unbounded FIFO queue safe for concurrent use. Push never
+// blocks, so workers can hand work to each other without deadlocking when a
+// burst of transfers arrives. Queued work isn't persisted: after a restart it
+// is rebuilt from the transfers still listed on put.io.
+type queue[T any] struct {
+	mu    sync.Mutex
+	items []T
+	ready chan struct{}
+}
+
+func newQueue[T any]() *queue[T] {
+	return &queue[T]{ready: make(chan struct{}, 1)}
+}
+
+// Push appends item to the queue.
+func (q *queue[T]) Push(item T) {
+	q.mu.Lock()
+	q.items = append(q.items, item)
+	q.mu.Unlock()
+	q.signal()
+}
+
+// Pop removes and returns the oldest item, waiting for one if the queue is
+// empty. It returns false once ctx is cancelled.
+func (q *queue[T]) Pop(ctx context.Context) (T, bool) {
+	for {
+		if item, ok := q.TryPop(); ok {
+			return item, true
+		}
+		select {
+		case <-ctx.Done():
+			var zero T
+			return zero, false
+		case <-q.ready:
+		}
+	}
+}
+
+// TryPop removes and returns the oldest item without waiting.
+func (q *queue[T]) TryPop() (T, bool) {
+	q.mu.Lock()
+	var zero T
+	if len(q.items) == 0 {
+		q.mu.Unlock()
+		return zero, false
+	}
+	item := q.items[0]
+	q.items[0] = zero
+	q.items = q.items[1:]
+	if len(q.items) == 0 {
+		q.items = nil
+	}
+	remaining := len(q.items) > 0
+	q.mu.Unlock()
+
+	// Wake another waiting consumer for the remaining items.
+	if remaining {
+		q.signal()
+	}
+	return item, true
+}
+
+// Len returns the number of queued items.
+func (q *queue[T]) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.items)
+}
+
+func (q *queue[T]) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,101 @@
+package download
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestQueueFIFO(t *testing.T) {
+	q := newQueue[int]()
+	for i := 0; i < 500; i++ {
+		q.Push(i)
+	}
+	if q.Len() != 500 {
+		t.Fatalf("expected 500 items, got %d", q.Len())
+	}
+	for i := 0; i < 500; i++ {
+		got, ok := q.TryPop()
+		if !ok || got != i {
+			t.Fatalf("expected %d, got %d (ok=%v)", i, got, ok)
+		}
+	}
+	if _, ok := q.TryPop(); ok {
+		t.Error("expected empty queue")
+	}
+}
+
+func TestQueuePopWaitsForPush(t *testing.T) {
+	q := newQueue[string]()
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		q.Push("item")
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	got, ok := q.Pop(ctx)
+	if !ok || got != "item" {
+		t.Errorf("expected 'item', got %q (ok=%v)", got, ok)
+	}
+}
+
+func TestQueuePopCancelled(t *testing.T) {
+	q := newQueue[int]()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, ok := q.Pop(ctx); ok {
+		t.Error("expected Pop to fail on cancelled context")
+	}
+}
+
+func TestQueueConcurrentConsumers(t *testing.T) {
+	q := newQueue[int]()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const items = 1000
+	var mu sync.Mutex
+	received := make(map[int]bool)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				item, ok := q.Pop(ctx)
+				if !ok {
+					return
+				}
+				mu.Lock()
+				received[item] = true
+				done := len(received) == items
+				mu.Unlock()
+				if done {
+					cancel()
+				}
+			}
+		}()
+	}
+	for i := 0; i < items; i++ {
+		q.Push(i)
+	}
+
+	wg.Wait()
+	if len(received) != items {
+		t.Errorf("expected %d items, got %d", items, len(received))
+	}
+}
+
+func TestQueueDepths(t *testing.T) {
+	manager := setupTestManager()
+	manager.transfers.Push(TransferMessage{Type: MessageImported})
+	manager.downloads.Push(DownloadTargetMessage{})
+	manager.downloads.Push(DownloadTargetMessage{})
+
+	depths := manager.QueueDepths()
+	if depths.Transfers != 1 || depths.Downloads != 2 {
+		t.Errorf("unexpected depths %+v", depths)
+	}
+}
@@ -0,0 +1,107 @@
+package download
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/ochronus/goputioarr/internal/app"
+)
+
+// reconcile works out where transfer was in the pipeline before a restart,
+// from the arrs, the stage journal and the files on disk, and resumes it from
+// there. A transfer that got past its download is checked with the targets
+// recorded then, e.g. the files unpacked from its archives. It reports whether transfer was resumed or is already handled;
+// anything else is left for the poller to download, which keeps the files that
+// are already complete. Reconciling a transfer twice doesn't queue it twice.
+func (m *Manager) reconcile(transfer *Transfer) bool {
+	stage, _ := m.container.Stages.Get(transfer.GetHash())
+	targets, ok := m.recordedTargets(transfer, stage)
+	if !ok {
+		var err error
+		targets, err = m.getDownloadTargets(transfer)
+		if err != nil {
+			m.transferLogger(transfer).Warnf("Could not get target for %s: %v", transfer.Name, err)
+			return false
+		}
+	}
+	transfer.SetTargets(targets)
+
+	present, total := completeFiles(targets)
+	switch {
+	// Asking the arrs first also recovers which of them imported the
+	// transfer, for its delete_remote_files setting.
+	case m.isImported(transfer) || stage == app.StageImported:
+		m.transferLogger(transfer).Infof("%s: already imported", transfer)
+		return m.resumeAt(transfer, app.StageImported, MessageImported)
+	case stage == app.StageWaitingForImport || (total > 0 && present == total):
+		m.transferLogger(transfer).Infof("%s: already downloaded, waiting for import", transfer)
+		transfer.MarkDownloaded()
+		return m.resumeAt(transfer, app.StageWaitingForImport, MessageDownloaded)
+	case present > 0:
+		m.transferLogger(transfer).Infof("%s: resuming download, %d of %d files already downloaded", transfer, present, total)
+	default:
+		m.transferLogger(transfer).Infof("%s: not imported yet", transfer)
+	}
+	return false
+}
+
+// resumeAt puts transfer back into the pipeline at stage by sending it msgType.
+func (m *Manager) resumeAt(transfer *Transfer, stage app.TransferStage, msgType TransferMessageType) bool {
+	if !m.claimPipeline(transfer) {
+		return true
+	}
+	m.track(transfer)
+	m.container.Transfers.SetStage(transfer.GetHash(), stage)
+	m.transfers.Push(TransferMessage{
+		Type:     msgType,
+		Transfer: transfer,
+	})
+	return true
+}
+
+// recordTargets records the targets transfer ended up with in the stage
+// journal, so that after a restart its imports are checked for those rather
+// than for the archives listed on put.io.
+func (m *Manager) recordTargets(transfer *Transfer, targets []DownloadTarget) {
+	data, err := json.Marshal(targets)
+	if err == nil {
+		err = m.container.Stages.SetTargets(transfer.GetHash(), data)
+	}
+	if err != nil {
+		m.transferLogger(transfer).Warnf("%s: failed to record the targets: %v", transfer, err)
+	}
+}
+
+// recordedTargets returns the targets recordTargets recorded for transfer if
+// it got past its download, i.e. reached stage.
+func (m *Manager) recordedTargets(transfer *Transfer, stage app.TransferStage) ([]DownloadTarget, bool) {
+	if stage != app.StageWaitingForImport && stage != app.StageImported {
+		return nil, false
+	}
+	data, ok := m.container.Stages.Targets(transfer.GetHash())
+	if !ok {
+		return nil, false
+	}
+	var targets []DownloadTarget
+	if err := json.Unmarshal(data, &targets); err != nil {
+		m.transferLogger(transfer).Warnf("%s: ignoring the recorded targets: %v", transfer, err)
+		return nil, false
+	}
+	return targets, true
+}
+
+// completeFiles counts the file targets and those of them that are completely
+// downloaded. Files still being written have a temporary name until done, so
+// any file at a target's path is complete.
+func completeFiles(targets []DownloadTarget) (present, total int) {
+	for _, target := range targets {
+		if target.TargetType != TargetTypeFile {
+			continue
+		}
+		total++
+		if _, err := os.Stat(target.To); err == nil {
+			present++
+		}
+	}
+	return present, total
+}
@@ -0,0 +1,173 @@
+package download
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ochronus/goputioarr/internal/app"
+	"github.com/ochronus/goputioarr/internal/services/putio"
+	"github.com/ochronus/goputioarr/internal/services/unpack"
+)
+
+// setupReconcileManager returns a manager whose put.io account has a transfer
+// of a folder with two files, downloaded to a temporary directory.
+func setupReconcileManager(t *testing.T) (*Manager, *Transfer) {
+	manager := setupTestManager()
+	manager.config.DownloadDirectory = t.TempDir()
+	manager.container.Transfers = app.NewTransferStore()
+	journal, _ := app.NewStageJournal("")
+	manager.container.Stages = journal
+	manager.putioClient = &mockPutioClient{
+		listFilesByID: map[int64]*putio.ListFileResponse{
+			100: {
+				Parent: putio.FileResponse{ID: 100, Name: "Show", FileType: "FOLDER"},
+				Files:  []putio.FileResponse{{ID: 200}, {ID: 300}},
+			},
+			200: {Parent: putio.FileResponse{ID: 200, Name: "e01.mkv", FileType: "VIDEO"}},
+			300: {Parent: putio.FileResponse{ID: 300, Name: "e02.mkv", FileType: "VIDEO"}},
+		},
+	}
+	fileID := int64(100)
+	hash := "hash123"
+	return manager, &Transfer{Name: "Show", FileID: &fileID, Hash: &hash, TransferID: 1}
+}
+
+func writeDownloaded(t *testing.T, manager *Manager, names ...string) {
+	dir := filepath.Join(manager.config.DownloadDirectory, "Show")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReconcileResumesDownloadedTransfer(t *testing.T) {
+	manager, transfer := setupReconcileManager(t)
+	writeDownloaded(t, manager, "e01.mkv", "e02.mkv")
+
+	if !manager.reconcile(transfer) || !manager.reconcile(transfer) {
+		t.Fatal("expected the downloaded transfer to be resumed")
+	}
+	msg, ok := popTransfer(manager, time.Second)
+	if !ok || msg.Type != MessageDownloaded {
+		t.Fatalf("expected the transfer to wait for its import, got %+v", msg)
+	}
+	if manager.transfers.Len() != 0 {
+		t.Errorf("expected reconciling twice to queue the transfer once, got %d more", manager.transfers.Len())
+	}
+	if state, _ := manager.container.Transfers.Get("hash123"); state.Stage != app.StageWaitingForImport {
+		t.Errorf("expected the waiting_for_import stage, got %s", state.Stage)
+	}
+}
+
+func TestReconcileResumesFromJournal(t *testing.T) {
+	manager, transfer := setupReconcileManager(t)
+	manager.container.Stages.Set("hash123", app.StageImported)
+
+	if !manager.reconcile(transfer) {
+		t.Fatal("expected the imported transfer to be resumed")
+	}
+	msg, ok := popTransfer(manager, time.Second)
+	if !ok || msg.Type != MessageImported {
+		t.Fatalf("expected the transfer to be watched while seeding, got %+v", msg)
+	}
+}
+
+func TestReconcileRecoversImportingArr(t *testing.T) {
+	manager, transfer := setupReconcileManager(t)
+	manager.container.Stages.Set("hash123", app.StageImported)
+	manager.arrClients = []ArrServiceClient{
+		{Name: "radarr", Client: &mockArrClient{}},
+		{Name: "sonarr", Client: &mockArrClient{imported: true}},
+	}
+
+	if !manager.reconcile(transfer) {
+		t.Fatal("expected the imported transfer to be resumed")
+	}
+	if got := transfer.GetImportedBy(); got != "sonarr" {
+		t.Errorf("expected the transfer to be imported by sonarr, got %q", got)
+	}
+}
+
+func TestReconcileLeavesPartialDownloadToThePoller(t *testing.T) {
+	manager, transfer := setupReconcileManager(t)
+	writeDownloaded(t, manager, "e01.mkv", "e02.mkv.downloading")
+
+	if manager.reconcile(transfer) {
+		t.Fatal("expected a partly downloaded transfer to be downloaded again")
+	}
+	if manager.transfers.Len() != 0 || manager.pipelines.len() != 0 {
+		t.Error("expected nothing to be queued or claimed")
+	}
+}
+
+func TestReconcileAfterUnpackChecksExtractedFiles(t *testing.T) {
+	downloadDir := t.TempDir()
+	journalPath := filepath.Join(t.TempDir(), "transfer_stages.json")
+	putioClient := &mockPutioClient{
+		listFilesByID: map[int64]*putio.ListFileResponse{
+			100: {Parent: putio.FileResponse{ID: 100, Name: "Show.zip", FileType: "VIDEO"}},
+		},
+	}
+	fileID := int64(100)
+	hash := "hash123"
+	newManager := func() *Manager {
+		manager := setupTestManager()
+		manager.config.DownloadDirectory = downloadDir
+		manager.container.Transfers = app.NewTransferStore()
+		manager.container.Unpacker = unpack.New()
+		journal, err := app.NewStageJournal(journalPath)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		manager.container.Stages = journal
+		journal.Watch(manager.container.Transfers, manager.logger)
+		manager.putioClient = putioClient
+		return manager
+	}
+
+	// Download and unpack the archive like handleQueuedForDownload does.
+	manager := newManager()
+	transfer := &Transfer{Name: "Show", FileID: &fileID, Hash: &hash, TransferID: 1}
+	targets, err := manager.getDownloadTargets(transfer)
+	if err != nil || len(targets) != 1 {
+		t.Fatalf("expected the archive as the only target, got %+v (err %v)", targets, err)
+	}
+	f, err := os.Create(targets[0].To)
+	if err != nil {
+		t.Fatalf("failed to create zip: %v", err)
+	}
+	zw := zip.NewWriter(f)
+	w, _ := zw.Create("show.mkv")
+	w.Write([]byte("video"))
+	zw.Close()
+	f.Close()
+	manager.track(transfer)
+	targets = manager.unpackTargets(targets)
+	manager.recordTargets(transfer, targets)
+	manager.container.Transfers.SetStage(hash, app.StageWaitingForImport)
+
+	// After a restart, put.io still lists the archive, which was moved into
+	// the folder it was extracted to.
+	arrClient := &mockArrClient{imported: true}
+	manager = newManager()
+	manager.arrClients = []ArrServiceClient{{Name: "sonarr", Client: arrClient}}
+	transfer = &Transfer{Name: "Show", FileID: &fileID, Hash: &hash, TransferID: 1}
+	if !manager.reconcile(transfer) {
+		t.Fatal("expected the unpacked transfer to be resumed")
+	}
+	msg, ok := popTransfer(manager, time.Second)
+	if !ok || msg.Type != MessageImported {
+		t.Fatalf("expected the transfer to be found imported, got %+v", msg)
+	}
+	extracted := filepath.Join(downloadDir, "Show", "show.mkv")
+	if len(arrClient.checkedPaths) != 1 || arrClient.checkedPaths[0] != extracted {
+		t.Errorf("expected the import of %s to be checked, got %v", extracted, arrClient.checkedPaths)
+	}
+}
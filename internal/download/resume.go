@@ -0,0 +1,84 @@
+package download
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+)
+
+// partialPath returns where a file target is written while it's downloaded.
+func partialPath(target *DownloadTarget) string {
+	return target.To + ".downloading"
+}
+
+// openPartial opens the file a download is written to and returns the offset
+// to continue at: the size of what's already downloaded with resume set, or
+// 0 after emptying the file otherwise.
+func openPartial(path string, resume bool) (*os.File, int64, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0666)
+	if err != nil {
+		return nil, 0, err
+	}
+	var offset int64
+	if resume {
+		if offset, err = f.Seek(0, io.SeekEnd); err != nil {
+			f.Close()
+			return nil, 0, err
+		}
+		return f, offset, nil
+	}
+	if err := f.Truncate(0); err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+	return f, 0, nil
+}
+
+// restartPartial empties a partly downloaded file to download it from the
+// start.
+func restartPartial(f *os.File) error {
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	_, err := f.Seek(0, io.SeekStart)
+	return err
+}
+
+// resumesAt reports whether resp carries the rest of a file of the given size
+// from offset on, so it can be appended to what's downloaded. A size of 0 is
+// unknown and isn't checked.
+func resumesAt(resp *http.Response, offset, size int64) bool {
+	if resp.StatusCode != http.StatusPartialContent {
+		return false
+	}
+	var start, end int64
+	var total string
+	if _, err := fmt.Sscanf(resp.Header.Get("Content-Range"), "bytes %d-%d/%s", &start, &end, &total); err != nil {
+		return false
+	}
+	if start != offset {
+		return false
+	}
+	return size == 0 || total == "*" || total == fmt.Sprint(size)
+}
+
+// failFetch returns err, the reason a download failed. The data downloaded is
+// kept when the download was aborted and may be retried, and discarded
+// otherwise.
+func (m *Manager) failFetch(target *DownloadTarget, err error) error {
+	if !downloadAborted(err) {
+		m.discardPartial(target)
+	}
+	return err
+}
+
+// discardPartial removes what was downloaded of a file target and takes it
+// off the transfer's progress.
+func (m *Manager) discardPartial(target *DownloadTarget) {
+	path := partialPath(target)
+	if info, err := os.Stat(path); err == nil {
+		m.container.Transfers.AddProgress(target.TransferHash, -info.Size())
+	}
+	os.Remove(path)
+}
@@ -0,0 +1,103 @@
+package download
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ochronus/goputioarr/internal/config"
+)
+
+// flakyServer serves content, stalling after the first half of it on the first
+// request. With ranges set, later requests are answered like a file server
+// would, honoring Range; otherwise the whole content is sent each time.
+func flakyServer(t *testing.T, content []byte, ranges bool) (*httptest.Server, func() []string) {
+	var mu sync.Mutex
+	var seen []string
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen = append(seen, r.Header.Get("Range"))
+		first := len(seen) == 1
+		mu.Unlock()
+
+		if first {
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			w.Write(content[:len(content)/2])
+			w.(http.Flusher).Flush()
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+			return
+		}
+		if !ranges {
+			w.Write(content)
+			return
+		}
+		http.ServeContent(w, r, "file.txt", time.Time{}, bytes.NewReader(content))
+	}))
+	t.Cleanup(func() {
+		close(release)
+		server.Close()
+	})
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), seen...)
+	}
+}
+
+func TestRetriedDownloadResumes(t *testing.T) {
+	content := []byte("hello world")
+	for _, ranges := range []bool{true, false} {
+		server, requests := flakyServer(t, content, ranges)
+		manager := setupTestManager()
+		manager.config.DownloadStallTimeout = config.Duration(50 * time.Millisecond)
+		manager.config.DownloadRetries = 1
+		targetPath := filepath.Join(t.TempDir(), "file.txt")
+
+		status := manager.downloadTarget(&DownloadTarget{
+			From: server.URL, To: targetPath, TargetType: TargetTypeFile, Size: int64(len(content)),
+		})
+		if status != DownloadStatusSuccess {
+			t.Fatalf("ranges %v: expected the retry to succeed, got %v", ranges, status)
+		}
+		if got := requests(); len(got) != 2 || got[1] != "bytes=5-" {
+			t.Errorf("ranges %v: expected the retry to ask for the rest of the file, got %q", ranges, got)
+		}
+		if data, _ := os.ReadFile(targetPath); !bytes.Equal(data, content) {
+			t.Errorf("ranges %v: expected %q, got %q", ranges, content, data)
+		}
+	}
+}
+
+func TestResumesAt(t *testing.T) {
+	tests := []struct {
+		status       int
+		contentRange string
+		size         int64
+		want         bool
+	}{
+		{http.StatusPartialContent, "bytes 5-10/11", 11, true},
+		{http.StatusPartialContent, "bytes 5-10/*", 11, true},
+		{http.StatusPartialContent, "bytes 5-10/11", 0, true},
+		{http.StatusPartialContent, "bytes 0-10/11", 11, false},
+		{http.StatusPartialContent, "bytes 5-10/12", 11, false},
+		{http.StatusPartialContent, "", 11, false},
+		{http.StatusOK, "", 11, false},
+	}
+	for _, tt := range tests {
+		resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+		resp.Header.Set("Content-Range", tt.contentRange)
+		if got := resumesAt(resp, 5, tt.size); got != tt.want {
+			t.Errorf("resumesAt(%d %q, size %d) = %v, want %v", tt.status, tt.contentRange, tt.size, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package download
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ochronus/goputioarr/internal/app"
+	"github.com/ochronus/goputioarr/internal/config"
+	"github.com/ochronus/goputioarr/internal/services/notify"
+)
+
+// waitForSpace holds back a file download while the download directory doesn't
+// have room for the rest of the file on top of min_free_space, reporting its
+// transfer as queued until imports free up enough space. STRM files take no
+// space worth waiting for. It returns false if ctx is cancelled first.
+func (m *Manager) waitForSpace(ctx context.Context, target *DownloadTarget) bool {
+	limit := m.config.MinFreeSpace
+	if limit <= 0 || target.TargetType != TargetTypeFile || target.Stream {
+		return true
+	}
+
+	remaining := remainingBytes(target)
+	needed := uint64(limit) + uint64(remaining)
+	waiting := false
+	for {
+		free, err := m.freeSpace(m.config.DownloadDirectory)
+		if err != nil {
+			// Better to risk running out of space than to stop downloading.
+			m.targetLogger(target).Debugf("%s: can't check free space: %v", target, err)
+			return true
+		}
+		if free >= needed {
+			if waiting {
+				m.container.Transfers.SetStage(target.TransferHash, app.StageDownloading)
+				if m.lowSpace.CompareAndSwap(true, false) {
+					m.logger.Infof("%s free in the download directory, resuming downloads", config.Size(free))
+				}
+			}
+			return true
+		}
+
+		if !waiting {
+			waiting = true
+			message := fmt.Sprintf("waiting for free space: %s free, the file needs %s on top of min_free_space (%s)", config.Size(free), config.Size(remaining), limit)
+			m.container.Transfers.SetStage(target.TransferHash, app.StageQueued)
+			m.container.Transfers.SetError(target.TransferHash, message)
+			if m.lowSpace.CompareAndSwap(false, true) {
+				m.logger.Warnf("Pausing downloads: %s free in the download directory, not enough for %s on top of min_free_space (%s)", config.Size(free), config.Size(remaining), limit)
+				m.notify(notify.Event{
+					Type:    notify.EventLowDiskSpace,
+					Title:   "Downloads paused",
+					Message: fmt.Sprintf("Only %s free in the download directory, not enough for %s on top of min_free_space (%s). Downloads resume once imports free up space.", config.Size(free), config.Size(remaining), limit),
+				})
+			}
+		}
+
+		timer := time.NewTimer(jitter(m.config.PollingInterval.Duration()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return false
+		case <-timer.C:
+		}
+	}
+}
+
+// remainingBytes returns how much of target is left to download: its size,
+// less what a partial download already wrote.
+func remainingBytes(target *DownloadTarget) int64 {
+	remaining := target.Size
+	if info, err := os.Stat(partialPath(target)); err == nil {
+		remaining -= info.Size()
+	}
+	return max(remaining, 0)
+}
@@ -0,0 +1,153 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ochronus/goputioarr/internal/app"
+	"github.com/ochronus/goputioarr/internal/config"
+	"github.com/ochronus/goputioarr/internal/services/notify"
+)
+
+// fakeFreeSpace returns the given values in turn, repeating the last one.
+type fakeFreeSpace struct {
+	mu     sync.Mutex
+	values []uint64
+	calls  int
+}
+
+func (f *fakeFreeSpace) free(dir string) (uint64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	i := min(f.calls, len(f.values)-1)
+	f.calls++
+	return f.values[i], nil
+}
+
+func setupSpaceManager(minFree config.Size, space *fakeFreeSpace) (*Manager, *recordingNotifier) {
+	manager := setupTestManager()
+	manager.config.MinFreeSpace = minFree
+	manager.config.PollingInterval = config.Duration(time.Millisecond)
+	manager.container.Transfers = app.NewTransferStore()
+	notifier := &recordingNotifier{}
+	manager.container.Notifier = notifier
+	manager.freeSpace = space.free
+	return manager, notifier
+}
+
+func TestWaitForSpaceSkipsCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		minFree config.Size
+		target  DownloadTarget
+	}{
+		{name: "disabled", minFree: 0, target: DownloadTarget{TargetType: TargetTypeFile}},
+		{name: "directory", minFree: 100, target: DownloadTarget{TargetType: TargetTypeDirectory}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			space := &fakeFreeSpace{values: []uint64{0}}
+			manager, _ := setupSpaceManager(tt.minFree, space)
+			if !manager.waitForSpace(context.Background(), &tt.target) {
+				t.Fatal("expected the download to go ahead")
+			}
+			if space.calls != 0 {
+				t.Errorf("expected free space not to be checked, got %d calls", space.calls)
+			}
+		})
+	}
+}
+
+func TestWaitForSpacePausesUntilSpaceIsFreed(t *testing.T) {
+	space := &fakeFreeSpace{values: []uint64{50, 50, 200}}
+	manager, notifier := setupSpaceManager(100, space)
+	hash := "abcd"
+	manager.container.Transfers.Track(hash, "Show", 1000)
+	target := &DownloadTarget{TargetType: TargetTypeFile, TransferHash: hash}
+
+	if !manager.waitForSpace(context.Background(), target) {
+		t.Fatal("expected the download to go ahead once space is freed")
+	}
+	if space.calls != 3 {
+		t.Errorf("expected free space to be checked until it sufficed, got %d calls", space.calls)
+	}
+	state, _ := manager.container.Transfers.Get(hash)
+	if state.Stage != app.StageDownloading || state.Error != "" {
+		t.Errorf("expected the transfer to be downloading again, got %+v", state)
+	}
+	if manager.lowSpace.Load() {
+		t.Error("expected low space to be cleared")
+	}
+	if len(notifier.events) != 1 || notifier.events[0].Type != notify.EventLowDiskSpace {
+		t.Errorf("expected one low disk space notification, got %+v", notifier.events)
+	}
+}
+
+func TestWaitForSpaceReportsQueuedWhileWaiting(t *testing.T) {
+	space := &fakeFreeSpace{values: []uint64{50}}
+	manager, notifier := setupSpaceManager(100, space)
+	hash := "abcd"
+	manager.container.Transfers.Track(hash, "Show", 1000)
+	target := &DownloadTarget{TargetType: TargetTypeFile, TransferHash: hash}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if manager.waitForSpace(ctx, target) {
+		t.Fatal("expected waiting to end when ctx is cancelled")
+	}
+	state, _ := manager.container.Transfers.Get(hash)
+	if state.Stage != app.StageQueued || state.Error == "" {
+		t.Errorf("expected the transfer to be queued with an error, got %+v", state)
+	}
+
+	// A second target doesn't notify again while space is still low.
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	manager.waitForSpace(ctx, target)
+	if len(notifier.events) != 1 {
+		t.Errorf("expected a single notification, got %d", len(notifier.events))
+	}
+}
+
+func TestWaitForSpaceCountsRemainingBytes(t *testing.T) {
+	space := &fakeFreeSpace{values: []uint64{500, 500, 1000}}
+	manager, _ := setupSpaceManager(100, space)
+	dir := t.TempDir()
+	target := &DownloadTarget{TargetType: TargetTypeFile, To: filepath.Join(dir, "file.mkv"), Size: 1000}
+	if err := os.WriteFile(partialPath(target), make([]byte, 200), 0644); err != nil {
+		t.Fatalf("failed to write partial download: %v", err)
+	}
+
+	// 800 bytes are left to download, so 900 must be free.
+	if !manager.waitForSpace(context.Background(), target) {
+		t.Fatal("expected the download to go ahead once there's room for it")
+	}
+	if space.calls != 3 {
+		t.Errorf("expected to wait until the rest of the file fits, got %d calls", space.calls)
+	}
+}
+
+func TestWaitForSpaceIgnoresCheckErrors(t *testing.T) {
+	manager, _ := setupSpaceManager(100, &fakeFreeSpace{values: []uint64{0}})
+	manager.freeSpace = func(string) (uint64, error) { return 0, errors.New("unsupported") }
+
+	if !manager.waitForSpace(context.Background(), &DownloadTarget{TargetType: TargetTypeFile}) {
+		t.Fatal("expected the download to go ahead when free space can't be checked")
+	}
+}
+
+func TestFreeSpace(t *testing.T) {
+	free, err := freeSpace(t.TempDir())
+	if err != nil {
+		t.Skipf("free space can't be checked here: %v", err)
+	}
+	if free == 0 {
+		t.Error("expected some free space in the temp directory")
+	}
+}
@@ -0,0 +1,58 @@
+package download
+
+import (
+	"time"
+
+	"github.com/ochronus/goputioarr/internal/config"
+	"github.com/ochronus/goputioarr/internal/services/putio"
+)
+
+// checkStalls marks the transfers put.io has been queueing, preparing or
+// downloading without progress for putio.stall_timeout as stalled, which
+// torrent-get reports as their error, and applies putio.stall_action to the
+// ones the manager handles.
+func (m *Manager) checkStalls(transfers []putio.Transfer, now time.Time) {
+	timeout := m.config.Putio.StallTimeout.Duration()
+	if timeout <= 0 {
+		return
+	}
+
+	for _, pt := range m.container.Stalls.Observe(transfers, now, timeout) {
+		transfer := NewTransfer(m.config, &pt)
+		log := m.transferLogger(transfer)
+		log.Warnf("%s: stalled, no progress on put.io for %s", transfer, timeout)
+		if !m.isRelevant(&pt) {
+			continue
+		}
+
+		switch m.config.Putio.StallAction {
+		case config.StallActionRetry:
+			if err := m.putioClient.RetryTransfer(pt.ID); err != nil {
+				log.Warnf("%s: failed to retry stalled transfer: %v", transfer, err)
+				continue
+			}
+			// Give the retried transfer the full timeout again.
+			m.container.Stalls.Forget(pt.ID)
+			log.Infof("%s: retried on put.io", transfer)
+		case config.StallActionRemove:
+			if err := m.putioClient.RemoveTransfer(pt.ID); err != nil {
+				log.Warnf("%s: failed to remove stalled transfer: %v", transfer, err)
+				continue
+			}
+			m.container.Stalls.Forget(pt.ID)
+			if _, err := m.container.Holds.Remove(transfer.GetHash()); err != nil {
+				log.Warnf("%s: failed to update held transfers: %v", transfer, err)
+			}
+			if err := m.container.Locations.Forget(transfer.GetHash()); err != nil {
+				log.Warnf("%s: failed to update transfer locations: %v", transfer, err)
+			}
+			if err := m.container.Labels.Forget(transfer.GetHash()); err != nil {
+				log.Warnf("%s: failed to update transfer labels: %v", transfer, err)
+			}
+			if _, err := m.container.Ownership.Remove(transfer.GetHash()); err != nil {
+				log.Warnf("%s: failed to update transfer ownership: %v", transfer, err)
+			}
+			log.Infof("%s: removed from put.io so the arrs can grab another release", transfer)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package download
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ochronus/goputioarr/internal/app"
+	"github.com/ochronus/goputioarr/internal/config"
+	"github.com/ochronus/goputioarr/internal/services/putio"
+)
+
+func TestCheckStalls(t *testing.T) {
+	tests := []struct {
+		name    string
+		action  string
+		owned   bool
+		retried []uint64
+		removed []uint64
+		stalled bool
+	}{
+		{name: "report", action: config.StallActionReport, owned: true, stalled: true},
+		{name: "retry", action: config.StallActionRetry, owned: true, retried: []uint64{7}},
+		{name: "remove", action: config.StallActionRemove, owned: true, removed: []uint64{7}},
+		{name: "foreign transfer is only reported", action: config.StallActionRemove, stalled: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := setupTestManager()
+			manager.config.Putio.StallTimeout = config.Duration(time.Hour)
+			manager.config.Putio.StallAction = tt.action
+			manager.container.Stalls = app.NewStallTracker()
+			ownership, err := app.NewOwnershipRegistry("")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			manager.container.Ownership = ownership
+			hash := "abcdef"
+			if tt.owned {
+				if err := ownership.Add(hash); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+			client := &mockPutioClient{}
+			manager.putioClient = client
+
+			downloaded := int64(10)
+			transfers := []putio.Transfer{{ID: 7, Hash: &hash, Status: "DOWNLOADING", Downloaded: &downloaded}}
+			start := time.Now()
+			manager.checkStalls(transfers, start)
+			manager.checkStalls(transfers, start.Add(time.Hour))
+
+			if len(client.retried) != len(tt.retried) || len(client.removed) != len(tt.removed) {
+				t.Errorf("expected retried %v and removed %v, got %v and %v", tt.retried, tt.removed, client.retried, client.removed)
+			}
+			if _, stalled := manager.container.Stalls.Stalled(7); stalled != tt.stalled {
+				t.Errorf("expected stalled=%v", tt.stalled)
+			}
+			if tt.removed != nil && ownership.Has(hash) {
+				t.Error("expected the removed transfer to be forgotten")
+			}
+		})
+	}
+}
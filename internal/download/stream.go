@@ -0,0 +1,44 @@
+package download
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// streamTarget turns a file target into one for an STRM file next to where the
+// file would have been downloaded. The arrs import STRM files like videos, and
+// media servers play the URL inside instead.
+func streamTarget(target *DownloadTarget) {
+	target.To = strmPath(target.To)
+	target.Stream = true
+}
+
+func strmPath(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".strm"
+}
+
+// writeStreamFile writes the STRM file of a stream target, pointing at the
+// proxy's stream endpoint for the put.io file, which redirects to a fresh
+// put.io URL on every play.
+func (m *Manager) writeStreamFile(target *DownloadTarget) error {
+	if target.FileID == 0 {
+		return fmt.Errorf("no put.io file for stream target")
+	}
+	if err := os.MkdirAll(filepath.Dir(target.To), 0777); err != nil {
+		return localError(err)
+	}
+
+	tmpPath := target.To + ".downloading"
+	content := m.container.StreamURL(target.FileID) + "\n"
+	// Media servers read the file too, so permissions come from the umask like
+	// those of downloaded files.
+	if err := os.WriteFile(tmpPath, []byte(content), 0666); err != nil {
+		return localError(err)
+	}
+	if err := chownToUser(tmpPath, m.config.UID, m.config.GID); err != nil {
+		m.targetLogger(target).Warnf("%s: failed to change ownership: %v", target, err)
+	}
+	return localError(os.Rename(tmpPath, target.To))
+}
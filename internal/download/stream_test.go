@@ -0,0 +1,87 @@
+package download
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ochronus/goputioarr/internal/config"
+	"github.com/ochronus/goputioarr/internal/services/putio"
+)
+
+func TestRecurseDownloadTargetsStreaming(t *testing.T) {
+	manager := setupTestManager()
+	manager.config.DownloadMode = config.DownloadModeStrm
+	manager.putioClient = &mockPutioClient{
+		listFilesByID: map[int64]*putio.ListFileResponse{
+			100: {
+				Parent: putio.FileResponse{ID: 100, Name: "Show S01", FileType: "FOLDER"},
+				Files:  []putio.FileResponse{{ID: 200}},
+			},
+			200: {
+				Parent: putio.FileResponse{ID: 200, Name: "Show.S01E01.mkv", FileType: "VIDEO"},
+			},
+		},
+	}
+
+	targets, err := manager.recurseDownloadTargets(100, "hash123", "", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(targets) != 2 {
+		t.Fatalf("expected 2 targets, got %+v", targets)
+	}
+	if targets[0].Stream {
+		t.Errorf("expected the directory target to be created as usual, got %+v", targets[0])
+	}
+	file := targets[1]
+	if !file.Stream || file.FileID != 200 || file.To != filepath.Join("/downloads", "Show S01", "Show.S01E01.strm") {
+		t.Errorf("unexpected stream target %+v", file)
+	}
+}
+
+func TestDownloadTargetWritesStreamFile(t *testing.T) {
+	manager := setupTestManager()
+	manager.config.StreamURL = "http://goputioarr:9091/"
+	target := &DownloadTarget{
+		FileID:     42,
+		To:         filepath.Join(t.TempDir(), "show", "Show.S01E01.strm"),
+		TargetType: TargetTypeFile,
+		Stream:     true,
+	}
+
+	if status := manager.downloadTarget(target); status != DownloadStatusSuccess {
+		t.Fatalf("expected success, got %v", status)
+	}
+	data, err := os.ReadFile(target.To)
+	if err != nil {
+		t.Fatalf("expected the stream file to be written: %v", err)
+	}
+	got := strings.TrimSpace(string(data))
+	if got != manager.container.StreamURL(42) || !strings.HasPrefix(got, "http://goputioarr:9091/stream/42?sig=") {
+		t.Errorf("unexpected stream file content %q", got)
+	}
+	if strings.Contains(got, "test-api-key") {
+		t.Errorf("expected the put.io API key to stay out of the stream file, got %q", got)
+	}
+	if _, err := os.Stat(target.To + ".downloading"); !os.IsNotExist(err) {
+		t.Error("expected no temporary file to be left behind")
+	}
+}
+
+func TestStrmPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/downloads/movie.mkv", want: "/downloads/movie.strm"},
+		{path: "/downloads/Show.S01E01.1080p.mp4", want: "/downloads/Show.S01E01.1080p.strm"},
+		{path: "/downloads/noext", want: "/downloads/noext.strm"},
+	}
+	for _, tt := range tests {
+		if got := strmPath(tt.path); got != tt.want {
+			t.Errorf("strmPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
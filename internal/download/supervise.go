@@ -0,0 +1,51 @@
+package download
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"time"
+)
+
+// workerRestartDelay is how long a crashed worker waits before it's started
+// again, so a panic on every message doesn't spin.
+const workerRestartDelay = time.Second
+
+// supervise runs worker until it returns or ctx is done, starting it again
+// after restartDelay whenever it panics, so a bug hit by one transfer doesn't
+// shrink the worker pool or crash the proxy.
+func (m *Manager) supervise(ctx context.Context, name string, worker func()) {
+	for m.recovered(name, worker) {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(m.restartDelay):
+		}
+		m.logger.Infof("Restarting %s", name)
+	}
+}
+
+// recovered runs fn and reports whether it panicked. The panic is logged with
+// its stack trace and counted as a worker crash.
+func (m *Manager) recovered(name string, fn func()) (panicked bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			panicked = true
+			m.container.Downloads.WorkerCrashed()
+			m.logger.Errorf("%s crashed: %v\n%s", name, r, debug.Stack())
+		}
+	}()
+	fn()
+	return false
+}
+
+// recoverTo turns a panic into *err, logging it with its stack trace and
+// counting it as a worker crash. It's deferred by goroutines whose caller
+// already handles their failure as an error.
+func (m *Manager) recoverTo(name string, err *error) {
+	if r := recover(); r != nil {
+		m.container.Downloads.WorkerCrashed()
+		m.logger.Errorf("%s crashed: %v\n%s", name, r, debug.Stack())
+		*err = fmt.Errorf("%s crashed: %v", name, r)
+	}
+}
@@ -0,0 +1,136 @@
+package download
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ochronus/goputioarr/internal/app"
+	"github.com/ochronus/goputioarr/internal/services/putio"
+)
+
+// panickingPutioClient panics when asked for a download URL.
+type panickingPutioClient struct {
+	mockPutioClient
+}
+
+func (p *panickingPutioClient) GetFileURL(fileID int64) (string, error) {
+	panic("boom")
+}
+
+// panickingListClient panics when asked to list the files of panicID.
+type panickingListClient struct {
+	mockPutioClient
+	panicID int64
+}
+
+func (p *panickingListClient) ListFiles(fileID int64) (*putio.ListFileResponse, error) {
+	if fileID == p.panicID {
+		panic("boom")
+	}
+	return p.mockPutioClient.ListFiles(fileID)
+}
+
+func TestSuperviseRestartsCrashedWorker(t *testing.T) {
+	manager := setupTestManager()
+	manager.container.Downloads = app.NewDownloadCounters()
+	manager.restartDelay = 0
+
+	runs := 0
+	manager.supervise(context.Background(), "test worker", func() {
+		runs++
+		if runs == 1 {
+			panic("boom")
+		}
+	})
+
+	if runs != 2 {
+		t.Errorf("expected the worker to be restarted once, ran %d times", runs)
+	}
+	if got := manager.container.Downloads.Snapshot().WorkerCrashes; got != 1 {
+		t.Errorf("expected 1 worker crash, got %d", got)
+	}
+}
+
+func TestSuperviseStopsWhenContextIsDone(t *testing.T) {
+	manager := setupTestManager()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	runs := 0
+	manager.supervise(ctx, "test worker", func() {
+		runs++
+		panic("boom")
+	})
+	if runs != 1 {
+		t.Errorf("expected no restart after the context is done, ran %d times", runs)
+	}
+}
+
+func TestRunTargetReportsPanicAsFailed(t *testing.T) {
+	manager := setupTestManager()
+	manager.putioClient = &panickingPutioClient{}
+	done := make(chan DownloadDoneStatus, 1)
+	msg := DownloadTargetMessage{
+		Target: DownloadTarget{
+			To:         filepath.Join(t.TempDir(), "episode.mkv"),
+			TargetType: TargetTypeFile,
+			FileID:     1,
+		},
+		DoneChan: done,
+	}
+
+	if !manager.recovered("download worker", func() { manager.runTarget(msg) }) {
+		t.Fatal("expected the download to panic")
+	}
+	if status := <-done; status != DownloadStatusFailed {
+		t.Errorf("expected the crashed download to be reported as failed, got %v", status)
+	}
+	if manager.stats.active.Load() != 0 {
+		t.Errorf("expected no active downloads left, got %d", manager.stats.active.Load())
+	}
+}
+
+func TestWalkDownloadTargetsReportsPanicAsError(t *testing.T) {
+	manager := setupTestManager()
+	manager.container.Downloads = app.NewDownloadCounters()
+	manager.putioClient = &panickingListClient{
+		mockPutioClient: mockPutioClient{listFilesByID: map[int64]*putio.ListFileResponse{
+			100: {
+				Parent: putio.FileResponse{ID: 100, Name: "root", FileType: "FOLDER"},
+				Files:  []putio.FileResponse{{ID: 200}},
+			},
+		}},
+		panicID: 200,
+	}
+
+	_, err := manager.recurseDownloadTargets(100, "hash123", "/downloads", true)
+	if err == nil || !strings.Contains(err.Error(), "crashed") {
+		t.Errorf("expected the panicking listing to fail the walk, got %v", err)
+	}
+	if got := manager.container.Downloads.Snapshot().WorkerCrashes; got != 1 {
+		t.Errorf("expected 1 worker crash, got %d", got)
+	}
+}
+
+func TestRunQueuedForDownloadForgetsPanickedTransfer(t *testing.T) {
+	manager := setupTestManager()
+	manager.container.Downloads = app.NewDownloadCounters()
+	manager.putioClient = &panickingListClient{panicID: 100}
+	fileID := int64(100)
+	hash := "hash123"
+	transfer := &Transfer{Name: "Show", TransferID: 7, FileID: &fileID, Hash: &hash}
+	manager.markSeen(7)
+
+	manager.runQueuedForDownload(transfer, func() {})
+	if got := manager.container.Downloads.Snapshot().WorkerCrashes; got != 1 {
+		t.Fatalf("expected the download to crash, got %d crashes", got)
+	}
+	if manager.isSeen(7) {
+		t.Error("expected the panicked transfer to be picked up again by the next poll")
+	}
+	if manager.inFlight.Load() != 0 {
+		t.Errorf("expected nothing in flight, got %d", manager.inFlight.Load())
+	}
+}
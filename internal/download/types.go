@@ -1,9 +1,11 @@
 package download
 
 import (
+	"crypto/sha1"
+	"encoding/hex"
 	"fmt"
-	"strings"
 	"sync"
+	"time"
 
 	"github.com/ochronus/goputioarr/internal/config"
 	"github.com/ochronus/goputioarr/internal/services/putio"
@@ -19,11 +21,22 @@ const (
 
 // DownloadTarget represents a file or directory to be downloaded
 type DownloadTarget struct {
+	// From is the URL to download a file from. Files on put.io have a FileID
+	// instead, since their download URLs expire.
 	From         string     `json:"from,omitempty"`
+	FileID       int64      `json:"file_id,omitempty"`
 	To           string     `json:"to"`
 	TargetType   TargetType `json:"target_type"`
 	TopLevel     bool       `json:"top_level"`
 	TransferHash string     `json:"transfer_hash"`
+	// TransferID is the put.io ID of the transfer, which correlates log
+	// lines about the target with the rest of the transfer's.
+	TransferID uint64 `json:"transfer_id,omitempty"`
+	// Stream is set for file targets written as STRM files pointing at
+	// put.io instead of being downloaded, in download_mode "strm".
+	Stream bool `json:"stream,omitempty"`
+	// Size is the size of a file target on put.io, in bytes.
+	Size int64 `json:"size,omitempty"`
 }
 
 // String returns a formatted string representation of the download target
@@ -40,10 +53,25 @@ type Transfer struct {
 	Name       string
 	FileID     *int64
 	Hash       *string
+	Size       int64
 	TransferID uint64
+	Created    time.Time
 	Targets    []DownloadTarget
 	Config     *config.Config
-	mu         sync.RWMutex
+	// FolderImport is set for items of putio.import_folder_id, which have no
+	// put.io transfer; TransferID is 0.
+	FolderImport bool
+	stalled      bool
+	importedBy   string
+	timestamps   Timestamps
+	mu           sync.RWMutex
+}
+
+// Timestamps records when a transfer reached each processing stage
+type Timestamps struct {
+	Started    time.Time
+	Downloaded time.Time
+	Imported   time.Time
 }
 
 // NewTransfer creates a new Transfer from a put.io transfer
@@ -53,16 +81,50 @@ func NewTransfer(cfg *config.Config, pt *putio.Transfer) *Transfer {
 		name = *pt.Name
 	}
 
+	var size int64
+	if pt.Size != nil {
+		size = *pt.Size
+	}
+
+	created, _ := pt.Created()
+
 	return &Transfer{
 		TransferID: pt.ID,
+		Created:    created,
 		Name:       name,
 		FileID:     pt.FileID,
 		Hash:       pt.Hash,
+		Size:       size,
 		Targets:    nil,
 		Config:     cfg,
 	}
 }
 
+// NewFolderTransfer creates a Transfer for an item of the import folder.
+func NewFolderTransfer(cfg *config.Config, f *putio.FileResponse) *Transfer {
+	fileID := f.ID
+	hash := FolderImportHash(f.ID)
+	created, _ := f.Created()
+
+	return &Transfer{
+		Created:      created,
+		Name:         f.Name,
+		FileID:       &fileID,
+		Hash:         &hash,
+		Size:         f.Size,
+		Config:       cfg,
+		FolderImport: true,
+	}
+}
+
+// FolderImportHash returns the hash an item of the import folder is reported
+// under. put.io files outside transfers have no info hash, so one is derived
+// from the file ID; it stays the same across restarts.
+func FolderImportHash(fileID int64) string {
+	sum := sha1.Sum([]byte(fmt.Sprintf("putio-file-%d", fileID)))
+	return hex.EncodeToString(sum[:])
+}
+
 // String returns a formatted string representation of the transfer
 func (t *Transfer) String() string {
 	hash := "0000"
@@ -94,6 +156,79 @@ func (t *Transfer) GetTargets() []DownloadTarget {
 	return t.Targets
 }
 
+// SetStalled flags the transfer as stalled (not imported within import_timeout)
+func (t *Transfer) SetStalled(stalled bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stalled = stalled
+}
+
+// IsStalled reports whether the transfer has been flagged as stalled
+func (t *Transfer) IsStalled() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.stalled
+}
+
+// SetImportedBy records the name of the arr service that imported the transfer
+func (t *Transfer) SetImportedBy(name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.importedBy = name
+}
+
+// GetImportedBy returns the name of the arr service that imported the transfer,
+// or an empty string if unknown
+func (t *Transfer) GetImportedBy() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.importedBy
+}
+
+// MarkStarted records the start of the local download
+func (t *Transfer) MarkStarted() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.timestamps.Started = time.Now()
+}
+
+// MarkDownloaded records the completion of the local download
+func (t *Transfer) MarkDownloaded() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.timestamps.Downloaded = time.Now()
+}
+
+// MarkImported records when the transfer was found imported
+func (t *Transfer) MarkImported() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.timestamps.Imported = time.Now()
+}
+
+// GetTimestamps returns the recorded stage timestamps
+func (t *Transfer) GetTimestamps() Timestamps {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.timestamps
+}
+
+// GrabToImport returns how long the transfer took from being added to put.io
+// to being imported, measured from the start of its download if put.io didn't
+// report when it was added. It's zero if the transfer wasn't imported.
+func (t *Transfer) GrabToImport() time.Duration {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	from := t.Created
+	if from.IsZero() {
+		from = t.timestamps.Started
+	}
+	if from.IsZero() || t.timestamps.Imported.Before(from) {
+		return 0
+	}
+	return t.timestamps.Imported.Sub(from)
+}
+
 // GetTopLevel returns the top-level download target
 func (t *Transfer) GetTopLevel() *DownloadTarget {
 	t.mu.RLock()
@@ -165,13 +300,10 @@ const (
 	DownloadStatusFailed
 )
 
-// ShouldSkipDirectory checks if a directory should be skipped based on configuration
+// ShouldSkipDirectory checks if a directory should be skipped based on
+// configuration. skipDirs entries may be names, globs or regular expressions,
+// see config.NamePatterns; invalid entries are ignored.
 func ShouldSkipDirectory(name string, skipDirs []string) bool {
-	lowerName := strings.ToLower(name)
-	for _, skipDir := range skipDirs {
-		if strings.ToLower(skipDir) == lowerName {
-			return true
-		}
-	}
-	return false
+	patterns, _ := config.ParseNamePatterns(skipDirs)
+	return patterns.Match(name)
 }
@@ -2,6 +2,7 @@ package download
 
 import (
 	"testing"
+	"time"
 
 	"github.com/ochronus/goputioarr/internal/config"
 	"github.com/ochronus/goputioarr/internal/services/putio"
@@ -475,3 +476,32 @@ func TestDownloadTargetFields(t *testing.T) {
 func ptrString(v string) *string {
 	return &v
 }
+
+func TestTransferGrabToImport(t *testing.T) {
+	created := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	started := created.Add(30 * time.Minute)
+	imported := created.Add(time.Hour)
+
+	tests := []struct {
+		name     string
+		created  time.Time
+		started  time.Time
+		imported time.Time
+		expected time.Duration
+	}{
+		{name: "from put.io creation", created: created, started: started, imported: imported, expected: time.Hour},
+		{name: "from download start", started: started, imported: imported, expected: 30 * time.Minute},
+		{name: "not imported", created: created, started: started, expected: 0},
+		{name: "unknown start", imported: imported, expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transfer := &Transfer{Created: tt.created}
+			transfer.timestamps = Timestamps{Started: tt.started, Imported: tt.imported}
+			if got := transfer.GrabToImport(); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
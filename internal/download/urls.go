@@ -0,0 +1,129 @@
+package download
+
+import (
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// fileURLTTL is how long a put.io download URL is reused. It's kept well
+	// below the lifetime of put.io's links, which they don't always state.
+	fileURLTTL = 5 * time.Minute
+	// fileURLMargin is how long before its stated expiry a download URL is
+	// resolved again, so a download doesn't start on a URL about to lapse.
+	fileURLMargin = time.Minute
+	// maxFileURLs caps how many download URLs are cached at once.
+	maxFileURLs = 1000
+)
+
+// fileURLExpiryParams are the query parameters put.io's download URLs carry
+// their expiry in, as a Unix timestamp.
+var fileURLExpiryParams = []string{"expires", "Expires", "e"}
+
+// fileURLCache keeps put.io download URLs until they expire, so retried
+// downloads don't resolve them again. Safe for concurrent use.
+type fileURLCache struct {
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[int64]cachedURL
+}
+
+type cachedURL struct {
+	url     string
+	expires time.Time
+}
+
+func newFileURLCache() *fileURLCache {
+	return &fileURLCache{now: time.Now, entries: make(map[int64]cachedURL)}
+}
+
+// get returns the cached URL of fileID, if it's still good to use.
+func (c *fileURLCache) get(fileID int64) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[fileID]
+	if !ok {
+		return "", false
+	}
+	if !c.now().Before(entry.expires) {
+		delete(c.entries, fileID)
+		return "", false
+	}
+	return entry.url, true
+}
+
+// put caches the URL of fileID for fileURLTTL, or until shortly before it
+// expires if that's sooner. Expired URLs are swept out, and once the cache is
+// full the URL closest to expiring makes room.
+func (c *fileURLCache) put(fileID int64, rawURL string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	expires := now.Add(fileURLTTL)
+	if stated := urlExpiry(rawURL, now).Add(-fileURLMargin); stated.Before(expires) {
+		expires = stated
+	}
+	if !now.Before(expires) {
+		return
+	}
+	c.sweepLocked(now)
+	if _, ok := c.entries[fileID]; !ok && len(c.entries) >= maxFileURLs {
+		c.evictLocked()
+	}
+	c.entries[fileID] = cachedURL{url: rawURL, expires: expires}
+}
+
+// sweepLocked drops the URLs that have expired.
+func (c *fileURLCache) sweepLocked(now time.Time) {
+	for fileID, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, fileID)
+		}
+	}
+}
+
+// evictLocked drops the URL closest to expiring.
+func (c *fileURLCache) evictLocked() {
+	var oldest int64
+	var oldestExpires time.Time
+	first := true
+	for fileID, entry := range c.entries {
+		if first || entry.expires.Before(oldestExpires) {
+			oldest, oldestExpires, first = fileID, entry.expires, false
+		}
+	}
+	if !first {
+		delete(c.entries, oldest)
+	}
+}
+
+// forget drops the cached URL of fileID, e.g. once put.io rejected it.
+func (c *fileURLCache) forget(fileID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, fileID)
+}
+
+// urlExpiry returns when rawURL expires according to its query string, or
+// fileURLTTL from now if it doesn't say.
+func urlExpiry(rawURL string, now time.Time) time.Time {
+	fallback := now.Add(fileURLTTL)
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return fallback
+	}
+	query := parsed.Query()
+	for _, param := range fileURLExpiryParams {
+		value := query.Get(param)
+		if value == "" {
+			continue
+		}
+		if unix, err := strconv.ParseInt(value, 10, 64); err == nil && unix > 0 {
+			return time.Unix(unix, 0)
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,126 @@
+package download
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestURLExpiry(t *testing.T) {
+	now := time.Unix(1000, 0)
+	tests := []struct {
+		name string
+		url  string
+		want time.Time
+	}{
+		{name: "expires", url: "https://s1.put.io/download/1?expires=5000&sig=x", want: time.Unix(5000, 0)},
+		{name: "short param", url: "https://s1.put.io/download/1?e=6000", want: time.Unix(6000, 0)},
+		{name: "no expiry", url: "https://s1.put.io/download/1?sig=x", want: now.Add(fileURLTTL)},
+		{name: "invalid expiry", url: "https://s1.put.io/download/1?expires=soon", want: now.Add(fileURLTTL)},
+		{name: "invalid URL", url: "://", want: now.Add(fileURLTTL)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := urlExpiry(tt.url, now); !got.Equal(tt.want) {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFileURLCache(t *testing.T) {
+	now := time.Unix(1000, 0)
+	cache := newFileURLCache()
+	cache.now = func() time.Time { return now }
+
+	cache.put(1, "https://s1.put.io/download/1")
+	cache.put(2, fmt.Sprintf("https://s1.put.io/download/2?expires=%d", now.Add(30*time.Second).Unix()))
+	if url, ok := cache.get(1); !ok || url != "https://s1.put.io/download/1" {
+		t.Fatalf("expected the cached URL, got %q", url)
+	}
+	if _, ok := cache.get(2); ok {
+		t.Error("expected a URL about to expire not to be cached")
+	}
+
+	cache.forget(1)
+	if _, ok := cache.get(1); ok {
+		t.Error("expected the forgotten URL to be gone")
+	}
+
+	cache.put(1, "https://s1.put.io/download/1")
+	now = now.Add(fileURLTTL - fileURLMargin)
+	if _, ok := cache.get(1); ok {
+		t.Error("expected the URL to expire")
+	}
+}
+
+func TestFileURLCacheUsesFixedTTL(t *testing.T) {
+	now := time.Unix(1000, 0)
+	cache := newFileURLCache()
+	cache.now = func() time.Time { return now }
+
+	cache.put(1, fmt.Sprintf("https://s1.put.io/download/1?expires=%d", now.Add(24*time.Hour).Unix()))
+	now = now.Add(fileURLTTL)
+	if _, ok := cache.get(1); ok {
+		t.Error("expected a long-lived URL to be dropped after fileURLTTL")
+	}
+}
+
+func TestFileURLCacheSweepsAndCaps(t *testing.T) {
+	now := time.Unix(1000, 0)
+	cache := newFileURLCache()
+	cache.now = func() time.Time { return now }
+
+	cache.put(1, "https://s1.put.io/download/1")
+	now = now.Add(fileURLTTL)
+	cache.put(2, "https://s1.put.io/download/2")
+	if _, ok := cache.entries[1]; ok {
+		t.Error("expected the expired URL to be swept out")
+	}
+
+	for id := int64(3); len(cache.entries) < maxFileURLs; id++ {
+		now = now.Add(time.Millisecond)
+		cache.put(id, "https://s1.put.io/download/x")
+	}
+	cache.put(-1, "https://s1.put.io/download/new")
+	if len(cache.entries) != maxFileURLs {
+		t.Errorf("expected the cache to stay at %d URLs, got %d", maxFileURLs, len(cache.entries))
+	}
+	if _, ok := cache.entries[2]; ok {
+		t.Error("expected the URL closest to expiring to be evicted")
+	}
+	if _, ok := cache.get(-1); !ok {
+		t.Error("expected the new URL to be cached")
+	}
+}
+
+func TestDownloadTargetReusesResolvedURL(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte("test file content"))
+	}))
+	defer server.Close()
+
+	manager := setupTestManager()
+	client := &rotatingURLClient{urls: []string{server.URL + "/file"}}
+	manager.putioClient = client
+	target := &DownloadTarget{FileID: 42, To: filepath.Join(t.TempDir(), "file.txt"), TargetType: TargetTypeFile}
+
+	if status := manager.downloadTarget(target); status != DownloadStatusFailed {
+		t.Fatalf("expected the first attempt to fail, got %v", status)
+	}
+	if status := manager.downloadTarget(target); status != DownloadStatusSuccess {
+		t.Fatalf("expected the second attempt to succeed, got %v", status)
+	}
+	if client.resolved != 1 {
+		t.Errorf("expected the URL to be resolved once, got %d", client.resolved)
+	}
+}
@@ -0,0 +1,46 @@
+// Package fileutil writes the proxy's state files, so a crash or a full disk
+// never leaves one half written.
+package fileutil
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// WriteAtomic replaces path with data. The data is written and synced to a
+// temporary file next to path, which is then renamed over it. Missing parent
+// directories are created.
+func WriteAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// WriteJSONAtomic replaces path with v encoded as indented JSON, like
+// WriteAtomic.
+func WriteJSONAtomic(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return WriteAtomic(path, data)
+}
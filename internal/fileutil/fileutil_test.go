@@ -0,0 +1,35 @@
+package fileutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteJSONAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state", "labels.json")
+
+	if err := WriteJSONAtomic(path, map[string]string{"abcd": "tv"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := WriteJSONAtomic(path, map[string]string{"abcd": "movies"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	if want := "{\n  \"abcd\": \"movies\"\n}"; string(data) != want {
+		t.Errorf("expected %q, got %q", want, data)
+	}
+
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("expected no temporary files left behind, got %v", entries)
+	}
+
+	if err := WriteJSONAtomic(path, func() {}); err == nil {
+		t.Error("expected an error for a value JSON can't encode")
+	}
+}
@@ -0,0 +1,78 @@
+// Package hint attaches short, user-facing hints to errors, saying what to
+// check or change to fix them. Hints are shown next to the error in logs, in
+// the CLI's output and in the failure messages reported through the API.
+package hint
+
+import (
+	"fmt"
+)
+
+// Error is an error carrying a hint.
+type Error struct {
+	Err  error
+	Hint string
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Hinter is implemented by errors that know their own hint, such as the
+// HTTP errors of the put.io and arr clients. An empty hint means none.
+type Hinter interface {
+	Hint() string
+}
+
+// Wrap attaches hint to err. It returns nil if err is nil.
+func Wrap(err error, hint string) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Err: err, Hint: hint}
+}
+
+// Errorf is fmt.Errorf returning an error carrying hint.
+func Errorf(hint, format string, args ...interface{}) error {
+	return &Error{Err: fmt.Errorf(format, args...), Hint: hint}
+}
+
+// Of returns the hint of the first error in err's tree that has one, or "" if
+// none does. Like errors.As, it looks through wrapped errors depth-first,
+// including every error joined with errors.Join.
+func Of(err error) string {
+	if err == nil {
+		return ""
+	}
+	if h, ok := err.(*Error); ok && h.Hint != "" {
+		return h.Hint
+	}
+	if h, ok := err.(Hinter); ok && h.Hint() != "" {
+		return h.Hint()
+	}
+	switch wrapped := err.(type) {
+	case interface{ Unwrap() error }:
+		return Of(wrapped.Unwrap())
+	case interface{ Unwrap() []error }:
+		for _, e := range wrapped.Unwrap() {
+			if h := Of(e); h != "" {
+				return h
+			}
+		}
+	}
+	return ""
+}
+
+// Format returns err's message followed by its hint, if it has one.
+func Format(err error) string {
+	if err == nil {
+		return ""
+	}
+	if h := Of(err); h != "" {
+		return fmt.Sprintf("%v (hint: %s)", err, h)
+	}
+	return err.Error()
+}
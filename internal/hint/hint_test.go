@@ -0,0 +1,62 @@
+package hint
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+type statusError struct{ hint string }
+
+func (e *statusError) Error() string { return "status error" }
+func (e *statusError) Hint() string  { return e.hint }
+
+func TestWrapAndOf(t *testing.T) {
+	if Wrap(nil, "ignored") != nil {
+		t.Error("expected wrapping nil to return nil")
+	}
+
+	base := errors.New("boom")
+	err := fmt.Errorf("outer: %w", Wrap(base, "check the thing"))
+	if got := Of(err); got != "check the thing" {
+		t.Errorf("Of = %q, want the wrapped hint", got)
+	}
+	if !errors.Is(err, base) {
+		t.Error("expected the wrapped error to stay in the chain")
+	}
+	if got := Of(errors.New("plain")); got != "" {
+		t.Errorf("Of(plain) = %q, want empty", got)
+	}
+}
+
+func TestOfUsesHinter(t *testing.T) {
+	err := fmt.Errorf("request failed: %w", &statusError{hint: "check the api key"})
+	if got := Of(err); got != "check the api key" {
+		t.Errorf("Of = %q, want the Hinter's hint", got)
+	}
+	if got := Of(&statusError{}); got != "" {
+		t.Errorf("Of = %q, want empty for an empty hint", got)
+	}
+}
+
+func TestOfLooksThroughJoinedErrors(t *testing.T) {
+	err := errors.Join(errors.New("plain"), fmt.Errorf("wrapped: %w", &statusError{hint: "check the url"}))
+	if got := Of(fmt.Errorf("outer: %w", err)); got != "check the url" {
+		t.Errorf("Of = %q, want the joined error's hint", got)
+	}
+	if got := Of(errors.Join(errors.New("a"), errors.New("b"))); got != "" {
+		t.Errorf("Of = %q, want empty", got)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	if got := Format(Errorf("fix it", "broken: %d", 1)); got != "broken: 1 (hint: fix it)" {
+		t.Errorf("Format = %q", got)
+	}
+	if got := Format(errors.New("broken")); got != "broken" {
+		t.Errorf("Format = %q, want the message alone", got)
+	}
+	if got := Format(nil); got != "" {
+		t.Errorf("Format(nil) = %q, want empty", got)
+	}
+}
@@ -0,0 +1,291 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ochronus/goputioarr/internal/fileutil"
+)
+
+// Entry describes a completed transfer.
+type Entry struct {
+	Name         string    `json:"name"`
+	Hash         string    `json:"hash"`
+	Size         int64     `json:"size"`
+	ImportedBy   string    `json:"imported_by,omitempty"`
+	StartedAt    time.Time `json:"started_at"`
+	DownloadedAt time.Time `json:"downloaded_at"`
+	ImportedAt   time.Time `json:"imported_at"`
+	CompletedAt  time.Time `json:"completed_at"`
+}
+
+// DownloadDuration returns how long the local download took.
+func (e Entry) DownloadDuration() time.Duration {
+	return between(e.StartedAt, e.DownloadedAt)
+}
+
+// ImportDuration returns how long the arr took to import the download.
+func (e Entry) ImportDuration() time.Duration {
+	return between(e.DownloadedAt, e.ImportedAt)
+}
+
+// TotalDuration returns the time from download start to completion.
+func (e Entry) TotalDuration() time.Duration {
+	return between(e.StartedAt, e.CompletedAt)
+}
+
+func between(from, to time.Time) time.Duration {
+	if from.IsZero() || to.IsZero() || to.Before(from) {
+		return 0
+	}
+	return to.Sub(from)
+}
+
+// Query selects history entries. Zero fields match every entry.
+type Query struct {
+	// Name matches entries whose name contains it, ignoring case.
+	Name string
+	// Hash matches the transfer hash, ignoring case.
+	Hash string
+	// ImportedBy matches the arr that imported the transfer, ignoring case.
+	ImportedBy string
+	// Since and Until bound the completion time.
+	Since time.Time
+	Until time.Time
+	// Limit caps the number of entries returned.
+	Limit int
+}
+
+// Matches reports whether e is selected by q, ignoring Limit.
+func (q Query) Matches(e Entry) bool {
+	if q.Name != "" && !strings.Contains(strings.ToLower(e.Name), strings.ToLower(q.Name)) {
+		return false
+	}
+	if q.Hash != "" && !strings.EqualFold(e.Hash, q.Hash) {
+		return false
+	}
+	if q.ImportedBy != "" && !strings.EqualFold(e.ImportedBy, q.ImportedBy) {
+		return false
+	}
+	if !q.Since.IsZero() && e.CompletedAt.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && e.CompletedAt.After(q.Until) {
+		return false
+	}
+	return true
+}
+
+// Store persists history entries.
+type Store interface {
+	Record(entry Entry) error
+	Query(q Query) ([]Entry, error)
+}
+
+// compactSlack is how many lines the history file may grow beyond twice the
+// entries kept at the last compaction before it's rewritten.
+const compactSlack = 100
+
+// FileStore keeps history as an append-only log of JSON lines. Recording an
+// entry appends a line; the file is compacted, dropping entries older than the
+// retention period and beyond the maximum entry count, once it has grown to
+// about twice the size it had after the last compaction.
+type FileStore struct {
+	path       string
+	retention  time.Duration
+	maxEntries int
+	now        func() time.Time
+	mu         sync.Mutex
+	// lines counts the lines in the file and kept the entries left by the last
+	// compaction; lines is -1 until the file has been read.
+	lines int
+	kept  int
+}
+
+var _ Store = (*FileStore)(nil)
+
+// NewFileStore creates a store backed by path. A zero retention or
+// maxEntries disables that limit.
+func NewFileStore(path string, retention time.Duration, maxEntries int) *FileStore {
+	return &FileStore{
+		path:       path,
+		retention:  retention,
+		maxEntries: maxEntries,
+		now:        time.Now,
+		lines:      -1,
+	}
+}
+
+// Record appends entry to the history, compacting the file when it has grown
+// enough.
+func (s *FileStore) Record(entry Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.lines < 0 {
+		if err := s.compact(); err != nil {
+			return err
+		}
+	}
+	if err := s.append(entry); err != nil {
+		return err
+	}
+	s.lines++
+	if s.lines >= 2*s.kept+compactSlack {
+		return s.compact()
+	}
+	return nil
+}
+
+// List returns the retained entries, newest first.
+func (s *FileStore) List() ([]Entry, error) {
+	return s.Query(Query{})
+}
+
+// Query returns the retained entries selected by q, newest first.
+func (s *FileStore) Query(q Query) ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	entries = s.prune(entries)
+	selected := entries[:0]
+	for _, e := range entries {
+		if q.Matches(e) {
+			selected = append(selected, e)
+		}
+	}
+	sort.SliceStable(selected, func(i, j int) bool {
+		return selected[i].CompletedAt.After(selected[j].CompletedAt)
+	})
+	if q.Limit > 0 && q.Limit < len(selected) {
+		selected = selected[:q.Limit]
+	}
+	return selected, nil
+}
+
+// Replace discards the history and keeps entries instead, applying the
+// retention settings.
+func (s *FileStore) Replace(entries []Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries = append([]Entry(nil), entries...)
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].CompletedAt.Before(entries[j].CompletedAt)
+	})
+	entries = s.prune(entries)
+	if err := s.save(entries); err != nil {
+		return err
+	}
+	s.lines, s.kept = len(entries), len(entries)
+	return nil
+}
+
+// compact rewrites the file with the retained entries.
+func (s *FileStore) compact() error {
+	entries, err := s.load()
+	if err != nil {
+		return err
+	}
+	entries = s.prune(entries)
+	if err := s.save(entries); err != nil {
+		return err
+	}
+	s.lines, s.kept = len(entries), len(entries)
+	return nil
+}
+
+// append adds entry as a line at the end of the file.
+func (s *FileStore) append(entry Entry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	return nil
+}
+
+// prune drops expired entries and keeps at most maxEntries of the newest ones.
+// entries must be in insertion order.
+func (s *FileStore) prune(entries []Entry) []Entry {
+	if s.retention > 0 {
+		cutoff := s.now().Add(-s.retention)
+		kept := entries[:0]
+		for _, e := range entries {
+			if e.CompletedAt.After(cutoff) {
+				kept = append(kept, e)
+			}
+		}
+		entries = kept
+	}
+	if s.maxEntries > 0 && len(entries) > s.maxEntries {
+		entries = entries[len(entries)-s.maxEntries:]
+	}
+	return entries
+}
+
+func (s *FileStore) load() ([]Entry, error) {
+	f, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history: %w", err)
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			// Skip corrupt lines rather than losing the whole history
+			continue
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	return entries, nil
+}
+
+// save atomically replaces the history file with entries.
+func (s *FileStore) save(entries []Entry) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			return fmt.Errorf("failed to write history: %w", err)
+		}
+	}
+	if err := fileutil.WriteAtomic(s.path, buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	return nil
+}
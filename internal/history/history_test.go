@@ -0,0 +1,220 @@
+package history
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileStoreRecordAndList(t *testing.T) {
+	store := NewFileStore(filepath.Join(t.TempDir(), "sub", "history.jsonl"), 0, 0)
+
+	now := time.Now()
+	if err := store.Record(Entry{Name: "older", Hash: "a", CompletedAt: now.Add(-time.Hour)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := store.Record(Entry{Name: "newer", Hash: "b", CompletedAt: now}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	entries, err := store.List()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Name != "newer" || entries[1].Name != "older" {
+		t.Errorf("expected entries newest first, got %+v", entries)
+	}
+}
+
+func TestFileStoreRetention(t *testing.T) {
+	now := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		retention  time.Duration
+		maxEntries int
+		expected   []string
+	}{
+		{name: "no limits", expected: []string{"c", "b", "a"}},
+		{name: "retention drops old entries", retention: 60 * time.Hour, expected: []string{"c", "b"}},
+		{name: "max entries keeps newest", maxEntries: 1, expected: []string{"c"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewFileStore(filepath.Join(t.TempDir(), "history.jsonl"), tt.retention, tt.maxEntries)
+			store.now = func() time.Time { return now }
+
+			for i, name := range []string{"a", "b", "c"} {
+				completed := now.Add(-time.Duration(72-24*i) * time.Hour)
+				if err := store.Record(Entry{Name: name, CompletedAt: completed}); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			entries, err := store.List()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(entries) != len(tt.expected) {
+				t.Fatalf("expected %d entries, got %+v", len(tt.expected), entries)
+			}
+			for i, name := range tt.expected {
+				if entries[i].Name != name {
+					t.Errorf("entry %d: expected %s, got %s", i, name, entries[i].Name)
+				}
+			}
+		})
+	}
+}
+
+func TestFileStoreReplace(t *testing.T) {
+	store := NewFileStore(filepath.Join(t.TempDir(), "history.jsonl"), 0, 2)
+	now := time.Now()
+	if err := store.Record(Entry{Name: "replaced", CompletedAt: now}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Newest first as returned by List; the oldest is over max entries.
+	err := store.Replace([]Entry{
+		{Name: "c", CompletedAt: now.Add(-time.Hour)},
+		{Name: "b", CompletedAt: now.Add(-2 * time.Hour)},
+		{Name: "a", CompletedAt: now.Add(-3 * time.Hour)},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	entries, err := store.List()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Name != "c" || entries[1].Name != "b" {
+		t.Errorf("expected the two newest imported entries, got %+v", entries)
+	}
+}
+
+func TestFileStoreSkipsCorruptLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	data := "{\"name\":\"ok\",\"completed_at\":\"2024-01-01T00:00:00Z\"}\nnot json\n\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatalf("failed to write history: %v", err)
+	}
+
+	entries, err := NewFileStore(path, 0, 0).List()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name != "ok" {
+		t.Errorf("expected the valid entry only, got %+v", entries)
+	}
+}
+
+func TestEntryDurations(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	e := Entry{
+		StartedAt:    start,
+		DownloadedAt: start.Add(10 * time.Minute),
+		ImportedAt:   start.Add(12 * time.Minute),
+		CompletedAt:  start.Add(time.Hour),
+	}
+
+	if got := e.DownloadDuration(); got != 10*time.Minute {
+		t.Errorf("expected download duration 10m, got %s", got)
+	}
+	if got := e.ImportDuration(); got != 2*time.Minute {
+		t.Errorf("expected import duration 2m, got %s", got)
+	}
+	if got := e.TotalDuration(); got != time.Hour {
+		t.Errorf("expected total duration 1h, got %s", got)
+	}
+	if got := (Entry{CompletedAt: start}).TotalDuration(); got != 0 {
+		t.Errorf("expected zero duration for missing start, got %s", got)
+	}
+}
+
+func TestFileStoreAppendsAndCompacts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	store := NewFileStore(path, 0, 10)
+	now := time.Now()
+
+	lines := func() int {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("failed to read history: %v", err)
+		}
+		return strings.Count(string(data), "\n")
+	}
+
+	for i := 0; i < compactSlack; i++ {
+		if err := store.Record(Entry{Name: fmt.Sprint(i), CompletedAt: now}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	// The first compaction happened at line 100 and kept 10 entries.
+	if got := lines(); got != 10 {
+		t.Errorf("expected the log to be compacted to 10 lines, got %d", got)
+	}
+
+	if err := store.Record(Entry{Name: "appended", CompletedAt: now.Add(time.Second)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := lines(); got != 11 {
+		t.Errorf("expected the entry to be appended, got %d lines", got)
+	}
+
+	entries, err := store.List()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 10 || entries[0].Name != "appended" {
+		t.Errorf("expected the 10 newest entries, got %+v", entries)
+	}
+}
+
+func TestFileStoreQuery(t *testing.T) {
+	store := NewFileStore(filepath.Join(t.TempDir(), "history.jsonl"), 0, 0)
+	now := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+	for _, e := range []Entry{
+		{Name: "Some.Show.S01E01", Hash: "AA", ImportedBy: "Sonarr", CompletedAt: now.Add(-48 * time.Hour)},
+		{Name: "Some.Show.S01E02", Hash: "BB", ImportedBy: "Sonarr", CompletedAt: now.Add(-24 * time.Hour)},
+		{Name: "Some.Movie", Hash: "CC", ImportedBy: "Radarr", CompletedAt: now},
+	} {
+		if err := store.Record(e); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		query    Query
+		expected []string
+	}{
+		{name: "all", query: Query{}, expected: []string{"CC", "BB", "AA"}},
+		{name: "name substring", query: Query{Name: "some.show"}, expected: []string{"BB", "AA"}},
+		{name: "hash", query: Query{Hash: "aa"}, expected: []string{"AA"}},
+		{name: "imported by", query: Query{ImportedBy: "radarr"}, expected: []string{"CC"}},
+		{name: "since", query: Query{Since: now.Add(-24 * time.Hour)}, expected: []string{"CC", "BB"}},
+		{name: "until", query: Query{Until: now.Add(-time.Hour)}, expected: []string{"BB", "AA"}},
+		{name: "limit", query: Query{ImportedBy: "sonarr", Limit: 1}, expected: []string{"BB"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := store.Query(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var hashes []string
+			for _, e := range entries {
+				hashes = append(hashes, e.Hash)
+			}
+			if strings.Join(hashes, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("expected %v, got %v", tt.expected, hashes)
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+//go:build transmissionrpc
+
+package http
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/hekmon/transmissionrpc/v3"
+	"github.com/ochronus/goputioarr/internal/app"
+	"github.com/ochronus/goputioarr/internal/config"
+	"github.com/ochronus/goputioarr/internal/putiomock"
+	"github.com/ochronus/goputioarr/internal/services/putio"
+	"github.com/sirupsen/logrus"
+)
+
+// The conformance tests drive the RPC endpoint with hekmon/transmissionrpc,
+// the client many Transmission tools are built on, against the in-memory
+// put.io API. The client handles basic auth, the X-Transmission-Session-Id
+// handshake and tags, and decodes responses into its strictly typed structs,
+// so a field of the wrong type fails the test. Run them with:
+//
+//	go test -tags transmissionrpc ./internal/http/
+
+func setupConformance(t *testing.T, password string) (*transmissionrpc.Client, *putiomock.Server) {
+	t.Helper()
+	mock := putiomock.New(putiomock.WithDownloadTime(time.Hour))
+	putioServer := httptest.NewServer(mock.Handler())
+	t.Cleanup(putioServer.Close)
+
+	cfg := config.DefaultConfig()
+	cfg.Username = "user"
+	cfg.Password = "pass"
+	cfg.DownloadDirectory = "/downloads"
+	cfg.Putio.APIKey = "token"
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	container := &app.Container{
+		Config:      cfg,
+		Logger:      logger,
+		PutioClient: putio.NewClient("token", putio.WithBaseURLs(putioServer.URL, putioServer.URL)),
+		Transfers:   app.NewTransferStore(),
+	}
+	server := httptest.NewServer(NewServer(container).GetRouter())
+	t.Cleanup(server.Close)
+
+	endpoint, err := url.Parse(server.URL + "/transmission/rpc")
+	if err != nil {
+		t.Fatalf("failed to parse the RPC URL: %v", err)
+	}
+	endpoint.User = url.UserPassword("user", password)
+	client, err := transmissionrpc.New(endpoint, nil)
+	if err != nil {
+		t.Fatalf("failed to create the client: %v", err)
+	}
+	return client, mock
+}
+
+func TestConformanceSessionGet(t *testing.T) {
+	client, _ := setupConformance(t, "pass")
+	ctx := context.Background()
+
+	ok, version, minimum, err := client.RPCVersion(ctx)
+	if err != nil {
+		t.Fatalf("rpc version: unexpected error: %v", err)
+	}
+	if !ok || version < minimum || minimum == 0 {
+		t.Errorf("expected a supported RPC version, got %d (minimum %d)", version, minimum)
+	}
+
+	session, err := client.SessionArgumentsGetAll(ctx)
+	if err != nil {
+		t.Fatalf("session-get: unexpected error: %v", err)
+	}
+	if session.Version == nil || *session.Version == "" {
+		t.Errorf("expected version information, got %+v", session)
+	}
+	if session.DownloadDir == nil || *session.DownloadDir != "/downloads" {
+		t.Errorf("expected download-dir /downloads, got %v", session.DownloadDir)
+	}
+}
+
+func TestConformanceSessionSet(t *testing.T) {
+	client, _ := setupConformance(t, "pass")
+	ctx := context.Background()
+
+	dir, ratio := "/downloads/tv", 2.5
+	limit, enabled := int64(500), true
+	err := client.SessionArgumentsSet(ctx, transmissionrpc.SessionArguments{
+		DownloadDir:           &dir,
+		SeedRatioLimit:        &ratio,
+		SpeedLimitDown:        &limit,
+		SpeedLimitDownEnabled: &enabled,
+	})
+	if err != nil {
+		t.Fatalf("session-set: unexpected error: %v", err)
+	}
+
+	session, err := client.SessionArgumentsGetAll(ctx)
+	if err != nil {
+		t.Fatalf("session-get: unexpected error: %v", err)
+	}
+	if session.DownloadDir == nil || *session.DownloadDir != dir ||
+		session.SeedRatioLimit == nil || *session.SeedRatioLimit != ratio ||
+		session.SeedRatioLimited == nil || !*session.SeedRatioLimited ||
+		session.SpeedLimitDown == nil || *session.SpeedLimitDown != limit ||
+		session.SpeedLimitDownEnabled == nil || !*session.SpeedLimitDownEnabled ||
+		session.SpeedLimitUpEnabled == nil || *session.SpeedLimitUpEnabled {
+		t.Errorf("expected the updated session, got %+v", session)
+	}
+
+	elsewhere := "/elsewhere"
+	if err := client.SessionArgumentsSet(ctx, transmissionrpc.SessionArguments{DownloadDir: &elsewhere}); err == nil {
+		t.Error("expected a download-dir outside the download directory to be refused")
+	}
+}
+
+func TestConformanceTorrentLifecycle(t *testing.T) {
+	client, mock := setupConformance(t, "pass")
+	ctx := context.Background()
+	const hash = "c12fe1c06bba254a9dc9f519b335aa7c1367a88a"
+	magnet := "magnet:?xt=urn:btih:" + hash + "&dn=Show"
+	dir, paused := "/downloads", false
+
+	added, err := client.TorrentAdd(ctx, transmissionrpc.TorrentAddPayload{Filename: &magnet, DownloadDir: &dir, Paused: &paused})
+	if err != nil {
+		t.Fatalf("torrent-add: unexpected error: %v", err)
+	}
+	if added.ID == nil || added.HashString == nil || *added.HashString != hash || added.Name == nil || *added.Name != "Show" {
+		t.Fatalf("torrent-add: expected torrent-added for %s, got %+v", hash, added)
+	}
+
+	duplicate, err := client.TorrentAdd(ctx, transmissionrpc.TorrentAddPayload{Filename: &magnet, DownloadDir: &dir, Paused: &paused})
+	if err != nil {
+		t.Fatalf("torrent-add duplicate: unexpected error: %v", err)
+	}
+	if duplicate.ID == nil || *duplicate.ID != *added.ID {
+		t.Errorf("torrent-add duplicate: expected torrent-duplicate, got %+v", duplicate)
+	}
+
+	metainfo := base64.StdEncoding.EncodeToString([]byte("d4:infod6:lengthi10e4:name8:file.mkv12:piece lengthi16384e6:pieces0:ee"))
+	uploaded, err := client.TorrentAdd(ctx, transmissionrpc.TorrentAddPayload{MetaInfo: &metainfo})
+	if err != nil {
+		t.Fatalf("torrent-add metainfo: unexpected error: %v", err)
+	}
+	if uploaded.ID == nil || uploaded.HashString == nil || uploaded.Name == nil || *uploaded.Name != "file.mkv" {
+		t.Fatalf("torrent-add metainfo: unexpected response %+v", uploaded)
+	}
+
+	// TorrentGetAll asks for every field the client knows, and fails if any
+	// of them doesn't decode.
+	all, err := client.TorrentGetAll(ctx)
+	if err != nil {
+		t.Fatalf("torrent-get: unexpected error: %v", err)
+	}
+	if len(all) != 2 {
+		t.Fatalf("torrent-get: expected 2 torrents, got %+v", all)
+	}
+	show := all[0]
+	if show.ID == nil || show.HashString == nil || *show.HashString != hash || show.DownloadDir == nil || *show.DownloadDir != "/downloads" {
+		t.Fatalf("torrent-get: unexpected torrent %+v", show)
+	}
+
+	byID, err := client.TorrentGet(ctx, []string{"id", "name", "hashString"}, []int64{*show.ID})
+	if err != nil || len(byID) != 1 || *byID[0].ID != *show.ID {
+		t.Errorf("torrent-get by id: expected only torrent %d, got %+v (err %v)", *show.ID, byID, err)
+	}
+	byHash, err := client.TorrentGetAllForHashes(ctx, []string{*uploaded.HashString})
+	if err != nil || len(byHash) != 1 || *byHash[0].Name != "file.mkv" {
+		t.Errorf("torrent-get by hash: expected only file.mkv, got %+v (err %v)", byHash, err)
+	}
+
+	err = client.TorrentRemove(ctx, transmissionrpc.TorrentRemovePayload{IDs: []int64{*show.ID}, DeleteLocalData: true})
+	if err != nil {
+		t.Fatalf("torrent-remove: unexpected error: %v", err)
+	}
+	err = client.TorrentRemove(ctx, transmissionrpc.TorrentRemovePayload{IDs: []int64{*uploaded.ID}})
+	if err != nil {
+		t.Fatalf("torrent-remove: unexpected error: %v", err)
+	}
+	if remaining := mock.Transfers(); len(remaining) != 0 {
+		t.Errorf("expected all transfers to be removed from put.io, got %+v", remaining)
+	}
+}
+
+func TestConformanceErrors(t *testing.T) {
+	client, _ := setupConformance(t, "pass")
+
+	filename := "not a magnet"
+	if _, err := client.TorrentAdd(context.Background(), transmissionrpc.TorrentAddPayload{Filename: &filename}); err == nil {
+		t.Error("expected an invalid magnet link to be refused")
+	}
+}
+
+func TestConformanceRejectsBadCredentials(t *testing.T) {
+	client, _ := setupConformance(t, "wrong")
+
+	if _, err := client.SessionArgumentsGetAll(context.Background()); err == nil {
+		t.Error("expected bad credentials to be rejected")
+	}
+}
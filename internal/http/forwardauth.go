@@ -0,0 +1,48 @@
+package http
+
+import (
+	"net"
+
+	"github.com/gin-gonic/gin"
+)
+
+// endpointGroupKey is the context key holding the endpoint group of the route.
+const endpointGroupKey = "endpointGroup"
+
+// endpointGroup tags the route with its forward_auth endpoint group.
+func endpointGroup(name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(endpointGroupKey, name)
+		c.Next()
+	}
+}
+
+// forwardAuthUser returns the user name set by the authenticating proxy, if
+// forward_auth is enabled for the route's endpoint group and the request comes
+// directly from one of trusted_proxies. Anyone else could set the header.
+func (h *Handler) forwardAuthUser(c *gin.Context) (string, bool) {
+	fa := h.config.ForwardAuth
+	if !fa.Allows(c.GetString(endpointGroupKey)) {
+		return "", false
+	}
+	user := c.GetHeader(fa.HeaderName())
+	if user == "" || !h.fromTrustedProxy(c) {
+		return "", false
+	}
+	return user, true
+}
+
+// fromTrustedProxy reports whether the direct peer, not the client named in
+// X-Forwarded-For, is one of trusted_proxies.
+func (h *Handler) fromTrustedProxy(c *gin.Context) bool {
+	ip := net.ParseIP(c.RemoteIP())
+	if ip == nil {
+		return false
+	}
+	for _, network := range h.trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
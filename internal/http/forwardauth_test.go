@@ -0,0 +1,113 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ochronus/goputioarr/internal/config"
+)
+
+func TestForwardAuth(t *testing.T) {
+	container := setupTestContainer()
+	container.Config.TrustedProxies = []string{"172.17.0.1"}
+	container.Config.ForwardAuth = &config.ForwardAuthConfig{Endpoints: []string{config.EndpointAPI}}
+	router := NewServer(container).GetRouter()
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		remoteAddr string
+		header     map[string]string
+		want       int
+	}{
+		{
+			name:       "header from trusted proxy",
+			method:     http.MethodGet,
+			path:       "/stats",
+			remoteAddr: "172.17.0.1:1234",
+			header:     map[string]string{"Remote-User": "alice"},
+			want:       http.StatusOK,
+		},
+		{
+			name:       "header from elsewhere",
+			method:     http.MethodGet,
+			path:       "/stats",
+			remoteAddr: "10.0.0.5:1234",
+			header:     map[string]string{"Remote-User": "alice"},
+			want:       http.StatusUnauthorized,
+		},
+		{
+			name:       "forwarded for a trusted proxy",
+			method:     http.MethodGet,
+			path:       "/history",
+			remoteAddr: "10.0.0.5:1234",
+			header:     map[string]string{"Remote-User": "alice", "X-Forwarded-For": "172.17.0.1"},
+			want:       http.StatusUnauthorized,
+		},
+		{
+			name:       "empty header",
+			method:     http.MethodGet,
+			path:       "/stats",
+			remoteAddr: "172.17.0.1:1234",
+			header:     map[string]string{"Remote-User": ""},
+			want:       http.StatusUnauthorized,
+		},
+		{
+			name:       "endpoint group not enabled",
+			method:     http.MethodGet,
+			path:       "/transmission/rpc",
+			remoteAddr: "172.17.0.1:1234",
+			header:     map[string]string{"Remote-User": "alice"},
+			want:       http.StatusForbidden,
+		},
+		{
+			name:       "credentials still accepted",
+			method:     http.MethodGet,
+			path:       "/transmission/rpc",
+			remoteAddr: "172.17.0.1:1234",
+			header:     map[string]string{"Authorization": basicAuthHeader("testuser", "testpass")},
+			want:       http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.RemoteAddr = tt.remoteAddr
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("expected status %d, got %d", tt.want, w.Code)
+			}
+		})
+	}
+}
+
+func TestForwardAuthCustomHeader(t *testing.T) {
+	container := setupTestContainer()
+	container.Config.TrustedProxies = []string{"172.17.0.0/16"}
+	container.Config.ForwardAuth = &config.ForwardAuthConfig{
+		Header:    "X-Forwarded-User",
+		Endpoints: []string{config.EndpointRPC},
+	}
+	router := NewServer(container).GetRouter()
+
+	for header, want := range map[string]int{
+		"X-Forwarded-User": http.StatusConflict,
+		"Remote-User":      http.StatusForbidden,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/transmission/rpc", nil)
+		req.RemoteAddr = "172.17.0.3:1234"
+		req.Header.Set(header, "alice")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != want {
+			t.Errorf("%s: expected status %d, got %d", header, want, w.Code)
+		}
+	}
+}
@@ -1,22 +1,34 @@
 package http
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
-	"net/url"
+	"path/filepath"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	_ "github.com/gin-gonic/gin/binding"
 	"github.com/ochronus/goputioarr/internal/app"
 	"github.com/ochronus/goputioarr/internal/config"
+	"github.com/ochronus/goputioarr/internal/hint"
 	"github.com/ochronus/goputioarr/internal/services/putio"
+	"github.com/ochronus/goputioarr/internal/services/torrent"
 	"github.com/ochronus/goputioarr/internal/services/transmission"
 	"github.com/sirupsen/logrus"
 )
 
-const sessionID = "useless-session-id"
+// Result strings used by Transmission for request-level errors.
+var (
+	errNoMethodName  = errors.New("no method name")
+	errUnknownMethod = errors.New("method name not recognized")
+)
 
 // Handler contains the HTTP handlers for the Transmission RPC protocol.
 type Handler struct {
@@ -24,132 +36,276 @@ type Handler struct {
 	config      *config.Config
 	putioClient putio.ClientAPI
 	logger      *logrus.Logger
+	limiter     *authLimiter
+	pending     *pendingTorrents
+	recent      *recentTorrents
+	settings    *sessionSettings
+	sessions    *sessionIDs
+	webdav      http.Handler
+	// authenticators are tried in order until one identifies the user.
+	authenticators []authenticator
+	trustedProxies []*net.IPNet
+	statuses       *unknownStatuses
 }
 
+// authenticator identifies the user making the request from one kind of
+// credential.
+type authenticator func(c *gin.Context) (user string, ok bool)
+
 // NewHandler creates a new HTTP handler.
 func NewHandler(container *app.Container) *Handler {
-	return &Handler{
-		container:   container,
-		config:      container.Config,
-		putioClient: container.PutioClient,
-		logger:      container.Logger,
+	// Validated with the configuration; an invalid list trusts no proxy.
+	trustedProxies, _ := config.ParseNetworks(container.Config.TrustedProxies)
+	h := &Handler{
+		container:      container,
+		config:         container.Config,
+		putioClient:    container.PutioClient,
+		logger:         container.Logger,
+		limiter:        newAuthLimiter(container.Config.Auth),
+		pending:        newPendingTorrents(),
+		statuses:       newUnknownStatuses(),
+		recent:         newRecentTorrents(),
+		settings:       newSessionSettings(),
+		sessions:       newSessionIDs(),
+		webdav:         newWebDAVHandler(container.Config.DownloadDirectory, container.Transfers),
+		trustedProxies: trustedProxies,
 	}
+	h.authenticators = []authenticator{h.certificateUser, h.forwardAuthUser, h.credentialsUser}
+	return h
 }
 
 // RPCPost handles POST requests to the Transmission RPC endpoint.
 func (h *Handler) RPCPost(c *gin.Context) {
 	// Validate user
-	if !h.validateUser(c) {
-		c.Header("X-Transmission-Session-Id", sessionID)
+	user, ok, locked := h.login(c)
+	if locked {
+		c.Status(http.StatusTooManyRequests)
+		return
+	}
+	if !ok || !h.validSession(c.GetHeader("X-Transmission-Session-Id")) {
+		c.Header("X-Transmission-Session-Id", h.sessionID())
 		c.Status(http.StatusConflict)
 		return
 	}
 
 	var req transmission.Request
 	if err := c.ShouldBindJSON(&req); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.Status(http.StatusRequestEntityTooLarge)
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	if strings.TrimSpace(req.Method) == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "method is required"})
-		return
+	log := h.requestLogger(c)
+	log.Debugf("RPC %s by %s", req.Method, user)
+
+	// Like Transmission, per-method failures are reported in the result field
+	// of an HTTP 200 response rather than as HTTP errors.
+	arguments, err := h.dispatch(log, &req, user)
+	result := "success"
+	if err != nil {
+		result = hint.Format(err)
+		log.Errorf("%s error: %s", req.Method, result)
+		arguments = nil
+	}
+
+	response := transmission.Response{
+		Result:    result,
+		Arguments: arguments,
+		Tag:       req.Tag,
 	}
 
-	var (
-		arguments interface{}
-		err       error
-	)
+	c.JSON(http.StatusOK, response)
+}
+
+// dispatch runs the requested RPC method and returns its response arguments.
+func (h *Handler) dispatch(log *logrus.Entry, req *transmission.Request, user string) (interface{}, error) {
+	switch strings.TrimSpace(req.Method) {
+	case "":
+		return nil, errNoMethodName
 
-	switch req.Method {
 	case "session-get":
-		arguments = transmission.DefaultConfig(h.config.DownloadDirectory)
+		session := h.settings.Get(h.remoteDownloadDirectory())
+		session.AltSpeedEnabled = h.container.Pause.Paused()
+		return session, nil
+
+	case "session-set":
+		log.Infof("session-set requested by %s", user)
+		return nil, h.handleSessionSet(log, req)
 
 	case "torrent-get":
-		arguments, err = h.handleTorrentGet()
+		var args transmission.TorrentGetArguments
+		if err := bindArguments(req, &args); err != nil {
+			return nil, err
+		}
+		resp, err := h.handleTorrentGet()
 		if err != nil {
-			h.logger.Errorf("torrent-get error: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
+			return nil, err
 		}
+		active, removed := h.recent.Observe(resp.Torrents)
+		if args.IDs.RecentlyActive() {
+			resp.Torrents, resp.Removed = active, removed
+			return resp, nil
+		}
+		resp.Torrents = filterTorrents(resp.Torrents, args.IDs)
+		return resp, nil
 
-	case "torrent-set":
+	case "torrent-set", "queue-move-top":
 		// Nothing to do here
-		arguments = nil
+		return nil, nil
 
-	case "queue-move-top":
-		// Nothing to do here
-		arguments = nil
+	case "torrent-start", "torrent-start-now":
+		log.Infof("%s requested by %s", req.Method, user)
+		return nil, h.handleTorrentStart(log, req)
+
+	case "torrent-stop":
+		log.Infof("torrent-stop requested by %s", user)
+		return nil, h.handleTorrentStop(log, req)
+
+	case "torrent-set-location":
+		log.Infof("torrent-set-location requested by %s", user)
+		return nil, h.handleTorrentSetLocation(log, req)
 
 	case "torrent-remove":
-		err = h.handleTorrentRemove(&req)
-		if err != nil {
-			h.logger.Errorf("torrent-remove error: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-		arguments = nil
+		log.Infof("torrent-remove requested by %s", user)
+		return nil, h.handleTorrentRemove(log, req)
 
 	case "torrent-add":
-		err = h.handleTorrentAdd(&req)
-		if err != nil {
-			h.logger.Errorf("torrent-add error: %v", err)
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			return
+		log.Infof("torrent-add requested by %s", user)
+		resp, err := h.handleTorrentAdd(log, req)
+		if resp == nil {
+			// Avoid a typed nil so the arguments are omitted
+			return nil, err
 		}
-		arguments = nil
+		return resp, err
 
 	default:
-		h.logger.Warnf("Unknown method: %s", req.Method)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown method"})
-		return
-	}
-
-	response := transmission.Response{
-		Result:    "success",
-		Arguments: arguments,
+		return nil, errUnknownMethod
 	}
-
-	c.JSON(http.StatusOK, response)
 }
 
 // RPCGet handles GET requests to the Transmission RPC endpoint (for authentication).
 func (h *Handler) RPCGet(c *gin.Context) {
-	if !h.validateUser(c) {
+	_, ok, locked := h.login(c)
+	if locked {
+		c.Status(http.StatusTooManyRequests)
+		return
+	}
+	if !ok {
 		c.Status(http.StatusForbidden)
 		return
 	}
 
-	c.Header("X-Transmission-Session-Id", sessionID)
+	c.Header("X-Transmission-Session-Id", h.sessionID())
 	c.Status(http.StatusConflict)
 }
 
-// validateUser validates the Basic Auth credentials.
+// login authenticates the request while enforcing the per-IP lockout. locked is
+// true when the client is currently locked out and was not authenticated.
+func (h *Handler) login(c *gin.Context) (user string, ok bool, locked bool) {
+	ip := c.ClientIP()
+	if h.limiter.Locked(ip) {
+		return "", false, true
+	}
+
+	user, ok = h.authenticate(c)
+	if ok {
+		h.limiter.RecordSuccess(ip)
+		return user, true, false
+	}
+
+	// Requests without credentials are part of the normal handshake and do not count.
+	if c.GetHeader("Authorization") != "" && h.limiter.RecordFailure(ip) {
+		h.logger.Warnf("Too many failed logins from %s, locking out", ip)
+	}
+	return "", false, false
+}
+
+// validateUser validates the request credentials.
 func (h *Handler) validateUser(c *gin.Context) bool {
+	_, ok := h.authenticate(c)
+	return ok
+}
+
+// authenticate returns the user identified by the first authenticator that
+// accepts the request.
+func (h *Handler) authenticate(c *gin.Context) (string, bool) {
+	for _, auth := range h.authenticators {
+		if user, ok := auth(c); ok {
+			return user, true
+		}
+	}
+	return "", false
+}
+
+// credentialsUser checks the Basic Auth credentials or Bearer token against
+// the configured users and returns the matching username.
+func (h *Handler) credentialsUser(c *gin.Context) (string, bool) {
 	authHeader := c.GetHeader("Authorization")
 	if authHeader == "" {
-		return false
+		return "", false
+	}
+
+	if strings.HasPrefix(authHeader, "Bearer ") {
+		token := strings.TrimPrefix(authHeader, "Bearer ")
+		if token == "" {
+			return "", false
+		}
+		for _, user := range h.config.Credentials() {
+			if user.Token != "" && secureCompare(user.Token, token) {
+				return user.Username, true
+			}
+		}
+		return "", false
 	}
 
 	if !strings.HasPrefix(authHeader, "Basic ") {
-		return false
+		return "", false
 	}
 
 	encoded := strings.TrimPrefix(authHeader, "Basic ")
 	decoded, err := base64.StdEncoding.DecodeString(encoded)
 	if err != nil {
-		return false
+		return "", false
 	}
 
 	parts := strings.SplitN(string(decoded), ":", 2)
 	if len(parts) != 2 {
-		return false
+		return "", false
 	}
 
 	username := parts[0]
 	password := parts[1]
 
-	return username == h.config.Username && password == h.config.Password
+	// Check every user so the response time doesn't reveal which usernames exist.
+	matched := ""
+	for _, user := range h.config.Credentials() {
+		usernameOK := secureCompare(user.Username, username)
+		passwordOK := secureCompare(user.Password, password)
+		// Token-only users cannot authenticate with an empty password.
+		if usernameOK && passwordOK && (user.Password != "" || user.Token == "") {
+			matched = user.Username
+		}
+	}
+
+	return matched, matched != ""
+}
+
+// secureCompare compares two strings in constant time. Both values are hashed
+// first so the comparison doesn't leak their lengths.
+func secureCompare(a, b string) bool {
+	ha := sha256.Sum256([]byte(a))
+	hb := sha256.Sum256([]byte(b))
+	return subtle.ConstantTimeCompare(ha[:], hb[:]) == 1
+}
+
+// remoteDownloadDirectory returns the download directory as seen by the arr
+// services, after applying the global path mappings.
+func (h *Handler) remoteDownloadDirectory() string {
+	return config.MapPath(h.config.DownloadDirectory, h.config.PathMappings)
 }
 
 // handleTorrentGet handles the torrent-get RPC method.
@@ -158,59 +314,517 @@ func (h *Handler) handleTorrentGet() (*transmission.TorrentGetResponse, error) {
 	if err != nil {
 		return nil, err
 	}
+	h.pending.FillHashes(transfers.Transfers)
 
+	downloadDir := h.remoteDownloadDirectory()
+	seedRatio, seedIdle := h.settings.SeedLimits()
+	localRate := h.container.Transfers.RecentRate()
+	known := make(map[string]bool, len(transfers.Transfers))
 	var torrents []*transmission.Torrent
 	for _, t := range transfers.Transfers {
-		torrent := transmission.TorrentFromPutIOTransfer(&t, h.config.DownloadDirectory)
+		torrent := transmission.TorrentFromPutIOTransfer(&t, downloadDir)
+		h.checkStatus(&t, torrent)
+		h.checkStall(&t, torrent)
+		if t.Hash != nil {
+			known[strings.ToLower(*t.Hash)] = true
+			if state, ok := h.container.Transfers.Get(*t.Hash); ok {
+				applyLocalState(torrent, state)
+			} else {
+				addLocalETA(torrent, localRate)
+			}
+		}
 		torrents = append(torrents, torrent)
 	}
+	h.statuses.Retain(transfers.Transfers)
+	for _, state := range h.container.Transfers.List() {
+		if state.FolderImport && !known[state.Hash] {
+			torrent := folderImportTorrent(state, downloadDir)
+			applyLocalState(torrent, state)
+			torrents = append(torrents, torrent)
+		}
+	}
+	torrents = append(torrents, h.pending.Torrents(known, downloadDir)...)
+	for _, torrent := range torrents {
+		// Report the name the download manager uses on disk, so the arrs
+		// look for the files in the right place.
+		if torrent.Name != "" {
+			torrent.Name = app.SanitizeName(torrent.Name)
+		}
+		// Like Transmission, report the session's limits for torrents
+		// following them (the default seed modes).
+		torrent.SeedRatioLimit = seedRatio
+		torrent.SeedIdleLimit = seedIdle
+		if torrent.HashString != nil && h.container.Holds.Has(*torrent.HashString) {
+			applyHold(torrent)
+		}
+		if torrent.HashString != nil {
+			if dir, ok := h.container.TransferDirectory(*torrent.HashString); ok {
+				torrent.DownloadDir = config.MapPath(dir, h.config.PathMappings)
+			}
+			torrent.Labels = h.container.Labels.Get(*torrent.HashString)
+		}
+	}
 
 	return &transmission.TorrentGetResponse{
 		Torrents: torrents,
 	}, nil
 }
 
+// folderImportTorrent reports an item of the import folder as a finished
+// torrent; applyLocalState holds it back until its files are on local disk.
+func folderImportTorrent(state app.TransferState, downloadDir string) *transmission.Torrent {
+	hash := state.Hash
+	return &transmission.Torrent{
+		HashString:     &hash,
+		Name:           state.Name,
+		DownloadDir:    downloadDir,
+		TotalSize:      state.Size,
+		DownloadedEver: state.Size,
+		IsFinished:     true,
+		Status:         transmission.StatusStopped,
+		FileCount:      1,
+	}
+}
+
+// filterTorrents returns the torrents selected by ids.
+func filterTorrents(torrents []*transmission.Torrent, ids transmission.TorrentIDs) []*transmission.Torrent {
+	if ids.All() {
+		return torrents
+	}
+	selected := make([]*transmission.Torrent, 0, len(torrents))
+	for _, torrent := range torrents {
+		hash := ""
+		if torrent.HashString != nil {
+			hash = *torrent.HashString
+		}
+		if ids.Matches(torrent.ID, hash) {
+			selected = append(selected, torrent)
+		}
+	}
+	return selected
+}
+
+// applyLocalState overrides put.io's view of a torrent with the download
+// manager's. A transfer put.io finished is only reported as done once its files
+// are on local disk, so the arrs don't try to import it too early.
+func applyLocalState(torrent *transmission.Torrent, state app.TransferState) {
+	switch state.Stage {
+	case app.StageDownloading, app.StageUnpacking, app.StageQueued:
+		size := state.Size
+		if size == 0 {
+			size = torrent.TotalSize
+		}
+		left := size - state.Downloaded
+		if state.Stage == app.StageUnpacking || left < 1 {
+			// Keep the torrent unfinished until the files are ready.
+			left = 1
+		}
+		torrent.Status = transmission.StatusDownloading
+		if state.Stage == app.StageQueued {
+			torrent.Status = transmission.StatusQueued
+		}
+		torrent.IsFinished = false
+		torrent.TotalSize = size
+		torrent.LeftUntilDone = left
+		torrent.DownloadedEver = size - left
+		torrent.ETA = -1
+		if state.Stage == app.StageDownloading && state.Rate > 0 {
+			torrent.ETA = left / state.Rate
+			torrent.RateDownload = state.Rate
+		}
+	case app.StageWaitingForImport, app.StageImported:
+		torrent.LeftUntilDone = 0
+		torrent.DownloadedEver = torrent.TotalSize
+	case app.StageFailed:
+		torrent.Status = transmission.StatusStopped
+		torrent.IsFinished = false
+	}
+	if state.Error != "" {
+		message := state.Error
+		torrent.ErrorString = &message
+	}
+}
+
+// addLocalETA adds the time the local download will take, at rate bytes per
+// second, to the ETA of a torrent put.io is still downloading, so it doesn't
+// drop to 0 when put.io finishes.
+func addLocalETA(torrent *transmission.Torrent, rate int64) {
+	if torrent.Status != transmission.StatusDownloading || torrent.ETA <= 0 || rate <= 0 {
+		return
+	}
+	torrent.ETA += torrent.TotalSize / rate
+}
+
+// applyHold reports a torrent added paused as stopped. Nothing has been
+// downloaded locally yet, so it must never look finished, whatever put.io says.
+func applyHold(torrent *transmission.Torrent) {
+	torrent.Status = transmission.StatusStopped
+	torrent.IsFinished = false
+	torrent.ETA = -1
+	if torrent.LeftUntilDone < 1 {
+		torrent.LeftUntilDone = max(torrent.TotalSize, 1)
+	}
+	torrent.DownloadedEver = max(torrent.TotalSize-torrent.LeftUntilDone, 0)
+}
+
 // handleTorrentAdd handles the torrent-add RPC method.
-func (h *Handler) handleTorrentAdd(req *transmission.Request) error {
+func (h *Handler) handleTorrentAdd(log *logrus.Entry, req *transmission.Request) (*transmission.TorrentAddResponse, error) {
 	var args transmission.TorrentAddArguments
 	if err := bindArguments(req, &args); err != nil {
-		return err
+		return nil, err
 	}
 
 	if args.Metainfo != "" {
-		data, err := base64.StdEncoding.DecodeString(args.Metainfo)
+		data, err := decodeMetainfo(args.Metainfo)
 		if err != nil {
-			return err
+			return nil, fmt.Errorf("invalid or corrupt torrent file: %w", err)
+		}
+
+		transfer, err := h.putioClient.UploadFile(data)
+		if err != nil {
+			return nil, err
+		}
+		log = withTransfer(log, transfer)
+
+		// put.io validates the torrent itself; parsing is only for reporting
+		meta, err := torrent.ParseMetainfo(data)
+		if err != nil {
+			log.Warnf("[ffff: unknown]: torrent file uploaded, but could not be parsed: %v", err)
+			if transfer == nil {
+				return nil, nil
+			}
+			if transfer.Hash != nil {
+				h.claim(log, *transfer.Hash)
+				h.label(log, *transfer.Hash, args.Labels)
+				h.place(log, *transfer.Hash)
+				if args.Paused {
+					h.hold(log, *transfer.Hash, "unknown")
+				}
+			}
+			return &transmission.TorrentAddResponse{TorrentAdded: torrentAdded(transfer, "", "")}, nil
 		}
 
-		return h.putioClient.UploadFile(data)
+		log.Infof("[%s: %s]: torrent file uploaded", shortHash(meta.InfoHash), meta.Name)
+		h.pending.Add(meta.InfoHash, meta.Name, meta.TotalSize)
+		if transfer != nil {
+			h.pending.Identify(transfer.ID, meta.InfoHash)
+		}
+		h.claim(log, meta.InfoHash)
+		h.label(log, meta.InfoHash, args.Labels)
+		h.place(log, meta.InfoHash)
+		if args.Paused {
+			h.hold(log, meta.InfoHash, meta.Name)
+		}
+		return &transmission.TorrentAddResponse{
+			TorrentAdded: torrentAdded(transfer, meta.Name, meta.InfoHash),
+		}, nil
 	}
 
 	if args.Filename == "" {
-		return nil
+		return nil, nil
+	}
+
+	name := "unknown"
+	hash := ""
+	if magnet, err := torrent.ParseMagnet(args.Filename); err == nil {
+		hash = magnet.InfoHash
+		if magnet.Name != "" {
+			name = magnet.Name
+		}
+	}
+
+	if hash != "" {
+		existing, err := h.findTransferByHash(hash)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			log = withTransfer(log, existing)
+			log.Infof("[%s: %s]: already on put.io, treating as duplicate", shortHash(hash), name)
+			h.claim(log, hash)
+			return &transmission.TorrentAddResponse{
+				TorrentDuplicate: torrentAdded(existing, name, hash),
+			}, nil
+		}
+	}
+
+	transfer, err := h.putioClient.AddTransfer(args.Filename)
+	if err != nil {
+		return nil, err
 	}
+	log = withTransfer(log, transfer)
 
-	if err := h.putioClient.AddTransfer(args.Filename); err != nil {
+	prefix := "ffff"
+	if hash != "" {
+		prefix = shortHash(hash)
+	}
+	log.Infof("[%s: %s]: magnet link uploaded", prefix, name)
+	h.pending.Add(hash, name, 0)
+	if transfer != nil {
+		h.pending.Identify(transfer.ID, hash)
+	}
+	if hash == "" && transfer != nil && transfer.Hash != nil {
+		hash = *transfer.Hash
+	}
+	if hash != "" {
+		h.claim(log, hash)
+		h.label(log, hash, args.Labels)
+		h.place(log, hash)
+		if args.Paused {
+			h.hold(log, hash, name)
+		}
+	} else {
+		log.Warnf("[ffff: %s]: unknown info hash, the transfer won't be managed unless manage_foreign_transfers is enabled", name)
+	}
+	if transfer == nil && hash == "" {
+		return nil, nil
+	}
+	return &transmission.TorrentAddResponse{
+		TorrentAdded: torrentAdded(transfer, name, hash),
+	}, nil
+}
+
+// claim records a transfer as added by the proxy so the download manager
+// handles it.
+func (h *Handler) claim(log *logrus.Entry, hash string) {
+	if err := h.container.Ownership.Add(hash); err != nil {
+		log.Warnf("[%s]: failed to record transfer ownership: %v", shortHash(hash), err)
+	}
+}
+
+// label records the labels a transfer was added with, which select its
+// [labels] settings.
+func (h *Handler) label(log *logrus.Entry, hash string, labels []string) {
+	if len(labels) == 0 {
+		return
+	}
+	if err := h.container.Labels.Set(hash, labels); err != nil {
+		log.Warnf("[%s]: failed to record transfer labels: %v", shortHash(hash), err)
+	}
+	if name, _, ok := h.config.LabelFor(labels); ok {
+		log.Infof("[%s]: using the settings of label %s", shortHash(hash), name)
+	}
+}
+
+// place downloads a new transfer to the session's download directory, if
+// session-set changed it.
+func (h *Handler) place(log *logrus.Entry, hash string) {
+	if dir := h.settings.LocalDir(); dir != "" {
+		if err := h.container.Locations.Set(hash, dir); err != nil {
+			log.Warnf("[%s]: failed to update transfer locations: %v", shortHash(hash), err)
+		}
+	}
+}
+
+// hold keeps a transfer added paused out of the download pipeline until
+// torrent-start is called for it.
+func (h *Handler) hold(log *logrus.Entry, hash, name string) {
+	if err := h.container.Holds.Add(hash); err != nil {
+		log.Warnf("[%s]: failed to update held transfers: %v", shortHash(hash), err)
+	}
+	log.Infof("[%s: %s]: added paused, waiting for torrent-start", shortHash(hash), name)
+}
+
+// withTransfer tags log with the ID of the put.io transfer, if there is one,
+// so the RPC can be matched with the download manager's lines for it.
+func withTransfer(log *logrus.Entry, transfer *putio.Transfer) *logrus.Entry {
+	if transfer == nil {
+		return log
+	}
+	return log.WithField("transfer_id", transfer.ID)
+}
+
+// torrentAdded describes a torrent-add result, preferring the details of the
+// put.io transfer (which may be nil) over the ones parsed locally.
+func torrentAdded(transfer *putio.Transfer, name, hash string) *transmission.TorrentAdded {
+	added := &transmission.TorrentAdded{Name: name, HashString: strings.ToLower(hash)}
+	if transfer == nil {
+		return added
+	}
+	added.ID = transfer.ID
+	if transfer.Name != nil && *transfer.Name != "" {
+		added.Name = *transfer.Name
+	}
+	if transfer.Hash != nil && *transfer.Hash != "" {
+		added.HashString = strings.ToLower(*transfer.Hash)
+	}
+	return added
+}
+
+// decodeMetainfo decodes a base64 torrent file. It streams the decode into a
+// buffer sized for the result, so the arguments aren't copied on the way.
+func decodeMetainfo(metainfo string) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Grow(base64.StdEncoding.DecodedLen(len(metainfo)))
+	if _, err := buf.ReadFrom(base64.NewDecoder(base64.StdEncoding, strings.NewReader(metainfo))); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// findTransferByHash returns the put.io transfer with the given info hash, or
+// nil if there is none.
+func (h *Handler) findTransferByHash(hash string) (*putio.Transfer, error) {
+	transfers, err := h.putioClient.ListTransfers()
+	if err != nil {
+		return nil, err
+	}
+	h.pending.FillHashes(transfers.Transfers)
+	for i, t := range transfers.Transfers {
+		if t.Hash != nil && strings.EqualFold(*t.Hash, hash) {
+			return &transfers.Transfers[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// handleTorrentStart handles the torrent-start and torrent-start-now RPC
+// methods by releasing the selected torrents that were added paused.
+func (h *Handler) handleTorrentStart(log *logrus.Entry, req *transmission.Request) error {
+	var args transmission.TorrentActionArguments
+	if err := bindArguments(req, &args); err != nil {
 		return err
 	}
 
-	name := "unknown"
-	if strings.HasPrefix(args.Filename, "magnet:") {
-		if parsed, err := url.Parse(args.Filename); err == nil {
-			if dn := parsed.Query().Get("dn"); dn != "" {
-				if decoded, err := url.QueryUnescape(dn); err == nil {
-					name = decoded
-				}
-			}
+	resp, err := h.handleTorrentGet()
+	if err != nil {
+		return err
+	}
+	for _, torrent := range filterTorrents(resp.Torrents, args.IDs) {
+		if torrent.HashString == nil {
+			continue
+		}
+		log := log.WithField("transfer_id", torrent.ID)
+		released, err := h.container.Holds.Remove(*torrent.HashString)
+		if err != nil {
+			log.Warnf("[%s]: failed to update held transfers: %v", shortHash(*torrent.HashString), err)
+		}
+		if released {
+			log.Infof("[%s: %s]: started", shortHash(*torrent.HashString), torrent.Name)
 		}
 	}
+	return nil
+}
+
+// handleTorrentStop handles the torrent-stop RPC method by holding the
+// selected torrents until torrent-start. Torrents the download manager
+// already picked up can't be stopped and keep downloading.
+func (h *Handler) handleTorrentStop(log *logrus.Entry, req *transmission.Request) error {
+	var args transmission.TorrentActionArguments
+	if err := bindArguments(req, &args); err != nil {
+		return err
+	}
 
-	h.logger.Infof("[ffff: %s]: magnet link uploaded", name)
+	resp, err := h.handleTorrentGet()
+	if err != nil {
+		return err
+	}
+	for _, torrent := range filterTorrents(resp.Torrents, args.IDs) {
+		if torrent.HashString == nil {
+			continue
+		}
+		hash := *torrent.HashString
+		log := log.WithField("transfer_id", torrent.ID)
+		if _, ok := h.container.Transfers.Get(hash); ok || torrent.IsFinished {
+			log.Infof("[%s: %s]: already downloading, can't be stopped", shortHash(hash), torrent.Name)
+			continue
+		}
+		if h.container.Holds.Has(hash) {
+			continue
+		}
+		if err := h.container.Holds.Add(hash); err != nil {
+			log.Warnf("[%s]: failed to update held transfers: %v", shortHash(hash), err)
+		}
+		log.Infof("[%s: %s]: stopped, waiting for torrent-start", shortHash(hash), torrent.Name)
+	}
+	return nil
+}
+
+// handleTorrentSetLocation handles the torrent-set-location RPC method by
+// recording the location, as seen by the arrs, as the download directory of
+// the selected torrents. It must lie within download_directory. Torrents the
+// download manager already picked up keep their files where they are, since
+// the arrs may be importing them; the others are downloaded to the location.
+func (h *Handler) handleTorrentSetLocation(log *logrus.Entry, req *transmission.Request) error {
+	var args transmission.TorrentSetLocationArguments
+	if err := bindArguments(req, &args); err != nil {
+		return err
+	}
+	if args.Location == "" {
+		return fmt.Errorf("location is required")
+	}
+	dir, err := h.localLocation(args.Location)
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.handleTorrentGet()
+	if err != nil {
+		return err
+	}
+	for _, torrent := range filterTorrents(resp.Torrents, args.IDs) {
+		if torrent.HashString == nil {
+			continue
+		}
+		hash := *torrent.HashString
+		log := log.WithField("transfer_id", torrent.ID)
+		if _, ok := h.container.Transfers.Get(hash); ok {
+			log.Infof("[%s: %s]: already downloading, keeping its files in place", shortHash(hash), torrent.Name)
+			continue
+		}
+		var err error
+		if dir == "" {
+			err = h.container.Locations.Forget(hash)
+		} else {
+			err = h.container.Locations.Set(hash, dir)
+		}
+		if err != nil {
+			log.Warnf("[%s]: failed to update transfer locations: %v", shortHash(hash), err)
+		}
+		log.Infof("[%s: %s]: location set to %s", shortHash(hash), torrent.Name, args.Location)
+	}
 	return nil
 }
 
+// handleSessionSet handles the session-set RPC method. A download-dir must
+// lie within download_directory; new torrents are downloaded there. Turtle
+// mode (alt-speed-enabled) pauses the download queue.
+func (h *Handler) handleSessionSet(log *logrus.Entry, req *transmission.Request) error {
+	var args transmission.SessionSetArguments
+	if err := bindArguments(req, &args); err != nil {
+		return err
+	}
+	var dir string
+	if args.DownloadDir != nil {
+		var err error
+		if dir, err = h.localLocation(*args.DownloadDir); err != nil {
+			return err
+		}
+		log.Infof("Download directory for new torrents set to %s", *args.DownloadDir)
+	}
+	if args.AltSpeedEnabled != nil {
+		h.setPaused(log, *args.AltSpeedEnabled)
+	}
+	h.settings.Set(&args, dir)
+	return nil
+}
+
+// localLocation translates a download directory as seen by the arrs to the
+// local path, refusing ones outside download_directory. It returns an empty
+// path for download_directory itself.
+func (h *Handler) localLocation(location string) (string, error) {
+	dir := filepath.Clean(config.UnmapPath(location, h.config.PathMappings))
+	if !app.WithinDirectory(h.config.DownloadDirectory, dir) {
+		return "", fmt.Errorf("location %s is outside the download directory", location)
+	}
+	if dir == filepath.Clean(h.config.DownloadDirectory) {
+		return "", nil
+	}
+	return dir, nil
+}
+
 // handleTorrentRemove handles the torrent-remove RPC method.
-func (h *Handler) handleTorrentRemove(req *transmission.Request) error {
+func (h *Handler) handleTorrentRemove(log *logrus.Entry, req *transmission.Request) error {
 	var args transmission.TorrentRemoveArguments
 	if err := bindArguments(req, &args); err != nil {
 		return err
@@ -224,34 +838,68 @@ func (h *Handler) handleTorrentRemove(req *transmission.Request) error {
 	if err != nil {
 		return err
 	}
+	h.pending.FillHashes(transfers.Transfers)
 
-	// Build a set of hashes to remove
-	hashSet := make(map[string]bool)
-	for _, id := range args.IDs {
-		hashSet[id] = true
+	// Find matching transfers and remove them, cleaning up after the ones
+	// put.io actually removed
+	var matched []putio.Transfer
+	var ids []uint64
+	for _, t := range transfers.Transfers {
+		hash := ""
+		if t.Hash != nil {
+			hash = *t.Hash
+		}
+		if args.IDs.Matches(t.ID, hash) {
+			matched = append(matched, t)
+			ids = append(ids, t.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	removed, removeErr := putio.RemoveEach(h.putioClient, ids)
+	if removeErr != nil {
+		log.Errorf("Failed to remove transfers: %v", removeErr)
+	}
+	done := make(map[uint64]bool, len(removed))
+	for _, id := range removed {
+		done[id] = true
 	}
 
-	// Find and remove matching transfers
-	for _, t := range transfers.Transfers {
-		if t.Hash == nil {
+	var fileIDs []int64
+	for _, t := range matched {
+		if !done[t.ID] {
 			continue
 		}
-
-		if hashSet[*t.Hash] {
-			if err := h.putioClient.RemoveTransfer(t.ID); err != nil {
-				h.logger.Errorf("Failed to remove transfer %d: %v", t.ID, err)
-				continue
+		if t.Hash != nil {
+			if _, err := h.container.Holds.Remove(*t.Hash); err != nil {
+				log.Warnf("[%s]: failed to update held transfers: %v", shortHash(*t.Hash), err)
+			}
+			if err := h.container.Locations.Forget(*t.Hash); err != nil {
+				log.Warnf("[%s]: failed to update transfer locations: %v", shortHash(*t.Hash), err)
 			}
+			h.container.Transfers.ForgetFailed(*t.Hash)
+			if err := h.container.Labels.Forget(*t.Hash); err != nil {
+				log.Warnf("[%s]: failed to update transfer labels: %v", shortHash(*t.Hash), err)
+			}
+		}
 
-			if t.UserfileExists && args.DeleteLocalData && t.FileID != nil {
-				if err := h.putioClient.DeleteFile(*t.FileID); err != nil {
-					h.logger.Errorf("Failed to delete file %d: %v", *t.FileID, err)
-				}
+		if t.UserfileExists && args.DeleteLocalData && t.FileID != nil {
+			if h.config.Streaming() {
+				// Imported STRM files point at the put.io files.
+				log.WithField("transfer_id", t.ID).Infof("Keeping the files of transfer %d for streaming", t.ID)
+				continue
 			}
+			fileIDs = append(fileIDs, *t.FileID)
+		}
+	}
+	if len(fileIDs) > 0 {
+		if err := h.putioClient.DeleteFiles(fileIDs); err != nil {
+			log.Errorf("Failed to delete files %v: %v", fileIDs, err)
 		}
 	}
 
-	return nil
+	return removeErr
 }
 
 func bindArguments[T any](req *transmission.Request, dest *T) error {
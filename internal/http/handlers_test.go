@@ -2,15 +2,24 @@ package http
 
 import (
 	"bytes"
+	"crypto/sha1"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/ochronus/goputioarr/internal/app"
 	"github.com/ochronus/goputioarr/internal/config"
+	"github.com/ochronus/goputioarr/internal/history"
 	"github.com/ochronus/goputioarr/internal/services/putio"
 	"github.com/ochronus/goputioarr/internal/services/transmission"
 	"github.com/sirupsen/logrus"
@@ -25,7 +34,12 @@ type mockPutioClient struct {
 	uploadErr     error
 	addErr        error
 	removeErr     error
+	removeErrs    map[uint64]error
 	deleteErr     error
+	added         []string
+	deleted       []int64
+	removed       []uint64
+	newTransfer   *putio.Transfer
 }
 
 func (m *mockPutioClient) GetAccountInfo() (*putio.AccountInfoResponse, error) {
@@ -39,24 +53,51 @@ func (m *mockPutioClient) ListTransfers() (*putio.ListTransferResponse, error) {
 	return &putio.ListTransferResponse{Transfers: []putio.Transfer{}}, nil
 }
 
+func (m *mockPutioClient) ListTransfersSince(uint64) (*putio.ListTransferResponse, error) {
+	return m.ListTransfers()
+}
+
 func (m *mockPutioClient) GetTransfer(transferID uint64) (*putio.GetTransferResponse, error) {
 	return &putio.GetTransferResponse{}, nil
 }
 
 func (m *mockPutioClient) RemoveTransfer(transferID uint64) error {
+	if err := m.removeErrs[transferID]; err != nil {
+		return err
+	}
+	m.removed = append(m.removed, transferID)
+	return m.removeErr
+}
+
+func (m *mockPutioClient) RemoveTransfers(transferIDs []uint64) error {
+	for _, id := range transferIDs {
+		if err := m.removeErrs[id]; err != nil {
+			return err
+		}
+	}
+	m.removed = append(m.removed, transferIDs...)
 	return m.removeErr
 }
 
+func (m *mockPutioClient) RetryTransfer(transferID uint64) error { return nil }
+
 func (m *mockPutioClient) DeleteFile(fileID int64) error {
+	m.deleted = append(m.deleted, fileID)
+	return m.deleteErr
+}
+
+func (m *mockPutioClient) DeleteFiles(fileIDs []int64) error {
+	m.deleted = append(m.deleted, fileIDs...)
 	return m.deleteErr
 }
 
-func (m *mockPutioClient) AddTransfer(url string) error {
-	return m.addErr
+func (m *mockPutioClient) AddTransfer(url string) (*putio.Transfer, error) {
+	m.added = append(m.added, url)
+	return m.newTransfer, m.addErr
 }
 
-func (m *mockPutioClient) UploadFile(data []byte) error {
-	return m.uploadErr
+func (m *mockPutioClient) UploadFile(data []byte) (*putio.Transfer, error) {
+	return m.newTransfer, m.uploadErr
 }
 
 func (m *mockPutioClient) ListFiles(fileID int64) (*putio.ListFileResponse, error) {
@@ -64,7 +105,7 @@ func (m *mockPutioClient) ListFiles(fileID int64) (*putio.ListFileResponse, erro
 }
 
 func (m *mockPutioClient) GetFileURL(fileID int64) (string, error) {
-	return "", nil
+	return fmt.Sprintf("https://cdn.put.io/files/%d", fileID), nil
 }
 
 func setupTestHandler() *Handler {
@@ -261,6 +302,7 @@ func TestRPCPostSessionGet(t *testing.T) {
 	req := httptest.NewRequest("POST", "/transmission/rpc", bytes.NewBufferString(body))
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", basicAuthHeader("testuser", "testpass"))
+	req.Header.Set("X-Transmission-Session-Id", handler.sessionID())
 
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
@@ -301,6 +343,7 @@ func TestRPCPostTorrentSet(t *testing.T) {
 	req := httptest.NewRequest("POST", "/transmission/rpc", bytes.NewBufferString(body))
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", basicAuthHeader("testuser", "testpass"))
+	req.Header.Set("X-Transmission-Session-Id", handler.sessionID())
 
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
@@ -327,6 +370,7 @@ func TestRPCPostQueueMoveTop(t *testing.T) {
 	req := httptest.NewRequest("POST", "/transmission/rpc", bytes.NewBufferString(body))
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", basicAuthHeader("testuser", "testpass"))
+	req.Header.Set("X-Transmission-Session-Id", handler.sessionID())
 
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
@@ -353,12 +397,21 @@ func TestRPCPostUnknownMethod(t *testing.T) {
 	req := httptest.NewRequest("POST", "/transmission/rpc", bytes.NewBufferString(body))
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", basicAuthHeader("testuser", "testpass"))
+	req.Header.Set("X-Transmission-Session-Id", handler.sessionID())
 
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 
-	if w.Code != http.StatusBadRequest {
-		t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var resp transmission.Response
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if resp.Result != "method name not recognized" {
+		t.Errorf("expected result 'method name not recognized', got '%s'", resp.Result)
 	}
 }
 
@@ -370,6 +423,7 @@ func TestRPCPostInvalidJSON(t *testing.T) {
 	req := httptest.NewRequest("POST", "/transmission/rpc", bytes.NewBufferString(body))
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", basicAuthHeader("testuser", "testpass"))
+	req.Header.Set("X-Transmission-Session-Id", handler.sessionID())
 
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
@@ -386,6 +440,7 @@ func TestRPCPostEmptyBody(t *testing.T) {
 	req := httptest.NewRequest("POST", "/transmission/rpc", nil)
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", basicAuthHeader("testuser", "testpass"))
+	req.Header.Set("X-Transmission-Session-Id", handler.sessionID())
 
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
@@ -404,7 +459,7 @@ func TestHandleTorrentAddNilArguments(t *testing.T) {
 		Arguments: nil,
 	}
 
-	err := handler.handleTorrentAdd(req)
+	_, err := handler.handleTorrentAdd(testLog(handler), req)
 	if err != nil {
 		t.Errorf("expected no error for nil arguments, got: %v", err)
 	}
@@ -418,21 +473,12 @@ func TestHandleTorrentRemoveNilArguments(t *testing.T) {
 		Arguments: nil,
 	}
 
-	err := handler.handleTorrentRemove(req)
+	err := handler.handleTorrentRemove(testLog(handler), req)
 	if err != nil {
 		t.Errorf("expected no error for nil arguments, got: %v", err)
 	}
 }
 
-func TestSessionIDConstant(t *testing.T) {
-	if sessionID == "" {
-		t.Error("sessionID should not be empty")
-	}
-	if sessionID != "useless-session-id" {
-		t.Errorf("unexpected sessionID value: %s", sessionID)
-	}
-}
-
 func TestHandlerConfigAccess(t *testing.T) {
 	handler := setupTestHandler()
 
@@ -454,6 +500,7 @@ func TestRPCPostContentType(t *testing.T) {
 	body := `{"method": "session-get"}`
 	req := httptest.NewRequest("POST", "/transmission/rpc", bytes.NewBufferString(body))
 	req.Header.Set("Authorization", basicAuthHeader("testuser", "testpass"))
+	req.Header.Set("X-Transmission-Session-Id", handler.sessionID())
 	// Not setting Content-Type header
 
 	w := httptest.NewRecorder()
@@ -474,6 +521,7 @@ func TestResponseFormat(t *testing.T) {
 	req := httptest.NewRequest("POST", "/transmission/rpc", bytes.NewBufferString(body))
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", basicAuthHeader("testuser", "testpass"))
+	req.Header.Set("X-Transmission-Session-Id", handler.sessionID())
 
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
@@ -497,6 +545,7 @@ func TestMultipleRequests(t *testing.T) {
 			req := httptest.NewRequest("POST", "/transmission/rpc", bytes.NewBufferString(body))
 			req.Header.Set("Content-Type", "application/json")
 			req.Header.Set("Authorization", basicAuthHeader("testuser", "testpass"))
+			req.Header.Set("X-Transmission-Session-Id", handler.sessionID())
 
 			w := httptest.NewRecorder()
 			router.ServeHTTP(w, req)
@@ -521,7 +570,55 @@ func TestTorrentAddWithMetainfo(t *testing.T) {
 
 	// This will fail because we can't actually upload to put.io in tests
 	// but we can verify the code path doesn't panic
-	_ = handler.handleTorrentAdd(req)
+	_, _ = handler.handleTorrentAdd(testLog(handler), req)
+}
+
+func TestTorrentAddReportsMetainfo(t *testing.T) {
+	handler := setupTestHandler()
+
+	info := "d6:lengthi1024e4:name8:file.mkv12:piece lengthi16384e6:pieces0:e"
+	torrentData := "d8:announce15:http://tracker/4:info" + info + "e"
+	req := &transmission.Request{
+		Method:    "torrent-add",
+		Arguments: rawArgs(map[string]interface{}{"metainfo": base64.StdEncoding.EncodeToString([]byte(torrentData))}),
+	}
+
+	resp, err := handler.handleTorrentAdd(testLog(handler), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp == nil || resp.TorrentAdded == nil {
+		t.Fatal("expected torrent-added in response")
+	}
+	sum := sha1.Sum([]byte(info))
+	expectedHash := hex.EncodeToString(sum[:])
+	if resp.TorrentAdded.HashString != expectedHash || resp.TorrentAdded.Name != "file.mkv" {
+		t.Errorf("unexpected torrent-added %+v", resp.TorrentAdded)
+	}
+
+	// The torrent is reported by torrent-get until put.io lists it
+	got, err := handler.handleTorrentGet()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got.Torrents) != 1 || *got.Torrents[0].HashString != expectedHash || got.Torrents[0].TotalSize != 1024 {
+		t.Fatalf("expected pending torrent in torrent-get, got %+v", got.Torrents)
+	}
+
+	putioHash := strings.ToUpper(expectedHash)
+	name := "file.mkv"
+	handler.putioClient = &mockPutioClient{
+		transfersResp: &putio.ListTransferResponse{Transfers: []putio.Transfer{
+			{ID: 7, Hash: &putioHash, Name: &name, Status: "DOWNLOADING"},
+		}},
+	}
+	got, err = handler.handleTorrentGet()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got.Torrents) != 1 || got.Torrents[0].ID != 7 {
+		t.Errorf("expected only the put.io transfer once listed, got %+v", got.Torrents)
+	}
 }
 
 func TestTorrentAddWithMagnetLink(t *testing.T) {
@@ -536,7 +633,7 @@ func TestTorrentAddWithMagnetLink(t *testing.T) {
 
 	// This will fail because we can't actually add to put.io in tests
 	// but we can verify the code path doesn't panic
-	_ = handler.handleTorrentAdd(req)
+	_, _ = handler.handleTorrentAdd(testLog(handler), req)
 }
 
 func TestTorrentAddWithInvalidMetainfo(t *testing.T) {
@@ -547,7 +644,7 @@ func TestTorrentAddWithInvalidMetainfo(t *testing.T) {
 		Arguments: rawArgs(map[string]interface{}{"metainfo": "!!!invalid-base64!!!"}),
 	}
 
-	err := handler.handleTorrentAdd(req)
+	_, err := handler.handleTorrentAdd(testLog(handler), req)
 	if err == nil {
 		t.Error("expected error for invalid base64, got nil")
 	}
@@ -561,6 +658,7 @@ func TestRPCPostTorrentRemoveNilArguments(t *testing.T) {
 	req := httptest.NewRequest("POST", "/transmission/rpc", bytes.NewBufferString(body))
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", basicAuthHeader("testuser", "testpass"))
+	req.Header.Set("X-Transmission-Session-Id", handler.sessionID())
 
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
@@ -578,6 +676,7 @@ func TestRPCPostTorrentAddNilArguments(t *testing.T) {
 	req := httptest.NewRequest("POST", "/transmission/rpc", bytes.NewBufferString(body))
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", basicAuthHeader("testuser", "testpass"))
+	req.Header.Set("X-Transmission-Session-Id", handler.sessionID())
 
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
@@ -587,6 +686,59 @@ func TestRPCPostTorrentAddNilArguments(t *testing.T) {
 	}
 }
 
+func TestTorrentAddClaimsOwnership(t *testing.T) {
+	handler := setupTestHandler()
+	registry, err := app.NewOwnershipRegistry("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	handler.container.Ownership = registry
+
+	req := &transmission.Request{
+		Method:    "torrent-add",
+		Arguments: rawArgs(map[string]interface{}{"filename": "magnet:?xt=urn:btih:C12FE1C06BBA254A9DC9F519B335AA7C1367A88A&dn=Show"}),
+	}
+	if _, err := handler.handleTorrentAdd(testLog(handler), req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !registry.Has("c12fe1c06bba254a9dc9f519b335aa7c1367a88a") {
+		t.Error("expected added transfer to be owned by the proxy")
+	}
+}
+
+func TestRPCPostTorrentAddReturnsTorrentAdded(t *testing.T) {
+	handler := setupTestHandler()
+	hash := "c12fe1c06bba254a9dc9f519b335aa7c1367a88a"
+	handler.putioClient = &mockPutioClient{newTransfer: &putio.Transfer{ID: 9, Hash: &hash}}
+	router := setupTestRouter(handler)
+
+	body := `{"method": "torrent-add", "arguments": {"filename": "magnet:?xt=urn:btih:C12FE1C06BBA254A9DC9F519B335AA7C1367A88A&dn=Show"}}`
+	req := httptest.NewRequest("POST", "/transmission/rpc", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", basicAuthHeader("testuser", "testpass"))
+	req.Header.Set("X-Transmission-Session-Id", handler.sessionID())
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var resp struct {
+		Result    string `json:"result"`
+		Arguments struct {
+			TorrentAdded *transmission.TorrentAdded `json:"torrent-added"`
+		} `json:"arguments"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if resp.Result != "success" {
+		t.Fatalf("expected result 'success', got '%s'", resp.Result)
+	}
+	added := resp.Arguments.TorrentAdded
+	if added == nil || added.ID != 9 || added.Name != "Show" || added.HashString != hash {
+		t.Errorf("unexpected torrent-added %+v", added)
+	}
+}
+
 func TestValidateUserPasswordWithColon(t *testing.T) {
 	handler := setupTestHandler()
 	handler.config.Password = "pass:word:with:colons"
@@ -625,6 +777,7 @@ func TestSessionGetResponseFields(t *testing.T) {
 	req := httptest.NewRequest("POST", "/transmission/rpc", bytes.NewBufferString(body))
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", basicAuthHeader("testuser", "testpass"))
+	req.Header.Set("X-Transmission-Session-Id", handler.sessionID())
 
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
@@ -662,8 +815,8 @@ func TestRPCGetSessionIdHeader(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	sessionIdHeader := w.Header().Get("X-Transmission-Session-Id")
-	if sessionIdHeader != "useless-session-id" {
-		t.Errorf("expected session ID 'useless-session-id', got '%s'", sessionIdHeader)
+	if sessionIdHeader == "" || sessionIdHeader == legacySessionID || !handler.sessions.Valid(sessionIdHeader) {
+		t.Errorf("expected a random session ID, got '%s'", sessionIdHeader)
 	}
 }
 
@@ -675,7 +828,7 @@ func TestRPCPostWithSessionIdHeader(t *testing.T) {
 	req := httptest.NewRequest("POST", "/transmission/rpc", bytes.NewBufferString(body))
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", basicAuthHeader("testuser", "testpass"))
-	req.Header.Set("X-Transmission-Session-Id", "useless-session-id")
+	req.Header.Set("X-Transmission-Session-Id", handler.sessionID())
 
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
@@ -704,7 +857,7 @@ func TestTorrentAddMagnetWithEncodedName(t *testing.T) {
 	}
 
 	// This will fail to add to put.io but shouldn't panic
-	_ = handler.handleTorrentAdd(req)
+	_, _ = handler.handleTorrentAdd(testLog(handler), req)
 }
 
 func TestTorrentAddMagnetWithoutName(t *testing.T) {
@@ -718,7 +871,50 @@ func TestTorrentAddMagnetWithoutName(t *testing.T) {
 	}
 
 	// This will fail to add to put.io but shouldn't panic
-	_ = handler.handleTorrentAdd(req)
+	_, _ = handler.handleTorrentAdd(testLog(handler), req)
+}
+
+func TestTorrentAddDuplicateMagnet(t *testing.T) {
+	handler := setupTestHandler()
+	existingHash := "C12FE1C06BBA254A9DC9F519B335AA7C1367A88A"
+	mockPutio := &mockPutioClient{
+		transfersResp: &putio.ListTransferResponse{Transfers: []putio.Transfer{
+			{ID: 1, Hash: &existingHash},
+		}},
+	}
+	handler.putioClient = mockPutio
+
+	duplicate := &transmission.Request{
+		Method:    "torrent-add",
+		Arguments: rawArgs(map[string]interface{}{"filename": "magnet:?xt=urn:btih:c12fe1c06bba254a9dc9f519b335aa7c1367a88a&dn=Show"}),
+	}
+	resp, err := handler.handleTorrentAdd(testLog(handler), duplicate)
+	if err != nil {
+		t.Fatalf("expected duplicate to succeed, got %v", err)
+	}
+	if resp == nil || resp.TorrentDuplicate == nil || resp.TorrentDuplicate.ID != 1 || resp.TorrentDuplicate.HashString != strings.ToLower(existingHash) {
+		t.Errorf("expected torrent-duplicate for the existing transfer, got %+v", resp)
+	}
+	if len(mockPutio.added) != 0 {
+		t.Errorf("expected duplicate not to be added, got %v", mockPutio.added)
+	}
+
+	fresh := &transmission.Request{
+		Method:    "torrent-add",
+		Arguments: rawArgs(map[string]interface{}{"filename": "magnet:?xt=urn:btih:0000000000000000000000000000000000000001&dn=Other"}),
+	}
+	newHash := "0000000000000000000000000000000000000001"
+	mockPutio.newTransfer = &putio.Transfer{ID: 2, Hash: &newHash}
+	resp, err = handler.handleTorrentAdd(testLog(handler), fresh)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp == nil || resp.TorrentAdded == nil || resp.TorrentAdded.ID != 2 || resp.TorrentAdded.Name != "Other" || resp.TorrentAdded.HashString != newHash {
+		t.Errorf("expected torrent-added for the new transfer, got %+v", resp)
+	}
+	if len(mockPutio.added) != 1 {
+		t.Errorf("expected new magnet to be added, got %v", mockPutio.added)
+	}
 }
 
 func TestRPCPostWithEmptyMethod(t *testing.T) {
@@ -729,12 +925,21 @@ func TestRPCPostWithEmptyMethod(t *testing.T) {
 	req := httptest.NewRequest("POST", "/transmission/rpc", bytes.NewBufferString(body))
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", basicAuthHeader("testuser", "testpass"))
+	req.Header.Set("X-Transmission-Session-Id", handler.sessionID())
 
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 
-	if w.Code != http.StatusBadRequest {
-		t.Errorf("expected status %d for empty method, got %d", http.StatusBadRequest, w.Code)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status %d for empty method, got %d", http.StatusOK, w.Code)
+	}
+
+	var resp transmission.Response
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if resp.Result != "no method name" {
+		t.Errorf("expected result 'no method name', got '%s'", resp.Result)
 	}
 }
 
@@ -746,12 +951,21 @@ func TestRPCPostWithWhitespaceMethod(t *testing.T) {
 	req := httptest.NewRequest("POST", "/transmission/rpc", bytes.NewBufferString(body))
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", basicAuthHeader("testuser", "testpass"))
+	req.Header.Set("X-Transmission-Session-Id", handler.sessionID())
 
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 
-	if w.Code != http.StatusBadRequest {
-		t.Errorf("expected status %d for whitespace method, got %d", http.StatusBadRequest, w.Code)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status %d for whitespace method, got %d", http.StatusOK, w.Code)
+	}
+
+	var resp transmission.Response
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if resp.Result != "no method name" {
+		t.Errorf("expected result 'no method name', got '%s'", resp.Result)
 	}
 }
 
@@ -765,7 +979,7 @@ func TestTorrentRemoveEmptyIDs(t *testing.T) {
 	}
 
 	// Should not error with nil arguments
-	err := handler.handleTorrentRemove(req)
+	err := handler.handleTorrentRemove(testLog(handler), req)
 	if err != nil {
 		t.Errorf("unexpected error for nil arguments: %v", err)
 	}
@@ -803,3 +1017,1047 @@ func TestHandlerConfigDownloadDirectory(t *testing.T) {
 		t.Errorf("expected DownloadDirectory '/custom/downloads', got '%s'", handler.config.DownloadDirectory)
 	}
 }
+
+func TestAuthenticateMultipleUsers(t *testing.T) {
+	handler := setupTestHandler()
+	handler.config.Users = []config.UserConfig{
+		{Username: "sonarr", Password: "sonarrpass"},
+		{Username: "dashboard", Token: "secret-token"},
+		{Username: "revoked", Password: "oldpass", Disabled: true},
+	}
+
+	tests := []struct {
+		name         string
+		auth         string
+		expectedUser string
+		expectedOK   bool
+	}{
+		{name: "top-level user", auth: basicAuthHeader("testuser", "testpass"), expectedUser: "testuser", expectedOK: true},
+		{name: "additional user", auth: basicAuthHeader("sonarr", "sonarrpass"), expectedUser: "sonarr", expectedOK: true},
+		{name: "additional user wrong password", auth: basicAuthHeader("sonarr", "testpass"), expectedOK: false},
+		{name: "bearer token", auth: "Bearer secret-token", expectedUser: "dashboard", expectedOK: true},
+		{name: "unknown bearer token", auth: "Bearer nope", expectedOK: false},
+		{name: "empty bearer token", auth: "Bearer ", expectedOK: false},
+		{name: "token-only user via basic auth", auth: basicAuthHeader("dashboard", ""), expectedOK: false},
+		{name: "disabled user", auth: basicAuthHeader("revoked", "oldpass"), expectedOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("GET", "/", nil)
+			c.Request.Header.Set("Authorization", tt.auth)
+
+			user, ok := handler.authenticate(c)
+			if ok != tt.expectedOK {
+				t.Fatalf("authenticate() ok = %v, expected %v", ok, tt.expectedOK)
+			}
+			if user != tt.expectedUser {
+				t.Errorf("authenticate() user = %q, expected %q", user, tt.expectedUser)
+			}
+		})
+	}
+}
+
+func TestRPCPostMethodErrorReportedInResult(t *testing.T) {
+	handler := setupTestHandler()
+	handler.putioClient = &mockPutioClient{addErr: errors.New("put.io is down")}
+	router := setupTestRouter(handler)
+
+	body := `{"method": "torrent-add", "arguments": {"filename": "magnet:?xt=urn:btih:abc"}, "tag": 7}`
+	req := httptest.NewRequest("POST", "/transmission/rpc", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", basicAuthHeader("testuser", "testpass"))
+	req.Header.Set("X-Transmission-Session-Id", handler.sessionID())
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var resp transmission.Response
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if resp.Result != "put.io is down" {
+		t.Errorf("expected result 'put.io is down', got '%s'", resp.Result)
+	}
+	if resp.Tag == nil || *resp.Tag != 7 {
+		t.Errorf("expected tag 7 to be echoed, got %v", resp.Tag)
+	}
+}
+
+func TestRPCPostInvalidMetainfoResult(t *testing.T) {
+	handler := setupTestHandler()
+	router := setupTestRouter(handler)
+
+	body := `{"method": "torrent-add", "arguments": {"metainfo": "!!!invalid!!!"}}`
+	req := httptest.NewRequest("POST", "/transmission/rpc", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", basicAuthHeader("testuser", "testpass"))
+	req.Header.Set("X-Transmission-Session-Id", handler.sessionID())
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var resp transmission.Response
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if !strings.HasPrefix(resp.Result, "invalid or corrupt torrent file") {
+		t.Errorf("expected corrupt torrent result, got '%s'", resp.Result)
+	}
+}
+
+func TestSessionGetAppliesPathMappings(t *testing.T) {
+	handler := setupTestHandler()
+	handler.config.PathMappings = map[string]string{"/downloads": "/data/downloads"}
+	router := setupTestRouter(handler)
+
+	req := httptest.NewRequest("POST", "/transmission/rpc", bytes.NewBufferString(`{"method": "session-get"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", basicAuthHeader("testuser", "testpass"))
+	req.Header.Set("X-Transmission-Session-Id", handler.sessionID())
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var resp struct {
+		Arguments transmission.Config `json:"arguments"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if resp.Arguments.DownloadDir != "/data/downloads" {
+		t.Errorf("expected download-dir '/data/downloads', got '%s'", resp.Arguments.DownloadDir)
+	}
+}
+
+func TestArrWebhook(t *testing.T) {
+	handler := setupTestHandler()
+	handler.container.Imports = app.NewImportTracker()
+	router := gin.New()
+	router.POST("/webhooks/arr", handler.ArrWebhook)
+
+	post := func(body, auth string) int {
+		req := httptest.NewRequest("POST", "/webhooks/arr", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	auth := basicAuthHeader("testuser", "testpass")
+	signal := handler.container.Imports.Wait("abcdef")
+
+	if code := post(`{"eventType": "Download", "downloadId": "ABCDEF"}`, ""); code != http.StatusUnauthorized {
+		t.Errorf("expected status %d without auth, got %d", http.StatusUnauthorized, code)
+	}
+	if code := post(`{"eventType": "Test"}`, auth); code != http.StatusOK {
+		t.Errorf("expected status %d for test event, got %d", http.StatusOK, code)
+	}
+	if code := post(`{"eventType": "Grab", "downloadId": "ABCDEF"}`, auth); code != http.StatusOK {
+		t.Errorf("expected status %d for grab event, got %d", http.StatusOK, code)
+	}
+	select {
+	case <-signal:
+		t.Fatal("did not expect grab event to mark import")
+	default:
+	}
+
+	handler.config.WebhookDownloadClient = "putio"
+	for _, body := range []string{
+		`{"eventType": "Download", "downloadId": "ABCDEF", "downloadClient": "qBittorrent", "downloadClientType": "QBittorrent"}`,
+		`{"eventType": "Download", "downloadId": "ABCDEF", "downloadClient": "other", "downloadClientType": "Transmission"}`,
+	} {
+		if code := post(body, auth); code != http.StatusOK {
+			t.Errorf("expected status %d for another client's import, got %d", http.StatusOK, code)
+		}
+	}
+	select {
+	case <-signal:
+		t.Fatal("did not expect another download client's import to signal")
+	default:
+	}
+
+	if code := post(`{"eventType": "Download", "downloadId": "ABCDEF", "downloadClient": "Putio", "downloadClientType": "Transmission"}`, auth); code != http.StatusOK {
+		t.Errorf("expected status %d for download event, got %d", http.StatusOK, code)
+	}
+	select {
+	case <-signal:
+	default:
+		t.Error("expected download event to signal the waiting transfer")
+	}
+
+	// Hashes nothing waits for are ignored.
+	if code := post(`{"eventType": "Download", "downloadId": "123456"}`, auth); code != http.StatusOK {
+		t.Errorf("expected status %d for an unknown transfer, got %d", http.StatusOK, code)
+	}
+	select {
+	case <-handler.container.Imports.Wait("123456"):
+		t.Error("did not expect an unknown transfer to be signaled")
+	default:
+	}
+
+	if code := post(`not json`, auth); code != http.StatusBadRequest {
+		t.Errorf("expected status %d for invalid JSON, got %d", http.StatusBadRequest, code)
+	}
+}
+
+type mockHistoryStore struct {
+	entries []history.Entry
+	err     error
+}
+
+func (m *mockHistoryStore) Record(entry history.Entry) error {
+	m.entries = append(m.entries, entry)
+	return m.err
+}
+
+func (m *mockHistoryStore) Query(q history.Query) ([]history.Entry, error) {
+	var entries []history.Entry
+	for _, e := range m.entries {
+		if q.Matches(e) {
+			entries = append(entries, e)
+		}
+	}
+	if q.Limit > 0 && q.Limit < len(entries) {
+		entries = entries[:q.Limit]
+	}
+	return entries, m.err
+}
+
+func TestHistory(t *testing.T) {
+	handler := setupTestHandler()
+	router := gin.New()
+	router.GET("/history", handler.History)
+
+	get := func(path, auth string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	auth := basicAuthHeader("testuser", "testpass")
+
+	if w := get("/history", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d without auth, got %d", http.StatusUnauthorized, w.Code)
+	}
+	if w := get("/history", auth); w.Code != http.StatusNotFound {
+		t.Errorf("expected status %d when history is disabled, got %d", http.StatusNotFound, w.Code)
+	}
+
+	handler.container.History = &mockHistoryStore{entries: []history.Entry{
+		{Name: "newer", Hash: "b", ImportedBy: "Sonarr"},
+		{Name: "older", Hash: "a"},
+	}}
+
+	w := get("/history?limit=1", auth)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	var resp struct {
+		Entries []history.Entry `json:"entries"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Entries) != 1 || resp.Entries[0].Name != "newer" || resp.Entries[0].ImportedBy != "Sonarr" {
+		t.Errorf("unexpected entries: %+v", resp.Entries)
+	}
+
+	w = get("/history?imported_by=sonarr&name=NEW", auth)
+	resp.Entries = nil
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Entries) != 1 || resp.Entries[0].Name != "newer" {
+		t.Errorf("expected filtered entries, got %+v", resp.Entries)
+	}
+
+	for _, query := range []string{"limit=abc", "since=yesterday", "until=2024-01-01"} {
+		if w := get("/history?"+query, auth); w.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d for %s, got %d", http.StatusBadRequest, query, w.Code)
+		}
+	}
+
+	handler.container.History = &mockHistoryStore{err: errors.New("disk error")}
+	if w := get("/history", auth); w.Code != http.StatusInternalServerError {
+		t.Errorf("expected status %d on store error, got %d", http.StatusInternalServerError, w.Code)
+	}
+}
+
+func TestTorrentGetReflectsLocalState(t *testing.T) {
+	handler := setupTestHandler()
+	store := app.NewTransferStore()
+	handler.container.Transfers = store
+
+	downloading, waiting, failed := "aaaa", "bbbb", "cccc"
+	size := int64(1000)
+	name := "Show"
+	handler.putioClient = &mockPutioClient{
+		transfersResp: &putio.ListTransferResponse{Transfers: []putio.Transfer{
+			{ID: 1, Hash: &downloading, Name: &name, Size: &size, Downloaded: &size, Status: "COMPLETED"},
+			{ID: 2, Hash: &waiting, Name: &name, Size: &size, Status: "SEEDING"},
+			{ID: 3, Hash: &failed, Name: &name, Size: &size, Downloaded: &size, Status: "COMPLETED"},
+		}},
+	}
+
+	store.Track("AAAA", name, size)
+	store.AddProgress("aaaa", 400)
+	store.Track("bbbb", name, size)
+	store.SetStage("bbbb", app.StageWaitingForImport)
+	store.Track("cccc", name, size)
+	store.Fail("cccc", "disk full")
+
+	resp, err := handler.handleTorrentGet()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.Torrents) != 3 {
+		t.Fatalf("expected 3 torrents, got %d", len(resp.Torrents))
+	}
+
+	got := resp.Torrents[0]
+	if got.Status != transmission.StatusDownloading || got.IsFinished || got.LeftUntilDone != 600 || got.DownloadedEver != 400 {
+		t.Errorf("expected local download progress, got %+v", got)
+	}
+
+	got = resp.Torrents[1]
+	if got.Status != transmission.StatusSeeding || got.LeftUntilDone != 0 {
+		t.Errorf("expected downloaded torrent waiting for import, got %+v", got)
+	}
+
+	got = resp.Torrents[2]
+	if got.Status != transmission.StatusStopped || got.ErrorString == nil || *got.ErrorString != "disk full" {
+		t.Errorf("expected failed torrent with error, got %+v", got)
+	}
+}
+
+func TestTorrentGetReportsFolderImports(t *testing.T) {
+	handler := setupTestHandler()
+	store := app.NewTransferStore()
+	handler.container.Transfers = store
+	handler.putioClient = &mockPutioClient{transfersResp: &putio.ListTransferResponse{}}
+
+	store.TrackFolderImport("aaaa", "Old Show", 1000)
+	store.AddProgress("aaaa", 250)
+	store.TrackFolderImport("bbbb", "Older Show", 500)
+	store.SetStage("bbbb", app.StageWaitingForImport)
+	// Transfers of put.io are only reported while put.io lists them.
+	store.Track("cccc", "Gone", 100)
+
+	resp, err := handler.handleTorrentGet()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.Torrents) != 2 {
+		t.Fatalf("expected the 2 folder imports, got %+v", resp.Torrents)
+	}
+
+	got := resp.Torrents[0]
+	if *got.HashString != "aaaa" || got.Name != "Old Show" || got.IsFinished || got.LeftUntilDone != 750 {
+		t.Errorf("expected a folder import being downloaded, got %+v", got)
+	}
+	got = resp.Torrents[1]
+	if *got.HashString != "bbbb" || !got.IsFinished || got.LeftUntilDone != 0 || got.Status != transmission.StatusStopped {
+		t.Errorf("expected a finished folder import, got %+v", got)
+	}
+}
+
+func TestApplyLocalStateKeepsUnpackingUnfinished(t *testing.T) {
+	torrent := &transmission.Torrent{TotalSize: 100, Status: transmission.StatusStopped, IsFinished: true}
+	applyLocalState(torrent, app.TransferState{Stage: app.StageUnpacking, Size: 100, Downloaded: 100})
+
+	if torrent.Status != transmission.StatusDownloading || torrent.IsFinished || torrent.LeftUntilDone != 1 {
+		t.Errorf("expected unpacking torrent to stay unfinished, got %+v", torrent)
+	}
+}
+
+func TestApplyLocalStateReportsQueuedWithError(t *testing.T) {
+	torrent := &transmission.Torrent{TotalSize: 100, Status: transmission.StatusSeeding, IsFinished: true}
+	applyLocalState(torrent, app.TransferState{Stage: app.StageQueued, Size: 100, Downloaded: 40, Error: "waiting for free space"})
+
+	if torrent.Status != transmission.StatusQueued || torrent.IsFinished || torrent.LeftUntilDone != 60 {
+		t.Errorf("expected a queued, unfinished torrent, got %+v", torrent)
+	}
+	if torrent.ErrorString == nil || *torrent.ErrorString != "waiting for free space" {
+		t.Errorf("expected the error to be reported, got %v", torrent.ErrorString)
+	}
+}
+
+func TestApplyLocalStateETAFromLocalRate(t *testing.T) {
+	torrent := &transmission.Torrent{TotalSize: 1000, Status: transmission.StatusSeeding, IsFinished: true}
+	applyLocalState(torrent, app.TransferState{Stage: app.StageDownloading, Size: 1000, Downloaded: 400, Rate: 20})
+
+	if torrent.ETA != 30 || torrent.RateDownload != 20 {
+		t.Errorf("expected a 30s ETA at 20 B/s, got %d at %d B/s", torrent.ETA, torrent.RateDownload)
+	}
+
+	torrent = &transmission.Torrent{TotalSize: 1000}
+	applyLocalState(torrent, app.TransferState{Stage: app.StageDownloading, Size: 1000, Downloaded: 400})
+	if torrent.ETA != -1 {
+		t.Errorf("expected an unknown ETA before the rate is measured, got %d", torrent.ETA)
+	}
+}
+
+func TestAddLocalETA(t *testing.T) {
+	tests := []struct {
+		name    string
+		torrent transmission.Torrent
+		rate    int64
+		want    int64
+	}{
+		{name: "put.io downloading", torrent: transmission.Torrent{Status: transmission.StatusDownloading, ETA: 60, TotalSize: 1200}, rate: 10, want: 180},
+		{name: "rate unknown", torrent: transmission.Torrent{Status: transmission.StatusDownloading, ETA: 60, TotalSize: 1200}, want: 60},
+		{name: "put.io ETA unknown", torrent: transmission.Torrent{Status: transmission.StatusDownloading, TotalSize: 1200}, rate: 10, want: 0},
+		{name: "not downloading", torrent: transmission.Torrent{Status: transmission.StatusQueued, ETA: 60, TotalSize: 1200}, rate: 10, want: 60},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			torrent := tt.torrent
+			addLocalETA(&torrent, tt.rate)
+			if torrent.ETA != tt.want {
+				t.Errorf("expected ETA %d, got %d", tt.want, torrent.ETA)
+			}
+		})
+	}
+}
+
+func TestHandleTorrentGetETAWhenIdle(t *testing.T) {
+	handler := setupTestHandler()
+	store := app.NewTransferStore()
+	handler.container.Transfers = store
+
+	// A finished local download leaves no current rate behind.
+	store.Track("aaaa", "Done", 1000)
+	store.AddProgress("aaaa", 1000)
+	store.Forget("aaaa")
+	if rate := store.CurrentRate(); rate != 0 {
+		t.Fatalf("expected no current rate, got %d", rate)
+	}
+
+	hash, name := "bbbb", "Show"
+	size, eta := int64(10000), int64(60)
+	handler.putioClient = &mockPutioClient{
+		transfersResp: &putio.ListTransferResponse{Transfers: []putio.Transfer{
+			{ID: 1, Hash: &hash, Name: &name, Size: &size, Status: "DOWNLOADING", EstimatedTime: &eta},
+		}},
+	}
+
+	resp, err := handler.handleTorrentGet()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := resp.Torrents[0].ETA; got != eta+10 {
+		t.Errorf("expected the ETA to include the local download at the last rate, got %d", got)
+	}
+}
+
+func TestTorrentAddPausedHoldsUntilStarted(t *testing.T) {
+	handler := setupTestHandler()
+	holds, _ := app.NewHoldRegistry("")
+	handler.container.Holds = holds
+	hash := "c12fe1c06bba254a9dc9f519b335aa7c1367a88a"
+	other := "dddd"
+	name := "Show"
+	size := int64(1000)
+	client := &mockPutioClient{newTransfer: &putio.Transfer{ID: 9, Hash: &hash}}
+	handler.putioClient = client
+
+	req := &transmission.Request{
+		Method:    "torrent-add",
+		Arguments: rawArgs(map[string]interface{}{"filename": "magnet:?xt=urn:btih:" + hash + "&dn=Show", "paused": true}),
+	}
+	if _, err := handler.handleTorrentAdd(testLog(handler), req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(client.added) != 1 {
+		t.Fatalf("expected the paused transfer to be added to put.io, got %v", client.added)
+	}
+	if !holds.Has(hash) {
+		t.Fatal("expected the paused transfer to be held")
+	}
+
+	client.transfersResp = &putio.ListTransferResponse{Transfers: []putio.Transfer{
+		{ID: 9, Hash: &hash, Name: &name, Size: &size, Downloaded: &size, Status: "COMPLETED"},
+		{ID: 10, Hash: &other, Name: &name, Size: &size, Downloaded: &size, Status: "COMPLETED"},
+	}}
+	resp, err := handler.handleTorrentGet()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := resp.Torrents[0]
+	if got.Status != transmission.StatusStopped || got.IsFinished || got.LeftUntilDone != size {
+		t.Errorf("expected held torrent to be stopped and unfinished, got %+v", got)
+	}
+	if resp.Torrents[1].LeftUntilDone != 0 {
+		t.Errorf("expected torrent added without paused to be unaffected, got %+v", resp.Torrents[1])
+	}
+
+	tests := []struct {
+		name string
+		ids  interface{}
+		held bool
+	}{
+		{name: "other torrent", ids: []int{10}, held: true},
+		{name: "by id", ids: []int{9}, held: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := &transmission.Request{Method: "torrent-start", Arguments: rawArgs(map[string]interface{}{"ids": tt.ids})}
+			if _, err := handler.dispatch(testLog(handler), start, "testuser"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if holds.Has(hash) != tt.held {
+				t.Errorf("expected held=%v", tt.held)
+			}
+		})
+	}
+}
+
+func TestTorrentStartAllReleasesPendingTorrents(t *testing.T) {
+	handler := setupTestHandler()
+	holds, _ := app.NewHoldRegistry("")
+	handler.container.Holds = holds
+
+	req := &transmission.Request{
+		Method:    "torrent-add",
+		Arguments: rawArgs(map[string]interface{}{"filename": "magnet:?xt=urn:btih:C12FE1C06BBA254A9DC9F519B335AA7C1367A88A&dn=Show", "paused": true}),
+	}
+	if _, err := handler.handleTorrentAdd(testLog(handler), req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	resp, err := handler.handleTorrentGet()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.Torrents) != 1 || resp.Torrents[0].Status != transmission.StatusStopped {
+		t.Fatalf("expected the pending torrent to be stopped, got %+v", resp.Torrents)
+	}
+
+	if _, err := handler.dispatch(testLog(handler), &transmission.Request{Method: "torrent-start-now"}, "testuser"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(holds.List()) != 0 {
+		t.Errorf("expected all holds to be released, got %v", holds.List())
+	}
+}
+
+func TestTorrentStopHoldsTransfersNotYetDownloading(t *testing.T) {
+	handler := setupTestHandler()
+	holds, _ := app.NewHoldRegistry("")
+	handler.container.Holds = holds
+	waiting, downloading := "aaaa", "bbbb"
+	name := "Show"
+	size := int64(1000)
+	handler.putioClient = &mockPutioClient{transfersResp: &putio.ListTransferResponse{Transfers: []putio.Transfer{
+		{ID: 1, Hash: &waiting, Name: &name, Size: &size, Status: "DOWNLOADING"},
+		{ID: 2, Hash: &downloading, Name: &name, Size: &size, Downloaded: &size, Status: "COMPLETED"},
+	}}}
+	handler.container.Transfers = app.NewTransferStore()
+	handler.container.Transfers.Track(downloading, name, size)
+
+	stop := &transmission.Request{Method: "torrent-stop", Arguments: rawArgs(map[string]interface{}{"ids": []int{1, 2}})}
+	if _, err := handler.dispatch(testLog(handler), stop, "testuser"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := holds.List(); !reflect.DeepEqual(got, []string{waiting}) {
+		t.Fatalf("expected only the transfer not yet downloading to be held, got %v", got)
+	}
+
+	resp, err := handler.handleTorrentGet()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Torrents[0].Status != transmission.StatusStopped {
+		t.Errorf("expected the stopped torrent to be reported as stopped, got %+v", resp.Torrents[0])
+	}
+
+	start := &transmission.Request{Method: "torrent-start", Arguments: rawArgs(map[string]interface{}{"ids": []int{1}})}
+	if _, err := handler.dispatch(testLog(handler), start, "testuser"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if holds.Has(waiting) {
+		t.Error("expected torrent-start to release the stopped torrent")
+	}
+}
+
+func TestTorrentAddRecordsLabels(t *testing.T) {
+	handler := setupTestHandler()
+	handler.config.PathMappings = map[string]string{"/downloads": "/data/downloads"}
+	handler.config.Labels = map[string]config.LabelConfig{"tv-sonarr": {Subdirectory: "tv"}}
+	labels, err := app.NewLabelRegistry("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	handler.container.Labels = labels
+	hash := "c12fe1c06bba254a9dc9f519b335aa7c1367a88a"
+	client := &mockPutioClient{newTransfer: &putio.Transfer{ID: 9, Hash: &hash}}
+	handler.putioClient = client
+
+	req := &transmission.Request{
+		Method:    "torrent-add",
+		Arguments: rawArgs(map[string]interface{}{"filename": "magnet:?xt=urn:btih:" + hash + "&dn=Show", "labels": []string{"tv-sonarr"}}),
+	}
+	if _, err := handler.handleTorrentAdd(testLog(handler), req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := labels.Get(hash); !reflect.DeepEqual(got, []string{"tv-sonarr"}) {
+		t.Fatalf("expected the labels to be recorded, got %v", got)
+	}
+
+	client.transfersResp = &putio.ListTransferResponse{Transfers: []putio.Transfer{{ID: 9, Hash: &hash, Status: "DOWNLOADING"}}}
+	resp, err := handler.handleTorrentGet()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := resp.Torrents[0]
+	if got.DownloadDir != "/data/downloads/tv" {
+		t.Errorf("expected the label's subdirectory in downloadDir, got %q", got.DownloadDir)
+	}
+	if !reflect.DeepEqual(got.Labels, []string{"tv-sonarr"}) {
+		t.Errorf("expected the labels to be reported, got %v", got.Labels)
+	}
+}
+
+func TestTorrentSetLocation(t *testing.T) {
+	handler := setupTestHandler()
+	handler.config.PathMappings = map[string]string{"/downloads": "/data/downloads"}
+	handler.container.Locations, _ = app.NewLocationRegistry("")
+	handler.container.Transfers = app.NewTransferStore()
+	hashA, hashB := "aaa", "bbb"
+	handler.putioClient = &mockPutioClient{transfersResp: &putio.ListTransferResponse{Transfers: []putio.Transfer{
+		{ID: 1, Hash: &hashA, Status: "DOWNLOADING"},
+		{ID: 2, Hash: &hashB, Status: "COMPLETED"},
+	}}}
+	// The download manager already picked up the second transfer.
+	handler.container.Transfers.Track(hashB, "b", 10)
+
+	setLocation := func(location string) error {
+		req := &transmission.Request{
+			Method:    "torrent-set-location",
+			Arguments: rawArgs(map[string]interface{}{"ids": []interface{}{1, "bbb"}, "location": location, "move": true}),
+		}
+		_, err := handler.dispatch(testLog(handler), req, "testuser")
+		return err
+	}
+
+	if err := setLocation("/data/downloads/tv"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dir, ok := handler.container.Locations.Get(hashA); !ok || dir != filepath.Join("/downloads", "tv") {
+		t.Errorf("expected the local location to be recorded, got %q %v", dir, ok)
+	}
+	if _, ok := handler.container.Locations.Get(hashB); ok {
+		t.Error("expected the transfer being downloaded to keep its location")
+	}
+
+	resp, err := handler.handleTorrentGet()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Torrents[0].DownloadDir != "/data/downloads/tv" || resp.Torrents[1].DownloadDir != "/data/downloads" {
+		t.Errorf("expected the location in downloadDir, got %q and %q", resp.Torrents[0].DownloadDir, resp.Torrents[1].DownloadDir)
+	}
+
+	if err := setLocation("/data/downloads"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := handler.container.Locations.Get(hashA); ok {
+		t.Error("expected moving back to the download directory to forget the location")
+	}
+
+	if err := setLocation("/elsewhere"); err == nil {
+		t.Error("expected locations outside the download directory to be refused")
+	}
+	if err := setLocation(""); err == nil {
+		t.Error("expected a missing location to be refused")
+	}
+}
+
+func TestSessionSetPlacesNewTorrents(t *testing.T) {
+	handler := setupTestHandler()
+	handler.container.Locations, _ = app.NewLocationRegistry("")
+	hash := "c12fe1c06bba254a9dc9f519b335aa7c1367a88a"
+
+	req := &transmission.Request{
+		Method:    "session-set",
+		Arguments: rawArgs(map[string]interface{}{"download-dir": "/downloads/tv", "seedRatioLimit": 2}),
+	}
+	if _, err := handler.dispatch(testLog(handler), req, "testuser"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	req = &transmission.Request{
+		Method:    "torrent-add",
+		Arguments: rawArgs(map[string]interface{}{"filename": "magnet:?xt=urn:btih:" + hash + "&dn=Show"}),
+	}
+	if _, err := handler.handleTorrentAdd(testLog(handler), req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dir, ok := handler.container.Locations.Get(hash); !ok || dir != filepath.Join("/downloads", "tv") {
+		t.Errorf("expected the new torrent in the session's download directory, got %q %v", dir, ok)
+	}
+
+	resp, err := handler.handleTorrentGet()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.Torrents) != 1 || resp.Torrents[0].DownloadDir != "/downloads/tv" || resp.Torrents[0].SeedRatioLimit != 2 {
+		t.Errorf("expected the session's directory and seed ratio, got %+v", resp.Torrents)
+	}
+}
+
+func TestTransfersReportsRemoteAndLocalProgress(t *testing.T) {
+	handler := setupTestHandler()
+	handler.container.Transfers = app.NewTransferStore()
+	hashA, hashB := "AAAA", "bbbb"
+	nameA, nameB := "Show", "Movie"
+	size, half := int64(1000), int64(500)
+	handler.putioClient = &mockPutioClient{transfersResp: &putio.ListTransferResponse{Transfers: []putio.Transfer{
+		{ID: 1, Hash: &hashA, Name: &nameA, Size: &size, Downloaded: &size, Status: "COMPLETED"},
+		{ID: 2, Hash: &hashB, Name: &nameB, Size: &size, Downloaded: &half, Status: "DOWNLOADING", DownSpeed: 50},
+	}}}
+	handler.container.Transfers.Track(hashA, nameA, size)
+	handler.container.Transfers.AddProgress(hashA, 250)
+	handler.container.Transfers.TrackFolderImport("cccc", "Folder item", 10)
+
+	router := gin.New()
+	router.GET("/transfers", handler.Transfers)
+	req := httptest.NewRequest("GET", "/transfers", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d without auth, got %d", http.StatusUnauthorized, w.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/transfers", nil)
+	req.Header.Set("Authorization", basicAuthHeader("testuser", "testpass"))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	var resp struct {
+		Transfers []transferProgress `json:"transfers"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if len(resp.Transfers) != 3 {
+		t.Fatalf("expected 3 transfers, got %+v", resp.Transfers)
+	}
+
+	show := resp.Transfers[0]
+	if show.Hash != "aaaa" || show.Remote.PercentDone != 1 || show.Local == nil || show.Local.PercentDone != 0.25 {
+		t.Errorf("expected put.io done and the local download a quarter done, got %+v %+v %+v", show, show.Remote, show.Local)
+	}
+	movie := resp.Transfers[1]
+	if movie.Remote.PercentDone != 0.5 || movie.Remote.Rate != 50 || movie.Local != nil {
+		t.Errorf("expected put.io halfway and no local progress, got %+v %+v", movie, movie.Remote)
+	}
+	folder := resp.Transfers[2]
+	if folder.Remote != nil || folder.Local == nil || folder.Name != "Folder item" {
+		t.Errorf("expected the folder import item with local progress only, got %+v", folder)
+	}
+}
+
+func TestPauseDownloads(t *testing.T) {
+	handler := setupTestHandler()
+	handler.container.Pause = app.NewPauseSwitch()
+	router := gin.New()
+	router.POST("/pause", handler.PauseDownloads)
+	router.POST("/resume", handler.ResumeDownloads)
+
+	post := func(path string, auth bool) (int, bool) {
+		req := httptest.NewRequest("POST", path, nil)
+		if auth {
+			req.Header.Set("Authorization", basicAuthHeader("testuser", "testpass"))
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp struct {
+			Paused bool `json:"paused"`
+		}
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp.Paused
+	}
+
+	if code, _ := post("/pause", false); code != http.StatusUnauthorized {
+		t.Errorf("expected status %d without auth, got %d", http.StatusUnauthorized, code)
+	}
+	if handler.container.Pause.Paused() {
+		t.Fatal("expected an unauthenticated request not to pause downloads")
+	}
+	if code, paused := post("/pause", true); code != http.StatusOK || !paused {
+		t.Errorf("expected downloads to be paused, got %d %v", code, paused)
+	}
+
+	session, err := handler.dispatch(testLog(handler), &transmission.Request{Method: "session-get"}, "testuser")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !session.(*transmission.Config).AltSpeedEnabled {
+		t.Error("expected session-get to report the pause as turtle mode")
+	}
+
+	if code, paused := post("/resume", true); code != http.StatusOK || paused {
+		t.Errorf("expected downloads to be resumed, got %d %v", code, paused)
+	}
+}
+
+func TestSessionSetAltSpeedPausesDownloads(t *testing.T) {
+	handler := setupTestHandler()
+	handler.container.Pause = app.NewPauseSwitch()
+
+	setAltSpeed := func(enabled bool) {
+		req := &transmission.Request{
+			Method:    "session-set",
+			Arguments: rawArgs(map[string]interface{}{"alt-speed-enabled": enabled}),
+		}
+		if _, err := handler.dispatch(testLog(handler), req, "testuser"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	setAltSpeed(true)
+	if !handler.container.Pause.Paused() {
+		t.Error("expected turtle mode to pause downloads")
+	}
+	setAltSpeed(false)
+	if handler.container.Pause.Paused() {
+		t.Error("expected leaving turtle mode to resume downloads")
+	}
+}
+
+func TestStats(t *testing.T) {
+	handler := setupTestHandler()
+	calls := putio.NewCallCounter()
+	calls.Record("transfers/list")
+	calls.Record("transfers/list")
+	handler.container.PutioCalls = calls
+	handler.container.Downloads = app.NewDownloadCounters()
+	handler.container.Downloads.Stalled()
+	handler.container.Pipeline = app.NewPipelineStats()
+	handler.container.Pipeline.Downloaded(1000)
+	handler.container.Pipeline.Completed(time.Minute)
+	router := gin.New()
+	router.GET("/stats", handler.Stats)
+
+	req := httptest.NewRequest("GET", "/stats", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d without auth, got %d", http.StatusUnauthorized, w.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/stats", nil)
+	req.Header.Set("Authorization", basicAuthHeader("testuser", "testpass"))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	var resp struct {
+		PutioCalls putioCallStats               `json:"putio_calls"`
+		Downloads  app.DownloadCountersSnapshot `json:"downloads"`
+		Pipeline   app.PipelineSnapshot         `json:"pipeline"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if resp.PutioCalls.Total["transfers/list"] != 2 || resp.PutioCalls.LastMinute == nil {
+		t.Errorf("unexpected stats %+v", resp.PutioCalls)
+	}
+	if resp.Downloads.Stalled != 1 {
+		t.Errorf("unexpected download stats %+v", resp.Downloads)
+	}
+	if day := resp.Pipeline.LastDay; day.BytesDownloaded != 1000 || day.Completed != 1 || day.AverageImportTime != 60 {
+		t.Errorf("unexpected pipeline stats %+v", resp.Pipeline)
+	}
+}
+
+func TestTorrentRemoveKeepsFilesWhenStreaming(t *testing.T) {
+	for _, mode := range []string{config.DownloadModeDownload, config.DownloadModeStrm} {
+		t.Run(mode, func(t *testing.T) {
+			handler := setupTestHandler()
+			handler.config.DownloadMode = mode
+			hash := "abc123"
+			fileID := int64(7)
+			client := &mockPutioClient{transfersResp: &putio.ListTransferResponse{Transfers: []putio.Transfer{
+				{ID: 1, Hash: &hash, FileID: &fileID, UserfileExists: true},
+			}}}
+			handler.putioClient = client
+
+			req := &transmission.Request{
+				Method:    "torrent-remove",
+				Arguments: json.RawMessage(`{"ids": ["abc123"], "delete-local-data": true}`),
+			}
+			if err := handler.handleTorrentRemove(testLog(handler), req); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			wantDeleted := mode == config.DownloadModeDownload
+			if deleted := len(client.deleted) == 1; deleted != wantDeleted {
+				t.Errorf("expected put.io files deleted: %v, got %v", wantDeleted, client.deleted)
+			}
+		})
+	}
+}
+
+func TestMagnetTransferMatchedBeforePutioKnowsHash(t *testing.T) {
+	handler := setupTestHandler()
+	client := &mockPutioClient{newTransfer: &putio.Transfer{ID: 9}}
+	handler.putioClient = client
+
+	add := &transmission.Request{
+		Method:    "torrent-add",
+		Arguments: rawArgs(map[string]interface{}{"filename": "magnet:?xt=urn:btih:C12FE1C06BBA254A9DC9F519B335AA7C1367A88A&dn=Show&tr=udp%3A%2F%2Fa%3A80&tr=udp%3A%2F%2Fb%3A80"}),
+	}
+	if _, err := handler.handleTorrentAdd(testLog(handler), add); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// put.io lists the transfer before it has fetched the metadata
+	client.transfersResp = &putio.ListTransferResponse{Transfers: []putio.Transfer{{ID: 9}}}
+	resp, err := handler.handleTorrentGet()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.Torrents) != 1 || resp.Torrents[0].HashString == nil || *resp.Torrents[0].HashString != "c12fe1c06bba254a9dc9f519b335aa7c1367a88a" {
+		t.Fatalf("expected one torrent with the magnet's hash, got %+v", resp.Torrents)
+	}
+
+	remove := &transmission.Request{
+		Method:    "torrent-remove",
+		Arguments: json.RawMessage(`{"ids": ["c12fe1c06bba254a9dc9f519b335aa7c1367a88a"]}`),
+	}
+	if err := handler.handleTorrentRemove(testLog(handler), remove); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(client.removed) != 1 || client.removed[0] != 9 {
+		t.Errorf("expected transfer 9 to be removed, got %v", client.removed)
+	}
+}
+
+func TestTorrentRemoveMatchesIDs(t *testing.T) {
+	hash := "c12fe1c06bba254a9dc9f519b335aa7c1367a88a"
+	other := "0000000000000000000000000000000000000001"
+	tests := []struct {
+		name string
+		args string
+		want []uint64
+	}{
+		{name: "uppercase hash", args: `{"ids": ["C12FE1C06BBA254A9DC9F519B335AA7C1367A88A"]}`, want: []uint64{1}},
+		{name: "numeric id", args: `{"ids": [2]}`, want: []uint64{2}},
+		{name: "numeric id as string", args: `{"ids": ["2"]}`, want: []uint64{2}},
+		{name: "single id", args: `{"ids": 1}`, want: []uint64{1}},
+		{name: "no match", args: `{"ids": ["ffff"]}`, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := setupTestHandler()
+			client := &mockPutioClient{transfersResp: &putio.ListTransferResponse{Transfers: []putio.Transfer{
+				{ID: 1, Hash: &hash},
+				{ID: 2, Hash: &other},
+			}}}
+			handler.putioClient = client
+
+			req := &transmission.Request{Method: "torrent-remove", Arguments: json.RawMessage(tt.args)}
+			if err := handler.handleTorrentRemove(testLog(handler), req); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(client.removed, tt.want) {
+				t.Errorf("expected %v removed, got %v", tt.want, client.removed)
+			}
+		})
+	}
+}
+
+func TestTorrentRemoveForgetsFailedState(t *testing.T) {
+	handler := setupTestHandler()
+	store := app.NewTransferStore()
+	handler.container.Transfers = store
+	failed := "aaaa"
+	store.Track(failed, "Show", 1)
+	store.Fail(failed, "local download failed")
+	handler.putioClient = &mockPutioClient{transfersResp: &putio.ListTransferResponse{Transfers: []putio.Transfer{
+		{ID: 1, Hash: &failed},
+	}}}
+
+	req := &transmission.Request{Method: "torrent-remove", Arguments: json.RawMessage(`{"ids": [1]}`)}
+	if err := handler.handleTorrentRemove(testLog(handler), req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := store.Get(failed); ok {
+		t.Error("expected the failed state to be dropped once the arr removed the transfer")
+	}
+}
+
+func TestTorrentRemoveFallsBackToSingleRemovals(t *testing.T) {
+	handler := setupTestHandler()
+	labels, _ := app.NewLabelRegistry("")
+	handler.container.Labels = labels
+	kept, gone := "aaaa", "bbbb"
+	labels.Set(kept, []string{"tv"})
+	labels.Set(gone, []string{"tv"})
+	client := &mockPutioClient{
+		transfersResp: &putio.ListTransferResponse{Transfers: []putio.Transfer{
+			{ID: 1, Hash: &kept},
+			{ID: 2, Hash: &gone},
+		}},
+		removeErrs: map[uint64]error{1: errors.New("boom")},
+	}
+	handler.putioClient = client
+
+	req := &transmission.Request{Method: "torrent-remove", Arguments: json.RawMessage(`{"ids": [1, 2]}`)}
+	err := handler.handleTorrentRemove(testLog(handler), req)
+	if err == nil || !strings.Contains(err.Error(), "transfer 1") {
+		t.Errorf("expected the failure to name transfer 1, got %v", err)
+	}
+	if !reflect.DeepEqual(client.removed, []uint64{2}) {
+		t.Errorf("expected transfer 2 to be removed on its own, got %v", client.removed)
+	}
+	if len(labels.Get(kept)) == 0 {
+		t.Error("expected the labels of the transfer put.io kept to stay")
+	}
+	if len(labels.Get(gone)) != 0 {
+		t.Error("expected the labels of the removed transfer to be forgotten")
+	}
+}
+
+func TestDecodeMetainfo(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		wantErr  bool
+	}{
+		{name: "plain", input: base64.StdEncoding.EncodeToString([]byte("d4:infod4:name4:testee")), expected: "d4:infod4:name4:testee"},
+		{name: "line wrapped", input: "ZDQ6aW5mb2Q0Om5h\r\nbWU0OnRlc3RlZQ==", expected: "d4:infod4:name4:testee"},
+		{name: "empty", input: "", expected: ""},
+		{name: "invalid", input: "!!!invalid-base64!!!", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := decodeMetainfo(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if string(data) != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, data)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ochronus/goputioarr/internal/history"
+)
+
+// History returns the completed transfers recorded in the download history,
+// newest first. The optional query parameters "name" (substring), "hash",
+// "imported_by", "since" and "until" (RFC 3339) filter the entries and
+// "limit" caps their number.
+func (h *Handler) History(c *gin.Context) {
+	_, ok, locked := h.login(c)
+	if locked {
+		c.Status(http.StatusTooManyRequests)
+		return
+	}
+	if !ok {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	if h.container.History == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "history is disabled"})
+		return
+	}
+
+	query, err := historyQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entries, err := h.container.History.Query(query)
+	if err != nil {
+		h.logger.Errorf("Failed to read history: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read history"})
+		return
+	}
+	if entries == nil {
+		entries = []history.Entry{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"entries": entries})
+}
+
+// historyQuery builds a history query from the request's query parameters.
+func historyQuery(c *gin.Context) (history.Query, error) {
+	query := history.Query{
+		Name:       c.Query("name"),
+		Hash:       c.Query("hash"),
+		ImportedBy: c.Query("imported_by"),
+	}
+	if limitStr := c.Query("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 0 {
+			return query, fmt.Errorf("invalid limit")
+		}
+		query.Limit = limit
+	}
+	for param, t := range map[string]*time.Time{"since": &query.Since, "until": &query.Until} {
+		if value := c.Query(param); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return query, fmt.Errorf("invalid %s, expected an RFC 3339 time", param)
+			}
+			*t = parsed
+		}
+	}
+	return query, nil
+}
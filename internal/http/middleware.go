@@ -0,0 +1,214 @@
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ochronus/goputioarr/internal/services/httpdump"
+	"github.com/sirupsen/logrus"
+)
+
+// allowNetworks rejects requests whose client IP is not inside one of the
+// given networks. An empty list allows every client.
+func allowNetworks(networks []*net.IPNet, logger *logrus.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if len(networks) == 0 {
+			c.Next()
+			return
+		}
+
+		ip := net.ParseIP(c.ClientIP())
+		if ip != nil {
+			for _, network := range networks {
+				if network.Contains(ip) {
+					c.Next()
+					return
+				}
+			}
+		}
+
+		logger.Warnf("Rejected request from %s: not in allowed_networks", c.ClientIP())
+		c.AbortWithStatus(http.StatusForbidden)
+	}
+}
+
+// gzipWriter compresses the response body. The gzip stream is only started on
+// the first write so bodyless responses (e.g. the 409 session handshake) are
+// left untouched.
+type gzipWriter struct {
+	gin.ResponseWriter
+	gz *gzip.Writer
+}
+
+func (w *gzipWriter) Write(data []byte) (int, error) {
+	if w.gz == nil {
+		header := w.Header()
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+		w.gz = gzip.NewWriter(w.ResponseWriter)
+	}
+	return w.gz.Write(data)
+}
+
+func (w *gzipWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *gzipWriter) close() {
+	if w.gz != nil {
+		w.gz.Close()
+	}
+}
+
+// gzipResponses compresses responses for clients that send Accept-Encoding: gzip.
+// Files served over WebDAV are left alone: they're mostly video, and
+// compressing them would break range requests.
+func gzipResponses() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if strings.HasPrefix(c.Request.URL.Path, webdavPrefix) {
+			c.Next()
+			return
+		}
+		c.Header("Vary", "Accept-Encoding")
+		if !acceptsGzip(c.GetHeader("Accept-Encoding")) {
+			c.Next()
+			return
+		}
+
+		writer := &gzipWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		defer writer.close()
+
+		c.Next()
+	}
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip.
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		coding := strings.ToLower(strings.TrimSpace(fields[0]))
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+		rejected := false
+		for _, param := range fields[1:] {
+			param = strings.ReplaceAll(strings.TrimSpace(param), " ", "")
+			if param == "q=0" || param == "q=0.0" || param == "q=0.00" || param == "q=0.000" {
+				rejected = true
+			}
+		}
+		if !rejected {
+			return true
+		}
+	}
+	return false
+}
+
+// limitBodySize rejects request bodies larger than maxBytes. Zero disables the limit.
+func limitBodySize(maxBytes int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if maxBytes <= 0 {
+			c.Next()
+			return
+		}
+		if c.Request.ContentLength > maxBytes {
+			c.AbortWithStatus(http.StatusRequestEntityTooLarge)
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
+		c.Next()
+	}
+}
+
+// requestIDKey is the gin context key holding the request's correlation ID.
+const requestIDKey = "request_id"
+
+// requestID assigns each request a correlation ID, reusing a sane
+// X-Request-Id sent by the client (or a proxy in front of it), and echoes it
+// in the response.
+func requestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader("X-Request-Id")
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		c.Set(requestIDKey, id)
+		c.Header("X-Request-Id", id)
+		c.Next()
+	}
+}
+
+// validRequestID reports whether id is short and safe to put in a log line.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, r := range id {
+		if !(r == '-' || r == '_' || r == '.' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// requestLogger returns a logger that tags each line with the request's
+// correlation ID.
+func (h *Handler) requestLogger(c *gin.Context) *logrus.Entry {
+	return h.logger.WithField(requestIDKey, c.GetString(requestIDKey))
+}
+
+// dumpWriter keeps a copy of the response body for dumpTraffic.
+type dumpWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *dumpWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *dumpWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// dumpTraffic logs the redacted request and response at trace level.
+func dumpTraffic(logger *logrus.Logger, name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !httpdump.Enabled(logger) {
+			c.Next()
+			return
+		}
+
+		log := logger.WithField(requestIDKey, c.GetString(requestIDKey))
+		if dump, err := httputil.DumpRequest(c.Request, true); err == nil {
+			httpdump.Log(log, name+" request", dump)
+		}
+
+		writer := &dumpWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+
+		var dump bytes.Buffer
+		fmt.Fprintf(&dump, "%s %d %s\r\n", c.Request.Proto, writer.Status(), http.StatusText(writer.Status()))
+		writer.Header().Write(&dump)
+		dump.WriteString("\r\n")
+		dump.Write(writer.body.Bytes())
+		httpdump.Log(log, name+" response", dump.Bytes())
+	}
+}
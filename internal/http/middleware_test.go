@@ -0,0 +1,326 @@
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ochronus/goputioarr/internal/config"
+	"github.com/ochronus/goputioarr/internal/services/transmission"
+	"github.com/sirupsen/logrus"
+)
+
+func TestAllowNetworks(t *testing.T) {
+	networks, err := config.ParseNetworks([]string{"192.168.1.0/24", "10.0.0.5"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		networks   []string
+		remoteAddr string
+		expected   int
+	}{
+		{name: "inside cidr", remoteAddr: "192.168.1.20:1234", expected: http.StatusOK},
+		{name: "single host", remoteAddr: "10.0.0.5:1234", expected: http.StatusOK},
+		{name: "outside", remoteAddr: "10.0.0.6:1234", expected: http.StatusForbidden},
+		{name: "public", remoteAddr: "8.8.8.8:1234", expected: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(allowNetworks(networks, setupTestLogger()))
+			router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expected {
+				t.Errorf("expected status %d, got %d", tt.expected, w.Code)
+			}
+		})
+	}
+}
+
+func TestAllowNetworksEmptyAllowsAll(t *testing.T) {
+	router := gin.New()
+	router.Use(allowNetworks(nil, setupTestLogger()))
+	router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "8.8.8.8:1234"
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestServerAllowedNetworksIgnoresUntrustedForwardedFor(t *testing.T) {
+	container := setupTestContainer()
+	container.Config.AllowedNetworks = []string{"192.168.1.0/24"}
+	server := NewServer(container)
+
+	req := httptest.NewRequest("GET", "/transmission/rpc", nil)
+	req.RemoteAddr = "8.8.8.8:1234"
+	req.Header.Set("X-Forwarded-For", "192.168.1.10")
+	w := httptest.NewRecorder()
+	server.GetRouter().ServeHTTP(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected status %d, got %d", http.StatusForbidden, w.Code)
+	}
+}
+
+func TestServerAllowedNetworksTrustedProxy(t *testing.T) {
+	container := setupTestContainer()
+	container.Config.AllowedNetworks = []string{"192.168.1.0/24"}
+	container.Config.TrustedProxies = []string{"172.17.0.1"}
+	server := NewServer(container)
+
+	req := httptest.NewRequest("GET", "/transmission/rpc", nil)
+	req.RemoteAddr = "172.17.0.1:1234"
+	req.Header.Set("X-Forwarded-For", "192.168.1.10")
+	req.Header.Set("Authorization", basicAuthHeader("testuser", "testpass"))
+	w := httptest.NewRecorder()
+	server.GetRouter().ServeHTTP(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected status %d, got %d", http.StatusConflict, w.Code)
+	}
+}
+
+func TestAcceptsGzip(t *testing.T) {
+	tests := []struct {
+		header   string
+		expected bool
+	}{
+		{"", false},
+		{"gzip", true},
+		{"deflate, gzip", true},
+		{"GZIP;q=0.5", true},
+		{"gzip;q=0", false},
+		{"br", false},
+		{"*", true},
+	}
+
+	for _, tt := range tests {
+		if got := acceptsGzip(tt.header); got != tt.expected {
+			t.Errorf("acceptsGzip(%q) = %v, expected %v", tt.header, got, tt.expected)
+		}
+	}
+}
+
+func TestServerGzipRPCResponse(t *testing.T) {
+	server := NewServer(setupTestContainer())
+
+	req := httptest.NewRequest("POST", "/transmission/rpc", bytes.NewBufferString(`{"method": "session-get"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", basicAuthHeader("testuser", "testpass"))
+	req.Header.Set("X-Transmission-Session-Id", server.handler.sessionID())
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	server.GetRouter().ServeHTTP(w, req)
+
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected gzip Content-Encoding, got %q", w.Header().Get("Content-Encoding"))
+	}
+
+	gz, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("failed to create gzip reader: %v", err)
+	}
+	body, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("failed to decompress body: %v", err)
+	}
+
+	var resp transmission.Response
+	if err := json.Unmarshal(body, &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if resp.Result != "success" {
+		t.Errorf("expected result 'success', got '%s'", resp.Result)
+	}
+}
+
+func TestServerGzipSkipsBodylessResponses(t *testing.T) {
+	server := NewServer(setupTestContainer())
+
+	req := httptest.NewRequest("POST", "/transmission/rpc", bytes.NewBufferString(`{"method": "session-get"}`))
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	server.GetRouter().ServeHTTP(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected status %d, got %d", http.StatusConflict, w.Code)
+	}
+	if w.Header().Get("Content-Encoding") != "" || w.Body.Len() != 0 {
+		t.Errorf("expected uncompressed empty body, got encoding %q and %d bytes",
+			w.Header().Get("Content-Encoding"), w.Body.Len())
+	}
+}
+
+func TestServerNoGzipWithoutAcceptEncoding(t *testing.T) {
+	server := NewServer(setupTestContainer())
+
+	req := httptest.NewRequest("POST", "/transmission/rpc", bytes.NewBufferString(`{"method": "session-get"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", basicAuthHeader("testuser", "testpass"))
+	req.Header.Set("X-Transmission-Session-Id", server.handler.sessionID())
+	w := httptest.NewRecorder()
+	server.GetRouter().ServeHTTP(w, req)
+
+	if w.Header().Get("Content-Encoding") != "" {
+		t.Errorf("expected no Content-Encoding, got %q", w.Header().Get("Content-Encoding"))
+	}
+	var resp transmission.Response
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+}
+
+func TestServerRejectsOversizedBody(t *testing.T) {
+	container := setupTestContainer()
+	container.Config.HTTP.MaxBodySize = 64
+	server := NewServer(container)
+
+	body := `{"method": "torrent-add", "arguments": {"metainfo": "` + strings.Repeat("A", 128) + `"}}`
+
+	// Declared Content-Length over the limit.
+	req := httptest.NewRequest("POST", "/transmission/rpc", bytes.NewBufferString(body))
+	req.Header.Set("Authorization", basicAuthHeader("testuser", "testpass"))
+	req.Header.Set("X-Transmission-Session-Id", server.handler.sessionID())
+	w := httptest.NewRecorder()
+	server.GetRouter().ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected status %d, got %d", http.StatusRequestEntityTooLarge, w.Code)
+	}
+
+	// Unknown length (chunked) body over the limit.
+	req = httptest.NewRequest("POST", "/transmission/rpc", io.NopCloser(bytes.NewBufferString(body)))
+	req.ContentLength = -1
+	req.Header.Set("Authorization", basicAuthHeader("testuser", "testpass"))
+	req.Header.Set("X-Transmission-Session-Id", server.handler.sessionID())
+	w = httptest.NewRecorder()
+	server.GetRouter().ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected status %d, got %d", http.StatusRequestEntityTooLarge, w.Code)
+	}
+}
+
+func TestServerAcceptsBodyWithinLimit(t *testing.T) {
+	container := setupTestContainer()
+	container.Config.HTTP.MaxBodySize = 1024
+	server := NewServer(container)
+
+	req := httptest.NewRequest("POST", "/transmission/rpc", bytes.NewBufferString(`{"method": "session-get"}`))
+	req.Header.Set("Authorization", basicAuthHeader("testuser", "testpass"))
+	req.Header.Set("X-Transmission-Session-Id", server.handler.sessionID())
+	w := httptest.NewRecorder()
+	server.GetRouter().ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestDumpTraffic(t *testing.T) {
+	var buf bytes.Buffer
+	logger := setupTestLogger()
+	logger.SetOutput(&buf)
+	logger.SetLevel(logrus.TraceLevel)
+
+	router := gin.New()
+	router.POST("/rpc", dumpTraffic(logger, "transmission rpc"), func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		c.String(http.StatusOK, "got %s", body)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(`{"method":"session-get"}`))
+	req.SetBasicAuth("user", "secret")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Body.String() != `got {"method":"session-get"}` {
+		t.Errorf("handler did not see the request body: %q", rec.Body.String())
+	}
+	out := buf.String()
+	for _, want := range []string{"transmission rpc request", "session-get", "transmission rpc response", "200 OK", "Authorization: [redacted]"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in log, got %q", want, out)
+		}
+	}
+	if strings.Contains(out, "dXNlcjpzZWNyZXQ=") {
+		t.Errorf("credentials leaked into log: %q", out)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+		reused   bool
+	}{
+		{name: "generated", incoming: "", reused: false},
+		{name: "reused", incoming: "abc-123", reused: true},
+		{name: "unsafe replaced", incoming: "bad id\nforged=1", reused: false},
+		{name: "too long replaced", incoming: strings.Repeat("a", 65), reused: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string
+			router := gin.New()
+			router.Use(requestID())
+			router.GET("/", func(c *gin.Context) {
+				seen = c.GetString(requestIDKey)
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.incoming != "" {
+				req.Header.Set("X-Request-Id", tt.incoming)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if seen == "" || rec.Header().Get("X-Request-Id") != seen {
+				t.Fatalf("expected the request ID %q to be echoed, got %q", seen, rec.Header().Get("X-Request-Id"))
+			}
+			if (seen == tt.incoming) != tt.reused {
+				t.Errorf("incoming %q, got %q", tt.incoming, seen)
+			}
+		})
+	}
+}
+
+func TestRPCLogsCarryRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	container := setupTestContainer()
+	container.Logger.SetOutput(&buf)
+	container.Logger.SetLevel(logrus.DebugLevel)
+	server := NewServer(container)
+
+	body := `{"method":"session-get"}`
+	req := httptest.NewRequest(http.MethodPost, "/transmission/rpc", strings.NewReader(body))
+	req.SetBasicAuth(container.Config.Username, container.Config.Password)
+	req.Header.Set("X-Transmission-Session-Id", server.handler.sessionID())
+	req.Header.Set("X-Request-Id", "req-42")
+	rec := httptest.NewRecorder()
+	server.router.ServeHTTP(rec, req)
+
+	if !strings.Contains(buf.String(), "request_id=req-42") {
+		t.Errorf("expected request_id in the RPC log lines, got %q", buf.String())
+	}
+}
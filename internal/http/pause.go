@@ -0,0 +1,51 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// PauseDownloads pauses the download queue: no new downloads start, while the
+// ones in progress finish. Useful before host maintenance.
+func (h *Handler) PauseDownloads(c *gin.Context) {
+	h.switchDownloads(c, true)
+}
+
+// ResumeDownloads lets new downloads start again after PauseDownloads.
+func (h *Handler) ResumeDownloads(c *gin.Context) {
+	h.switchDownloads(c, false)
+}
+
+// switchDownloads authenticates the request, pauses or resumes the download
+// queue and reports whether it's paused.
+func (h *Handler) switchDownloads(c *gin.Context, paused bool) {
+	user, ok, locked := h.login(c)
+	if locked {
+		c.Status(http.StatusTooManyRequests)
+		return
+	}
+	if !ok {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	log := h.requestLogger(c)
+	log.Infof("%s requested by %s", c.Request.URL.Path, user)
+	h.setPaused(log, paused)
+	c.JSON(http.StatusOK, gin.H{"paused": h.container.Pause.Paused()})
+}
+
+// setPaused pauses or resumes the download queue, logging changes.
+func (h *Handler) setPaused(log *logrus.Entry, paused bool) {
+	if paused {
+		if h.container.Pause.Pause() {
+			log.Info("Downloads paused, downloads in progress will finish")
+		}
+		return
+	}
+	if h.container.Pause.Resume() {
+		log.Info("Downloads resumed")
+	}
+}
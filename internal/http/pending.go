@@ -0,0 +1,115 @@
+package http
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ochronus/goputioarr/internal/services/putio"
+	"github.com/ochronus/goputioarr/internal/services/transmission"
+)
+
+// pendingTTL is how long an added torrent is reported before put.io lists it.
+const pendingTTL = 10 * time.Minute
+
+// pendingTorrent is a torrent accepted by torrent-add that put.io hasn't listed yet.
+type pendingTorrent struct {
+	name  string
+	size  int64
+	added time.Time
+}
+
+// pendingTorrents remembers torrents added through torrent-add until put.io
+// lists them, so torrent-get can report them (and their hash) right away.
+// It also remembers the info hash of transfers put.io lists without one yet,
+// which happens for magnet links until put.io has fetched the metadata.
+type pendingTorrents struct {
+	mu     sync.Mutex
+	ttl    time.Duration
+	now    func() time.Time
+	items  map[string]pendingTorrent
+	hashes map[uint64]string
+}
+
+func newPendingTorrents() *pendingTorrents {
+	return &pendingTorrents{
+		ttl:    pendingTTL,
+		now:    time.Now,
+		items:  make(map[string]pendingTorrent),
+		hashes: make(map[uint64]string),
+	}
+}
+
+// Add records a torrent by info hash.
+func (p *pendingTorrents) Add(hash, name string, size int64) {
+	if hash == "" {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.items[strings.ToLower(hash)] = pendingTorrent{name: name, size: size, added: p.now()}
+}
+
+// Identify records the info hash of the put.io transfer with the given ID.
+func (p *pendingTorrents) Identify(id uint64, hash string) {
+	if id == 0 || hash == "" {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.hashes[id] = strings.ToLower(hash)
+}
+
+// FillHashes sets the recorded info hash on transfers put.io lists without
+// one. Transfers that now have a hash of their own, or that are gone, are
+// forgotten.
+func (p *pendingTorrents) FillHashes(transfers []putio.Transfer) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	listed := make(map[uint64]bool, len(transfers))
+	for i := range transfers {
+		t := &transfers[i]
+		hash, ok := p.hashes[t.ID]
+		if !ok {
+			continue
+		}
+		if t.Hash != nil && *t.Hash != "" {
+			continue
+		}
+		t.Hash = &hash
+		listed[t.ID] = true
+	}
+	for id := range p.hashes {
+		if !listed[id] {
+			delete(p.hashes, id)
+		}
+	}
+}
+
+// Torrents drops entries that put.io now lists (known holds lowercase hashes)
+// or that expired, and returns the rest as queued Transmission torrents.
+func (p *pendingTorrents) Torrents(known map[string]bool, downloadDir string) []*transmission.Torrent {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	var torrents []*transmission.Torrent
+	for hash, item := range p.items {
+		if known[hash] || now.Sub(item.added) > p.ttl {
+			delete(p.items, hash)
+			continue
+		}
+		hashString := hash
+		torrents = append(torrents, &transmission.Torrent{
+			HashString:    &hashString,
+			Name:          item.name,
+			DownloadDir:   downloadDir,
+			TotalSize:     item.size,
+			LeftUntilDone: item.size,
+			Status:        transmission.StatusQueued,
+			FileCount:     1,
+		})
+	}
+	return torrents
+}
@@ -0,0 +1,52 @@
+package http
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ochronus/goputioarr/internal/services/putio"
+)
+
+func TestPendingTorrents(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	p := newPendingTorrents()
+	p.now = func() time.Time { return now }
+
+	p.Add("", "ignored", 0)
+	p.Add("ABCDEF", "Show", 100)
+	p.Add("123456", "Movie", 200)
+
+	torrents := p.Torrents(map[string]bool{"123456": true}, "/downloads")
+	if len(torrents) != 1 || *torrents[0].HashString != "abcdef" || torrents[0].Name != "Show" || torrents[0].DownloadDir != "/downloads" {
+		t.Fatalf("unexpected pending torrents %+v", torrents)
+	}
+	if torrents := p.Torrents(nil, "/downloads"); len(torrents) != 1 {
+		t.Errorf("expected listed torrent to be forgotten, got %d torrents", len(torrents))
+	}
+
+	now = now.Add(pendingTTL + time.Second)
+	if torrents := p.Torrents(nil, "/downloads"); len(torrents) != 0 {
+		t.Errorf("expected expired torrents to be dropped, got %+v", torrents)
+	}
+}
+
+func TestPendingTorrentsFillHashes(t *testing.T) {
+	p := newPendingTorrents()
+	p.Identify(0, "ignored")
+	p.Identify(1, "ABCDEF")
+	p.Identify(2, "123456")
+	p.Identify(3, "fedcba")
+
+	own := "654321"
+	transfers := []putio.Transfer{{ID: 1}, {ID: 2, Hash: &own}}
+	p.FillHashes(transfers)
+	if transfers[0].Hash == nil || *transfers[0].Hash != "abcdef" {
+		t.Errorf("expected hash to be filled in, got %v", transfers[0].Hash)
+	}
+	if *transfers[1].Hash != "654321" {
+		t.Errorf("expected put.io's hash to be kept, got %q", *transfers[1].Hash)
+	}
+	if len(p.hashes) != 1 || p.hashes[1] != "abcdef" {
+		t.Errorf("expected hashed and missing transfers to be forgotten, got %v", p.hashes)
+	}
+}
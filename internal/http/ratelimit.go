@@ -0,0 +1,102 @@
+package http
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ochronus/goputioarr/internal/config"
+)
+
+// authAttempts tracks failed logins for a single client IP.
+type authAttempts struct {
+	failures     int
+	firstFailure time.Time
+	lockedUntil  time.Time
+}
+
+// authLimiter locks out client IPs after repeated failed logins.
+type authLimiter struct {
+	maxFailures int
+	window      time.Duration
+	lockout     time.Duration
+	now         func() time.Time
+
+	mu       sync.Mutex
+	attempts map[string]*authAttempts
+}
+
+// newAuthLimiter creates a limiter from cfg. It returns nil when rate limiting
+// is disabled; all methods are safe to call on a nil limiter.
+func newAuthLimiter(cfg config.AuthConfig) *authLimiter {
+	if cfg.MaxFailures <= 0 {
+		return nil
+	}
+	return &authLimiter{
+		maxFailures: cfg.MaxFailures,
+		window:      cfg.Window.Duration(),
+		lockout:     cfg.Lockout.Duration(),
+		now:         time.Now,
+		attempts:    make(map[string]*authAttempts),
+	}
+}
+
+// Locked reports whether ip is currently locked out.
+func (l *authLimiter) Locked(ip string) bool {
+	if l == nil {
+		return false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	state, ok := l.attempts[ip]
+	if !ok {
+		return false
+	}
+	return l.now().Before(state.lockedUntil)
+}
+
+// RecordFailure registers a failed login for ip and reports whether it is now locked out.
+func (l *authLimiter) RecordFailure(ip string) bool {
+	if l == nil {
+		return false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.pruneLocked(now)
+
+	state, ok := l.attempts[ip]
+	if !ok || now.Sub(state.firstFailure) > l.window {
+		state = &authAttempts{firstFailure: now}
+		l.attempts[ip] = state
+	}
+	state.failures++
+	if state.failures >= l.maxFailures {
+		state.lockedUntil = now.Add(l.lockout)
+		state.failures = 0
+		state.firstFailure = now
+		return true
+	}
+	return false
+}
+
+// RecordSuccess clears the failure history for ip.
+func (l *authLimiter) RecordSuccess(ip string) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.attempts, ip)
+}
+
+// pruneLocked drops entries whose window and lockout have both expired.
+// The caller must hold l.mu.
+func (l *authLimiter) pruneLocked(now time.Time) {
+	for ip, state := range l.attempts {
+		if now.Sub(state.firstFailure) > l.window && !now.Before(state.lockedUntil) {
+			delete(l.attempts, ip)
+		}
+	}
+}
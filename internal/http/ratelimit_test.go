@@ -0,0 +1,138 @@
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ochronus/goputioarr/internal/config"
+)
+
+func TestNewAuthLimiterDisabled(t *testing.T) {
+	limiter := newAuthLimiter(config.AuthConfig{})
+	if limiter != nil {
+		t.Fatal("expected nil limiter when max_failures is zero")
+	}
+	// Methods must be safe on a nil limiter.
+	if limiter.Locked("1.2.3.4") {
+		t.Error("expected nil limiter to never lock")
+	}
+	if limiter.RecordFailure("1.2.3.4") {
+		t.Error("expected nil limiter to never lock")
+	}
+	limiter.RecordSuccess("1.2.3.4")
+}
+
+func TestAuthLimiterLockout(t *testing.T) {
+	now := time.Now()
+	limiter := newAuthLimiter(config.AuthConfig{
+		MaxFailures: 3,
+		Window:      config.Seconds(60),
+		Lockout:     config.Seconds(300),
+	})
+	limiter.now = func() time.Time { return now }
+
+	if limiter.RecordFailure("ip") || limiter.RecordFailure("ip") {
+		t.Fatal("did not expect lockout before max failures")
+	}
+	if limiter.Locked("ip") {
+		t.Fatal("did not expect ip to be locked yet")
+	}
+	if !limiter.RecordFailure("ip") {
+		t.Fatal("expected lockout after max failures")
+	}
+	if !limiter.Locked("ip") {
+		t.Fatal("expected ip to be locked")
+	}
+	if limiter.Locked("other") {
+		t.Error("expected other ips to be unaffected")
+	}
+
+	now = now.Add(301 * time.Second)
+	if limiter.Locked("ip") {
+		t.Error("expected lockout to expire")
+	}
+}
+
+func TestAuthLimiterWindowExpiry(t *testing.T) {
+	now := time.Now()
+	limiter := newAuthLimiter(config.AuthConfig{
+		MaxFailures: 2,
+		Window:      config.Seconds(10),
+		Lockout:     config.Seconds(60),
+	})
+	limiter.now = func() time.Time { return now }
+
+	limiter.RecordFailure("ip")
+	now = now.Add(11 * time.Second)
+	if limiter.RecordFailure("ip") {
+		t.Error("expected failure count to reset after window")
+	}
+}
+
+func TestAuthLimiterSuccessResets(t *testing.T) {
+	limiter := newAuthLimiter(config.AuthConfig{
+		MaxFailures: 2,
+		Window:      config.Seconds(60),
+		Lockout:     config.Seconds(60),
+	})
+
+	limiter.RecordFailure("ip")
+	limiter.RecordSuccess("ip")
+	if limiter.RecordFailure("ip") {
+		t.Error("expected success to reset the failure count")
+	}
+}
+
+func TestRPCPostLockout(t *testing.T) {
+	handler := setupTestHandler()
+	handler.limiter = newAuthLimiter(config.AuthConfig{
+		MaxFailures: 2,
+		Window:      config.Seconds(60),
+		Lockout:     config.Seconds(60),
+	})
+	router := setupTestRouter(handler)
+
+	post := func(auth string) int {
+		req := httptest.NewRequest("POST", "/transmission/rpc", bytes.NewBufferString(`{"method": "session-get"}`))
+		req.Header.Set("Content-Type", "application/json")
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Unauthenticated handshake requests don't count as failures.
+	for i := 0; i < 3; i++ {
+		if code := post(""); code != http.StatusConflict {
+			t.Fatalf("expected status %d, got %d", http.StatusConflict, code)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		if code := post(basicAuthHeader("testuser", "wrong")); code != http.StatusConflict {
+			t.Fatalf("expected status %d, got %d", http.StatusConflict, code)
+		}
+	}
+
+	// Even valid credentials are rejected while locked out.
+	if code := post(basicAuthHeader("testuser", "testpass")); code != http.StatusTooManyRequests {
+		t.Errorf("expected status %d, got %d", http.StatusTooManyRequests, code)
+	}
+}
+
+func TestSecureCompare(t *testing.T) {
+	if !secureCompare("secret", "secret") {
+		t.Error("expected equal strings to match")
+	}
+	if secureCompare("secret", "secreT") || secureCompare("secret", "secret2") || secureCompare("", "x") {
+		t.Error("expected different strings not to match")
+	}
+	if !secureCompare("", "") {
+		t.Error("expected empty strings to match")
+	}
+}
@@ -0,0 +1,102 @@
+package http
+
+import (
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ochronus/goputioarr/internal/services/transmission"
+)
+
+// recentlyActiveWindow is how long a changed or removed torrent is reported to
+// torrent-get with ids "recently-active", as in Transmission.
+const recentlyActiveWindow = 60 * time.Second
+
+// recentEntry is the last reported state of a torrent.
+type recentEntry struct {
+	id          uint64
+	fingerprint string
+	changed     time.Time
+}
+
+// recentTorrents compares the torrents of successive torrent-get calls to
+// answer ids "recently-active": torrents that appeared or changed, and the IDs
+// of those that went away, within recentlyActiveWindow. Clients that sync
+// incrementally poll well within the window, so they see every change.
+type recentTorrents struct {
+	mu      sync.Mutex
+	window  time.Duration
+	now     func() time.Time
+	seen    map[string]recentEntry
+	removed map[uint64]time.Time
+}
+
+func newRecentTorrents() *recentTorrents {
+	return &recentTorrents{
+		window:  recentlyActiveWindow,
+		now:     time.Now,
+		seen:    make(map[string]recentEntry),
+		removed: make(map[uint64]time.Time),
+	}
+}
+
+// Observe records the current torrents and returns the recently active ones
+// along with the IDs of the recently removed ones.
+func (r *recentTorrents) Observe(torrents []*transmission.Torrent) ([]*transmission.Torrent, []uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	seen := make(map[string]recentEntry, len(torrents))
+	active := make([]*transmission.Torrent, 0)
+	for _, torrent := range torrents {
+		key := torrentKey(torrent)
+		fingerprint := torrentFingerprint(torrent)
+		entry, ok := r.seen[key]
+		if !ok || entry.fingerprint != fingerprint {
+			entry = recentEntry{id: torrent.ID, fingerprint: fingerprint, changed: now}
+		}
+		seen[key] = entry
+		// A torrent that came back is no longer removed.
+		delete(r.removed, torrent.ID)
+		if now.Sub(entry.changed) < r.window {
+			active = append(active, torrent)
+		}
+	}
+	for key, entry := range r.seen {
+		// Pending torrents have no ID to report until put.io lists them.
+		if _, ok := seen[key]; !ok && entry.id != 0 {
+			r.removed[entry.id] = now
+		}
+	}
+	r.seen = seen
+
+	removed := make([]uint64, 0, len(r.removed))
+	for id, at := range r.removed {
+		if now.Sub(at) >= r.window {
+			delete(r.removed, id)
+			continue
+		}
+		removed = append(removed, id)
+	}
+	return active, removed
+}
+
+// torrentKey identifies a torrent across calls. Torrents that put.io doesn't
+// list yet have no ID, only a hash.
+func torrentKey(torrent *transmission.Torrent) string {
+	if torrent.HashString != nil && *torrent.HashString != "" {
+		return *torrent.HashString
+	}
+	return strconv.FormatUint(torrent.ID, 10)
+}
+
+// torrentFingerprint changes whenever any reported field of the torrent does.
+func torrentFingerprint(torrent *transmission.Torrent) string {
+	data, err := json.Marshal(torrent)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
@@ -0,0 +1,81 @@
+package http
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ochronus/goputioarr/internal/services/putio"
+	"github.com/ochronus/goputioarr/internal/services/transmission"
+)
+
+func testTorrent(id uint64, hash string, downloaded int64) *transmission.Torrent {
+	return &transmission.Torrent{ID: id, HashString: &hash, DownloadedEver: downloaded}
+}
+
+func TestRecentTorrentsObserve(t *testing.T) {
+	now := time.Unix(0, 0)
+	recent := newRecentTorrents()
+	recent.now = func() time.Time { return now }
+
+	active, removed := recent.Observe([]*transmission.Torrent{testTorrent(1, "aaa", 50), testTorrent(2, "bbb", 1)})
+	if len(active) != 2 || len(removed) != 0 {
+		t.Fatalf("expected new torrents to be active, got %d active, removed %v", len(active), removed)
+	}
+
+	now = now.Add(recentlyActiveWindow)
+	active, removed = recent.Observe([]*transmission.Torrent{testTorrent(1, "aaa", 75), testTorrent(2, "bbb", 1)})
+	if len(active) != 1 || active[0].ID != 1 || len(removed) != 0 {
+		t.Fatalf("expected only the changed torrent to be active, got %+v, removed %v", active, removed)
+	}
+
+	now = now.Add(time.Second)
+	active, removed = recent.Observe([]*transmission.Torrent{testTorrent(1, "aaa", 75)})
+	if len(active) != 1 || len(removed) != 1 || removed[0] != 2 {
+		t.Fatalf("expected torrent 2 to be removed, got %+v, removed %v", active, removed)
+	}
+
+	now = now.Add(recentlyActiveWindow)
+	active, removed = recent.Observe([]*transmission.Torrent{testTorrent(1, "aaa", 75)})
+	if len(active) != 0 || len(removed) != 0 {
+		t.Errorf("expected nothing recent after the window, got %+v, removed %v", active, removed)
+	}
+}
+
+func TestRecentTorrentsPendingWithoutID(t *testing.T) {
+	recent := newRecentTorrents()
+	recent.Observe([]*transmission.Torrent{testTorrent(0, "aaa", 0)})
+	// put.io now lists the pending torrent under an ID.
+	active, removed := recent.Observe([]*transmission.Torrent{testTorrent(7, "aaa", 0)})
+	if len(active) != 1 || len(removed) != 0 {
+		t.Errorf("expected the listed torrent to be active and nothing removed, got %+v, removed %v", active, removed)
+	}
+}
+
+func TestTorrentGetRecentlyActive(t *testing.T) {
+	handler := setupTestHandler()
+	hashA, hashB := "aaa", "bbb"
+	client := &mockPutioClient{transfersResp: &putio.ListTransferResponse{Transfers: []putio.Transfer{
+		{ID: 1, Hash: &hashA, Status: "DOWNLOADING"},
+		{ID: 2, Hash: &hashB, Status: "COMPLETED"},
+	}}}
+	handler.putioClient = client
+
+	get := func() *transmission.TorrentGetResponse {
+		t.Helper()
+		req := &transmission.Request{Method: "torrent-get", Arguments: rawArgs(map[string]interface{}{"ids": "recently-active"})}
+		resp, err := handler.dispatch(testLog(handler), req, "testuser")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return resp.(*transmission.TorrentGetResponse)
+	}
+
+	if resp := get(); len(resp.Torrents) != 2 || len(resp.Removed) != 0 {
+		t.Fatalf("expected both torrents on the first call, got %+v", resp)
+	}
+	client.transfersResp.Transfers = client.transfersResp.Transfers[:1]
+	resp := get()
+	if len(resp.Removed) != 1 || resp.Removed[0] != 2 {
+		t.Errorf("expected torrent 2 in removed, got %v", resp.Removed)
+	}
+}
@@ -33,19 +33,60 @@ func NewServer(container *app.Container) *Server {
 
 	router := gin.New()
 
+	// Only honor X-Forwarded-For from configured proxies so client IPs can't be spoofed
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		container.Logger.Warnf("Invalid trusted_proxies, trusting none: %v", err)
+		_ = router.SetTrustedProxies(nil)
+	}
+
 	// Add recovery middleware
 	router.Use(gin.Recovery())
 
-	// Add logging middleware
-	router.Use(func(c *gin.Context) {
-		c.Next()
-	})
+	// Restrict access to trusted networks
+	allowed, err := config.ParseNetworks(cfg.AllowedNetworks)
+	if err != nil {
+		container.Logger.Errorf("Invalid allowed_networks, rejecting all requests: %v", err)
+		router.Use(func(c *gin.Context) {
+			c.AbortWithStatus(http.StatusForbidden)
+		})
+	} else {
+		router.Use(allowNetworks(allowed, container.Logger))
+	}
+
+	// Cap request body size
+	router.Use(limitBodySize(cfg.HTTP.MaxBodySize))
+
+	// Compress responses for clients that support it
+	router.Use(gzipResponses())
+
+	// Tag each request with a correlation ID for the logs
+	router.Use(requestID())
 
 	handler := NewHandler(container)
 
 	// Register routes
-	router.POST("/transmission/rpc", handler.RPCPost)
-	router.GET("/transmission/rpc", handler.RPCGet)
+	rpc := router.Group("/transmission", endpointGroup(config.EndpointRPC))
+	rpcDump := dumpTraffic(container.Logger, "transmission rpc")
+	rpc.POST("/rpc", rpcDump, handler.RPCPost)
+	rpc.GET("/rpc", rpcDump, handler.RPCGet)
+	router.POST("/webhooks/arr", endpointGroup(config.EndpointWebhooks), handler.ArrWebhook)
+	api := router.Group("", endpointGroup(config.EndpointAPI))
+	api.GET("/history", handler.History)
+	api.GET("/stats", handler.Stats)
+	api.GET("/transfers", handler.Transfers)
+	api.POST("/pause", handler.PauseDownloads)
+	api.POST("/resume", handler.ResumeDownloads)
+	if cfg.Streaming() {
+		router.GET(app.StreamPrefix+"/:file_id", handler.Stream)
+		router.HEAD(app.StreamPrefix+"/:file_id", handler.Stream)
+	}
+	if cfg.WebDAV.Enabled {
+		dav := router.Group(webdavPrefix, endpointGroup(config.EndpointWebDAV))
+		for _, method := range webdavMethods {
+			dav.Handle(method, "", handler.WebDAV)
+			dav.Handle(method, "/*path", handler.WebDAV)
+		}
+	}
 
 	return &Server{
 		container: container,
@@ -64,16 +105,36 @@ func (s *Server) Start() error {
 // StartWithContext starts the HTTP server and shuts down gracefully when the context is canceled.
 func (s *Server) StartWithContext(ctx context.Context) error {
 	addr := fmt.Sprintf("%s:%d", s.config.BindAddress, s.config.Port)
-	s.logger.Infof("Starting web server at http://%s", addr)
+	tlsConfig, err := buildTLSConfig(s.config.TLS)
+	if err != nil {
+		return err
+	}
+	scheme := "http"
+	if tlsConfig != nil {
+		scheme = "https"
+	}
+	s.logger.Infof("Starting web server at %s://%s", scheme, addr)
 
 	s.srv = &http.Server{
-		Addr:    addr,
-		Handler: s.router,
+		Addr:              addr,
+		Handler:           s.router,
+		ReadHeaderTimeout: s.config.HTTP.ReadHeaderTimeout.Duration(),
+		ReadTimeout:       s.config.HTTP.ReadTimeout.Duration(),
+		WriteTimeout:      s.config.HTTP.WriteTimeout.Duration(),
+		IdleTimeout:       s.config.HTTP.IdleTimeout.Duration(),
+		TLSConfig:         tlsConfig,
 	}
 
 	errCh := make(chan error, 1)
 	go func() {
-		if err := s.srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if tlsConfig != nil {
+			// The certificate is already loaded into TLSConfig.
+			err = s.srv.ListenAndServeTLS("", "")
+		} else {
+			err = s.srv.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			errCh <- err
 		}
 		close(errCh)
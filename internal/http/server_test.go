@@ -3,6 +3,7 @@ package http
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/gin-gonic/gin"
@@ -35,6 +36,11 @@ func setupTestLogger() *logrus.Logger {
 	return logger
 }
 
+// testLog returns the untagged request logger for calling handlers directly.
+func testLog(h *Handler) *logrus.Entry {
+	return logrus.NewEntry(h.logger)
+}
+
 func setupTestContainer() *app.Container {
 	cfg := setupTestConfig()
 	logger := setupTestLogger()
@@ -111,8 +117,12 @@ func TestServerRouteRegistration(t *testing.T) {
 
 	foundPostRPC := false
 	foundGetRPC := false
+	foundWebhook := false
 
 	for _, route := range routes {
+		if route.Path == "/webhooks/arr" && route.Method == "POST" {
+			foundWebhook = true
+		}
 		if route.Path == "/transmission/rpc" {
 			if route.Method == "POST" {
 				foundPostRPC = true
@@ -129,6 +139,9 @@ func TestServerRouteRegistration(t *testing.T) {
 	if !foundGetRPC {
 		t.Error("GET /transmission/rpc route not registered")
 	}
+	if !foundWebhook {
+		t.Error("POST /webhooks/arr route not registered")
+	}
 }
 
 func TestServerRoutesRespond(t *testing.T) {
@@ -292,3 +305,35 @@ func TestServerReleaseModeForNonDebug(t *testing.T) {
 		})
 	}
 }
+
+func TestStreamRedirectsSignedLinks(t *testing.T) {
+	container := setupTestContainer()
+	container.Config.DownloadMode = config.DownloadModeStrm
+	container.Config.StreamURL = "http://goputioarr:9091"
+	router := NewServer(container).GetRouter()
+
+	signed := strings.TrimPrefix(container.StreamURL(42), "http://goputioarr:9091")
+	tests := []struct {
+		name     string
+		path     string
+		status   int
+		location string
+	}{
+		{name: "signed", path: signed, status: http.StatusFound, location: "https://cdn.put.io/files/42"},
+		{name: "unsigned", path: "/stream/42", status: http.StatusNotFound},
+		{name: "signature of another file", path: strings.Replace(signed, "/42?", "/43?", 1), status: http.StatusNotFound},
+		{name: "invalid id", path: "/stream/abc?sig=x", status: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, w.Code)
+			}
+			if got := w.Header().Get("Location"); got != tt.location {
+				t.Errorf("expected location %q, got %q", tt.location, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package http
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"sync"
+	"time"
+)
+
+// legacySessionID is the fixed session ID handed out with
+// http.legacy_session_id.
+const legacySessionID = "useless-session-id"
+
+// sessionIDLifetime is how long a session ID is handed out before it is
+// replaced, as in Transmission.
+const sessionIDLifetime = time.Hour
+
+// sessionIDs manages the X-Transmission-Session-Id clients must send with
+// their RPC requests, Transmission's protection against cross-site request
+// forgery. The ID is random and replaced every sessionIDLifetime; the previous
+// one stays valid so requests already in flight aren't bounced.
+type sessionIDs struct {
+	mu       sync.Mutex
+	lifetime time.Duration
+	now      func() time.Time
+	current  string
+	previous string
+	expires  time.Time
+}
+
+func newSessionIDs() *sessionIDs {
+	return &sessionIDs{lifetime: sessionIDLifetime, now: time.Now}
+}
+
+// Current returns the session ID to hand out, rotating it once it expired.
+func (s *sessionIDs) Current() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rotate()
+	return s.current
+}
+
+// Valid reports whether id is the current or the previous session ID.
+func (s *sessionIDs) Valid(id string) bool {
+	if id == "" {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rotate()
+	return secureCompare(id, s.current) || (s.previous != "" && secureCompare(id, s.previous))
+}
+
+func (s *sessionIDs) rotate() {
+	now := s.now()
+	if s.current != "" && now.Before(s.expires) {
+		return
+	}
+	s.previous = s.current
+	s.current = newSessionID()
+	s.expires = now.Add(s.lifetime)
+}
+
+func newSessionID() string {
+	var b [24]byte
+	rand.Read(b[:])
+	return base64.RawURLEncoding.EncodeToString(b[:])
+}
+
+// sessionID returns the X-Transmission-Session-Id to hand out.
+func (h *Handler) sessionID() string {
+	if h.config.HTTP.LegacySessionID {
+		return legacySessionID
+	}
+	return h.sessions.Current()
+}
+
+// validSession reports whether the request carries a valid session ID. Any
+// request does with http.legacy_session_id.
+func (h *Handler) validSession(id string) bool {
+	return h.config.HTTP.LegacySessionID || h.sessions.Valid(id)
+}
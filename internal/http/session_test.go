@@ -0,0 +1,87 @@
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSessionIDsRotate(t *testing.T) {
+	now := time.Unix(0, 0)
+	sessions := newSessionIDs()
+	sessions.now = func() time.Time { return now }
+
+	first := sessions.Current()
+	if first == "" || first != sessions.Current() {
+		t.Fatalf("expected a stable session ID, got %q", first)
+	}
+	if !sessions.Valid(first) || sessions.Valid("") || sessions.Valid("forged") {
+		t.Error("expected only the handed out session ID to be valid")
+	}
+
+	now = now.Add(sessionIDLifetime)
+	second := sessions.Current()
+	if second == first {
+		t.Fatal("expected the session ID to be replaced once it expired")
+	}
+	if !sessions.Valid(first) || !sessions.Valid(second) {
+		t.Error("expected the previous session ID to stay valid")
+	}
+
+	now = now.Add(sessionIDLifetime)
+	sessions.Current()
+	if sessions.Valid(first) {
+		t.Error("expected session IDs to be dropped after two rotations")
+	}
+}
+
+func TestRPCPostSessionHandshake(t *testing.T) {
+	handler := setupTestHandler()
+	router := setupTestRouter(handler)
+
+	post := func(sessionID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/transmission/rpc", bytes.NewBufferString(`{"method": "session-get"}`))
+		req.Header.Set("Authorization", basicAuthHeader("testuser", "testpass"))
+		if sessionID != "" {
+			req.Header.Set("X-Transmission-Session-Id", sessionID)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, sessionID := range []string{"", "forged", legacySessionID} {
+		w := post(sessionID)
+		if w.Code != http.StatusConflict {
+			t.Errorf("session ID %q: expected status %d, got %d", sessionID, http.StatusConflict, w.Code)
+		}
+		if w.Header().Get("X-Transmission-Session-Id") != handler.sessionID() {
+			t.Errorf("session ID %q: expected the current session ID in the 409", sessionID)
+		}
+	}
+
+	w := post(handler.sessionID())
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status %d with the session ID, got %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestRPCPostLegacySessionID(t *testing.T) {
+	handler := setupTestHandler()
+	handler.config.HTTP.LegacySessionID = true
+	router := setupTestRouter(handler)
+
+	if handler.sessionID() != legacySessionID {
+		t.Errorf("expected the fixed session ID, got %q", handler.sessionID())
+	}
+
+	req := httptest.NewRequest("POST", "/transmission/rpc", bytes.NewBufferString(`{"method": "session-get"}`))
+	req.Header.Set("Authorization", basicAuthHeader("testuser", "testpass"))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected requests without a session ID to be accepted, got %d", w.Code)
+	}
+}
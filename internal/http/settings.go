@@ -0,0 +1,63 @@
+package http
+
+import (
+	"sync"
+
+	"github.com/ochronus/goputioarr/internal/services/transmission"
+)
+
+// sessionSettings holds the Transmission session as changed by session-set.
+// It lives in memory, starting from transmission.DefaultConfig on each start.
+// Only the download directory has an effect: new torrents are downloaded
+// there. The seeding and speed limits are reported back to clients, but
+// put.io seeds and the download manager downloads regardless. Turtle mode
+// pauses the download queue, which keeps its own state.
+type sessionSettings struct {
+	mu     sync.Mutex
+	config transmission.Config
+	// localDir is the local path of the session's download-dir, or empty for
+	// download_directory.
+	localDir string
+}
+
+func newSessionSettings() *sessionSettings {
+	return &sessionSettings{config: *transmission.DefaultConfig("")}
+}
+
+// Get returns the session, reporting downloadDir if the download directory
+// wasn't changed.
+func (s *sessionSettings) Get(downloadDir string) *transmission.Config {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cfg := s.config
+	if s.localDir == "" {
+		cfg.DownloadDir = downloadDir
+	}
+	return &cfg
+}
+
+// Set applies args to the session. localDir is the local path of
+// args.DownloadDir, if set, and empty when it is download_directory.
+func (s *sessionSettings) Set(args *transmission.SessionSetArguments, localDir string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config.Apply(args)
+	if args.DownloadDir != nil {
+		s.localDir = localDir
+	}
+}
+
+// LocalDir returns the local path of the session's download directory, or
+// empty for download_directory.
+func (s *sessionSettings) LocalDir() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.localDir
+}
+
+// SeedLimits returns the session's seeding limits.
+func (s *sessionSettings) SeedLimits() (ratio float32, idleMinutes uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.config.SeedRatioLimit, s.config.IdleSeedingLimit
+}
@@ -0,0 +1,42 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// putioCallStats reports put.io API requests by endpoint.
+type putioCallStats struct {
+	LastMinute map[string]int `json:"last_minute"`
+	Total      map[string]int `json:"total"`
+}
+
+// Stats returns the number of put.io API requests made during the previous
+// minute and since startup, by endpoint, the number of downloads aborted and
+// retried since startup, and the pipeline's throughput, success rate and time
+// to import over the last hour and day, the number of transfers seen with
+// each put.io status the proxy doesn't know, and whether downloads are paused.
+func (h *Handler) Stats(c *gin.Context) {
+	_, ok, locked := h.login(c)
+	if locked {
+		c.Status(http.StatusTooManyRequests)
+		return
+	}
+	if !ok {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	calls := h.container.PutioCalls
+	c.JSON(http.StatusOK, gin.H{
+		"putio_calls": putioCallStats{
+			LastMinute: calls.LastMinute(),
+			Total:      calls.Totals(),
+		},
+		"downloads":              h.container.Downloads.Snapshot(),
+		"pipeline":               h.container.Pipeline.Snapshot(),
+		"unknown_putio_statuses": h.statuses.Counts(),
+		"paused":                 h.container.Pause.Paused(),
+	})
+}
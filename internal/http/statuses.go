@@ -0,0 +1,96 @@
+package http
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ochronus/goputioarr/internal/services/putio"
+	"github.com/ochronus/goputioarr/internal/services/transmission"
+	"github.com/sirupsen/logrus"
+)
+
+// unknownStatuses tracks the put.io transfer statuses that aren't mapped to a
+// Transmission status, so a change on put.io's side is noticed.
+type unknownStatuses struct {
+	mu        sync.Mutex
+	transfers map[string]map[uint64]bool
+}
+
+func newUnknownStatuses() *unknownStatuses {
+	return &unknownStatuses{transfers: make(map[string]map[uint64]bool)}
+}
+
+// Observe records a transfer with an unknown status and reports whether the
+// status is new.
+func (u *unknownStatuses) Observe(status string, transferID uint64) (first bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	seen, ok := u.transfers[status]
+	if !ok {
+		seen = make(map[uint64]bool)
+		u.transfers[status] = seen
+	}
+	seen[transferID] = true
+	return !ok
+}
+
+// Retain forgets the transfers that are no longer listed with the status they
+// were recorded with. Statuses stay known, so they're only warned about once.
+func (u *unknownStatuses) Retain(listed []putio.Transfer) {
+	current := make(map[uint64]string, len(listed))
+	for _, t := range listed {
+		current[t.ID] = t.Status
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for status, seen := range u.transfers {
+		for id := range seen {
+			if current[id] != status {
+				delete(seen, id)
+			}
+		}
+	}
+}
+
+// Counts returns the number of listed transfers with each unknown status.
+func (u *unknownStatuses) Counts() map[string]int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	counts := make(map[string]int, len(u.transfers))
+	for status, seen := range u.transfers {
+		counts[status] = len(seen)
+	}
+	return counts
+}
+
+// checkStatus warns, once per status, about a put.io transfer status that
+// isn't mapped to a Transmission status and counts it for /stats. With
+// putio.strict_statuses it's also reported as the torrent's error.
+func (h *Handler) checkStatus(transfer *putio.Transfer, torrent *transmission.Torrent) {
+	if _, ok := transmission.ParseStatus(transfer.Status); ok {
+		return
+	}
+	if h.statuses.Observe(transfer.Status, transfer.ID) {
+		h.logger.WithFields(logrus.Fields{
+			"status":      transfer.Status,
+			"transfer_id": transfer.ID,
+			"name":        torrent.Name,
+		}).Warn("Unknown put.io transfer status, reporting it as waiting to be checked")
+	}
+	if h.config.Putio.StrictStatuses && torrent.ErrorString == nil {
+		message := fmt.Sprintf("unknown put.io status %q", transfer.Status)
+		torrent.ErrorString = &message
+	}
+}
+
+// checkStall reports a transfer the download manager found stalled on put.io
+// as the torrent's error, so the arrs can treat it like a stalled torrent.
+func (h *Handler) checkStall(transfer *putio.Transfer, torrent *transmission.Torrent) {
+	since, ok := h.container.Stalls.Stalled(transfer.ID)
+	if !ok || torrent.ErrorString != nil {
+		return
+	}
+	message := fmt.Sprintf("stalled: no progress on put.io since %s", since.UTC().Format(time.RFC3339))
+	torrent.ErrorString = &message
+}
@@ -0,0 +1,115 @@
+package http
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ochronus/goputioarr/internal/app"
+	"github.com/ochronus/goputioarr/internal/services/putio"
+	"github.com/ochronus/goputioarr/internal/services/transmission"
+	"github.com/sirupsen/logrus"
+)
+
+func TestUnknownStatusesObserve(t *testing.T) {
+	u := newUnknownStatuses()
+	if !u.Observe("TELEPORTING", 1) {
+		t.Error("expected the first sighting to be new")
+	}
+	if u.Observe("TELEPORTING", 1) || u.Observe("TELEPORTING", 2) {
+		t.Error("expected later sightings not to be new")
+	}
+	u.Observe("WARPING", 3)
+
+	counts := u.Counts()
+	if len(counts) != 2 || counts["TELEPORTING"] != 2 || counts["WARPING"] != 1 {
+		t.Errorf("unexpected counts %v", counts)
+	}
+}
+
+func TestUnknownStatusesRetain(t *testing.T) {
+	u := newUnknownStatuses()
+	u.Observe("TELEPORTING", 1)
+	u.Observe("TELEPORTING", 2)
+	u.Observe("WARPING", 3)
+
+	// 1 was removed and 3 moved on to a known status.
+	u.Retain([]putio.Transfer{{ID: 2, Status: "TELEPORTING"}, {ID: 3, Status: "DOWNLOADING"}})
+
+	counts := u.Counts()
+	if counts["TELEPORTING"] != 1 || counts["WARPING"] != 0 {
+		t.Errorf("unexpected counts %v", counts)
+	}
+	if u.Observe("WARPING", 4) {
+		t.Error("expected a status seen before not to be new")
+	}
+}
+
+func TestCheckStatus(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    string
+		strict    bool
+		wantError string
+	}{
+		{name: "known", status: "DOWNLOADING", strict: true},
+		{name: "unknown", status: "TELEPORTING"},
+		{name: "unknown strict", status: "TELEPORTING", strict: true, wantError: `unknown put.io status "TELEPORTING"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := setupTestHandler()
+			handler.config.Putio.StrictStatuses = tt.strict
+			var buf bytes.Buffer
+			handler.logger.SetOutput(&buf)
+			handler.logger.SetLevel(logrus.WarnLevel)
+
+			for id := uint64(1); id <= 2; id++ {
+				transfer := &putio.Transfer{ID: id, Status: tt.status}
+				torrent := transmission.TorrentFromPutIOTransfer(transfer, "/downloads")
+				handler.checkStatus(transfer, torrent)
+
+				gotError := ""
+				if torrent.ErrorString != nil {
+					gotError = *torrent.ErrorString
+				}
+				if gotError != tt.wantError {
+					t.Errorf("expected error %q, got %q", tt.wantError, gotError)
+				}
+			}
+
+			wantWarnings := 0
+			if tt.wantError != "" || tt.status != "DOWNLOADING" {
+				wantWarnings = 1
+			}
+			if got := strings.Count(buf.String(), "Unknown put.io transfer status"); got != wantWarnings {
+				t.Errorf("expected %d warnings, got %d: %s", wantWarnings, got, buf.String())
+			}
+			if wantWarnings > 0 && !strings.Contains(buf.String(), "status=TELEPORTING") {
+				t.Errorf("expected the raw status to be logged, got %s", buf.String())
+			}
+		})
+	}
+}
+
+func TestCheckStall(t *testing.T) {
+	handler := setupTestHandler()
+	handler.container.Stalls = app.NewStallTracker()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	downloaded := int64(10)
+	transfer := &putio.Transfer{ID: 7, Status: "DOWNLOADING", Downloaded: &downloaded}
+	handler.container.Stalls.Observe([]putio.Transfer{*transfer}, start, time.Hour)
+
+	torrent := transmission.TorrentFromPutIOTransfer(transfer, "/downloads")
+	handler.checkStall(transfer, torrent)
+	if torrent.ErrorString != nil {
+		t.Fatalf("expected no error before the stall timeout, got %q", *torrent.ErrorString)
+	}
+
+	handler.container.Stalls.Observe([]putio.Transfer{*transfer}, start.Add(time.Hour), time.Hour)
+	handler.checkStall(transfer, torrent)
+	if torrent.ErrorString == nil || *torrent.ErrorString != "stalled: no progress on put.io since 2024-01-01T00:00:00Z" {
+		t.Errorf("expected the stall to be reported, got %v", torrent.ErrorString)
+	}
+}
@@ -0,0 +1,27 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Stream redirects a media server playing a STRM file to a fresh put.io URL
+// for the file. Media servers can't log in, so the link's signature stands
+// in for credentials; it only unlocks the one file.
+func (h *Handler) Stream(c *gin.Context) {
+	fileID, err := strconv.ParseInt(c.Param("file_id"), 10, 64)
+	if err != nil || !h.container.ValidStreamSignature(fileID, c.Query("sig")) {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	url, err := h.putioClient.GetFileURL(fileID)
+	if err != nil {
+		h.logger.Errorf("Failed to get the put.io URL of file %d: %v", fileID, err)
+		c.Status(http.StatusBadGateway)
+		return
+	}
+	c.Redirect(http.StatusFound, url)
+}
@@ -0,0 +1,59 @@
+package http
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ochronus/goputioarr/internal/config"
+)
+
+// buildTLSConfig loads the server certificate and, with a client CA, sets up
+// client certificate verification. It returns nil if TLS isn't configured.
+func buildTLSConfig(cfg config.TLSConfig) (*tls.Config, error) {
+	if !cfg.Enabled() {
+		return nil, nil
+	}
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if cfg.ClientCAFile == "" {
+		return tlsConfig, nil
+	}
+
+	pem, err := os.ReadFile(cfg.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tls.client_ca_file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("tls.client_ca_file contains no PEM certificates")
+	}
+	tlsConfig.ClientCAs = pool
+	tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	if cfg.ClientAuth == config.ClientAuthAlternative {
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return tlsConfig, nil
+}
+
+// certificateUser returns the common name of the client certificate, if the
+// request was authenticated by one in tls.client_auth "alternative" mode.
+// The handshake already verified it against tls.client_ca_file.
+func (h *Handler) certificateUser(c *gin.Context) (string, bool) {
+	if h.config.TLS.ClientAuth != config.ClientAuthAlternative {
+		return "", false
+	}
+	state := c.Request.TLS
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return "", false
+	}
+	return state.VerifiedChains[0][0].Subject.CommonName, true
+}
@@ -0,0 +1,200 @@
+package http
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ochronus/goputioarr/internal/config"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns the PEM certificate and key of a leaf signed by the CA.
+func (ca *testCA) issue(t *testing.T, commonName string, usage x509.ExtKeyUsage) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeFile(t *testing.T, dir, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// startTLSServer serves the proxy's router over HTTPS, verifying client
+// certificates in clientAuth mode, and returns its URL and the CA that signs
+// the server and client certificates.
+func startTLSServer(t *testing.T, clientAuth string) (string, *testCA) {
+	t.Helper()
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	certPEM, keyPEM := ca.issue(t, "goputioarr", x509.ExtKeyUsageServerAuth)
+	tlsCfg := config.TLSConfig{
+		CertFile:     writeFile(t, dir, "server.crt", certPEM),
+		KeyFile:      writeFile(t, dir, "server.key", keyPEM),
+		ClientCAFile: writeFile(t, dir, "ca.crt", ca.pem),
+		ClientAuth:   clientAuth,
+	}
+
+	container := setupTestContainer()
+	container.Config.TLS = tlsCfg
+	tlsConfig, err := buildTLSConfig(tlsCfg)
+	if err != nil {
+		t.Fatalf("buildTLSConfig: %v", err)
+	}
+	server := httptest.NewUnstartedServer(NewServer(container).GetRouter())
+	server.TLS = tlsConfig
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server.URL, ca
+}
+
+func tlsClient(t *testing.T, ca *testCA, withCert bool) *http.Client {
+	t.Helper()
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(ca.pem)
+	tlsConfig := &tls.Config{RootCAs: roots}
+	if withCert {
+		certPEM, keyPEM := ca.issue(t, "laptop", x509.ExtKeyUsageClientAuth)
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+}
+
+func getStats(client *http.Client, url string, withPassword bool) (int, error) {
+	req, _ := http.NewRequest(http.MethodGet, url+"/stats", nil)
+	if withPassword {
+		req.SetBasicAuth("testuser", "testpass")
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+func TestClientCertificateRequired(t *testing.T) {
+	url, ca := startTLSServer(t, config.ClientAuthRequired)
+
+	if _, err := getStats(tlsClient(t, ca, false), url, true); err == nil {
+		t.Error("expected the handshake to fail without a client certificate")
+	}
+	if status, err := getStats(tlsClient(t, ca, true), url, false); err != nil || status != http.StatusUnauthorized {
+		t.Errorf("expected the password to still be required, got %d (%v)", status, err)
+	}
+	if status, err := getStats(tlsClient(t, ca, true), url, true); err != nil || status != http.StatusOK {
+		t.Errorf("expected certificate and password to log in, got %d (%v)", status, err)
+	}
+}
+
+func TestClientCertificateAlternative(t *testing.T) {
+	url, ca := startTLSServer(t, config.ClientAuthAlternative)
+
+	if status, err := getStats(tlsClient(t, ca, true), url, false); err != nil || status != http.StatusOK {
+		t.Errorf("expected the certificate alone to log in, got %d (%v)", status, err)
+	}
+	if status, err := getStats(tlsClient(t, ca, false), url, true); err != nil || status != http.StatusOK {
+		t.Errorf("expected the password alone to log in, got %d (%v)", status, err)
+	}
+	if status, err := getStats(tlsClient(t, ca, false), url, false); err != nil || status != http.StatusUnauthorized {
+		t.Errorf("expected no credentials to be refused, got %d (%v)", status, err)
+	}
+}
+
+func TestBuildTLSConfig(t *testing.T) {
+	if tlsConfig, err := buildTLSConfig(config.TLSConfig{}); tlsConfig != nil || err != nil {
+		t.Errorf("expected no TLS without a certificate, got %v (%v)", tlsConfig, err)
+	}
+
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	certPEM, keyPEM := ca.issue(t, "goputioarr", x509.ExtKeyUsageServerAuth)
+	cfg := config.TLSConfig{
+		CertFile: writeFile(t, dir, "server.crt", certPEM),
+		KeyFile:  writeFile(t, dir, "server.key", keyPEM),
+	}
+	tlsConfig, err := buildTLSConfig(cfg)
+	if err != nil || tlsConfig.ClientAuth != tls.NoClientCert {
+		t.Errorf("expected plain TLS, got %v (%v)", tlsConfig, err)
+	}
+
+	cfg.ClientCAFile = writeFile(t, dir, "ca.crt", []byte("not a certificate"))
+	if _, err := buildTLSConfig(cfg); err == nil {
+		t.Error("expected an error for a client CA without certificates")
+	}
+	cfg.ClientCAFile = ""
+	cfg.KeyFile = writeFile(t, dir, "other.key", []byte("garbage"))
+	if _, err := buildTLSConfig(cfg); err == nil {
+		t.Error("expected an error for an invalid key")
+	}
+}
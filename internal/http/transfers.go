@@ -0,0 +1,121 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ochronus/goputioarr/internal/app"
+	"github.com/ochronus/goputioarr/internal/services/putio"
+)
+
+// transferProgress reports both halves of a transfer's pipeline: put.io
+// fetching it, and the download manager fetching its files. Either is missing
+// until that half starts.
+type transferProgress struct {
+	ID     uint64          `json:"id,omitempty"`
+	Hash   string          `json:"hash,omitempty"`
+	Name   string          `json:"name"`
+	Remote *remoteProgress `json:"remote,omitempty"`
+	Local  *localProgress  `json:"local,omitempty"`
+}
+
+// remoteProgress is put.io's progress fetching a transfer. Rate is in bytes
+// per second and PercentDone, like Transmission's, between 0 and 1.
+type remoteProgress struct {
+	Status      string  `json:"status"`
+	Size        int64   `json:"size"`
+	Downloaded  int64   `json:"downloaded"`
+	PercentDone float64 `json:"percent_done"`
+	Rate        int64   `json:"rate"`
+}
+
+// localProgress is the download manager's progress fetching a transfer's
+// files from put.io.
+type localProgress struct {
+	Stage       app.TransferStage `json:"stage"`
+	Size        int64             `json:"size"`
+	Downloaded  int64             `json:"downloaded"`
+	PercentDone float64           `json:"percent_done"`
+	Rate        int64             `json:"rate"`
+	Error       string            `json:"error,omitempty"`
+}
+
+// Transfers returns the progress of each transfer on put.io and of each one
+// the download manager is fetching, separately, so it's clear which half of
+// the pipeline holds a transfer up.
+func (h *Handler) Transfers(c *gin.Context) {
+	_, ok, locked := h.login(c)
+	if locked {
+		c.Status(http.StatusTooManyRequests)
+		return
+	}
+	if !ok {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	resp, err := h.putioClient.ListTransfers()
+	if err != nil {
+		h.logger.Errorf("Failed to list put.io transfers: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "failed to list put.io transfers"})
+		return
+	}
+
+	known := make(map[string]bool, len(resp.Transfers))
+	transfers := make([]transferProgress, 0, len(resp.Transfers))
+	for _, t := range resp.Transfers {
+		progress := transferProgress{ID: t.ID, Remote: newRemoteProgress(&t)}
+		if t.Name != nil {
+			progress.Name = *t.Name
+		}
+		if t.Hash != nil {
+			progress.Hash = strings.ToLower(*t.Hash)
+			known[progress.Hash] = true
+			if state, ok := h.container.Transfers.Get(progress.Hash); ok {
+				progress.Local = newLocalProgress(state)
+			}
+		}
+		transfers = append(transfers, progress)
+	}
+	// Items of the import folder, or transfers already removed from put.io,
+	// only have local progress.
+	for _, state := range h.container.Transfers.List() {
+		if !known[state.Hash] {
+			transfers = append(transfers, transferProgress{Hash: state.Hash, Name: state.Name, Local: newLocalProgress(state)})
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"transfers": transfers})
+}
+
+func newRemoteProgress(t *putio.Transfer) *remoteProgress {
+	progress := &remoteProgress{Status: t.Status, Rate: t.DownSpeed}
+	if t.Size != nil {
+		progress.Size = *t.Size
+	}
+	if t.Downloaded != nil {
+		progress.Downloaded = *t.Downloaded
+	}
+	progress.PercentDone = percentDone(progress.Downloaded, progress.Size)
+	return progress
+}
+
+func newLocalProgress(state app.TransferState) *localProgress {
+	return &localProgress{
+		Stage:       state.Stage,
+		Size:        state.Size,
+		Downloaded:  state.Downloaded,
+		PercentDone: percentDone(state.Downloaded, state.Size),
+		Rate:        state.Rate,
+		Error:       state.Error,
+	}
+}
+
+// percentDone returns the fraction of size downloaded, between 0 and 1.
+func percentDone(downloaded, size int64) float64 {
+	if size <= 0 {
+		return 0
+	}
+	return min(float64(downloaded)/float64(size), 1)
+}
@@ -0,0 +1,211 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ochronus/goputioarr/internal/app"
+	"golang.org/x/net/webdav"
+)
+
+const (
+	// webdavPrefix is where the download directory is served over WebDAV.
+	webdavPrefix = "/webdav"
+	// statusFileName is the virtual file at the root of the WebDAV view that
+	// reports the transfers being downloaded or waiting for import.
+	statusFileName = "goputioarr-status.json"
+)
+
+// webdavMethods are routed to the WebDAV handler. Only the read methods are
+// served; the others are answered with 405 so clients know the view is
+// read-only.
+var webdavMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodOptions, "PROPFIND",
+	http.MethodPut, http.MethodDelete, "MKCOL", "COPY", "MOVE", "PROPPATCH", "LOCK", "UNLOCK",
+}
+
+func webdavReadMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, "PROPFIND":
+		return true
+	}
+	return false
+}
+
+// newWebDAVHandler serves dir read-only, hiding partial downloads.
+func newWebDAVHandler(dir string, transfers *app.TransferStore) *webdav.Handler {
+	return &webdav.Handler{
+		Prefix:     webdavPrefix,
+		FileSystem: &downloadsFS{dir: webdav.Dir(dir), transfers: transfers},
+		LockSystem: webdav.NewMemLS(),
+	}
+}
+
+// WebDAV serves the download directory read-only, with the same credentials
+// and lockout as the RPC endpoint.
+func (h *Handler) WebDAV(c *gin.Context) {
+	_, ok, locked := h.login(c)
+	if locked {
+		c.Status(http.StatusTooManyRequests)
+		return
+	}
+	if !ok {
+		c.Header("WWW-Authenticate", `Basic realm="goputioarr"`)
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+	if !webdavReadMethod(c.Request.Method) {
+		c.Header("Allow", "GET, HEAD, OPTIONS, PROPFIND")
+		c.Status(http.StatusMethodNotAllowed)
+		return
+	}
+	// Files can take much longer than http.write_timeout to transfer. Not
+	// every writer supports deadlines, e.g. in tests.
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+	h.webdav.ServeHTTP(c.Writer, c.Request)
+}
+
+// downloadsFS is a read-only webdav.FileSystem over the download directory.
+// Files still being downloaded are hidden, and the root holds a status file
+// with the state of the transfers in progress.
+type downloadsFS struct {
+	dir       webdav.Dir
+	transfers *app.TransferStore
+}
+
+func (fs *downloadsFS) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	return os.ErrPermission
+}
+
+func (fs *downloadsFS) RemoveAll(ctx context.Context, name string) error {
+	return os.ErrPermission
+}
+
+func (fs *downloadsFS) Rename(ctx context.Context, oldName, newName string) error {
+	return os.ErrPermission
+}
+
+func (fs *downloadsFS) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0 {
+		return nil, os.ErrPermission
+	}
+	switch {
+	case isStatusFile(name):
+		return fs.statusFile()
+	case isPartialDownload(name):
+		return nil, os.ErrNotExist
+	}
+	f, err := fs.dir.OpenFile(ctx, name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &listingFile{File: f, fs: fs, root: isRoot(name)}, nil
+}
+
+func (fs *downloadsFS) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	switch {
+	case isStatusFile(name):
+		f, err := fs.statusFile()
+		if err != nil {
+			return nil, err
+		}
+		return f.Stat()
+	case isPartialDownload(name):
+		return nil, os.ErrNotExist
+	}
+	return fs.dir.Stat(ctx, name)
+}
+
+// statusFile renders the state of the tracked transfers.
+func (fs *downloadsFS) statusFile() (*memFile, error) {
+	states := fs.transfers.List()
+	if states == nil {
+		states = []app.TransferState{}
+	}
+	data, err := json.MarshalIndent(struct {
+		Transfers []app.TransferState `json:"transfers"`
+	}{states}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return &memFile{
+		Reader: bytes.NewReader(data),
+		info:   memFileInfo{name: statusFileName, size: int64(len(data)), modTime: time.Now()},
+	}, nil
+}
+
+func isRoot(name string) bool {
+	return path.Clean("/"+name) == "/"
+}
+
+func isStatusFile(name string) bool {
+	return path.Clean("/"+name) == "/"+statusFileName
+}
+
+func isPartialDownload(name string) bool {
+	return strings.HasSuffix(name, ".downloading")
+}
+
+// listingFile hides partial downloads from directory listings and adds the
+// status file to the root's.
+type listingFile struct {
+	webdav.File
+	fs   *downloadsFS
+	root bool
+}
+
+func (f *listingFile) Readdir(count int) ([]os.FileInfo, error) {
+	infos, err := f.File.Readdir(count)
+	filtered := infos[:0]
+	for _, info := range infos {
+		if !isPartialDownload(info.Name()) {
+			filtered = append(filtered, info)
+		}
+	}
+	if f.root && count <= 0 && err == nil {
+		status, statusErr := f.fs.statusFile()
+		if statusErr == nil {
+			info, _ := status.Stat()
+			filtered = append(filtered, info)
+		}
+	}
+	return filtered, err
+}
+
+func (f *listingFile) Write(p []byte) (int, error) {
+	return 0, os.ErrPermission
+}
+
+// memFile is a read-only in-memory webdav.File.
+type memFile struct {
+	*bytes.Reader
+	info memFileInfo
+}
+
+func (f *memFile) Close() error { return nil }
+
+func (f *memFile) Readdir(count int) ([]os.FileInfo, error) { return nil, os.ErrInvalid }
+
+func (f *memFile) Stat() (os.FileInfo, error) { return f.info, nil }
+
+func (f *memFile) Write(p []byte) (int, error) { return 0, os.ErrPermission }
+
+type memFileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+func (i memFileInfo) Name() string       { return i.name }
+func (i memFileInfo) Size() int64        { return i.size }
+func (i memFileInfo) Mode() os.FileMode  { return 0444 }
+func (i memFileInfo) ModTime() time.Time { return i.modTime }
+func (i memFileInfo) IsDir() bool        { return false }
+func (i memFileInfo) Sys() interface{}   { return nil }
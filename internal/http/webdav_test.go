@@ -0,0 +1,115 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ochronus/goputioarr/internal/app"
+)
+
+func setupWebDAVRouter(t *testing.T) (*gin.Engine, string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "Show S01"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(dir, "Show S01", "ep1.mkv"), []byte("0123456789"), 0644)
+	os.WriteFile(filepath.Join(dir, "movie.mkv.downloading"), []byte("partial"), 0644)
+
+	container := setupTestContainer()
+	container.Config.DownloadDirectory = dir
+	container.Config.WebDAV.Enabled = true
+	container.Transfers = app.NewTransferStore()
+	container.Transfers.Track("abc123", "Movie", 100)
+	return NewServer(container).GetRouter(), dir
+}
+
+func webdavRequest(router *gin.Engine, method, target string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	req.Header.Set("Authorization", basicAuthHeader("testuser", "testpass"))
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestWebDAVListing(t *testing.T) {
+	router, _ := setupWebDAVRouter(t)
+
+	w := webdavRequest(router, "PROPFIND", "/webdav/", map[string]string{"Depth": "1"})
+	if w.Code != http.StatusMultiStatus {
+		t.Fatalf("expected 207, got %d: %s", w.Code, w.Body.String())
+	}
+	body := w.Body.String()
+	for _, want := range []string{"Show%20S01", statusFileName} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %s in the listing: %s", want, body)
+		}
+	}
+	if strings.Contains(body, ".downloading") {
+		t.Errorf("expected partial downloads to be hidden: %s", body)
+	}
+
+	if w := webdavRequest(router, http.MethodGet, "/webdav/movie.mkv.downloading", nil); w.Code != http.StatusNotFound {
+		t.Errorf("expected partial downloads to be unreachable, got %d", w.Code)
+	}
+}
+
+func TestWebDAVGet(t *testing.T) {
+	router, _ := setupWebDAVRouter(t)
+
+	w := webdavRequest(router, http.MethodGet, "/webdav/Show%20S01/ep1.mkv", map[string]string{
+		"Range":           "bytes=2-4",
+		"Accept-Encoding": "gzip",
+	})
+	if w.Code != http.StatusPartialContent || w.Body.String() != "234" {
+		t.Errorf("expected a range of the file, got %d %q", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Content-Encoding") != "" {
+		t.Errorf("expected files not to be compressed, got %q", w.Header().Get("Content-Encoding"))
+	}
+
+	w = webdavRequest(router, http.MethodGet, "/webdav/"+statusFileName, nil)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"hash": "abc123"`) {
+		t.Errorf("expected the transfer states, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestWebDAVReadOnly(t *testing.T) {
+	router, dir := setupWebDAVRouter(t)
+
+	for _, method := range []string{http.MethodPut, http.MethodDelete, "MKCOL", "MOVE"} {
+		w := webdavRequest(router, method, "/webdav/Show%20S01/ep1.mkv", nil)
+		if w.Code != http.StatusMethodNotAllowed {
+			t.Errorf("%s: expected 405, got %d", method, w.Code)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "Show S01", "ep1.mkv")); err != nil {
+		t.Errorf("expected the file to be untouched: %v", err)
+	}
+}
+
+func TestWebDAVRequiresAuth(t *testing.T) {
+	router, _ := setupWebDAVRouter(t)
+
+	req := httptest.NewRequest("PROPFIND", "/webdav/", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") == "" {
+		t.Errorf("expected a basic auth challenge, got %d %v", w.Code, w.Header())
+	}
+}
+
+func TestWebDAVDisabled(t *testing.T) {
+	router := NewServer(setupTestContainer()).GetRouter()
+	if w := webdavRequest(router, http.MethodGet, "/webdav/", nil); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 when WebDAV is disabled, got %d", w.Code)
+	}
+}
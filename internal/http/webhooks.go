@@ -0,0 +1,57 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ochronus/goputioarr/internal/services/arr"
+)
+
+// ArrWebhook handles Sonarr/Radarr/Whisparr webhook notifications. "Download"
+// (on import) events have the download manager check the imports of the
+// referenced transfer right away, so cleanup doesn't have to wait for the next
+// history poll.
+func (h *Handler) ArrWebhook(c *gin.Context) {
+	_, ok, locked := h.login(c)
+	if locked {
+		c.Status(http.StatusTooManyRequests)
+		return
+	}
+	if !ok {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	var payload arr.WebhookPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	log := h.requestLogger(c)
+	switch {
+	case payload.EventType == arr.WebhookEventTest:
+		log.Infof("Received test webhook")
+	case !payload.IsImport():
+		log.Debugf("Ignoring webhook event %q", payload.EventType)
+	case !payload.FromDownloadClient(h.config.WebhookDownloadClient):
+		log.Debugf("[%s]: ignoring import by download client %q", shortHash(payload.DownloadID), payload.DownloadClient)
+	case h.container.Imports.Notify(payload.DownloadID):
+		log.Infof("[%s]: import reported by webhook", shortHash(payload.DownloadID))
+	default:
+		log.Debugf("[%s]: ignoring import of a transfer not waiting for one", shortHash(payload.DownloadID))
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// shortHash returns the first four characters of a hash, matching the log
+// prefix used for transfers.
+func shortHash(hash string) string {
+	hash = strings.ToLower(hash)
+	if len(hash) > 4 {
+		return hash[:4]
+	}
+	return hash
+}
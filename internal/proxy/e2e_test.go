@@ -0,0 +1,136 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ochronus/goputioarr/internal/app"
+	"github.com/ochronus/goputioarr/internal/config"
+	"github.com/ochronus/goputioarr/internal/putiomock"
+	"github.com/ochronus/goputioarr/internal/services/arr"
+	"github.com/ochronus/goputioarr/internal/services/putio"
+	"github.com/sirupsen/logrus"
+)
+
+// importingArr imports every downloaded file by hardlinking it into a library
+// directory, like an arr configured to use hardlinks.
+type importingArr struct {
+	library string
+
+	mu       sync.Mutex
+	imported map[string]string
+}
+
+func (a *importingArr) CheckImported(targetPath string) (bool, error) {
+	if _, err := os.Stat(targetPath); err != nil {
+		return false, nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, ok := a.imported[targetPath]; !ok {
+		dest := filepath.Join(a.library, filepath.Base(targetPath))
+		if err := os.Link(targetPath, dest); err != nil {
+			return false, err
+		}
+		a.imported[targetPath] = dest
+	}
+	return true, nil
+}
+
+func (a *importingArr) FindImports(targetPaths []string) (map[string]arr.Import, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	imports := make(map[string]arr.Import)
+	for _, targetPath := range targetPaths {
+		if dest, ok := a.imported[targetPath]; ok {
+			imports[targetPath] = arr.Import{ImportedPath: dest, Exact: true}
+		}
+	}
+	return imports, nil
+}
+
+func (a *importingArr) Blocklist(string) (bool, error) { return false, nil }
+
+func (a *importingArr) SystemStatus() (*arr.SystemStatus, error) {
+	return &arr.SystemStatus{AppName: "Sonarr", Version: "4.0.0"}, nil
+}
+
+func (a *importingArr) count() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.imported)
+}
+
+func TestEndToEndMagnetDownloadImport(t *testing.T) {
+	mock := putiomock.New(
+		putiomock.WithDownloadTime(100*time.Millisecond),
+		putiomock.WithSeedTime(300*time.Millisecond),
+		putiomock.WithFileSize(64*1024),
+	)
+	putioServer := httptest.NewServer(mock.Handler())
+	defer putioServer.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.Username = "user"
+	cfg.Password = "pass"
+	cfg.BindAddress = "127.0.0.1"
+	cfg.Port = 0
+	cfg.DownloadDirectory = t.TempDir()
+	cfg.StateDirectory = t.TempDir()
+	cfg.PollingInterval = config.Duration(20 * time.Millisecond)
+	cfg.Putio.APIKey = "token"
+
+	arr := &importingArr{library: t.TempDir(), imported: make(map[string]string)}
+	container, err := app.NewContainer(cfg,
+		app.WithPutioClient(putio.NewClient("token", putio.WithBaseURLs(putioServer.URL, putioServer.URL))),
+	)
+	if err != nil {
+		t.Fatalf("failed to build container: %v", err)
+	}
+	container.Logger.SetLevel(logrus.ErrorLevel)
+	container.ArrClients = []app.ArrServiceClient{{Name: "sonarr", Client: arr}}
+
+	p := New(container)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- p.Run(ctx) }()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	body := `{"method":"torrent-add","arguments":{"filename":"magnet:?xt=urn:btih:C12FE1C06BBA254A9DC9F519B335AA7C1367A88A&dn=Show"}}`
+	router := p.Server().GetRouter()
+	req := rpcRequest(t, router, body)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "torrent-added") {
+		t.Fatalf("torrent-add failed: %d %s", rec.Code, rec.Body.String())
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for len(mock.Transfers()) > 0 || mock.FileCount() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected transfer to be cleaned up from put.io, have %+v", mock.Transfers())
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	if arr.count() != 1 {
+		t.Errorf("expected one imported file, got %d", arr.count())
+	}
+	library, _ := os.ReadDir(arr.library)
+	if len(library) != 1 || library[0].Name() != "Show.mkv" {
+		t.Errorf("expected Show.mkv in the library, got %v", library)
+	}
+	if _, err := os.Stat(filepath.Join(cfg.DownloadDirectory, "Show")); !os.IsNotExist(err) {
+		t.Errorf("expected local download to be removed after import, stat err: %v", err)
+	}
+}
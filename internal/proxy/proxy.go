@@ -0,0 +1,84 @@
+// Package proxy wires the download manager and the HTTP server together from
+// a single app.Container, so both share the same put.io and arr clients.
+package proxy
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ochronus/goputioarr/internal/app"
+	"github.com/ochronus/goputioarr/internal/blackhole"
+	"github.com/ochronus/goputioarr/internal/download"
+	httpserver "github.com/ochronus/goputioarr/internal/http"
+)
+
+// Proxy runs the download manager and the HTTP server.
+type Proxy struct {
+	container *app.Container
+	manager   *download.Manager
+	server    *httpserver.Server
+	blackhole *blackhole.Watcher
+}
+
+// New builds the download manager and HTTP server from container. Any client
+// set on the container (including mocks) is used by both.
+func New(container *app.Container) *Proxy {
+	return &Proxy{
+		container: container,
+		manager:   download.NewManager(container),
+		server:    httpserver.NewServer(container),
+		blackhole: blackhole.NewWatcher(container),
+	}
+}
+
+// Manager returns the download manager.
+func (p *Proxy) Manager() *download.Manager {
+	return p.manager
+}
+
+// Server returns the HTTP server.
+func (p *Proxy) Server() *httpserver.Server {
+	return p.server
+}
+
+// Run checks the arr services, starts the download manager and, if
+// configured, the blackhole watcher and RSS fetcher, and serves HTTP until ctx
+// is cancelled or the server fails. Everything is stopped before Run returns;
+// with drain_timeout, the manager first gets to finish its downloads.
+func (p *Proxy) Run(ctx context.Context) error {
+	// Probing may wait on unreachable services; don't hold up startup.
+	go app.ProbeArrClients(p.container.ArrClients, p.container.Logger)
+
+	// Stopped after the manager, so its last state changes are published.
+	p.container.MQTT.Start()
+	defer p.container.MQTT.Stop()
+
+	// The manager outlives ctx so it can be drained once ctx is done.
+	if err := p.manager.StartWithContext(context.WithoutCancel(ctx)); err != nil {
+		return fmt.Errorf("failed to start download manager: %w", err)
+	}
+	defer p.stopManager()
+
+	p.blackhole.Start()
+	defer p.blackhole.Stop()
+	p.container.RSS.Start()
+	defer p.container.RSS.Stop()
+
+	return p.server.StartWithContext(ctx)
+}
+
+// stopManager drains the download manager for up to drain_timeout, if set, and
+// stops it.
+func (p *Proxy) stopManager() {
+	timeout := p.container.Config.DrainTimeout.Duration()
+	if timeout <= 0 {
+		p.manager.Stop()
+		return
+	}
+	p.container.Logger.Infof("Waiting up to %s for downloads to finish", timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := p.manager.Drain(ctx); err != nil {
+		p.container.Logger.Warnf("Stopped before all downloads finished: %v", err)
+	}
+}
@@ -0,0 +1,225 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ochronus/goputioarr/internal/app"
+	"github.com/ochronus/goputioarr/internal/config"
+	"github.com/ochronus/goputioarr/internal/services/putio"
+	"github.com/sirupsen/logrus"
+)
+
+type mockPutioClient struct {
+	mu        sync.Mutex
+	listCalls int
+}
+
+func (m *mockPutioClient) GetAccountInfo() (*putio.AccountInfoResponse, error) {
+	return &putio.AccountInfoResponse{}, nil
+}
+func (m *mockPutioClient) ListTransfers() (*putio.ListTransferResponse, error) {
+	m.mu.Lock()
+	m.listCalls++
+	m.mu.Unlock()
+	name, hash := "Show", "abcd"
+	return &putio.ListTransferResponse{Transfers: []putio.Transfer{
+		{ID: 1, Name: &name, Hash: &hash, Status: "DOWNLOADING"},
+	}}, nil
+}
+func (m *mockPutioClient) ListTransfersSince(uint64) (*putio.ListTransferResponse, error) {
+	return m.ListTransfers()
+}
+func (m *mockPutioClient) GetTransfer(id uint64) (*putio.GetTransferResponse, error) {
+	return &putio.GetTransferResponse{Transfer: putio.Transfer{ID: id}}, nil
+}
+func (m *mockPutioClient) RemoveTransfer(uint64) error                 { return nil }
+func (m *mockPutioClient) DeleteFile(int64) error                      { return nil }
+func (m *mockPutioClient) RemoveTransfers([]uint64) error              { return nil }
+func (m *mockPutioClient) RetryTransfer(uint64) error                  { return nil }
+func (m *mockPutioClient) DeleteFiles([]int64) error                   { return nil }
+func (m *mockPutioClient) AddTransfer(string) (*putio.Transfer, error) { return nil, nil }
+func (m *mockPutioClient) UploadFile([]byte) (*putio.Transfer, error)  { return nil, nil }
+func (m *mockPutioClient) ListFiles(fileID int64) (*putio.ListFileResponse, error) {
+	return &putio.ListFileResponse{Parent: putio.FileResponse{ID: fileID, FileType: "FOLDER"}}, nil
+}
+func (m *mockPutioClient) GetFileURL(int64) (string, error) { return "", nil }
+
+func (m *mockPutioClient) calls() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.listCalls
+}
+
+func testContainer(t *testing.T, client putio.ClientAPI) *app.Container {
+	t.Helper()
+	cfg := config.DefaultConfig()
+	cfg.Username = "user"
+	cfg.Password = "pass"
+	cfg.DownloadDirectory = t.TempDir()
+	cfg.BindAddress = "127.0.0.1"
+	cfg.Port = 0
+	cfg.StateDirectory = t.TempDir()
+	cfg.Putio.APIKey = "key"
+
+	container, err := app.NewContainer(cfg, app.WithPutioClient(client), app.WithPutioValidation(false))
+	if err != nil {
+		t.Fatalf("failed to build container: %v", err)
+	}
+	container.Logger.SetLevel(logrus.ErrorLevel)
+	return container
+}
+
+// rpcRequest builds an authenticated RPC request carrying the session ID from
+// the X-Transmission-Session-Id handshake.
+func rpcRequest(t *testing.T, router http.Handler, body string) *http.Request {
+	t.Helper()
+	handshake := httptest.NewRequest(http.MethodGet, "/transmission/rpc", nil)
+	handshake.SetBasicAuth("user", "pass")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, handshake)
+	sessionID := rec.Header().Get("X-Transmission-Session-Id")
+	if rec.Code != http.StatusConflict || sessionID == "" {
+		t.Fatalf("session handshake failed: %d", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/transmission/rpc", strings.NewReader(body))
+	req.SetBasicAuth("user", "pass")
+	req.Header.Set("X-Transmission-Session-Id", sessionID)
+	return req
+}
+
+func TestNewSharesContainerClients(t *testing.T) {
+	client := &mockPutioClient{}
+	p := New(testContainer(t, client))
+
+	router := p.Server().GetRouter()
+	req := rpcRequest(t, router, `{"method":"torrent-get"}`)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Arguments struct {
+			Torrents []struct {
+				HashString string `json:"hashString"`
+			} `json:"torrents"`
+		} `json:"arguments"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Arguments.Torrents) != 1 || resp.Arguments.Torrents[0].HashString != "abcd" {
+		t.Errorf("expected the mock transfer in torrent-get, got %s", rec.Body.String())
+	}
+	if p.Manager() == nil {
+		t.Error("expected a download manager")
+	}
+}
+
+func TestRunStopsOnCancel(t *testing.T) {
+	client := &mockPutioClient{}
+	p := New(testContainer(t, client))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- p.Run(ctx) }()
+
+	deadline := time.After(2 * time.Second)
+	for client.calls() == 0 {
+		select {
+		case <-deadline:
+			t.Fatal("expected the download manager to poll the mock client")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected Run to return after cancel")
+	}
+}
+
+func TestRunDrainsManager(t *testing.T) {
+	client := &mockPutioClient{}
+	container := testContainer(t, client)
+	container.Config.DrainTimeout = config.Duration(time.Second)
+	p := New(container)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- p.Run(ctx) }()
+
+	deadline := time.After(2 * time.Second)
+	for client.calls() == 0 {
+		select {
+		case <-deadline:
+			t.Fatal("expected the download manager to poll the mock client")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected Run to return once the idle manager is drained")
+	}
+}
+
+func TestRunWatchesBlackhole(t *testing.T) {
+	container := testContainer(t, &mockPutioClient{})
+	dir := t.TempDir()
+	container.Config.Blackhole = &config.BlackholeConfig{Directory: dir}
+	hash := "c12fe1c06bba254a9dc9f519b335aa7c1367a88a"
+	path := filepath.Join(dir, "Show.magnet")
+	if err := os.WriteFile(path, []byte("magnet:?xt=urn:btih:"+hash), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Minute)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+	p := New(container)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- p.Run(ctx) }()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	deadline := time.After(2 * time.Second)
+	for {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatal("expected the blackhole file to be picked up")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	if !container.Ownership.Has(hash) {
+		t.Error("expected the added transfer to be claimed")
+	}
+}
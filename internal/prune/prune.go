@@ -0,0 +1,124 @@
+// Package prune finds and removes put.io clutter the proxy doesn't clean up:
+// old finished transfers and files in the managed folder that no transfer
+// refers to anymore.
+package prune
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ochronus/goputioarr/internal/services/putio"
+)
+
+// Options select what to prune.
+type Options struct {
+	// OlderThan is the minimum age of pruned transfers and files.
+	OlderThan time.Duration
+	// FolderID is the managed folder. Orphaned files are only looked for when
+	// it's set, as the root folder holds everything else on the account too.
+	FolderID int64
+	// Now is the time ages are measured from. It defaults to time.Now().
+	Now time.Time
+}
+
+// Plan is what would be pruned.
+type Plan struct {
+	Transfers []putio.Transfer
+	Files     []putio.FileResponse
+}
+
+// finished reports whether a transfer is done on put.io, successfully or not.
+func finished(t putio.Transfer) bool {
+	switch t.Status {
+	case "COMPLETED", "SEEDING", "ERROR":
+		return true
+	}
+	return false
+}
+
+// NewPlan lists the transfers and files matching opts.
+func NewPlan(client putio.ClientAPI, opts Options) (*Plan, error) {
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	cutoff := now.Add(-opts.OlderThan)
+
+	transfers, err := client.ListTransfers()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list transfers: %w", err)
+	}
+
+	plan := &Plan{}
+	// Files of transfers that stay aren't orphaned.
+	kept := make(map[int64]bool)
+	for _, t := range transfers.Transfers {
+		if finished(t) && transferBefore(t, cutoff) {
+			plan.Transfers = append(plan.Transfers, t)
+			continue
+		}
+		if t.FileID != nil {
+			kept[*t.FileID] = true
+		}
+	}
+
+	if opts.FolderID == 0 {
+		return plan, nil
+	}
+	files, err := client.ListFiles(opts.FolderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+	for _, f := range files.Files {
+		if kept[f.ID] {
+			continue
+		}
+		if created, ok := f.Created(); ok && created.Before(cutoff) {
+			plan.Files = append(plan.Files, f)
+		}
+	}
+	return plan, nil
+}
+
+// transferBefore reports whether t finished, or failing that was created,
+// before cutoff. Transfers of unknown age are kept.
+func transferBefore(t putio.Transfer, cutoff time.Time) bool {
+	at, ok := t.Finished()
+	if !ok {
+		if at, ok = t.Created(); !ok {
+			return false
+		}
+	}
+	return at.Before(cutoff)
+}
+
+// Empty reports whether there's nothing to prune.
+func (p *Plan) Empty() bool {
+	return len(p.Transfers) == 0 && len(p.Files) == 0
+}
+
+// Apply removes the planned transfers and deletes the planned files, batching
+// the put.io requests. It carries on past failures and returns them together.
+func (p *Plan) Apply(client putio.ClientAPI) error {
+	var errs []error
+	if len(p.Transfers) > 0 {
+		ids := make([]uint64, len(p.Transfers))
+		for i, t := range p.Transfers {
+			ids[i] = t.ID
+		}
+		if removed, err := putio.RemoveEach(client, ids); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove %d of %d transfers: %w", len(ids)-len(removed), len(ids), err))
+		}
+	}
+	if len(p.Files) > 0 {
+		ids := make([]int64, len(p.Files))
+		for i, f := range p.Files {
+			ids[i] = f.ID
+		}
+		if err := client.DeleteFiles(ids); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete %d files: %w", len(ids), err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,167 @@
+package prune
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ochronus/goputioarr/internal/services/putio"
+)
+
+type mockPutioClient struct {
+	transfers   []putio.Transfer
+	files       map[int64][]putio.FileResponse
+	listedFiles []int64
+	removed     []uint64
+	deleted     []int64
+	deleteErr   error
+	removeErrs  map[uint64]error
+}
+
+func (m *mockPutioClient) GetAccountInfo() (*putio.AccountInfoResponse, error) {
+	return &putio.AccountInfoResponse{}, nil
+}
+func (m *mockPutioClient) ListTransfers() (*putio.ListTransferResponse, error) {
+	return &putio.ListTransferResponse{Transfers: m.transfers}, nil
+}
+func (m *mockPutioClient) ListTransfersSince(uint64) (*putio.ListTransferResponse, error) {
+	return m.ListTransfers()
+}
+func (m *mockPutioClient) GetTransfer(id uint64) (*putio.GetTransferResponse, error) {
+	return &putio.GetTransferResponse{Transfer: putio.Transfer{ID: id}}, nil
+}
+func (m *mockPutioClient) RemoveTransfer(id uint64) error {
+	if err := m.removeErrs[id]; err != nil {
+		return err
+	}
+	m.removed = append(m.removed, id)
+	return nil
+}
+func (m *mockPutioClient) DeleteFile(id int64) error {
+	m.deleted = append(m.deleted, id)
+	return m.deleteErr
+}
+func (m *mockPutioClient) RemoveTransfers(ids []uint64) error {
+	for _, id := range ids {
+		if err := m.removeErrs[id]; err != nil {
+			return err
+		}
+	}
+	m.removed = append(m.removed, ids...)
+	return nil
+}
+func (m *mockPutioClient) RetryTransfer(uint64) error { return nil }
+func (m *mockPutioClient) DeleteFiles(ids []int64) error {
+	m.deleted = append(m.deleted, ids...)
+	return m.deleteErr
+}
+func (m *mockPutioClient) AddTransfer(string) (*putio.Transfer, error) { return nil, nil }
+func (m *mockPutioClient) UploadFile([]byte) (*putio.Transfer, error)  { return nil, nil }
+func (m *mockPutioClient) ListFiles(fileID int64) (*putio.ListFileResponse, error) {
+	m.listedFiles = append(m.listedFiles, fileID)
+	return &putio.ListFileResponse{Files: m.files[fileID]}, nil
+}
+func (m *mockPutioClient) GetFileURL(int64) (string, error) { return "", nil }
+
+var now = time.Date(2024, 6, 30, 12, 0, 0, 0, time.UTC)
+
+func daysAgo(days int) *string {
+	s := now.AddDate(0, 0, -days).Format("2006-01-02T15:04:05")
+	return &s
+}
+
+func fileID(id int64) *int64 {
+	return &id
+}
+
+func TestNewPlan(t *testing.T) {
+	client := &mockPutioClient{
+		transfers: []putio.Transfer{
+			{ID: 1, Status: "COMPLETED", FinishedAt: daysAgo(40), FileID: fileID(100)},
+			{ID: 2, Status: "SEEDING", FinishedAt: daysAgo(5), FileID: fileID(101)},
+			{ID: 3, Status: "DOWNLOADING", CreatedAt: daysAgo(60), FileID: fileID(102)},
+			{ID: 4, Status: "ERROR", CreatedAt: daysAgo(31)},
+			{ID: 5, Status: "COMPLETED"},
+		},
+		files: map[int64][]putio.FileResponse{
+			7: {
+				{ID: 100, Name: "pruned transfer", CreatedAt: daysAgo(40)},
+				{ID: 101, Name: "recent transfer", CreatedAt: daysAgo(40)},
+				{ID: 102, Name: "active transfer", CreatedAt: daysAgo(60)},
+				{ID: 103, Name: "orphan", CreatedAt: daysAgo(90)},
+				{ID: 104, Name: "new orphan", CreatedAt: daysAgo(1)},
+				{ID: 105, Name: "unknown age"},
+			},
+		},
+	}
+
+	plan, err := NewPlan(client, Options{OlderThan: 30 * 24 * time.Hour, FolderID: 7, Now: now})
+	if err != nil {
+		t.Fatalf("NewPlan: %v", err)
+	}
+
+	var transfers []uint64
+	for _, tr := range plan.Transfers {
+		transfers = append(transfers, tr.ID)
+	}
+	if len(transfers) != 2 || transfers[0] != 1 || transfers[1] != 4 {
+		t.Errorf("expected transfers 1 and 4, got %v", transfers)
+	}
+	var files []int64
+	for _, f := range plan.Files {
+		files = append(files, f.ID)
+	}
+	if len(files) != 2 || files[0] != 100 || files[1] != 103 {
+		t.Errorf("expected files 100 and 103, got %v", files)
+	}
+	if len(client.removed) != 0 || len(client.deleted) != 0 {
+		t.Error("expected planning not to change anything")
+	}
+}
+
+func TestNewPlanSkipsFilesWithoutFolder(t *testing.T) {
+	client := &mockPutioClient{
+		transfers: []putio.Transfer{{ID: 1, Status: "COMPLETED", FinishedAt: daysAgo(40)}},
+	}
+	plan, err := NewPlan(client, Options{OlderThan: 24 * time.Hour, Now: now})
+	if err != nil {
+		t.Fatalf("NewPlan: %v", err)
+	}
+	if len(client.listedFiles) != 0 {
+		t.Errorf("expected the root folder not to be listed, got %v", client.listedFiles)
+	}
+	if len(plan.Transfers) != 1 || plan.Empty() {
+		t.Errorf("unexpected plan %+v", plan)
+	}
+}
+
+func TestApply(t *testing.T) {
+	client := &mockPutioClient{deleteErr: errors.New("boom")}
+	plan := &Plan{
+		Transfers: []putio.Transfer{{ID: 1}, {ID: 2}},
+		Files:     []putio.FileResponse{{ID: 10, Name: "a"}, {ID: 11, Name: "b"}},
+	}
+
+	err := plan.Apply(client)
+	if err == nil || !strings.Contains(err.Error(), "failed to delete 2 files") {
+		t.Errorf("expected the delete failure, got %v", err)
+	}
+	if len(client.removed) != 2 || len(client.deleted) != 2 {
+		t.Errorf("expected every removal to be attempted, got %v and %v", client.removed, client.deleted)
+	}
+}
+
+func TestApplyRemovesTransfersOneByOneAfterBatchFailure(t *testing.T) {
+	client := &mockPutioClient{removeErrs: map[uint64]error{2: errors.New("boom")}}
+	plan := &Plan{Transfers: []putio.Transfer{{ID: 1}, {ID: 2}, {ID: 3}}}
+
+	err := plan.Apply(client)
+	if err == nil || !strings.Contains(err.Error(), "failed to remove 1 of 3 transfers") {
+		t.Errorf("expected the single failure, got %v", err)
+	}
+	if !reflect.DeepEqual(client.removed, []uint64{1, 3}) {
+		t.Errorf("expected the other transfers to be removed, got %v", client.removed)
+	}
+}
@@ -0,0 +1,556 @@
+// Package putiomock implements an in-memory put.io API covering the endpoints
+// the proxy uses. It backs end-to-end tests and the run command's demo mode.
+//
+// Transfers added through the API download for a configurable time, then
+// produce a folder holding a single video file and seed before completing.
+package putiomock
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ochronus/goputioarr/internal/services/putio"
+	"github.com/ochronus/goputioarr/internal/services/torrent"
+)
+
+const (
+	// DefaultDownloadTime is how long a transfer downloads on put.io.
+	DefaultDownloadTime = 10 * time.Second
+	// DefaultSeedTime is how long a finished transfer seeds.
+	DefaultSeedTime = 30 * time.Second
+	// DefaultFileSize is the size of the video file of each transfer.
+	DefaultFileSize = 1 << 20
+
+	timeLayout = "2006-01-02T15:04:05"
+)
+
+// file is a file or folder in the mock account.
+type file struct {
+	ID       int64
+	ParentID int64
+	Name     string
+	FileType string
+	Size     int64
+}
+
+// transfer is a transfer in the mock account.
+type transfer struct {
+	putio.Transfer
+	added time.Time
+}
+
+// Server is an in-memory put.io API. Create one with New and serve it with
+// Handler.
+type Server struct {
+	downloadTime time.Duration
+	seedTime     time.Duration
+	fileSize     int64
+	now          func() time.Time
+
+	mu             sync.Mutex
+	transfers      map[uint64]*transfer
+	files          map[int64]*file
+	nextTransferID uint64
+	nextFileID     int64
+}
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithDownloadTime sets how long transfers download before finishing.
+func WithDownloadTime(d time.Duration) Option {
+	return func(s *Server) {
+		s.downloadTime = d
+	}
+}
+
+// WithSeedTime sets how long finished transfers seed before completing.
+func WithSeedTime(d time.Duration) Option {
+	return func(s *Server) {
+		s.seedTime = d
+	}
+}
+
+// WithFileSize sets the size of the video file each transfer produces.
+func WithFileSize(n int64) Option {
+	return func(s *Server) {
+		if n > 0 {
+			s.fileSize = n
+		}
+	}
+}
+
+// WithClock overrides the time source (useful for tests).
+func WithClock(now func() time.Time) Option {
+	return func(s *Server) {
+		s.now = now
+	}
+}
+
+// New creates an empty mock account.
+func New(opts ...Option) *Server {
+	s := &Server{
+		downloadTime:   DefaultDownloadTime,
+		seedTime:       DefaultSeedTime,
+		fileSize:       DefaultFileSize,
+		now:            time.Now,
+		transfers:      make(map[uint64]*transfer),
+		files:          make(map[int64]*file),
+		nextTransferID: 1,
+		nextFileID:     100,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Handler returns the HTTP handler serving the API. Point both base URLs of
+// putio.NewClient (see putio.WithBaseURLs) at it.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /account/info", s.accountInfo)
+	mux.HandleFunc("GET /transfers/list", s.listTransfers)
+	mux.HandleFunc("POST /transfers/list/continue", s.listTransfers)
+	mux.HandleFunc("GET /transfers/{id}", s.getTransfer)
+	mux.HandleFunc("POST /transfers/add", s.addTransfer)
+	mux.HandleFunc("POST /transfers/remove", s.removeTransfers)
+	mux.HandleFunc("POST /transfers/retry", s.retryTransfer)
+	mux.HandleFunc("POST /files/upload", s.uploadFile)
+	mux.HandleFunc("GET /files/list", s.listFiles)
+	mux.HandleFunc("POST /files/list/continue", s.listFiles)
+	mux.HandleFunc("POST /files/delete", s.deleteFiles)
+	mux.HandleFunc("GET /files/{id}/url", s.fileURL)
+	mux.HandleFunc("GET /download/{id}", s.download)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Download URLs are pre-signed on put.io, so only API calls carry a token.
+		if !strings.HasPrefix(r.URL.Path, "/download/") && !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+			writeError(w, http.StatusUnauthorized, "missing token")
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// Add creates a transfer as if it had been added to the account directly.
+func (s *Server) Add(name, hash string) putio.Transfer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addLocked(name, hash, 0).Transfer
+}
+
+// Transfers returns the current transfers, ordered by ID.
+func (s *Server) Transfers() []putio.Transfer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.transfersLocked()
+}
+
+// FileCount returns the number of files and folders in the account.
+func (s *Server) FileCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.files)
+}
+
+func (s *Server) addLocked(name, hash string, parentID int64) *transfer {
+	if name == "" {
+		name = "transfer-" + strconv.FormatUint(s.nextTransferID, 10)
+	}
+	now := s.now().UTC()
+	created := now.Format(timeLayout)
+	t := &transfer{
+		Transfer: putio.Transfer{
+			ID:        s.nextTransferID,
+			Name:      stringPtr(name),
+			Size:      int64Ptr(s.fileSize),
+			Status:    "IN_QUEUE",
+			CreatedAt: &created,
+			StartedAt: &created,
+		},
+		added: now,
+	}
+	if hash != "" {
+		t.Hash = stringPtr(strings.ToLower(hash))
+	}
+	if parentID != 0 {
+		t.SaveParentID = int64Ptr(parentID)
+	}
+	s.transfers[t.ID] = t
+	s.nextTransferID++
+	s.advanceLocked(t)
+	return t
+}
+
+// advanceLocked moves a transfer along its lifecycle based on its age.
+func (s *Server) advanceLocked(t *transfer) {
+	age := s.now().Sub(t.added)
+	switch {
+	case age < s.downloadTime:
+		downloaded := s.fileSize
+		if s.downloadTime > 0 {
+			downloaded = int64(float64(s.fileSize) * age.Seconds() / s.downloadTime.Seconds())
+		}
+		remaining := int64((s.downloadTime - age).Seconds())
+		t.Status = "DOWNLOADING"
+		t.Downloaded = &downloaded
+		t.EstimatedTime = &remaining
+		return
+	case age < s.downloadTime+s.seedTime:
+		t.Status = "SEEDING"
+	default:
+		t.Status = "COMPLETED"
+	}
+
+	t.Downloaded = int64Ptr(s.fileSize)
+	t.EstimatedTime = nil
+	if t.FileID == nil {
+		s.createFilesLocked(t)
+	}
+	if t.FinishedAt == nil {
+		finished := t.added.Add(s.downloadTime).UTC().Format(timeLayout)
+		t.FinishedAt = &finished
+	}
+}
+
+// createFilesLocked stores the folder and video file a finished transfer produced.
+func (s *Server) createFilesLocked(t *transfer) {
+	var parentID int64
+	if t.SaveParentID != nil {
+		parentID = *t.SaveParentID
+	}
+	folder := &file{ID: s.nextFileID, ParentID: parentID, Name: *t.Name, FileType: "FOLDER"}
+	video := &file{ID: s.nextFileID + 1, ParentID: folder.ID, Name: *t.Name + ".mkv", FileType: "VIDEO", Size: s.fileSize}
+	s.nextFileID += 2
+	s.files[folder.ID] = folder
+	s.files[video.ID] = video
+	t.FileID = int64Ptr(folder.ID)
+	t.UserfileExists = true
+}
+
+func (s *Server) transfersLocked() []putio.Transfer {
+	transfers := make([]putio.Transfer, 0, len(s.transfers))
+	for _, t := range s.transfers {
+		s.advanceLocked(t)
+		transfers = append(transfers, t.Transfer)
+	}
+	sort.Slice(transfers, func(i, j int) bool { return transfers[i].ID < transfers[j].ID })
+	return transfers
+}
+
+func (s *Server) accountInfo(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, putio.AccountInfoResponse{Info: putio.AccountInfo{
+		Username:      "demo",
+		Mail:          "demo@example.com",
+		AccountActive: true,
+	}})
+}
+
+func (s *Server) listTransfers(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	transfers := s.transfersLocked()
+	s.mu.Unlock()
+
+	start, end, cursor, err := page(r, len(transfers))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, putio.ListTransferResponse{Transfers: transfers[start:end], Cursor: cursor})
+}
+
+func (s *Server) getTransfer(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid transfer id")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.transfers[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "transfer not found")
+		return
+	}
+	s.advanceLocked(t)
+	writeJSON(w, putio.GetTransferResponse{Transfer: t.Transfer})
+}
+
+func (s *Server) addTransfer(w http.ResponseWriter, r *http.Request) {
+	link := r.FormValue("url")
+	magnet, err := torrent.ParseMagnet(link)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	parentID, _ := strconv.ParseInt(r.FormValue("save_parent_id"), 10, 64)
+
+	s.mu.Lock()
+	t := s.addLocked(magnet.Name, magnet.InfoHash, parentID)
+	s.mu.Unlock()
+	writeJSON(w, map[string]interface{}{"transfer": t.Transfer})
+}
+
+func (s *Server) uploadFile(w http.ResponseWriter, r *http.Request) {
+	upload, _, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "missing file")
+		return
+	}
+	defer upload.Close()
+	data, err := io.ReadAll(upload)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	meta, err := torrent.ParseMetainfo(data)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	parentID, _ := strconv.ParseInt(r.FormValue("save_parent_id"), 10, 64)
+
+	s.mu.Lock()
+	t := s.addLocked(meta.Name, meta.InfoHash, parentID)
+	s.mu.Unlock()
+	writeJSON(w, map[string]interface{}{"transfer": t.Transfer})
+}
+
+func (s *Server) removeTransfers(w http.ResponseWriter, r *http.Request) {
+	ids, err := parseIDs(r.FormValue("transfer_ids"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	for _, id := range ids {
+		delete(s.transfers, uint64(id))
+	}
+	s.mu.Unlock()
+	writeJSON(w, map[string]string{"status": "OK"})
+}
+
+// retryTransfer starts an unfinished transfer's download over.
+func (s *Server) retryTransfer(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(r.FormValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.transfers[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "transfer not found")
+		return
+	}
+	if t.FileID == nil {
+		t.added = s.now()
+		s.advanceLocked(t)
+	}
+	writeJSON(w, map[string]interface{}{"transfer": t.Transfer})
+}
+
+func (s *Server) listFiles(w http.ResponseWriter, r *http.Request) {
+	parentID, err := strconv.ParseInt(r.FormValue("parent_id"), 10, 64)
+	if err != nil && r.FormValue("cursor") == "" {
+		writeError(w, http.StatusBadRequest, "invalid parent_id")
+		return
+	}
+	if cursor := r.FormValue("cursor"); cursor != "" {
+		// Continuation cursors encode the parent and the offset.
+		parent, _, _ := strings.Cut(cursor, ":")
+		parentID, _ = strconv.ParseInt(parent, 10, 64)
+	}
+
+	s.mu.Lock()
+	parent, ok := s.files[parentID]
+	var children []putio.FileResponse
+	for _, f := range s.files {
+		if f.ParentID == parentID {
+			children = append(children, fileResponse(f))
+		}
+	}
+	s.mu.Unlock()
+
+	if !ok && parentID != 0 {
+		writeError(w, http.StatusNotFound, "file not found")
+		return
+	}
+	sort.Slice(children, func(i, j int) bool { return children[i].ID < children[j].ID })
+
+	start, end, cursor, err := page(r, len(children))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if cursor != nil {
+		next := fmt.Sprintf("%d:%s", parentID, *cursor)
+		cursor = &next
+	}
+
+	resp := putio.ListFileResponse{Files: children[start:end], Cursor: cursor}
+	if ok {
+		resp.Parent = fileResponse(parent)
+	} else {
+		resp.Parent = putio.FileResponse{ID: 0, Name: "root", FileType: "FOLDER"}
+	}
+	writeJSON(w, resp)
+}
+
+func (s *Server) deleteFiles(w http.ResponseWriter, r *http.Request) {
+	ids, err := parseIDs(r.FormValue("file_ids"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	for _, id := range ids {
+		s.deleteFileLocked(id)
+	}
+	s.mu.Unlock()
+	writeJSON(w, map[string]string{"status": "OK"})
+}
+
+func (s *Server) deleteFileLocked(id int64) {
+	for _, f := range s.files {
+		if f.ParentID == id {
+			s.deleteFileLocked(f.ID)
+		}
+	}
+	delete(s.files, id)
+}
+
+func (s *Server) fileURL(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid file id")
+		return
+	}
+	s.mu.Lock()
+	_, ok := s.files[id]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "file not found")
+		return
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	writeJSON(w, putio.URLResponse{URL: fmt.Sprintf("%s://%s/download/%d", scheme, r.Host, id)})
+}
+
+func (s *Server) download(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid file id")
+		return
+	}
+	s.mu.Lock()
+	f, ok := s.files[id]
+	s.mu.Unlock()
+	if !ok || f.FileType == "FOLDER" {
+		writeError(w, http.StatusNotFound, "file not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "video/x-matroska")
+	w.Header().Set("Content-Length", strconv.FormatInt(f.Size, 10))
+	chunk := make([]byte, 32*1024)
+	for i := range chunk {
+		chunk[i] = byte(i)
+	}
+	for remaining := f.Size; remaining > 0; {
+		n := int64(len(chunk))
+		if remaining < n {
+			n = remaining
+		}
+		if _, err := w.Write(chunk[:n]); err != nil {
+			return
+		}
+		remaining -= n
+	}
+}
+
+// page returns the slice bounds for the requested page of n items and the
+// cursor of the next page, if any. The cursor is the offset of the next page.
+func page(r *http.Request, n int) (start, end int, cursor *string, err error) {
+	perPage := n
+	if v := r.FormValue("per_page"); v != "" {
+		perPage, err = strconv.Atoi(v)
+		if err != nil || perPage <= 0 {
+			return 0, 0, nil, fmt.Errorf("invalid per_page")
+		}
+	}
+	if v := r.FormValue("cursor"); v != "" {
+		if _, offset, found := strings.Cut(v, ":"); found {
+			v = offset
+		}
+		start, err = strconv.Atoi(v)
+		if err != nil || start < 0 || start > n {
+			return 0, 0, nil, fmt.Errorf("invalid cursor")
+		}
+	}
+	end = start + perPage
+	if end >= n {
+		return start, n, nil, nil
+	}
+	next := strconv.Itoa(end)
+	return start, end, &next, nil
+}
+
+func parseIDs(value string) ([]int64, error) {
+	var ids []int64
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid id %q", part)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func fileResponse(f *file) putio.FileResponse {
+	contentType := "application/x-directory"
+	if f.FileType == "VIDEO" {
+		contentType = "video/x-matroska"
+	}
+	return putio.FileResponse{ID: f.ID, Name: f.Name, FileType: f.FileType, ContentType: contentType, Size: f.Size}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"status": "ERROR", "error_message": message})
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func int64Ptr(n int64) *int64 {
+	return &n
+}
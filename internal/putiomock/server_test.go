@@ -0,0 +1,192 @@
+package putiomock
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ochronus/goputioarr/internal/services/putio"
+)
+
+// fakeClock is a manually advanced time source.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newTestServer(t *testing.T, opts ...Option) (*Server, *putio.Client) {
+	t.Helper()
+	mock := New(opts...)
+	srv := httptest.NewServer(mock.Handler())
+	t.Cleanup(srv.Close)
+	client := putio.NewClient("token", putio.WithBaseURLs(srv.URL, srv.URL), putio.WithHTTPClient(srv.Client()))
+	return mock, client
+}
+
+func TestTransferLifecycle(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	mock, client := newTestServer(t,
+		WithClock(clock.Now),
+		WithDownloadTime(10*time.Second),
+		WithSeedTime(10*time.Second),
+		WithFileSize(4096),
+	)
+
+	added, err := client.AddTransfer("magnet:?xt=urn:btih:C12FE1C06BBA254A9DC9F519B335AA7C1367A88A&dn=Show")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if added == nil || *added.Name != "Show" || *added.Hash != "c12fe1c06bba254a9dc9f519b335aa7c1367a88a" {
+		t.Fatalf("unexpected transfer %+v", added)
+	}
+
+	clock.Advance(5 * time.Second)
+	got, err := client.GetTransfer(added.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Transfer.Status != "DOWNLOADING" || *got.Transfer.Downloaded != 2048 || got.Transfer.IsDownloadable() {
+		t.Errorf("expected transfer halfway through downloading, got %+v", got.Transfer)
+	}
+
+	clock.Advance(5 * time.Second)
+	got, _ = client.GetTransfer(added.ID)
+	if got.Transfer.Status != "SEEDING" || !got.Transfer.IsDownloadable() {
+		t.Fatalf("expected seeding transfer with files, got %+v", got.Transfer)
+	}
+
+	folder, err := client.ListFiles(*got.Transfer.FileID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if folder.Parent.FileType != "FOLDER" || len(folder.Files) != 1 || folder.Files[0].FileType != "VIDEO" {
+		t.Fatalf("unexpected listing %+v", folder)
+	}
+
+	url, err := client.GetFileURL(folder.Files[0].ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || len(body) != 4096 {
+		t.Errorf("expected 4096 byte download, got %d bytes (status %d)", len(body), resp.StatusCode)
+	}
+
+	clock.Advance(10 * time.Second)
+	got, _ = client.GetTransfer(added.ID)
+	if got.Transfer.Status != "COMPLETED" {
+		t.Errorf("expected completed transfer, got %s", got.Transfer.Status)
+	}
+
+	if err := client.RemoveTransfer(added.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := client.DeleteFile(*got.Transfer.FileID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(mock.Transfers()) != 0 || mock.FileCount() != 0 {
+		t.Errorf("expected empty account, got %d transfers and %d files", len(mock.Transfers()), mock.FileCount())
+	}
+}
+
+func TestRetryTransferRestartsDownload(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	_, client := newTestServer(t, WithClock(clock.Now), WithDownloadTime(10*time.Second), WithFileSize(4096))
+
+	added, err := client.AddTransfer("magnet:?xt=urn:btih:C12FE1C06BBA254A9DC9F519B335AA7C1367A88A&dn=Show")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	clock.Advance(5 * time.Second)
+	if err := client.RetryTransfer(added.ID); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := client.GetTransfer(added.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Transfer.Status != "DOWNLOADING" || *got.Transfer.Downloaded != 0 {
+		t.Errorf("expected the download to start over, got %+v", got.Transfer)
+	}
+
+	if err := client.RetryTransfer(added.ID + 1); err == nil {
+		t.Error("expected an error for an unknown transfer")
+	}
+}
+
+func TestUploadTorrent(t *testing.T) {
+	mock := New(WithDownloadTime(0))
+	srv := httptest.NewServer(mock.Handler())
+	defer srv.Close()
+	client := putio.NewClient("token", putio.WithBaseURLs(srv.URL, srv.URL), putio.WithSaveParentID(77))
+
+	metainfo := []byte("d4:infod6:lengthi10e4:name8:file.mkv12:piece lengthi16384e6:pieces0:ee")
+	added, err := client.UploadFile(metainfo)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if added == nil || *added.Name != "file.mkv" || added.Hash == nil || added.SaveParentID == nil || *added.SaveParentID != 77 {
+		t.Fatalf("unexpected transfer %+v", added)
+	}
+	if !added.IsDownloadable() {
+		t.Error("expected transfer without download time to finish right away")
+	}
+}
+
+func TestListTransfersPaginates(t *testing.T) {
+	mock := New()
+	srv := httptest.NewServer(mock.Handler())
+	defer srv.Close()
+	client := putio.NewClient("token", putio.WithBaseURLs(srv.URL, srv.URL), putio.WithTransfersPerPage(2))
+
+	for i := 0; i < 5; i++ {
+		mock.Add("", "")
+	}
+	resp, err := client.ListTransfers()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.Transfers) != 5 {
+		t.Fatalf("expected 5 transfers across pages, got %d", len(resp.Transfers))
+	}
+	for i, transfer := range resp.Transfers {
+		if transfer.ID != uint64(i+1) {
+			t.Errorf("expected transfer %d at position %d, got %d", i+1, i, transfer.ID)
+		}
+	}
+}
+
+func TestRequiresToken(t *testing.T) {
+	mock := New()
+	srv := httptest.NewServer(mock.Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/transfers/list")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401, got %d", resp.StatusCode)
+	}
+}
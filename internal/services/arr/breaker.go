@@ -0,0 +1,177 @@
+package arr
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultFailureThreshold is the number of consecutive failed requests
+	// that opens the circuit
+	DefaultFailureThreshold = 3
+	// DefaultCooldown is how long requests are skipped once the circuit opens
+	DefaultCooldown = 5 * time.Minute
+)
+
+// ErrCircuitOpen is returned instead of calling a service that keeps failing
+var ErrCircuitOpen = errors.New("service is failing, requests are paused")
+
+// Breaker wraps a ClientAPI with a circuit breaker, so a service that is down
+// doesn't stall every import check on retries. After a number of consecutive
+// failures the circuit opens and calls fail fast with ErrCircuitOpen for the
+// cooldown period. Then a single trial call is let through: success closes the
+// circuit, failure opens it for another cooldown.
+type Breaker struct {
+	client    ClientAPI
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+	onChange  func(open bool, err error)
+
+	mu        sync.Mutex
+	failures  int
+	open      bool
+	openUntil time.Time
+	trial     bool
+}
+
+var _ ClientAPI = (*Breaker)(nil)
+
+// BreakerOption configures the Breaker
+type BreakerOption func(*Breaker)
+
+// WithFailureThreshold sets the number of consecutive failures that opens the
+// circuit. Zero keeps the default
+func WithFailureThreshold(n int) BreakerOption {
+	return func(b *Breaker) {
+		if n > 0 {
+			b.threshold = n
+		}
+	}
+}
+
+// WithCooldown sets how long calls are skipped once the circuit opens. Zero
+// keeps the default
+func WithCooldown(d time.Duration) BreakerOption {
+	return func(b *Breaker) {
+		if d > 0 {
+			b.cooldown = d
+		}
+	}
+}
+
+// WithStateChange registers fn to be called when the circuit opens or closes,
+// with the error that opened it
+func WithStateChange(fn func(open bool, err error)) BreakerOption {
+	return func(b *Breaker) {
+		b.onChange = fn
+	}
+}
+
+// NewBreaker wraps client with a circuit breaker
+func NewBreaker(client ClientAPI, opts ...BreakerOption) *Breaker {
+	b := &Breaker{
+		client:    client,
+		threshold: DefaultFailureThreshold,
+		cooldown:  DefaultCooldown,
+		now:       time.Now,
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// Open reports whether the circuit is open
+func (b *Breaker) Open() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.open
+}
+
+// CheckImported calls the wrapped client unless the circuit is open
+func (b *Breaker) CheckImported(targetPath string) (bool, error) {
+	var imported bool
+	err := b.call(func() (err error) {
+		imported, err = b.client.CheckImported(targetPath)
+		return err
+	})
+	return imported, err
+}
+
+// FindImports calls the wrapped client unless the circuit is open
+func (b *Breaker) FindImports(targetPaths []string) (map[string]Import, error) {
+	var imports map[string]Import
+	err := b.call(func() (err error) {
+		imports, err = b.client.FindImports(targetPaths)
+		return err
+	})
+	return imports, err
+}
+
+// Blocklist calls the wrapped client unless the circuit is open
+func (b *Breaker) Blocklist(downloadID string) (bool, error) {
+	var found bool
+	err := b.call(func() (err error) {
+		found, err = b.client.Blocklist(downloadID)
+		return err
+	})
+	return found, err
+}
+
+// SystemStatus calls the wrapped client unless the circuit is open
+func (b *Breaker) SystemStatus() (*SystemStatus, error) {
+	var status *SystemStatus
+	err := b.call(func() (err error) {
+		status, err = b.client.SystemStatus()
+		return err
+	})
+	return status, err
+}
+
+func (b *Breaker) call(fn func() error) error {
+	if !b.allow() {
+		return ErrCircuitOpen
+	}
+	err := fn()
+	b.record(err)
+	return err
+}
+
+// allow reports whether a call may go through: always while the circuit is
+// closed, and once the cooldown has passed for a single trial call
+func (b *Breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.open {
+		return true
+	}
+	if b.trial || b.now().Before(b.openUntil) {
+		return false
+	}
+	b.trial = true
+	return true
+}
+
+func (b *Breaker) record(err error) {
+	b.mu.Lock()
+	wasOpen := b.open
+	b.trial = false
+	if err == nil {
+		b.failures = 0
+		b.open = false
+	} else {
+		b.failures++
+		if b.open || b.failures >= b.threshold {
+			b.open = true
+			b.openUntil = b.now().Add(b.cooldown)
+		}
+	}
+	open := b.open
+	b.mu.Unlock()
+
+	if open != wasOpen && b.onChange != nil {
+		b.onChange(open, err)
+	}
+}
@@ -0,0 +1,102 @@
+package arr
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type stubClient struct {
+	calls int
+	err   error
+}
+
+func (s *stubClient) CheckImported(string) (bool, error) {
+	s.calls++
+	return s.err == nil, s.err
+}
+
+func (s *stubClient) FindImports(paths []string) (map[string]Import, error) {
+	s.calls++
+	return map[string]Import{paths[0]: {ImportedPath: "/library/file.mkv", Exact: true}}, s.err
+}
+
+func (s *stubClient) Blocklist(string) (bool, error) {
+	s.calls++
+	return true, s.err
+}
+
+func (s *stubClient) SystemStatus() (*SystemStatus, error) {
+	s.calls++
+	return &SystemStatus{AppName: "Sonarr"}, s.err
+}
+
+func TestBreakerOpensAfterConsecutiveFailures(t *testing.T) {
+	stub := &stubClient{err: errors.New("connection refused")}
+	now := time.Unix(0, 0)
+	var changes []bool
+	breaker := NewBreaker(stub,
+		WithFailureThreshold(2),
+		WithCooldown(time.Minute),
+		WithStateChange(func(open bool, err error) { changes = append(changes, open) }),
+	)
+	breaker.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if _, err := breaker.CheckImported("/downloads/file.mkv"); !errors.Is(err, stub.err) {
+			t.Fatalf("expected the client error, got %v", err)
+		}
+	}
+	if !breaker.Open() {
+		t.Fatal("expected the circuit to open after 2 failures")
+	}
+
+	if _, err := breaker.FindImports([]string{"/downloads/file.mkv"}); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("expected ErrCircuitOpen, got %v", err)
+	}
+	if stub.calls != 2 {
+		t.Errorf("expected calls to be skipped while open, got %d calls", stub.calls)
+	}
+
+	// After the cooldown a failing trial call reopens the circuit.
+	now = now.Add(time.Minute)
+	if _, err := breaker.Blocklist("hash"); !errors.Is(err, stub.err) {
+		t.Errorf("expected the trial call to reach the client, got %v", err)
+	}
+	if _, err := breaker.SystemStatus(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("expected the circuit to reopen, got %v", err)
+	}
+
+	// A successful trial call closes it.
+	now = now.Add(time.Minute)
+	stub.err = nil
+	if imported, err := breaker.CheckImported("/downloads/file.mkv"); err != nil || !imported {
+		t.Errorf("expected the trial call to succeed, got %v, %v", imported, err)
+	}
+	if breaker.Open() {
+		t.Error("expected the circuit to close")
+	}
+	if len(changes) != 2 || !changes[0] || changes[1] {
+		t.Errorf("expected one open and one close notification, got %v", changes)
+	}
+}
+
+func TestBreakerResetsFailuresOnSuccess(t *testing.T) {
+	stub := &stubClient{}
+	breaker := NewBreaker(stub, WithFailureThreshold(2))
+
+	for _, err := range []error{errors.New("timeout"), nil, errors.New("timeout")} {
+		stub.err = err
+		breaker.CheckImported("/downloads/file.mkv")
+	}
+	if breaker.Open() {
+		t.Error("expected non-consecutive failures to keep the circuit closed")
+	}
+}
+
+func TestBreakerDefaults(t *testing.T) {
+	breaker := NewBreaker(&stubClient{}, WithFailureThreshold(0), WithCooldown(0))
+	if breaker.threshold != DefaultFailureThreshold || breaker.cooldown != DefaultCooldown {
+		t.Errorf("expected defaults, got %d/%v", breaker.threshold, breaker.cooldown)
+	}
+}
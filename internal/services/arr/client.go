@@ -1,10 +1,15 @@
 package arr
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"path"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/ochronus/goputioarr/internal/services/retry"
@@ -14,28 +19,122 @@ const (
 	timeout     = 30 * time.Second
 	maxRetries  = 3
 	backoffBase = 200 * time.Millisecond
+
+	// DefaultAPIVersion is the API version used unless configured or discovered
+	DefaultAPIVersion = "v3"
 )
 
 // Client represents an Arr (Sonarr/Radarr/Whisparr) API client
 type Client struct {
-	baseURL    string
-	apiKey     string
-	httpClient *http.Client
-	sleeper    func(time.Duration)
+	baseURL           string
+	apiKey            string
+	httpClient        *http.Client
+	sleeper           func(time.Duration)
+	matchImportedPath bool
+	caseInsensitive   bool
+	historyPath       string
+	maxRetries        int
+	backoff           time.Duration
+
+	versionMu    sync.Mutex
+	apiVersion   string
+	versionFixed bool
 }
 
 var _ ClientAPI = (*Client)(nil)
 
+// ClientOption configures the Client
+type ClientOption func(*Client)
+
+// WithImportedPathMatching also matches history records on importedPath, and
+// on droppedPath when it is a parent directory of the target (folder imports)
+func WithImportedPathMatching(enabled bool) ClientOption {
+	return func(c *Client) {
+		c.matchImportedPath = enabled
+	}
+}
+
+// WithCaseInsensitivePaths compares paths case-insensitively
+func WithCaseInsensitivePaths(enabled bool) ClientOption {
+	return func(c *Client) {
+		c.caseInsensitive = enabled
+	}
+}
+
+// WithAPIVersion pins the API version (e.g. "v1"), disabling discovery. An
+// empty version keeps the default with discovery
+func WithAPIVersion(version string) ClientOption {
+	return func(c *Client) {
+		if version != "" {
+			c.apiVersion = version
+			c.versionFixed = true
+		}
+	}
+}
+
+// WithHistoryPath overrides the path of the history endpoint (e.g.
+// "/api/v1/history"). An empty path keeps the default
+func WithHistoryPath(historyPath string) ClientOption {
+	return func(c *Client) {
+		c.historyPath = historyPath
+	}
+}
+
+// WithTimeout sets the timeout of each HTTP request. Zero keeps the default
+func WithTimeout(d time.Duration) ClientOption {
+	return func(c *Client) {
+		if d > 0 {
+			c.httpClient.Timeout = d
+		}
+	}
+}
+
+// WithTransport sets the round tripper used for every request.
+func WithTransport(rt http.RoundTripper) ClientOption {
+	return func(c *Client) {
+		if rt != nil {
+			c.httpClient.Transport = rt
+		}
+	}
+}
+
+// WithMaxRetries sets the number of attempts per request, including the first.
+// Zero keeps the default
+func WithMaxRetries(n int) ClientOption {
+	return func(c *Client) {
+		if n > 0 {
+			c.maxRetries = n
+		}
+	}
+}
+
+// WithBackoff sets the delay before the first retry; it doubles for each
+// further retry. Zero keeps the default
+func WithBackoff(d time.Duration) ClientOption {
+	return func(c *Client) {
+		if d > 0 {
+			c.backoff = d
+		}
+	}
+}
+
 // NewClient creates a new Arr client
-func NewClient(baseURL, apiKey string) *Client {
-	return &Client{
+func NewClient(baseURL, apiKey string, opts ...ClientOption) *Client {
+	c := &Client{
 		baseURL: baseURL,
 		apiKey:  apiKey,
 		httpClient: &http.Client{
 			Timeout: timeout,
 		},
-		sleeper: time.Sleep,
+		sleeper:    time.Sleep,
+		maxRetries: maxRetries,
+		backoff:    backoffBase,
+		apiVersion: DefaultAPIVersion,
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
+	return c
 }
 
 // HistoryResponse represents the API response for history
@@ -61,13 +160,50 @@ func (e *HTTPError) Error() string {
 	return fmt.Sprintf("url: %s, status: %s", e.URL, e.Status)
 }
 
+// Hint returns what to check for the error's status, or "" if there's nothing
+// the user can do about it.
+func (e *HTTPError) Hint() string {
+	switch {
+	case e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden:
+		return "check the service's api_key, found under Settings > General in its web UI"
+	case e.StatusCode == http.StatusNotFound:
+		return "check that the service's url includes its URL base, e.g. http://localhost:8989/sonarr"
+	case e.StatusCode >= 500:
+		return "the service is having problems; check its logs"
+	}
+	return ""
+}
+
+// ConnectionError is a request that got no response from an arr service.
+type ConnectionError struct {
+	URL string
+	Err error
+}
+
+func (e *ConnectionError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ConnectionError) Unwrap() error {
+	return e.Err
+}
+
+// Hint returns what to check to reach the service.
+func (e *ConnectionError) Hint() string {
+	var netErr net.Error
+	if errors.Is(e.Err, context.DeadlineExceeded) || (errors.As(e.Err, &netErr) && netErr.Timeout()) {
+		return "the service took too long to answer; check its load, or raise its timeout"
+	}
+	return "check that the service is running and reachable at its configured url"
+}
+
 // doRequest executes an HTTP request with the API key header and retries with backoff on 5xx/429
 func (c *Client) doRequest(method, url string) (*http.Response, error) {
 	var respOut *http.Response
 
 	err := retry.Do(nil, retry.Config{
-		MaxRetries: maxRetries,
-		BaseDelay:  backoffBase,
+		MaxRetries: c.maxRetries,
+		BaseDelay:  c.backoff,
 		ShouldRetry: func(err error) bool {
 			if err == nil {
 				return false
@@ -79,7 +215,7 @@ func (c *Client) doRequest(method, url string) (*http.Response, error) {
 			return true
 		},
 		DelayFunc: func(attempt int, err error) time.Duration {
-			fallback := backoffBase * time.Duration(1<<attempt)
+			fallback := c.backoff * time.Duration(1<<attempt)
 			var httpErr *HTTPError
 			if errors.As(err, &httpErr) && httpErr.RetryAfter != "" {
 				return retry.RetryAfterDelay(httpErr.RetryAfter, fallback)
@@ -97,7 +233,7 @@ func (c *Client) doRequest(method, url string) (*http.Response, error) {
 
 		resp, err := c.httpClient.Do(req)
 		if err != nil {
-			return err
+			return &ConnectionError{URL: url, Err: err}
 		}
 
 		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
@@ -117,48 +253,329 @@ func (c *Client) doRequest(method, url string) (*http.Response, error) {
 	return respOut, nil
 }
 
+// apiInfo is the response of the /api discovery endpoint
+type apiInfo struct {
+	Current    string   `json:"current"`
+	Deprecated []string `json:"deprecated"`
+}
+
+// endpointURL returns the URL of an API endpoint such as "history" or
+// "queue/12", honoring the configured history path
+func (c *Client) endpointURL(endpoint, query string) string {
+	u := c.baseURL + c.endpointPath(endpoint)
+	if query != "" {
+		u += "?" + query
+	}
+	return u
+}
+
+func (c *Client) endpointPath(endpoint string) string {
+	if endpoint == "history" && c.historyPath != "" {
+		return c.historyPath
+	}
+	c.versionMu.Lock()
+	defer c.versionMu.Unlock()
+	return "/api/" + c.apiVersion + "/" + endpoint
+}
+
+// get requests an API endpoint. When the endpoint doesn't exist and the API
+// version wasn't configured, the version is discovered once through /api and
+// the request retried, so services on other API versions work unconfigured
+func (c *Client) get(endpoint, query string) (*http.Response, string, error) {
+	url := c.endpointURL(endpoint, query)
+	resp, err := c.doRequest(http.MethodGet, url)
+	if err != nil || resp.StatusCode != http.StatusNotFound {
+		return resp, url, err
+	}
+	if endpoint == "history" && c.historyPath != "" {
+		return resp, url, nil
+	}
+	changed, discoverErr := c.discoverAPIVersion()
+	if discoverErr != nil || !changed {
+		return resp, url, nil
+	}
+
+	resp.Body.Close()
+	url = c.endpointURL(endpoint, query)
+	resp, err = c.doRequest(http.MethodGet, url)
+	return resp, url, err
+}
+
+// discoverAPIVersion asks the service for its current API version and reports
+// whether it differs from the one in use. Discovery happens at most once
+// successfully, and never when the version was configured
+func (c *Client) discoverAPIVersion() (bool, error) {
+	c.versionMu.Lock()
+	defer c.versionMu.Unlock()
+	if c.versionFixed {
+		return false, nil
+	}
+
+	url := c.baseURL + "/api"
+	resp, err := c.doRequest(http.MethodGet, url)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		// Older versions have no discovery endpoint; keep the default
+		c.versionFixed = true
+		return false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, &HTTPError{URL: url, StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+
+	var info apiInfo
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return false, fmt.Errorf("url: %s, error decoding response: %w", url, err)
+	}
+	c.versionFixed = true
+	if info.Current == "" || info.Current == c.apiVersion {
+		return false, nil
+	}
+	c.apiVersion = info.Current
+	return true, nil
+}
+
 // CheckImported checks if a file has been imported by checking the history
 func (c *Client) CheckImported(targetPath string) (bool, error) {
+	record, err := c.findImport(targetPath)
+	if err != nil {
+		return false, err
+	}
+	return record != nil, nil
+}
+
+// Import describes where an arr imported a download path to.
+type Import struct {
+	// ImportedPath is the library path recorded by the arr.
+	ImportedPath string
+	// Exact is set when the record's droppedPath is the download path
+	// itself, rather than a folder holding it or a path it holds.
+	Exact bool
+}
+
+// FindImports returns the import recorded in the history for each of
+// targetPaths that has one, reading the history once. Paths matching several
+// records get the first, preferring an exact match.
+func (c *Client) FindImports(targetPaths []string) (map[string]Import, error) {
+	imports := make(map[string]Import, len(targetPaths))
+	err := c.eachImportRecord(func(record HistoryRecord) bool {
+		for _, targetPath := range targetPaths {
+			if found, ok := imports[targetPath]; ok && found.Exact {
+				continue
+			}
+			if !c.recordMatches(record, targetPath) {
+				continue
+			}
+			exact := c.normalizePath(record.Data["droppedPath"]) == c.normalizePath(targetPath)
+			if _, ok := imports[targetPath]; !ok || exact {
+				imports[targetPath] = Import{ImportedPath: record.Data["importedPath"], Exact: exact}
+			}
+		}
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+	return imports, nil
+}
+
+// findImport returns the import history record matching targetPath, or nil
+func (c *Client) findImport(targetPath string) (*HistoryRecord, error) {
+	var found *HistoryRecord
+	err := c.eachImportRecord(func(record HistoryRecord) bool {
+		if c.recordMatches(record, targetPath) {
+			found = &record
+			return true
+		}
+		return false
+	})
+	return found, err
+}
+
+// eachImportRecord calls fn with the import records of the history, newest
+// first, until fn returns true.
+func (c *Client) eachImportRecord(fn func(HistoryRecord) bool) error {
 	inspected := 0
 	page := 0
 
 	for {
-		url := fmt.Sprintf("%s/api/v3/history?includeSeries=false&includeEpisode=false&page=%d&pageSize=1000",
-			c.baseURL, page)
-
-		resp, err := c.doRequest("GET", url)
+		resp, url, err := c.get("history",
+			fmt.Sprintf("includeSeries=false&includeEpisode=false&page=%d&pageSize=1000", page))
 		if err != nil {
-			return false, err
+			return err
 		}
 
 		if resp.StatusCode != http.StatusOK {
 			resp.Body.Close()
-			return false, &HTTPError{URL: url, StatusCode: resp.StatusCode, Status: resp.Status}
+			return &HTTPError{URL: url, StatusCode: resp.StatusCode, Status: resp.Status}
 		}
 
 		var historyResponse HistoryResponse
 		if err := json.NewDecoder(resp.Body).Decode(&historyResponse); err != nil {
 			resp.Body.Close()
-			return false, fmt.Errorf("url: %s, error decoding response: %w", url, err)
+			return fmt.Errorf("url: %s, error decoding response: %w", url, err)
 		}
 		resp.Body.Close()
 
 		for _, record := range historyResponse.Records {
-			if record.EventType == "downloadFolderImported" {
-				droppedPath, ok := record.Data["droppedPath"]
-				if ok && droppedPath == targetPath {
-					return true, nil
-				}
+			if record.EventType == "downloadFolderImported" && fn(record) {
+				return nil
+			}
+			inspected++
+		}
+
+		if len(historyResponse.Records) == 0 || historyResponse.TotalRecords <= inspected {
+			return nil
+		}
+		page++
+	}
+}
+
+// SystemStatus represents the API response for the system status
+type SystemStatus struct {
+	AppName      string `json:"appName"`
+	InstanceName string `json:"instanceName"`
+	Version      string `json:"version"`
+}
+
+// SystemStatus returns the service's name and version. It fails with an
+// HTTPError with status 401 when the API key is rejected
+func (c *Client) SystemStatus() (*SystemStatus, error) {
+	resp, url, err := c.get("system/status", "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &HTTPError{URL: url, StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+
+	var status SystemStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, fmt.Errorf("url: %s, error decoding response: %w", url, err)
+	}
+	return &status, nil
+}
+
+// QueueResponse represents the API response for the download queue
+type QueueResponse struct {
+	TotalRecords int           `json:"totalRecords"`
+	Records      []QueueRecord `json:"records"`
+}
+
+// QueueRecord represents a single queue item
+type QueueRecord struct {
+	ID         int64  `json:"id"`
+	DownloadID string `json:"downloadId"`
+	Title      string `json:"title"`
+}
+
+// Blocklist removes the queue items belonging to downloadID, removing them from
+// the download client and adding the release to the blocklist so the arr
+// searches for another one. It reports whether any queue item matched.
+func (c *Client) Blocklist(downloadID string) (bool, error) {
+	var ids []int64
+	inspected := 0
+	page := 1
+	for {
+		resp, url, err := c.get("queue",
+			fmt.Sprintf("page=%d&pageSize=1000&includeUnknownSeriesItems=true&includeUnknownMovieItems=true", page))
+		if err != nil {
+			return false, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return false, &HTTPError{URL: url, StatusCode: resp.StatusCode, Status: resp.Status}
+		}
+		var queueResponse QueueResponse
+		if err := json.NewDecoder(resp.Body).Decode(&queueResponse); err != nil {
+			resp.Body.Close()
+			return false, fmt.Errorf("url: %s, error decoding response: %w", url, err)
+		}
+		resp.Body.Close()
+
+		for _, record := range queueResponse.Records {
+			if strings.EqualFold(record.DownloadID, downloadID) {
+				ids = append(ids, record.ID)
 			}
 			inspected++
 		}
+		if len(queueResponse.Records) == 0 || queueResponse.TotalRecords <= inspected {
+			break
+		}
+		page++
+	}
 
-		if historyResponse.TotalRecords > inspected {
-			page++
-		} else {
-			return false, nil
+	for _, id := range ids {
+		url := c.endpointURL(fmt.Sprintf("queue/%d", id), "removeFromClient=true&blocklist=true")
+		resp, err := c.doRequest(http.MethodDelete, url)
+		if err != nil {
+			return false, err
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return false, &HTTPError{URL: url, StatusCode: resp.StatusCode, Status: resp.Status}
 		}
 	}
+
+	return len(ids) > 0, nil
+}
+
+// recordMatches reports whether an import history record refers to targetPath.
+// With importedPath matching, a record also matches when its droppedPath or
+// importedPath is a folder holding targetPath, or a file inside targetPath.
+func (c *Client) recordMatches(record HistoryRecord, targetPath string) bool {
+	target := c.normalizePath(targetPath)
+
+	if droppedPath, ok := record.Data["droppedPath"]; ok {
+		dropped := c.normalizePath(droppedPath)
+		if dropped == target {
+			return true
+		}
+		if c.matchImportedPath && pathsOverlap(dropped, target) {
+			return true
+		}
+	}
+
+	if c.matchImportedPath {
+		if importedPath, ok := record.Data["importedPath"]; ok {
+			imported := c.normalizePath(importedPath)
+			if imported == target || pathsOverlap(imported, target) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// normalizePath converts separators to forward slashes, cleans the path and
+// optionally lowercases it so equivalent paths compare equal
+func (c *Client) normalizePath(p string) string {
+	if p == "" {
+		return ""
+	}
+	p = path.Clean(strings.ReplaceAll(p, "\\", "/"))
+	if c.caseInsensitive {
+		p = strings.ToLower(p)
+	}
+	return p
+}
+
+// isPathPrefix reports whether dir is a parent directory of p
+func isPathPrefix(dir, p string) bool {
+	if dir == "" || dir == "/" || dir == "." {
+		return false
+	}
+	return strings.HasPrefix(p, dir+"/")
+}
+
+// pathsOverlap reports whether either path is inside the other
+func pathsOverlap(a, b string) bool {
+	return isPathPrefix(a, b) || isPathPrefix(b, a)
 }
 
 // CheckImportedMultiService checks if a file has been imported by any of the configured services
@@ -1,12 +1,17 @@
 package arr
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/ochronus/goputioarr/internal/hint"
 )
 
 func TestNewClient(t *testing.T) {
@@ -394,6 +399,43 @@ func TestClientTimeout(t *testing.T) {
 	}
 }
 
+func TestClientTimeoutAndRetryOptions(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test-key",
+		WithTimeout(2*time.Minute),
+		WithMaxRetries(5),
+		WithBackoff(time.Second),
+	)
+	var delays []time.Duration
+	client.sleeper = func(d time.Duration) { delays = append(delays, d) }
+
+	if client.httpClient.Timeout != 2*time.Minute {
+		t.Errorf("expected timeout 2m, got %v", client.httpClient.Timeout)
+	}
+	if _, err := client.CheckImported("/downloads/movie.mkv"); err == nil {
+		t.Fatal("expected error after exhausting retries")
+	}
+	if attempts != 5 {
+		t.Errorf("expected 5 attempts, got %d", attempts)
+	}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second}
+	if !reflect.DeepEqual(delays, want) {
+		t.Errorf("expected delays %v, got %v", want, delays)
+	}
+
+	defaults := NewClient(server.URL, "test-key", WithTimeout(0), WithMaxRetries(0), WithBackoff(0))
+	if defaults.httpClient.Timeout != timeout || defaults.maxRetries != maxRetries || defaults.backoff != backoffBase {
+		t.Errorf("expected zero values to keep the defaults, got %v/%d/%v",
+			defaults.httpClient.Timeout, defaults.maxRetries, defaults.backoff)
+	}
+}
+
 func TestHistoryResponseEmptyRecords(t *testing.T) {
 	jsonData := `{
 		"totalRecords": 0,
@@ -796,3 +838,394 @@ func TestCheckImportedDoesNotRetryOn400(t *testing.T) {
 		t.Fatalf("expected no retries on 400, got %d attempts", attempts)
 	}
 }
+
+func TestRecordMatches(t *testing.T) {
+	record := func(dropped, imported string) HistoryRecord {
+		return HistoryRecord{
+			EventType: "downloadFolderImported",
+			Data:      map[string]string{"droppedPath": dropped, "importedPath": imported},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		opts     []ClientOption
+		record   HistoryRecord
+		target   string
+		expected bool
+	}{
+		{
+			name:     "exact dropped path",
+			record:   record("/downloads/Show/ep.mkv", "/tv/Show/ep.mkv"),
+			target:   "/downloads/Show/ep.mkv",
+			expected: true,
+		},
+		{
+			name:     "separator and trailing slash normalization",
+			record:   record(`\downloads\Show\ep.mkv`, ""),
+			target:   "/downloads/Show/ep.mkv",
+			expected: true,
+		},
+		{
+			name:     "case mismatch without option",
+			record:   record("/Downloads/show/EP.mkv", ""),
+			target:   "/downloads/Show/ep.mkv",
+			expected: false,
+		},
+		{
+			name:     "case mismatch with option",
+			opts:     []ClientOption{WithCaseInsensitivePaths(true)},
+			record:   record("/Downloads/show/EP.mkv", ""),
+			target:   "/downloads/Show/ep.mkv",
+			expected: true,
+		},
+		{
+			name:     "imported path without option",
+			record:   record("/other/ep.mkv", "/downloads/Show/ep.mkv"),
+			target:   "/downloads/Show/ep.mkv",
+			expected: false,
+		},
+		{
+			name:     "imported path with option",
+			opts:     []ClientOption{WithImportedPathMatching(true)},
+			record:   record("/other/ep.mkv", "/downloads/Show/ep.mkv"),
+			target:   "/downloads/Show/ep.mkv",
+			expected: true,
+		},
+		{
+			name:     "dropped folder prefix with option",
+			opts:     []ClientOption{WithImportedPathMatching(true)},
+			record:   record("/downloads/Show", "/tv/Show/ep.mkv"),
+			target:   "/downloads/Show/ep.mkv",
+			expected: true,
+		},
+		{
+			name:     "dropped file inside target folder with option",
+			opts:     []ClientOption{WithImportedPathMatching(true)},
+			record:   record("/downloads/Show/S01/ep.mkv", "/tv/Show/ep.mkv"),
+			target:   "/downloads/Show",
+			expected: true,
+		},
+		{
+			name:     "imported folder prefix with option",
+			opts:     []ClientOption{WithImportedPathMatching(true)},
+			record:   record("/other", "/downloads/Show"),
+			target:   "/downloads/Show/ep.mkv",
+			expected: true,
+		},
+		{
+			name:     "imported file inside target folder with option",
+			opts:     []ClientOption{WithImportedPathMatching(true)},
+			record:   record("/other/ep.mkv", "/downloads/Show/Renamed.S01E01.mkv"),
+			target:   "/downloads/Show",
+			expected: true,
+		},
+		{
+			name:     "imported path partial component does not match",
+			opts:     []ClientOption{WithImportedPathMatching(true)},
+			record:   record("/other/ep.mkv", "/downloads/Show.Extras/ep.mkv"),
+			target:   "/downloads/Show",
+			expected: false,
+		},
+		{
+			name:     "imported folder prefix without option",
+			record:   record("/other", "/downloads/Show"),
+			target:   "/downloads/Show/ep.mkv",
+			expected: false,
+		},
+		{
+			name:     "dropped folder partial component does not match",
+			opts:     []ClientOption{WithImportedPathMatching(true)},
+			record:   record("/downloads/Sho", "/tv/Show/ep.mkv"),
+			target:   "/downloads/Show/ep.mkv",
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient("http://localhost", "key", tt.opts...)
+			if got := client.recordMatches(tt.record, tt.target); got != tt.expected {
+				t.Errorf("recordMatches() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCheckImportedCaseInsensitive(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"totalRecords": 1, "records": [{"eventType": "downloadFolderImported", "data": {"droppedPath": "D:/Downloads/Movie.mkv"}}]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test-key", WithCaseInsensitivePaths(true))
+	imported, err := client.CheckImported(`d:\downloads\movie.mkv`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !imported {
+		t.Error("expected case-insensitive match")
+	}
+}
+
+func TestFindImports(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"totalRecords": 3, "records": [
+			{"eventType": "downloadFolderImported", "data": {"droppedPath": "/downloads/Show", "importedPath": "/tv/Show/S01E02.mkv"}},
+			{"eventType": "downloadFolderImported", "data": {"droppedPath": "/downloads/Show/e01.mkv", "importedPath": "/tv/Show/S01E01.mkv"}},
+			{"eventType": "downloadFolderImported", "data": {"droppedPath": "/downloads/movie.mkv", "importedPath": "/movies/Movie (2020)/movie.mkv"}}
+		]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test-key", WithImportedPathMatching(true))
+	imports, err := client.FindImports([]string{"/downloads/movie.mkv", "/downloads/Show/e01.mkv", "/downloads/Show/e02.mkv", "/downloads/other.mkv"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("expected the history to be read once, got %d requests", requests)
+	}
+
+	expected := map[string]Import{
+		"/downloads/movie.mkv":    {ImportedPath: "/movies/Movie (2020)/movie.mkv", Exact: true},
+		"/downloads/Show/e01.mkv": {ImportedPath: "/tv/Show/S01E01.mkv", Exact: true},
+		"/downloads/Show/e02.mkv": {ImportedPath: "/tv/Show/S01E02.mkv"},
+	}
+	if !reflect.DeepEqual(imports, expected) {
+		t.Errorf("FindImports() = %+v, expected %+v", imports, expected)
+	}
+}
+
+func TestBlocklist(t *testing.T) {
+	var deleted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v3/queue":
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"totalRecords": 3, "records": [
+				{"id": 1, "downloadId": "ABCDEF"},
+				{"id": 2, "downloadId": "OTHER"},
+				{"id": 3, "downloadId": "abcdef"}
+			]}`))
+		case r.Method == http.MethodDelete:
+			if r.URL.Query().Get("blocklist") != "true" || r.URL.Query().Get("removeFromClient") != "true" {
+				t.Errorf("unexpected query: %s", r.URL.RawQuery)
+			}
+			deleted = append(deleted, r.URL.Path)
+			w.WriteHeader(http.StatusOK)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test-key")
+	found, err := client.Blocklist("abcdef")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !found {
+		t.Error("expected queue item to be found")
+	}
+	if len(deleted) != 2 || deleted[0] != "/api/v3/queue/1" || deleted[1] != "/api/v3/queue/3" {
+		t.Errorf("unexpected deletions: %v", deleted)
+	}
+}
+
+func TestBlocklistNotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("unexpected %s request", r.Method)
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"totalRecords": 0, "records": []}`))
+	}))
+	defer server.Close()
+
+	found, err := NewClient(server.URL, "test-key").Blocklist("abcdef")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if found {
+		t.Error("expected no queue item to be found")
+	}
+}
+
+func TestAPIVersionDiscovery(t *testing.T) {
+	var discoveries int
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		switch r.URL.Path {
+		case "/api":
+			discoveries++
+			w.Write([]byte(`{"current": "v1", "deprecated": []}`))
+		case "/api/v1/history":
+			w.Write([]byte(`{"totalRecords": 1, "records": [
+				{"eventType": "downloadFolderImported", "data": {"droppedPath": "/downloads/file.mkv"}}
+			]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test-key")
+	for i := 0; i < 2; i++ {
+		imported, err := client.CheckImported("/downloads/file.mkv")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !imported {
+			t.Error("expected file to be imported")
+		}
+	}
+	if discoveries != 1 {
+		t.Errorf("expected the version to be discovered once, got %d", discoveries)
+	}
+	want := []string{"/api/v3/history", "/api", "/api/v1/history", "/api/v1/history"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("expected requests %v, got %v", want, paths)
+	}
+}
+
+func TestAPIVersionOverrides(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []ClientOption
+		endpoint  string
+		wantPaths []string
+	}{
+		{name: "pinned version", opts: []ClientOption{WithAPIVersion("v1")}, endpoint: "history", wantPaths: []string{"/api/v1/history"}},
+		{name: "history path", opts: []ClientOption{WithHistoryPath("/custom/history")}, endpoint: "history", wantPaths: []string{"/custom/history"}},
+		{name: "history path leaves queue alone", opts: []ClientOption{WithHistoryPath("/custom/history")}, endpoint: "queue", wantPaths: []string{"/api/v3/queue", "/api"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var paths []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				paths = append(paths, r.URL.Path)
+				w.WriteHeader(http.StatusNotFound)
+			}))
+			defer server.Close()
+
+			client := NewClient(server.URL, "test-key", tt.opts...)
+			if tt.endpoint == "history" {
+				_, err := client.CheckImported("/downloads/file.mkv")
+				var httpErr *HTTPError
+				if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusNotFound {
+					t.Errorf("expected a 404 error, got %v", err)
+				}
+			} else {
+				client.Blocklist("abcdef")
+			}
+			if !reflect.DeepEqual(paths, tt.wantPaths) {
+				t.Errorf("expected requests %v, got %v", tt.wantPaths, paths)
+			}
+		})
+	}
+}
+
+func TestAPIVersionDiscoveryUnavailable(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "test-key")
+	client.CheckImported("/downloads/file.mkv")
+	client.CheckImported("/downloads/file.mkv")
+
+	want := []string{"/api/v3/history", "/api", "/api/v3/history"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("expected discovery to be attempted once, got %v", paths)
+	}
+}
+
+func TestSystemStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v3/system/status" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		if r.Header.Get("X-Api-Key") != "test-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"appName": "Radarr", "instanceName": "Radarr 4K", "version": "5.2.6.8376"}`))
+	}))
+	defer server.Close()
+
+	status, err := NewClient(server.URL, "test-key").SystemStatus()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status.AppName != "Radarr" || status.InstanceName != "Radarr 4K" || status.Version != "5.2.6.8376" {
+		t.Errorf("unexpected status %+v", status)
+	}
+
+	_, err = NewClient(server.URL, "wrong-key").SystemStatus()
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected a 401 error, got %v", err)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestClientWithTransport(t *testing.T) {
+	rt := roundTripFunc(func(*http.Request) (*http.Response, error) { return nil, nil })
+	client := NewClient("http://localhost:8989", "test-key", WithTransport(rt), WithTimeout(time.Minute))
+	if client.httpClient.Transport == nil {
+		t.Error("expected custom transport")
+	}
+	if client.httpClient.Timeout != time.Minute {
+		t.Errorf("expected timeout 1m, got %v", client.httpClient.Timeout)
+	}
+}
+
+func TestConnectionErrorHints(t *testing.T) {
+	failing := roundTripFunc(func(*http.Request) (*http.Response, error) { return nil, errors.New("connection refused") })
+	client := NewClient("http://localhost:8989", "test-key", WithTransport(failing))
+	client.sleeper = func(time.Duration) {}
+
+	_, err := client.CheckImported("/downloads/movie.mkv")
+	var connErr *ConnectionError
+	if !errors.As(err, &connErr) {
+		t.Fatalf("expected a ConnectionError, got %v", err)
+	}
+	if got := hint.Of(err); !strings.Contains(got, "reachable") {
+		t.Errorf("hint = %q, want one about reaching the service", got)
+	}
+	timedOut := &ConnectionError{Err: context.DeadlineExceeded}
+	if got := timedOut.Hint(); !strings.Contains(got, "timeout") {
+		t.Errorf("hint for a timeout = %q, want one about the timeout", got)
+	}
+}
+
+func TestHTTPErrorHints(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	_, err := NewClient(server.URL, "test-key").CheckImported("/downloads/movie.mkv")
+	if got := hint.Of(err); !strings.Contains(got, "URL base") {
+		t.Errorf("hint for a 404 = %q, want one about the URL base", got)
+	}
+	if got := (&HTTPError{StatusCode: http.StatusUnauthorized}).Hint(); !strings.Contains(got, "api_key") {
+		t.Errorf("hint for a 401 = %q, want one about the api_key", got)
+	}
+	if got := (&HTTPError{StatusCode: http.StatusBadRequest}).Hint(); got != "" {
+		t.Errorf("hint for a 400 = %q, want none", got)
+	}
+}
@@ -4,4 +4,7 @@ package arr
 // It enables mocking Arr interactions in tests without hitting real services.
 type ClientAPI interface {
 	CheckImported(targetPath string) (bool, error)
+	FindImports(targetPaths []string) (map[string]Import, error)
+	Blocklist(downloadID string) (bool, error)
+	SystemStatus() (*SystemStatus, error)
 }
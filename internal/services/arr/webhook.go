@@ -0,0 +1,33 @@
+package arr
+
+import "strings"
+
+// WebhookPayload is the subset of a Sonarr/Radarr/Whisparr webhook
+// notification needed to identify the imported download
+type WebhookPayload struct {
+	EventType          string `json:"eventType"`
+	DownloadClient     string `json:"downloadClient"`
+	DownloadClientType string `json:"downloadClientType"`
+	DownloadID         string `json:"downloadId"`
+}
+
+// Webhook event types sent by the arrs
+const (
+	WebhookEventTest     = "Test"
+	WebhookEventDownload = "Download"
+)
+
+// IsImport reports whether the payload describes a completed import
+func (p *WebhookPayload) IsImport() bool {
+	return p.EventType == WebhookEventDownload && p.DownloadID != ""
+}
+
+// FromDownloadClient reports whether the payload is about a download of the
+// Transmission download client named name, or of any Transmission client if
+// name is empty. Payloads not naming their client are accepted.
+func (p *WebhookPayload) FromDownloadClient(name string) bool {
+	if p.DownloadClientType != "" && !strings.EqualFold(p.DownloadClientType, "Transmission") {
+		return false
+	}
+	return name == "" || p.DownloadClient == "" || strings.EqualFold(p.DownloadClient, name)
+}
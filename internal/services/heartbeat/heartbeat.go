@@ -0,0 +1,103 @@
+// Package heartbeat pings a push monitor such as healthchecks.io or Uptime
+// Kuma, so a dead instance is noticed without running a separate check.
+package heartbeat
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTimeout = 10 * time.Second
+	// minInterval throttles success pings; monitors expect one every few
+	// minutes, not every polling cycle.
+	minInterval = time.Minute
+)
+
+// Pinger reports successes to a heartbeat URL and failures to the URL with
+// /fail appended, as healthchecks.io expects. All methods are safe to call on
+// a nil Pinger.
+type Pinger struct {
+	url        string
+	httpClient *http.Client
+	now        func() time.Time
+
+	mu       sync.Mutex
+	lastPing time.Time
+	failing  bool
+}
+
+// New creates a Pinger for url.
+func New(url string) *Pinger {
+	return &Pinger{
+		url:        url,
+		httpClient: &http.Client{Timeout: defaultTimeout},
+		now:        time.Now,
+	}
+}
+
+// Success pings the heartbeat URL with a GET, as Uptime Kuma push monitors
+// expect, at most once a minute unless the previous
+// ping reported a failure.
+func (p *Pinger) Success(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	now := p.now()
+	if !p.failing && !p.lastPing.IsZero() && now.Sub(p.lastPing) < minInterval {
+		p.mu.Unlock()
+		return nil
+	}
+	p.lastPing, p.failing = now, false
+	p.mu.Unlock()
+	return p.ping(ctx, http.MethodGet, p.url, "")
+}
+
+// Fail posts message to the failure URL.
+func (p *Pinger) Fail(ctx context.Context, message string) error {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	p.lastPing, p.failing = p.now(), true
+	p.mu.Unlock()
+	return p.ping(ctx, http.MethodPost, failURL(p.url), message)
+}
+
+func (p *Pinger) ping(ctx context.Context, method, target, body string) error {
+	req, err := http.NewRequestWithContext(ctx, method, target, strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("heartbeat failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("heartbeat failed: %s", resp.Status)
+	}
+	return nil
+}
+
+// failURL appends /fail to the path of rawURL, keeping any query string.
+func failURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return strings.TrimSuffix(rawURL, "/") + "/fail"
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/fail"
+	if u.RawPath != "" {
+		u.RawPath = strings.TrimSuffix(u.RawPath, "/") + "/fail"
+	}
+	return u.String()
+}
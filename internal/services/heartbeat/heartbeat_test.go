@@ -0,0 +1,104 @@
+package heartbeat
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestFailURL(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{input: "https://hc-ping.com/uuid", expected: "https://hc-ping.com/uuid/fail"},
+		{input: "https://hc-ping.com/uuid/", expected: "https://hc-ping.com/uuid/fail"},
+		{input: "http://kuma:3001/api/push/token?status=up", expected: "http://kuma:3001/api/push/token/fail?status=up"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := failURL(tt.input); got != tt.expected {
+				t.Errorf("failURL(%q) = %q, expected %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+type ping struct {
+	method, path, body string
+}
+
+func setupPinger(t *testing.T) (*Pinger, *[]ping, *time.Time) {
+	t.Helper()
+	var pings []ping
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		pings = append(pings, ping{r.Method, r.URL.Path, string(body)})
+	}))
+	t.Cleanup(server.Close)
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	pinger := New(server.URL + "/uuid")
+	pinger.now = func() time.Time { return now }
+	return pinger, &pings, &now
+}
+
+func TestPingerThrottlesSuccess(t *testing.T) {
+	pinger, pings, now := setupPinger(t)
+	ctx := context.Background()
+
+	for _, step := range []time.Duration{0, 10 * time.Second, 50 * time.Second} {
+		*now = now.Add(step)
+		if err := pinger.Success(ctx); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	expected := []ping{{"GET", "/uuid", ""}, {"GET", "/uuid", ""}}
+	if len(*pings) != len(expected) || (*pings)[0] != expected[0] || (*pings)[1] != expected[1] {
+		t.Errorf("expected %v, got %v", expected, *pings)
+	}
+}
+
+func TestPingerReportsRecoveryImmediately(t *testing.T) {
+	pinger, pings, now := setupPinger(t)
+	ctx := context.Background()
+
+	if err := pinger.Fail(ctx, "put.io is down"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	*now = now.Add(time.Second)
+	if err := pinger.Success(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []ping{{"POST", "/uuid/fail", "put.io is down"}, {"GET", "/uuid", ""}}
+	if len(*pings) != len(expected) || (*pings)[0] != expected[0] || (*pings)[1] != expected[1] {
+		t.Errorf("expected %v, got %v", expected, *pings)
+	}
+}
+
+func TestPingerHTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	if err := New(server.URL).Success(context.Background()); err == nil {
+		t.Error("expected error for non-2xx response")
+	}
+}
+
+func TestPingerNilSafe(t *testing.T) {
+	var pinger *Pinger
+	if err := pinger.Success(context.Background()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := pinger.Fail(context.Background(), "down"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
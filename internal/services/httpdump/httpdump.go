@@ -0,0 +1,92 @@
+// Package httpdump logs sanitized HTTP requests and responses at trace level
+// so protocol problems can be debugged without a packet capture.
+package httpdump
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// MaxBodyBytes caps how much of a dump is logged.
+const MaxBodyBytes = 64 << 10
+
+const redacted = "[redacted]"
+
+var (
+	secretHeaders = regexp.MustCompile(`(?im)^((?:Authorization|Proxy-Authorization|X-Api-Key|Cookie|Set-Cookie):)[^\r\n]*`)
+	secretParams  = regexp.MustCompile(`(?i)((?:^|[?&\s])(?:apikey|api_key|token|oauth_token|access_token|password)=)[^&\s]*`)
+	secretFields  = regexp.MustCompile(`(?i)("(?:apikey|api_key|token|oauth_token|access_token|password)"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+)
+
+// Redact masks credentials in a dumped request or response: auth headers,
+// token query parameters and password or token fields in JSON bodies.
+func Redact(dump string) string {
+	dump = secretHeaders.ReplaceAllString(dump, "$1 "+redacted)
+	dump = secretParams.ReplaceAllString(dump, "${1}"+redacted)
+	return secretFields.ReplaceAllString(dump, `${1}"`+redacted+`"`)
+}
+
+// Log writes a redacted dump to logger at trace level, truncated to
+// MaxBodyBytes.
+func Log(logger *logrus.Entry, label string, dump []byte) {
+	text := string(dump)
+	if len(text) > MaxBodyBytes {
+		text = fmt.Sprintf("%s\n... (%d bytes truncated)", text[:MaxBodyBytes], len(text)-MaxBodyBytes)
+	}
+	logger.Tracef("%s:\n%s", label, Redact(text))
+}
+
+// Enabled reports whether logger would log dumps.
+func Enabled(logger *logrus.Logger) bool {
+	return logger != nil && logger.IsLevelEnabled(logrus.TraceLevel)
+}
+
+// Transport is an http.RoundTripper that logs each request and response
+// through Log when the logger is at trace level.
+type Transport struct {
+	base   http.RoundTripper
+	logger *logrus.Logger
+	name   string
+}
+
+// NewTransport wraps base, or http.DefaultTransport when base is nil. name
+// identifies the remote service in the log.
+func NewTransport(base http.RoundTripper, logger *logrus.Logger, name string) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{base: base, logger: logger, name: name}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !Enabled(t.logger) {
+		return t.base.RoundTrip(req)
+	}
+
+	if dump, err := httputil.DumpRequestOut(req, dumpableBody(req.Header)); err == nil {
+		Log(logrus.NewEntry(t.logger), t.name+" request", dump)
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		t.logger.Tracef("%s request failed: %v", t.name, err)
+		return resp, err
+	}
+
+	if dump, err := httputil.DumpResponse(resp, dumpableBody(resp.Header)); err == nil {
+		Log(logrus.NewEntry(t.logger), t.name+" response", dump)
+	}
+	return resp, nil
+}
+
+// dumpableBody reports whether a body is worth logging; multipart uploads
+// carry torrent files rather than anything readable.
+func dumpableBody(header http.Header) bool {
+	return !strings.HasPrefix(header.Get("Content-Type"), "multipart/")
+}
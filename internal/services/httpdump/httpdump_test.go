@@ -0,0 +1,134 @@
+package httpdump
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "authorization header",
+			input:    "GET / HTTP/1.1\r\nAuthorization: Bearer abc123\r\nHost: x\r\n",
+			expected: "GET / HTTP/1.1\r\nAuthorization: [redacted]\r\nHost: x\r\n",
+		},
+		{
+			name:     "api key header",
+			input:    "X-Api-Key: secret\r\n",
+			expected: "X-Api-Key: [redacted]\r\n",
+		},
+		{
+			name:     "query token",
+			input:    "GET /v2/files/list?oauth_token=abc&parent_id=0 HTTP/1.1",
+			expected: "GET /v2/files/list?oauth_token=[redacted]&parent_id=0 HTTP/1.1",
+		},
+		{
+			name:     "form password",
+			input:    "user=bob&password=hunter2",
+			expected: "user=bob&password=[redacted]",
+		},
+		{
+			name:     "json fields",
+			input:    `{"apiKey": "k\"ey", "token":"t", "name":"show"}`,
+			expected: `{"apiKey": "[redacted]", "token":"[redacted]", "name":"show"}`,
+		},
+		{
+			name:     "nothing secret",
+			input:    `{"method":"torrent-get","arguments":{}}`,
+			expected: `{"method":"torrent-get","arguments":{}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Redact(tt.input); got != tt.expected {
+				t.Errorf("Redact(%q) = %q, expected %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLogTruncates(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&buf)
+	logger.SetLevel(logrus.TraceLevel)
+
+	Log(logrus.NewEntry(logger), "big", bytes.Repeat([]byte("a"), MaxBodyBytes+10))
+
+	if !strings.Contains(buf.String(), "10 bytes truncated") {
+		t.Errorf("expected truncation note, got %d bytes of log", buf.Len())
+	}
+}
+
+func TestTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"echo":` + string(body) + `,"access_token":"fresh"}`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		level  logrus.Level
+		logged bool
+	}{
+		{name: "trace", level: logrus.TraceLevel, logged: true},
+		{name: "debug", level: logrus.DebugLevel, logged: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := logrus.New()
+			logger.SetOutput(&buf)
+			logger.SetLevel(tt.level)
+
+			client := &http.Client{Transport: NewTransport(nil, logger, "sonarr")}
+			req, _ := http.NewRequest(http.MethodPost, server.URL+"/api?apikey=secret", strings.NewReader(`{"password":"pw"}`))
+			req.Header.Set("X-Api-Key", "secret")
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+
+			if !strings.Contains(string(body), `"password":"pw"`) {
+				t.Errorf("request body not forwarded intact: %s", body)
+			}
+			if !strings.Contains(string(body), `"access_token":"fresh"`) {
+				t.Errorf("response body not returned intact: %s", body)
+			}
+
+			out := buf.String()
+			if !tt.logged {
+				if out != "" {
+					t.Errorf("expected no output, got %q", out)
+				}
+				return
+			}
+			for _, want := range []string{"sonarr request", "sonarr response", "[redacted]"} {
+				if !strings.Contains(out, want) {
+					t.Errorf("expected %q in log, got %q", want, out)
+				}
+			}
+			for _, secret := range []string{"secret", "pw", "fresh"} {
+				if strings.Contains(out, secret) {
+					t.Errorf("secret %q leaked into log: %q", secret, out)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+// Package mqtt publishes messages to an MQTT broker at QoS 0, on top of the
+// Eclipse Paho client.
+package mqtt
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+)
+
+const (
+	defaultKeepAlive = 60 * time.Second
+	connectTimeout   = 10 * time.Second
+	// disconnectQuiesce is how long Close lets pending messages go out.
+	disconnectQuiesce = 250 * time.Millisecond
+)
+
+// Message is an MQTT application message.
+type Message struct {
+	Topic   string
+	Payload []byte
+	Retain  bool
+}
+
+// Client publishes to a broker. It connects on the first Publish; once
+// connected, it reconnects by itself after the connection drops.
+type Client struct {
+	broker    string
+	clientID  string
+	username  string
+	password  string
+	keepAlive time.Duration
+	will      *Message
+	birth     *Message
+
+	mu     sync.Mutex
+	client paho.Client
+}
+
+// Option configures the Client.
+type Option func(*Client)
+
+// WithCredentials sets the username and password sent when connecting.
+func WithCredentials(username, password string) Option {
+	return func(c *Client) {
+		c.username = username
+		c.password = password
+	}
+}
+
+// WithClientID sets the client identifier.
+func WithClientID(id string) Option {
+	return func(c *Client) {
+		c.clientID = id
+	}
+}
+
+// WithWill sets the message the broker publishes if the client goes away
+// without disconnecting. Close publishes it too.
+func WithWill(msg Message) Option {
+	return func(c *Client) {
+		c.will = &msg
+	}
+}
+
+// WithBirth sets a message published after every (re)connect, typically the
+// counterpart of the will.
+func WithBirth(msg Message) Option {
+	return func(c *Client) {
+		c.birth = &msg
+	}
+}
+
+// WithKeepAlive sets the keep-alive interval agreed with the broker.
+func WithKeepAlive(d time.Duration) Option {
+	return func(c *Client) {
+		if d > 0 {
+			c.keepAlive = d
+		}
+	}
+}
+
+// NewClient creates a client for broker, a URL like "tcp://host:1883" or,
+// with TLS, "ssl://host:8883". The mqtt, mqtts and tls schemes work too.
+func NewClient(broker string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(broker)
+	if err != nil {
+		return nil, fmt.Errorf("invalid broker URL: %w", err)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid broker URL %q: missing host", broker)
+	}
+
+	port := u.Port()
+	switch u.Scheme {
+	case "tcp", "mqtt":
+		if port == "" {
+			port = "1883"
+		}
+	case "ssl", "tls", "mqtts":
+		if port == "" {
+			port = "8883"
+		}
+	default:
+		return nil, fmt.Errorf("invalid broker URL %q: scheme must be tcp, mqtt, ssl, tls or mqtts", broker)
+	}
+	u.Host = net.JoinHostPort(u.Hostname(), port)
+
+	c := &Client{broker: u.String(), clientID: "goputioarr", keepAlive: defaultKeepAlive}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	options := paho.NewClientOptions().
+		AddBroker(c.broker).
+		SetClientID(c.clientID).
+		SetUsername(c.username).
+		SetPassword(c.password).
+		SetKeepAlive(c.keepAlive).
+		SetConnectTimeout(connectTimeout).
+		SetAutoReconnect(true).
+		SetOnConnectHandler(c.connected)
+	if c.will != nil {
+		options.SetBinaryWill(c.will.Topic, c.will.Payload, 0, c.will.Retain)
+	}
+	c.client = paho.NewClient(options)
+	return c, nil
+}
+
+// connected publishes the birth message after every (re)connect.
+func (c *Client) connected(client paho.Client) {
+	if c.birth != nil {
+		client.Publish(c.birth.Topic, 0, c.birth.Retain, c.birth.Payload)
+	}
+}
+
+// Publish sends msg, connecting first if the client isn't connected or
+// reconnecting.
+func (c *Client) Publish(ctx context.Context, msg Message) error {
+	if err := c.connect(ctx); err != nil {
+		return err
+	}
+	return wait(ctx, c.client.Publish(msg.Topic, 0, msg.Retain, msg.Payload))
+}
+
+func (c *Client) connect(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.client.IsConnected() {
+		return nil
+	}
+	if err := wait(ctx, c.client.Connect()); err != nil {
+		return fmt.Errorf("connecting to MQTT broker: %w", err)
+	}
+	return nil
+}
+
+// Close publishes the will, since brokers drop it on a clean disconnect, and
+// disconnects.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.client.IsConnected() {
+		return nil
+	}
+	var err error
+	if c.will != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+		err = wait(ctx, c.client.Publish(c.will.Topic, 0, c.will.Retain, c.will.Payload))
+		cancel()
+	}
+	c.client.Disconnect(uint(disconnectQuiesce / time.Millisecond))
+	return err
+}
+
+// wait waits for token to complete or ctx to end and returns its error.
+func wait(ctx context.Context, token paho.Token) error {
+	select {
+	case <-token.Done():
+		return token.Error()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,64 @@
+package mqtt
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewClient(t *testing.T) {
+	tests := []struct {
+		broker  string
+		want    string
+		wantErr bool
+	}{
+		{broker: "tcp://broker", want: "tcp://broker:1883"},
+		{broker: "mqtt://broker:1884", want: "mqtt://broker:1884"},
+		{broker: "ssl://broker", want: "ssl://broker:8883"},
+		{broker: "mqtts://broker:9000", want: "mqtts://broker:9000"},
+		{broker: "http://broker", wantErr: true},
+		{broker: "tcp://", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.broker, func(t *testing.T) {
+			client, err := NewClient(tt.broker)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if client.broker != tt.want {
+				t.Errorf("expected broker %q, got %q", tt.want, client.broker)
+			}
+		})
+	}
+}
+
+func TestClientBrokerUnreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	client, err := NewClient("tcp://" + addr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = client.Publish(ctx, Message{Topic: "goputioarr/active", Payload: []byte("1")})
+	if err == nil || !strings.Contains(err.Error(), "connecting to MQTT broker") {
+		t.Errorf("expected a connection error, got %v", err)
+	}
+	if err := client.Close(); err != nil {
+		t.Errorf("expected closing a client that never connected to succeed, got %v", err)
+	}
+}
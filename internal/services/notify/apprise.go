@@ -0,0 +1,107 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"strings"
+	"text/template"
+)
+
+// AppriseTemplate is the default Apprise message template; the title is sent
+// separately.
+const AppriseTemplate = "{{.Message}}"
+
+// appriseTypes maps events to Apprise notification types, which services use
+// to pick an icon or color. Other events are sent as "info".
+var appriseTypes = map[EventType]string{
+	EventImportTimeout:   "warning",
+	EventPutioRecovered:  "success",
+	EventLowDiskSpace:    "warning",
+	EventDownloadFailed:  "failure",
+	EventPutioAuthFailed: "failure",
+}
+
+func appriseType(t EventType) string {
+	if kind, ok := appriseTypes[t]; ok {
+		return kind
+	}
+	return "info"
+}
+
+// AppriseAPI sends events through an Apprise API server, either to the given
+// Apprise URLs or, if key is set, to the configuration stored under key.
+type AppriseAPI struct {
+	apiURL     string
+	key        string
+	urls       []string
+	template   *template.Template
+	httpClient *http.Client
+}
+
+var _ Notifier = (*AppriseAPI)(nil)
+
+// NewAppriseAPI creates a notifier that posts messages rendered with tmpl to
+// the Apprise API server at apiURL.
+func NewAppriseAPI(apiURL, key string, urls []string, tmpl *template.Template) *AppriseAPI {
+	return &AppriseAPI{
+		apiURL:     strings.TrimSuffix(apiURL, "/"),
+		key:        key,
+		urls:       urls,
+		template:   tmpl,
+		httpClient: &http.Client{Timeout: defaultTimeout},
+	}
+}
+
+// Notify posts event to the Apprise API.
+func (a *AppriseAPI) Notify(ctx context.Context, event Event) error {
+	body, err := render(a.template, event)
+	if err != nil {
+		return err
+	}
+	payload := map[string]string{
+		"title": event.Title,
+		"body":  body,
+		"type":  appriseType(event.Type),
+	}
+	endpoint := a.apiURL + "/notify/"
+	if a.key != "" {
+		endpoint += a.key
+	} else {
+		payload["urls"] = strings.Join(a.urls, ",")
+	}
+	return postJSON(ctx, a.httpClient, "apprise", endpoint, payload)
+}
+
+// AppriseCLI sends events to Apprise URLs by running the apprise command.
+type AppriseCLI struct {
+	binary   string
+	urls     []string
+	template *template.Template
+}
+
+var _ Notifier = (*AppriseCLI)(nil)
+
+// NewAppriseCLI creates a notifier that runs binary (default "apprise") to
+// send messages rendered with tmpl to urls.
+func NewAppriseCLI(binary string, urls []string, tmpl *template.Template) *AppriseCLI {
+	if binary == "" {
+		binary = "apprise"
+	}
+	return &AppriseCLI{binary: binary, urls: urls, template: tmpl}
+}
+
+// Notify runs apprise for event.
+func (a *AppriseCLI) Notify(ctx context.Context, event Event) error {
+	body, err := render(a.template, event)
+	if err != nil {
+		return err
+	}
+	args := append([]string{"-t", event.Title, "-b", body, "-n", appriseType(event.Type), "--"}, a.urls...)
+	cmd := exec.CommandContext(ctx, a.binary, args...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("apprise notification failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
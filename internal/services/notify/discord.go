@@ -0,0 +1,41 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"text/template"
+)
+
+// DiscordTemplate is the default Discord message template.
+const DiscordTemplate = "**{{.Title}}**\n{{.Message}}"
+
+// Discord posts events to a Discord channel webhook.
+type Discord struct {
+	webhookURL string
+	template   *template.Template
+	httpClient *http.Client
+}
+
+var _ Notifier = (*Discord)(nil)
+
+// NewDiscord creates a notifier that posts messages rendered with tmpl to a
+// Discord webhook URL.
+func NewDiscord(webhookURL string, tmpl *template.Template) *Discord {
+	return &Discord{
+		webhookURL: webhookURL,
+		template:   tmpl,
+		httpClient: &http.Client{Timeout: defaultTimeout},
+	}
+}
+
+// Notify posts event to the Discord webhook.
+func (d *Discord) Notify(ctx context.Context, event Event) error {
+	content, err := render(d.template, event)
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, d.httpClient, "discord", d.webhookURL, map[string]string{
+		"username": "goputioarr",
+		"content":  content,
+	})
+}
@@ -0,0 +1,138 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// EmailTemplate is the default email body template; the title is the
+// subject.
+const EmailTemplate = "{{.Message}}\n{{if .Transfer}}\nTransfer: {{.Transfer}}{{end}}{{if .Hash}}\nHash: {{.Hash}}{{end}}\n"
+
+// implicitTLSPort is the SMTP submission port that expects TLS from the
+// start instead of STARTTLS.
+const implicitTLSPort = 465
+
+// Email sends events by SMTP. Connections on port 465 use TLS from the start;
+// on other ports STARTTLS is used if the server offers it. Credentials are
+// only sent over TLS, or to localhost.
+type Email struct {
+	host     string
+	port     int
+	auth     smtp.Auth
+	from     string
+	to       []string
+	template *template.Template
+	now      func() time.Time
+}
+
+var _ Notifier = (*Email)(nil)
+
+// NewEmail creates a notifier that mails events rendered with tmpl from from
+// to every address in to, through the SMTP server at host:port. An empty
+// username sends without authenticating.
+func NewEmail(host string, port int, username, password, from string, to []string, tmpl *template.Template) *Email {
+	e := &Email{host: host, port: port, from: from, to: to, template: tmpl, now: time.Now}
+	if username != "" {
+		e.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return e
+}
+
+// Notify mails event.
+func (e *Email) Notify(ctx context.Context, event Event) error {
+	body, err := render(e.template, event)
+	if err != nil {
+		return err
+	}
+	if err := e.send(ctx, e.message(event.Title, body)); err != nil {
+		return fmt.Errorf("email notification failed: %w", err)
+	}
+	return nil
+}
+
+func (e *Email) send(ctx context.Context, message []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(e.host, strconv.Itoa(e.port)))
+	if err != nil {
+		return err
+	}
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+	tlsConfig := &tls.Config{ServerName: e.host}
+	if e.port == implicitTLSPort {
+		conn = tls.Client(conn, tlsConfig)
+	}
+
+	client, err := smtp.NewClient(conn, e.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok && e.port != implicitTLSPort {
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if e.auth != nil {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return errors.New("server doesn't support AUTH")
+		}
+		if err := client.Auth(e.auth); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(e.from); err != nil {
+		return err
+	}
+	for _, to := range e.to {
+		if err := client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(message); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// message builds the RFC 5322 message with CRLF line endings.
+func (e *Email) message(subject, body string) []byte {
+	var buf bytes.Buffer
+	header := func(name, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", name, value)
+	}
+	header("From", e.from)
+	header("To", strings.Join(e.to, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", "[goputioarr] "+subject))
+	header("Date", e.now().Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("Content-Transfer-Encoding", "8bit")
+	buf.WriteString("\r\n")
+	body = strings.ReplaceAll(body, "\r\n", "\n")
+	buf.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return buf.Bytes()
+}
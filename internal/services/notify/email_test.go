@@ -0,0 +1,133 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSMTP accepts one SMTP session, offering AUTH PLAIN but not STARTTLS, and
+// sends the commands and message data it received to session.
+func fakeSMTP(t *testing.T) (port int, session chan []string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	session = make(chan []string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+		var lines []string
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				session <- lines
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			lines = append(lines, line)
+			switch cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0]); cmd {
+			case "EHLO":
+				reply("250-localhost")
+				reply("250 AUTH PLAIN")
+			case "AUTH":
+				reply("235 Authenticated")
+			case "DATA":
+				reply("354 Go ahead")
+				for {
+					data, err := r.ReadString('\n')
+					if err != nil {
+						session <- lines
+						return
+					}
+					if data == ".\r\n" {
+						break
+					}
+					lines = append(lines, strings.TrimRight(data, "\r\n"))
+				}
+				reply("250 Queued")
+			case "QUIT":
+				reply("221 Bye")
+				session <- lines
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+
+	_, portText, _ := net.SplitHostPort(listener.Addr().String())
+	port, _ = strconv.Atoi(portText)
+	return port, session
+}
+
+func TestEmailNotify(t *testing.T) {
+	port, session := fakeSMTP(t)
+	tmpl, err := ParseTemplate("", EmailTemplate)
+	if err != nil {
+		t.Fatalf("ParseTemplate: %v", err)
+	}
+	email := NewEmail("localhost", port, "user", "secret", "goputioarr@example.com", []string{"me@example.com", "you@example.com"}, tmpl)
+	email.now = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	event := Event{Type: EventDownloadFailed, Title: "Download failed", Message: "Downloading Show failed", Transfer: "Show", Hash: "abc"}
+	if err := email.Notify(context.Background(), event); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+
+	var lines []string
+	select {
+	case lines = <-session:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the SMTP session")
+	}
+	received := strings.Join(lines, "\n")
+	for _, want := range []string{
+		"AUTH PLAIN",
+		"MAIL FROM:<goputioarr@example.com>",
+		"RCPT TO:<me@example.com>",
+		"RCPT TO:<you@example.com>",
+		"To: me@example.com, you@example.com",
+		"Subject: [goputioarr] Download failed",
+		"Date: Tue, 02 Jan 2024 03:04:05 +0000",
+		"Downloading Show failed",
+		"Transfer: Show",
+		"Hash: abc",
+		"QUIT",
+	} {
+		if !strings.Contains(received, want) {
+			t.Errorf("expected session to contain %q, got:\n%s", want, received)
+		}
+	}
+}
+
+func TestEmailNotifyUnreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	_, portText, _ := net.SplitHostPort(listener.Addr().String())
+	listener.Close()
+	port, _ := strconv.Atoi(portText)
+
+	tmpl, _ := ParseTemplate("", EmailTemplate)
+	email := NewEmail("127.0.0.1", port, "", "", "a@example.com", []string{"b@example.com"}, tmpl)
+	err = email.Notify(context.Background(), Event{Type: EventPutioAuthFailed})
+	if err == nil || !strings.Contains(err.Error(), "email notification failed") {
+		t.Errorf("expected connection error, got %v", err)
+	}
+}
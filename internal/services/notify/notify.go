@@ -0,0 +1,137 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"text/template"
+)
+
+// EventType identifies the kind of notification.
+type EventType string
+
+const (
+	// EventImportTimeout is sent when a downloaded transfer was not imported in time.
+	EventImportTimeout EventType = "import_timeout"
+	// EventPutioRecovered is sent when put.io responds again after failed polls.
+	EventPutioRecovered EventType = "putio_recovered"
+	// EventLowDiskSpace is sent when downloads pause because free space in the
+	// download directory fell below min_free_space.
+	EventLowDiskSpace EventType = "low_disk_space"
+	// EventDailySummary reports the pipeline statistics of the last day, if
+	// daily summaries are enabled.
+	EventDailySummary EventType = "daily_summary"
+	// EventDownloadFailed is sent when a transfer failed to download after
+	// exhausting download_retries.
+	EventDownloadFailed EventType = "download_failed"
+	// EventPutioAuthFailed is sent when put.io rejects the API key, e.g. after
+	// it was revoked.
+	EventPutioAuthFailed EventType = "putio_auth_failed"
+)
+
+// EventTypes lists every event type, in the order they were introduced.
+var EventTypes = []EventType{
+	EventImportTimeout, EventPutioRecovered, EventLowDiskSpace, EventDailySummary,
+	EventDownloadFailed, EventPutioAuthFailed,
+}
+
+// FailureEvents are the event types that need the user's attention.
+var FailureEvents = []EventType{EventImportTimeout, EventLowDiskSpace, EventDownloadFailed, EventPutioAuthFailed}
+
+// Event describes something the user should be told about.
+type Event struct {
+	Type       EventType `json:"type"`
+	Title      string    `json:"title"`
+	Message    string    `json:"message"`
+	Transfer   string    `json:"transfer,omitempty"`
+	Hash       string    `json:"hash,omitempty"`
+	TransferID uint64    `json:"transfer_id,omitempty"`
+}
+
+// Notifier delivers events to an external service.
+type Notifier interface {
+	Notify(ctx context.Context, event Event) error
+}
+
+// Multi fans an event out to several notifiers. An empty Multi is a no-op.
+type Multi []Notifier
+
+var _ Notifier = Multi(nil)
+
+// Notify sends event to every notifier and joins their errors.
+func (m Multi) Notify(ctx context.Context, event Event) error {
+	var errs []error
+	for _, n := range m {
+		if err := n.Notify(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Filtered passes on only the events of the given types.
+type Filtered struct {
+	notifier Notifier
+	types    map[EventType]bool
+}
+
+var _ Notifier = (*Filtered)(nil)
+
+// Filter returns a notifier that sends events to n only if their type is one
+// of names. An empty names keeps every event. Unknown names are an error.
+func Filter(n Notifier, names []string) (Notifier, error) {
+	if len(names) == 0 {
+		return n, nil
+	}
+	types := make(map[EventType]bool, len(names))
+	for _, name := range names {
+		known := false
+		for _, t := range EventTypes {
+			if EventType(name) == t {
+				known = true
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown event %q, expected one of %v", name, EventTypes)
+		}
+		types[EventType(name)] = true
+	}
+	return &Filtered{notifier: n, types: types}, nil
+}
+
+// Notify sends event if its type was selected.
+func (f *Filtered) Notify(ctx context.Context, event Event) error {
+	if !f.types[event.Type] {
+		return nil
+	}
+	return f.notifier.Notify(ctx, event)
+}
+
+// ParseTemplate parses a message template, which is executed with the Event,
+// e.g. "{{.Title}}: {{.Message}}". An empty text uses fallback. Templates
+// referring to fields an Event doesn't have are rejected here rather than
+// when a notification is due.
+func ParseTemplate(text, fallback string) (*template.Template, error) {
+	if text == "" {
+		text = fallback
+	}
+	tmpl, err := template.New("message").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	if err := tmpl.Execute(io.Discard, Event{}); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// render executes tmpl for event.
+func render(tmpl *template.Template, event Event) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, event); err != nil {
+		return "", fmt.Errorf("rendering %s notification: %w", event.Type, err)
+	}
+	return buf.String(), nil
+}
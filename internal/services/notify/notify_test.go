@@ -0,0 +1,129 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type recordingNotifier struct {
+	events []Event
+	err    error
+}
+
+func (r *recordingNotifier) Notify(ctx context.Context, event Event) error {
+	r.events = append(r.events, event)
+	return r.err
+}
+
+func TestMultiNotify(t *testing.T) {
+	first := &recordingNotifier{}
+	second := &recordingNotifier{err: errors.New("boom")}
+	third := &recordingNotifier{}
+
+	err := Multi{first, second, third}.Notify(context.Background(), Event{Type: EventImportTimeout})
+	if err == nil || err.Error() != "boom" {
+		t.Errorf("expected joined error 'boom', got %v", err)
+	}
+	if len(first.events) != 1 || len(third.events) != 1 {
+		t.Error("expected every notifier to receive the event despite errors")
+	}
+
+	if err := (Multi{}).Notify(context.Background(), Event{}); err != nil {
+		t.Errorf("expected empty Multi to be a no-op, got %v", err)
+	}
+}
+
+func TestWebhookNotify(t *testing.T) {
+	var received Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("expected POST, got %s", r.Method)
+		}
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected content type %q", r.Header.Get("Content-Type"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("failed to decode body: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	event := Event{Type: EventImportTimeout, Title: "title", Message: "msg", Transfer: "Show", Hash: "abcd", TransferID: 42}
+	if err := NewWebhook(server.URL).Notify(context.Background(), event); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if received != event {
+		t.Errorf("expected %+v, got %+v", event, received)
+	}
+}
+
+func TestWebhookNotifyHTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	if err := NewWebhook(server.URL).Notify(context.Background(), Event{}); err == nil {
+		t.Error("expected error for non-2xx response")
+	}
+}
+
+func TestFilter(t *testing.T) {
+	recorder := &recordingNotifier{}
+	filtered, err := Filter(recorder, []string{"import_timeout", "daily_summary"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, eventType := range EventTypes {
+		filtered.Notify(context.Background(), Event{Type: eventType})
+	}
+	if len(recorder.events) != 2 || recorder.events[0].Type != EventImportTimeout || recorder.events[1].Type != EventDailySummary {
+		t.Errorf("expected only the selected events, got %+v", recorder.events)
+	}
+
+	if all, _ := Filter(recorder, nil); all != Notifier(recorder) {
+		t.Error("expected no filter without event names")
+	}
+	if _, err := Filter(recorder, []string{"imported"}); err == nil {
+		t.Error("expected an error for an unknown event")
+	}
+}
+
+func TestParseTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected string
+		wantErr  bool
+	}{
+		{name: "fallback", text: "", expected: "Title: msg"},
+		{name: "custom", text: "[{{.Type}}] {{.Transfer}}", expected: "[import_timeout] Show"},
+		{name: "syntax error", text: "{{.Title", wantErr: true},
+		{name: "unknown field", text: "{{.Nope}}", wantErr: true},
+	}
+
+	event := Event{Type: EventImportTimeout, Title: "Title", Message: "msg", Transfer: "Show"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := ParseTemplate(tt.text, "{{.Title}}: {{.Message}}")
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got, err := render(tmpl, event)
+			if err != nil || got != tt.expected {
+				t.Errorf("expected %q, got %q (%v)", tt.expected, got, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"text/template"
+)
+
+// PushoverTemplate is the default Pushover message template; the title is
+// sent separately.
+const PushoverTemplate = "{{.Message}}"
+
+const pushoverAPIURL = "https://api.pushover.net/1/messages.json"
+
+// Pushover sends events to a Pushover user or group.
+type Pushover struct {
+	apiURL     string
+	token      string
+	userKey    string
+	template   *template.Template
+	httpClient *http.Client
+}
+
+var _ Notifier = (*Pushover)(nil)
+
+// NewPushover creates a notifier that sends messages rendered with tmpl to
+// userKey using the application token.
+func NewPushover(token, userKey string, tmpl *template.Template) *Pushover {
+	return &Pushover{
+		apiURL:     pushoverAPIURL,
+		token:      token,
+		userKey:    userKey,
+		template:   tmpl,
+		httpClient: &http.Client{Timeout: defaultTimeout},
+	}
+}
+
+// Notify sends event to Pushover.
+func (p *Pushover) Notify(ctx context.Context, event Event) error {
+	message, err := render(p.template, event)
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, p.httpClient, "pushover", p.apiURL, map[string]string{
+		"token":   p.token,
+		"user":    p.userKey,
+		"title":   event.Title,
+		"message": message,
+	})
+}
@@ -0,0 +1,47 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"text/template"
+)
+
+// TelegramTemplate is the default Telegram message template.
+const TelegramTemplate = "{{.Title}}\n{{.Message}}"
+
+const telegramAPIURL = "https://api.telegram.org"
+
+// Telegram sends events to a Telegram chat through a bot.
+type Telegram struct {
+	apiURL     string
+	botToken   string
+	chatID     string
+	template   *template.Template
+	httpClient *http.Client
+}
+
+var _ Notifier = (*Telegram)(nil)
+
+// NewTelegram creates a notifier that sends messages rendered with tmpl to
+// chatID as the bot with the given token.
+func NewTelegram(botToken, chatID string, tmpl *template.Template) *Telegram {
+	return &Telegram{
+		apiURL:     telegramAPIURL,
+		botToken:   botToken,
+		chatID:     chatID,
+		template:   tmpl,
+		httpClient: &http.Client{Timeout: defaultTimeout},
+	}
+}
+
+// Notify sends event to the Telegram chat.
+func (t *Telegram) Notify(ctx context.Context, event Event) error {
+	text, err := render(t.template, event)
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, t.httpClient, "telegram", t.apiURL+"/bot"+t.botToken+"/sendMessage", map[string]string{
+		"chat_id": t.chatID,
+		"text":    text,
+	})
+}
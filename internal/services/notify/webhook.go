@@ -0,0 +1,65 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const defaultTimeout = 10 * time.Second
+
+// Webhook posts events as JSON to a URL.
+type Webhook struct {
+	url        string
+	httpClient *http.Client
+}
+
+var _ Notifier = (*Webhook)(nil)
+
+// NewWebhook creates a notifier that posts to url.
+func NewWebhook(url string) *Webhook {
+	return &Webhook{
+		url:        url,
+		httpClient: &http.Client{Timeout: defaultTimeout},
+	}
+}
+
+// Notify posts event to the webhook URL.
+func (w *Webhook) Notify(ctx context.Context, event Event) error {
+	return postJSON(ctx, w.httpClient, "webhook", w.url, event)
+}
+
+// postJSON posts body as JSON to target and fails unless it gets a 2xx
+// response. Errors leave out the URL, which may contain credentials.
+func postJSON(ctx context.Context, client *http.Client, backend, target string, body any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("%s notification failed: invalid URL", backend)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("%s notification failed: %w", backend, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s notification failed: %s", backend, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package putio
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CallCounter counts put.io API requests by endpoint, so users can check the
+// proxy stays within put.io's rate limits. Every attempt counts, including
+// retries. All methods are safe to call on a nil counter.
+type CallCounter struct {
+	now func() time.Time
+
+	mu      sync.Mutex
+	minute  time.Time
+	current map[string]int
+	last    map[string]int
+	total   map[string]int
+}
+
+// NewCallCounter creates an empty CallCounter.
+func NewCallCounter() *CallCounter {
+	return &CallCounter{
+		now:     time.Now,
+		current: make(map[string]int),
+		last:    make(map[string]int),
+		total:   make(map[string]int),
+	}
+}
+
+// Record counts one request to endpoint.
+func (c *CallCounter) Record(endpoint string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rotateLocked()
+	c.current[endpoint]++
+	c.total[endpoint]++
+}
+
+// LastMinute returns the requests made during the previous full minute, by
+// endpoint.
+func (c *CallCounter) LastMinute() map[string]int {
+	if c == nil {
+		return map[string]int{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rotateLocked()
+	return copyCounts(c.last)
+}
+
+// Totals returns the requests made since the counter was created, by
+// endpoint.
+func (c *CallCounter) Totals() map[string]int {
+	if c == nil {
+		return map[string]int{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return copyCounts(c.total)
+}
+
+// rotateLocked starts a new minute bucket once the clock passes into the next
+// minute. The previous bucket is kept only if it covers the minute just ended.
+func (c *CallCounter) rotateLocked() {
+	minute := c.now().Truncate(time.Minute)
+	if minute.Equal(c.minute) {
+		return
+	}
+	if minute.Sub(c.minute) == time.Minute {
+		c.last = c.current
+	} else {
+		c.last = make(map[string]int)
+	}
+	c.current = make(map[string]int)
+	c.minute = minute
+}
+
+func copyCounts(counts map[string]int) map[string]int {
+	out := make(map[string]int, len(counts))
+	for endpoint, n := range counts {
+		out[endpoint] = n
+	}
+	return out
+}
+
+// endpointName names the API endpoint of a request URL, e.g.
+// "transfers/list" or "files/{id}/url", leaving out the API version and IDs
+// so requests to the same endpoint are counted together.
+func endpointName(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "unknown"
+	}
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(segments) > 0 && segments[0] == "v2" {
+		segments = segments[1:]
+	}
+	for i, segment := range segments {
+		if segment != "" && strings.Trim(segment, "0123456789") == "" {
+			segments[i] = "{id}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
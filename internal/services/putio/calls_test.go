@@ -0,0 +1,91 @@
+package putio
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCallCounterMinutes(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 10, 0, time.UTC)
+	counter := NewCallCounter()
+	counter.now = func() time.Time { return now }
+
+	counter.Record("transfers/list")
+	counter.Record("transfers/list")
+	counter.Record("files/{id}/url")
+	if got := counter.LastMinute(); len(got) != 0 {
+		t.Errorf("expected nothing for the previous minute yet, got %v", got)
+	}
+
+	now = now.Add(time.Minute)
+	counter.Record("transfers/list")
+	want := map[string]int{"transfers/list": 2, "files/{id}/url": 1}
+	if got := counter.LastMinute(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v for the previous minute, got %v", want, got)
+	}
+
+	// An idle minute in between means nothing happened in the previous one.
+	now = now.Add(2 * time.Minute)
+	if got := counter.LastMinute(); len(got) != 0 {
+		t.Errorf("expected an idle previous minute, got %v", got)
+	}
+
+	want = map[string]int{"transfers/list": 3, "files/{id}/url": 1}
+	if got := counter.Totals(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected totals %v, got %v", want, got)
+	}
+}
+
+func TestCallCounterNil(t *testing.T) {
+	var counter *CallCounter
+	counter.Record("transfers/list")
+	if len(counter.LastMinute()) != 0 || len(counter.Totals()) != 0 {
+		t.Error("expected a nil counter to count nothing")
+	}
+}
+
+func TestEndpointName(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{url: "https://api.put.io/v2/transfers/list?per_page=500", expected: "transfers/list"},
+		{url: "https://api.put.io/v2/files/1234/url", expected: "files/{id}/url"},
+		{url: "https://api.put.io/v2/transfers/42", expected: "transfers/{id}"},
+		{url: "https://upload.put.io/v2/files/upload", expected: "files/upload"},
+		{url: "http://127.0.0.1:4000/account/info", expected: "account/info"},
+	}
+	for _, tt := range tests {
+		if got := endpointName(tt.url); got != tt.expected {
+			t.Errorf("endpointName(%q) = %q, expected %q", tt.url, got, tt.expected)
+		}
+	}
+}
+
+func TestClientCountsCalls(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{"transfers":[]}`))
+	}))
+	defer server.Close()
+
+	counter := NewCallCounter()
+	client := NewClient("token", WithBaseURLs(server.URL, server.URL), WithHTTPClient(server.Client()), WithCallCounter(counter))
+	client.sleeper = func(time.Duration) {}
+	if _, err := client.ListTransfers(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[string]int{"transfers/list": 2}
+	if got := counter.Totals(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected retries to be counted too, got %v", got)
+	}
+}
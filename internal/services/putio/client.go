@@ -2,25 +2,44 @@ package putio
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
+	"net"
 	"net/http"
+	"net/url"
+	"runtime/debug"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/ochronus/goputioarr/internal/services/retry"
+	"github.com/ochronus/goputioarr/internal/services/torrent"
 )
 
 const (
 	defaultBaseURL   = "https://api.put.io/v2"
 	defaultUploadURL = "https://upload.put.io/v2"
 	defaultTimeout   = 10 * time.Second
+	// defaultUploadTimeout allows for slow uploads of large torrent files.
+	defaultUploadTimeout = 120 * time.Second
+
+	// DefaultFilesPerPage is the page size used when listing folders.
+	DefaultFilesPerPage = 1000
+	// DefaultTransfersPerPage is the page size used when listing transfers.
+	DefaultTransfersPerPage = 500
 
 	maxRetries  = 3
 	backoffBase = 200 * time.Millisecond
+
+	// maxBatchSize is the most IDs sent in one remove or delete request.
+	maxBatchSize = 100
+	// batchConcurrency is how many batches are sent at once.
+	batchConcurrency = 4
 )
 
 type HTTPError struct {
@@ -34,13 +53,60 @@ func (e *HTTPError) Error() string {
 	return fmt.Sprintf("url: %s, status: %s", e.URL, e.Status)
 }
 
+// Hint returns what to check for the error's status, or "" if there's nothing
+// the user can do about it.
+func (e *HTTPError) Hint() string {
+	switch {
+	case e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden:
+		return "put.io rejected putio.api_key; generate a new one with `goputioarr get-token`"
+	case e.StatusCode == http.StatusTooManyRequests:
+		return "put.io is rate limiting requests; consider raising polling_interval"
+	case e.StatusCode >= 500:
+		return "put.io is having problems; requests are retried automatically"
+	}
+	return ""
+}
+
+// ConnectionError is a request that got no response from put.io.
+type ConnectionError struct {
+	URL string
+	Err error
+}
+
+func (e *ConnectionError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ConnectionError) Unwrap() error {
+	return e.Err
+}
+
+// Hint returns what to check to reach put.io.
+func (e *ConnectionError) Hint() string {
+	if isTimeout(e.Err) {
+		return "put.io took too long to answer; raise putio.timeout, or putio.upload_timeout for torrent uploads"
+	}
+	return "check the network connection and putio.api_url"
+}
+
+// isTimeout reports whether err is a request that timed out.
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout())
+}
+
 // Client represents a Put.io API client.
 type Client struct {
-	apiToken   string
-	baseURL    string
-	uploadURL  string
-	httpClient *http.Client
-	sleeper    func(time.Duration)
+	apiToken         string
+	baseURL          string
+	uploadURL        string
+	httpClient       *http.Client
+	uploadClient     *http.Client
+	sleeper          func(time.Duration)
+	filesPerPage     int
+	transfersPerPage int
+	saveParentID     int64
+	calls            *CallCounter
 }
 
 var _ ClientAPI = (*Client)(nil)
@@ -48,27 +114,83 @@ var _ ClientAPI = (*Client)(nil)
 // ClientOption configures the Client.
 type ClientOption func(*Client)
 
-// WithBaseURLs overrides the API and upload base URLs (useful for tests).
+// WithBaseURLs overrides the API and upload base URLs, e.g. for a proxy or an
+// API mock. Empty URLs keep put.io's.
 func WithBaseURLs(apiBaseURL, uploadBaseURL string) ClientOption {
 	return func(c *Client) {
 		if apiBaseURL != "" {
-			c.baseURL = apiBaseURL
+			c.baseURL = strings.TrimSuffix(apiBaseURL, "/")
 		}
 		if uploadBaseURL != "" {
-			c.uploadURL = uploadBaseURL
+			c.uploadURL = strings.TrimSuffix(uploadBaseURL, "/")
 		}
 	}
 }
 
-// WithHTTPClient overrides the HTTP client (useful for tests).
+// WithHTTPClient overrides the HTTP client (useful for tests). Uploads use a
+// copy of it, so they can have their own timeout.
 func WithHTTPClient(hc *http.Client) ClientOption {
 	return func(c *Client) {
 		if hc != nil {
+			upload := *hc
 			c.httpClient = hc
+			c.uploadClient = &upload
 		}
 	}
 }
 
+// WithTransport sets the round tripper used for every request.
+func WithTransport(rt http.RoundTripper) ClientOption {
+	return func(c *Client) {
+		if rt != nil {
+			c.httpClient.Transport = rt
+			c.uploadClient.Transport = rt
+		}
+	}
+}
+
+// WithTimeouts sets the time limit of API requests and of torrent file
+// uploads. Zero means no limit.
+func WithTimeouts(request, upload time.Duration) ClientOption {
+	return func(c *Client) {
+		c.httpClient.Timeout = request
+		c.uploadClient.Timeout = upload
+	}
+}
+
+// WithFilesPerPage sets the page size used by ListFiles.
+func WithFilesPerPage(n int) ClientOption {
+	return func(c *Client) {
+		if n > 0 {
+			c.filesPerPage = n
+		}
+	}
+}
+
+// WithTransfersPerPage sets the page size used by ListTransfers.
+func WithTransfersPerPage(n int) ClientOption {
+	return func(c *Client) {
+		if n > 0 {
+			c.transfersPerPage = n
+		}
+	}
+}
+
+// WithSaveParentID makes AddTransfer and UploadFile save into the given put.io
+// folder instead of the account's default folder. Zero keeps the default.
+func WithSaveParentID(id int64) ClientOption {
+	return func(c *Client) {
+		c.saveParentID = id
+	}
+}
+
+// WithCallCounter counts every request the client makes in counter.
+func WithCallCounter(counter *CallCounter) ClientOption {
+	return func(c *Client) {
+		c.calls = counter
+	}
+}
+
 // NewClient creates a new Put.io client.
 func NewClient(apiToken string, opts ...ClientOption) *Client {
 	c := &Client{
@@ -78,7 +200,12 @@ func NewClient(apiToken string, opts ...ClientOption) *Client {
 		httpClient: &http.Client{
 			Timeout: defaultTimeout,
 		},
-		sleeper: time.Sleep,
+		uploadClient: &http.Client{
+			Timeout: defaultUploadTimeout,
+		},
+		sleeper:          time.Sleep,
+		filesPerPage:     DefaultFilesPerPage,
+		transfersPerPage: DefaultTransfersPerPage,
 	}
 
 	for _, opt := range opts {
@@ -112,8 +239,22 @@ type Transfer struct {
 	Status         string  `json:"status"`
 	StartedAt      *string `json:"started_at"`
 	ErrorMessage   *string `json:"error_message"`
+	CreatedAt      *string `json:"created_at"`
 	FileID         *int64  `json:"file_id"`
+	SaveParentID   *int64  `json:"save_parent_id"`
 	UserfileExists bool    `json:"userfile_exists"`
+	// Uploaded and CurrentRatio report what put.io seeded. They are missing
+	// for transfers that didn't seed.
+	Uploaded       *int64   `json:"uploaded"`
+	CurrentRatio   *float64 `json:"current_ratio"`
+	SecondsSeeding *int64   `json:"seconds_seeding"`
+	// Swarm activity while put.io downloads or seeds; speeds are in bytes
+	// per second.
+	PeersConnected     int64 `json:"peers_connected"`
+	PeersGettingFromUs int64 `json:"peers_getting_from_us"`
+	PeersSendingToUs   int64 `json:"peers_sending_to_us"`
+	DownSpeed          int64 `json:"down_speed"`
+	UpSpeed            int64 `json:"up_speed"`
 }
 
 // IsDownloadable returns true if the transfer has a file_id.
@@ -121,9 +262,32 @@ func (t *Transfer) IsDownloadable() bool {
 	return t.FileID != nil
 }
 
+// Created returns when the transfer was created on put.io, if known.
+func (t *Transfer) Created() (time.Time, bool) {
+	return parseTime(t.CreatedAt)
+}
+
+// Finished returns when the transfer finished on put.io, if known.
+func (t *Transfer) Finished() (time.Time, bool) {
+	return parseTime(t.FinishedAt)
+}
+
+// parseTime parses a put.io timestamp, which is in UTC without a zone.
+func parseTime(value *string) (time.Time, bool) {
+	if value == nil {
+		return time.Time{}, false
+	}
+	parsed, err := time.Parse("2006-01-02T15:04:05", *value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return parsed, true
+}
+
 // ListTransferResponse represents the API response for list transfers.
 type ListTransferResponse struct {
 	Transfers []Transfer `json:"transfers"`
+	Cursor    *string    `json:"cursor"`
 }
 
 // GetTransferResponse represents the API response for get transfer.
@@ -133,16 +297,24 @@ type GetTransferResponse struct {
 
 // FileResponse represents a file from put.io.
 type FileResponse struct {
-	ContentType string `json:"content_type"`
-	ID          int64  `json:"id"`
-	Name        string `json:"name"`
-	FileType    string `json:"file_type"`
+	ContentType string  `json:"content_type"`
+	ID          int64   `json:"id"`
+	Name        string  `json:"name"`
+	FileType    string  `json:"file_type"`
+	Size        int64   `json:"size"`
+	CreatedAt   *string `json:"created_at"`
+}
+
+// Created returns when the file was created on put.io, if known.
+func (f *FileResponse) Created() (time.Time, bool) {
+	return parseTime(f.CreatedAt)
 }
 
 // ListFileResponse represents the API response for list files.
 type ListFileResponse struct {
 	Files  []FileResponse `json:"files"`
 	Parent FileResponse   `json:"parent"`
+	Cursor *string        `json:"cursor"`
 }
 
 // URLResponse represents the API response for getting a file URL.
@@ -154,6 +326,14 @@ type requestFactory func() (io.ReadCloser, string, error)
 
 // doRequest executes an HTTP request with authorization and retries with backoff on 5xx/429.
 func (c *Client) doRequest(method, url string, factory requestFactory) (*http.Response, error) {
+	return c.doRequestChecked(c.httpClient, method, url, factory, nil)
+}
+
+// doRequestChecked is doRequest with the given HTTP client, for requests that
+// aren't safe to repeat. Before each retry it calls done, which reports whether
+// the failed attempt took effect anyway; if so it returns a nil response and no
+// error.
+func (c *Client) doRequestChecked(client *http.Client, method, url string, factory requestFactory, done func() bool) (*http.Response, error) {
 	var respOut *http.Response
 
 	err := retry.Do(nil, retry.Config{
@@ -179,6 +359,10 @@ func (c *Client) doRequest(method, url string, factory requestFactory) (*http.Re
 		},
 		Sleeper: c.sleeper,
 	}, func(attempt int) error {
+		if attempt > 0 && done != nil && done() {
+			return nil
+		}
+
 		body, contentType, err := factory()
 		if err != nil {
 			return err
@@ -194,9 +378,10 @@ func (c *Client) doRequest(method, url string, factory requestFactory) (*http.Re
 			req.Header.Set("Content-Type", contentType)
 		}
 
-		resp, err := c.httpClient.Do(req)
+		c.calls.Record(endpointName(url))
+		resp, err := client.Do(req)
 		if err != nil {
-			return err
+			return &ConnectionError{URL: url, Err: err}
 		}
 
 		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
@@ -241,28 +426,51 @@ func (c *Client) GetAccountInfo() (*AccountInfoResponse, error) {
 
 // ListTransfers returns the user's transfers.
 func (c *Client) ListTransfers() (*ListTransferResponse, error) {
-	url := c.baseURL + "/transfers/list"
+	return c.ListTransfersSince(0)
+}
 
-	resp, err := c.doRequest(http.MethodGet, url, func() (io.ReadCloser, string, error) {
-		return nil, "", nil
-	})
-	if err != nil {
+// ListTransfersSince returns the user's transfers, newest first, without
+// following the cursor past the page that reaches a transfer with an ID of at
+// most minID. The last page may still hold such older transfers. A zero minID
+// lists every transfer.
+func (c *Client) ListTransfersSince(minID uint64) (*ListTransferResponse, error) {
+	requestURL := fmt.Sprintf("%s/transfers/list?per_page=%d", c.baseURL, c.transfersPerPage)
+	var result ListTransferResponse
+	if err := c.getPage(http.MethodGet, requestURL, "", c.transfersPerPage, &result); err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, &HTTPError{URL: url, StatusCode: resp.StatusCode, Status: resp.Status}
-	}
-
-	var result ListTransferResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
+	// Follow the cursor until put.io reports no more pages or the known
+	// transfers are reached
+	cursor := result.Cursor
+	reached := reachesID(result.Transfers, minID)
+	for !reached && cursor != nil && *cursor != "" {
+		var page ListTransferResponse
+		if err := c.getPage(http.MethodPost, c.baseURL+"/transfers/list/continue", *cursor, c.transfersPerPage, &page); err != nil {
+			return nil, err
+		}
+		result.Transfers = append(result.Transfers, page.Transfers...)
+		cursor = page.Cursor
+		reached = reachesID(page.Transfers, minID)
 	}
+	result.Cursor = nil
 
 	return &result, nil
 }
 
+// reachesID reports whether transfers include one with an ID of at most minID.
+func reachesID(transfers []Transfer, minID uint64) bool {
+	if minID == 0 {
+		return false
+	}
+	for _, t := range transfers {
+		if t.ID <= minID {
+			return true
+		}
+	}
+	return false
+}
+
 // GetTransfer returns a specific transfer.
 func (c *Client) GetTransfer(transferID uint64) (*GetTransferResponse, error) {
 	url := fmt.Sprintf("%s/transfers/%d", c.baseURL, transferID)
@@ -288,34 +496,78 @@ func (c *Client) GetTransfer(transferID uint64) (*GetTransferResponse, error) {
 
 // RemoveTransfer removes a transfer.
 func (c *Client) RemoveTransfer(transferID uint64) error {
-	var buf bytes.Buffer
-	writer := multipart.NewWriter(&buf)
-	_ = writer.WriteField("transfer_ids", strconv.FormatUint(transferID, 10))
-	writer.Close()
-	url := c.baseURL + "/transfers/remove"
-
-	resp, err := c.doRequest(http.MethodPost, url, func() (io.ReadCloser, string, error) {
-		return io.NopCloser(bytes.NewReader(buf.Bytes())), writer.FormDataContentType(), nil
-	})
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
+	return c.RemoveTransfers([]uint64{transferID})
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return &HTTPError{URL: url, StatusCode: resp.StatusCode, Status: resp.Status}
+// RemoveTransfers removes transfers, batching their IDs into as few requests
+// as possible.
+func (c *Client) RemoveTransfers(transferIDs []uint64) error {
+	ids := make([]string, len(transferIDs))
+	for i, id := range transferIDs {
+		ids[i] = strconv.FormatUint(id, 10)
 	}
+	return c.postBatches("/transfers/remove", "transfer_ids", ids)
+}
 
-	return nil
+// RetryTransfer asks put.io to start a transfer over.
+func (c *Client) RetryTransfer(transferID uint64) error {
+	return c.postIDs("/transfers/retry", "id", []string{strconv.FormatUint(transferID, 10)})
 }
 
 // DeleteFile deletes a file or directory.
 func (c *Client) DeleteFile(fileID int64) error {
+	return c.DeleteFiles([]int64{fileID})
+}
+
+// DeleteFiles deletes files or directories, batching their IDs into as few
+// requests as possible.
+func (c *Client) DeleteFiles(fileIDs []int64) error {
+	ids := make([]string, len(fileIDs))
+	for i, id := range fileIDs {
+		ids[i] = strconv.FormatInt(id, 10)
+	}
+	return c.postBatches("/files/delete", "file_ids", ids)
+}
+
+// postBatches posts ids to path as a comma-separated field, at most
+// maxBatchSize per request. Batches are sent concurrently, and every batch is
+// attempted even if another one fails.
+func (c *Client) postBatches(path, field string, ids []string) error {
+	var batches [][]string
+	for len(ids) > 0 {
+		n := min(len(ids), maxBatchSize)
+		batches = append(batches, ids[:n])
+		ids = ids[n:]
+	}
+
+	errs := make([]error, len(batches))
+	sem := make(chan struct{}, batchConcurrency)
+	var wg sync.WaitGroup
+	for i, batch := range batches {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			defer func() {
+				if r := recover(); r != nil {
+					errs[i] = fmt.Errorf("posting to %s panicked: %v\n%s", path, r, debug.Stack())
+				}
+			}()
+			errs[i] = c.postIDs(path, field, batch)
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// postIDs posts a single batch of ids to path.
+func (c *Client) postIDs(path, field string, ids []string) error {
 	var buf bytes.Buffer
 	writer := multipart.NewWriter(&buf)
-	_ = writer.WriteField("file_ids", strconv.FormatInt(fileID, 10))
+	_ = writer.WriteField(field, strings.Join(ids, ","))
 	writer.Close()
-	url := c.baseURL + "/files/delete"
+	url := c.baseURL + path
 
 	resp, err := c.doRequest(http.MethodPost, url, func() (io.ReadCloser, string, error) {
 		return io.NopCloser(bytes.NewReader(buf.Bytes())), writer.FormDataContentType(), nil
@@ -333,10 +585,13 @@ func (c *Client) DeleteFile(fileID int64) error {
 }
 
 // AddTransfer adds a new transfer from a URL or magnet link.
-func (c *Client) AddTransfer(url string) error {
+func (c *Client) AddTransfer(url string) (*Transfer, error) {
 	var buf bytes.Buffer
 	writer := multipart.NewWriter(&buf)
 	_ = writer.WriteField("url", url)
+	if c.saveParentID != 0 {
+		_ = writer.WriteField("save_parent_id", strconv.FormatInt(c.saveParentID, 10))
+	}
 	writer.Close()
 	requestURL := c.baseURL + "/transfers/add"
 
@@ -344,71 +599,159 @@ func (c *Client) AddTransfer(url string) error {
 		return io.NopCloser(bytes.NewReader(buf.Bytes())), writer.FormDataContentType(), nil
 	})
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return &HTTPError{URL: requestURL, StatusCode: resp.StatusCode, Status: resp.Status}
+		return nil, &HTTPError{URL: requestURL, StatusCode: resp.StatusCode, Status: resp.Status}
 	}
 
-	return nil
+	return decodeTransfer(resp.Body)
 }
 
 // UploadFile uploads a torrent file.
-func (c *Client) UploadFile(data []byte) error {
-	var buf bytes.Buffer
-	writer := multipart.NewWriter(&buf)
+func (c *Client) UploadFile(data []byte) (*Transfer, error) {
+	url := c.uploadURL + "/files/upload"
 
-	part, err := writer.CreateFormFile("file", "upload.torrent")
+	// A failed upload may still have created the transfer, so before retrying
+	// look for it by info hash instead of adding a duplicate.
+	var existing *Transfer
+	var done func() bool
+	if meta, err := torrent.ParseMetainfo(data); err == nil {
+		done = func() bool {
+			existing = c.findTransfer(meta.InfoHash)
+			return existing != nil
+		}
+	}
+
+	// The multipart body is streamed through a pipe rather than buffered, so
+	// uploads don't hold a second copy of the torrent in memory. Each attempt
+	// gets a fresh pipe; the transport closes the reader, which unblocks the
+	// writer if the request fails early.
+	resp, err := c.doRequestChecked(c.uploadClient, http.MethodPost, url, func() (io.ReadCloser, string, error) {
+		pr, pw := io.Pipe()
+		writer := multipart.NewWriter(pw)
+		go func() {
+			pw.CloseWithError(c.writeUpload(writer, data))
+		}()
+		return pr, writer.FormDataContentType(), nil
+	}, done)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if _, err := part.Write(data); err != nil {
-		return err
+	if resp == nil {
+		return existing, nil
 	}
+	defer resp.Body.Close()
 
-	_ = writer.WriteField("filename", "upload.torrent")
-	writer.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &HTTPError{URL: url, StatusCode: resp.StatusCode, Status: resp.Status}
+	}
 
-	url := c.uploadURL + "/files/upload"
+	return decodeTransfer(resp.Body)
+}
 
-	resp, err := c.doRequest(http.MethodPost, url, func() (io.ReadCloser, string, error) {
-		return io.NopCloser(bytes.NewReader(buf.Bytes())), writer.FormDataContentType(), nil
-	})
+// findTransfer returns the transfer with the given info hash, or nil if there
+// is none or the transfers can't be listed.
+func (c *Client) findTransfer(hash string) *Transfer {
+	transfers, err := c.ListTransfers()
 	if err != nil {
-		return err
+		return nil
 	}
-	defer resp.Body.Close()
+	for i, t := range transfers.Transfers {
+		if t.Hash != nil && strings.EqualFold(*t.Hash, hash) {
+			return &transfers.Transfers[i]
+		}
+	}
+	return nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return &HTTPError{URL: url, StatusCode: resp.StatusCode, Status: resp.Status}
+// writeUpload writes the multipart form of a torrent file upload.
+func (c *Client) writeUpload(writer *multipart.Writer, data []byte) error {
+	part, err := writer.CreateFormFile("file", "upload.torrent")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, bytes.NewReader(data)); err != nil {
+		return err
+	}
+	if err := writer.WriteField("filename", "upload.torrent"); err != nil {
+		return err
+	}
+	if c.saveParentID != 0 {
+		if err := writer.WriteField("save_parent_id", strconv.FormatInt(c.saveParentID, 10)); err != nil {
+			return err
+		}
 	}
+	return writer.Close()
+}
 
-	return nil
+// decodeTransfer reads the transfer created by an add or upload call. It
+// returns nil if the response doesn't describe a transfer.
+func decodeTransfer(body io.Reader) (*Transfer, error) {
+	var result struct {
+		Transfer *Transfer `json:"transfer"`
+	}
+	if err := json.NewDecoder(body).Decode(&result); err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return result.Transfer, nil
 }
 
 // ListFiles lists files in a directory.
 func (c *Client) ListFiles(fileID int64) (*ListFileResponse, error) {
-	url := fmt.Sprintf("%s/files/list?parent_id=%d", c.baseURL, fileID)
-	resp, err := c.doRequest(http.MethodGet, url, func() (io.ReadCloser, string, error) {
-		return nil, "", nil
+	requestURL := fmt.Sprintf("%s/files/list?parent_id=%d&per_page=%d", c.baseURL, fileID, c.filesPerPage)
+	var result ListFileResponse
+	if err := c.getPage(http.MethodGet, requestURL, "", c.filesPerPage, &result); err != nil {
+		return nil, err
+	}
+
+	// Follow the cursor until put.io reports no more pages
+	cursor := result.Cursor
+	for cursor != nil && *cursor != "" {
+		var page ListFileResponse
+		if err := c.getPage(http.MethodPost, c.baseURL+"/files/list/continue", *cursor, c.filesPerPage, &page); err != nil {
+			return nil, err
+		}
+		result.Files = append(result.Files, page.Files...)
+		cursor = page.Cursor
+	}
+	result.Cursor = nil
+
+	return &result, nil
+}
+
+// getPage fetches a single page of a paginated listing into out. A non-empty
+// cursor is posted as a form together with the page size.
+func (c *Client) getPage(method, requestURL, cursor string, perPage int, out interface{}) error {
+	var form string
+	if cursor != "" {
+		form = url.Values{
+			"cursor":   {cursor},
+			"per_page": {strconv.Itoa(perPage)},
+		}.Encode()
+	}
+
+	resp, err := c.doRequest(method, requestURL, func() (io.ReadCloser, string, error) {
+		if form == "" {
+			return nil, "", nil
+		}
+		return io.NopCloser(strings.NewReader(form)), "application/x-www-form-urlencoded", nil
 	})
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, &HTTPError{URL: url, StatusCode: resp.StatusCode, Status: resp.Status}
-	}
-
-	var result ListFileResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
+		return &HTTPError{URL: requestURL, StatusCode: resp.StatusCode, Status: resp.Status}
 	}
 
-	return &result, nil
+	return json.NewDecoder(resp.Body).Decode(out)
 }
 
 // GetFileURL returns the download URL for a file.
@@ -435,11 +778,11 @@ func (c *Client) GetFileURL(fileID int64) (string, error) {
 }
 
 // GetOOB returns a new OOB (out-of-band) code for authentication.
-func GetOOB() (string, error) {
-	url := "https://api.put.io/v2/oauth2/oob/code?app_id=6487"
-	resp, err := http.Get(url)
+func (c *Client) GetOOB() (string, error) {
+	url := c.baseURL + "/oauth2/oob/code?app_id=6487"
+	resp, err := c.httpClient.Get(url)
 	if err != nil {
-		return "", err
+		return "", &ConnectionError{URL: url, Err: err}
 	}
 	defer resp.Body.Close()
 
@@ -461,11 +804,11 @@ func GetOOB() (string, error) {
 }
 
 // CheckOOB checks if the OOB code has been linked and returns the OAuth token.
-func CheckOOB(oobCode string) (string, error) {
-	url := fmt.Sprintf("https://api.put.io/v2/oauth2/oob/code/%s", oobCode)
-	resp, err := http.Get(url)
+func (c *Client) CheckOOB(oobCode string) (string, error) {
+	url := fmt.Sprintf("%s/oauth2/oob/code/%s", c.baseURL, oobCode)
+	resp, err := c.httpClient.Get(url)
 	if err != nil {
-		return "", err
+		return "", &ConnectionError{URL: url, Err: err}
 	}
 	defer resp.Body.Close()
 
@@ -1,13 +1,22 @@
 package putio
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"sort"
 	"strings"
+	"sync"
 	"testing"
 	"time"
+
+	"github.com/ochronus/goputioarr/internal/hint"
 )
 
 func TestNewClient(t *testing.T) {
@@ -23,6 +32,19 @@ func TestNewClient(t *testing.T) {
 	}
 }
 
+func TestWithTimeouts(t *testing.T) {
+	client := NewClient("token")
+	if client.httpClient.Timeout != defaultTimeout || client.uploadClient.Timeout != defaultUploadTimeout {
+		t.Errorf("unexpected default timeouts %s and %s", client.httpClient.Timeout, client.uploadClient.Timeout)
+	}
+
+	hc := &http.Client{}
+	client = NewClient("token", WithHTTPClient(hc), WithTimeouts(time.Second, time.Minute))
+	if client.httpClient != hc || hc.Timeout != time.Second || client.uploadClient.Timeout != time.Minute {
+		t.Errorf("unexpected timeouts %s and %s", client.httpClient.Timeout, client.uploadClient.Timeout)
+	}
+}
+
 func TestTransferIsDownloadable(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -111,12 +133,20 @@ func TestListTransfersResponseParsing(t *testing.T) {
 				"downloaded": 500000,
 				"status": "DOWNLOADING",
 				"file_id": 456,
-				"userfile_exists": true
+				"userfile_exists": true,
+				"peers_connected": 12,
+				"peers_getting_from_us": 3,
+				"peers_sending_to_us": 9,
+				"down_speed": 2048000,
+				"up_speed": 64000
 			},
 			{
 				"id": 124,
 				"status": "SEEDING",
-				"userfile_exists": false
+				"userfile_exists": false,
+				"uploaded": 1500000,
+				"current_ratio": 1.5,
+				"seconds_seeding": 3600
 			}
 		]
 	}`
@@ -175,6 +205,21 @@ func TestListTransfersResponseParsing(t *testing.T) {
 	if t2.IsDownloadable() {
 		t.Error("expected transfer to not be downloadable")
 	}
+	if t2.Uploaded == nil || *t2.Uploaded != 1500000 || t2.CurrentRatio == nil || *t2.CurrentRatio != 1.5 {
+		t.Errorf("unexpected seeding stats: %v %v", t2.Uploaded, t2.CurrentRatio)
+	}
+	if t2.SecondsSeeding == nil || *t2.SecondsSeeding != 3600 {
+		t.Errorf("unexpected seconds_seeding: %v", t2.SecondsSeeding)
+	}
+	if t1.PeersConnected != 12 || t1.PeersGettingFromUs != 3 || t1.PeersSendingToUs != 9 {
+		t.Errorf("unexpected peers: %d %d %d", t1.PeersConnected, t1.PeersGettingFromUs, t1.PeersSendingToUs)
+	}
+	if t1.DownSpeed != 2048000 || t1.UpSpeed != 64000 {
+		t.Errorf("unexpected speeds: %d %d", t1.DownSpeed, t1.UpSpeed)
+	}
+	if t1.Uploaded != nil || t1.CurrentRatio != nil {
+		t.Error("expected no seeding stats for a transfer that didn't seed")
+	}
 }
 
 func TestGetTransferResponseParsing(t *testing.T) {
@@ -295,35 +340,29 @@ func TestGetOOB(t *testing.T) {
 	}))
 	defer server.Close()
 
-	// Test response parsing
-	jsonData := `{"code": "XYZ789"}`
-	var result map[string]string
-	err := json.Unmarshal([]byte(jsonData), &result)
+	client := NewClient("", WithBaseURLs(server.URL+"/v2", ""))
+	code, err := client.GetOOB()
 	if err != nil {
-		t.Fatalf("failed to unmarshal: %v", err)
+		t.Fatalf("unexpected error: %v", err)
 	}
-
-	code, ok := result["code"]
-	if !ok {
-		t.Fatal("code not found in response")
-	}
-	if code != "XYZ789" {
-		t.Errorf("expected code 'XYZ789', got '%s'", code)
+	if code != "ABC123" {
+		t.Errorf("expected code 'ABC123', got '%s'", code)
 	}
 }
 
 func TestCheckOOB(t *testing.T) {
-	// Test response parsing
-	jsonData := `{"oauth_token": "my-oauth-token-12345"}`
-	var result map[string]string
-	err := json.Unmarshal([]byte(jsonData), &result)
-	if err != nil {
-		t.Fatalf("failed to unmarshal: %v", err)
-	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/oauth2/oob/code/ABC123" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		w.Write([]byte(`{"oauth_token": "my-oauth-token-12345"}`))
+	}))
+	defer server.Close()
 
-	token, ok := result["oauth_token"]
-	if !ok {
-		t.Fatal("oauth_token not found in response")
+	client := NewClient("", WithBaseURLs(server.URL+"/v2", ""))
+	token, err := client.CheckOOB("ABC123")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
 	}
 	if token != "my-oauth-token-12345" {
 		t.Errorf("expected token 'my-oauth-token-12345', got '%s'", token)
@@ -895,3 +934,382 @@ func TestListTransferResponseEmpty(t *testing.T) {
 		t.Errorf("expected 0 transfers, got %d", len(response.Transfers))
 	}
 }
+
+func TestAddTransferReturnsTransfer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/transfers/add":
+			w.Write([]byte(`{"status": "OK", "transfer": {"id": 42, "hash": "abcdef", "name": "Show", "status": "IN_QUEUE"}}`))
+		case "/files/upload":
+			w.Write([]byte(`{"status": "OK"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient("token", WithBaseURLs(server.URL, server.URL), WithHTTPClient(server.Client()))
+
+	transfer, err := client.AddTransfer("magnet:?xt=urn:btih:abcdef")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if transfer == nil || transfer.ID != 42 || transfer.Hash == nil || *transfer.Hash != "abcdef" {
+		t.Errorf("unexpected transfer %+v", transfer)
+	}
+
+	transfer, err = client.UploadFile([]byte("torrent"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if transfer != nil {
+		t.Errorf("expected no transfer when the response has none, got %+v", transfer)
+	}
+}
+
+func TestListFilesFollowsCursor(t *testing.T) {
+	var continueCalls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/files/list":
+			if got := r.URL.Query().Get("per_page"); got != "2" {
+				t.Errorf("expected per_page=2, got %q", got)
+			}
+			w.Write([]byte(`{"parent": {"id": 1, "name": "Show", "file_type": "FOLDER"}, "files": [{"id": 2}, {"id": 3}], "cursor": "page2"}`))
+		case "/files/list/continue":
+			if r.Method != http.MethodPost {
+				t.Errorf("expected POST, got %s", r.Method)
+			}
+			r.ParseForm()
+			continueCalls++
+			switch r.PostForm.Get("cursor") {
+			case "page2":
+				w.Write([]byte(`{"files": [{"id": 4}, {"id": 5}], "cursor": "page3"}`))
+			case "page3":
+				w.Write([]byte(`{"files": [{"id": 6}], "cursor": null}`))
+			default:
+				t.Errorf("unexpected cursor %q", r.PostForm.Get("cursor"))
+				w.WriteHeader(http.StatusBadRequest)
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient("token", WithBaseURLs(server.URL, server.URL), WithHTTPClient(server.Client()), WithFilesPerPage(2))
+	result, err := client.ListFiles(1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Parent.Name != "Show" {
+		t.Errorf("expected parent to be kept, got %+v", result.Parent)
+	}
+	if len(result.Files) != 5 {
+		t.Fatalf("expected 5 files across pages, got %d", len(result.Files))
+	}
+	if result.Files[4].ID != 6 {
+		t.Errorf("expected files in page order, got %+v", result.Files)
+	}
+	if continueCalls != 2 {
+		t.Errorf("expected 2 continue calls, got %d", continueCalls)
+	}
+}
+
+func TestListFilesContinueError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/files/list" {
+			w.Write([]byte(`{"parent": {"id": 1}, "files": [{"id": 2}], "cursor": "next"}`))
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	client := NewClient("token", WithBaseURLs(server.URL, server.URL), WithHTTPClient(server.Client()))
+	if _, err := client.ListFiles(1); err == nil {
+		t.Fatal("expected error when a continuation page fails")
+	}
+}
+
+func TestListTransfersFollowsCursor(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/transfers/list":
+			if got := r.URL.Query().Get("per_page"); got != "1" {
+				t.Errorf("expected per_page=1, got %q", got)
+			}
+			w.Write([]byte(`{"transfers": [{"id": 1, "created_at": "2024-06-01T12:00:00"}], "cursor": "next"}`))
+		case "/transfers/list/continue":
+			r.ParseForm()
+			if r.PostForm.Get("cursor") != "next" {
+				t.Errorf("unexpected cursor %q", r.PostForm.Get("cursor"))
+			}
+			w.Write([]byte(`{"transfers": [{"id": 2}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient("token", WithBaseURLs(server.URL, server.URL), WithHTTPClient(server.Client()), WithTransfersPerPage(1))
+	result, err := client.ListTransfers()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.Transfers) != 2 || result.Transfers[1].ID != 2 {
+		t.Fatalf("expected transfers from both pages, got %+v", result.Transfers)
+	}
+
+	created, ok := result.Transfers[0].Created()
+	if !ok || !created.Equal(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected created time %v (ok=%v)", created, ok)
+	}
+	if _, ok := result.Transfers[1].Created(); ok {
+		t.Error("expected no created time for transfer without created_at")
+	}
+}
+
+func TestListTransfersSinceStopsAtKnownTransfers(t *testing.T) {
+	var continueCalls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/transfers/list":
+			w.Write([]byte(`{"transfers": [{"id": 30}, {"id": 29}], "cursor": "page2"}`))
+		case "/transfers/list/continue":
+			continueCalls++
+			r.ParseForm()
+			switch r.PostForm.Get("cursor") {
+			case "page2":
+				w.Write([]byte(`{"transfers": [{"id": 28}, {"id": 20}], "cursor": "page3"}`))
+			default:
+				w.Write([]byte(`{"transfers": [{"id": 19}, {"id": 1}]}`))
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient("token", WithBaseURLs(server.URL, server.URL), WithHTTPClient(server.Client()), WithTransfersPerPage(2))
+	result, err := client.ListTransfersSince(25)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.Transfers) != 4 || continueCalls != 1 {
+		t.Errorf("expected paging to stop at the page reaching ID 25, got %d transfers after %d continue calls", len(result.Transfers), continueCalls)
+	}
+
+	continueCalls = 0
+	if result, err = client.ListTransfers(); err != nil || len(result.Transfers) != 6 || continueCalls != 2 {
+		t.Errorf("expected every page without a known ID, got %d transfers after %d continue calls (err %v)", len(result.Transfers), continueCalls, err)
+	}
+}
+
+func TestAddTransferSaveParentID(t *testing.T) {
+	var fields []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("failed to parse form: %v", err)
+		}
+		fields = append(fields, r.FormValue("save_parent_id"))
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("token", WithBaseURLs(server.URL, server.URL), WithHTTPClient(server.Client()), WithSaveParentID(77))
+	if _, err := client.AddTransfer("magnet:?xt=urn:btih:abc"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := client.UploadFile([]byte("torrent")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(fields) != 2 || fields[0] != "77" || fields[1] != "77" {
+		t.Errorf("unexpected parent fields %v", fields)
+	}
+}
+
+func TestUploadFileStreamsTorrent(t *testing.T) {
+	data := bytes.Repeat([]byte("d4:info"), 100000)
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			t.Fatalf("failed to read uploaded file: %v", err)
+		}
+		got, _ := io.ReadAll(file)
+		if !bytes.Equal(got, data) || r.FormValue("filename") != "upload.torrent" {
+			t.Errorf("unexpected upload of %d bytes named %q", len(got), r.FormValue("filename"))
+		}
+		if attempts == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{"transfer": {"id": 5}}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("token", WithBaseURLs(server.URL, server.URL), WithHTTPClient(server.Client()))
+	client.sleeper = func(time.Duration) {}
+	transfer, err := client.UploadFile(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if transfer == nil || transfer.ID != 5 || attempts != 2 {
+		t.Errorf("expected the retried upload to succeed, got %+v after %d attempts", transfer, attempts)
+	}
+}
+
+func TestUploadFileRetryFindsCreatedTransfer(t *testing.T) {
+	info := "d6:lengthi1e4:name4:teste"
+	sum := sha1.Sum([]byte(info))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	uploads := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/files/upload":
+			uploads++
+			w.WriteHeader(http.StatusBadGateway)
+		case "/transfers/list":
+			w.Write([]byte(`{"transfers": [{"id": 3, "hash": "` + hash + `"}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient("token", WithBaseURLs(server.URL, server.URL), WithHTTPClient(server.Client()))
+	client.sleeper = func(time.Duration) {}
+	transfer, err := client.UploadFile([]byte("d4:info" + info + "e"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if transfer == nil || transfer.ID != 3 {
+		t.Errorf("expected the transfer created by the failed upload, got %+v", transfer)
+	}
+	if uploads != 1 {
+		t.Errorf("expected no re-upload, got %d uploads", uploads)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestWithTransport(t *testing.T) {
+	var seen string
+	rt := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		seen = req.URL.Path
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"info":{"username":"u"}}`)),
+			Request:    req,
+		}, nil
+	})
+	client := NewClient("token", WithBaseURLs("http://putio.invalid", ""), WithTransport(rt))
+
+	if _, err := client.GetAccountInfo(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if seen != "/account/info" {
+		t.Errorf("expected request through transport for /account/info, got %q", seen)
+	}
+}
+
+func TestTimestamps(t *testing.T) {
+	finishedAt := "2024-06-02T08:30:00"
+	transfer := Transfer{FinishedAt: &finishedAt}
+	finished, ok := transfer.Finished()
+	if !ok || !finished.Equal(time.Date(2024, 6, 2, 8, 30, 0, 0, time.UTC)) {
+		t.Errorf("unexpected finished time %v (ok=%v)", finished, ok)
+	}
+
+	invalid := "yesterday"
+	file := FileResponse{CreatedAt: &invalid}
+	if _, ok := file.Created(); ok {
+		t.Error("expected no created time for an unparseable created_at")
+	}
+	file.CreatedAt = &finishedAt
+	if created, ok := file.Created(); !ok || !created.Equal(finished) {
+		t.Errorf("unexpected created time %v (ok=%v)", created, ok)
+	}
+}
+
+func TestRemoveTransfersBatchesIDs(t *testing.T) {
+	var mu sync.Mutex
+	var batches []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/transfers/remove" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		mu.Lock()
+		batches = append(batches, r.FormValue("transfer_ids"))
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	ids := make([]uint64, maxBatchSize+2)
+	for i := range ids {
+		ids[i] = uint64(i + 1)
+	}
+	client := NewClient("token", WithBaseURLs(server.URL, server.URL), WithHTTPClient(server.Client()))
+	if err := client.RemoveTransfers(ids); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sort.Slice(batches, func(i, j int) bool { return len(batches[i]) < len(batches[j]) })
+	if len(batches) != 2 || batches[0] != "101,102" || !strings.HasPrefix(batches[1], "1,2,3,") {
+		t.Errorf("unexpected batches %q", batches)
+	}
+}
+
+func TestDeleteFilesReportsFailedBatches(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/files/delete" || r.FormValue("file_ids") != "7,8" {
+			t.Errorf("unexpected request %s %q", r.URL.Path, r.FormValue("file_ids"))
+		}
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	client := NewClient("token", WithBaseURLs(server.URL, server.URL), WithHTTPClient(server.Client()))
+	var httpErr *HTTPError
+	if err := client.DeleteFiles([]int64{7, 8}); !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected a bad request error, got %v", err)
+	}
+	if err := client.DeleteFiles(nil); err != nil {
+		t.Errorf("expected no request for no files, got %v", err)
+	}
+}
+
+func TestConnectionErrorHints(t *testing.T) {
+	failing := roundTripFunc(func(*http.Request) (*http.Response, error) { return nil, errors.New("connection refused") })
+	client := NewClient("token", WithTransport(failing))
+	client.sleeper = func(time.Duration) {}
+
+	_, err := client.GetAccountInfo()
+	var connErr *ConnectionError
+	if !errors.As(err, &connErr) {
+		t.Fatalf("expected a ConnectionError, got %v", err)
+	}
+	if got := hint.Of(err); !strings.Contains(got, "putio.api_url") {
+		t.Errorf("hint = %q, want one about putio.api_url", got)
+	}
+	timedOut := &ConnectionError{Err: context.DeadlineExceeded}
+	if got := timedOut.Hint(); !strings.Contains(got, "putio.timeout") {
+		t.Errorf("hint for a timeout = %q, want one about putio.timeout", got)
+	}
+}
+
+func TestPostBatchesReportsPanicAsError(t *testing.T) {
+	panicking := roundTripFunc(func(*http.Request) (*http.Response, error) { panic("boom") })
+	client := NewClient("token", WithTransport(panicking))
+
+	err := client.RemoveTransfers([]uint64{1, 2})
+	if err == nil || !strings.Contains(err.Error(), "panicked") {
+		t.Errorf("expected the panic as an error, got %v", err)
+	}
+}
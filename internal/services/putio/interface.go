@@ -1,15 +1,48 @@
 package putio
 
+import (
+	"errors"
+	"fmt"
+)
+
 // ClientAPI defines the methods required to interact with put.io.
 // It mirrors the concrete client so it can be mocked in tests.
 type ClientAPI interface {
 	GetAccountInfo() (*AccountInfoResponse, error)
 	ListTransfers() (*ListTransferResponse, error)
+	ListTransfersSince(minID uint64) (*ListTransferResponse, error)
 	GetTransfer(transferID uint64) (*GetTransferResponse, error)
 	RemoveTransfer(transferID uint64) error
+	RemoveTransfers(transferIDs []uint64) error
+	RetryTransfer(transferID uint64) error
 	DeleteFile(fileID int64) error
-	AddTransfer(url string) error
-	UploadFile(data []byte) error
+	DeleteFiles(fileIDs []int64) error
+	AddTransfer(url string) (*Transfer, error)
+	UploadFile(data []byte) (*Transfer, error)
 	ListFiles(fileID int64) (*ListFileResponse, error)
 	GetFileURL(fileID int64) (string, error)
 }
+
+// RemoveEach removes transfers in one batch. If the batch fails it falls back
+// to removing them one at a time, so one transfer put.io refuses doesn't keep
+// the rest around. It returns the IDs that were removed along with the
+// failures.
+func RemoveEach(client ClientAPI, transferIDs []uint64) ([]uint64, error) {
+	if len(transferIDs) == 0 {
+		return nil, nil
+	}
+	if err := client.RemoveTransfers(transferIDs); err == nil {
+		return transferIDs, nil
+	}
+
+	var removed []uint64
+	var errs []error
+	for _, id := range transferIDs {
+		if err := client.RemoveTransfer(id); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove transfer %d: %w", id, err))
+			continue
+		}
+		removed = append(removed, id)
+	}
+	return removed, errors.Join(errs...)
+}
@@ -0,0 +1,148 @@
+// Package rclone talks to the remote control API of an rclone instance
+// started with --rc, to move files to and delete them from rclone remotes.
+package rclone
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ErrNotFound is returned when the file or directory an operation refers to
+// doesn't exist.
+var ErrNotFound = errors.New("not found")
+
+// Client calls rclone remote control commands.
+type Client struct {
+	baseURL    string
+	username   string
+	password   string
+	httpClient *http.Client
+}
+
+// Option configures the Client.
+type Option func(*Client)
+
+// WithCredentials sets the --rc-user and --rc-pass to authenticate with.
+func WithCredentials(username, password string) Option {
+	return func(c *Client) {
+		c.username = username
+		c.password = password
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to call rclone. Moves take as long
+// as the upload, so the default client has no timeout; commands are bounded by
+// their context instead.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// NewClient creates a client for the rclone remote control API at baseURL,
+// e.g. http://localhost:5572.
+func NewClient(baseURL string, opts ...Option) *Client {
+	c := &Client{baseURL: strings.TrimRight(baseURL, "/"), httpClient: &http.Client{}}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// MoveFile moves the file srcRemote of the srcFs file system to dstRemote of
+// dstFs. File systems are local directories or rclone remotes such as
+// "gdrive:downloads".
+func (c *Client) MoveFile(ctx context.Context, srcFs, srcRemote, dstFs, dstRemote string) error {
+	return c.call(ctx, "operations/movefile", map[string]interface{}{
+		"srcFs":     srcFs,
+		"srcRemote": srcRemote,
+		"dstFs":     dstFs,
+		"dstRemote": dstRemote,
+	}, nil)
+}
+
+// MoveDir moves the contents of srcFs to dstFs, removing the emptied source
+// directories.
+func (c *Client) MoveDir(ctx context.Context, srcFs, dstFs string) error {
+	return c.call(ctx, "sync/move", map[string]interface{}{
+		"srcFs":              srcFs,
+		"dstFs":              dstFs,
+		"deleteEmptySrcDirs": true,
+	}, nil)
+}
+
+// Stat reports whether remote exists in fs and whether it's a directory.
+func (c *Client) Stat(ctx context.Context, fs, remote string) (exists, isDir bool, err error) {
+	var result struct {
+		Item *struct {
+			IsDir bool `json:"IsDir"`
+		} `json:"item"`
+	}
+	if err := c.call(ctx, "operations/stat", map[string]interface{}{"fs": fs, "remote": remote}, &result); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return false, false, nil
+		}
+		return false, false, err
+	}
+	if result.Item == nil {
+		return false, false, nil
+	}
+	return true, result.Item.IsDir, nil
+}
+
+// DeleteFile deletes the file remote of fs.
+func (c *Client) DeleteFile(ctx context.Context, fs, remote string) error {
+	return c.call(ctx, "operations/deletefile", map[string]interface{}{"fs": fs, "remote": remote}, nil)
+}
+
+// Purge deletes the directory remote of fs and everything in it.
+func (c *Client) Purge(ctx context.Context, fs, remote string) error {
+	return c.call(ctx, "operations/purge", map[string]interface{}{"fs": fs, "remote": remote}, nil)
+}
+
+// call runs command with params and decodes its result into out, if not nil.
+func (c *Client) call(ctx context.Context, command string, params map[string]interface{}, out interface{}) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/"+command, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.username != "" || c.password != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("rclone %s failed: %w", command, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var failure struct {
+			Error string `json:"error"`
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		message := resp.Status
+		if json.Unmarshal(data, &failure) == nil && failure.Error != "" {
+			message = failure.Error
+		}
+		if resp.StatusCode == http.StatusNotFound {
+			return fmt.Errorf("rclone %s: %s: %w", command, message, ErrNotFound)
+		}
+		return fmt.Errorf("rclone %s failed: %s", command, message)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
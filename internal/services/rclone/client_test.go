@@ -0,0 +1,128 @@
+package rclone
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type recordedCall struct {
+	command string
+	params  map[string]interface{}
+}
+
+func newTestServer(t *testing.T, handler func(command string, params map[string]interface{}) (int, string)) (*httptest.Server, *[]recordedCall) {
+	t.Helper()
+	var calls []recordedCall
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "rc" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var params map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			t.Errorf("invalid request body: %v", err)
+		}
+		command := strings.TrimPrefix(r.URL.Path, "/")
+		calls = append(calls, recordedCall{command: command, params: params})
+		status, body := handler(command, params)
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func TestMoveFile(t *testing.T) {
+	server, calls := newTestServer(t, func(string, map[string]interface{}) (int, string) {
+		return http.StatusOK, "{}"
+	})
+	client := NewClient(server.URL+"/", WithCredentials("rc", "secret"))
+
+	if err := client.MoveFile(context.Background(), "/downloads", "movie.mkv", "gdrive:downloads", "movie.mkv"); err != nil {
+		t.Fatalf("MoveFile: %v", err)
+	}
+	if len(*calls) != 1 || (*calls)[0].command != "operations/movefile" {
+		t.Fatalf("unexpected calls %+v", *calls)
+	}
+	params := (*calls)[0].params
+	if params["srcFs"] != "/downloads" || params["srcRemote"] != "movie.mkv" || params["dstFs"] != "gdrive:downloads" || params["dstRemote"] != "movie.mkv" {
+		t.Errorf("unexpected params %+v", params)
+	}
+}
+
+func TestMoveDir(t *testing.T) {
+	server, calls := newTestServer(t, func(string, map[string]interface{}) (int, string) {
+		return http.StatusOK, "{}"
+	})
+	client := NewClient(server.URL, WithCredentials("rc", "secret"))
+
+	if err := client.MoveDir(context.Background(), "/downloads/show", "gdrive:downloads/show"); err != nil {
+		t.Fatalf("MoveDir: %v", err)
+	}
+	params := (*calls)[0].params
+	if (*calls)[0].command != "sync/move" || params["deleteEmptySrcDirs"] != true {
+		t.Errorf("unexpected call %+v", (*calls)[0])
+	}
+}
+
+func TestStat(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		body       string
+		wantExists bool
+		wantDir    bool
+		wantErr    bool
+	}{
+		{name: "directory", status: http.StatusOK, body: `{"item": {"Path": "show", "IsDir": true}}`, wantExists: true, wantDir: true},
+		{name: "file", status: http.StatusOK, body: `{"item": {"Path": "movie.mkv", "IsDir": false}}`, wantExists: true},
+		{name: "missing", status: http.StatusOK, body: `{"item": null}`},
+		{name: "missing remote directory", status: http.StatusNotFound, body: `{"error": "directory not found"}`},
+		{name: "failure", status: http.StatusInternalServerError, body: `{"error": "didn't find section in config file"}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := newTestServer(t, func(string, map[string]interface{}) (int, string) {
+				return tt.status, tt.body
+			})
+			client := NewClient(server.URL, WithCredentials("rc", "secret"))
+
+			exists, isDir, err := client.Stat(context.Background(), "gdrive:downloads", "show")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if exists != tt.wantExists || isDir != tt.wantDir {
+				t.Errorf("expected exists=%v dir=%v, got %v %v", tt.wantExists, tt.wantDir, exists, isDir)
+			}
+		})
+	}
+}
+
+func TestCallErrors(t *testing.T) {
+	server, _ := newTestServer(t, func(command string, _ map[string]interface{}) (int, string) {
+		if command == "operations/deletefile" {
+			return http.StatusNotFound, `{"error": "object not found"}`
+		}
+		return http.StatusInternalServerError, `{"error": "quota exceeded"}`
+	})
+	client := NewClient(server.URL, WithCredentials("rc", "secret"))
+
+	err := client.DeleteFile(context.Background(), "gdrive:downloads", "gone.mkv")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	err = client.Purge(context.Background(), "gdrive:downloads", "show")
+	if err == nil || !strings.Contains(err.Error(), "quota exceeded") || errors.Is(err, ErrNotFound) {
+		t.Errorf("expected rclone's error message, got %v", err)
+	}
+
+	unauthenticated := NewClient(server.URL)
+	if err := unauthenticated.Purge(context.Background(), "gdrive:downloads", "show"); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("expected an authentication error, got %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+// Package release looks up goputioarr releases on GitHub.
+package release
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const latestURL = "https://api.github.com/repos/ochronus/goputioarr/releases/latest"
+
+// Release is a published goputioarr release.
+type Release struct {
+	// Version is the tag without the leading "v".
+	Version string
+	URL     string
+	Assets  map[string]string
+}
+
+// Client queries GitHub for releases.
+type Client struct {
+	latestURL  string
+	httpClient *http.Client
+}
+
+// Option configures the Client.
+type Option func(*Client)
+
+// WithLatestURL overrides the GitHub API URL of the latest release.
+func WithLatestURL(url string) Option {
+	return func(c *Client) {
+		c.latestURL = url
+	}
+}
+
+// NewClient creates a release client.
+func NewClient(opts ...Option) *Client {
+	c := &Client{
+		latestURL:  latestURL,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Latest returns the latest release.
+func (c *Client) Latest(ctx context.Context) (*Release, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.latestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query latest release: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status from GitHub: %s", resp.Status)
+	}
+
+	var data struct {
+		TagName string `json:"tag_name"`
+		HTMLURL string `json:"html_url"`
+		Assets  []struct {
+			Name               string `json:"name"`
+			BrowserDownloadURL string `json:"browser_download_url"`
+		} `json:"assets"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, fmt.Errorf("failed to decode GitHub response: %w", err)
+	}
+
+	release := &Release{
+		Version: strings.TrimPrefix(data.TagName, "v"),
+		URL:     data.HTMLURL,
+		Assets:  make(map[string]string, len(data.Assets)),
+	}
+	for _, asset := range data.Assets {
+		release.Assets[asset.Name] = asset.BrowserDownloadURL
+	}
+	return release, nil
+}
+
+// IsNewer reports whether version latest is newer than current. Versions are
+// compared by their dotted numbers, with or without a leading "v"; anything
+// else, such as the "dev" of local builds, is never older.
+func IsNewer(latest, current string) bool {
+	l, ok := parse(latest)
+	if !ok {
+		return false
+	}
+	c, ok := parse(current)
+	if !ok {
+		return false
+	}
+	for i := 0; i < max(len(l), len(c)); i++ {
+		var a, b int
+		if i < len(l) {
+			a = l[i]
+		}
+		if i < len(c) {
+			b = c[i]
+		}
+		if a != b {
+			return a > b
+		}
+	}
+	return false
+}
+
+// parse splits a version such as "v1.2.3" into its numbers. A pre-release or
+// build suffix ("-rc1", "+abc") is ignored.
+func parse(version string) ([]int, bool) {
+	version = strings.TrimPrefix(version, "v")
+	if i := strings.IndexAny(version, "-+"); i >= 0 {
+		version = version[:i]
+	}
+	if version == "" {
+		return nil, false
+	}
+	var numbers []int
+	for _, part := range strings.Split(version, ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, false
+		}
+		numbers = append(numbers, n)
+	}
+	return numbers, true
+}
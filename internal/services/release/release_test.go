@@ -0,0 +1,61 @@
+package release
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLatest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"tag_name":"v1.2.3","html_url":"https://github.com/ochronus/goputioarr/releases/tag/v1.2.3",
+			"assets":[{"name":"goputioarr-linux-amd64","browser_download_url":"https://example.com/linux"}]}`))
+	}))
+	defer server.Close()
+
+	latest, err := NewClient(WithLatestURL(server.URL)).Latest(context.Background())
+	if err != nil {
+		t.Fatalf("Latest: %v", err)
+	}
+	if latest.Version != "1.2.3" || latest.URL != "https://github.com/ochronus/goputioarr/releases/tag/v1.2.3" {
+		t.Errorf("unexpected release %+v", latest)
+	}
+	if latest.Assets["goputioarr-linux-amd64"] != "https://example.com/linux" {
+		t.Errorf("unexpected assets %v", latest.Assets)
+	}
+}
+
+func TestLatestError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "rate limited", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	if _, err := NewClient(WithLatestURL(server.URL)).Latest(context.Background()); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestIsNewer(t *testing.T) {
+	tests := []struct {
+		latest, current string
+		expected        bool
+	}{
+		{"0.5.42", "0.5.41", true},
+		{"v0.6.0", "0.5.41", true},
+		{"0.5.41", "v0.5.41", false},
+		{"0.5.40", "0.5.41", false},
+		{"1.0", "0.9.9", true},
+		{"1.0.1", "1.0", true},
+		{"1.0.0", "1.0", false},
+		{"0.5.41", "0.5.41-rc1", false},
+		{"0.5.41", "dev", false},
+		{"main", "0.5.41", false},
+	}
+	for _, tt := range tests {
+		if got := IsNewer(tt.latest, tt.current); got != tt.expected {
+			t.Errorf("IsNewer(%q, %q) = %v, expected %v", tt.latest, tt.current, got, tt.expected)
+		}
+	}
+}
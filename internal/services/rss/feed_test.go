@@ -0,0 +1,101 @@
+package rss
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const torznabFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:torznab="http://torznab.com/schemas/2015/feed">
+<channel>
+  <title>Prowlarr</title>
+  <item>
+    <title>Some Show S01E01 1080p</title>
+    <guid>https://indexer/details/1</guid>
+    <link>https://indexer/download/1.torrent</link>
+    <enclosure url="https://indexer/download/1.torrent" length="100" type="application/x-bittorrent"/>
+    <torznab:attr name="infohash" value="C12FE1C06BBA254A9DC9F519B335AA7C1367A88A"/>
+  </item>
+  <item>
+    <title>Other Show S02E03</title>
+    <link>https://indexer/download/2.torrent</link>
+    <torznab:attr name="magneturl" value="magnet:?xt=urn:btih:0123456789abcdef0123456789abcdef01234567&amp;dn=Other"/>
+  </item>
+  <item>
+    <title>No link</title>
+  </item>
+</channel>
+</rss>`
+
+const atomFeed = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <entry>
+    <title>Some Movie 2024</title>
+    <id>tag:example,2024:1</id>
+    <link rel="alternate" href="https://example/movie"/>
+    <link rel="enclosure" href="https://example/movie.torrent"/>
+  </entry>
+</feed>`
+
+func TestParseRSS(t *testing.T) {
+	items, err := Parse(strings.NewReader(torznabFeed))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected 2 items with links, got %+v", items)
+	}
+
+	first := items[0]
+	if first.Title != "Some Show S01E01 1080p" || first.GUID != "https://indexer/details/1" ||
+		first.Link != "https://indexer/download/1.torrent" || first.InfoHash != "c12fe1c06bba254a9dc9f519b335aa7c1367a88a" {
+		t.Errorf("unexpected first item %+v", first)
+	}
+
+	second := items[1]
+	if !strings.HasPrefix(second.Link, "magnet:") || second.GUID != second.Link {
+		t.Errorf("expected the magnet link to be preferred and used as GUID, got %+v", second)
+	}
+	if second.InfoHash != "0123456789abcdef0123456789abcdef01234567" {
+		t.Errorf("expected the info hash from the magnet link, got %q", second.InfoHash)
+	}
+}
+
+func TestParseAtom(t *testing.T) {
+	items, err := Parse(strings.NewReader(atomFeed))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(items) != 1 || items[0].Link != "https://example/movie.torrent" || items[0].GUID != "tag:example,2024:1" {
+		t.Errorf("unexpected items %+v", items)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	if _, err := Parse(strings.NewReader("<rss><channel>")); err == nil {
+		t.Error("expected an error for a truncated feed")
+	}
+}
+
+func TestFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/feed" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(atomFeed))
+	}))
+	defer server.Close()
+
+	client := NewClient(WithHTTPClient(server.Client()))
+	items, err := client.Fetch(context.Background(), server.URL+"/feed")
+	if err != nil || len(items) != 1 {
+		t.Fatalf("expected 1 item, got %+v (%v)", items, err)
+	}
+	if _, err := client.Fetch(context.Background(), server.URL+"/missing"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("expected a status error, got %v", err)
+	}
+}
@@ -0,0 +1,125 @@
+package torrent
+
+import (
+	"encoding/base32"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	btihPrefix = "urn:btih:"
+	// btmhPrefix introduces a BitTorrent v2 info hash, a multihash.
+	btmhPrefix = "urn:btmh:"
+	// sha256Multihash prefixes a 32-byte SHA-256 digest in a multihash.
+	sha256Multihash = "1220"
+)
+
+// Magnet holds the fields of a magnet link the proxy cares about.
+type Magnet struct {
+	InfoHash string // lowercase hex, empty if the link has no btih or btmh
+	Name     string
+}
+
+// ParseMagnet extracts the info hash and display name from a magnet link.
+// The exact topic may be given as xt or as numbered xt.1, xt.2 parameters.
+// Base32 info hashes are converted to hex. Links with only a BitTorrent v2
+// hash (btmh) get its first 20 bytes, which is how v2 torrents are
+// identified to v1 clients and trackers.
+func ParseMagnet(link string) (Magnet, error) {
+	if !strings.HasPrefix(link, "magnet:") {
+		return Magnet{}, fmt.Errorf("not a magnet link")
+	}
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return Magnet{}, fmt.Errorf("invalid magnet link: %w", err)
+	}
+	query := parsed.Query()
+
+	var m Magnet
+	m.Name = query.Get("dn")
+
+	// Invalid exact topics are skipped in favour of later ones; the link is
+	// only rejected if none of them is usable.
+	var v2Hash string
+	var invalid error
+	for _, xt := range exactTopics(query) {
+		lower := strings.ToLower(xt)
+		switch {
+		case strings.HasPrefix(lower, btihPrefix):
+			hash, err := normalizeInfoHash(xt[len(btihPrefix):])
+			if err != nil {
+				invalid = err
+				continue
+			}
+			m.InfoHash = hash
+			return m, nil
+		case strings.HasPrefix(lower, btmhPrefix) && v2Hash == "":
+			hash, err := truncateV2Hash(lower[len(btmhPrefix):])
+			if err != nil {
+				invalid = err
+				continue
+			}
+			v2Hash = hash
+		}
+	}
+	if v2Hash == "" && invalid != nil {
+		return Magnet{}, invalid
+	}
+	m.InfoHash = v2Hash
+	return m, nil
+}
+
+// exactTopics returns the xt parameters of a magnet link, followed by the
+// numbered xt.1, xt.2, ... ones in order.
+func exactTopics(query url.Values) []string {
+	topics := append([]string{}, query["xt"]...)
+	var numbered []string
+	for key := range query {
+		if strings.HasPrefix(key, "xt.") {
+			numbered = append(numbered, key)
+		}
+	}
+	sort.Slice(numbered, func(i, j int) bool {
+		a, _ := strconv.Atoi(strings.TrimPrefix(numbered[i], "xt."))
+		b, _ := strconv.Atoi(strings.TrimPrefix(numbered[j], "xt."))
+		return a < b
+	})
+	for _, key := range numbered {
+		topics = append(topics, query[key]...)
+	}
+	return topics
+}
+
+// truncateV2Hash returns the first 20 bytes of a SHA-256 multihash as hex.
+func truncateV2Hash(multihash string) (string, error) {
+	digest := strings.TrimPrefix(multihash, sha256Multihash)
+	if len(digest) != 64 || len(multihash) != len(digest)+len(sha256Multihash) {
+		return "", fmt.Errorf("invalid v2 info hash %q", multihash)
+	}
+	if _, err := hex.DecodeString(digest); err != nil {
+		return "", fmt.Errorf("invalid v2 info hash %q", multihash)
+	}
+	return digest[:40], nil
+}
+
+// normalizeInfoHash converts a hex or base32 info hash to lowercase hex.
+func normalizeInfoHash(hash string) (string, error) {
+	switch len(hash) {
+	case 40:
+		if _, err := hex.DecodeString(hash); err != nil {
+			return "", fmt.Errorf("invalid info hash %q", hash)
+		}
+		return strings.ToLower(hash), nil
+	case 32:
+		decoded, err := base32.StdEncoding.DecodeString(strings.ToUpper(hash))
+		if err != nil {
+			return "", fmt.Errorf("invalid info hash %q", hash)
+		}
+		return hex.EncodeToString(decoded), nil
+	}
+	return "", fmt.Errorf("invalid info hash %q", hash)
+}
@@ -0,0 +1,85 @@
+package torrent
+
+import "testing"
+
+func TestParseMagnet(t *testing.T) {
+	tests := []struct {
+		name         string
+		link         string
+		expectedHash string
+		expectedName string
+		wantErr      bool
+	}{
+		{
+			name:         "hex hash with name",
+			link:         "magnet:?xt=urn:btih:C12FE1C06BBA254A9DC9F519B335AA7C1367A88A&dn=Some%20Show%20S01E01",
+			expectedHash: "c12fe1c06bba254a9dc9f519b335aa7c1367a88a",
+			expectedName: "Some Show S01E01",
+		},
+		{
+			name:         "base32 hash",
+			link:         "magnet:?xt=urn:btih:YEX6DQDLXISUVHOJ6UM3GNNKPQJWPKEK",
+			expectedHash: "c12fe1c06bba254a9dc9f519b335aa7c1367a88a",
+		},
+		{
+			name:         "no btih",
+			link:         "magnet:?dn=name",
+			expectedName: "name",
+		},
+		{
+			name:         "uppercase urn and several trackers",
+			link:         "magnet:?XT=urn:btih:C12FE1C06BBA254A9DC9F519B335AA7C1367A88A&xt=URN:BTIH:C12FE1C06BBA254A9DC9F519B335AA7C1367A88A&tr=udp%3A%2F%2Fa%3A80&tr=udp%3A%2F%2Fb%3A80",
+			expectedHash: "c12fe1c06bba254a9dc9f519b335aa7c1367a88a",
+		},
+		{
+			name:         "numbered exact topics",
+			link:         "magnet:?xt.2=urn:ed2k:31D6CFE0D16AE931B73C59D7E0C089C0&xt.1=urn:btih:C12FE1C06BBA254A9DC9F519B335AA7C1367A88A&dn=Show",
+			expectedHash: "c12fe1c06bba254a9dc9f519b335aa7c1367a88a",
+			expectedName: "Show",
+		},
+		{
+			name:         "hybrid prefers btih",
+			link:         "magnet:?xt=urn:btmh:1220d2474e86c95b19b8bcfdb92bc12c9d44667cfa36d2474e86c95b19b8bcfdb92b&xt=urn:btih:C12FE1C06BBA254A9DC9F519B335AA7C1367A88A",
+			expectedHash: "c12fe1c06bba254a9dc9f519b335aa7c1367a88a",
+		},
+		{
+			name:         "v2 only",
+			link:         "magnet:?xt=urn:btmh:1220D2474E86C95B19B8BCFDB92BC12C9D44667CFA36D2474E86C95B19B8BCFDB92B",
+			expectedHash: "d2474e86c95b19b8bcfdb92bc12c9d44667cfa36",
+		},
+		{
+			name:         "invalid topic before a valid one",
+			link:         "magnet:?xt.1=urn:btih:nothex&xt.2=urn:btih:C12FE1C06BBA254A9DC9F519B335AA7C1367A88A",
+			expectedHash: "c12fe1c06bba254a9dc9f519b335aa7c1367a88a",
+		},
+		{
+			name:         "invalid btih next to a v2 hash",
+			link:         "magnet:?xt=urn:btih:nothex&xt=urn:btmh:1220D2474E86C95B19B8BCFDB92BC12C9D44667CFA36D2474E86C95B19B8BCFDB92B",
+			expectedHash: "d2474e86c95b19b8bcfdb92bc12c9d44667cfa36",
+		},
+		{name: "invalid v2 hash", link: "magnet:?xt=urn:btmh:1114abcd", wantErr: true},
+		{name: "invalid hash", link: "magnet:?xt=urn:btih:nothex", wantErr: true},
+		{name: "not a magnet", link: "http://example.com/file.torrent", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := ParseMagnet(tt.link)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if m.InfoHash != tt.expectedHash {
+				t.Errorf("expected hash %q, got %q", tt.expectedHash, m.InfoHash)
+			}
+			if m.Name != tt.expectedName {
+				t.Errorf("expected name %q, got %q", tt.expectedName, m.Name)
+			}
+		})
+	}
+}
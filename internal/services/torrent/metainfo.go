@@ -0,0 +1,173 @@
+package torrent
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// maxDepth bounds nesting so crafted input can't exhaust the stack.
+const maxDepth = 64
+
+var errUnexpectedEnd = errors.New("unexpected end of data")
+
+// Metainfo holds the fields of a .torrent file the proxy cares about.
+type Metainfo struct {
+	InfoHash  string // lowercase hex SHA-1 of the bencoded info dictionary
+	Name      string
+	TotalSize int64
+}
+
+// ParseMetainfo decodes a bencoded .torrent file and computes its info hash.
+func ParseMetainfo(data []byte) (Metainfo, error) {
+	d := &decoder{data: data, infoStart: -1}
+	if d.peek() != 'd' {
+		return Metainfo{}, fmt.Errorf("invalid torrent: expected dictionary")
+	}
+	root, err := d.value(0)
+	if err != nil {
+		return Metainfo{}, fmt.Errorf("invalid torrent: %w", err)
+	}
+	if d.pos != len(data) {
+		return Metainfo{}, fmt.Errorf("invalid torrent: trailing data")
+	}
+	if d.infoStart < 0 {
+		return Metainfo{}, fmt.Errorf("invalid torrent: missing info dictionary")
+	}
+
+	info, ok := root.(map[string]interface{})["info"].(map[string]interface{})
+	if !ok {
+		return Metainfo{}, fmt.Errorf("invalid torrent: info is not a dictionary")
+	}
+
+	sum := sha1.Sum(data[d.infoStart:d.infoEnd])
+	m := Metainfo{InfoHash: hex.EncodeToString(sum[:])}
+	if name, ok := info["name"].(string); ok {
+		m.Name = name
+	}
+	if length, ok := info["length"].(int64); ok {
+		m.TotalSize = length
+	} else if files, ok := info["files"].([]interface{}); ok {
+		for _, f := range files {
+			if file, ok := f.(map[string]interface{}); ok {
+				if length, ok := file["length"].(int64); ok {
+					m.TotalSize += length
+				}
+			}
+		}
+	}
+	return m, nil
+}
+
+// decoder is a minimal bencode decoder. It records the byte range of the
+// top-level "info" value so the info hash can be computed over the original
+// encoding.
+type decoder struct {
+	data      []byte
+	pos       int
+	infoStart int
+	infoEnd   int
+}
+
+func (d *decoder) peek() byte {
+	if d.pos >= len(d.data) {
+		return 0
+	}
+	return d.data[d.pos]
+}
+
+func (d *decoder) value(depth int) (interface{}, error) {
+	if depth > maxDepth {
+		return nil, errors.New("nesting too deep")
+	}
+	switch c := d.peek(); {
+	case c == 'i':
+		return d.integer()
+	case c == 'l':
+		return d.list(depth)
+	case c == 'd':
+		return d.dict(depth)
+	case c >= '0' && c <= '9':
+		return d.str()
+	case c == 0:
+		return nil, errUnexpectedEnd
+	default:
+		return nil, fmt.Errorf("unexpected %q at offset %d", c, d.pos)
+	}
+}
+
+func (d *decoder) integer() (int64, error) {
+	d.pos++ // 'i'
+	end := bytes.IndexByte(d.data[d.pos:], 'e')
+	if end < 0 {
+		return 0, errUnexpectedEnd
+	}
+	n, err := strconv.ParseInt(string(d.data[d.pos:d.pos+end]), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid integer at offset %d", d.pos)
+	}
+	d.pos += end + 1
+	return n, nil
+}
+
+func (d *decoder) str() (string, error) {
+	colon := bytes.IndexByte(d.data[d.pos:], ':')
+	if colon < 0 {
+		return "", errUnexpectedEnd
+	}
+	length, err := strconv.Atoi(string(d.data[d.pos : d.pos+colon]))
+	if err != nil || length < 0 {
+		return "", fmt.Errorf("invalid string length at offset %d", d.pos)
+	}
+	start := d.pos + colon + 1
+	if length > len(d.data)-start {
+		return "", errUnexpectedEnd
+	}
+	d.pos = start + length
+	return string(d.data[start:d.pos]), nil
+}
+
+func (d *decoder) list(depth int) ([]interface{}, error) {
+	d.pos++ // 'l'
+	var items []interface{}
+	for d.peek() != 'e' {
+		item, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	d.pos++
+	return items, nil
+}
+
+func (d *decoder) dict(depth int) (map[string]interface{}, error) {
+	d.pos++ // 'd'
+	dict := make(map[string]interface{})
+	for d.peek() != 'e' {
+		if c := d.peek(); c < '0' || c > '9' {
+			if c == 0 {
+				return nil, errUnexpectedEnd
+			}
+			return nil, fmt.Errorf("dictionary key must be a string at offset %d", d.pos)
+		}
+		key, err := d.str()
+		if err != nil {
+			return nil, err
+		}
+		start := d.pos
+		val, err := d.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if depth == 0 && key == "info" {
+			d.infoStart, d.infoEnd = start, d.pos
+		}
+		dict[key] = val
+	}
+	d.pos++
+	return dict, nil
+}
@@ -0,0 +1,81 @@
+package torrent
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"testing"
+)
+
+func TestParseMetainfo(t *testing.T) {
+	singleInfo := "d6:lengthi1024e4:name8:file.mkv12:piece lengthi16384e6:pieces0:e"
+	multiInfo := "d5:filesld6:lengthi100e4:pathl5:a.mkveed6:lengthi50e4:pathl5:b.srteee4:name4:Show12:piece lengthi16384e6:pieces0:e"
+
+	hashOf := func(info string) string {
+		sum := sha1.Sum([]byte(info))
+		return hex.EncodeToString(sum[:])
+	}
+
+	tests := []struct {
+		name         string
+		data         string
+		expectedHash string
+		expectedName string
+		expectedSize int64
+		wantErr      bool
+	}{
+		{
+			name:         "single file",
+			data:         "d8:announce15:http://tracker/4:info" + singleInfo + "e",
+			expectedHash: hashOf(singleInfo),
+			expectedName: "file.mkv",
+			expectedSize: 1024,
+		},
+		{
+			name:         "multi file",
+			data:         "d4:info" + multiInfo + "8:url-listl0:ee",
+			expectedHash: hashOf(multiInfo),
+			expectedName: "Show",
+			expectedSize: 150,
+		},
+		{name: "missing info", data: "d8:announce3:fooe", wantErr: true},
+		{name: "not a dictionary", data: "l4:infoe", wantErr: true},
+		{name: "truncated", data: "d4:infod4:name", wantErr: true},
+		{name: "string length overflow", data: "d4:info99:abce", wantErr: true},
+		{name: "trailing data", data: "d4:info" + singleInfo + "ejunk", wantErr: true},
+		{name: "empty", data: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := ParseMetainfo([]byte(tt.data))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", m)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if m.InfoHash != tt.expectedHash {
+				t.Errorf("expected hash %s, got %s", tt.expectedHash, m.InfoHash)
+			}
+			if m.Name != tt.expectedName {
+				t.Errorf("expected name %q, got %q", tt.expectedName, m.Name)
+			}
+			if m.TotalSize != tt.expectedSize {
+				t.Errorf("expected size %d, got %d", tt.expectedSize, m.TotalSize)
+			}
+		})
+	}
+}
+
+func TestParseMetainfoDepthLimit(t *testing.T) {
+	data := "d4:info"
+	for i := 0; i < 1000; i++ {
+		data += "l"
+	}
+	if _, err := ParseMetainfo([]byte(data)); err == nil {
+		t.Fatal("expected error for deeply nested input")
+	}
+}
@@ -2,6 +2,9 @@ package transmission
 
 import (
 	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/ochronus/goputioarr/internal/services/putio"
@@ -11,35 +14,95 @@ import (
 type Response struct {
 	Result    string      `json:"result"`
 	Arguments interface{} `json:"arguments,omitempty"`
+	Tag       *int64      `json:"tag,omitempty"`
 }
 
 // Request represents a Transmission RPC request
 type Request struct {
 	Method    string          `json:"method"`
 	Arguments json.RawMessage `json:"arguments,omitempty"`
+	Tag       *int64          `json:"tag,omitempty"`
 }
 
 // Config represents Transmission session configuration
 type Config struct {
-	RPCVersion              string  `json:"rpc-version"`
+	RPCVersion              int64   `json:"rpc-version"`
+	RPCVersionMinimum       int64   `json:"rpc-version-minimum"`
 	Version                 string  `json:"version"`
 	DownloadDir             string  `json:"download-dir"`
 	SeedRatioLimit          float32 `json:"seedRatioLimit"`
 	SeedRatioLimited        bool    `json:"seedRatioLimited"`
 	IdleSeedingLimit        uint64  `json:"idle-seeding-limit"`
 	IdleSeedingLimitEnabled bool    `json:"idle-seeding-limit-enabled"`
+	SpeedLimitDown          uint64  `json:"speed-limit-down"`
+	SpeedLimitDownEnabled   bool    `json:"speed-limit-down-enabled"`
+	SpeedLimitUp            uint64  `json:"speed-limit-up"`
+	SpeedLimitUpEnabled     bool    `json:"speed-limit-up-enabled"`
+	AltSpeedEnabled         bool    `json:"alt-speed-enabled"`
 }
 
 // DefaultConfig returns a Config with default values
 func DefaultConfig(downloadDir string) *Config {
 	return &Config{
-		RPCVersion:              "18",
+		RPCVersion:              18,
+		RPCVersionMinimum:       14,
 		Version:                 "14.0.0",
 		DownloadDir:             downloadDir,
 		SeedRatioLimit:          1.0,
 		SeedRatioLimited:        true,
 		IdleSeedingLimit:        100,
 		IdleSeedingLimitEnabled: false,
+		SpeedLimitDown:          100,
+		SpeedLimitUp:            100,
+	}
+}
+
+// SessionSetArguments represents arguments for session-set method. Fields
+// left out of the request are nil and keep their value.
+type SessionSetArguments struct {
+	DownloadDir             *string  `json:"download-dir"`
+	SeedRatioLimit          *float32 `json:"seedRatioLimit"`
+	SeedRatioLimited        *bool    `json:"seedRatioLimited"`
+	IdleSeedingLimit        *uint64  `json:"idle-seeding-limit"`
+	IdleSeedingLimitEnabled *bool    `json:"idle-seeding-limit-enabled"`
+	SpeedLimitDown          *uint64  `json:"speed-limit-down"`
+	SpeedLimitDownEnabled   *bool    `json:"speed-limit-down-enabled"`
+	SpeedLimitUp            *uint64  `json:"speed-limit-up"`
+	SpeedLimitUpEnabled     *bool    `json:"speed-limit-up-enabled"`
+	AltSpeedEnabled         *bool    `json:"alt-speed-enabled"`
+}
+
+// Apply updates c with the fields set in args.
+func (c *Config) Apply(args *SessionSetArguments) {
+	if args.DownloadDir != nil {
+		c.DownloadDir = *args.DownloadDir
+	}
+	if args.SeedRatioLimit != nil {
+		c.SeedRatioLimit = *args.SeedRatioLimit
+	}
+	if args.SeedRatioLimited != nil {
+		c.SeedRatioLimited = *args.SeedRatioLimited
+	}
+	if args.IdleSeedingLimit != nil {
+		c.IdleSeedingLimit = *args.IdleSeedingLimit
+	}
+	if args.IdleSeedingLimitEnabled != nil {
+		c.IdleSeedingLimitEnabled = *args.IdleSeedingLimitEnabled
+	}
+	if args.SpeedLimitDown != nil {
+		c.SpeedLimitDown = *args.SpeedLimitDown
+	}
+	if args.SpeedLimitDownEnabled != nil {
+		c.SpeedLimitDownEnabled = *args.SpeedLimitDownEnabled
+	}
+	if args.SpeedLimitUp != nil {
+		c.SpeedLimitUp = *args.SpeedLimitUp
+	}
+	if args.SpeedLimitUpEnabled != nil {
+		c.SpeedLimitUpEnabled = *args.SpeedLimitUpEnabled
+	}
+	if args.AltSpeedEnabled != nil {
+		c.AltSpeedEnabled = *args.AltSpeedEnabled
 	}
 }
 
@@ -57,11 +120,20 @@ type Torrent struct {
 	SecondsDownloading int64         `json:"secondsDownloading"`
 	ErrorString        *string       `json:"errorString"`
 	DownloadedEver     int64         `json:"downloadedEver"`
+	UploadedEver       int64         `json:"uploadedEver"`
+	UploadRatio        float64       `json:"uploadRatio"`
+	SecondsSeeding     int64         `json:"secondsSeeding"`
+	PeersConnected     int64         `json:"peersConnected"`
+	PeersGettingFromUs int64         `json:"peersGettingFromUs"`
+	PeersSendingToUs   int64         `json:"peersSendingToUs"`
+	RateDownload       int64         `json:"rateDownload"`
+	RateUpload         int64         `json:"rateUpload"`
 	SeedRatioLimit     float32       `json:"seedRatioLimit"`
 	SeedRatioMode      uint32        `json:"seedRatioMode"`
 	SeedIdleLimit      uint64        `json:"seedIdleLimit"`
 	SeedIdleMode       uint32        `json:"seedIdleMode"`
 	FileCount          uint32        `json:"fileCount"`
+	Labels             []string      `json:"labels,omitempty"`
 }
 
 // TorrentStatus represents the status of a torrent
@@ -77,28 +149,40 @@ const (
 	StatusSeeding     TorrentStatus = 6
 )
 
-// StatusFromString converts a put.io status string to a TorrentStatus
-func StatusFromString(status string) TorrentStatus {
-	switch status {
+// ParseStatus converts a put.io transfer status to a TorrentStatus. ok is
+// false for statuses it doesn't know, which are reported as StatusCheckWait.
+//
+// put.io fetches a magnet's metadata while PREPARING_DOWNLOAD and moves the
+// files into place while COMPLETING; neither is done yet, so both are reported
+// as downloading, like Transmission does for the same stages. WAITING
+// transfers are waiting for a slot on put.io, i.e. queued.
+func ParseStatus(status string) (TorrentStatus, bool) {
+	switch strings.ToUpper(strings.TrimSpace(status)) {
 	case "STOPPED", "COMPLETED", "ERROR":
-		return StatusStopped
-	case "CHECKWAIT", "PREPARING_DOWNLOAD":
-		return StatusCheckWait
-	case "CHECK", "COMPLETING":
-		return StatusCheck
-	case "QUEUED", "IN_QUEUE":
-		return StatusQueued
-	case "DOWNLOADING":
-		return StatusDownloading
-	case "SEEDINGWAIT":
-		return StatusSeedingWait
+		return StatusStopped, true
+	case "CHECKWAIT":
+		return StatusCheckWait, true
+	case "CHECK":
+		return StatusCheck, true
+	case "QUEUED", "IN_QUEUE", "WAITING", "WAITING_FOR_COMPLETE_QUEUE":
+		return StatusQueued, true
+	case "PREPARING_DOWNLOAD", "DOWNLOADING", "COMPLETING":
+		return StatusDownloading, true
+	case "SEEDINGWAIT", "SEEDING_WAIT":
+		return StatusSeedingWait, true
 	case "SEEDING":
-		return StatusSeeding
+		return StatusSeeding, true
 	default:
-		return StatusCheckWait
+		return StatusCheckWait, false
 	}
 }
 
+// StatusFromString converts a put.io status string to a TorrentStatus
+func StatusFromString(status string) TorrentStatus {
+	parsed, _ := ParseStatus(status)
+	return parsed
+}
+
 // TorrentFromPutIOTransfer converts a put.io Transfer to a Transmission Torrent
 func TorrentFromPutIOTransfer(t *putio.Transfer, downloadDir string) *Torrent {
 	var startedAt time.Time
@@ -140,6 +224,14 @@ func TorrentFromPutIOTransfer(t *putio.Transfer, downloadDir string) *Torrent {
 		eta = *t.EstimatedTime
 	}
 
+	var uploaded, secondsSeeding int64
+	if t.Uploaded != nil {
+		uploaded = *t.Uploaded
+	}
+	if t.SecondsSeeding != nil {
+		secondsSeeding = *t.SecondsSeeding
+	}
+
 	return &Torrent{
 		ID:                 t.ID,
 		HashString:         t.Hash,
@@ -153,6 +245,14 @@ func TorrentFromPutIOTransfer(t *putio.Transfer, downloadDir string) *Torrent {
 		SecondsDownloading: secondsDownloading,
 		ErrorString:        t.ErrorMessage,
 		DownloadedEver:     downloaded,
+		UploadedEver:       uploaded,
+		UploadRatio:        uploadRatio(t, uploaded, downloaded),
+		SecondsSeeding:     secondsSeeding,
+		PeersConnected:     t.PeersConnected,
+		PeersGettingFromUs: t.PeersGettingFromUs,
+		PeersSendingToUs:   t.PeersSendingToUs,
+		RateDownload:       t.DownSpeed,
+		RateUpload:         t.UpSpeed,
 		SeedRatioLimit:     0.0,
 		SeedRatioMode:      0,
 		SeedIdleLimit:      0,
@@ -161,19 +261,126 @@ func TorrentFromPutIOTransfer(t *putio.Transfer, downloadDir string) *Torrent {
 	}
 }
 
+// uploadRatio returns the ratio put.io reports for the transfer, or works it
+// out from the bytes uploaded and downloaded.
+func uploadRatio(t *putio.Transfer, uploaded, downloaded int64) float64 {
+	if t.CurrentRatio != nil {
+		return *t.CurrentRatio
+	}
+	if downloaded <= 0 {
+		return 0
+	}
+	return float64(uploaded) / float64(downloaded)
+}
+
 // TorrentAddArguments represents arguments for torrent-add method
 type TorrentAddArguments struct {
-	Metainfo string `json:"metainfo,omitempty"`
-	Filename string `json:"filename,omitempty"`
+	Metainfo string   `json:"metainfo,omitempty"`
+	Filename string   `json:"filename,omitempty"`
+	Paused   bool     `json:"paused,omitempty"`
+	Labels   []string `json:"labels,omitempty"`
+}
+
+// TorrentAdded identifies the torrent created (or already present) for torrent-add
+type TorrentAdded struct {
+	ID         uint64 `json:"id"`
+	Name       string `json:"name"`
+	HashString string `json:"hashString"`
+}
+
+// TorrentAddResponse represents the response for torrent-add method
+type TorrentAddResponse struct {
+	TorrentAdded     *TorrentAdded `json:"torrent-added,omitempty"`
+	TorrentDuplicate *TorrentAdded `json:"torrent-duplicate,omitempty"`
+}
+
+// TorrentIDs identifies torrents in a request. Transmission accepts a single
+// numeric ID, a hash string, or a list mixing both; numeric IDs are kept in
+// their decimal form. The special value "recently-active" is kept as is.
+type TorrentIDs []string
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (ids *TorrentIDs) UnmarshalJSON(data []byte) error {
+	var list []json.RawMessage
+	if err := json.Unmarshal(data, &list); err != nil {
+		list = []json.RawMessage{data}
+	}
+
+	parsed := make(TorrentIDs, 0, len(list))
+	for _, raw := range list {
+		var hash string
+		if err := json.Unmarshal(raw, &hash); err == nil {
+			parsed = append(parsed, hash)
+			continue
+		}
+		var id uint64
+		if err := json.Unmarshal(raw, &id); err != nil {
+			return fmt.Errorf("invalid torrent id %s", raw)
+		}
+		parsed = append(parsed, strconv.FormatUint(id, 10))
+	}
+	*ids = parsed
+	return nil
+}
+
+// All reports whether ids selects every torrent, i.e. it is empty or
+// "recently-active".
+func (ids TorrentIDs) All() bool {
+	return len(ids) == 0 || ids.RecentlyActive()
+}
+
+// RecentlyActive reports whether ids is "recently-active", with which
+// torrent-get only returns the torrents that changed lately along with the IDs
+// of the removed ones.
+func (ids TorrentIDs) RecentlyActive() bool {
+	return len(ids) == 1 && ids[0] == "recently-active"
+}
+
+// Matches reports whether ids selects the torrent with the given numeric ID or
+// info hash. Hashes are compared case-insensitively, and numeric IDs sent as
+// strings match their transfer too.
+func (ids TorrentIDs) Matches(id uint64, hash string) bool {
+	hash = strings.TrimSpace(hash)
+	for _, candidate := range ids {
+		candidate = strings.TrimSpace(candidate)
+		if n, err := strconv.ParseUint(candidate, 10, 64); err == nil && n == id {
+			return true
+		}
+		if hash != "" && strings.EqualFold(candidate, hash) {
+			return true
+		}
+	}
+	return false
+}
+
+// TorrentGetArguments represents arguments for torrent-get method
+type TorrentGetArguments struct {
+	Fields []string   `json:"fields"`
+	IDs    TorrentIDs `json:"ids"`
+}
+
+// TorrentActionArguments represents arguments for torrent-start and similar methods
+type TorrentActionArguments struct {
+	IDs TorrentIDs `json:"ids"`
 }
 
 // TorrentRemoveArguments represents arguments for torrent-remove method
 type TorrentRemoveArguments struct {
-	IDs             []string `json:"ids"`
-	DeleteLocalData bool     `json:"delete-local-data"`
+	IDs             TorrentIDs `json:"ids"`
+	DeleteLocalData bool       `json:"delete-local-data"`
+}
+
+// TorrentSetLocationArguments represents arguments for torrent-set-location method
+type TorrentSetLocationArguments struct {
+	IDs      TorrentIDs `json:"ids"`
+	Location string     `json:"location"`
+	Move     bool       `json:"move"`
 }
 
 // TorrentGetResponse represents the response for torrent-get method
 type TorrentGetResponse struct {
 	Torrents []*Torrent `json:"torrents"`
+	// Removed holds the IDs of the torrents removed lately, for ids
+	// "recently-active".
+	Removed []uint64 `json:"removed,omitempty"`
 }
@@ -10,8 +10,8 @@ import (
 func TestDefaultConfig(t *testing.T) {
 	cfg := DefaultConfig("/downloads")
 
-	if cfg.RPCVersion != "18" {
-		t.Errorf("expected RPCVersion '18', got '%s'", cfg.RPCVersion)
+	if cfg.RPCVersion != 18 {
+		t.Errorf("expected RPCVersion 18, got %d", cfg.RPCVersion)
 	}
 	if cfg.Version != "14.0.0" {
 		t.Errorf("expected Version '14.0.0', got '%s'", cfg.Version)
@@ -70,13 +70,17 @@ func TestStatusFromString(t *testing.T) {
 		{"COMPLETED", StatusStopped},
 		{"ERROR", StatusStopped},
 		{"CHECKWAIT", StatusCheckWait},
-		{"PREPARING_DOWNLOAD", StatusCheckWait},
+		{"PREPARING_DOWNLOAD", StatusDownloading},
 		{"CHECK", StatusCheck},
-		{"COMPLETING", StatusCheck},
+		{"COMPLETING", StatusDownloading},
 		{"QUEUED", StatusQueued},
 		{"IN_QUEUE", StatusQueued},
+		{"WAITING", StatusQueued},
+		{"WAITING_FOR_COMPLETE_QUEUE", StatusQueued},
 		{"DOWNLOADING", StatusDownloading},
+		{"downloading", StatusDownloading},
 		{"SEEDINGWAIT", StatusSeedingWait},
+		{"SEEDING_WAIT", StatusSeedingWait},
 		{"SEEDING", StatusSeeding},
 		{"UNKNOWN_STATUS", StatusCheckWait}, // default
 		{"", StatusCheckWait},               // empty string
@@ -92,6 +96,15 @@ func TestStatusFromString(t *testing.T) {
 	}
 }
 
+func TestParseStatusUnknown(t *testing.T) {
+	if status, ok := ParseStatus("TELEPORTING"); ok || status != StatusCheckWait {
+		t.Errorf("expected an unknown status reported as check wait, got %d, %v", status, ok)
+	}
+	if _, ok := ParseStatus("SEEDING"); !ok {
+		t.Error("expected SEEDING to be known")
+	}
+}
+
 func TestTorrentStatusValues(t *testing.T) {
 	if StatusStopped != 0 {
 		t.Errorf("expected StatusStopped = 0, got %d", StatusStopped)
@@ -213,6 +226,71 @@ func TestTorrentFromPutIOTransferWithNilFields(t *testing.T) {
 	}
 }
 
+func TestTorrentFromPutIOTransferSeedingStats(t *testing.T) {
+	size, downloaded, uploaded, seeding := int64(1000), int64(1000), int64(2500), int64(600)
+	ratio := 2.4
+
+	tests := []struct {
+		name      string
+		transfer  *putio.Transfer
+		uploaded  int64
+		ratio     float64
+		secSeeded int64
+	}{
+		{
+			name:      "ratio from put.io",
+			transfer:  &putio.Transfer{Size: &size, Downloaded: &downloaded, Uploaded: &uploaded, CurrentRatio: &ratio, SecondsSeeding: &seeding},
+			uploaded:  2500,
+			ratio:     2.4,
+			secSeeded: 600,
+		},
+		{
+			name:     "ratio worked out",
+			transfer: &putio.Transfer{Size: &size, Downloaded: &downloaded, Uploaded: &uploaded},
+			uploaded: 2500,
+			ratio:    2.5,
+		},
+		{
+			name:     "nothing downloaded",
+			transfer: &putio.Transfer{Uploaded: &uploaded},
+			uploaded: 2500,
+		},
+		{
+			name:     "no seeding stats",
+			transfer: &putio.Transfer{Size: &size, Downloaded: &downloaded},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			torrent := TorrentFromPutIOTransfer(tt.transfer, "/downloads")
+			if torrent.UploadedEver != tt.uploaded || torrent.UploadRatio != tt.ratio || torrent.SecondsSeeding != tt.secSeeded {
+				t.Errorf("expected uploaded %d, ratio %v, seeding %d, got %d, %v, %d",
+					tt.uploaded, tt.ratio, tt.secSeeded, torrent.UploadedEver, torrent.UploadRatio, torrent.SecondsSeeding)
+			}
+		})
+	}
+}
+
+func TestTorrentFromPutIOTransferSwarmActivity(t *testing.T) {
+	transfer := &putio.Transfer{
+		Status:             "DOWNLOADING",
+		PeersConnected:     12,
+		PeersGettingFromUs: 3,
+		PeersSendingToUs:   9,
+		DownSpeed:          2048000,
+		UpSpeed:            64000,
+	}
+
+	torrent := TorrentFromPutIOTransfer(transfer, "/downloads")
+	if torrent.PeersConnected != 12 || torrent.PeersGettingFromUs != 3 || torrent.PeersSendingToUs != 9 {
+		t.Errorf("unexpected peers: %d %d %d", torrent.PeersConnected, torrent.PeersGettingFromUs, torrent.PeersSendingToUs)
+	}
+	if torrent.RateDownload != 2048000 || torrent.RateUpload != 64000 {
+		t.Errorf("unexpected rates: %d %d", torrent.RateDownload, torrent.RateUpload)
+	}
+}
+
 func TestTorrentFromPutIOTransferFinished(t *testing.T) {
 	finishedAt := "2024-01-15T12:00:00"
 	transfer := &putio.Transfer{
@@ -520,6 +598,22 @@ func TestConfigIdleSeedingSettings(t *testing.T) {
 	}
 }
 
+func TestConfigApply(t *testing.T) {
+	cfg := DefaultConfig("/downloads")
+	var args SessionSetArguments
+	if err := json.Unmarshal([]byte(`{"seedRatioLimit": 3, "seedRatioLimited": false, "speed-limit-up": 50, "speed-limit-up-enabled": true}`), &args); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg.Apply(&args)
+
+	if cfg.SeedRatioLimit != 3 || cfg.SeedRatioLimited || cfg.SpeedLimitUp != 50 || !cfg.SpeedLimitUpEnabled {
+		t.Errorf("expected the fields to be updated, got %+v", cfg)
+	}
+	if cfg.DownloadDir != "/downloads" || cfg.IdleSeedingLimit != 100 || cfg.SpeedLimitDown != 100 {
+		t.Errorf("expected the other fields to keep their value, got %+v", cfg)
+	}
+}
+
 func TestTorrentAllFields(t *testing.T) {
 	hash := "abc123def456"
 	errorStr := "Test error"
@@ -588,11 +682,12 @@ func TestStatusFromStringAllStatuses(t *testing.T) {
 		"COMPLETED":          StatusStopped,
 		"ERROR":              StatusStopped,
 		"CHECKWAIT":          StatusCheckWait,
-		"PREPARING_DOWNLOAD": StatusCheckWait,
+		"PREPARING_DOWNLOAD": StatusDownloading,
 		"CHECK":              StatusCheck,
-		"COMPLETING":         StatusCheck,
+		"COMPLETING":         StatusDownloading,
 		"QUEUED":             StatusQueued,
 		"IN_QUEUE":           StatusQueued,
+		"WAITING":            StatusQueued,
 		"DOWNLOADING":        StatusDownloading,
 		"SEEDINGWAIT":        StatusSeedingWait,
 		"SEEDING":            StatusSeeding,
@@ -847,3 +942,74 @@ func TestTorrentFromPutIOTransferZeroValues(t *testing.T) {
 		t.Errorf("expected ETA 0, got %d", torrent.ETA)
 	}
 }
+
+func TestTorrentIDsUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected TorrentIDs
+		wantErr  bool
+	}{
+		{name: "single number", input: `5`, expected: TorrentIDs{"5"}},
+		{name: "single hash", input: `"abcd"`, expected: TorrentIDs{"abcd"}},
+		{name: "numbers", input: `[1, 2]`, expected: TorrentIDs{"1", "2"}},
+		{name: "mixed", input: `[1, "abcd"]`, expected: TorrentIDs{"1", "abcd"}},
+		{name: "recently active", input: `"recently-active"`, expected: TorrentIDs{"recently-active"}},
+		{name: "invalid", input: `[true]`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ids TorrentIDs
+			err := json.Unmarshal([]byte(tt.input), &ids)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %v", ids)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(ids) != len(tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, ids)
+			}
+			for i := range ids {
+				if ids[i] != tt.expected[i] {
+					t.Errorf("expected %v, got %v", tt.expected, ids)
+				}
+			}
+		})
+	}
+}
+
+func TestTorrentIDsMatches(t *testing.T) {
+	ids := TorrentIDs{"7", "ABCD"}
+	if !ids.Matches(7, "") {
+		t.Error("expected numeric ID to match")
+	}
+	if !ids.Matches(1, "abcd") {
+		t.Error("expected hash to match case-insensitively")
+	}
+	if ids.Matches(1, "ffff") {
+		t.Error("did not expect a match")
+	}
+	if !(TorrentIDs{" 007 "}).Matches(7, "") {
+		t.Error("expected padded numeric ID to match")
+	}
+	if !(TorrentIDs{" abcd"}).Matches(1, "ABCD ") {
+		t.Error("expected padded hash to match")
+	}
+	if (TorrentIDs{"ABCD"}).Matches(1, "") {
+		t.Error("did not expect a hash to match a transfer without one")
+	}
+	if ids.All() {
+		t.Error("did not expect explicit IDs to select all torrents")
+	}
+	if !(TorrentIDs{}).All() || !(TorrentIDs{"recently-active"}).All() {
+		t.Error("expected empty and recently-active IDs to select all torrents")
+	}
+	if !(TorrentIDs{"recently-active"}).RecentlyActive() || (TorrentIDs{}).RecentlyActive() || ids.RecentlyActive() {
+		t.Error("expected only recently-active IDs to be recently active")
+	}
+}
@@ -0,0 +1,171 @@
+package unpack
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/nwaples/rardecode/v2"
+)
+
+var (
+	partRarPattern = regexp.MustCompile(`(?i)\.part(\d+)\.rar$`)
+	oldRarPattern  = regexp.MustCompile(`(?i)\.r\d{2}$`)
+	zipPartPattern = regexp.MustCompile(`(?i)\.z\d{2}$`)
+)
+
+// IsArchive reports whether name is a zip or RAR archive or one of its volumes.
+func IsArchive(name string) bool {
+	lower := strings.ToLower(name)
+	return strings.HasSuffix(lower, ".rar") || strings.HasSuffix(lower, ".zip") ||
+		oldRarPattern.MatchString(lower) || zipPartPattern.MatchString(lower)
+}
+
+// IsFirstVolume reports whether name is the volume extraction has to start
+// from: a .zip, a .rar without a part number, or the .part1.rar of a set.
+func IsFirstVolume(name string) bool {
+	lower := strings.ToLower(name)
+	if strings.HasSuffix(lower, ".zip") {
+		return true
+	}
+	if !strings.HasSuffix(lower, ".rar") {
+		return false
+	}
+	if m := partRarPattern.FindStringSubmatch(lower); m != nil {
+		return strings.TrimLeft(m[1], "0") == "1"
+	}
+	return true
+}
+
+// Unpacker extracts zip and RAR archives in-process.
+type Unpacker struct{}
+
+// New creates an Unpacker
+func New() *Unpacker {
+	return &Unpacker{}
+}
+
+// Extract unpacks archive into dest and returns the paths of the extracted
+// files. For multi-volume RAR sets archive is the first volume; the others
+// are found next to it.
+func (u *Unpacker) Extract(ctx context.Context, archive, dest string) ([]string, error) {
+	if strings.HasSuffix(strings.ToLower(archive), ".zip") {
+		return extractZip(ctx, archive, dest)
+	}
+	return extractRar(ctx, archive, dest)
+}
+
+// extractZip extracts a zip archive, refusing entries that would escape dest.
+func extractZip(ctx context.Context, archive, dest string) ([]string, error) {
+	r, err := zip.OpenReader(archive)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", archive, err)
+	}
+	defer r.Close()
+
+	var extracted []string
+	for _, f := range r.File {
+		target, err := safeJoin(dest, f.Name)
+		if err != nil {
+			return extracted, err
+		}
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, 0777); err != nil {
+				return extracted, err
+			}
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return extracted, fmt.Errorf("opening %s: %w", f.Name, err)
+		}
+		err = writeFile(ctx, rc, target)
+		rc.Close()
+		if err != nil {
+			return extracted, fmt.Errorf("extracting %s: %w", f.Name, err)
+		}
+		extracted = append(extracted, target)
+	}
+	return extracted, nil
+}
+
+// extractRar extracts a RAR archive, following its volumes, and refuses
+// entries that would escape dest.
+func extractRar(ctx context.Context, archive, dest string) ([]string, error) {
+	r, err := rardecode.OpenReader(archive)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", archive, err)
+	}
+	defer r.Close()
+
+	var extracted []string
+	for {
+		header, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return extracted, nil
+		}
+		if err != nil {
+			return extracted, fmt.Errorf("reading %s: %w", archive, err)
+		}
+		target, err := safeJoin(dest, header.Name)
+		if err != nil {
+			return extracted, err
+		}
+		if header.IsDir {
+			if err := os.MkdirAll(target, 0777); err != nil {
+				return extracted, err
+			}
+			continue
+		}
+		if err := writeFile(ctx, r, target); err != nil {
+			return extracted, fmt.Errorf("extracting %s: %w", header.Name, err)
+		}
+		extracted = append(extracted, target)
+	}
+}
+
+// writeFile copies r into a new file at target, stopping when ctx is done.
+func writeFile(ctx context.Context, r io.Reader, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0777); err != nil {
+		return err
+	}
+	out, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, &contextReader{ctx: ctx, r: r}); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// contextReader fails reads once ctx is done, so large archives don't hold up
+// shutdown.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
+
+// safeJoin joins name onto dest, rejecting absolute paths and path traversal.
+func safeJoin(dest, name string) (string, error) {
+	target := filepath.Join(dest, name)
+	rel, err := filepath.Rel(dest, target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) || filepath.IsAbs(name) {
+		return "", fmt.Errorf("illegal path in archive: %s", name)
+	}
+	return target, nil
+}
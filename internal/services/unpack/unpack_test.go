@@ -0,0 +1,228 @@
+package unpack
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIsArchive(t *testing.T) {
+	tests := []struct {
+		name     string
+		archive  bool
+		firstVol bool
+	}{
+		{name: "release.rar", archive: true, firstVol: true},
+		{name: "release.part1.rar", archive: true, firstVol: true},
+		{name: "release.part01.rar", archive: true, firstVol: true},
+		{name: "release.part02.rar", archive: true, firstVol: false},
+		{name: "release.part10.rar", archive: true, firstVol: false},
+		{name: "release.r00", archive: true, firstVol: false},
+		{name: "release.ZIP", archive: true, firstVol: true},
+		{name: "release.z01", archive: true, firstVol: false},
+		{name: "release.mkv", archive: false, firstVol: false},
+		{name: "release.nfo", archive: false, firstVol: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsArchive(tt.name); got != tt.archive {
+				t.Errorf("IsArchive(%q) = %v, expected %v", tt.name, got, tt.archive)
+			}
+			if got := IsFirstVolume(tt.name); got != tt.firstVol {
+				t.Errorf("IsFirstVolume(%q) = %v, expected %v", tt.name, got, tt.firstVol)
+			}
+		})
+	}
+}
+
+func writeZip(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("failed to create zip: %v", err)
+	}
+	w := zip.NewWriter(f)
+	for name, content := range files {
+		fw, err := w.Create(name)
+		if err != nil {
+			t.Fatalf("failed to add %s: %v", name, err)
+		}
+		fw.Write([]byte(content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to write zip: %v", err)
+	}
+	f.Close()
+}
+
+func TestExtractZip(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "release.zip")
+	writeZip(t, archive, map[string]string{
+		"movie.mkv":   "video",
+		"subs/en.srt": "subtitle",
+		"subs/empty/": "",
+	})
+
+	extracted, err := New().Extract(context.Background(), archive, dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(extracted) != 2 {
+		t.Fatalf("expected 2 extracted files, got %v", extracted)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, "subs", "en.srt"))
+	if err != nil || string(content) != "subtitle" {
+		t.Errorf("expected extracted subtitle, got %q (err %v)", content, err)
+	}
+}
+
+func TestExtractZipRejectsTraversal(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "evil.zip")
+	writeZip(t, archive, map[string]string{"../escape.txt": "nope"})
+
+	dest := filepath.Join(dir, "dest")
+	if _, err := New().Extract(context.Background(), archive, dest); err == nil {
+		t.Fatal("expected error for path traversal")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "escape.txt")); !os.IsNotExist(err) {
+		t.Error("expected file outside destination not to be written")
+	}
+}
+
+// rarBlock encodes a RAR 4 block header with its CRC.
+func rarBlock(htype byte, flags uint16, body []byte) []byte {
+	header := make([]byte, 7, 7+len(body))
+	header[2] = htype
+	binary.LittleEndian.PutUint16(header[3:], flags)
+	binary.LittleEndian.PutUint16(header[5:], uint16(7+len(body)))
+	header = append(header, body...)
+	binary.LittleEndian.PutUint16(header, uint16(crc32.ChecksumIEEE(header[2:])))
+	return header
+}
+
+// writeRar writes content stored (uncompressed) as name, split evenly across
+// volumes named base.partN.rar, and returns the path of the first volume.
+func writeRar(t *testing.T, dir, base, name, content string, volumes int) string {
+	t.Helper()
+	const (
+		hasData     = 0x8000
+		splitBefore = 0x0001
+		splitAfter  = 0x0002
+		arcVolume   = 0x0001
+		newNaming   = 0x0010
+		notLast     = 0x0001
+	)
+	sum := crc32.ChecksumIEEE([]byte(content))
+	chunk := (len(content) + volumes - 1) / volumes
+
+	for v := 0; v < volumes; v++ {
+		part := content[min(v*chunk, len(content)):min((v+1)*chunk, len(content))]
+		var arcFlags uint16
+		if volumes > 1 {
+			arcFlags = arcVolume | newNaming
+		}
+		fileFlags := uint16(hasData)
+		if v > 0 {
+			fileFlags |= splitBefore
+		}
+		if v < volumes-1 {
+			fileFlags |= splitAfter
+		}
+
+		file := make([]byte, 25)
+		binary.LittleEndian.PutUint32(file[0:], uint32(len(part))) // packed size
+		binary.LittleEndian.PutUint32(file[4:], uint32(len(content)))
+		binary.LittleEndian.PutUint32(file[9:], sum)
+		file[17] = 29   // decoder version
+		file[18] = 0x30 // stored
+		binary.LittleEndian.PutUint16(file[19:], uint16(len(name)))
+		binary.LittleEndian.PutUint32(file[21:], 0x20)
+		file = append(file, name...)
+
+		var endFlags uint16
+		if v < volumes-1 {
+			endFlags = notLast
+		}
+
+		data := []byte("Rar!\x1a\x07\x00")
+		data = append(data, rarBlock(0x73, arcFlags, make([]byte, 6))...)
+		data = append(data, rarBlock(0x74, fileFlags, file)...)
+		data = append(data, part...)
+		data = append(data, rarBlock(0x7b, endFlags, nil)...)
+
+		path := filepath.Join(dir, base+".rar")
+		if volumes > 1 {
+			path = filepath.Join(dir, fmt.Sprintf("%s.part%d.rar", base, v+1))
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatalf("failed to write volume: %v", err)
+		}
+	}
+	if volumes > 1 {
+		return filepath.Join(dir, base+".part1.rar")
+	}
+	return filepath.Join(dir, base+".rar")
+}
+
+func TestExtractRar(t *testing.T) {
+	tests := []struct {
+		name    string
+		volumes int
+	}{
+		{name: "single volume", volumes: 1},
+		{name: "multi volume", volumes: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			archive := writeRar(t, dir, "release", "movie.mkv", "some video content", tt.volumes)
+
+			extracted, err := New().Extract(context.Background(), archive, dir)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(extracted) != 1 || extracted[0] != filepath.Join(dir, "movie.mkv") {
+				t.Fatalf("expected movie.mkv to be extracted, got %v", extracted)
+			}
+			content, err := os.ReadFile(extracted[0])
+			if err != nil || string(content) != "some video content" {
+				t.Errorf("expected extracted content, got %q (err %v)", content, err)
+			}
+		})
+	}
+}
+
+func TestExtractRarRejectsTraversal(t *testing.T) {
+	dir := t.TempDir()
+	archive := writeRar(t, dir, "evil", "../escape.txt", "nope", 1)
+
+	dest := filepath.Join(dir, "dest")
+	if _, err := New().Extract(context.Background(), archive, dest); err == nil {
+		t.Fatal("expected error for path traversal")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "escape.txt")); !os.IsNotExist(err) {
+		t.Error("expected file outside destination not to be written")
+	}
+}
+
+func TestExtractRarCorrupt(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "release.rar")
+	if err := os.WriteFile(archive, []byte("Rar!"), 0644); err != nil {
+		t.Fatalf("failed to write archive: %v", err)
+	}
+
+	if _, err := New().Extract(context.Background(), archive, dir); err == nil {
+		t.Fatal("expected error for a corrupt archive")
+	}
+}
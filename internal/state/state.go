@@ -0,0 +1,160 @@
+// Package state exports the proxy's persisted state to a single JSON snapshot
+// and imports it again, to back it up or move it to another host.
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/ochronus/goputioarr/internal/config"
+	"github.com/ochronus/goputioarr/internal/fileutil"
+	"github.com/ochronus/goputioarr/internal/history"
+)
+
+// Version is the snapshot format written by Export. Version 1 snapshots keyed
+// the state files by file name; they're still imported.
+const Version = 2
+
+// Snapshot is a copy of the persisted state. Files holds the JSON state files
+// of the state directory by what they hold, e.g. "labels", so a snapshot
+// doesn't depend on how the files are named.
+type Snapshot struct {
+	Version    int                        `json:"version"`
+	ExportedAt time.Time                  `json:"exported_at"`
+	Files      map[string]json.RawMessage `json:"files"`
+	History    []history.Entry            `json:"history"`
+}
+
+// statePaths returns the JSON state files cfg uses, by what they hold.
+func statePaths(cfg *config.Config) map[string]string {
+	paths := make(map[string]string)
+	for name, path := range map[string]string{
+		"owned_transfers": cfg.OwnedTransfersPath(),
+		"labels":          cfg.TransferLabelsPath(),
+		"held_transfers":  cfg.HeldTransfersPath(),
+		"locations":       cfg.TransferLocationsPath(),
+		"stages":          cfg.TransferStagesPath(),
+		"rss_seen":        cfg.RSSSeenPath(),
+	} {
+		if path != "" {
+			paths[name] = path
+		}
+	}
+	return paths
+}
+
+// v1Names maps the file names version 1 snapshots keyed state files by to
+// what the files hold.
+var v1Names = map[string]string{
+	"owned_transfers.json":    "owned_transfers",
+	"transfer_labels.json":    "labels",
+	"held_transfers.json":     "held_transfers",
+	"transfer_locations.json": "locations",
+	"transfer_stages.json":    "stages",
+	"rss_seen.json":           "rss_seen",
+}
+
+func historyStore(cfg *config.Config) *history.FileStore {
+	return history.NewFileStore(cfg.History.Path, cfg.History.Retention.Duration(), cfg.History.MaxEntries)
+}
+
+// Export reads the state files and history cfg uses. Missing files are left
+// out of the snapshot.
+func Export(cfg *config.Config, now time.Time) (*Snapshot, error) {
+	snapshot := &Snapshot{
+		Version:    Version,
+		ExportedAt: now.UTC(),
+		Files:      make(map[string]json.RawMessage),
+	}
+	for name, path := range statePaths(cfg) {
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		if !json.Valid(data) {
+			return nil, fmt.Errorf("%s is not valid JSON", path)
+		}
+		snapshot.Files[name] = data
+	}
+
+	entries, err := historyStore(cfg).List()
+	if err != nil {
+		return nil, err
+	}
+	if entries == nil {
+		entries = []history.Entry{}
+	}
+	snapshot.History = entries
+	return snapshot, nil
+}
+
+// Existing returns the names of the state files and history present, which
+// Import would replace.
+func Existing(cfg *config.Config) []string {
+	var names []string
+	for name, path := range statePaths(cfg) {
+		if _, err := os.Stat(path); err == nil {
+			names = append(names, name)
+		}
+	}
+	if _, err := os.Stat(cfg.History.Path); err == nil {
+		names = append(names, "history")
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Import replaces the state cfg uses with snapshot: its files are written,
+// state files it doesn't hold are removed and the history is replaced. The
+// proxy must not be running, it would overwrite the files with its own state.
+func Import(cfg *config.Config, snapshot *Snapshot) error {
+	if snapshot.Version < 1 || snapshot.Version > Version {
+		return fmt.Errorf("unsupported snapshot version %d", snapshot.Version)
+	}
+	files := snapshot.Files
+	if snapshot.Version == 1 {
+		files = make(map[string]json.RawMessage, len(snapshot.Files))
+		for name, data := range snapshot.Files {
+			if renamed, ok := v1Names[name]; ok {
+				name = renamed
+			}
+			files[name] = data
+		}
+	}
+	paths := statePaths(cfg)
+	for name, data := range files {
+		if _, ok := paths[name]; !ok {
+			return fmt.Errorf("unknown state file %q in snapshot", name)
+		}
+		if !json.Valid(data) {
+			return fmt.Errorf("state file %q in snapshot is not valid JSON", name)
+		}
+	}
+
+	for name, path := range paths {
+		data, ok := files[name]
+		if !ok {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove %s: %w", name, err)
+			}
+			continue
+		}
+		if err := fileutil.WriteAtomic(path, data); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+
+	if len(snapshot.History) == 0 {
+		if err := os.Remove(cfg.History.Path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove history: %w", err)
+		}
+		return nil
+	}
+	return historyStore(cfg).Replace(snapshot.History)
+}
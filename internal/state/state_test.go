@@ -0,0 +1,145 @@
+package state
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ochronus/goputioarr/internal/config"
+	"github.com/ochronus/goputioarr/internal/history"
+)
+
+func testConfig(t *testing.T) *config.Config {
+	t.Helper()
+	dir := t.TempDir()
+	return &config.Config{
+		StateDirectory: dir,
+		History:        config.HistoryConfig{Path: filepath.Join(dir, "history.jsonl")},
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	source := testConfig(t)
+	if err := os.WriteFile(source.OwnedTransfersPath(), []byte(`["abc"]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(source.RSSSeenPath(), []byte(`{"feed\nguid":"2024-01-01T00:00:00Z"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	completed := time.Now().UTC().Truncate(time.Second)
+	if err := history.NewFileStore(source.History.Path, 0, 0).Record(history.Entry{Name: "Show", Hash: "abc", CompletedAt: completed}); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot, err := Export(source, time.Now())
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if len(snapshot.Files) != 2 || len(snapshot.History) != 1 {
+		t.Fatalf("expected 2 files and 1 history entry, got %+v", snapshot)
+	}
+	if _, ok := snapshot.Files["owned_transfers"]; !ok {
+		t.Errorf("expected state files keyed by what they hold, got %v", snapshot.Files)
+	}
+
+	// Round trip through JSON as the command does.
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Snapshot
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	target := testConfig(t)
+	// Stale state the snapshot doesn't hold is removed.
+	if err := os.WriteFile(target.TransferLabelsPath(), []byte(`{}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := Existing(target); !reflect.DeepEqual(got, []string{"labels"}) {
+		t.Errorf("Existing() = %v", got)
+	}
+	if err := Import(target, &decoded); err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+
+	owned, err := os.ReadFile(target.OwnedTransfersPath())
+	if err != nil || string(owned) != `["abc"]` {
+		t.Errorf("expected owned transfers to be imported, got %q, %v", owned, err)
+	}
+	if _, err := os.Stat(target.TransferLabelsPath()); !os.IsNotExist(err) {
+		t.Errorf("expected labels missing from the snapshot to be removed, got %v", err)
+	}
+	entries, err := history.NewFileStore(target.History.Path, 0, 0).List()
+	if err != nil || len(entries) != 1 || entries[0].Name != "Show" || !entries[0].CompletedAt.Equal(completed) {
+		t.Errorf("expected history to be imported, got %+v, %v", entries, err)
+	}
+	want := []string{"history", "owned_transfers", "rss_seen"}
+	if got := Existing(target); !reflect.DeepEqual(got, want) {
+		t.Errorf("Existing() = %v, want %v", got, want)
+	}
+}
+
+func TestExportIncludesHeldTransfersAndLocations(t *testing.T) {
+	cfg := testConfig(t)
+	if err := os.WriteFile(cfg.HeldTransfersPath(), []byte(`["abc"]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cfg.TransferLocationsPath(), []byte(`{"abc":"/downloads/tv"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot, err := Export(cfg, time.Now())
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	for _, name := range []string{"held_transfers", "locations"} {
+		if _, ok := snapshot.Files[name]; !ok {
+			t.Errorf("expected %s in the snapshot, got %v", name, snapshot.Files)
+		}
+	}
+}
+
+func TestImportVersion1Snapshot(t *testing.T) {
+	cfg := testConfig(t)
+	snapshot := &Snapshot{Version: 1, Files: map[string]json.RawMessage{
+		"owned_transfers.json": json.RawMessage(`["abc"]`),
+		"transfer_labels.json": json.RawMessage(`{"abc":["tv"]}`),
+	}}
+	if err := Import(cfg, snapshot); err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	labels, err := os.ReadFile(cfg.TransferLabelsPath())
+	if err != nil || string(labels) != `{"abc":["tv"]}` {
+		t.Errorf("expected labels to be imported, got %q, %v", labels, err)
+	}
+	if got := Existing(cfg); !reflect.DeepEqual(got, []string{"labels", "owned_transfers"}) {
+		t.Errorf("Existing() = %v", got)
+	}
+}
+
+func TestImportRejectsInvalidSnapshots(t *testing.T) {
+	tests := []struct {
+		name     string
+		snapshot Snapshot
+	}{
+		{"unknown version", Snapshot{Version: Version + 1}},
+		{"missing version", Snapshot{}},
+		{"unknown file", Snapshot{Version: Version, Files: map[string]json.RawMessage{"../config.toml": json.RawMessage(`{}`)}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(t)
+			if err := Import(cfg, &tt.snapshot); err == nil {
+				t.Fatal("expected an error")
+			}
+			if got := Existing(cfg); len(got) != 0 {
+				t.Errorf("expected nothing to be written, got %v", got)
+			}
+		})
+	}
+}
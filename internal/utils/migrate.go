@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/BurntSushi/toml"
+	"github.com/ochronus/goputioarr/internal/config"
+)
+
+// rustConfig is the config format of the original Rust putioarr. goputioarr
+// reads every setting it has the same way, so migrating means carrying over
+// the settings it knows and reporting the rest.
+type rustConfig struct {
+	Username             string          `toml:"username,omitempty"`
+	Password             string          `toml:"password,omitempty"`
+	DownloadDirectory    string          `toml:"download_directory,omitempty"`
+	BindAddress          string          `toml:"bind_address,omitempty"`
+	Port                 *int            `toml:"port,omitempty"`
+	Loglevel             string          `toml:"loglevel,omitempty"`
+	UID                  *int            `toml:"uid,omitempty"`
+	PollingInterval      *int64          `toml:"polling_interval,omitempty"`
+	SkipDirectories      *[]string       `toml:"skip_directories,omitempty"`
+	OrchestrationWorkers *int            `toml:"orchestration_workers,omitempty"`
+	DownloadWorkers      *int            `toml:"download_workers,omitempty"`
+	Putio                rustPutioConfig `toml:"putio"`
+	Sonarr               *rustArrConfig  `toml:"sonarr,omitempty"`
+	Radarr               *rustArrConfig  `toml:"radarr,omitempty"`
+	Whisparr             *rustArrConfig  `toml:"whisparr,omitempty"`
+}
+
+type rustPutioConfig struct {
+	APIKey string `toml:"api_key"`
+}
+
+type rustArrConfig struct {
+	URL    string `toml:"url"`
+	APIKey string `toml:"api_key"`
+}
+
+// MigrationResult is a goputioarr config converted from a Rust putioarr one.
+type MigrationResult struct {
+	Config string
+	// Skipped lists the settings of the old config goputioarr doesn't know.
+	Skipped []string
+	// Invalid is why the migrated config doesn't validate, if it doesn't.
+	Invalid error
+}
+
+// MigrateConfig converts the Rust putioarr config at fromPath.
+func MigrateConfig(fromPath string) (*MigrationResult, error) {
+	var old rustConfig
+	meta, err := toml.DecodeFile(fromPath, &old)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read putioarr config: %w", err)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# Migrated from the putioarr config %s.\n", fromPath)
+	buf.WriteString("# See the README for the settings goputioarr adds.\n\n")
+	encoder := toml.NewEncoder(&buf)
+	encoder.Indent = ""
+	if err := encoder.Encode(old); err != nil {
+		return nil, fmt.Errorf("failed to write config: %w", err)
+	}
+
+	result := &MigrationResult{Config: buf.String()}
+	for _, key := range meta.Undecoded() {
+		result.Skipped = append(result.Skipped, key.String())
+	}
+	sort.Strings(result.Skipped)
+
+	cfg := config.DefaultConfig()
+	if _, err := toml.Decode(result.Config, cfg); err != nil {
+		return nil, fmt.Errorf("migrated config is invalid: %w", err)
+	}
+	result.Invalid = cfg.Validate()
+	return result, nil
+}
+
+// Migrate writes the goputioarr equivalent of the Rust putioarr config at
+// fromPath to configPath, backing up an existing file, and reports what
+// didn't carry over. fromPath and configPath may be the same file.
+func Migrate(fromPath, configPath string) error {
+	result, err := MigrateConfig(fromPath)
+	if err != nil {
+		return err
+	}
+	if err := writeConfig(configPath, result.Config); err != nil {
+		return err
+	}
+	for _, key := range result.Skipped {
+		fmt.Printf("Skipped unknown setting %s\n", key)
+	}
+	if result.Invalid != nil {
+		fmt.Printf("Warning: the migrated config doesn't validate yet: %v\n", result.Invalid)
+	}
+	return nil
+}
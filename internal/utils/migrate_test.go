@@ -0,0 +1,128 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ochronus/goputioarr/internal/config"
+)
+
+func TestMigrateConfig(t *testing.T) {
+	downloads := t.TempDir()
+	fromPath := filepath.Join(t.TempDir(), "config.toml")
+	old := `username = "myusername"
+password = "mypassword"
+download_directory = "` + downloads + `"
+bind_address = "127.0.0.1"
+port = 9092
+loglevel = "debug"
+uid = 1001
+polling_interval = 30
+skip_directories = ["sample"]
+orchestration_workers = 5
+download_workers = 2
+keep_files = true
+
+[putio]
+api_key = "putiokey"
+
+[radarr]
+url = "http://radarr:7878"
+api_key = "radarrkey"
+`
+	if err := os.WriteFile(fromPath, []byte(old), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := MigrateConfig(fromPath)
+	if err != nil {
+		t.Fatalf("MigrateConfig: %v", err)
+	}
+	if result.Invalid != nil {
+		t.Errorf("expected a valid config, got %v", result.Invalid)
+	}
+	if len(result.Skipped) != 1 || result.Skipped[0] != "keep_files" {
+		t.Errorf("expected keep_files to be skipped, got %v", result.Skipped)
+	}
+
+	cfg := decodeConfig(t, result.Config)
+	if cfg.Username != "myusername" || cfg.Password != "mypassword" || cfg.DownloadDirectory != downloads {
+		t.Errorf("credentials or directory not migrated: %+v", cfg)
+	}
+	if cfg.BindAddress != "127.0.0.1" || cfg.Port != 9092 || cfg.Loglevel != "debug" || cfg.UID != 1001 {
+		t.Errorf("server settings not migrated: %+v", cfg)
+	}
+	if cfg.PollingInterval.Duration() != 30*time.Second || cfg.OrchestrationWorkers != 5 || cfg.DownloadWorkers != 2 {
+		t.Errorf("worker settings not migrated: %+v", cfg)
+	}
+	if len(cfg.SkipDirectories) != 1 || cfg.SkipDirectories[0] != "sample" {
+		t.Errorf("skip_directories not migrated: %v", cfg.SkipDirectories)
+	}
+	if cfg.Putio.APIKey != "putiokey" || cfg.Radarr == nil || cfg.Radarr.URL != "http://radarr:7878" || cfg.Radarr.APIKey != "radarrkey" {
+		t.Errorf("services not migrated: putio %+v radarr %+v", cfg.Putio, cfg.Radarr)
+	}
+	if cfg.Sonarr != nil || cfg.Whisparr != nil {
+		t.Error("expected unconfigured services to stay unset")
+	}
+}
+
+func TestMigrateConfigKeepsDefaults(t *testing.T) {
+	fromPath := filepath.Join(t.TempDir(), "config.toml")
+	old := "username = \"u\"\npassword = \"p\"\n\n[putio]\napi_key = \"k\"\n"
+	if err := os.WriteFile(fromPath, []byte(old), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := MigrateConfig(fromPath)
+	if err != nil {
+		t.Fatalf("MigrateConfig: %v", err)
+	}
+	for _, key := range []string{"port", "uid", "polling_interval", "skip_directories", "[sonarr]"} {
+		if strings.Contains(result.Config, key) {
+			t.Errorf("expected unset %s to be left to the defaults, got:\n%s", key, result.Config)
+		}
+	}
+	cfg := decodeConfig(t, result.Config)
+	defaults := config.DefaultConfig()
+	if cfg.Port != defaults.Port || cfg.UID != defaults.UID || cfg.PollingInterval != defaults.PollingInterval {
+		t.Errorf("expected defaults, got %+v", cfg)
+	}
+	// No download directory or arr service yet.
+	if result.Invalid == nil {
+		t.Error("expected the incomplete config not to validate")
+	}
+}
+
+func TestMigrateInPlace(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.toml")
+	old := "username = \"u\"\npassword = \"p\"\n\n[putio]\napi_key = \"k\"\n"
+	if err := os.WriteFile(path, []byte(old), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Migrate(path, path); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	backup, err := os.ReadFile(path + ".bak")
+	if err != nil || string(backup) != old {
+		t.Errorf("expected the old config to be backed up, got %q (%v)", backup, err)
+	}
+	migrated, err := os.ReadFile(path)
+	if err != nil || !strings.Contains(string(migrated), "Migrated from the putioarr config") {
+		t.Errorf("expected the migrated config, got %q (%v)", migrated, err)
+	}
+}
+
+func TestMigrateConfigInvalidTOML(t *testing.T) {
+	fromPath := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(fromPath, []byte("username = "), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := MigrateConfig(fromPath); err == nil || !strings.Contains(err.Error(), "failed to read putioarr config") {
+		t.Errorf("expected read error, got %v", err)
+	}
+}
@@ -48,7 +48,7 @@ func TestConfigTemplateDefaultValues(t *testing.T) {
 		"port":                  "9091",
 		"loglevel":              `"info"`,
 		"uid":                   "1000",
-		"polling_interval":      "10",
+		"polling_interval":      `"10s"`,
 		"orchestration_workers": "10",
 		"download_workers":      "4",
 	}
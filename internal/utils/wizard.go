@@ -0,0 +1,356 @@
+package utils
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/ochronus/goputioarr/internal/config"
+	"github.com/ochronus/goputioarr/internal/services/arr"
+	"github.com/ochronus/goputioarr/internal/services/putio"
+	"golang.org/x/term"
+)
+
+// wizardArr describes an arr service the wizard asks about and the template
+// lines it fills in.
+type wizardArr struct {
+	key         string
+	name        string
+	defaultURL  string
+	urlLine     string
+	apiKeyLine  string
+	sectionLine string
+}
+
+var wizardArrs = []wizardArr{
+	{key: "sonarr", name: "Sonarr", defaultURL: "http://localhost:8989",
+		sectionLine: "[sonarr]", urlLine: `url = "http://mysonarrhost:8989/sonarr"`, apiKeyLine: `api_key = "MYSONARRAPIKEY"`},
+	{key: "radarr", name: "Radarr", defaultURL: "http://localhost:7878",
+		sectionLine: "[radarr]", urlLine: `url = "http://myradarrhost:7878/radarr"`, apiKeyLine: `api_key = "MYRADARRAPIKEY"`},
+	{key: "whisparr", name: "Whisparr", defaultURL: "http://localhost:6969",
+		sectionLine: "[whisparr]", urlLine: `url = "http://mywhisparrhost:6969/radarr"`, apiKeyLine: `api_key = "MYWHISPARRAPIKEY"`},
+}
+
+// WizardAnswers are the settings the config wizard asks for.
+type WizardAnswers struct {
+	Username          string
+	Password          string
+	DownloadDirectory string
+	PutioAPIKey       string
+	// PutioAPIURL is the put.io API to use instead of put.io's, if not empty.
+	PutioAPIURL string
+	// Arrs maps "sonarr", "radarr" and "whisparr" to the configured services.
+	Arrs map[string]WizardArrAnswer
+}
+
+// WizardArrAnswer is the connection to an arr service.
+type WizardArrAnswer struct {
+	URL    string
+	APIKey string
+}
+
+// Wizard asks for the settings a working config needs on the terminal,
+// checking the put.io API key and arr connections as it goes.
+type Wizard struct {
+	in     *bufio.Reader
+	out    io.Writer
+	apiURL string
+
+	// readSecret reads an answer without echoing it, if in is a terminal.
+	readSecret func() (string, error)
+
+	getToken   func() (string, error)
+	checkPutio func(apiKey string) error
+	checkArr   func(url, apiKey string) (string, error)
+}
+
+// NewWizard creates a wizard reading answers from in and prompting on out.
+// A non-empty apiURL is the put.io API to use instead of put.io's.
+func NewWizard(in io.Reader, out io.Writer, apiURL string) *Wizard {
+	w := &Wizard{
+		in:       bufio.NewReader(in),
+		out:      out,
+		apiURL:   apiURL,
+		getToken: func() (string, error) { return GetToken(apiURL) },
+		checkPutio: func(apiKey string) error {
+			_, err := putio.NewClient(apiKey, putio.WithBaseURLs(apiURL, "")).GetAccountInfo()
+			return err
+		},
+		checkArr: func(url, apiKey string) (string, error) {
+			client := arr.NewClient(url, apiKey, arr.WithTimeout(10*time.Second), arr.WithMaxRetries(1))
+			status, err := client.SystemStatus()
+			if err != nil {
+				return "", err
+			}
+			return strings.TrimSpace(status.AppName + " " + status.Version), nil
+		},
+	}
+	if f, ok := in.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		w.readSecret = func() (string, error) {
+			secret, err := term.ReadPassword(int(f.Fd()))
+			// The newline typed isn't echoed either.
+			fmt.Fprintln(w.out)
+			return string(secret), err
+		}
+	}
+	return w
+}
+
+// Run asks for every setting and returns the rendered config.
+func (w *Wizard) Run() (string, error) {
+	answers, err := w.ask()
+	if err != nil {
+		return "", err
+	}
+	content := RenderConfig(answers)
+
+	// Catch mistakes now rather than when the proxy starts.
+	cfg := config.DefaultConfig()
+	if _, err := toml.Decode(content, cfg); err == nil {
+		if err := cfg.Validate(); err != nil {
+			fmt.Fprintf(w.out, "Warning: the config doesn't validate yet: %v\n", err)
+		}
+	}
+	return content, nil
+}
+
+func (w *Wizard) ask() (WizardAnswers, error) {
+	answers := WizardAnswers{PutioAPIURL: w.apiURL, Arrs: make(map[string]WizardArrAnswer)}
+	var err error
+
+	if answers.PutioAPIKey, err = w.askPutioKey(); err != nil {
+		return answers, err
+	}
+	if answers.DownloadDirectory, err = w.askDownloadDirectory(); err != nil {
+		return answers, err
+	}
+
+	fmt.Fprintln(w.out, "\nSonarr, Radarr and Whisparr connect to the proxy as a Transmission client with these credentials.")
+	if answers.Username, err = w.prompt("Username", "goputioarr"); err != nil {
+		return answers, err
+	}
+	if answers.Password, err = w.promptSecret("Password (empty to generate one)"); err != nil {
+		return answers, err
+	}
+	if answers.Password == "" {
+		answers.Password = generatePassword()
+		fmt.Fprintf(w.out, "Generated password: %s\n", answers.Password)
+	}
+
+	for len(answers.Arrs) == 0 {
+		for _, service := range wizardArrs {
+			answer, ok, err := w.askArr(service)
+			if err != nil {
+				return answers, err
+			}
+			if ok {
+				answers.Arrs[service.key] = answer
+			}
+		}
+		if len(answers.Arrs) == 0 {
+			fmt.Fprintln(w.out, "At least one of Sonarr, Radarr or Whisparr is required.")
+		}
+	}
+	return answers, nil
+}
+
+func (w *Wizard) askPutioKey() (string, error) {
+	for {
+		key, err := w.promptSecret("put.io API key (empty to link this device at put.io/link)")
+		if err != nil {
+			return "", err
+		}
+		if key == "" {
+			return w.getToken()
+		}
+		if err := w.checkPutio(key); err != nil {
+			fmt.Fprintf(w.out, "put.io rejected the key: %v\n", err)
+			continue
+		}
+		return key, nil
+	}
+}
+
+func (w *Wizard) askDownloadDirectory() (string, error) {
+	for {
+		dir, err := w.prompt("Download directory, readable by the arrs", "")
+		if err != nil {
+			return "", err
+		}
+		if dir == "" {
+			continue
+		}
+		if !filepath.IsAbs(dir) {
+			fmt.Fprintln(w.out, "Use an absolute path.")
+			continue
+		}
+		if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+			create, err := w.confirm(fmt.Sprintf("%s doesn't exist. Create it?", dir), true)
+			if err != nil {
+				return "", err
+			}
+			if create {
+				if err := os.MkdirAll(dir, 0755); err != nil {
+					fmt.Fprintf(w.out, "Couldn't create it: %v\n", err)
+					continue
+				}
+			}
+		}
+		return dir, nil
+	}
+}
+
+// askArr asks for the connection to service and checks it. ok is false if
+// the user skipped the service.
+func (w *Wizard) askArr(service wizardArr) (answer WizardArrAnswer, ok bool, err error) {
+	fmt.Fprintln(w.out)
+	for {
+		configure, err := w.confirm(fmt.Sprintf("Configure %s?", service.name), service.key == "sonarr")
+		if err != nil || !configure {
+			return answer, false, err
+		}
+		if answer.URL, err = w.prompt(service.name+" URL", service.defaultURL); err != nil {
+			return answer, false, err
+		}
+		for answer.APIKey == "" {
+			if answer.APIKey, err = w.promptSecret(service.name + " API key (Settings -> General)"); err != nil {
+				return answer, false, err
+			}
+		}
+
+		status, checkErr := w.checkArr(answer.URL, answer.APIKey)
+		if checkErr == nil {
+			fmt.Fprintf(w.out, "Connected to %s\n", status)
+			return answer, true, nil
+		}
+		fmt.Fprintf(w.out, "Couldn't connect to %s: %v\n", service.name, checkErr)
+		keep, err := w.confirm("Keep these settings anyway?", false)
+		if err != nil || keep {
+			return answer, keep, err
+		}
+		answer = WizardArrAnswer{}
+	}
+}
+
+// promptSecret asks for a password or key, without echoing the answer when
+// reading from a terminal.
+func (w *Wizard) promptSecret(question string) (string, error) {
+	if w.readSecret == nil {
+		return w.prompt(question, "")
+	}
+	fmt.Fprintf(w.out, "%s: ", question)
+	answer, err := w.readSecret()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(answer), nil
+}
+
+// prompt asks a question and returns the trimmed answer, or def if it's
+// empty.
+func (w *Wizard) prompt(question, def string) (string, error) {
+	if def != "" {
+		fmt.Fprintf(w.out, "%s [%s]: ", question, def)
+	} else {
+		fmt.Fprintf(w.out, "%s: ", question)
+	}
+	line, err := w.in.ReadString('\n')
+	switch {
+	case errors.Is(err, io.EOF) && line == "":
+		return "", errors.New("config wizard: input ended before all questions were answered")
+	case err != nil && !errors.Is(err, io.EOF):
+		return "", err
+	}
+	if answer := strings.TrimSpace(line); answer != "" {
+		return answer, nil
+	}
+	return def, nil
+}
+
+// confirm asks a yes/no question.
+func (w *Wizard) confirm(question string, def bool) (bool, error) {
+	hint := "y/N"
+	if def {
+		hint = "Y/n"
+	}
+	for {
+		answer, err := w.prompt(fmt.Sprintf("%s (%s)", question, hint), "")
+		if err != nil {
+			return false, err
+		}
+		switch strings.ToLower(answer) {
+		case "":
+			return def, nil
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+	}
+}
+
+// RenderConfig fills in the config template with answers. The arr sections
+// that weren't configured are commented out.
+func RenderConfig(answers WizardAnswers) string {
+	content := strings.Replace(configTemplate, "{{PUTIO_API_KEY}}", answers.PutioAPIKey, 1)
+	replace := func(old, new string) {
+		content = strings.Replace(content, old, new, 1)
+	}
+	replace(`username = "myusername"`, "username = "+tomlString(answers.Username))
+	replace(`password = "mypassword"`, "password = "+tomlString(answers.Password))
+	replace(`download_directory = "/path/to/downloads"`, "download_directory = "+tomlString(answers.DownloadDirectory))
+
+	for _, service := range wizardArrs {
+		if answer, ok := answers.Arrs[service.key]; ok {
+			replace(service.urlLine, "url = "+tomlString(answer.URL))
+			replace(service.apiKeyLine, "api_key = "+tomlString(answer.APIKey))
+			continue
+		}
+		replace("\n"+service.sectionLine+"\n", "\n# "+service.sectionLine+"\n")
+		replace(service.urlLine, "# "+service.urlLine)
+		replace(service.apiKeyLine, "# "+service.apiKeyLine)
+	}
+	return setPutioAPIURL(content, answers.PutioAPIURL)
+}
+
+// setPutioAPIURL sets the put.io api_url in a config rendered from the
+// template, if apiURL isn't empty.
+func setPutioAPIURL(content, apiURL string) string {
+	if apiURL == "" {
+		return content
+	}
+	return strings.Replace(content, `# api_url = "https://api.put.io/v2"`, "api_url = "+tomlString(apiURL), 1)
+}
+
+// tomlString quotes s as a TOML basic string.
+func tomlString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, `\u%04X`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+func generatePassword() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,216 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/ochronus/goputioarr/internal/config"
+)
+
+// testWizard returns a wizard answering with the given lines. The put.io key
+// "good" and arr API key "arrkey" pass the checks.
+func testWizard(lines ...string) (*Wizard, *bytes.Buffer) {
+	out := &bytes.Buffer{}
+	w := NewWizard(strings.NewReader(strings.Join(lines, "\n")+"\n"), out, "")
+	w.getToken = func() (string, error) { return "linked-token", nil }
+	w.checkPutio = func(apiKey string) error {
+		if apiKey != "good" {
+			return errors.New("401 Unauthorized")
+		}
+		return nil
+	}
+	w.checkArr = func(url, apiKey string) (string, error) {
+		if apiKey != "arrkey" {
+			return "", errors.New("401 Unauthorized")
+		}
+		return "Sonarr 4.0.0", nil
+	}
+	return w, out
+}
+
+func decodeConfig(t *testing.T, content string) *config.Config {
+	t.Helper()
+	cfg := config.DefaultConfig()
+	if _, err := toml.Decode(content, cfg); err != nil {
+		t.Fatalf("generated config is not valid TOML: %v\n%s", err, content)
+	}
+	return cfg
+}
+
+func TestWizardRun(t *testing.T) {
+	downloads := filepath.Join(t.TempDir(), "downloads")
+	w, out := testWizard(
+		"bad",         // put.io key, rejected
+		"good",        // put.io key
+		"relative",    // download directory, not absolute
+		downloads,     // download directory
+		"",            // create it (default yes)
+		"",            // username (default)
+		`pa"ss`,       // password
+		"",            // configure Sonarr (default yes)
+		"",            // Sonarr URL (default)
+		"wrong",       // Sonarr API key, rejected
+		"n",           // don't keep it
+		"y",           // configure Sonarr again
+		"http://tv",   // Sonarr URL
+		"arrkey",      // Sonarr API key
+		"",            // configure Radarr (default no)
+		"y",           // configure Whisparr
+		"http://w",    // Whisparr URL
+		"whisparrkey", // API key, rejected
+		"y",           // keep it anyway
+	)
+
+	content, err := w.Run()
+	if err != nil {
+		t.Fatalf("Run: %v\n%s", err, out)
+	}
+
+	cfg := decodeConfig(t, content)
+	if cfg.Putio.APIKey != "good" || cfg.Username != "goputioarr" || cfg.Password != `pa"ss` || cfg.DownloadDirectory != downloads {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+	if cfg.Sonarr == nil || cfg.Sonarr.URL != "http://tv" || cfg.Sonarr.APIKey != "arrkey" {
+		t.Errorf("unexpected sonarr %+v", cfg.Sonarr)
+	}
+	if cfg.Radarr != nil {
+		t.Errorf("expected radarr to be left out, got %+v", cfg.Radarr)
+	}
+	if cfg.Whisparr == nil || cfg.Whisparr.APIKey != "whisparrkey" {
+		t.Errorf("unexpected whisparr %+v", cfg.Whisparr)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected a working config, got %v", err)
+	}
+	if info, err := os.Stat(downloads); err != nil || !info.IsDir() {
+		t.Errorf("expected the download directory to be created: %v", err)
+	}
+
+	for _, want := range []string{"put.io rejected the key", "Use an absolute path", "Connected to Sonarr 4.0.0", "Couldn't connect to Whisparr"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out.String(), "Warning") {
+		t.Errorf("expected no validation warning, got:\n%s", out)
+	}
+}
+
+func TestWizardLinksDeviceAndGeneratesPassword(t *testing.T) {
+	dir := t.TempDir()
+	w, out := testWizard(
+		"",     // put.io key: link the device
+		dir,    // download directory
+		"user", // username
+		"",     // password: generate
+		"n",    // no Sonarr
+		"",     // no Radarr
+		"",     // no Whisparr
+		"",     // Sonarr, second round
+		"",     // URL
+		"arrkey",
+		"", // no Radarr
+		"", // no Whisparr
+	)
+
+	content, err := w.Run()
+	if err != nil {
+		t.Fatalf("Run: %v\n%s", err, out)
+	}
+	cfg := decodeConfig(t, content)
+	if cfg.Putio.APIKey != "linked-token" {
+		t.Errorf("expected the linked token, got %q", cfg.Putio.APIKey)
+	}
+	if len(cfg.Password) < 16 || !strings.Contains(out.String(), "Generated password: "+cfg.Password) {
+		t.Errorf("expected a generated password to be shown, got %q", cfg.Password)
+	}
+	if !strings.Contains(out.String(), "At least one of Sonarr, Radarr or Whisparr is required") {
+		t.Errorf("expected to be asked for an arr again, got:\n%s", out)
+	}
+	if cfg.Sonarr == nil || cfg.Sonarr.URL != "http://localhost:8989" {
+		t.Errorf("expected sonarr with the default URL, got %+v", cfg.Sonarr)
+	}
+}
+
+func TestWizardInputEnds(t *testing.T) {
+	w, _ := testWizard("good")
+	if _, err := w.Run(); err == nil || !strings.Contains(err.Error(), "input ended") {
+		t.Errorf("expected input ended error, got %v", err)
+	}
+}
+
+func TestGenerateConfigInteractive(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "nested", "config.toml")
+	in := strings.NewReader("")
+	if err := GenerateConfigInteractive(configPath, "", in, &bytes.Buffer{}); err == nil {
+		t.Fatal("expected error for empty input")
+	}
+	if _, err := os.Stat(configPath); !os.IsNotExist(err) {
+		t.Error("expected no config to be written when the wizard fails")
+	}
+}
+
+func TestWizardUsesPutioAPIURL(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	w := NewWizard(strings.NewReader(""), &bytes.Buffer{}, server.URL+"/v2")
+	if err := w.checkPutio("key"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(paths) != 1 || paths[0] != "/v2/account/info" {
+		t.Errorf("expected the key to be checked against the configured API, got %v", paths)
+	}
+
+	cfg := decodeConfig(t, RenderConfig(WizardAnswers{PutioAPIKey: "key", PutioAPIURL: server.URL + "/v2"}))
+	if cfg.Putio.APIURL != server.URL+"/v2" {
+		t.Errorf("expected api_url to be written, got %q", cfg.Putio.APIURL)
+	}
+	if cfg := decodeConfig(t, RenderConfig(WizardAnswers{PutioAPIKey: "key"})); cfg.Putio.APIURL != "" {
+		t.Errorf("expected api_url to stay commented out, got %q", cfg.Putio.APIURL)
+	}
+}
+
+func TestTOMLString(t *testing.T) {
+	tests := map[string]string{
+		`plain`:     `"plain"`,
+		`a"b\c`:     `"a\"b\\c"`,
+		"tab\there": `"tab\u0009here"`,
+	}
+	for in, expected := range tests {
+		if got := tomlString(in); got != expected {
+			t.Errorf("tomlString(%q) = %s, expected %s", in, got, expected)
+		}
+	}
+}
+
+func TestWizardPromptSecretDoesNotEcho(t *testing.T) {
+	w, out := testWizard("visible")
+	w.readSecret = func() (string, error) { return " hidden ", nil }
+
+	secret, err := w.promptSecret("Password")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if secret != "hidden" {
+		t.Errorf("expected the secret to be read without echo, got %q", secret)
+	}
+	answer, err := w.prompt("Username", "")
+	if err != nil || answer != "visible" {
+		t.Errorf("expected other answers to be read from the input, got %q, %v", answer, err)
+	}
+	if strings.Contains(out.String(), "hidden") {
+		t.Errorf("expected the secret not to be written out, got %q", out.String())
+	}
+}